@@ -5,6 +5,7 @@ import (
 	"log"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/itchio/butler/butlerd/jsonrpc2"
 	"github.com/itchio/headway/state"
@@ -27,6 +28,14 @@ type ServeTCPParams struct {
 	Log       bool
 	KeepAlive bool
 
+	// If enabled, only these processes may connect
+	PeerPolicy *PeerPolicy
+	// If non-zero, connections that haven't sent a request
+	// in that long must authenticate again
+	IdleTimeout time.Duration
+	// What connections may call, defaults to full access
+	PermissionLevel PermissionLevel
+
 	ShutdownChan chan struct{}
 }
 
@@ -90,9 +99,38 @@ func (s *Server) serveTCPKeepAlive(ctx context.Context, params ServeTCPParams) e
 }
 
 func (s *Server) handleTCPConn(parentCtx context.Context, params ServeTCPParams, tcpConn net.Conn) error {
-	gh := newGatedHandler(params.Handler, params.Secret)
+	remoteAddress := tcpConn.RemoteAddr().String()
+	if params.PeerPolicy.Enabled() {
+		peer, err := params.PeerPolicy.Check(tcpConn)
+		if err != nil {
+			rejection := ConnectionRejection{
+				Reason:        err.Error(),
+				RemoteAddress: remoteAddress,
+			}
+			if peer != nil {
+				rejection.PID = int64(peer.PID)
+				rejection.Executable = peer.Executable
+			}
+			Connections.reject(rejection)
+			return tcpConn.Close()
+		}
+	}
+	Connections.accept()
 
-	ctx, cancel := context.WithCancel(parentCtx)
+	level := params.PermissionLevel
+	if level == "" {
+		level = PermissionLevelFull
+	}
+	access := &connAccess{level: level}
+	gh := newGatedHandler(params.Handler, gatedHandlerParams{
+		secret:        params.Secret,
+		idleTimeout:   params.IdleTimeout,
+		maxLevel:      level,
+		access:        access,
+		remoteAddress: remoteAddress,
+	})
+
+	ctx, cancel := context.WithCancel(withConnAccess(parentCtx, access))
 	defer cancel()
 
 	conn := jsonrpc2.NewConn(ctx, jsonrpc2.NewRwcTransport(tcpConn), gh)
@@ -103,61 +141,132 @@ func (s *Server) handleTCPConn(parentCtx context.Context, params ServeTCPParams,
 
 //
 
+type gatedHandlerParams struct {
+	secret        string
+	idleTimeout   time.Duration
+	maxLevel      PermissionLevel
+	access        *connAccess
+	remoteAddress string
+}
+
 type gatedHandler struct {
 	authenticateChan  chan struct{}
 	authenticated     bool
 	authenticateMutex sync.Mutex
 
-	secret string
+	// guarded by authenticateMutex
+	expired    bool
+	inflight   int
+	lastActive time.Time
+
+	params gatedHandlerParams
 	inner  jsonrpc2.Handler
 }
 
 var _ jsonrpc2.Handler = (*gatedHandler)(nil)
 
-func newGatedHandler(inner jsonrpc2.Handler, secret string) jsonrpc2.Handler {
+func newGatedHandler(inner jsonrpc2.Handler, params gatedHandlerParams) jsonrpc2.Handler {
 	return &gatedHandler{
 		authenticateChan: make(chan struct{}),
 		authenticated:    false,
 
-		secret: secret,
+		params: params,
 		inner:  inner,
 	}
 }
 
 func (h *gatedHandler) HandleRequest(conn jsonrpc2.Conn, req jsonrpc2.Request) (interface{}, error) {
 	if req.Method == "Meta.Authenticate" {
-		var params MetaAuthenticateParams
+		return h.authenticate(req)
+	}
 
-		if req.Params != nil {
-			err := jsonrpc2.DecodeJSON(*req.Params, &params)
-			if err != nil {
-				return nil, err
-			}
+	<-h.authenticateChan
+	if !h.startRequest() {
+		Connections.expire()
+		return nil, &jsonrpc2.Error{
+			Code:    CodeAuthenticationExpired.RpcErrorCode(),
+			Message: CodeAuthenticationExpired.RpcErrorMessage(),
 		}
+	}
+	defer h.finishRequest()
 
-		if params.Secret != h.secret {
-			return nil, errors.Errorf("Invalid secret")
+	return h.inner.HandleRequest(conn, req)
+}
+
+func (h *gatedHandler) authenticate(req jsonrpc2.Request) (interface{}, error) {
+	var params MetaAuthenticateParams
+
+	if req.Params != nil {
+		err := jsonrpc2.DecodeJSON(*req.Params, &params)
+		if err != nil {
+			return nil, err
 		}
+	}
+	err := params.Validate()
+	if err != nil {
+		return nil, err
+	}
 
-		func() {
-			h.authenticateMutex.Lock()
-			defer h.authenticateMutex.Unlock()
+	if params.Secret != h.params.secret {
+		Connections.reject(ConnectionRejection{
+			Reason:        "invalid secret",
+			RemoteAddress: h.params.remoteAddress,
+		})
+		return nil, errors.Errorf("Invalid secret")
+	}
 
-			if !h.authenticated {
-				h.authenticated = true
-				// notify any pending requests that they are free to go
-				close(h.authenticateChan)
-			}
-		}()
+	level := h.params.maxLevel
+	if params.PermissionLevel != "" {
+		if permissionRank(params.PermissionLevel) > permissionRank(level) {
+			return nil, errors.Errorf("Permission level (%s) exceeds what the daemon allows (%s)", params.PermissionLevel, level)
+		}
+		level = params.PermissionLevel
+	}
+	h.params.access.set(level)
+
+	func() {
+		h.authenticateMutex.Lock()
+		defer h.authenticateMutex.Unlock()
 
-		result := MetaAuthenticateResult{
-			OK: true,
+		h.expired = false
+		h.lastActive = time.Now()
+		if !h.authenticated {
+			h.authenticated = true
+			// notify any pending requests that they are free to go
+			close(h.authenticateChan)
 		}
-		return result, nil
-	} else {
-		<-h.authenticateChan
-		return h.inner.HandleRequest(conn, req)
+	}()
+
+	result := MetaAuthenticateResult{
+		OK:              true,
+		PermissionLevel: level,
+	}
+	return result, nil
+}
+
+// startRequest returns false if the connection has expired, otherwise
+// it counts the request as in-flight: connections don't expire while
+// a request (like Meta.Flow) is running.
+func (h *gatedHandler) startRequest() bool {
+	h.authenticateMutex.Lock()
+	defer h.authenticateMutex.Unlock()
+
+	if h.params.idleTimeout > 0 && h.inflight == 0 && time.Since(h.lastActive) > h.params.idleTimeout {
+		h.expired = true
 	}
+	if h.expired {
+		return false
+	}
+	h.inflight++
+	return true
+}
+
+func (h *gatedHandler) finishRequest() {
+	h.authenticateMutex.Lock()
+	defer h.authenticateMutex.Unlock()
+
+	h.inflight--
+	h.lastActive = time.Now()
 }
 
 func (h *gatedHandler) HandleNotification(conn jsonrpc2.Conn, notif jsonrpc2.Notification) {
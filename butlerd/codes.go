@@ -12,6 +12,24 @@ var codeMessages = map[Code]string{
 
 	CodeNoCompatibleUploads: "No compatible uploads were found.",
 
+	CodeUnsafeFilenames: "Some files of this build can't be created on this platform.",
+
+	CodeUploadSuccessorNotConfirmed: "This update switches to a different upload, and must be confirmed first.",
+
+	CodeGameNotYetReleased: "This game hasn't been released yet.",
+
+	CodeNotEnoughSpace: "There isn't enough free disk space to install this.",
+
+	CodeBuildNotFound: "The requested build doesn't exist, or doesn't belong to that upload.",
+
+	CodeInstallFolderExhausted: "Could not find a free install folder name, too many copies are installed already.",
+
+	CodeStagingFolderMismatch: "That staging folder was used for something else, it can't be resumed.",
+
+	CodeAmbiguousUpload: "Several compatible uploads were found, and none was picked.",
+
+	CodeInstallFolderMissing: "The install folder could not be found on disk.",
+
 	CodeUnsupportedHost: "This title is hosted on an incompatible third-party website",
 
 	CodeNoLaunchCandidates: "Nothing that can be launched was found.",
@@ -25,6 +43,14 @@ var codeMessages = map[Code]string{
 	CodeDatabaseBusy: "The database is busy",
 
 	CodeCantRemoveLocationBecauseOfActiveDownloads: "An install location could not be removed because it has active downloads",
+
+	CodeInstallLocationNotEmpty: "An install location could not be removed because games are still installed in it",
+
+	CodePermissionDenied: "This connection isn't allowed to do that",
+
+	CodeAuthenticationExpired: "This connection was idle for too long and must authenticate again",
+
+	CodeInvalidFilter: "The filter expression is invalid",
 }
 
 func (code Code) RpcErrorMessage() string {
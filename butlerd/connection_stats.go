@@ -0,0 +1,78 @@
+package butlerd
+
+import (
+	"sync"
+	"time"
+
+	"github.com/itchio/butler/comm"
+)
+
+// maxRecentRejections is how many rejections System.Stats lists
+const maxRecentRejections = 20
+
+// ConnectionLog counts what happened to the daemon's connections
+type ConnectionLog struct {
+	mu               sync.Mutex
+	accepted         int64
+	rejected         int64
+	expired          int64
+	denied           int64
+	recentRejections []*ConnectionRejection
+}
+
+// Connections holds the stats of every connection since the daemon started
+var Connections = &ConnectionLog{}
+
+func (cl *ConnectionLog) accept() {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.accepted++
+}
+
+// reject logs rejection and remembers it for System.Stats
+func (cl *ConnectionLog) reject(rejection ConnectionRejection) {
+	if rejection.Time.IsZero() {
+		rejection.Time = time.Now().UTC()
+	}
+	comm.Warnf("butlerd: rejected connection from %s (pid %d, %q): %s",
+		rejection.RemoteAddress, rejection.PID, rejection.Executable, rejection.Reason)
+
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.rejected++
+	cl.recentRejections = append(cl.recentRejections, &rejection)
+	if len(cl.recentRejections) > maxRecentRejections {
+		cl.recentRejections = cl.recentRejections[1:]
+	}
+}
+
+func (cl *ConnectionLog) expire() {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.expired++
+}
+
+func (cl *ConnectionLog) deny() {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.denied++
+}
+
+// Stats returns a snapshot of the counters
+func (cl *ConnectionLog) Stats() *ConnectionStats {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	res := &ConnectionStats{
+		Accepted:         cl.accepted,
+		Rejected:         cl.rejected,
+		Expired:          cl.expired,
+		Denied:           cl.denied,
+		RecentRejections: []*ConnectionRejection{},
+	}
+	for _, r := range cl.recentRejections {
+		rCopy := *r
+		res.RecentRejections = append(res.RecentRejections, &rCopy)
+	}
+	return res
+}
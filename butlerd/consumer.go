@@ -15,6 +15,10 @@ type NewStateConsumerParams struct {
 
 	// Optional
 	LogFile *os.File
+
+	// Optional, recorded along with messages in RecentLogs
+	Module string
+	Fields map[string]interface{}
 }
 
 func NewStateConsumer(params *NewStateConsumerParams) (*state.Consumer, error) {
@@ -24,6 +28,12 @@ func NewStateConsumer(params *NewStateConsumerParams) (*state.Consumer, error) {
 
 	c := &state.Consumer{
 		OnMessage: func(level, msg string) {
+			RecentLogs.AddEntry(RecentLog{
+				Level:   level,
+				Message: msg,
+				Module:  params.Module,
+				Fields:  params.Fields,
+			})
 			err := params.Conn.Notify("Log", LogNotification{
 				Level:   LogLevel(level),
 				Message: msg,
@@ -2,7 +2,10 @@ package main
 
 import (
 	"fmt"
+	"go/format"
 	"strings"
+
+	"github.com/pkg/errors"
 )
 
 func (bc *generousContext) generateGoCode() error {
@@ -33,6 +36,7 @@ func (bc *generousContext) generateGoCode() error {
 	must(scope.assimilate("github.com/itchio/butler/butlerd", "types.go"))
 
 	var clientRequests []string
+	var readOnlyRequests []string
 
 	for _, category := range scope.categoryList {
 		cat := scope.categories[category]
@@ -54,6 +58,9 @@ func (bc *generousContext) generateGoCode() error {
 				if entry.caller == callerClient {
 					clientRequests = append(clientRequests, method)
 				}
+				if entry.readOnly {
+					readOnlyRequests = append(readOnlyRequests, method)
+				}
 
 				doc.line("// %s (Request)", method)
 				doc.line("")
@@ -72,7 +79,11 @@ func (bc *generousContext) generateGoCode() error {
 					}
 					doc.line("")
 					doc.line("func (r *%s) %sRegister(router router, f func(*butlerd.RequestContext, %s) (*%s, error)) {", typeName, helperPrefix, paramsTypeName, resultTypeName)
-					doc.line("  router.Register(%#v, func (rc *butlerd.RequestContext) (interface{}, error) {", method)
+					if entry.tx {
+						doc.line("  router.Register(%#v, butlerd.TransactionMiddleware(func (rc *butlerd.RequestContext) (interface{}, error) {", method)
+					} else {
+						doc.line("  router.Register(%#v, func (rc *butlerd.RequestContext) (interface{}, error) {", method)
+					}
 					doc.line("    var params %s", paramsTypeName)
 					doc.line("    err := json.Unmarshal(*rc.Params, &params)")
 					doc.line("    if err != nil {")
@@ -90,7 +101,11 @@ func (bc *generousContext) generateGoCode() error {
 					doc.line("    	return nil, errors.New(%#v)", fmt.Sprintf("internal error: nil result for %s", method))
 					doc.line("    }")
 					doc.line("    return res, nil")
-					doc.line("  })")
+					if entry.tx {
+						doc.line("  }))")
+					} else {
+						doc.line("  })")
+					}
 					doc.line("}")
 				}
 
@@ -162,5 +177,34 @@ func (bc *generousContext) generateGoCode() error {
 	doc.commit("")
 	doc.write()
 
+	return bc.generateReadOnlyMethods(readOnlyRequests)
+}
+
+// generateReadOnlyMethods lists the requests tagged `@readonly`, which
+// read-only connections are allowed to call.
+func (bc *generousContext) generateReadOnlyMethods(methods []string) error {
+	doc := bc.newGenerousRelativeDoc("../readonly_methods.go")
+
+	doc.line("// Code generated by generous; DO NOT EDIT.")
+	doc.line("")
+	doc.line("package butlerd")
+	doc.line("")
+	doc.line("// readOnlyMethods don't change anything: the database cache they may")
+	doc.line("// fill is the same one any fetch would.")
+	doc.line("var readOnlyMethods = map[string]bool{")
+	for _, method := range methods {
+		doc.line("%#v: true,", method)
+	}
+	doc.line("}")
+	doc.line("")
+
+	doc.commit("")
+	src, err := format.Source([]byte(doc.doc))
+	if err != nil {
+		return errors.WithMessage(err, "formatting read-only methods")
+	}
+	doc.doc = string(src)
+	doc.write()
+
 	return nil
 }
@@ -45,6 +45,8 @@ type entryInfo struct {
 	name         string
 	typeName     string
 	caller       callerInfo
+	tx           bool
+	readOnly     bool
 	enumValues   []*enumValue
 	structFields []*structField
 }
@@ -151,6 +153,8 @@ func (s *scope) assimilate(pkg string, file string) error {
 						var customName string
 						var doc []string
 						var caller = callerUnknown
+						var tx bool
+						var readOnly bool
 
 						lines := getCommentLines(gd.Doc)
 						if len(lines) > 0 {
@@ -170,6 +174,10 @@ func (s *scope) assimilate(pkg string, file string) error {
 									category = value
 								case "tags":
 									tags = strings.Split(value, ", ")
+								case "transactional":
+									tx = true
+								case "readonly":
+									readOnly = true
 								case "caller":
 									switch value {
 									case "server":
@@ -224,6 +232,8 @@ func (s *scope) assimilate(pkg string, file string) error {
 							category: category,
 							doc:      doc,
 							caller:   caller,
+							tx:       tx,
+							readOnly: readOnly,
 						}
 
 						if typeKind == entryTypeKindStruct {
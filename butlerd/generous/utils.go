@@ -51,7 +51,8 @@ func isArrayAlias(ts *ast.TypeSpec) bool {
 }
 
 func parseTag(line string) (tag string, value string) {
-	if strings.HasPrefix(line, "@") {
+	if strings.HasPrefix(line, "@") && !strings.HasPrefix(line, "@@") {
+		tag = line[1:]
 		for i := 1; i < len(line); i++ {
 			if line[i] == ' ' {
 				tag = line[1:i]
@@ -92,6 +93,8 @@ func typeToString(e ast.Expr) string {
 		}
 	case *ast.ArrayType:
 		return typeToString(node.Elt) + "[]"
+	case *ast.InterfaceType:
+		return "any"
 	case *ast.MapType:
 		return "{ [key: " + typeToString(node.Key) + "]: " + typeToString(node.Value) + " }"
 	default:
@@ -0,0 +1,140 @@
+package integrate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CaveUninstallBatch(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Spring Cleaner")
+	first := installOldestBuild(bi, _developer, "Played Once", 1)
+	second := installOldestBuild(bi, _developer, "Never Played", 1)
+	kept := installOldestBuild(bi, _developer, "Moved By Hand", 1)
+
+	installFolder := func(caveID string) string {
+		caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+			CaveID: caveID,
+		})
+		must(err)
+		return caveRes.Cave.InstallInfo.InstallFolder
+	}
+	firstFolder := installFolder(first)
+	secondFolder := installFolder(second)
+	keptFolder := installFolder(kept)
+
+	_, err := messages.CaveUninstallBatch.TestCall(rc, butlerd.CaveUninstallBatchParams{
+		CaveIDs: []string{first, "not-a-cave"},
+		DryRun:  true,
+	})
+	assert.Error(err, "unknown caves are rejected")
+
+	dryRes, err := messages.CaveUninstallBatch.TestCall(rc, butlerd.CaveUninstallBatchParams{
+		CaveIDs:     []string{first, second},
+		DeleteFiles: true,
+		DryRun:      true,
+	})
+	must(err)
+	assert.Empty(dryRes.BatchID)
+	assert.True(dryRes.TotalSizeToFree > 0)
+	if assert.Len(dryRes.CavesToDelete, 2) {
+		assert.EqualValues(first, dryRes.CavesToDelete[0].ID)
+		assert.EqualValues(second, dryRes.CavesToDelete[1].ID)
+	}
+	assert.DirExists(firstFolder, "dry runs don't remove anything")
+
+	progress := make(chan butlerd.CaveUninstallProgressNotification, 16)
+	messages.CaveUninstallProgress.Register(h, func(params butlerd.CaveUninstallProgressNotification) {
+		progress <- params
+	})
+
+	waitBatch := func(batchID string, numCaves int) *butlerd.CaveBatchStatusResult {
+		uninstalled := 0
+		for uninstalled < numCaves {
+			select {
+			case p := <-progress:
+				if p.BatchID != batchID {
+					// notifications may arrive out of order
+					continue
+				}
+				switch p.Cave.State {
+				case butlerd.CaveBatchItemStateUninstalled:
+					uninstalled++
+				case butlerd.CaveBatchItemStateFailed:
+					t.Fatalf("cave %s failed: %s", p.Cave.CaveID, p.Cave.Error)
+				}
+			case <-time.After(30 * time.Second):
+				t.Fatal("batch didn't complete in time")
+			}
+		}
+
+		// done is set right after the last notification
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			statusRes, err := messages.CaveBatchStatus.TestCall(rc, butlerd.CaveBatchStatusParams{
+				BatchID: batchID,
+			})
+			must(err)
+			if statusRes.Done || time.Now().After(deadline) {
+				return statusRes
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+
+	batchRes, err := messages.CaveUninstallBatch.TestCall(rc, butlerd.CaveUninstallBatchParams{
+		CaveIDs:     []string{first, second, first},
+		DeleteFiles: true,
+	})
+	must(err)
+	statusRes := waitBatch(batchRes.BatchID, 2)
+	assert.True(statusRes.Done)
+	assert.EqualValues(1, statusRes.Progress)
+	assert.Len(statusRes.Caves, 2, "duplicates are ignored")
+	assert.NoDirExists(firstFolder)
+	assert.NoDirExists(secondFolder)
+
+	// pretend the game is running
+	runlockPath := filepath.Join(keptFolder, ".itch", "runlock.json")
+	must(os.MkdirAll(filepath.Dir(runlockPath), 0o755))
+	must(ioutil.WriteFile(runlockPath, []byte(fmt.Sprintf(`{"task":"launch","butlerPID":%d}`, os.Getpid())), 0o644))
+
+	batchRes, err = messages.CaveUninstallBatch.TestCall(rc, butlerd.CaveUninstallBatchParams{
+		CaveIDs: []string{kept},
+	})
+	must(err)
+	time.Sleep(1500 * time.Millisecond)
+	statusRes, err = messages.CaveBatchStatus.TestCall(rc, butlerd.CaveBatchStatusParams{
+		BatchID: batchRes.BatchID,
+	})
+	must(err)
+	assert.False(statusRes.Done, "waits for the game to exit, even if files are left alone")
+	must(os.Remove(runlockPath))
+
+	statusRes = waitBatch(batchRes.BatchID, 1)
+	assert.True(statusRes.Done)
+	assert.DirExists(keptFolder, "files are left alone unless deleteFiles is set")
+
+	for _, caveID := range []string{first, second, kept} {
+		caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+			CaveID: caveID,
+		})
+		must(err)
+		assert.Nil(caveRes.Cave, "cave %s is gone", caveID)
+	}
+}
@@ -0,0 +1,148 @@
+package integrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CaveVerify(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Integrity Checker")
+
+	_game := _developer.MakeGame("Wharf Game")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.ChannelName = "default"
+	_upload.PushBuild(func(ac *mitch.ArchiveContext) {
+		ac.SetName("default.zip")
+		ac.Entry("game.exe").String("not really a game")
+		ac.Entry("data/one.dat").String("first level")
+		ac.Entry("data/two.dat").String("second level")
+	})
+
+	_zipGame := _developer.MakeGame("Zip Game")
+	_zipGame.Publish()
+	_zipUpload := _zipGame.MakeUpload("All platforms")
+	_zipUpload.SetAllPlatforms()
+	_zipUpload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+		ac.Entry("game.exe").String("not really a game either")
+		ac.Entry("readme.txt").String("have fun")
+	})
+
+	_nakedGame := _developer.MakeGame("Naked Game")
+	_nakedGame.Publish()
+	_nakedUpload := _nakedGame.MakeUpload("All platforms")
+	_nakedUpload.SetAllPlatforms()
+	_nakedUpload.SetHostedContents("game.exe", []byte("a lonely executable"))
+
+	installFolderOf := func(caveID string) string {
+		caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+			CaveID: caveID,
+		})
+		must(err)
+		return caveRes.Cave.InstallInfo.InstallFolder
+	}
+	writeFile := func(folder string, name string, contents string) {
+		p := filepath.Join(folder, filepath.FromSlash(name))
+		must(os.MkdirAll(filepath.Dir(p), 0o755))
+		must(ioutil.WriteFile(p, []byte(contents), 0o644))
+	}
+
+	var progressMutex sync.Mutex
+	var progressPaths map[string]bool
+	messages.CaveVerifyProgress.Register(h, func(params butlerd.CaveVerifyProgressNotification) {
+		progressMutex.Lock()
+		defer progressMutex.Unlock()
+		progressPaths[params.Path] = true
+	})
+	// notifications are handled concurrently, and can
+	// arrive after the call returns.
+	assertProgressed := func(paths ...string) {
+		assert.Eventually(func() bool {
+			progressMutex.Lock()
+			defer progressMutex.Unlock()
+			for _, p := range paths {
+				if !progressPaths[p] {
+					return false
+				}
+			}
+			return true
+		}, 2*time.Second, 10*time.Millisecond, "progress was notified for %v", paths)
+	}
+
+	verify := func(caveID string) *butlerd.CaveVerifyResult {
+		progressMutex.Lock()
+		progressPaths = make(map[string]bool)
+		progressMutex.Unlock()
+		res, err := messages.CaveVerify.TestCall(rc, butlerd.CaveVerifyParams{
+			CaveID: caveID,
+		})
+		must(err)
+		return res
+	}
+
+	caveID := bi.Install(butlerd.InstallQueueParams{
+		Game: bi.FetchGame(_game.ID),
+	}).CaveID
+	res := verify(caveID)
+	assert.EqualValues(butlerd.CaveVerifyMethodSignature, res.Method)
+	assert.Empty(res.Missing)
+	assert.Empty(res.Added)
+	assert.Empty(res.Corrupted)
+	assertProgressed("game.exe", "data/one.dat", "data/two.dat")
+
+	installFolder := installFolderOf(caveID)
+	writeFile(installFolder, "data/one.dat", "modded level")
+	must(os.Remove(filepath.Join(installFolder, "data", "two.dat")))
+	writeFile(installFolder, "notes.txt", "my notes")
+	res = verify(caveID)
+	assert.EqualValues([]string{"data/two.dat"}, res.Missing)
+	assert.EqualValues([]string{"notes.txt"}, res.Added)
+	assert.EqualValues([]string{"data/one.dat"}, res.Corrupted, "contents are checked, not just sizes")
+
+	zipCaveID := bi.Install(butlerd.InstallQueueParams{
+		Game: bi.FetchGame(_zipGame.ID),
+	}).CaveID
+	zipFolder := installFolderOf(zipCaveID)
+	writeFile(zipFolder, "game.exe", "truncated")
+	must(os.Remove(filepath.Join(zipFolder, "readme.txt")))
+	writeFile(zipFolder, "saves/slot1.sav", "level 99")
+	res = verify(zipCaveID)
+	assert.EqualValues(butlerd.CaveVerifyMethodArchive, res.Method)
+	assert.EqualValues([]string{"readme.txt"}, res.Missing)
+	assert.EqualValues([]string{"saves/slot1.sav"}, res.Added)
+	assert.EqualValues([]string{"game.exe"}, res.Corrupted)
+	assertProgressed("game.exe", "readme.txt")
+
+	nakedCaveID := bi.Install(butlerd.InstallQueueParams{
+		Game: bi.FetchGame(_nakedGame.ID),
+	}).CaveID
+	nakedFolder := installFolderOf(nakedCaveID)
+	writeFile(nakedFolder, "game.exe", "sizes can't be checked")
+	res = verify(nakedCaveID)
+	assert.EqualValues(butlerd.CaveVerifyMethodReceipt, res.Method)
+	assert.Empty(res.Missing)
+	assert.Empty(res.Corrupted)
+
+	must(os.Remove(filepath.Join(nakedFolder, "game.exe")))
+	res = verify(nakedCaveID)
+	assert.EqualValues([]string{"game.exe"}, res.Missing)
+}
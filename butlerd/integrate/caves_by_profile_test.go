@@ -0,0 +1,55 @@
+package integrate
+
+import (
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CavesByProfile(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	profile := bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Profile Keeper")
+	_game := _developer.MakeGame("Whose Game Is It Anyway")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContents()
+
+	_, err := messages.CavesByProfile.TestCall(rc, butlerd.CavesByProfileParams{})
+	assert.Error(err, "profile ID is required")
+
+	res, err := messages.CavesByProfile.TestCall(rc, butlerd.CavesByProfileParams{
+		ProfileID: profile.ID,
+	})
+	must(err)
+	assert.Empty(res.Caves, "nothing installed yet")
+
+	queueRes := bi.Install(butlerd.InstallQueueParams{
+		Game:              bi.FetchGame(_game.ID),
+		InstallLocationID: "tmp",
+	})
+
+	res, err = messages.CavesByProfile.TestCall(rc, butlerd.CavesByProfileParams{
+		ProfileID: profile.ID,
+	})
+	must(err)
+	if assert.Len(res.Caves, 1) {
+		assert.EqualValues(queueRes.CaveID, res.Caves[0].ID)
+		assert.EqualValues(_game.ID, res.Caves[0].Game.ID)
+	}
+
+	res, err = messages.CavesByProfile.TestCall(rc, butlerd.CavesByProfileParams{
+		ProfileID: profile.ID + 1,
+	})
+	must(err)
+	assert.Empty(res.Caves, "caves of other profiles aren't listed")
+}
@@ -0,0 +1,106 @@
+package integrate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CavesDowngrade(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Hotfix Enjoyer")
+	_game := _developer.MakeGame("Broken Update")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.ChannelName = "default"
+	for i := 1; i <= 3; i++ {
+		version := fmt.Sprintf("v%d", i)
+		_upload.PushBuild(func(ac *mitch.ArchiveContext) {
+			ac.SetName("default.zip")
+			ac.Entry("version.txt").String(version)
+			if i == 3 {
+				ac.Entry(".itch.toml").String(`minimum-save-version = "3.0"`)
+			}
+		})
+	}
+
+	game := bi.FetchGame(_game.ID)
+	installRes := bi.Install(butlerd.InstallQueueParams{
+		Game: game,
+	})
+	caveID := installRes.CaveID
+
+	historyRes, err := messages.CavesListBuildHistory.TestCall(rc, butlerd.CavesListBuildHistoryParams{
+		CaveID: caveID,
+	})
+	must(err)
+	if !assert.Len(historyRes.Builds, 3) {
+		return
+	}
+	newest := historyRes.Builds[0]
+	oldest := historyRes.Builds[2]
+	assert.True(newest.Current)
+	assert.False(newest.DowngradeEligible)
+	assert.False(oldest.Current)
+	assert.True(oldest.DowngradeEligible)
+	assert.True(oldest.MayBreakSaves, "older builds have no user version")
+
+	_, err = messages.CavesDowngrade.TestCall(rc, butlerd.CavesDowngradeParams{
+		CaveID:  caveID,
+		BuildID: newest.Build.ID,
+	})
+	assert.Error(err, "can only downgrade to older builds")
+
+	downgradeRes, err := messages.CavesDowngrade.TestCall(rc, butlerd.CavesDowngradeParams{
+		CaveID:  caveID,
+		BuildID: oldest.Build.ID,
+	})
+	must(err)
+	assert.NotEmpty(downgradeRes.SaveWarning)
+
+	downloadsRes, err := messages.DownloadsList.TestCall(rc, butlerd.DownloadsListParams{})
+	must(err)
+	var download *butlerd.Download
+	for _, d := range downloadsRes.Downloads {
+		if d.ID == downgradeRes.DownloadID {
+			download = d
+		}
+	}
+	if !assert.NotNil(download) {
+		return
+	}
+	assert.EqualValues(butlerd.DownloadReasonDowngrade, download.Reason)
+	assert.EqualValues(oldest.Build.ID, download.Build.ID)
+
+	_, err = messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+		ID:            download.ID,
+		StagingFolder: download.StagingFolder,
+	})
+	must(err)
+
+	caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+		CaveID: caveID,
+	})
+	must(err)
+	assert.EqualValues(oldest.Build.ID, caveRes.Cave.Build.ID)
+	assert.True(caveRes.Cave.InstallInfo.Pinned, "downgraded caves aren't upgraded right away")
+
+	contents, err := ioutil.ReadFile(filepath.Join(caveRes.Cave.InstallInfo.InstallFolder, "version.txt"))
+	must(err)
+	assert.EqualValues("v1", string(contents))
+}
@@ -0,0 +1,129 @@
+package integrate
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CavesFiles(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Modder Friendly")
+	_game := _developer.MakeGame("Moddable Game")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+		ac.Entry("game.exe").String("not really a game")
+		ac.Entry("data/levels/one.json").String(`{"name":"one"}`)
+		ac.Entry("data/levels/two.json").String(`{"name":"two"}`)
+		ac.Entry("data/main.pak").String("0123456789")
+	})
+
+	game := bi.FetchGame(_game.ID)
+	caveID := bi.Install(butlerd.InstallQueueParams{
+		Game: game,
+	}).CaveID
+
+	listFiles := func(glob string) []string {
+		res, err := messages.CavesListFiles.TestCall(rc, butlerd.CavesListFilesParams{
+			CaveID: caveID,
+			Glob:   glob,
+		})
+		must(err)
+		var paths []string
+		for _, f := range res.Files {
+			paths = append(paths, f.Path)
+		}
+		// receipts list files in extraction order
+		sort.Strings(paths)
+		return paths
+	}
+
+	assert.Len(listFiles(""), 4)
+	assert.EqualValues([]string{"data/main.pak"}, listFiles("data/*.pak"))
+	assert.EqualValues([]string{"data/levels/one.json", "data/levels/two.json"}, listFiles("**/*.json"))
+	assert.EqualValues([]string{"game.exe"}, listFiles("*"))
+
+	_, err := messages.CavesListFiles.TestCall(rc, butlerd.CavesListFilesParams{
+		CaveID: caveID,
+		Glob:   "[",
+	})
+	assert.Error(err, "malformed globs are rejected")
+
+	readFile := func(relativePath string, offset int64, length int64) (*butlerd.CavesReadFileResult, error) {
+		return messages.CavesReadFile.TestCall(rc, butlerd.CavesReadFileParams{
+			CaveID:       caveID,
+			RelativePath: relativePath,
+			Offset:       offset,
+			Length:       length,
+		})
+	}
+
+	res, err := readFile("data/main.pak", 2, 4)
+	must(err)
+	data, err := base64.StdEncoding.DecodeString(res.Data)
+	must(err)
+	assert.EqualValues("2345", string(data))
+	assert.EqualValues(10, res.Size)
+	assert.False(res.EOF)
+
+	res, err = readFile("data/main.pak", 8, 100)
+	must(err)
+	data, err = base64.StdEncoding.DecodeString(res.Data)
+	must(err)
+	assert.EqualValues("89", string(data), "short reads at the end of files")
+	assert.True(res.EOF)
+
+	_, err = readFile("data/main.pak", 0, butlerd.CavesReadFileMaxLength+1)
+	assert.Error(err, "reads are limited in size")
+
+	caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+		CaveID: caveID,
+	})
+	must(err)
+	installFolder := caveRes.Cave.InstallInfo.InstallFolder
+	outside := filepath.Join(filepath.Dir(installFolder), "secret.txt")
+	must(ioutil.WriteFile(outside, []byte("hunter2"), 0o644))
+	defer os.Remove(outside)
+
+	for _, attack := range []string{
+		"../secret.txt",
+		"data/../../secret.txt",
+		"data/levels/../../../secret.txt",
+		outside,
+		"/etc/passwd",
+		`..\secret.txt`,
+		"secret.txt",
+		".itch/receipt.json.gz",
+	} {
+		_, err = readFile(attack, 0, 16)
+		assert.Error(err, "refuses to read (%s)", attack)
+	}
+
+	if runtime.GOOS != "windows" {
+		// a file from the receipt was swapped with a symlink
+		levelPath := filepath.Join(installFolder, "data", "levels", "one.json")
+		must(os.Remove(levelPath))
+		must(os.Symlink(outside, levelPath))
+		_, err = readFile("data/levels/one.json", 0, 16)
+		assert.Error(err, "refuses to follow symlinks out of the install folder")
+	}
+}
@@ -0,0 +1,106 @@
+package integrate
+
+import (
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CavesLaunchTargets(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Toolsmith")
+	_game := _developer.MakeGame("Level Editor Simulator")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+		ac.Entry(".itch.toml").String(`
+[[actions]]
+name = "play"
+path = "https://example.org/play"
+
+[[actions]]
+name = "editor"
+path = "https://example.org/editor"
+args = ["--fullscreen"]
+`)
+	})
+
+	queueRes := bi.Install(butlerd.InstallQueueParams{
+		Game:              bi.FetchGame(_game.ID),
+		InstallLocationID: "tmp",
+	})
+	caveID := queueRes.CaveID
+
+	targetsRes, err := messages.CavesGetLaunchTargets.TestCall(rc, butlerd.CavesGetLaunchTargetsParams{
+		CaveID: caveID,
+	})
+	must(err)
+	assert.Empty(targetsRes.PreferredLaunchTarget)
+	if assert.Len(targetsRes.Targets, 2) {
+		assert.EqualValues("play", targetsRes.Targets[0].Name)
+		assert.EqualValues("editor", targetsRes.Targets[1].Name)
+		assert.EqualValues("https://example.org/editor", targetsRes.Targets[1].Path)
+		assert.EqualValues([]string{"--fullscreen"}, targetsRes.Targets[1].Args)
+	}
+
+	_, err = messages.CavesSetLaunchTarget.TestCall(rc, butlerd.CavesSetLaunchTargetParams{
+		CaveID:     caveID,
+		TargetName: "server",
+	})
+	assert.Error(err, "target must be in the manifest")
+
+	_, err = messages.CavesSetLaunchTarget.TestCall(rc, butlerd.CavesSetLaunchTargetParams{
+		CaveID:     caveID,
+		TargetName: "editor",
+	})
+	must(err)
+
+	caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+		CaveID: caveID,
+	})
+	must(err)
+	assert.EqualValues("editor", caveRes.Cave.InstallInfo.PreferredLaunchTarget)
+
+	var launchedURL string
+	messages.URLLaunch.TestRegister(h, func(rc *butlerd.RequestContext, params butlerd.URLLaunchParams) (*butlerd.URLLaunchResult, error) {
+		launchedURL = params.URL
+		return &butlerd.URLLaunchResult{}, nil
+	})
+	picked := false
+	messages.PickManifestAction.TestRegister(h, func(rc *butlerd.RequestContext, params butlerd.PickManifestActionParams) (*butlerd.PickManifestActionResult, error) {
+		picked = true
+		return &butlerd.PickManifestActionResult{Index: 0}, nil
+	})
+
+	launch := func() {
+		_, err := messages.Launch.TestCall(rc, butlerd.LaunchParams{
+			CaveID:     caveID,
+			PrereqsDir: "./tmp/prereqs",
+		})
+		must(err)
+	}
+
+	launch()
+	assert.False(picked, "preferred target is used without asking")
+	assert.EqualValues("https://example.org/editor", launchedURL)
+
+	_, err = messages.CavesSetLaunchTarget.TestCall(rc, butlerd.CavesSetLaunchTargetParams{
+		CaveID: caveID,
+	})
+	must(err)
+
+	launch()
+	assert.True(picked, "asks again once cleared")
+	assert.EqualValues("https://example.org/play", launchedURL)
+}
@@ -0,0 +1,183 @@
+package integrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/jsonrpc2"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CavesRelink(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Folder Shuffler")
+	_game := _developer.MakeGame("Goes Places")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+		ac.Entry(".itch.toml").String(`
+[[actions]]
+name = "play"
+path = "https://example.org/play"
+`)
+		ac.Entry("data/level.json").String(`{"name":"one"}`)
+	})
+
+	game := bi.FetchGame(_game.ID)
+	caveID := bi.Install(butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+	}).CaveID
+
+	installFolder := func() string {
+		caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+			CaveID: caveID,
+		})
+		must(err)
+		return caveRes.Cave.InstallInfo.InstallFolder
+	}
+
+	// what the user would do in their file manager
+	move := func(to string) {
+		must(os.Rename(installFolder(), to))
+	}
+
+	prereqs := func() error {
+		_, err := messages.InstallPrereqs.TestCall(rc, butlerd.InstallPrereqsParams{
+			CaveID:     caveID,
+			PrereqsDir: "./tmp/prereqs",
+		})
+		return err
+	}
+
+	candidatesOf := func(err error) []*butlerd.MovedCaveCandidate {
+		t.Helper()
+		var data struct {
+			Candidates []*butlerd.MovedCaveCandidate `json:"candidates"`
+		}
+		if assert.Error(err) {
+			je, ok := err.(*jsonrpc2.Error)
+			if assert.True(ok) {
+				assert.EqualValues(butlerd.CodeInstallFolderDisappeared, je.Code)
+				must(je.GetData(&data))
+			}
+		}
+		return data.Candidates
+	}
+
+	originalFolder := installFolder()
+	movedFolder := filepath.Join(filepath.Dir(originalFolder), "moved-by-hand")
+	move(movedFolder)
+
+	candidates := candidatesOf(prereqs())
+	if assert.Len(candidates, 1) {
+		assert.EqualValues(movedFolder, candidates[0].InstallFolder)
+		assert.EqualValues("tmp", candidates[0].InstallLocationID)
+		assert.True(candidates[0].Exact)
+	}
+
+	findRes, err := messages.CavesFindMoved.TestCall(rc, butlerd.CavesFindMovedParams{
+		CaveID: caveID,
+	})
+	must(err)
+	assert.EqualValues(candidates, findRes.Candidates)
+
+	{
+		// another copy of the same build: only a user can tell which one is right
+		copyFolder := filepath.Join(filepath.Dir(originalFolder), "copied-by-hand")
+		must(os.MkdirAll(filepath.Join(copyFolder, ".itch"), 0o755))
+		receipt, err := ioutil.ReadFile(filepath.Join(movedFolder, ".itch", "receipt.json.gz"))
+		must(err)
+		must(ioutil.WriteFile(filepath.Join(copyFolder, ".itch", "receipt.json.gz"), receipt, 0o644))
+
+		findRes, err = messages.CavesFindMoved.TestCall(rc, butlerd.CavesFindMovedParams{
+			CaveID: caveID,
+		})
+		must(err)
+		assert.Len(findRes.Candidates, 2)
+
+		_, err = messages.CavesRelink.TestCall(rc, butlerd.CavesRelinkParams{
+			CaveID:        caveID,
+			InstallFolder: copyFolder,
+		})
+		assert.Error(err, "files listed in the receipt are missing")
+
+		_, err = messages.SettingsSet.TestCall(rc, butlerd.SettingsSetParams{
+			Key:   "caves.autoRelink",
+			Value: true,
+		})
+		must(err)
+		assert.Len(candidatesOf(prereqs()), 2, "ambiguous matches aren't relinked automatically")
+
+		must(os.RemoveAll(copyFolder))
+	}
+
+	_, err = messages.CavesRelink.TestCall(rc, butlerd.CavesRelinkParams{
+		CaveID:        caveID,
+		InstallFolder: movedFolder,
+	})
+	must(err)
+	assert.EqualValues(movedFolder, installFolder())
+	must(prereqs())
+
+	// outside of any install location
+	otherDisk, err := ioutil.TempDir("", "relink-other-disk")
+	must(err)
+	defer os.RemoveAll(otherDisk)
+	outsideFolder := filepath.Join(otherDisk, "goes-places")
+	move(outsideFolder)
+
+	candidates = candidatesOf(prereqs())
+	assert.Empty(candidates, "only install locations are searched at launch")
+
+	findRes, err = messages.CavesFindMoved.TestCall(rc, butlerd.CavesFindMovedParams{
+		CaveID:     caveID,
+		ExtraRoots: []string{otherDisk},
+	})
+	must(err)
+	if assert.Len(findRes.Candidates, 1) {
+		assert.EqualValues(outsideFolder, findRes.Candidates[0].InstallFolder)
+		assert.Empty(findRes.Candidates[0].InstallLocationID)
+	}
+
+	_, err = messages.CavesRelink.TestCall(rc, butlerd.CavesRelinkParams{
+		CaveID:        caveID,
+		InstallFolder: outsideFolder,
+	})
+	must(err)
+	assert.EqualValues(outsideFolder, installFolder())
+
+	// back in the install location: relinked automatically, since
+	// it's the only candidate, and an exact one
+	move(movedFolder)
+	must(prereqs())
+	assert.EqualValues(movedFolder, installFolder())
+
+	eventsRes, err := messages.CavesListEvents.TestCall(rc, butlerd.CavesListEventsParams{
+		CaveID: caveID,
+	})
+	must(err)
+	if assert.Len(eventsRes.Events, 3) {
+		for _, event := range eventsRes.Events {
+			assert.EqualValues(butlerd.CaveEventTypeRelinked, event.Type)
+		}
+		// most recent first
+		assert.EqualValues(outsideFolder, eventsRes.Events[0].FromInstallFolder)
+		assert.EqualValues(movedFolder, eventsRes.Events[0].ToInstallFolder)
+		assert.EqualValues(originalFolder, eventsRes.Events[2].FromInstallFolder)
+		assert.EqualValues(movedFolder, eventsRes.Events[2].ToInstallFolder)
+	}
+}
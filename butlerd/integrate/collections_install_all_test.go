@@ -0,0 +1,77 @@
+package integrate
+
+import (
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CollectionsInstallAll(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_, err := messages.CollectionsInstallAll.TestCall(rc, butlerd.CollectionsInstallAllParams{
+		CollectionID:      1234,
+		InstallLocationID: "tmp",
+	})
+	assert.Error(err, "collection must be fetched first")
+
+	// mitch has no collections, so fill a batch by hand
+	_developer := bi.Server.Store().MakeUser("Jam Host")
+	queue := func(title string, batchID string) *butlerd.InstallQueueResult {
+		_game := _developer.MakeGame(title)
+		_game.Publish()
+		_upload := _game.MakeUpload("All platforms")
+		_upload.SetAllPlatforms()
+		_upload.SetZipContents()
+
+		queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+			Game:              bi.FetchGame(_game.ID),
+			InstallLocationID: "tmp",
+		})
+		must(err)
+		_, err = messages.DownloadsQueue.TestCall(rc, butlerd.DownloadsQueueParams{
+			Item:    queueRes,
+			BatchID: batchID,
+		})
+		must(err)
+		return queueRes
+	}
+	first := queue("Entry One", "jam")
+	queue("Entry Two", "jam")
+	queue("Not An Entry", "")
+
+	listRes, err := messages.DownloadsList.TestCall(rc, butlerd.DownloadsListParams{
+		BatchID: "jam",
+	})
+	must(err)
+	if assert.Len(listRes.Downloads, 2) {
+		assert.EqualValues(first.ID, listRes.Downloads[0].ID)
+		assert.EqualValues("jam", listRes.Downloads[0].BatchID)
+	}
+
+	cancelRes, err := messages.CollectionsInstallAllCancel.TestCall(rc, butlerd.CollectionsInstallAllCancelParams{
+		BatchID:        "jam",
+		DiscardPending: true,
+	})
+	must(err)
+	assert.False(cancelRes.DidCancel, "the batch was done queuing")
+	assert.EqualValues(2, cancelRes.Discarded)
+
+	listRes, err = messages.DownloadsList.TestCall(rc, butlerd.DownloadsListParams{
+		BatchID: "jam",
+	})
+	must(err)
+	assert.Empty(listRes.Downloads)
+
+	listRes, err = messages.DownloadsList.TestCall(rc, butlerd.DownloadsListParams{})
+	must(err)
+	assert.Len(listRes.Downloads, 1, "downloads outside the batch are kept")
+}
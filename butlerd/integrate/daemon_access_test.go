@@ -0,0 +1,110 @@
+package integrate
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/jsonrpc2"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DaemonAccess(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t, withIdleTimeout(500*time.Millisecond))
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	assertCode := func(code butlerd.Code, err error) {
+		t.Helper()
+		if assert.Error(err) {
+			je, ok := err.(*jsonrpc2.Error)
+			if assert.True(ok) {
+				assert.EqualValues(code, je.Code)
+			}
+		}
+	}
+
+	authRes, err := messages.MetaAuthenticate.TestCall(rc, butlerd.MetaAuthenticateParams{
+		Secret:          bi.Secret,
+		PermissionLevel: butlerd.PermissionLevelReadOnly,
+	})
+	must(err)
+	assert.EqualValues(butlerd.PermissionLevelReadOnly, authRes.PermissionLevel)
+
+	_, err = messages.DownloadsList.TestCall(rc, butlerd.DownloadsListParams{})
+	must(err)
+	_, err = messages.DownloadsClearFinished.TestCall(rc, butlerd.DownloadsClearFinishedParams{})
+	assertCode(butlerd.CodePermissionDenied, err)
+
+	_, err = messages.MetaAuthenticate.TestCall(rc, butlerd.MetaAuthenticateParams{
+		Secret:          bi.Secret,
+		PermissionLevel: butlerd.PermissionLevelDownloadsOnly,
+	})
+	must(err)
+	_, err = messages.DownloadsClearFinished.TestCall(rc, butlerd.DownloadsClearFinishedParams{})
+	must(err)
+	_, err = messages.CavesSetPinned.TestCall(rc, butlerd.CavesSetPinnedParams{
+		CaveID: "not-a-cave",
+	})
+	assertCode(butlerd.CodePermissionDenied, err)
+
+	time.Sleep(time.Second)
+	_, err = messages.VersionGet.TestCall(rc, butlerd.VersionGetParams{})
+	assertCode(butlerd.CodeAuthenticationExpired, err)
+
+	_, err = messages.MetaAuthenticate.TestCall(rc, butlerd.MetaAuthenticateParams{
+		Secret: bi.Secret,
+	})
+	must(err)
+	_, err = messages.VersionGet.TestCall(rc, butlerd.VersionGetParams{})
+	must(err)
+
+	{
+		tcpConn, err := net.DialTimeout("tcp", bi.Address, 2*time.Second)
+		must(err)
+		jc := jsonrpc2.NewConn(bi.Ctx, jsonrpc2.NewRwcTransport(tcpConn), newHandler(bi.Consumer))
+		_, err = messages.MetaAuthenticate.TestCall(&butlerd.RequestContext{
+			Conn:     jc,
+			Ctx:      bi.Ctx,
+			Consumer: bi.Consumer,
+		}, butlerd.MetaAuthenticateParams{
+			Secret: "hunter2",
+		})
+		assert.Error(err)
+		jc.Close()
+	}
+
+	statsRes, err := messages.SystemStats.TestCall(rc, butlerd.SystemStatsParams{})
+	must(err)
+	stats := statsRes.Connections
+	assert.EqualValues(2, stats.Accepted)
+	assert.EqualValues(1, stats.Rejected)
+	assert.EqualValues(1, stats.Expired)
+	assert.EqualValues(2, stats.Denied)
+	if assert.Len(stats.RecentRejections, 1) {
+		assert.EqualValues("invalid secret", stats.RecentRejections[0].Reason)
+	}
+}
+
+func Test_DaemonAllowParentTree(t *testing.T) {
+	if !butlerd.PeerChecksSupported {
+		t.Skip("peer checks are not supported on this platform")
+	}
+	assert := assert.New(t)
+
+	bi := newInstance(t, withAllowParentTree())
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	_, err := messages.VersionGet.TestCall(rc, butlerd.VersionGetParams{})
+	must(err)
+
+	statsRes, err := messages.SystemStats.TestCall(rc, butlerd.SystemStatsParams{})
+	must(err)
+	assert.EqualValues(1, statsRes.Connections.Accepted)
+	assert.EqualValues(0, statsRes.Connections.Rejected)
+}
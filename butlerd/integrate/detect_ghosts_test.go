@@ -0,0 +1,62 @@
+package integrate
+
+import (
+	"os"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/jsonrpc2"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DetectGhosts(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Spooky Developer")
+	_game := _developer.MakeGame("Haunted Mansion")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContents()
+
+	game := bi.FetchGame(_game.ID)
+	res := bi.Install(butlerd.InstallQueueParams{
+		Game: game,
+	})
+
+	ghostsRes, err := messages.CavesDetectGhosts.TestCall(rc, butlerd.CavesDetectGhostsParams{})
+	must(err)
+	assert.Empty(ghostsRes.Ghosts)
+
+	caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+		CaveID: res.CaveID,
+	})
+	must(err)
+	must(os.RemoveAll(caveRes.Cave.InstallInfo.InstallFolder))
+
+	ghostsRes, err = messages.CavesDetectGhosts.TestCall(rc, butlerd.CavesDetectGhostsParams{})
+	must(err)
+	assert.Len(ghostsRes.Ghosts, 1)
+	ghost := ghostsRes.Ghosts[0]
+	assert.EqualValues(res.CaveID, ghost.CaveID)
+	assert.EqualValues(caveRes.Cave.InstallInfo.InstallFolder, ghost.InstallFolder)
+	assert.Contains(ghost.SuggestedActions, butlerd.GhostCaveActionReinstall)
+
+	_, err = messages.CavesOpenInstallFolder.TestCall(rc, butlerd.CavesOpenInstallFolderParams{
+		CaveID: res.CaveID,
+	})
+	if assert.Error(err) {
+		je, ok := err.(*jsonrpc2.Error)
+		if assert.True(ok) {
+			assert.EqualValues(butlerd.CodeInstallFolderMissing, je.Code)
+		}
+	}
+}
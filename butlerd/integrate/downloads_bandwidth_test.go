@@ -0,0 +1,221 @@
+package integrate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DownloadsBandwidth(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Shared Connection")
+	_game := _developer.MakeGame("Slow and Steady")
+	_game.Publish()
+	_upload := _game.MakeUpload("web version")
+	_upload.SetAllPlatforms()
+	_upload.PushBuild(func(ac *mitch.ArchiveContext) {
+		ac.SetName("html5.zip")
+		ac.Entry("index.html").String("<p>Slow and Steady</p>")
+	})
+
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              bi.FetchGame(_game.ID),
+		InstallLocationID: "tmp",
+	})
+	must(err)
+
+	// at one byte per second, that download would take minutes
+	_, err = messages.DownloadsQueue.TestCall(rc, butlerd.DownloadsQueueParams{
+		Item:                    queueRes,
+		BandwidthBytesPerSecond: 1,
+	})
+	must(err)
+
+	bandwidth := func() int64 {
+		res, err := messages.DownloadsList.TestCall(rc, butlerd.DownloadsListParams{})
+		must(err)
+		if !assert.Len(res.Downloads, 1) {
+			return -1
+		}
+		return res.Downloads[0].BandwidthBytesPerSecond
+	}
+	assert.EqualValues(1, bandwidth())
+
+	started := make(chan struct{}, 1)
+	finished := make(chan string, 1)
+	messages.DownloadsDriveStarted.Register(h, func(params butlerd.DownloadsDriveStartedNotification) {
+		started <- struct{}{}
+	})
+	messages.DownloadsDriveErrored.Register(h, func(params butlerd.DownloadsDriveErroredNotification) {
+		finished <- ""
+	})
+	messages.DownloadsDriveFinished.Register(h, func(params butlerd.DownloadsDriveFinishedNotification) {
+		finished <- params.Download.ID
+	})
+
+	driveDone := make(chan error, 1)
+	go func() {
+		_, err := messages.DownloadsDrive.TestCall(rc, butlerd.DownloadsDriveParams{})
+		driveDone <- err
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(20 * time.Second):
+		must(errors.New("timed out waiting for the download to start"))
+	}
+
+	select {
+	case <-finished:
+		must(errors.New("download finished despite being throttled"))
+	case <-time.After(2 * time.Second):
+		// still crawling along
+	}
+
+	_, err = messages.DownloadsSetBandwidth.TestCall(rc, butlerd.DownloadsSetBandwidthParams{
+		DownloadID: queueRes.ID,
+	})
+	must(err)
+	assert.EqualValues(0, bandwidth())
+
+	select {
+	case id := <-finished:
+		assert.EqualValues(queueRes.ID, id, "unthrottled downloads finish")
+	case <-time.After(20 * time.Second):
+		must(errors.New("timed out waiting for the download to finish"))
+	}
+
+	_, err = messages.DownloadsDriveCancel.TestCall(rc, butlerd.DownloadsDriveCancelParams{})
+	must(err)
+	must(<-driveDone)
+
+	_, err = messages.DownloadsSetBandwidth.TestCall(rc, butlerd.DownloadsSetBandwidthParams{
+		DownloadID:              "not-a-download",
+		BandwidthBytesPerSecond: 1024,
+	})
+	assert.Error(err)
+}
+
+func Test_DownloadsThrottle(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Metered Connection")
+	_game := _developer.MakeGame("Drip Feed")
+	_game.Publish()
+	_upload := _game.MakeUpload("web version")
+	_upload.SetAllPlatforms()
+	_upload.PushBuild(func(ac *mitch.ArchiveContext) {
+		ac.SetName("html5.zip")
+		ac.Entry("index.html").String("<p>Drip Feed</p>")
+	})
+
+	setThrottle := func(maxBytesPerSecond int64) {
+		_, err := messages.DownloadsSetThrottle.TestCall(rc, butlerd.DownloadsSetThrottleParams{
+			MaxBytesPerSecond: maxBytesPerSecond,
+		})
+		must(err)
+	}
+
+	_, err := messages.DownloadsSetThrottle.TestCall(rc, butlerd.DownloadsSetThrottleParams{
+		MaxBytesPerSecond: -1,
+	})
+	assert.Error(err)
+
+	setThrottle(1)
+	defer setThrottle(0)
+
+	settingRes, err := messages.SettingsGet.TestCall(rc, butlerd.SettingsGetParams{
+		Key: "downloads.maxBytesPerSecond",
+	})
+	must(err)
+	assert.EqualValues(1, settingRes.Setting.Value)
+
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              bi.FetchGame(_game.ID),
+		InstallLocationID: "tmp",
+		QueueDownload:     true,
+	})
+	must(err)
+
+	started := make(chan struct{}, 1)
+	finished := make(chan string, 1)
+	limits := make(chan int64, 64)
+	messages.DownloadsDriveStarted.Register(h, func(params butlerd.DownloadsDriveStartedNotification) {
+		started <- struct{}{}
+	})
+	messages.DownloadsDriveProgress.Register(h, func(params butlerd.DownloadsDriveProgressNotification) {
+		select {
+		case limits <- params.Progress.LimitBPS:
+		default:
+		}
+	})
+	messages.DownloadsDriveErrored.Register(h, func(params butlerd.DownloadsDriveErroredNotification) {
+		finished <- ""
+	})
+	messages.DownloadsDriveFinished.Register(h, func(params butlerd.DownloadsDriveFinishedNotification) {
+		finished <- params.Download.ID
+	})
+
+	driveDone := make(chan error, 1)
+	go func() {
+		_, err := messages.DownloadsDrive.TestCall(rc, butlerd.DownloadsDriveParams{})
+		driveDone <- err
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(20 * time.Second):
+		must(errors.New("timed out waiting for the download to start"))
+	}
+
+	select {
+	case <-finished:
+		must(errors.New("download finished despite the throttle"))
+	case <-time.After(2 * time.Second):
+		// still crawling along
+	}
+
+	// lifting the throttle applies to the download in progress
+	setThrottle(0)
+
+	select {
+	case id := <-finished:
+		assert.EqualValues(queueRes.ID, id, "unthrottled downloads finish")
+	case <-time.After(20 * time.Second):
+		must(errors.New("timed out waiting for the download to finish"))
+	}
+
+drain:
+	for {
+		select {
+		case limit := <-limits:
+			if limit != 0 {
+				assert.EqualValues(1, limit, "progress reports the throttle")
+			}
+		default:
+			break drain
+		}
+	}
+
+	_, err = messages.DownloadsDriveCancel.TestCall(rc, butlerd.DownloadsDriveCancelParams{})
+	must(err)
+	must(<-driveDone)
+}
@@ -0,0 +1,95 @@
+package integrate
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DownloadsGetByGameID(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Library Keeper")
+	queue := func(title string) (int64, *butlerd.InstallQueueResult) {
+		_game := _developer.MakeGame(title)
+		_game.Publish()
+		_upload := _game.MakeUpload("web version")
+		_upload.SetAllPlatforms()
+		_upload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+			ac.Entry("index.html").String("<p>" + title + "</p>")
+		})
+
+		queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+			Game:              bi.FetchGame(_game.ID),
+			InstallLocationID: "tmp",
+			QueueDownload:     true,
+		})
+		must(err)
+		return _game.ID, queueRes
+	}
+	gameID, queued := queue("Tracked by game")
+	otherGameID, _ := queue("Someone else")
+
+	byGame := func(gameID int64) []*butlerd.Download {
+		res, err := messages.DownloadsGetByGameID.TestCall(rc, butlerd.DownloadsGetByGameIDParams{
+			GameID: gameID,
+		})
+		must(err)
+		return res.Downloads
+	}
+
+	dls := byGame(gameID)
+	if assert.Len(dls, 1) {
+		assert.EqualValues(queued.ID, dls[0].ID)
+		assert.EqualValues(gameID, dls[0].Game.ID, "game is preloaded")
+		assert.Nil(dls[0].FinishedAt)
+	}
+	assert.Len(byGame(otherGameID), 1)
+	assert.Empty(byGame(gameID + 1000))
+
+	driveDone := make(chan error, 1)
+	var finished int32
+	messages.DownloadsDriveErrored.Register(h, func(params butlerd.DownloadsDriveErroredNotification) {
+		driveDone <- errors.New("Got unexpected DriveErrored")
+	})
+	messages.DownloadsDriveFinished.Register(h, func(params butlerd.DownloadsDriveFinishedNotification) {
+		if atomic.AddInt32(&finished, 1) == 2 {
+			_, err := messages.DownloadsDriveCancel.TestCall(rc, butlerd.DownloadsDriveCancelParams{})
+			must(err)
+		}
+	})
+
+	go func() {
+		_, err := messages.DownloadsDrive.TestCall(rc, butlerd.DownloadsDriveParams{})
+		driveDone <- err
+	}()
+
+	select {
+	case err := <-driveDone:
+		must(err)
+	case <-time.After(10 * time.Second):
+		must(errors.New("timed out"))
+	}
+
+	dls = byGame(gameID)
+	if assert.Len(dls, 1, "recently finished downloads are still listed") {
+		assert.NotNil(dls[0].FinishedAt)
+		assert.EqualValues(queued.CaveID, dls[0].CaveID)
+	}
+
+	_, err := messages.DownloadsClearFinished.TestCall(rc, butlerd.DownloadsClearFinishedParams{})
+	must(err)
+	assert.Empty(byGame(gameID), "cleared downloads aren't listed")
+}
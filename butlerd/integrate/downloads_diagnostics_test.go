@@ -0,0 +1,93 @@
+package integrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DownloadsDiagnostics(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	// no file system we run on accepts a name that long
+	longName := strings.Repeat("o", 300) + ".txt"
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Careless Developer")
+	_game := _developer.MakeGame("Diagnostics Simulator")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+		ac.Entry("readme.txt").String("Good luck!")
+		ac.Entry("data/" + longName).String("Unreachable")
+	})
+
+	game := bi.FetchGame(_game.ID)
+
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+		QueueDownload:     true,
+	})
+	must(err)
+
+	driveDone := make(chan error, 1)
+	messages.DownloadsDriveErrored.Register(h, func(params butlerd.DownloadsDriveErroredNotification) {
+		_, err := messages.DownloadsDriveCancel.TestCall(rc, butlerd.DownloadsDriveCancelParams{})
+		must(err)
+	})
+	messages.DownloadsDriveFinished.Register(h, func(params butlerd.DownloadsDriveFinishedNotification) {
+		driveDone <- errors.New("Got unexpected DriveFinished")
+	})
+
+	go func() {
+		_, err := messages.DownloadsDrive.TestCall(rc, butlerd.DownloadsDriveParams{})
+		driveDone <- err
+	}()
+
+	select {
+	case err := <-driveDone:
+		must(err)
+	case <-time.After(10 * time.Second):
+		must(errors.New("timed out"))
+	}
+
+	res, err := messages.SystemGetOperationDiagnostics.TestCall(rc, butlerd.SystemGetOperationDiagnosticsParams{
+		DownloadID: queueRes.ID,
+	})
+	must(err)
+
+	diag := res.Diagnostics
+	if !assert.NotNil(diag) {
+		return
+	}
+	assert.Contains(diag.Error, "component-too-long")
+
+	foundGame := false
+	for _, call := range diag.Calls {
+		bi.Logf("%s %s => %d (%d ms)", call.Method, call.URL, call.Status, call.LatencyMs)
+		if strings.Contains(call.URL, fmt.Sprintf("/games/%d", _game.ID)) {
+			foundGame = true
+		}
+	}
+	assert.True(foundGame, "should have recorded fetching the game")
+
+	payload, err := json.Marshal(diag)
+	must(err)
+	assert.NotContains(string(payload), ConstantAPIKey)
+}
@@ -0,0 +1,106 @@
+package integrate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DownloadsDrainQueue(t *testing.T) {
+	assert := assert.New(t)
+
+	// slow enough that nothing finishes while the test runs
+	bi := newInstance(t, withMockTransfers(64*1024))
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Queue Hoarder")
+	queue := func(title string) *butlerd.InstallQueueResult {
+		_game := _developer.MakeGame(title)
+		_game.Publish()
+		_upload := _game.MakeUpload("All platforms")
+		_upload.SetAllPlatforms()
+		_upload.Filename = "huge.zip"
+		_upload.Size = 64 * 1024 * 1024
+
+		game := bi.FetchGame(_game.ID)
+		uploadsRes, err := bi.Client().ListGameUploads(rc.Ctx, itchio.ListGameUploadsParams{
+			GameID: game.ID,
+		})
+		must(err)
+
+		queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+			Game:              game,
+			Upload:            uploadsRes.Uploads[0],
+			InstallLocationID: "tmp",
+			QueueDownload:     true,
+		})
+		must(err)
+		return queueRes
+	}
+	first := queue("Driven")
+	second := queue("Waiting")
+	third := queue("Paused")
+
+	_, err := messages.DownloadsPause.TestCall(rc, butlerd.DownloadsPauseParams{
+		DownloadID: third.ID,
+	})
+	must(err)
+
+	started := make(chan string, 3)
+	messages.DownloadsDriveStarted.Register(h, func(params butlerd.DownloadsDriveStartedNotification) {
+		started <- params.Download.ID
+	})
+	messages.DownloadsDriveFinished.Register(h, func(params butlerd.DownloadsDriveFinishedNotification) {
+		bi.Logf("Download %s finished, it should have been drained", params.Download.ID)
+	})
+
+	driveDone := make(chan error, 1)
+	go func() {
+		_, err := messages.DownloadsDrive.TestCall(rc, butlerd.DownloadsDriveParams{})
+		driveDone <- err
+	}()
+
+	select {
+	case id := <-started:
+		assert.EqualValues(first.ID, id)
+	case <-time.After(20 * time.Second):
+		must(errors.New("timed out waiting for the drive to start"))
+	}
+
+	drainRes, err := messages.DownloadsDrainQueue.TestCall(rc, butlerd.DownloadsDrainQueueParams{
+		Reason: "clearing a corrupted queue",
+	})
+	must(err)
+	assert.EqualValues(3, drainRes.Drained)
+
+	listRes, err := messages.DownloadsList.TestCall(rc, butlerd.DownloadsListParams{})
+	must(err)
+	assert.Empty(listRes.Downloads)
+	for _, q := range []*butlerd.InstallQueueResult{first, second, third} {
+		assert.NoDirExists(q.StagingFolder)
+		assert.NoDirExists(q.InstallFolder, "fresh installs don't leave anything behind")
+	}
+
+	// the drive is still running, with nothing left to do
+	select {
+	case id := <-started:
+		assert.Fail("drained downloads shouldn't be started", id)
+	case <-time.After(2 * time.Second):
+	}
+
+	_, err = messages.DownloadsDriveCancel.TestCall(rc, butlerd.DownloadsDriveCancelParams{})
+	must(err)
+	must(<-driveDone)
+
+	drainRes, err = messages.DownloadsDrainQueue.TestCall(rc, butlerd.DownloadsDrainQueueParams{})
+	must(err)
+	assert.EqualValues(0, drainRes.Drained)
+}
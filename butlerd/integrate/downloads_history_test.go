@@ -0,0 +1,209 @@
+package integrate
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/mitch"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DownloadsHistory(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Adam's Atom, eek")
+	_game := _developer.MakeGame("Some web game")
+	_game.Publish()
+	_upload := _game.MakeUpload("web version")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+		ac.Entry("index.html").String("<p>Well hello</p>")
+	})
+
+	game := bi.FetchGame(_game.ID)
+
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+		QueueDownload:     true,
+	})
+	must(err)
+
+	historyRes, err := messages.DownloadsGetHistory.TestCall(rc, butlerd.DownloadsGetHistoryParams{
+		DownloadID: queueRes.ID,
+	})
+	must(err)
+	assert.Len(historyRes.Chunks, 0, "nothing transferred before driving")
+
+	var notifsLock sync.Mutex
+	var notifs []string
+	record := func(notif string) {
+		notifsLock.Lock()
+		defer notifsLock.Unlock()
+		if len(notifs) == 0 || notifs[len(notifs)-1] != notif {
+			notifs = append(notifs, notif)
+		}
+	}
+
+	driveDone := make(chan error, 1)
+	messages.DownloadsDriveStarted.Register(h, func(params butlerd.DownloadsDriveStartedNotification) {
+		record("started")
+	})
+	messages.DownloadsDriveProgress.Register(h, func(params butlerd.DownloadsDriveProgressNotification) {
+		record(params.Progress.Stage)
+	})
+	messages.DownloadsDriveErrored.Register(h, func(params butlerd.DownloadsDriveErroredNotification) {
+		driveDone <- errors.New("Got unexpected DriveErrored")
+	})
+	messages.DownloadsDriveFinished.Register(h, func(params butlerd.DownloadsDriveFinishedNotification) {
+		record("finished")
+		_, err := messages.DownloadsDriveCancel.TestCall(rc, butlerd.DownloadsDriveCancelParams{})
+		must(err)
+	})
+
+	go func() {
+		_, err := messages.DownloadsDrive.TestCall(rc, butlerd.DownloadsDriveParams{})
+		driveDone <- err
+	}()
+
+	select {
+	case err := <-driveDone:
+		must(err)
+	case <-time.After(10 * time.Second):
+		must(errors.New("timed out"))
+	}
+
+	historyRes, err = messages.DownloadsGetHistory.TestCall(rc, butlerd.DownloadsGetHistoryParams{
+		DownloadID: queueRes.ID,
+	})
+	must(err)
+	if assert.NotEmpty(historyRes.Chunks) {
+		for _, c := range historyRes.Chunks {
+			assert.True(c.BytesTransferred > 0)
+			assert.EqualValues("127.0.0.1", c.ServerIP)
+		}
+	}
+	assert.Nil(historyRes.Jitter, "not enough chunks for jitter")
+
+	_, err = messages.DownloadsGetHistory.TestCall(rc, butlerd.DownloadsGetHistoryParams{
+		DownloadID: "not-a-download",
+	})
+	assert.Error(err)
+}
+
+func Test_DownloadsSpeedHistory(t *testing.T) {
+	assert := assert.New(t)
+
+	const speed = 2 * 1024 * 1024
+	bi := newInstance(t, withMockTransfers(speed))
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Graph Plotter")
+	_game := _developer.MakeGame("Steady Download")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.Filename = "steady.zip"
+	_upload.Size = 5 * speed / 2
+
+	game := bi.FetchGame(_game.ID)
+	uploadsRes, err := bi.Client().ListGameUploads(rc.Ctx, itchio.ListGameUploadsParams{
+		GameID: game.ID,
+	})
+	must(err)
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		Upload:            uploadsRes.Uploads[0],
+		InstallLocationID: "tmp",
+		QueueDownload:     true,
+	})
+	must(err)
+
+	speedRes, err := messages.DownloadsGetSpeedHistory.TestCall(rc, butlerd.DownloadsGetSpeedHistoryParams{
+		DownloadID: queueRes.ID,
+	})
+	must(err)
+	assert.Empty(speedRes.Samples, "nothing transferred before driving")
+
+	var notifsLock sync.Mutex
+	var notifs []string
+	record := func(notif string) {
+		notifsLock.Lock()
+		defer notifsLock.Unlock()
+		if len(notifs) == 0 || notifs[len(notifs)-1] != notif {
+			notifs = append(notifs, notif)
+		}
+	}
+
+	driveDone := make(chan error, 1)
+	messages.DownloadsDriveStarted.Register(h, func(params butlerd.DownloadsDriveStartedNotification) {
+		record("started")
+	})
+	messages.DownloadsDriveProgress.Register(h, func(params butlerd.DownloadsDriveProgressNotification) {
+		record(params.Progress.Stage)
+	})
+	messages.DownloadsDriveErrored.Register(h, func(params butlerd.DownloadsDriveErroredNotification) {
+		driveDone <- errors.New("Got unexpected DriveErrored")
+	})
+	messages.DownloadsDriveFinished.Register(h, func(params butlerd.DownloadsDriveFinishedNotification) {
+		record("finished")
+		_, err := messages.DownloadsDriveCancel.TestCall(rc, butlerd.DownloadsDriveCancelParams{})
+		must(err)
+	})
+	go func() {
+		_, err := messages.DownloadsDrive.TestCall(rc, butlerd.DownloadsDriveParams{})
+		driveDone <- err
+	}()
+
+	select {
+	case err := <-driveDone:
+		must(err)
+	case <-time.After(20 * time.Second):
+		must(errors.New("timed out"))
+	}
+
+	speedRes, err = messages.DownloadsGetSpeedHistory.TestCall(rc, butlerd.DownloadsGetSpeedHistoryParams{
+		DownloadID: queueRes.ID,
+	})
+	must(err)
+	notifsLock.Lock()
+	assert.EqualValues([]string{"started", "download", "install", "finished"}, notifs,
+		"installing only starts once the download is done")
+	notifsLock.Unlock()
+
+	// the download takes 2.5s, then installing another 2.5s
+	if assert.True(len(speedRes.Samples) >= 4, "one sample per second") {
+		assert.InDelta(speed, speedRes.Samples[0].SpeedBPS, speed*0.3)
+	}
+	assert.InDelta(speed, speedRes.PeakSpeedBPS, speed*0.3)
+	assert.True(speedRes.AverageSpeedBPS > 0)
+	assert.True(speedRes.AverageSpeedBPS <= speedRes.PeakSpeedBPS)
+
+	lastRes, err := messages.DownloadsGetSpeedHistory.TestCall(rc, butlerd.DownloadsGetSpeedHistoryParams{
+		DownloadID:   queueRes.ID,
+		LastNSeconds: 1,
+	})
+	must(err)
+	assert.Len(lastRes.Samples, 1)
+
+	_, err = messages.DownloadsGetSpeedHistory.TestCall(rc, butlerd.DownloadsGetSpeedHistoryParams{
+		DownloadID:   queueRes.ID,
+		LastNSeconds: 301,
+	})
+	assert.Error(err)
+}
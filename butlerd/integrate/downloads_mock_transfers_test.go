@@ -0,0 +1,130 @@
+package integrate
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DownloadsMockTransfers(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t, withMockTransfers(8*1024*1024))
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Stunt Double")
+	_game := _developer.MakeGame("Very Large Game")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	// no contents: if anything tried to download it for real, it'd fail
+	_upload.Filename = "huge.zip"
+	_upload.Size = 4 * 1024 * 1024
+
+	game := bi.FetchGame(_game.ID)
+	uploadsRes, err := bi.Client().ListGameUploads(rc.Ctx, itchio.ListGameUploadsParams{
+		GameID: game.ID,
+	})
+	must(err)
+
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		Upload:            uploadsRes.Uploads[0],
+		InstallLocationID: "tmp",
+		QueueDownload:     true,
+	})
+	must(err)
+
+	var notifsLock sync.Mutex
+	var started, finished bool
+	var stages = make(map[string]bool)
+	var maxProgress float64
+
+	messages.DownloadsDriveStarted.Register(h, func(params butlerd.DownloadsDriveStartedNotification) {
+		notifsLock.Lock()
+		defer notifsLock.Unlock()
+		started = true
+	})
+
+	messages.DownloadsDriveProgress.Register(h, func(params butlerd.DownloadsDriveProgressNotification) {
+		notifsLock.Lock()
+		defer notifsLock.Unlock()
+		stages[params.Progress.Stage] = true
+		if params.Progress.Progress > maxProgress {
+			maxProgress = params.Progress.Progress
+		}
+	})
+
+	driveDone := make(chan error)
+
+	messages.DownloadsDriveErrored.Register(h, func(params butlerd.DownloadsDriveErroredNotification) {
+		msg := "Got unexpected DriveErrored"
+		if params.Download.ErrorMessage != nil {
+			msg += ": " + *params.Download.ErrorMessage
+		}
+		driveDone <- errors.New(msg)
+	})
+
+	messages.DownloadsDriveFinished.Register(h, func(params butlerd.DownloadsDriveFinishedNotification) {
+		notifsLock.Lock()
+		finished = true
+		notifsLock.Unlock()
+
+		_, err := messages.DownloadsDriveCancel.TestCall(rc, butlerd.DownloadsDriveCancelParams{})
+		must(err)
+	})
+
+	go func() {
+		_, err := messages.DownloadsDrive.TestCall(rc, butlerd.DownloadsDriveParams{})
+		driveDone <- err
+	}()
+
+	select {
+	case err := <-driveDone:
+		must(err)
+	case <-time.After(20 * time.Second):
+		must(errors.New("timed out"))
+	}
+
+	notifsLock.Lock()
+	assert.True(started, "driver started the download")
+	assert.True(finished, "driver finished the download")
+	assert.True(stages["download"], "got download progress")
+	assert.True(stages["install"], "got install progress")
+	assert.True(maxProgress > 0, "progress moved")
+	notifsLock.Unlock()
+
+	listRes, err := messages.DownloadsList.TestCall(rc, butlerd.DownloadsListParams{})
+	must(err)
+	if assert.Len(listRes.Downloads, 1) {
+		assert.NotNil(listRes.Downloads[0].FinishedAt, "history row is finished")
+		assert.Nil(listRes.Downloads[0].Error)
+	}
+
+	caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+		CaveID: queueRes.CaveID,
+	})
+	must(err)
+	installFolder := caveRes.Cave.InstallInfo.InstallFolder
+	for _, name := range []string{".itch/receipt.json.gz", "README.txt", "index.html"} {
+		_, err := os.Stat(filepath.Join(installFolder, name))
+		assert.NoError(err, "has %s", name)
+	}
+
+	_, err = messages.UninstallPerform.TestCall(rc, butlerd.UninstallPerformParams{
+		CaveID: queueRes.CaveID,
+	})
+	must(err)
+}
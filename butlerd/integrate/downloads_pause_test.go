@@ -0,0 +1,115 @@
+package integrate
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DownloadsPause(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Metered Connection")
+	queue := func(title string) *butlerd.InstallQueueResult {
+		_game := _developer.MakeGame(title)
+		_game.Publish()
+		_upload := _game.MakeUpload("web version")
+		_upload.SetAllPlatforms()
+		_upload.PushBuild(func(ac *mitch.ArchiveContext) {
+			ac.SetName("html5.zip")
+			ac.Entry("index.html").String("<p>" + title + "</p>")
+		})
+
+		queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+			Game:              bi.FetchGame(_game.ID),
+			InstallLocationID: "tmp",
+			QueueDownload:     true,
+		})
+		must(err)
+		return queueRes
+	}
+	first := queue("First in line")
+	second := queue("Second in line")
+
+	_, err := messages.DownloadsPause.TestCall(rc, butlerd.DownloadsPauseParams{
+		DownloadID: first.ID,
+	})
+	must(err)
+
+	paused := func() map[string]bool {
+		res, err := messages.DownloadsList.TestCall(rc, butlerd.DownloadsListParams{})
+		must(err)
+		states := make(map[string]bool)
+		for _, d := range res.Downloads {
+			states[d.ID] = d.Paused
+		}
+		return states
+	}
+	assert.EqualValues(map[string]bool{first.ID: true, second.ID: false}, paused())
+
+	var lock sync.Mutex
+	var startedOrder []string
+	finished := make(chan string, 2)
+
+	messages.DownloadsDriveStarted.Register(h, func(params butlerd.DownloadsDriveStartedNotification) {
+		lock.Lock()
+		defer lock.Unlock()
+		startedOrder = append(startedOrder, params.Download.ID)
+	})
+	messages.DownloadsDriveErrored.Register(h, func(params butlerd.DownloadsDriveErroredNotification) {
+		bi.Logf("Download %s errored", params.Download.ID)
+		finished <- ""
+	})
+	messages.DownloadsDriveFinished.Register(h, func(params butlerd.DownloadsDriveFinishedNotification) {
+		finished <- params.Download.ID
+	})
+
+	driveDone := make(chan error, 1)
+	go func() {
+		_, err := messages.DownloadsDrive.TestCall(rc, butlerd.DownloadsDriveParams{})
+		driveDone <- err
+	}()
+
+	waitFinished := func() string {
+		select {
+		case id := <-finished:
+			return id
+		case <-time.After(20 * time.Second):
+			must(errors.New("timed out waiting for a download to finish"))
+			return ""
+		}
+	}
+
+	assert.EqualValues(second.ID, waitFinished(), "paused downloads are skipped")
+
+	_, err = messages.DownloadsResume.TestCall(rc, butlerd.DownloadsResumeParams{
+		DownloadID: first.ID,
+	})
+	must(err)
+	assert.EqualValues(first.ID, waitFinished(), "resumed downloads are picked up")
+
+	_, err = messages.DownloadsDriveCancel.TestCall(rc, butlerd.DownloadsDriveCancelParams{})
+	must(err)
+	must(<-driveDone)
+
+	lock.Lock()
+	assert.EqualValues([]string{second.ID, first.ID}, startedOrder)
+	lock.Unlock()
+
+	_, err = messages.DownloadsPause.TestCall(rc, butlerd.DownloadsPauseParams{
+		DownloadID: "not-a-download",
+	})
+	assert.Error(err)
+}
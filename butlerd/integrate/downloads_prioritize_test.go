@@ -0,0 +1,148 @@
+package integrate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DownloadsPrioritize(t *testing.T) {
+	assert := assert.New(t)
+
+	// at 1MiB/s, the large one would take minutes
+	bi := newInstance(t, withMockTransfers(1024*1024))
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Impatient Player")
+	queue := func(title string, size int64) *butlerd.InstallQueueResult {
+		_game := _developer.MakeGame(title)
+		_game.Publish()
+		_upload := _game.MakeUpload("All platforms")
+		_upload.SetAllPlatforms()
+		_upload.Filename = "game.zip"
+		_upload.Size = size
+
+		game := bi.FetchGame(_game.ID)
+		uploadsRes, err := bi.Client().ListGameUploads(rc.Ctx, itchio.ListGameUploadsParams{
+			GameID: game.ID,
+		})
+		must(err)
+
+		queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+			Game:              game,
+			Upload:            uploadsRes.Uploads[0],
+			InstallLocationID: "tmp",
+			QueueDownload:     true,
+		})
+		must(err)
+		return queueRes
+	}
+	slow := queue("Takes Forever", 1024*1024*1024)
+	second := queue("Second in line", 64*1024)
+	third := queue("Third in line", 64*1024)
+
+	order := func() []string {
+		res, err := messages.DownloadsList.TestCall(rc, butlerd.DownloadsListParams{})
+		must(err)
+		var ids []string
+		for _, d := range res.Downloads {
+			ids = append(ids, d.ID)
+		}
+		return ids
+	}
+	assert.EqualValues([]string{slow.ID, second.ID, third.ID}, order())
+
+	_, err := messages.DownloadsPrioritize.TestCall(rc, butlerd.DownloadsPrioritizeParams{
+		DownloadID: third.ID,
+	})
+	must(err)
+	assert.EqualValues([]string{third.ID, slow.ID, second.ID}, order())
+
+	_, err = messages.DownloadsPrioritize.TestCall(rc, butlerd.DownloadsPrioritizeParams{
+		DownloadID: slow.ID,
+	})
+	must(err)
+	assert.EqualValues([]string{slow.ID, third.ID, second.ID}, order())
+
+	started := make(chan string, 8)
+	finished := make(chan string, 8)
+	preempted := make(chan butlerd.DownloadsDrivePreemptedNotification, 8)
+	messages.DownloadsDriveStarted.Register(h, func(params butlerd.DownloadsDriveStartedNotification) {
+		started <- params.Download.ID
+	})
+	messages.DownloadsDrivePreempted.Register(h, func(params butlerd.DownloadsDrivePreemptedNotification) {
+		preempted <- params
+	})
+	messages.DownloadsDriveErrored.Register(h, func(params butlerd.DownloadsDriveErroredNotification) {
+		bi.Logf("Download %s errored", params.Download.ID)
+		finished <- ""
+	})
+	messages.DownloadsDriveFinished.Register(h, func(params butlerd.DownloadsDriveFinishedNotification) {
+		finished <- params.Download.ID
+	})
+
+	driveDone := make(chan error, 1)
+	go func() {
+		_, err := messages.DownloadsDrive.TestCall(rc, butlerd.DownloadsDriveParams{})
+		driveDone <- err
+	}()
+
+	wait := func(c chan string, what string) string {
+		select {
+		case id := <-c:
+			return id
+		case <-time.After(20 * time.Second):
+			must(errors.Errorf("timed out waiting for a download to %s", what))
+			return ""
+		}
+	}
+
+	prioritize := func(next *butlerd.InstallQueueResult) {
+		assert.EqualValues(slow.ID, wait(started, "start"))
+
+		_, err := messages.DownloadsPrioritize.TestCall(rc, butlerd.DownloadsPrioritizeParams{
+			DownloadID: next.ID,
+		})
+		must(err)
+
+		select {
+		case p := <-preempted:
+			assert.EqualValues(slow.ID, p.Download.ID)
+			assert.EqualValues(next.ID, p.NextDownloadID)
+		case <-time.After(20 * time.Second):
+			must(errors.New("timed out waiting for the slow download to be set aside"))
+		}
+		assert.EqualValues(next.ID, wait(started, "start"))
+		assert.EqualValues(next.ID, wait(finished, "finish"))
+	}
+	prioritize(second)
+	// the slow one is still in the queue, and is next
+	prioritize(third)
+	assert.EqualValues(slow.ID, wait(started, "start"))
+
+	_, err = messages.DownloadsDriveCancel.TestCall(rc, butlerd.DownloadsDriveCancelParams{})
+	must(err)
+	must(<-driveDone)
+
+	// finished downloads are left where they are
+	before := order()
+	_, err = messages.DownloadsPrioritize.TestCall(rc, butlerd.DownloadsPrioritizeParams{
+		DownloadID: third.ID,
+	})
+	must(err)
+	assert.EqualValues(before, order())
+	assert.Empty(preempted)
+
+	_, err = messages.DownloadsPrioritize.TestCall(rc, butlerd.DownloadsPrioritizeParams{
+		DownloadID: "not-a-download",
+	})
+	assert.Error(err)
+}
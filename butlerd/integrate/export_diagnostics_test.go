@@ -0,0 +1,87 @@
+package integrate
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ExportDiagnostics(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Helpful Developer")
+	_game := _developer.MakeGame("Bug Report Simulator")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContents()
+
+	game := bi.FetchGame(_game.ID)
+	res := bi.Install(butlerd.InstallQueueParams{
+		Game: game,
+	})
+
+	tmpDir, err := ioutil.TempDir("", "export-diagnostics-test")
+	must(err)
+	defer os.RemoveAll(tmpDir)
+
+	zipPath := filepath.Join(tmpDir, "diagnostics.zip")
+	exportRes, err := messages.SystemExportDiagnostics.TestCall(rc, butlerd.SystemExportDiagnosticsParams{
+		Path: zipPath,
+	})
+	must(err)
+	assert.EqualValues(zipPath, exportRes.Path)
+
+	zr, err := zip.OpenReader(zipPath)
+	must(err)
+	defer zr.Close()
+
+	contents := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		must(err)
+		payload, err := ioutil.ReadAll(r)
+		must(err)
+		r.Close()
+		contents[f.Name] = string(payload)
+	}
+
+	for _, name := range []string{"version.json", "settings.json", "timeline.json", "install-locations.json", "environment.json", "logs.txt"} {
+		assert.Contains(contents, name)
+		assert.Contains(exportRes.Files, name)
+	}
+
+	for name, payload := range contents {
+		assert.NotContains(payload, ConstantAPIKey, "API key leaked in %s", name)
+	}
+
+	assert.Contains(contents["settings.json"], `"apiKey": "[redacted]"`)
+	assert.Contains(contents["timeline.json"], res.CaveID)
+	assert.Contains(contents["timeline.json"], "cave-installed")
+	assert.Contains(contents["install-locations.json"], `"exists": true`)
+	assert.Contains(contents["environment.json"], `"numCpu"`)
+	assert.NotEmpty(contents["logs.txt"])
+
+	envRes, err := messages.SystemEnvironmentInfo.TestCall(rc, butlerd.SystemEnvironmentInfoParams{})
+	must(err)
+	env := envRes.Environment
+	assert.NotEmpty(env.OS)
+	assert.NotEmpty(env.GoVersion)
+	assert.True(env.NumCPU > 0)
+	assert.True(env.ProcessID > 0)
+	assert.NotEmpty(env.TempDir)
+	assert.NotEmpty(env.Variables)
+}
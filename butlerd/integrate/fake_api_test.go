@@ -0,0 +1,79 @@
+package integrate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/fakeapi"
+	"github.com/itchio/mitch"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FakeAPIMisbehaving(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t, withFakeAPIBehaviors(fakeapi.Behaviors{
+		Latency:        5 * time.Millisecond,
+		RateLimitEvery: 3,
+	}))
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Unreliable Host")
+	_game := _developer.MakeGame("Sometimes Slow")
+	_game.Type = "html"
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.PushBuild(func(ac *mitch.ArchiveContext) {
+		ac.SetName("html5.zip")
+		ac.Entry("index.html").String("<p>Made it</p>")
+	})
+
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              bi.FetchGame(_game.ID),
+		InstallLocationID: "tmp",
+		QueueDownload:     true,
+	})
+	must(err)
+
+	driveDone := make(chan error, 1)
+	messages.DownloadsDriveErrored.Register(h, func(params butlerd.DownloadsDriveErroredNotification) {
+		driveDone <- errors.Errorf("download errored: %v", params.Download.ErrorMessage)
+	})
+	messages.DownloadsDriveFinished.Register(h, func(params butlerd.DownloadsDriveFinishedNotification) {
+		_, err := messages.DownloadsDriveCancel.TestCall(rc, butlerd.DownloadsDriveCancelParams{})
+		must(err)
+	})
+	go func() {
+		_, err := messages.DownloadsDrive.TestCall(rc, butlerd.DownloadsDriveParams{})
+		driveDone <- err
+	}()
+
+	select {
+	case err := <-driveDone:
+		must(err)
+	case <-time.After(30 * time.Second):
+		must(errors.New("timed out"))
+	}
+
+	plansRes, err := messages.LaunchPrecomputePlans.TestCall(rc, butlerd.LaunchPrecomputePlansParams{})
+	must(err)
+	if assert.Len(plansRes.Plans, 1) {
+		plan := plansRes.Plans[0]
+		assert.EqualValues(queueRes.CaveID, plan.CaveID)
+		assert.True(plan.OK)
+		assert.EqualValues([]butlerd.LaunchStrategy{butlerd.LaunchStrategyHTML}, plan.Strategies)
+	}
+
+	statsRes, err := messages.SystemStats.TestCall(rc, butlerd.SystemStatsParams{})
+	must(err)
+	assert.NotZero(statsRes.RateLimiter.RateLimited, "the fake API pushed back")
+	assert.EqualValues(statsRes.RateLimiter.RateLimited, statsRes.RateLimiter.Retries, "and every call was retried")
+	assert.Zero(statsRes.RateLimiter.Failures)
+}
@@ -0,0 +1,267 @@
+package integrate
+
+import (
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FetchCavesByInstallDate(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("John Doe")
+
+	var caveIDs []string
+	for _, title := range []string{"First Game", "Second Game", "Third Game"} {
+		_game := _developer.MakeGame(title)
+		_game.Publish()
+		_upload := _game.MakeUpload("All platforms")
+		_upload.SetAllPlatforms()
+		_upload.SetZipContents()
+
+		caveIDs = append(caveIDs, bi.Install(butlerd.InstallQueueParams{
+			Game: bi.FetchGame(_game.ID),
+		}).CaveID)
+	}
+
+	fetchIDs := func(reverse bool) []string {
+		res, err := messages.FetchCaves.TestCall(rc, butlerd.FetchCavesParams{
+			SortBy:  "installedAt",
+			Reverse: reverse,
+		})
+		must(err)
+		var ids []string
+		for _, cave := range res.Items {
+			ids = append(ids, cave.ID)
+		}
+		return ids
+	}
+
+	assert.EqualValues([]string{caveIDs[2], caveIDs[1], caveIDs[0]}, fetchIDs(false), "newest installs first")
+	assert.EqualValues(caveIDs, fetchIDs(true))
+
+	res, err := messages.FetchCaves.TestCall(rc, butlerd.FetchCavesParams{})
+	must(err)
+	var defaultIDs []string
+	for _, cave := range res.Items {
+		defaultIDs = append(defaultIDs, cave.ID)
+	}
+	assert.EqualValues(fetchIDs(false), defaultIDs, "install date is the default sort")
+}
+
+func Test_FetchCavesUpdateFilters(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Filter Fan")
+	fresh := installOldestBuild(bi, _developer, "Installed Once", 2)
+	updated := installOldestBuild(bi, _developer, "Updated Since", 2)
+
+	reinstall := func(caveID string, latest bool) {
+		caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{CaveID: caveID})
+		must(err)
+		build := caveRes.Cave.Build
+		if latest {
+			buildsRes, err := bi.Client().ListUploadBuilds(rc.Ctx, itchio.ListUploadBuildsParams{
+				UploadID: caveRes.Cave.Upload.ID,
+			})
+			must(err)
+			build = buildsRes.Builds[0]
+		}
+
+		queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+			CaveID: caveID,
+			Game:   caveRes.Cave.Game,
+			Upload: caveRes.Cave.Upload,
+			Build:  build,
+		})
+		must(err)
+		_, err = messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+			ID:            queueRes.ID,
+			StagingFolder: queueRes.StagingFolder,
+		})
+		must(err)
+	}
+	reinstall(fresh, false)
+	reinstall(updated, true)
+
+	fetchIDs := func(filters butlerd.CavesFilters) []string {
+		res, err := messages.FetchCaves.TestCall(rc, butlerd.FetchCavesParams{
+			SortBy:  "installedAt",
+			Filters: filters,
+		})
+		must(err)
+		var ids []string
+		for _, cave := range res.Items {
+			ids = append(ids, cave.ID)
+		}
+		return ids
+	}
+
+	assert.EqualValues([]string{fresh}, fetchIDs(butlerd.CavesFilters{NeverUpdated: true}), "reinstalling the same build isn't an update")
+	// both were just installed: caves installed before the window are
+	// checked in endpoints/fetch, which can backdate them
+	assert.EqualValues([]string{updated, fresh}, fetchIDs(butlerd.CavesFilters{UpdatedWithinDays: 7}))
+
+	_, err := messages.FetchCaves.TestCall(rc, butlerd.FetchCavesParams{
+		Filters: butlerd.CavesFilters{UpdatedWithinDays: -1},
+	})
+	assert.Error(err)
+}
+
+func Test_FetchCavesByVirtualMachine(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Retro Fan")
+
+	install := func(title string, manifest string) string {
+		_game := _developer.MakeGame(title)
+		_game.Publish()
+		_upload := _game.MakeUpload("All platforms")
+		_upload.SetAllPlatforms()
+		_upload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+			ac.Entry("GAME.EXE").String("not really a DOS game")
+			if manifest != "" {
+				ac.Entry(".itch.toml").String(manifest)
+			}
+		})
+
+		return bi.Install(butlerd.InstallQueueParams{
+			Game: bi.FetchGame(_game.ID),
+		}).CaveID
+	}
+	dos := install("Dungeon of 1991", "[[actions]]\nname = \"play\"\npath = \"GAME.EXE\"\n\n[[prereqs]]\nname = \"dosbox\"\n\n[[prereqs]]\nname = \"vcredist-2015-x86\"\n")
+	native := install("Modern Game", "[[prereqs]]\nname = \"vcredist-2015-x86\"\n")
+	plain := install("Plain Game", "")
+
+	caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+		CaveID: dos,
+	})
+	must(err)
+	assert.EqualValues(butlerd.VirtualMachineTypeDOSBox, caveRes.Cave.InstallInfo.VirtualMachineRequired)
+
+	for _, caveID := range []string{native, plain} {
+		caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+			CaveID: caveID,
+		})
+		must(err)
+		assert.Empty(caveRes.Cave.InstallInfo.VirtualMachineRequired, "runs natively")
+	}
+
+	fetchIDs := func(vm butlerd.VirtualMachineType) []string {
+		res, err := messages.FetchCaves.TestCall(rc, butlerd.FetchCavesParams{
+			Filters: butlerd.CavesFilters{VirtualMachineRequired: vm},
+		})
+		must(err)
+		var ids []string
+		for _, cave := range res.Items {
+			ids = append(ids, cave.ID)
+		}
+		return ids
+	}
+	assert.EqualValues([]string{dos}, fetchIDs(butlerd.VirtualMachineTypeDOSBox))
+	assert.Empty(fetchIDs(butlerd.VirtualMachineTypeWine))
+	assert.Len(fetchIDs(""), 3)
+
+	_, err = messages.FetchCaves.TestCall(rc, butlerd.FetchCavesParams{
+		Filters: butlerd.CavesFilters{VirtualMachineRequired: "commodore64"},
+	})
+	assert.Error(err)
+}
+
+func Test_FetchCavesFiltersAndPages(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Page Turner")
+	_game := _developer.MakeGame("Twice Installed")
+	_game.Publish()
+	for _, name := range []string{"Windows", "Linux"} {
+		_upload := _game.MakeUpload(name)
+		_upload.SetAllPlatforms()
+		_upload.ChannelName = name
+		_upload.PushBuild(func(ac *mitch.ArchiveContext) {
+			ac.SetName("default.zip")
+			ac.Entry("platform.txt").String(name)
+		})
+	}
+	installOldestBuild(bi, _developer, "Once Installed", 1)
+
+	game := bi.FetchGame(_game.ID)
+	uploadsRes, err := bi.Client().ListGameUploads(rc.Ctx, itchio.ListGameUploadsParams{
+		GameID: game.ID,
+	})
+	must(err)
+	var caveIDs []string
+	for _, upload := range uploadsRes.Uploads {
+		caveIDs = append(caveIDs, bi.Install(butlerd.InstallQueueParams{
+			Game:   game,
+			Upload: upload,
+		}).CaveID)
+	}
+
+	params := butlerd.FetchCavesParams{
+		Limit: 1,
+		Filters: butlerd.CavesFilters{
+			GameID:            game.ID,
+			InstallLocationID: "tmp",
+		},
+	}
+	var ids []string
+	for page := 0; page < 3; page++ {
+		res, err := messages.FetchCaves.TestCall(rc, params)
+		must(err)
+		for _, cave := range res.Items {
+			ids = append(ids, cave.ID)
+			assert.EqualValues(game.ID, cave.Game.ID)
+			assert.EqualValues("Twice Installed", cave.Game.Title, "games come along")
+			if assert.NotNil(cave.Upload) {
+				assert.NotZero(cave.Upload.ID)
+			}
+			if assert.NotNil(cave.Build) {
+				assert.NotZero(cave.Build.ID)
+			}
+		}
+		if res.NextCursor == "" {
+			break
+		}
+		params.Cursor = res.NextCursor
+	}
+	assert.ElementsMatch(caveIDs, ids)
+
+	res, err := messages.FetchCaves.TestCall(rc, butlerd.FetchCavesParams{
+		Filters: butlerd.CavesFilters{
+			InstallLocationID: "elsewhere",
+		},
+	})
+	must(err)
+	assert.Empty(res.Items)
+}
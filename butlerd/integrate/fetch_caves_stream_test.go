@@ -0,0 +1,111 @@
+package integrate
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FetchCavesStream(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Hoarder")
+	var caveIDs []string
+	for _, title := range []string{"First Pile", "Second Pile", "Third Pile"} {
+		caveIDs = append(caveIDs, installOldestBuild(bi, _developer, title, 1))
+	}
+
+	var chunksLock sync.Mutex
+	var chunks []butlerd.FetchChunkNotification
+	messages.FetchChunk.Register(h, func(params butlerd.FetchChunkNotification) {
+		chunksLock.Lock()
+		defer chunksLock.Unlock()
+		chunks = append(chunks, params)
+	})
+
+	// chunks are handled as they come in, so they
+	// may not all be in by the time the result is
+	receive := func(summary *butlerd.FetchStreamSummary) []string {
+		t.Helper()
+		assert.Eventually(func() bool {
+			chunksLock.Lock()
+			defer chunksLock.Unlock()
+			return int64(len(chunks)) >= summary.Chunks
+		}, 5*time.Second, 10*time.Millisecond)
+
+		chunksLock.Lock()
+		defer chunksLock.Unlock()
+		sort.Slice(chunks, func(i, j int) bool {
+			return chunks[i].Seq < chunks[j].Seq
+		})
+		var ids []string
+		for i, chunk := range chunks {
+			assert.EqualValues(i, chunk.Seq)
+			assert.EqualValues("Fetch.Caves", chunk.Method)
+			for _, item := range chunk.Items {
+				cave := item.(map[string]interface{})
+				ids = append(ids, cave["id"].(string))
+				assert.NotNil(cave["game"], "games come along")
+			}
+		}
+		chunks = nil
+		return ids
+	}
+
+	params := butlerd.FetchCavesParams{
+		SortBy:    "title",
+		Stream:    true,
+		ChunkSize: 2,
+	}
+	res, err := messages.FetchCaves.TestCall(rc, params)
+	must(err)
+	assert.Empty(res.Items)
+	assert.Empty(res.NextCursor)
+	if assert.NotNil(res.Stream) {
+		assert.EqualValues(&butlerd.FetchStreamSummary{Chunks: 2, Items: 3}, res.Stream)
+		assert.EqualValues(caveIDs, receive(res.Stream))
+	}
+
+	// streams can be paged through too
+	params.Limit = 1
+	res, err = messages.FetchCaves.TestCall(rc, params)
+	must(err)
+	assert.NotEmpty(res.NextCursor)
+	if assert.NotNil(res.Stream) {
+		assert.EqualValues(&butlerd.FetchStreamSummary{Chunks: 1, Items: 1}, res.Stream)
+		assert.EqualValues(caveIDs[:1], receive(res.Stream))
+	}
+
+	params.Limit = 0
+	params.Cursor = res.NextCursor
+	res, err = messages.FetchCaves.TestCall(rc, params)
+	must(err)
+	assert.Empty(res.NextCursor)
+	if assert.NotNil(res.Stream) {
+		assert.EqualValues(caveIDs[1:], receive(res.Stream))
+	}
+
+	res, err = messages.FetchCaves.TestCall(rc, butlerd.FetchCavesParams{
+		SortBy: "title",
+	})
+	must(err)
+	assert.Len(res.Items, 3, "still works without streaming")
+	assert.Nil(res.Stream)
+
+	_, err = messages.FetchCaves.TestCall(rc, butlerd.FetchCavesParams{
+		Stream:    true,
+		ChunkSize: butlerd.MaxStreamChunkSize + 1,
+	})
+	assert.Error(err, "chunks are capped")
+}
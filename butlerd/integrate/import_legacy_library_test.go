@@ -0,0 +1,237 @@
+package integrate
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/hush/bfs"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ImportLegacyLibrary(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("John Doe")
+	_game := _developer.MakeGame("Old Favorite")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContents()
+
+	_legacyGame := _developer.MakeGame("Really Old Favorite")
+	_legacyGame.Publish()
+	_legacyUpload := _legacyGame.MakeUpload("All platforms")
+	_legacyUpload.SetAllPlatforms()
+	_legacyUpload.SetZipContents()
+
+	wd, err := os.Getwd()
+	must(err)
+	root := filepath.Join(wd, "tmp-legacy-library")
+	must(os.RemoveAll(root))
+	defer os.RemoveAll(root)
+
+	makeFolder := func(name string) string {
+		folder := filepath.Join(root, "apps", name)
+		must(os.MkdirAll(folder, 0o755))
+		must(ioutil.WriteFile(filepath.Join(folder, "game.exe"), []byte("not really a game"), 0o755))
+		return folder
+	}
+
+	// installed by a recent version of the app
+	receipt := &bfs.Receipt{
+		Game:   bi.FetchGame(_game.ID),
+		Upload: bi.FetchUpload(_upload.ID),
+		Files:  []string{"game.exe"},
+	}
+	must(receipt.WriteReceipt(makeFolder("old-favorite")))
+
+	// installed by a much older version of the app
+	writeLegacyReceipt := func(folder string, gameID int64, uploadID int64) {
+		legacyReceipt, err := json.Marshal(map[string]interface{}{
+			"cave": map[string]interface{}{
+				"id":          "legacy-cave-id",
+				"gameId":      gameID,
+				"uploadId":    uploadID,
+				"installedAt": 1500000000000.0,
+			},
+		})
+		must(err)
+		must(os.MkdirAll(filepath.Join(folder, ".itch"), 0o755))
+		must(ioutil.WriteFile(filepath.Join(folder, ".itch", "receipt.json"), legacyReceipt, 0o644))
+	}
+	writeLegacyReceipt(makeFolder("really-old-favorite"), _legacyGame.ID, _legacyUpload.ID)
+	writeLegacyReceipt(makeFolder("pulled-upload"), _legacyGame.ID, 0xdeadbeef)
+
+	// unpacked by hand
+	makeFolder("some-zip")
+
+	importLibrary := func() map[string]*butlerd.LegacyLibraryImportItem {
+		res, err := messages.SystemImportLegacyLibrary.TestCall(rc, butlerd.SystemImportLegacyLibraryParams{
+			RootPath:          root,
+			Mode:              butlerd.LegacyLibraryImportModeMove,
+			InstallLocationID: "tmp",
+		})
+		must(err)
+		items := make(map[string]*butlerd.LegacyLibraryImportItem)
+		for _, item := range res.Items {
+			items[filepath.Base(item.Folder)] = item
+		}
+		return items
+	}
+
+	items := importLibrary()
+	assert.Len(items, 4)
+
+	assert.EqualValues(butlerd.LegacyLibraryImportStatusImported, items["old-favorite"].Status)
+	assert.EqualValues(butlerd.LegacyLibraryImportStatusNeedsVerification, items["really-old-favorite"].Status, "legacy receipt has no file list")
+	assert.EqualValues("legacy-cave-id", items["really-old-favorite"].CaveID, "legacy cave IDs are kept")
+	assert.EqualValues(butlerd.LegacyLibraryImportStatusUnrecognized, items["pulled-upload"].Status)
+	assert.EqualValues(butlerd.LegacyLibraryImportStatusUnrecognized, items["some-zip"].Status)
+
+	for _, name := range []string{"old-favorite", "really-old-favorite"} {
+		item := items[name]
+		caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{CaveID: item.CaveID})
+		must(err)
+		assert.EqualValues(item.InstallFolder, caveRes.Cave.InstallInfo.InstallFolder)
+		_, err = os.Stat(filepath.Join(item.InstallFolder, "game.exe"))
+		assert.NoError(err, "%s was moved to the install location", name)
+		_, err = os.Stat(item.Folder)
+		assert.True(os.IsNotExist(err), "%s isn't in the root path anymore", name)
+	}
+	_, err = os.Stat(items["some-zip"].Folder)
+	assert.NoError(err, "unrecognized folders are left alone")
+
+	// running it again is harmless
+	again := importLibrary()
+	assert.Len(again, 4)
+	for name, item := range items {
+		assert.EqualValues(item, again[name])
+	}
+
+	cavesRes, err := messages.FetchCaves.TestCall(rc, butlerd.FetchCavesParams{})
+	must(err)
+	assert.Len(cavesRes.Items, 2)
+}
+
+func Test_ImportLegacyLibraryInPlace(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("John Doe")
+	_game := _developer.MakeGame("Stays Put")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContents()
+
+	root, err := ioutil.TempDir("", "legacy-library")
+	must(err)
+	defer os.RemoveAll(root)
+
+	folder := filepath.Join(root, "stays-put")
+	must(os.MkdirAll(folder, 0o755))
+	receipt := &bfs.Receipt{
+		Game:   bi.FetchGame(_game.ID),
+		Upload: bi.FetchUpload(_upload.ID),
+		Files:  []string{},
+	}
+	must(receipt.WriteReceipt(folder))
+
+	res, err := messages.SystemImportLegacyLibrary.TestCall(rc, butlerd.SystemImportLegacyLibraryParams{
+		RootPath: root,
+		Mode:     butlerd.LegacyLibraryImportModeInPlace,
+	})
+	must(err)
+	if assert.Len(res.Items, 1) {
+		item := res.Items[0]
+		assert.EqualValues(butlerd.LegacyLibraryImportStatusNeedsVerification, item.Status)
+		assert.EqualValues(folder, item.InstallFolder)
+
+		caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{CaveID: item.CaveID})
+		must(err)
+		assert.EqualValues(folder, caveRes.Cave.InstallInfo.InstallFolder)
+	}
+}
+
+func Test_ImportLegacyLibraryResume(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("John Doe")
+	_game := _developer.MakeGame("Interrupted")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContents()
+
+	wd, err := os.Getwd()
+	must(err)
+	root := filepath.Join(wd, "tmp-legacy-library")
+	must(os.RemoveAll(root))
+	must(os.MkdirAll(root, 0o755))
+	defer os.RemoveAll(root)
+
+	// a previous import moved the folder, then got interrupted
+	// before it could save the cave.
+	installFolder := filepath.Join(wd, "tmp", "interrupted")
+	must(os.MkdirAll(installFolder, 0o755))
+	receipt := &bfs.Receipt{
+		Game:   bi.FetchGame(_game.ID),
+		Upload: bi.FetchUpload(_upload.ID),
+		Files:  []string{},
+	}
+	must(receipt.WriteReceipt(installFolder))
+
+	state, err := json.Marshal(map[string]interface{}{
+		"items": map[string]interface{}{
+			"interrupted": map[string]interface{}{
+				"pending":       true,
+				"caveId":        "resumed-cave-id",
+				"installFolder": installFolder,
+			},
+		},
+	})
+	must(err)
+	must(ioutil.WriteFile(filepath.Join(root, ".butler-import.json"), state, 0o644))
+
+	res, err := messages.SystemImportLegacyLibrary.TestCall(rc, butlerd.SystemImportLegacyLibraryParams{
+		RootPath:          root,
+		Mode:              butlerd.LegacyLibraryImportModeMove,
+		InstallLocationID: "tmp",
+	})
+	must(err)
+	if assert.Len(res.Items, 1) {
+		item := res.Items[0]
+		assert.EqualValues(butlerd.LegacyLibraryImportStatusNeedsVerification, item.Status)
+		assert.EqualValues("resumed-cave-id", item.CaveID)
+		assert.EqualValues(installFolder, item.InstallFolder)
+
+		caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{CaveID: item.CaveID})
+		must(err)
+		assert.EqualValues(installFolder, caveRes.Cave.InstallInfo.InstallFolder)
+	}
+}
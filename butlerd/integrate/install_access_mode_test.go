@@ -0,0 +1,103 @@
+package integrate
+
+import (
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallAccessMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("access modes are ignored on Windows")
+	}
+
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Shared Sheep")
+	_game := _developer.MakeGame("Family Computer")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+		ac.Entry("readme.txt").String("Everybody gets a turn")
+	})
+
+	_, err := messages.InstallLocationsUpdate.TestCall(rc, butlerd.InstallLocationsUpdateParams{
+		ID:         "tmp",
+		AccessMode: "everyone-and-their-cat",
+	})
+	assert.Error(err, "unknown access modes are rejected")
+
+	updateRes, err := messages.InstallLocationsUpdate.TestCall(rc, butlerd.InstallLocationsUpdateParams{
+		ID:         "tmp",
+		AccessMode: butlerd.InstallLocationAccessModePrivate,
+	})
+	must(err)
+	assert.EqualValues(butlerd.InstallLocationAccessModePrivate, updateRes.InstallLocation.AccessMode)
+
+	game := bi.FetchGame(_game.ID)
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+	})
+	must(err)
+
+	_, err = messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+		ID:            queueRes.ID,
+		StagingFolder: queueRes.StagingFolder,
+	})
+	must(err)
+
+	folderPerm := func() os.FileMode {
+		stats, err := os.Stat(queueRes.InstallFolder)
+		must(err)
+		return stats.Mode().Perm()
+	}
+	assert.EqualValues(0o700, folderPerm(), "fresh install gets private permissions")
+
+	_, err = messages.InstallLocationsUpdate.TestCall(rc, butlerd.InstallLocationsUpdateParams{
+		ID:         "tmp",
+		AccessMode: butlerd.InstallLocationAccessModeGroup,
+	})
+	must(err)
+	assert.EqualValues(0o750, folderPerm(), "existing caves follow access mode changes")
+
+	// someone loosened things by hand, reinstalling enforces the mode again
+	must(os.Chmod(queueRes.InstallFolder, 0o777))
+	queueRes, err = messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		CaveID: queueRes.CaveID,
+		Reason: butlerd.DownloadReasonReinstall,
+	})
+	must(err)
+
+	_, err = messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+		ID:            queueRes.ID,
+		StagingFolder: queueRes.StagingFolder,
+	})
+	must(err)
+	assert.EqualValues(0o750, folderPerm(), "reinstall enforces access mode")
+
+	_, err = messages.InstallLocationsUpdate.TestCall(rc, butlerd.InstallLocationsUpdateParams{
+		ID: "tmp",
+	})
+	must(err)
+	must(os.Chmod(queueRes.InstallFolder, 0o711))
+	getRes, err := messages.InstallLocationsGetByID.TestCall(rc, butlerd.InstallLocationsGetByIDParams{
+		ID: "tmp",
+	})
+	must(err)
+	assert.EqualValues("", getRes.InstallLocation.AccessMode)
+	assert.EqualValues(0o711, folderPerm(), "unmanaged locations are left alone")
+}
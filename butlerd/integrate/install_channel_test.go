@@ -0,0 +1,85 @@
+package integrate
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/jsonrpc2"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallChannelName(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Release Engineer")
+	_game := _developer.MakeGame("Many Channels")
+	_game.Publish()
+	pushChannel := func(channelName string) *mitch.Upload {
+		_upload := _game.MakeUpload(channelName)
+		_upload.SetAllPlatforms()
+		_upload.ChannelName = channelName
+		_upload.PushBuild(func(ac *mitch.ArchiveContext) {
+			ac.SetName(channelName + ".zip")
+			ac.Entry("game.exe").String(channelName + " build")
+		})
+		return _upload
+	}
+	_stable := pushChannel("linux-stable")
+	pushChannel("linux-beta")
+
+	game := bi.FetchGame(_game.ID)
+
+	// nobody answers PickUpload, so this only works if it isn't asked
+	queued, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+		ChannelName:       "linux-stable",
+	})
+	must(err)
+	assert.EqualValues(_stable.ID, queued.Upload.ID)
+
+	_, err = messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+		ChannelName:       "windows",
+	})
+	assert.Error(err, "falls back to asking which upload to install")
+
+	_, err = messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+		ChannelRequired:   true,
+	})
+	assert.Error(err, "channelRequired needs a channelName")
+
+	_, err = messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+		ChannelName:       "windows",
+		ChannelRequired:   true,
+	})
+	je, ok := err.(*jsonrpc2.Error)
+	if assert.True(ok, "should be a jsonrpc2 error") {
+		assert.EqualValues(butlerd.CodeNoCompatibleUploads, je.Code)
+		if assert.NotNil(je.Data) {
+			var data struct {
+				Rejections []*butlerd.UploadRejection `json:"rejections"`
+			}
+			must(json.Unmarshal(*je.Data, &data))
+			assert.Len(data.Rejections, 2)
+			for _, r := range data.Rejections {
+				assert.EqualValues(butlerd.UploadExclusionChannel, r.Reason)
+			}
+		}
+	}
+}
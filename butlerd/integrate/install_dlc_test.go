@@ -0,0 +1,143 @@
+package integrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallDLC(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Season Pass Seller")
+	_game := _developer.MakeGame("Base Game")
+	_game.Publish()
+	_base := _game.MakeUpload("Base game")
+	_base.SetAllPlatforms()
+	_base.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+		ac.Entry("game.exe").String("base game")
+	})
+	_dlc := _game.MakeUpload("expansion-pack")
+	_dlc.SetAllPlatforms()
+	_dlc.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+		ac.SetName("expansion-pack.zip")
+		ac.Entry("levels/expansion.dat").String("more levels")
+	})
+	_soundtrack := _game.MakeUpload("Soundtrack")
+	_soundtrack.SetAllPlatforms()
+	_soundtrack.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+		ac.Entry("track01.ogg").String("la la la")
+	})
+
+	game := bi.FetchGame(_game.ID)
+	parent := bi.Install(butlerd.InstallQueueParams{
+		Game:              game,
+		Upload:            bi.FetchUpload(_base.ID),
+		InstallLocationID: "tmp",
+	})
+
+	_, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		Upload:            bi.FetchUpload(_dlc.ID),
+		InstallLocationID: "tmp",
+		UseParentFolder:   true,
+	})
+	assert.Error(err, "useParentFolder needs parentCaveId")
+
+	_, err = messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		CaveID:       parent.CaveID,
+		ParentCaveID: parent.CaveID,
+	})
+	assert.Error(err, "only new caves can be DLC")
+
+	// the install location comes from the parent cave
+	dlc := bi.Install(butlerd.InstallQueueParams{
+		Game:            game,
+		Upload:          bi.FetchUpload(_dlc.ID),
+		ParentCaveID:    parent.CaveID,
+		UseParentFolder: true,
+	})
+
+	fetchCave := func(caveID string) *butlerd.Cave {
+		caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+			CaveID: caveID,
+		})
+		must(err)
+		return caveRes.Cave
+	}
+	parentCave := fetchCave(parent.CaveID)
+	dlcCave := fetchCave(dlc.CaveID)
+	assert.EqualValues(parent.CaveID, dlcCave.InstallInfo.DLCParentCaveID)
+	assert.Empty(parentCave.InstallInfo.DLCParentCaveID)
+	assert.EqualValues("tmp", dlcCave.InstallInfo.InstallLocation)
+	assert.EqualValues(filepath.Join(parentCave.InstallInfo.InstallFolder, "expansion-pack"), dlcCave.InstallInfo.InstallFolder)
+	assert.FileExists(filepath.Join(dlcCave.InstallInfo.InstallFolder, "levels", "expansion.dat"))
+	assert.FileExists(filepath.Join(parentCave.InstallInfo.InstallFolder, "game.exe"))
+
+	// reinstalls stay inside the parent
+	bi.Install(butlerd.InstallQueueParams{
+		CaveID: dlc.CaveID,
+	})
+	assert.EqualValues(dlcCave.InstallInfo.InstallFolder, fetchCave(dlc.CaveID).InstallInfo.InstallFolder)
+
+	// DLC may also have a folder of its own
+	separate := bi.Install(butlerd.InstallQueueParams{
+		Game:              game,
+		Upload:            bi.FetchUpload(_soundtrack.ID),
+		InstallLocationID: "tmp",
+		ParentCaveID:      parent.CaveID,
+	})
+	separateCave := fetchCave(separate.CaveID)
+	assert.EqualValues(parent.CaveID, separateCave.InstallInfo.DLCParentCaveID)
+	assert.EqualValues(filepath.Dir(parentCave.InstallInfo.InstallFolder), filepath.Dir(separateCave.InstallInfo.InstallFolder))
+
+	dlcsRes, err := messages.CavesGetDLCs.TestCall(rc, butlerd.CavesGetDLCsParams{
+		CaveID: parent.CaveID,
+	})
+	must(err)
+	var dlcIDs []string
+	for _, c := range dlcsRes.Caves {
+		dlcIDs = append(dlcIDs, c.ID)
+	}
+	assert.ElementsMatch([]string{dlc.CaveID, separate.CaveID}, dlcIDs)
+
+	dlcsRes, err = messages.CavesGetDLCs.TestCall(rc, butlerd.CavesGetDLCsParams{
+		CaveID: dlc.CaveID,
+	})
+	must(err)
+	assert.Empty(dlcsRes.Caves)
+
+	// DLC in the parent's folder is uninstalled along with it
+	savePath := filepath.Join(dlcCave.InstallInfo.InstallFolder, "saves", "slot1.dat")
+	must(os.MkdirAll(filepath.Dir(savePath), 0o755))
+	must(ioutil.WriteFile(savePath, []byte("level 99"), 0o644))
+
+	_, err = messages.UninstallPerform.TestCall(rc, butlerd.UninstallPerformParams{
+		CaveID:           parent.CaveID,
+		PreserveUserData: []butlerd.UninstallFileCategory{butlerd.UninstallFileCategorySaves},
+	})
+	must(err)
+	for _, caveID := range []string{parent.CaveID, dlc.CaveID} {
+		caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+			CaveID: caveID,
+		})
+		must(err)
+		assert.Nil(caveRes.Cave, "(%s) is gone", caveID)
+	}
+	assert.NoFileExists(filepath.Join(parentCave.InstallInfo.InstallFolder, "game.exe"))
+	assert.NoFileExists(filepath.Join(dlcCave.InstallInfo.InstallFolder, "levels", "expansion.dat"))
+	assert.FileExists(savePath, "the DLC's saves are preserved")
+	assert.FileExists(filepath.Join(separateCave.InstallInfo.InstallFolder, "track01.ogg"), "DLC with a folder of its own stays")
+}
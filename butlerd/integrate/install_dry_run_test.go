@@ -0,0 +1,78 @@
+package integrate
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallDryRun(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Careful Planner")
+	_game := _developer.MakeGame("Measure Twice")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+		ac.Entry("game.exe").String("not really a game")
+		ac.Entry("data.pak").Random(0xfeedface, 1024*1024)
+	})
+
+	game := bi.FetchGame(_game.ID)
+
+	_, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+		DryRun:            true,
+		QueueDownload:     true,
+	})
+	assert.Error(err, "dry runs can't queue downloads")
+
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+		DryRun:            true,
+	})
+	must(err)
+
+	assert.EqualValues(_upload.ID, queueRes.Upload.ID)
+	assert.EqualValues("tmp", queueRes.InstallLocationID)
+	assert.NotEmpty(queueRes.InstallFolder)
+	assert.Empty(queueRes.CaveID)
+	assert.Empty(queueRes.StagingFolder)
+	assert.True(queueRes.EstimatedDownloadSize > 0)
+	assert.True(queueRes.EstimatedInstallSize >= 1024*1024, "the archive's contents are measured")
+
+	// nothing is left on disk, or in the database
+	stagingEntries, err := ioutil.ReadDir(filepath.Join(filepath.Dir(queueRes.InstallFolder), "downloads"))
+	if err == nil {
+		assert.Empty(stagingEntries)
+	}
+	assert.NoDirExists(queueRes.InstallFolder)
+
+	cavesRes, err := messages.FetchCaves.TestCall(rc, butlerd.FetchCavesParams{})
+	must(err)
+	assert.Empty(cavesRes.Items)
+
+	// the real thing still works afterwards
+	queueRes, err = messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+	})
+	must(err)
+	assert.NotEmpty(queueRes.StagingFolder)
+	assert.DirExists(queueRes.StagingFolder)
+}
@@ -0,0 +1,62 @@
+package integrate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/jsonrpc2"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallFolderExhausted(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Lab Admin")
+	_game := _developer.MakeGame("Shared Copies")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContents()
+
+	game := bi.FetchGame(_game.ID)
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+	})
+	must(err)
+	base := queueRes.InstallFolder
+
+	// a location already full of copies
+	must(os.MkdirAll(base, 0o755))
+	for i := 2; i <= 3; i++ {
+		must(os.MkdirAll(fmt.Sprintf("%s %d", base, i), 0o755))
+	}
+
+	_, err = messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:                 game,
+		InstallLocationID:    "tmp",
+		UniqueFolderMaxTries: 3,
+	})
+	je, ok := err.(*jsonrpc2.Error)
+	if assert.True(ok, "should be a jsonrpc2 error") {
+		assert.EqualValues(butlerd.CodeInstallFolderExhausted, je.Code)
+	}
+
+	queueRes, err = messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+	})
+	must(err)
+	assert.EqualValues(filepath.Base(base)+" 4", filepath.Base(queueRes.InstallFolder))
+}
@@ -0,0 +1,163 @@
+package integrate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallFolderNameTemplate(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Build Bot")
+	_game := _developer.MakeGame("Review Builds")
+	_game.Publish()
+	pushChannel := func(channelName string) {
+		_upload := _game.MakeUpload(channelName)
+		_upload.SetAllPlatforms()
+		_upload.ChannelName = channelName
+		_upload.PushBuild(func(ac *mitch.ArchiveContext) {
+			ac.SetName(channelName + ".zip")
+			ac.Entry("game.exe").String(channelName + " build")
+		})
+	}
+	pushChannel("review")
+	pushChannel("nightly")
+
+	tmpDir, err := ioutil.TempDir("", "folder-name-template-test")
+	must(err)
+	defer os.RemoveAll(tmpDir)
+
+	_, err = messages.InstallLocationsAdd.TestCall(rc, butlerd.InstallLocationsAddParams{
+		ID:                 "ci",
+		Path:               tmpDir,
+		FolderNameTemplate: "../{build_id}",
+	})
+	assert.Error(err, "templates can't step out of the location")
+
+	_, err = messages.InstallLocationsAdd.TestCall(rc, butlerd.InstallLocationsAddParams{
+		ID:   "ci",
+		Path: tmpDir,
+	})
+	must(err)
+
+	game := bi.FetchGame(_game.ID)
+	install := func(channelName string) *butlerd.InstallQueueResult {
+		queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+			Game:              game,
+			InstallLocationID: "ci",
+			ChannelName:       channelName,
+		})
+		must(err)
+		_, err = messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+			ID:            queueRes.ID,
+			StagingFolder: queueRes.StagingFolder,
+		})
+		must(err)
+		return queueRes
+	}
+
+	slugged := install("review")
+	sluggedFolderName := filepath.Base(slugged.InstallFolder)
+
+	updateRes, err := messages.InstallLocationsUpdate.TestCall(rc, butlerd.InstallLocationsUpdateParams{
+		ID:                 "ci",
+		FolderNameTemplate: "{game_id}-{channel}-{build_id}",
+	})
+	must(err)
+	assert.EqualValues("{game_id}-{channel}-{build_id}", updateRes.InstallLocation.FolderNameTemplate)
+
+	templated := install("nightly")
+	assert.EqualValues(filepath.Join(tmpDir, fmt.Sprintf("%d-nightly-%d", _game.ID, templated.Build.ID)), templated.InstallFolder)
+
+	// the existing cave keeps its name until asked
+	expectedFolderName := fmt.Sprintf("%d-review-%d", _game.ID, slugged.Build.ID)
+	assert.DirExists(slugged.InstallFolder)
+	applyRes, err := messages.CavesApplyNamingTemplate.TestCall(rc, butlerd.CavesApplyNamingTemplateParams{
+		InstallLocationID: "ci",
+		CaveIDs:           []string{slugged.CaveID},
+	})
+	must(err)
+	if assert.Len(applyRes.Renames, 1) {
+		rename := applyRes.Renames[0]
+		assert.EqualValues(slugged.CaveID, rename.CaveID)
+		assert.True(rename.Renamed)
+		assert.EqualValues(sluggedFolderName, rename.OldFolderName)
+		assert.EqualValues(expectedFolderName, rename.NewFolderName)
+		assert.NoDirExists(slugged.InstallFolder)
+		assert.FileExists(filepath.Join(tmpDir, rename.NewFolderName, "game.exe"))
+		assert.NoFileExists(filepath.Join(tmpDir, rename.NewFolderName, ".itch", "runlock.json"))
+	}
+
+	caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+		CaveID: slugged.CaveID,
+	})
+	must(err)
+	assert.EqualValues(filepath.Join(tmpDir, expectedFolderName), caveRes.Cave.InstallInfo.InstallFolder)
+
+	eventsRes, err := messages.CavesListEvents.TestCall(rc, butlerd.CavesListEventsParams{
+		CaveID: slugged.CaveID,
+	})
+	must(err)
+	if assert.Len(eventsRes.Events, 1) {
+		event := eventsRes.Events[0]
+		assert.EqualValues(butlerd.CaveEventTypeRenamed, event.Type)
+		assert.EqualValues(slugged.InstallFolder, event.FromInstallFolder)
+		assert.EqualValues(filepath.Join(tmpDir, expectedFolderName), event.ToInstallFolder)
+	}
+
+	applyRes, err = messages.CavesApplyNamingTemplate.TestCall(rc, butlerd.CavesApplyNamingTemplateParams{
+		InstallLocationID: "ci",
+	})
+	must(err)
+	assert.Len(applyRes.Renames, 2)
+	for _, rename := range applyRes.Renames {
+		assert.False(rename.Renamed, "both already follow the template")
+		assert.Empty(rename.Error)
+	}
+
+	// subfolders are made as needed...
+	_, err = messages.InstallLocationsUpdate.TestCall(rc, butlerd.InstallLocationsUpdateParams{
+		ID:                 "ci",
+		FolderNameTemplate: "{game_id}/{channel}",
+	})
+	must(err)
+	applyRes, err = messages.CavesApplyNamingTemplate.TestCall(rc, butlerd.CavesApplyNamingTemplateParams{
+		InstallLocationID: "ci",
+	})
+	must(err)
+	gameFolder := filepath.Join(tmpDir, fmt.Sprintf("%d", _game.ID))
+	for _, rename := range applyRes.Renames {
+		assert.True(rename.Renamed)
+		assert.Empty(rename.Error)
+		assert.EqualValues(gameFolder, filepath.Dir(filepath.Join(tmpDir, rename.NewFolderName)))
+	}
+	assert.FileExists(filepath.Join(gameFolder, fmt.Sprintf("nightly-%d", templated.Upload.ID), "game.exe"))
+
+	// ...and removed once they're empty
+	uninstall := func(caveID string) {
+		_, err := messages.UninstallPerform.TestCall(rc, butlerd.UninstallPerformParams{
+			CaveID: caveID,
+		})
+		must(err)
+	}
+	uninstall(slugged.CaveID)
+	assert.DirExists(gameFolder, "still has the other cave")
+	uninstall(templated.CaveID)
+	assert.NoDirExists(gameFolder)
+	assert.DirExists(tmpDir)
+}
@@ -0,0 +1,89 @@
+package integrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallFolderNameOverride(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Laura")
+	_game := _developer.MakeGame("Consoles")
+	_game.Publish()
+	pushChannel := func(channelName string) {
+		_upload := _game.MakeUpload(channelName)
+		_upload.SetAllPlatforms()
+		_upload.ChannelName = channelName
+		_upload.PushBuild(func(ac *mitch.ArchiveContext) {
+			ac.SetName(channelName + ".zip")
+			ac.Entry("game.exe").String(channelName + " build")
+		})
+	}
+	pushChannel("stable")
+	pushChannel("beta")
+
+	tmpDir, err := ioutil.TempDir("", "install-folder-name-test")
+	must(err)
+	defer os.RemoveAll(tmpDir)
+
+	_, err = messages.InstallLocationsAdd.TestCall(rc, butlerd.InstallLocationsAddParams{
+		ID:   "custom",
+		Path: tmpDir,
+	})
+	must(err)
+
+	game := bi.FetchGame(_game.ID)
+
+	_, err = messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "custom",
+		InstallFolderName: "../..",
+	})
+	assert.Error(err, "nothing usable left")
+
+	install := func(channelName string) *butlerd.InstallQueueResult {
+		queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+			Game:              game,
+			InstallLocationID: "custom",
+			ChannelName:       channelName,
+			InstallFolderName: "CON",
+		})
+		must(err)
+		_, err = messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+			ID:            queueRes.ID,
+			StagingFolder: queueRes.StagingFolder,
+		})
+		must(err)
+		return queueRes
+	}
+
+	stable := install("stable")
+	assert.EqualValues("CON_", stable.InstallFolderName)
+	assert.EqualValues(filepath.Join(tmpDir, "CON_"), stable.InstallFolder)
+	assert.FileExists(filepath.Join(stable.InstallFolder, "game.exe"))
+
+	beta := install("beta")
+	assert.EqualValues("CON_ 2", beta.InstallFolderName, "still made unique")
+	assert.EqualValues(filepath.Join(tmpDir, "CON_ 2"), beta.InstallFolder)
+
+	_, err = messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		CaveID:            stable.CaveID,
+		InstallFolderName: "elsewhere",
+	})
+	assert.Error(err, "existing caves keep their folder")
+}
@@ -0,0 +1,61 @@
+package integrate
+
+import (
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallIgnoreInstallers(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Portable Person")
+	_game := _developer.MakeGame("Runs From Anywhere")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	// no installer handles that extension, so it can only
+	// be installed by ignoring installers
+	_upload.SetHostedContents("game.itchportable", []byte("not really a game"))
+
+	game := bi.FetchGame(_game.ID)
+	ignoreInstallers := true
+	queueRes := bi.Install(butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+		IgnoreInstallers:  &ignoreInstallers,
+	})
+	caveID := queueRes.CaveID
+
+	ignoresInstallers := func() bool {
+		caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+			CaveID: caveID,
+		})
+		must(err)
+		return caveRes.Cave.InstallInfo.IgnoreInstallers
+	}
+	assert.True(ignoresInstallers(), "recorded on the cave")
+
+	// would fail with "no manager for installer unknown" if the
+	// cave didn't remember it
+	bi.Install(butlerd.InstallQueueParams{
+		CaveID: caveID,
+	})
+	assert.True(ignoresInstallers(), "kept when not set")
+
+	_upload.SetZipContents()
+	ignoreInstallers = false
+	bi.Install(butlerd.InstallQueueParams{
+		CaveID:           caveID,
+		IgnoreInstallers: &ignoreInstallers,
+	})
+	assert.False(ignoresInstallers(), "cleared when set to false")
+}
@@ -0,0 +1,135 @@
+package integrate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallLocalArchive(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	// no profile: kiosks may never have logged in
+	dir, err := ioutil.TempDir("", "local-archive")
+	must(err)
+	defer os.RemoveAll(dir)
+
+	makeArchive := func(name string) string {
+		archivePath := filepath.Join(dir, name)
+		f, err := os.Create(archivePath)
+		must(err)
+		zw := zip.NewWriter(f)
+		w, err := zw.Create("index.html")
+		must(err)
+		_, err = w.Write([]byte("<p>Welcome to the kiosk</p>"))
+		must(err)
+		must(zw.Close())
+		must(f.Close())
+		return archivePath
+	}
+
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		InstallLocationID: "tmp",
+		LocalArchivePath:  makeArchive("Kiosk Game.zip"),
+	})
+	must(err)
+	assert.True(queueRes.Game.ID < 0, "made-up games have negative IDs")
+	assert.True(queueRes.Upload.ID < 0, "made-up uploads have negative IDs")
+	assert.EqualValues("Kiosk Game", queueRes.Game.Title)
+	assert.Nil(queueRes.Build)
+
+	performRes, err := messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+		ID:            queueRes.ID,
+		StagingFolder: queueRes.StagingFolder,
+	})
+	must(err)
+
+	caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+		CaveID: performRes.CaveID,
+	})
+	must(err)
+	cave := caveRes.Cave
+	assert.EqualValues(queueRes.Game.ID, cave.Game.ID)
+	assert.EqualValues("Kiosk Game", cave.Game.Title)
+	assert.EqualValues("Kiosk Game.zip", cave.Upload.Filename)
+	assert.EqualValues(operate.UploadStorageLocal, cave.Upload.Storage)
+	assert.True(cave.InstallInfo.Pinned, "nothing to update local installs from")
+	assert.FileExists(filepath.Join(cave.InstallInfo.InstallFolder, "index.html"))
+
+	launched := false
+	messages.HTMLLaunch.TestRegister(h, func(rc *butlerd.RequestContext, params butlerd.HTMLLaunchParams) (*butlerd.HTMLLaunchResult, error) {
+		launched = true
+		return &butlerd.HTMLLaunchResult{}, nil
+	})
+	_, err = messages.Launch.TestCall(rc, butlerd.LaunchParams{
+		CaveID:     cave.ID,
+		PrereqsDir: "/tmp/prereqs",
+	})
+	must(err)
+	assert.True(launched)
+
+	_, err = messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		InstallLocationID: "tmp",
+		LocalArchivePath:  filepath.Join(dir, "nope.zip"),
+	})
+	assert.Error(err, "local archive must exist")
+
+	otherRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		InstallLocationID: "tmp",
+		LocalArchivePath:  makeArchive("other.zip"),
+	})
+	must(err)
+	assert.True(otherRes.Game.ID < queueRes.Game.ID, "each local archive gets its own game")
+	assert.True(otherRes.Upload.ID < queueRes.Upload.ID, "each local archive gets its own upload")
+
+	dottedRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		InstallLocationID: "tmp",
+		LocalArchivePath:  makeArchive("Kiosk.Game.v1.2.zip"),
+	})
+	must(err)
+	assert.EqualValues("Kiosk.Game.v1.2", dottedRes.Game.Title, "only archive extensions are stripped")
+
+	tarballPath := filepath.Join(dir, "Tarball Game.tar.gz")
+	{
+		f, err := os.Create(tarballPath)
+		must(err)
+		gw := gzip.NewWriter(f)
+		tw := tar.NewWriter(gw)
+		contents := []byte("<p>Unpacked from a tarball</p>")
+		must(tw.WriteHeader(&tar.Header{
+			Name: "index.html",
+			Mode: 0o644,
+			Size: int64(len(contents)),
+		}))
+		_, err = tw.Write(contents)
+		must(err)
+		must(tw.Close())
+		must(gw.Close())
+		must(f.Close())
+	}
+	tarballRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		InstallLocationID: "tmp",
+		LocalArchivePath:  tarballPath,
+	})
+	must(err)
+	assert.EqualValues("Tarball Game", tarballRes.Game.Title)
+	_, err = messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+		ID:            tarballRes.ID,
+		StagingFolder: tarballRes.StagingFolder,
+	})
+	must(err)
+	assert.FileExists(filepath.Join(tarballRes.InstallFolder, "index.html"))
+}
@@ -0,0 +1,159 @@
+package integrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/jsonrpc2"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallLocations(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Disk Juggler")
+	_game := _developer.MakeGame("Portable")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContents()
+
+	tmpDir, err := ioutil.TempDir("", "install-locations-test")
+	must(err)
+	defer os.RemoveAll(tmpDir)
+
+	tmpRes, err := messages.InstallLocationsGetByID.TestCall(rc, butlerd.InstallLocationsGetByIDParams{
+		ID: "tmp",
+	})
+	must(err)
+	tmpPath := tmpRes.InstallLocation.Path
+
+	nestedPath := filepath.Join(tmpPath, "nested")
+	must(os.MkdirAll(nestedPath, 0o755))
+	_, err = messages.InstallLocationsAdd.TestCall(rc, butlerd.InstallLocationsAddParams{
+		Path: nestedPath,
+	})
+	assert.Error(err, "locations can't be inside of each other")
+	_, err = messages.InstallLocationsAdd.TestCall(rc, butlerd.InstallLocationsAddParams{
+		Path: filepath.Dir(tmpPath),
+	})
+	assert.Error(err, "locations can't contain each other")
+	_, err = messages.InstallLocationsAdd.TestCall(rc, butlerd.InstallLocationsAddParams{
+		Path: filepath.Join(tmpDir, "does-not-exist"),
+	})
+	assert.Error(err, "locations must exist")
+
+	addRes, err := messages.InstallLocationsAdd.TestCall(rc, butlerd.InstallLocationsAddParams{
+		Path: tmpDir,
+	})
+	must(err)
+	otherID := addRes.InstallLocation.ID
+	assert.NotEmpty(otherID, "IDs are generated")
+
+	addRes, err = messages.InstallLocationsAdd.TestCall(rc, butlerd.InstallLocationsAddParams{
+		ID:   otherID,
+		Path: tmpDir,
+	})
+	must(err)
+	assert.EqualValues(otherID, addRes.InstallLocation.ID, "adding the same location twice does nothing")
+	_, err = messages.InstallLocationsAdd.TestCall(rc, butlerd.InstallLocationsAddParams{
+		ID:   otherID,
+		Path: nestedPath,
+	})
+	assert.Error(err, "existing locations keep their path")
+
+	caveID := bi.Install(butlerd.InstallQueueParams{
+		Game:              bi.FetchGame(_game.ID),
+		InstallLocationID: "tmp",
+	}).CaveID
+	caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+		CaveID: caveID,
+	})
+	must(err)
+	installFolder := caveRes.Cave.InstallInfo.InstallFolder
+
+	_, err = messages.InstallLocationsRemove.TestCall(rc, butlerd.InstallLocationsRemoveParams{
+		ID: "tmp",
+	})
+	je, ok := err.(*jsonrpc2.Error)
+	if assert.True(ok, "should be a jsonrpc2 error") {
+		assert.EqualValues(butlerd.CodeInstallLocationNotEmpty, je.Code)
+	}
+
+	_, err = messages.InstallLocationsRemove.TestCall(rc, butlerd.InstallLocationsRemoveParams{
+		ID:    "tmp",
+		Force: true,
+	})
+	assert.Error(err, "force needs a fallback")
+
+	_, err = messages.InstallLocationsRemove.TestCall(rc, butlerd.InstallLocationsRemoveParams{
+		ID:         "tmp",
+		Force:      true,
+		FallbackID: otherID,
+	})
+	must(err)
+
+	caveRes, err = messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+		CaveID: caveID,
+	})
+	must(err)
+	assert.EqualValues(otherID, caveRes.Cave.InstallInfo.InstallLocation)
+	assert.EqualValues(installFolder, caveRes.Cave.InstallInfo.InstallFolder, "files stay where they are")
+	assert.DirExists(installFolder)
+
+	_, err = messages.InstallLocationsGetByID.TestCall(rc, butlerd.InstallLocationsGetByIDParams{
+		ID: "tmp",
+	})
+	assert.Error(err)
+}
+
+func Test_InstallLocationsAddInfersMetadata(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	tmpDir, err := ioutil.TempDir("", "install-locations-metadata-test")
+	must(err)
+	defer os.RemoveAll(tmpDir)
+	tmpDir, err = filepath.EvalSymlinks(tmpDir)
+	must(err)
+
+	realPath := filepath.Join(tmpDir, "Games Disk")
+	must(os.MkdirAll(realPath, 0o755))
+	linkPath := filepath.Join(tmpDir, "link")
+	must(os.Symlink(realPath, linkPath))
+
+	addRes, err := messages.InstallLocationsAdd.TestCall(rc, butlerd.InstallLocationsAddParams{
+		Path: linkPath,
+	})
+	must(err)
+	il := addRes.InstallLocation
+	assert.NotEmpty(il.ID)
+	assert.EqualValues(realPath, il.Path, "symlinks are resolved")
+	assert.EqualValues("Games Disk", il.Label)
+
+	_, err = messages.InstallLocationsAdd.TestCall(rc, butlerd.InstallLocationsAddParams{
+		Path: realPath,
+	})
+	assert.Error(err, "adding the same folder through another path overlaps")
+
+	filePath := filepath.Join(tmpDir, "not-a-folder")
+	must(ioutil.WriteFile(filePath, []byte("hi"), 0o644))
+	_, err = messages.InstallLocationsAdd.TestCall(rc, butlerd.InstallLocationsAddParams{
+		Path: filePath,
+	})
+	assert.Error(err, "files can't be install locations")
+}
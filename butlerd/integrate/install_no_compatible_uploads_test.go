@@ -0,0 +1,60 @@
+package integrate
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/jsonrpc2"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallNoCompatibleUploads(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Picky Developer")
+	_game := _developer.MakeGame("Nothing For You")
+	_game.Publish()
+	_windows := _game.MakeUpload("Windows only")
+	_windows.PlatformWindows = true
+	_windows.SetZipContents()
+	_deb := _game.MakeUpload("Debian package")
+	_deb.PlatformLinux = true
+	_deb.SetHostedContents("game.deb", []byte("not really a package"))
+
+	game := bi.FetchGame(_game.ID)
+
+	_, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+	})
+	assert.Error(err)
+
+	je, ok := err.(*jsonrpc2.Error)
+	if assert.True(ok, "should be a jsonrpc2 error") {
+		assert.EqualValues(butlerd.CodeNoCompatibleUploads, je.Code)
+		if assert.NotNil(je.Data) {
+			var data struct {
+				Rejections []*butlerd.UploadRejection `json:"rejections"`
+			}
+			must(json.Unmarshal(*je.Data, &data))
+
+			reasons := make(map[int64]butlerd.UploadExclusion)
+			for _, r := range data.Rejections {
+				reasons[r.Upload.ID] = r.Reason
+			}
+			assert.EqualValues(map[int64]butlerd.UploadExclusion{
+				_windows.ID: butlerd.UploadExclusionPlatform,
+				_deb.ID:     butlerd.UploadExclusionFormat,
+			}, reasons)
+		}
+	}
+}
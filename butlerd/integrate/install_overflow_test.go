@@ -0,0 +1,172 @@
+package integrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/hush"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallOverflow(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Small SSD Owner")
+	_game := _developer.MakeGame("Huge Textures")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.ChannelName = "default"
+	_build1 := _upload.PushBuild(func(ac *mitch.ArchiveContext) {
+		ac.SetName("default.zip")
+		ac.Entry("game.exe").String("not really a game")
+		ac.Entry("data/textures.pak").Random(0xfeed, 9*1024*1024)
+	})
+
+	tmpDir, err := ioutil.TempDir("", "overflow-test")
+	must(err)
+	defer os.RemoveAll(tmpDir)
+
+	_, err = messages.InstallLocationsAdd.TestCall(rc, butlerd.InstallLocationsAddParams{
+		ID:   "second",
+		Path: tmpDir,
+	})
+	must(err)
+
+	game := bi.FetchGame(_game.ID)
+	_, err = messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:               game,
+		InstallLocationID:  "tmp",
+		OverflowLocationID: "nope",
+	})
+	assert.Error(err, "overflow location must exist")
+
+	caveID := bi.Install(butlerd.InstallQueueParams{
+		Game:               game,
+		InstallLocationID:  "tmp",
+		OverflowLocationID: "second",
+	}).CaveID
+
+	fetchCave := func() *butlerd.Cave {
+		caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+			CaveID: caveID,
+		})
+		must(err)
+		return caveRes.Cave
+	}
+
+	cave := fetchCave()
+	installFolder := cave.InstallInfo.InstallFolder
+	overflowFolder := filepath.Join(tmpDir, "overflow", caveID)
+	texturesPath := filepath.Join(installFolder, "data", "textures.pak")
+
+	stats, err := os.Lstat(texturesPath)
+	must(err)
+	assert.True(stats.Mode()&os.ModeSymlink != 0, "large files are symlinked back")
+	assert.FileExists(filepath.Join(overflowFolder, "data", "textures.pak"))
+	stats, err = os.Lstat(filepath.Join(installFolder, "game.exe"))
+	must(err)
+	assert.True(stats.Mode().IsRegular(), "executables stay in the install folder")
+
+	if assert.Len(cave.InstallInfo.LocationUsage, 2) {
+		assert.EqualValues("tmp", cave.InstallInfo.LocationUsage[0].InstallLocationID)
+		assert.EqualValues("second", cave.InstallInfo.LocationUsage[1].InstallLocationID)
+		assert.EqualValues(9*1024*1024, cave.InstallInfo.LocationUsage[1].Bytes)
+	}
+
+	rebuildRes, err := messages.CavesRebuildReceipt.TestCall(rc, butlerd.CavesRebuildReceiptParams{
+		CaveID: caveID,
+	})
+	must(err)
+	assert.True(rebuildRes.Rebuild.CheckedSignature)
+	for _, f := range rebuildRes.Rebuild.Files {
+		assert.EqualValues(butlerd.ReceiptFileStatusOfficial, f.Status, "(%s) is checked where it really is", f.Path)
+	}
+
+	_, err = messages.InstallLocationsRemove.TestCall(rc, butlerd.InstallLocationsRemoveParams{
+		ID: "second",
+	})
+	assert.Error(err, "overflow locations in use can't be removed")
+
+	// reinstalls keep the cave split
+	bi.Install(butlerd.InstallQueueParams{
+		CaveID: caveID,
+	})
+	stats, err = os.Lstat(texturesPath)
+	must(err)
+	assert.True(stats.Mode()&os.ModeSymlink != 0)
+	assert.Len(fetchCave().InstallInfo.LocationUsage, 2)
+
+	// upgrades patch files where they are
+	_build2 := _upload.PushBuild(func(ac *mitch.ArchiveContext) {
+		ac.SetName("default.zip")
+		ac.Entry("game.exe").String("not really a game")
+		ac.Entry("data/textures.pak").Random(0xfeed, 10*1024*1024)
+	})
+	upgradeRes := bi.Install(butlerd.InstallQueueParams{
+		CaveID: caveID,
+		Build:  bi.FetchBuild(_build2.ID),
+	})
+	bi.FindEvent(upgradeRes.Events, hush.InstallEventPatching)
+	stats, err = os.Lstat(texturesPath)
+	must(err)
+	assert.True(stats.Mode()&os.ModeSymlink != 0, "patched files stay in the overflow location")
+	if usage := fetchCave().InstallInfo.LocationUsage; assert.Len(usage, 2) {
+		assert.EqualValues(10*1024*1024, usage[1].Bytes)
+	}
+
+	// so do heals
+	healRes := bi.Install(butlerd.InstallQueueParams{
+		CaveID: caveID,
+		Build:  bi.FetchBuild(_build1.ID),
+	})
+	bi.FindEvent(healRes.Events, hush.InstallEventHeal)
+	stats, err = os.Lstat(texturesPath)
+	must(err)
+	assert.True(stats.Mode()&os.ModeSymlink != 0, "healed files go back to the overflow location")
+	if usage := fetchCave().InstallInfo.LocationUsage; assert.Len(usage, 2) {
+		assert.EqualValues(9*1024*1024, usage[1].Bytes)
+	}
+	rebuildRes, err = messages.CavesRebuildReceipt.TestCall(rc, butlerd.CavesRebuildReceiptParams{
+		CaveID: caveID,
+	})
+	must(err)
+	for _, f := range rebuildRes.Rebuild.Files {
+		assert.EqualValues(butlerd.ReceiptFileStatusOfficial, f.Status, "(%s) was healed", f.Path)
+	}
+
+	// no cave can take the overflow folder
+	_other := _developer.MakeGame("Overflow")
+	_other.Publish()
+	_otherUpload := _other.MakeUpload("All platforms")
+	_otherUpload.SetAllPlatforms()
+	_otherUpload.SetZipContents()
+	dryRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              bi.FetchGame(_other.ID),
+		InstallLocationID: "second",
+		InstallFolderName: "Overflow",
+		DryRun:            true,
+	})
+	must(err)
+	assert.NotEqual("overflow", strings.ToLower(dryRes.InstallFolderName))
+
+	_, err = messages.UninstallPerform.TestCall(rc, butlerd.UninstallPerformParams{
+		CaveID: caveID,
+	})
+	must(err)
+	assert.NoDirExists(installFolder)
+	assert.NoDirExists(overflowFolder)
+}
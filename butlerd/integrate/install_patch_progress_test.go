@@ -0,0 +1,99 @@
+package integrate
+
+import (
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/hush"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallPatchProgress(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("John Doe")
+	_game := _developer.MakeGame("Patchy Game")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+
+	_upload.PushBuild(func(ac *mitch.ArchiveContext) {
+		ac.Entry("game.exe").Random(0x1, 4096)
+		ac.Entry("data/levels.pak").Random(0x2, 4096)
+		ac.Entry("data/old.pak").Random(0x3, 1024)
+	})
+
+	game := bi.FetchGame(_game.ID)
+	caveID := bi.Install(butlerd.InstallQueueParams{
+		Game: game,
+	}).CaveID
+
+	var notifs []butlerd.InstallPatchProgressNotification
+	messages.InstallPatchProgress.Register(h, func(params butlerd.InstallPatchProgressNotification) {
+		notifs = append(notifs, params)
+	})
+
+	upgrade := func(_build *mitch.Build, patchProgress bool) *butlerd.InstallPerformResult {
+		queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+			Game:              game,
+			CaveID:            caveID,
+			Upload:            bi.FetchUpload(_upload.ID),
+			Build:             bi.FetchBuild(_build.ID),
+			InstallLocationID: "tmp",
+		})
+		must(err)
+
+		res, err := messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+			ID:            queueRes.ID,
+			StagingFolder: queueRes.StagingFolder,
+			PatchProgress: patchProgress,
+		})
+		must(err)
+		return res
+	}
+
+	bi.Logf("Pushing second build...")
+	_build2 := _upload.PushBuild(func(ac *mitch.ArchiveContext) {
+		ac.Entry("game.exe").Random(0x4, 4096)
+		ac.Entry("data/levels-v2.pak").Random(0x2, 4096)
+		ac.Entry("data/new.pak").Random(0x5, 1024)
+	})
+
+	res := upgrade(_build2, false)
+	assert.Len(bi.FindEvents(res.Events, hush.InstallEventPatching), 1)
+	assert.Empty(notifs, "no detailed progress unless asked for")
+
+	bi.Logf("Pushing third build...")
+	_build3 := _upload.PushBuild(func(ac *mitch.ArchiveContext) {
+		ac.Entry("game.exe").Random(0x6, 4096)
+		ac.Entry("data/levels-v3.pak").Random(0x2, 4096)
+	})
+
+	res = upgrade(_build3, true)
+	assert.Len(bi.FindEvents(res.Events, hush.InstallEventPatching), 1)
+	if assert.NotEmpty(notifs) {
+		last := notifs[len(notifs)-1]
+		// game.exe, levels-v3.pak, and new.pak which is deleted
+		assert.EqualValues(3, last.OperationsTotal)
+		assert.EqualValues(last.OperationsTotal, last.OperationsDone)
+		for _, n := range notifs {
+			assert.NotEmpty(n.ID)
+			assert.True(n.OperationsDone <= n.OperationsTotal)
+			assert.True(n.FileBytesDone <= n.FileBytesTotal)
+		}
+	}
+
+	bi.InstallAndVerify(butlerd.InstallQueueParams{
+		Game:   game,
+		CaveID: caveID,
+	})
+}
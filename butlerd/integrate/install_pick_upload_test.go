@@ -0,0 +1,159 @@
+package integrate
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/jsonrpc2"
+	"github.com/itchio/butler/butlerd/messages"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallPickUploadStrategy(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Headless Publisher")
+	_game := _developer.MakeGame("Two Flavors")
+	_game.Publish()
+	_plain := _game.MakeUpload("Plain zip")
+	_plain.SetAllPlatforms()
+	_plain.SetZipContents()
+	_wharf := _game.MakeUpload("Wharf channel")
+	_wharf.SetAllPlatforms()
+	_wharf.ChannelName = "default"
+	_wharf.PushBuild(func(ac *mitch.ArchiveContext) {
+		ac.SetName("default.zip")
+		ac.Entry("game.exe").String("wharf build")
+	})
+
+	game := bi.FetchGame(_game.ID)
+
+	// nobody answers PickUpload, and there's no strategy: fail like before
+	_, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+	})
+	assert.Error(err)
+
+	_, err = messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:                  game,
+		InstallLocationID:     "tmp",
+		DefaultUploadStrategy: butlerd.DefaultUploadStrategyAbort,
+	})
+	if assert.Error(err) {
+		je, ok := err.(*jsonrpc2.Error)
+		if assert.True(ok) {
+			assert.EqualValues(butlerd.CodeOperationAborted, je.Code)
+		}
+	}
+
+	queued, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:                  game,
+		InstallLocationID:     "tmp",
+		DefaultUploadStrategy: butlerd.DefaultUploadStrategyPreferWharf,
+	})
+	must(err)
+	assert.EqualValues(_wharf.ID, queued.Upload.ID)
+	assert.NotNil(queued.Build)
+
+	_, err = messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:                  game,
+		InstallLocationID:     "tmp",
+		DefaultUploadStrategy: "whatever",
+	})
+	assert.Error(err, "unknown strategies are rejected")
+
+	// the client is there, but never answers
+	unblock := make(chan struct{})
+	defer close(unblock)
+	messages.PickUpload.TestRegister(h, func(rc *butlerd.RequestContext, params butlerd.PickUploadParams) (*butlerd.PickUploadResult, error) {
+		<-unblock
+		return &butlerd.PickUploadResult{Index: -1}, nil
+	})
+
+	start := time.Now()
+	queued, err = messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:                     game,
+		InstallLocationID:        "tmp",
+		DefaultUploadStrategy:    butlerd.DefaultUploadStrategyFirst,
+		PickUploadTimeoutSeconds: 1,
+	})
+	must(err)
+	assert.NotNil(queued.Upload)
+	assert.True(time.Since(start) >= time.Second, "waits for the client first")
+}
+
+func Test_InstallPickUploadBySize(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Headless Publisher")
+	_game := _developer.MakeGame("Three Sizes")
+	_game.Publish()
+	makeUpload := func(name string, size int64) *mitch.Upload {
+		_upload := _game.MakeUpload(name)
+		_upload.SetAllPlatforms()
+		_upload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+			ac.Entry("game.dat").Random(size, size)
+		})
+		return _upload
+	}
+	makeUpload("Medium", 64*1024)
+	_large := makeUpload("Large", 256*1024)
+	_small := makeUpload("Small", 1024)
+
+	game := bi.FetchGame(_game.ID)
+
+	for _, tc := range []struct {
+		strategy butlerd.DefaultUploadStrategy
+		upload   *mitch.Upload
+	}{
+		{butlerd.DefaultUploadStrategyLargest, _large},
+		{butlerd.DefaultUploadStrategySmallest, _small},
+	} {
+		queued, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+			Game:                  game,
+			InstallLocationID:     "tmp",
+			DefaultUploadStrategy: tc.strategy,
+			DryRun:                true,
+		})
+		must(err)
+		assert.EqualValues(tc.upload.ID, queued.Upload.ID, "%s", tc.strategy)
+	}
+
+	_, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:                  game,
+		InstallLocationID:     "tmp",
+		DefaultUploadStrategy: butlerd.DefaultUploadStrategyFail,
+	})
+	if assert.Error(err) {
+		je, ok := err.(*jsonrpc2.Error)
+		if assert.True(ok) {
+			assert.EqualValues(butlerd.CodeAmbiguousUpload, je.Code)
+			if assert.NotNil(je.Data) {
+				var data struct {
+					Uploads []*itchio.Upload `json:"uploads"`
+				}
+				must(json.Unmarshal(*je.Data, &data))
+				assert.Len(data.Uploads, 3)
+			}
+		}
+	}
+}
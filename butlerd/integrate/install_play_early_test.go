@@ -0,0 +1,142 @@
+package integrate
+
+import (
+	"archive/zip"
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallPlayEarly(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	// large enough for reads of the central directory not to go through it
+	const musicSize = 2 * 1024 * 1024
+
+	_developer := bi.Server.Store().MakeUser("Impatient Player")
+	_game := _developer.MakeGame("Big Soundtrack")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	// the soundtrack has to come last, which mitch archives don't promise
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	addEntry := func(name string, contents []byte) {
+		w, err := zw.Create(name)
+		must(err)
+		_, err = w.Write(contents)
+		must(err)
+	}
+	addEntry(".itch.toml", []byte("[[optional]]\nname = \"soundtrack\"\npaths = [\"music\"]\n"))
+	addEntry("game.exe", []byte("not really a game"))
+	music := make([]byte, musicSize)
+	_, err := rand.New(rand.NewSource(0xbeef)).Read(music)
+	must(err)
+	addEntry("music/theme.ogg", music)
+	must(zw.Close())
+	_upload.SetHostedContents("soundtrack.zip", zipBuf.Bytes())
+
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              bi.FetchGame(_game.ID),
+		InstallLocationID: "tmp",
+		PlayEarly:         true,
+	})
+	must(err)
+
+	// slow enough for the soundtrack to take a while
+	_, err = messages.DownloadsQueue.TestCall(rc, butlerd.DownloadsQueueParams{
+		Item:                    queueRes,
+		BandwidthBytesPerSecond: 256 * 1024,
+	})
+	must(err)
+
+	finished := make(chan string, 1)
+	messages.DownloadsDriveErrored.Register(h, func(params butlerd.DownloadsDriveErroredNotification) {
+		finished <- ""
+	})
+	messages.DownloadsDriveFinished.Register(h, func(params butlerd.DownloadsDriveFinishedNotification) {
+		finished <- params.Download.ID
+	})
+
+	driveDone := make(chan error, 1)
+	go func() {
+		_, err := messages.DownloadsDrive.TestCall(rc, butlerd.DownloadsDriveParams{})
+		driveDone <- err
+	}()
+
+	fetchCave := func() *butlerd.Cave {
+		res, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+			CaveID: queueRes.CaveID,
+		})
+		must(err)
+		return res.Cave
+	}
+	verify := func() *butlerd.CaveVerifyResult {
+		res, err := messages.CaveVerify.TestCall(rc, butlerd.CaveVerifyParams{
+			CaveID: queueRes.CaveID,
+		})
+		must(err)
+		return res
+	}
+
+	var cave *butlerd.Cave
+	if !assert.Eventually(func() bool {
+		cave = fetchCave()
+		return cave != nil
+	}, 20*time.Second, 100*time.Millisecond, "cave is saved before the download is done") {
+		return
+	}
+	assert.True(cave.InstallInfo.PlayableEarly)
+	assert.EqualValues(musicSize, cave.InstallInfo.RemainingBytes)
+	assert.FileExists(filepath.Join(cave.InstallInfo.InstallFolder, "game.exe"))
+
+	verifyRes := verify()
+	assert.True(verifyRes.PlayableEarly)
+	assert.EqualValues(butlerd.CaveVerifyMethodArchive, verifyRes.Method)
+	assert.Empty(verifyRes.Missing, "the soundtrack isn't missing, it's still being installed")
+	assert.Empty(verifyRes.Added)
+	assert.Empty(verifyRes.Corrupted)
+
+	_, err = messages.DownloadsSetBandwidth.TestCall(rc, butlerd.DownloadsSetBandwidthParams{
+		DownloadID: queueRes.ID,
+	})
+	must(err)
+
+	select {
+	case id := <-finished:
+		assert.EqualValues(queueRes.ID, id)
+	case <-time.After(20 * time.Second):
+		must(errors.New("timed out waiting for the download to finish"))
+	}
+
+	cave = fetchCave()
+	assert.False(cave.InstallInfo.PlayableEarly, "fully installed")
+	assert.EqualValues(0, cave.InstallInfo.RemainingBytes)
+	stats, err := os.Stat(filepath.Join(cave.InstallInfo.InstallFolder, "music", "theme.ogg"))
+	must(err)
+	assert.EqualValues(musicSize, stats.Size())
+
+	verifyRes = verify()
+	assert.False(verifyRes.PlayableEarly)
+	assert.Empty(verifyRes.Missing)
+	assert.Empty(verifyRes.Added)
+	assert.Empty(verifyRes.Corrupted)
+
+	_, err = messages.DownloadsDriveCancel.TestCall(rc, butlerd.DownloadsDriveCancelParams{})
+	must(err)
+	must(<-driveDone)
+}
@@ -0,0 +1,105 @@
+package integrate
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/jsonrpc2"
+	"github.com/itchio/butler/butlerd/messages"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallPreferredBuild(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Speedrunner")
+	_game := _developer.MakeGame("Frame Perfect")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.ChannelName = "default"
+	for i := 1; i <= 3; i++ {
+		version := fmt.Sprintf("v%d", i)
+		_upload.PushBuild(func(ac *mitch.ArchiveContext) {
+			ac.SetName("default.zip")
+			ac.Entry("version.txt").String(version)
+		})
+	}
+	_otherUpload := _game.MakeUpload("Soundtrack")
+	_otherUpload.ChannelName = "soundtrack"
+	_otherUpload.PushBuild(func(ac *mitch.ArchiveContext) {
+		ac.SetName("default.zip")
+		ac.Entry("track01.ogg").String("la la la")
+	})
+
+	game := bi.FetchGame(_game.ID)
+	upload := bi.FetchUpload(_upload.ID)
+	buildsRes, err := bi.Client().ListUploadBuilds(rc.Ctx, itchio.ListUploadBuildsParams{
+		UploadID: upload.ID,
+	})
+	must(err)
+	assert.Len(buildsRes.Builds, 3)
+	oldest := buildsRes.Builds[len(buildsRes.Builds)-1]
+	otherBuild := bi.FetchUpload(_otherUpload.ID).Build
+
+	_, err = messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		Upload:            upload,
+		InstallLocationID: "tmp",
+		PreferredBuildID:  otherBuild.ID,
+	})
+	je, ok := err.(*jsonrpc2.Error)
+	if assert.True(ok, "should be a jsonrpc2 error") {
+		assert.EqualValues(butlerd.CodeBuildNotFound, je.Code)
+	}
+
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		Upload:            upload,
+		InstallLocationID: "tmp",
+		PreferredBuildID:  oldest.ID,
+	})
+	must(err)
+	if assert.NotNil(queueRes.Build) {
+		assert.EqualValues(oldest.ID, queueRes.Build.ID)
+	}
+
+	_, err = messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+		ID:            queueRes.ID,
+		StagingFolder: queueRes.StagingFolder,
+	})
+	must(err)
+
+	caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+		CaveID: queueRes.CaveID,
+	})
+	must(err)
+	assert.EqualValues(oldest.ID, caveRes.Cave.Build.ID)
+	assert.True(caveRes.Cave.InstallInfo.Pinned, "cave is pinned to the preferred build")
+
+	checkUpdate := func() *butlerd.CheckUpdateResult {
+		checkRes, err := messages.CheckUpdate.TestCall(rc, butlerd.CheckUpdateParams{
+			CaveIDs: []string{queueRes.CaveID},
+		})
+		must(err)
+		return checkRes
+	}
+	assert.Empty(checkUpdate().Updates, "pinned caves aren't updated")
+
+	_, err = messages.CavesSetPinned.TestCall(rc, butlerd.CavesSetPinnedParams{
+		CaveID: queueRes.CaveID,
+		Pinned: false,
+	})
+	must(err)
+	assert.Len(checkUpdate().Updates, 1, "unpinned cave can be updated to the latest build")
+}
@@ -0,0 +1,203 @@
+package integrate
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallQueueAlreadyQueued(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	// a second client, like the itch app retrying from another window
+	otherRc, _, otherCancel := bi.Connect()
+	defer otherCancel()
+
+	_developer := bi.Server.Store().MakeUser("Double Clicker")
+	_game := _developer.MakeGame("Install Twice Simulator")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContents()
+
+	game := bi.FetchGame(_game.ID)
+	uploadsRes, err := bi.Client().ListGameUploads(rc.Ctx, itchio.ListGameUploadsParams{
+		GameID: game.ID,
+	})
+	must(err)
+	queueParams := butlerd.InstallQueueParams{
+		Game:              game,
+		Upload:            uploadsRes.Uploads[0],
+		InstallLocationID: "tmp",
+		QueueDownload:     true,
+	}
+
+	var wg sync.WaitGroup
+	results := make([]*butlerd.InstallQueueResult, 2)
+	for i, queueRc := range []*butlerd.RequestContext{rc, otherRc} {
+		wg.Add(1)
+		go func(i int, queueRc *butlerd.RequestContext) {
+			defer wg.Done()
+			res, err := messages.InstallQueue.TestCall(queueRc, queueParams)
+			must(err)
+			results[i] = res
+		}(i, queueRc)
+	}
+	wg.Wait()
+
+	first, second := results[0], results[1]
+	if first.AlreadyQueued {
+		first, second = second, first
+	}
+	assert.False(first.AlreadyQueued)
+	assert.True(second.AlreadyQueued, "one of them finds the other's download")
+	assert.EqualValues(first.ID, second.ID)
+	assert.EqualValues(first.CaveID, second.CaveID)
+	assert.EqualValues(first.StagingFolder, second.StagingFolder)
+	assert.EqualValues(first.InstallFolder, second.InstallFolder)
+	assert.EqualValues(first.InstallFolderName, second.InstallFolderName)
+	assert.EqualValues(first.Upload.ID, second.Upload.ID)
+
+	listRes, err := messages.DownloadsList.TestCall(rc, butlerd.DownloadsListParams{})
+	must(err)
+	assert.Len(listRes.Downloads, 1)
+
+	// the queued download is found even without an upload
+	sameGame, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+	})
+	must(err)
+	assert.True(sameGame.AlreadyQueued)
+	assert.EqualValues(first.ID, sameGame.ID)
+
+	queueParams.Force = true
+	forced, err := messages.InstallQueue.TestCall(rc, queueParams)
+	must(err)
+	assert.False(forced.AlreadyQueued)
+	assert.NotEqual(first.ID, forced.ID)
+	assert.NotEqual(first.CaveID, forced.CaveID)
+
+	// clients that queue the download themselves end up with the same one
+	_twoStep := _developer.MakeGame("Queue Then Download")
+	_twoStep.Publish()
+	_twoStepUpload := _twoStep.MakeUpload("All platforms")
+	_twoStepUpload.SetAllPlatforms()
+	_twoStepUpload.SetZipContents()
+	twoStepParams := butlerd.InstallQueueParams{
+		Game:              bi.FetchGame(_twoStep.ID),
+		Upload:            bi.FetchUpload(_twoStepUpload.ID),
+		InstallLocationID: "tmp",
+	}
+	for i, queueRc := range []*butlerd.RequestContext{rc, otherRc} {
+		wg.Add(1)
+		go func(i int, queueRc *butlerd.RequestContext) {
+			defer wg.Done()
+			res, err := messages.InstallQueue.TestCall(queueRc, twoStepParams)
+			must(err)
+			results[i] = res
+		}(i, queueRc)
+	}
+	wg.Wait()
+	assert.EqualValues(results[0].ID, results[1].ID)
+	for _, res := range results {
+		_, err := messages.DownloadsQueue.TestCall(rc, butlerd.DownloadsQueueParams{
+			Item: res,
+		})
+		must(err)
+	}
+	listRes, err = messages.DownloadsList.TestCall(rc, butlerd.DownloadsListParams{})
+	must(err)
+	twoStepDownloads := 0
+	for _, download := range listRes.Downloads {
+		if download.Game.ID == _twoStep.ID {
+			twoStepDownloads++
+		}
+	}
+	assert.EqualValues(1, twoStepDownloads, "queuing the same item twice does nothing")
+}
+
+func Test_InstallQueueAlreadyQueuedWhilePicking(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	otherRc, _, otherCancel := bi.Connect()
+	defer otherCancel()
+
+	_developer := bi.Server.Store().MakeUser("Slow Picker")
+	_game := _developer.MakeGame("Indecision Simulator")
+	_game.Publish()
+	var uploadID int64
+	for _, name := range []string{"First flavor", "Second flavor"} {
+		_upload := _game.MakeUpload(name)
+		_upload.SetAllPlatforms()
+		_upload.SetZipContents()
+		uploadID = _upload.ID
+	}
+	game := bi.FetchGame(_game.ID)
+
+	picking := make(chan struct{})
+	unblock := make(chan struct{})
+	messages.PickUpload.TestRegister(h, func(rc *butlerd.RequestContext, params butlerd.PickUploadParams) (*butlerd.PickUploadResult, error) {
+		close(picking)
+		<-unblock
+		return &butlerd.PickUploadResult{Index: 0}, nil
+	})
+
+	picked := make(chan *butlerd.InstallQueueResult)
+	go func() {
+		res, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+			Game:              game,
+			InstallLocationID: "tmp",
+			QueueDownload:     true,
+		})
+		must(err)
+		picked <- res
+	}()
+	<-picking
+
+	// the client taking its time doesn't hold up others
+	queued := make(chan *butlerd.InstallQueueResult)
+	go func() {
+		res, err := messages.InstallQueue.TestCall(otherRc, butlerd.InstallQueueParams{
+			Game:              game,
+			Upload:            bi.FetchUpload(uploadID),
+			InstallLocationID: "tmp",
+			QueueDownload:     true,
+		})
+		must(err)
+		queued <- res
+	}()
+	var first *butlerd.InstallQueueResult
+	select {
+	case first = <-queued:
+		assert.False(first.AlreadyQueued)
+	case <-time.After(10 * time.Second):
+		t.Fatal("queuing waited on the other client picking an upload")
+	}
+
+	close(unblock)
+	second := <-picked
+	assert.True(second.AlreadyQueued, "finds the download queued while it was picking")
+	assert.EqualValues(first.ID, second.ID)
+
+	listRes, err := messages.DownloadsList.TestCall(rc, butlerd.DownloadsListParams{})
+	must(err)
+	assert.Len(listRes.Downloads, 1)
+}
@@ -0,0 +1,93 @@
+package integrate
+
+import (
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallQueueMany(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Collection Curator")
+	makeGame := func(title string, uploads ...string) int64 {
+		_game := _developer.MakeGame(title)
+		_game.Publish()
+		for _, name := range uploads {
+			_upload := _game.MakeUpload(name)
+			_upload.SetAllPlatforms()
+			_upload.SetZipContents()
+		}
+		return _game.ID
+	}
+
+	simple := bi.FetchGame(makeGame("Simple", "All platforms"))
+	choosy := bi.FetchGame(makeGame("Choosy", "Demo", "Full game"))
+	empty := bi.FetchGame(makeGame("Empty"))
+
+	items := []*butlerd.InstallQueueManyItem{
+		{Game: simple, InstallLocationID: "tmp"},
+		{Game: choosy, InstallLocationID: "tmp"},
+		{Game: empty, InstallLocationID: "tmp"},
+		{Game: simple, InstallLocationID: "nowhere"},
+	}
+
+	res, err := messages.InstallQueueMany.TestCall(rc, butlerd.InstallQueueManyParams{
+		Items: items,
+	})
+	must(err)
+	if assert.Len(res.Items, 4) {
+		assert.EqualValues(butlerd.InstallQueueManyStatusQueued, res.Items[0].Status)
+		if assert.NotNil(res.Items[0].Queued) {
+			assert.NotEmpty(res.Items[0].Queued.CaveID)
+		}
+
+		assert.EqualValues(butlerd.InstallQueueManyStatusNeedsAttention, res.Items[1].Status)
+		assert.Len(res.Items[1].Uploads, 2)
+
+		assert.EqualValues(butlerd.InstallQueueManyStatusFailed, res.Items[2].Status)
+		assert.EqualValues(butlerd.CodeNoCompatibleUploads, res.Items[2].ErrorCode)
+
+		assert.EqualValues(butlerd.InstallQueueManyStatusFailed, res.Items[3].Status)
+		assert.NotEmpty(res.Items[3].Error)
+	}
+
+	pickCalls := 0
+	var pickedUploadID int64
+	messages.PickUploads.TestRegister(h, func(rc *butlerd.RequestContext, params butlerd.PickUploadsParams) (*butlerd.PickUploadsResult, error) {
+		pickCalls++
+		assert.Len(params.Items, 1)
+		assert.EqualValues(choosy.ID, params.Items[0].Game.ID)
+		pickedUploadID = params.Items[0].Uploads[1].ID
+		return &butlerd.PickUploadsResult{Indices: []int64{1}}, nil
+	})
+
+	res, err = messages.InstallQueueMany.TestCall(rc, butlerd.InstallQueueManyParams{
+		Items:         items[:2],
+		PickUploads:   true,
+		QueueDownload: true,
+	})
+	must(err)
+	assert.EqualValues(1, pickCalls, "a single dialog for all games")
+	if assert.Len(res.Items, 2) {
+		for _, item := range res.Items {
+			assert.EqualValues(butlerd.InstallQueueManyStatusQueued, item.Status, "%s", item.Game.Title)
+		}
+		picked := res.Items[1].Queued
+		if assert.NotNil(picked) {
+			assert.EqualValues(pickedUploadID, picked.Upload.ID)
+		}
+	}
+
+	downloadsRes, err := messages.DownloadsList.TestCall(rc, butlerd.DownloadsListParams{})
+	must(err)
+	assert.Len(downloadsRes.Downloads, 2)
+}
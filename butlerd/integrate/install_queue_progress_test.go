@@ -0,0 +1,131 @@
+package integrate
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallQueueProgress(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Phase Shifter")
+	_game := _developer.MakeGame("Loading Screen Simulator")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContents()
+
+	var notifsLock sync.Mutex
+	var notifs []butlerd.InstallQueueProgressNotification
+	messages.InstallQueueProgress.Register(h, func(params butlerd.InstallQueueProgressNotification) {
+		notifsLock.Lock()
+		defer notifsLock.Unlock()
+		notifs = append(notifs, params)
+	})
+
+	game := bi.FetchGame(_game.ID)
+	_, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+		DryRun:            true,
+	})
+	must(err)
+	notifsLock.Lock()
+	assert.Empty(notifs, "nothing to match dry runs with")
+	notifsLock.Unlock()
+
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+	})
+	must(err)
+
+	notifsLock.Lock()
+	defer notifsLock.Unlock()
+	var phases []butlerd.InstallQueuePhase
+	for _, n := range notifs {
+		phases = append(phases, n.Phase)
+		assert.EqualValues(queueRes.ID, n.ID)
+		assert.EqualValues(game.ID, n.GameID)
+		assert.EqualValues(queueRes.CaveID, n.CaveID)
+	}
+	assert.EqualValues([]butlerd.InstallQueuePhase{
+		butlerd.InstallQueuePhaseRefreshingGame,
+		butlerd.InstallQueuePhaseResolvingUpload,
+		butlerd.InstallQueuePhasePreparing,
+	}, phases)
+}
+
+func Test_InstallPrepareProgress(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Progress Bar Enthusiast")
+	_game := _developer.MakeGame("Almost There")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContents()
+
+	var notifsLock sync.Mutex
+	var notifs []butlerd.InstallPrepareProgressNotification
+	readyReceived := make(chan struct{}, 1)
+	messages.InstallPrepareProgress.Register(h, func(params butlerd.InstallPrepareProgressNotification) {
+		notifsLock.Lock()
+		defer notifsLock.Unlock()
+		notifs = append(notifs, params)
+		if params.Stage == butlerd.InstallPrepareStageReady {
+			readyReceived <- struct{}{}
+		}
+	})
+
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              bi.FetchGame(_game.ID),
+		InstallLocationID: "tmp",
+	})
+	must(err)
+
+	// notifications are handled concurrently with the result
+	select {
+	case <-readyReceived:
+	case <-time.After(5 * time.Second):
+		must(errors.New("timed out waiting for ready"))
+	}
+
+	notifsLock.Lock()
+	defer notifsLock.Unlock()
+	var ready []butlerd.InstallPrepareProgressNotification
+	for _, n := range notifs {
+		assert.EqualValues(queueRes.ID, n.ID)
+		assert.Contains([]butlerd.InstallPrepareStage{
+			butlerd.InstallPrepareStageFetchingSignature,
+			butlerd.InstallPrepareStageComputingPatch,
+			butlerd.InstallPrepareStageReady,
+		}, n.Stage)
+		assert.True(n.BytesDone <= n.TotalBytes || n.TotalBytes == 0)
+		if n.Stage == butlerd.InstallPrepareStageReady {
+			ready = append(ready, n)
+		}
+	}
+	if assert.Len(ready, 1, "ready is always sent, once") {
+		assert.True(ready[0].TotalBytes > 0, "the source was probed")
+		assert.EqualValues(ready[0].TotalBytes, ready[0].BytesDone)
+	}
+}
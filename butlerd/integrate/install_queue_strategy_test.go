@@ -0,0 +1,78 @@
+package integrate
+
+import (
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallQueueStrategy(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Bandwidth Watcher")
+	_game := _developer.MakeGame("Counting Bytes")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_build1 := _upload.PushBuild(func(ac *mitch.ArchiveContext) {
+		ac.Entry("game.exe").String("version one")
+		ac.Entry("data.pak").Random(0x1, 512*1024)
+	})
+
+	game := bi.FetchGame(_game.ID)
+	upload := bi.FetchUpload(_upload.ID)
+
+	queueAndPerform := func(params butlerd.InstallQueueParams) *butlerd.InstallQueueResult {
+		queueRes, err := messages.InstallQueue.TestCall(rc, params)
+		must(err)
+		_, err = messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+			ID:            queueRes.ID,
+			StagingFolder: queueRes.StagingFolder,
+		})
+		must(err)
+		return queueRes
+	}
+
+	build1 := bi.FetchBuild(_build1.ID)
+	queueRes := queueAndPerform(butlerd.InstallQueueParams{
+		Game:              game,
+		Upload:            upload,
+		InstallLocationID: "tmp",
+	})
+	assert.EqualValues(butlerd.InstallStrategyArchive, queueRes.Strategy)
+	assert.NotZero(queueRes.EstimatedDownloadSize)
+	assert.EqualValues(upload.Size, queueRes.EstimatedDownloadSize)
+	assert.True(queueRes.EstimatedInstallSize >= 512*1024)
+	caveID := queueRes.CaveID
+
+	_build2 := _upload.PushBuild(func(ac *mitch.ArchiveContext) {
+		ac.Entry("game.exe").String("version two")
+		ac.Entry("data.pak").Random(0x1, 512*1024)
+	})
+	build2 := bi.FetchBuild(_build2.ID)
+	queueRes = queueAndPerform(butlerd.InstallQueueParams{
+		CaveID: caveID,
+		Build:  build2,
+	})
+	assert.EqualValues(butlerd.InstallStrategyPatch, queueRes.Strategy)
+	assert.NotZero(queueRes.EstimatedDownloadSize)
+	assert.True(queueRes.EstimatedDownloadSize < upload.Size, "only the patch is downloaded")
+	assert.True(queueRes.EstimatedInstallSize >= 512*1024)
+
+	queueRes = queueAndPerform(butlerd.InstallQueueParams{
+		CaveID: caveID,
+		Build:  build1,
+	})
+	assert.EqualValues(butlerd.InstallStrategyHeal, queueRes.Strategy)
+	assert.Zero(queueRes.EstimatedDownloadSize, "only what's changed is downloaded, which isn't known yet")
+	assert.True(queueRes.EstimatedInstallSize >= 512*1024)
+}
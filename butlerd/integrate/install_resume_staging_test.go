@@ -0,0 +1,169 @@
+package integrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/jsonrpc2"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallResumeStagingFolder(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Flaky Connection")
+	makeGame := func(title string) int64 {
+		_game := _developer.MakeGame(title)
+		_game.Publish()
+		_upload := _game.MakeUpload("All platforms")
+		_upload.SetAllPlatforms()
+		_upload.PushBuild(func(ac *mitch.ArchiveContext) {
+			ac.SetName("default.zip")
+			ac.Entry("index.html").String("<p>" + title + "</p>")
+		})
+		return _game.ID
+	}
+	game := bi.FetchGame(makeGame("Interrupted"))
+	otherGame := bi.FetchGame(makeGame("Unrelated"))
+
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+	})
+	must(err)
+
+	// as if butler had exited halfway through the download
+	partialPath := filepath.Join(queueRes.StagingFolder, "partial-download.bin")
+	must(ioutil.WriteFile(partialPath, []byte("already downloaded"), 0o644))
+
+	assertMismatch := func(params butlerd.InstallQueueParams) {
+		_, err := messages.InstallQueue.TestCall(rc, params)
+		je, ok := err.(*jsonrpc2.Error)
+		if assert.True(ok, "should be a jsonrpc2 error") {
+			assert.EqualValues(butlerd.CodeStagingFolderMismatch, je.Code)
+		}
+	}
+	assertMismatch(butlerd.InstallQueueParams{
+		Game:                otherGame,
+		InstallLocationID:   "tmp",
+		ResumeStagingFolder: queueRes.StagingFolder,
+	})
+	assertMismatch(butlerd.InstallQueueParams{
+		Game:                game,
+		InstallLocationID:   "tmp",
+		ResumeStagingFolder: filepath.Join(os.TempDir(), "elsewhere"),
+	})
+	assert.FileExists(partialPath, "mismatches leave the staging folder alone")
+
+	resumeRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		InstallLocationID:   "tmp",
+		ResumeStagingFolder: queueRes.StagingFolder,
+	})
+	must(err)
+	assert.EqualValues(queueRes.ID, resumeRes.ID)
+	assert.EqualValues(queueRes.StagingFolder, resumeRes.StagingFolder)
+	assert.EqualValues(queueRes.CaveID, resumeRes.CaveID)
+	assert.EqualValues(queueRes.InstallFolder, resumeRes.InstallFolder)
+	assert.EqualValues(game.ID, resumeRes.Game.ID, "game is taken from the staging folder")
+	assert.EqualValues(queueRes.Upload.ID, resumeRes.Upload.ID)
+	assert.FileExists(partialPath, "what was downloaded is kept")
+
+	_, err = messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+		ID:            resumeRes.ID,
+		StagingFolder: resumeRes.StagingFolder,
+	})
+	must(err)
+	assert.FileExists(filepath.Join(resumeRes.InstallFolder, "index.html"))
+
+	// the install went through, there's nothing left to resume
+	freshRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		CaveID:              resumeRes.CaveID,
+		ResumeStagingFolder: resumeRes.StagingFolder,
+	})
+	must(err)
+	assert.NotEqual(resumeRes.ID, freshRes.ID)
+}
+
+func Test_InstallQueueAgainResumes(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Crashy Computer")
+	_game := _developer.MakeGame("Queued Twice")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.PushBuild(func(ac *mitch.ArchiveContext) {
+		ac.SetName("default.zip")
+		ac.Entry("index.html").String("<p>twice</p>")
+	})
+	game := bi.FetchGame(_game.ID)
+	upload := bi.FetchUpload(_upload.ID)
+
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		Upload:            upload,
+		InstallLocationID: "tmp",
+	})
+	must(err)
+
+	// butler went away halfway through the download, and the
+	// client doesn't know where it was staged
+	partialPath := filepath.Join(queueRes.StagingFolder, "partial-download.bin")
+	must(ioutil.WriteFile(partialPath, []byte("already downloaded"), 0o644))
+
+	// the server has a hiccup while the install is being prepared
+	_game.Published = false
+	_, err = messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		Upload:            upload,
+		InstallLocationID: "tmp",
+	})
+	assert.Error(err)
+	assert.FileExists(partialPath, "failing to prepare doesn't throw away what was downloaded")
+	_game.Published = true
+
+	againRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		Upload:            upload,
+		InstallLocationID: "tmp",
+	})
+	must(err)
+	assert.EqualValues(queueRes.ID, againRes.ID)
+	assert.EqualValues(queueRes.StagingFolder, againRes.StagingFolder)
+	assert.EqualValues(queueRes.CaveID, againRes.CaveID)
+	assert.FileExists(partialPath, "what was downloaded is kept")
+
+	dryRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		Upload:            upload,
+		InstallLocationID: "tmp",
+		DryRun:            true,
+	})
+	must(err)
+	assert.Empty(dryRes.ID)
+	assert.FileExists(partialPath, "dry runs don't touch it")
+
+	_, err = messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+		ID:            againRes.ID,
+		StagingFolder: againRes.StagingFolder,
+	})
+	must(err)
+	assert.FileExists(filepath.Join(againRes.InstallFolder, "index.html"))
+}
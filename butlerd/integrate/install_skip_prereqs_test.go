@@ -0,0 +1,131 @@
+package integrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallSkipPrereqs(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Redistributable Enjoyer")
+	_game := _developer.MakeGame("Needs Everything")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+		ac.Entry(".itch.toml").String(`
+[[actions]]
+name = "play"
+path = "https://example.org/play"
+
+[[prereqs]]
+name = "vcredist-2015-x64"
+`)
+	})
+
+	queueRes := bi.Install(butlerd.InstallQueueParams{
+		Game:              bi.FetchGame(_game.ID),
+		InstallLocationID: "tmp",
+		SkipPrereqs:       true,
+	})
+	caveID := queueRes.CaveID
+
+	prereqsSkipped := func() bool {
+		caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+			CaveID: caveID,
+		})
+		must(err)
+		return caveRes.Cave.InstallInfo.PrereqsSkipped
+	}
+	assert.True(prereqsSkipped(), "recorded on the cave")
+
+	_, err := messages.InstallPrereqs.TestCall(rc, butlerd.InstallPrereqsParams{
+		CaveID: caveID,
+	})
+	assert.Error(err, "prereqsDir is required")
+
+	_, err = messages.InstallPrereqs.TestCall(rc, butlerd.InstallPrereqsParams{
+		CaveID:     caveID,
+		PrereqsDir: "./tmp/prereqs",
+	})
+	must(err)
+	assert.False(prereqsSkipped(), "cleared once prereqs are installed")
+}
+
+func Test_LaunchSkipPrereqs(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Redistributable Enjoyer")
+	_game := _developer.MakeGame("Needs Everything Natively")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+		ac.Entry(".itch.toml").String(`
+[[actions]]
+name = "play"
+path = "play.sh"
+
+[[prereqs]]
+name = "vcredist-2015-x64"
+`)
+		ac.Entry("play.sh").String("#!/bin/sh\nexit 0\n")
+	})
+
+	queueRes := bi.Install(butlerd.InstallQueueParams{
+		Game:              bi.FetchGame(_game.ID),
+		InstallLocationID: "tmp",
+		SkipPrereqs:       true,
+	})
+	caveID := queueRes.CaveID
+
+	prereqsSkipped := func() bool {
+		caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+			CaveID: caveID,
+		})
+		must(err)
+		return caveRes.Cave.InstallInfo.PrereqsSkipped
+	}
+
+	prereqsDir := "./tmp/prereqs-launch"
+	_, err := messages.Launch.TestCall(rc, butlerd.LaunchParams{
+		CaveID:     caveID,
+		PrereqsDir: prereqsDir,
+	})
+	must(err)
+	assert.True(prereqsSkipped(), "launching doesn't handle them")
+
+	// with an install marker, handling prereqs succeeds without
+	// needing the registry
+	markerPath := filepath.Join(prereqsDir, "vcredist-2015-x64", ".installed")
+	must(os.MkdirAll(filepath.Dir(markerPath), 0o755))
+	must(ioutil.WriteFile(markerPath, []byte("Installed"), 0o644))
+
+	_, err = messages.Launch.TestCall(rc, butlerd.LaunchParams{
+		CaveID:       caveID,
+		PrereqsDir:   prereqsDir,
+		ForcePrereqs: true,
+	})
+	must(err)
+	assert.False(prereqsSkipped(), "forcePrereqs handles them anyway")
+}
@@ -0,0 +1,118 @@
+package integrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallStagingPath(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Scratch Disk Enjoyer")
+	_game := _developer.MakeGame("Fast and Slow")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContents()
+
+	tmpDir, err := ioutil.TempDir("", "staging-path-test")
+	must(err)
+	defer os.RemoveAll(tmpDir)
+
+	archivePath := filepath.Join(tmpDir, "archive")
+	must(os.MkdirAll(archivePath, 0o755))
+	scratchPath := filepath.Join(tmpDir, "scratch")
+	must(os.MkdirAll(scratchPath, 0o755))
+
+	_, err = messages.InstallLocationsAdd.TestCall(rc, butlerd.InstallLocationsAddParams{
+		ID:          "archive",
+		Path:        archivePath,
+		StagingPath: filepath.Join(tmpDir, "does-not-exist"),
+	})
+	assert.Error(err, "staging paths must exist")
+
+	addRes, err := messages.InstallLocationsAdd.TestCall(rc, butlerd.InstallLocationsAddParams{
+		ID:   "archive",
+		Path: archivePath,
+	})
+	must(err)
+	assert.EqualValues("", addRes.InstallLocation.StagingPath)
+
+	updateRes, err := messages.InstallLocationsUpdate.TestCall(rc, butlerd.InstallLocationsUpdateParams{
+		ID:          "archive",
+		StagingPath: scratchPath,
+	})
+	must(err)
+	assert.EqualValues(scratchPath, updateRes.InstallLocation.StagingPath)
+
+	game := bi.FetchGame(_game.ID)
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "archive",
+	})
+	must(err)
+	assert.EqualValues(filepath.Join(scratchPath, "downloads"), filepath.Dir(queueRes.StagingFolder), "staging happens on the scratch disk")
+	assert.EqualValues(archivePath, filepath.Dir(queueRes.InstallFolder), "installs still go to the archive")
+
+	_, err = messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+		ID:            queueRes.ID,
+		StagingFolder: queueRes.StagingFolder,
+	})
+	must(err)
+
+	leftover := filepath.Join(scratchPath, "downloads", "leftover")
+	must(os.MkdirAll(leftover, 0o755))
+	searchRes, err := messages.CleanDownloadsSearch.TestCall(rc, butlerd.CleanDownloadsSearchParams{
+		Roots: []string{filepath.Join(archivePath, "downloads")},
+	})
+	must(err)
+	if assert.Len(searchRes.Entries, 1, "external staging roots are scanned") {
+		assert.EqualValues(leftover, searchRes.Entries[0].Path)
+	}
+
+	queueRes, err = messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		CaveID:        queueRes.CaveID,
+		Reason:        butlerd.DownloadReasonReinstall,
+		QueueDownload: true,
+	})
+	must(err)
+
+	searchRes, err = messages.CleanDownloadsSearch.TestCall(rc, butlerd.CleanDownloadsSearchParams{
+		Roots: []string{filepath.Join(archivePath, "downloads")},
+	})
+	must(err)
+	assert.Len(searchRes.Entries, 1, "staging folders of downloads in progress are never cleaned")
+
+	_, err = messages.InstallLocationsRemove.TestCall(rc, butlerd.InstallLocationsRemoveParams{
+		ID: "archive",
+	})
+	assert.Error(err, "can't remove a location with downloads in progress")
+	_, err = os.Stat(queueRes.StagingFolder)
+	assert.NoError(err, "staging data of downloads in progress is kept")
+
+	_, err = messages.InstallLocationsUpdate.TestCall(rc, butlerd.InstallLocationsUpdateParams{
+		ID: "archive",
+	})
+	must(err)
+	queueRes, err = messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		CaveID: queueRes.CaveID,
+		Reason: butlerd.DownloadReasonReinstall,
+		// the reinstall above is still queued
+		Force: true,
+	})
+	must(err)
+	assert.EqualValues(filepath.Join(archivePath, "downloads"), filepath.Dir(queueRes.StagingFolder), "staging goes back inside the location")
+}
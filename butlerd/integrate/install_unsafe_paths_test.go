@@ -0,0 +1,132 @@
+package integrate
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/jsonrpc2"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallUnsafePaths(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	// no file system we run on accepts a name that long
+	longName := strings.Repeat("o", 300) + ".txt"
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Careless Developer")
+	_game := _developer.MakeGame("Long Names Simulator")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+		ac.Entry("readme.txt").String("Good luck!")
+		ac.Entry("data/" + longName).String("Unreachable")
+	})
+
+	game := bi.FetchGame(_game.ID)
+
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+	})
+	must(err)
+
+	_, err = messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+		ID:            queueRes.ID,
+		StagingFolder: queueRes.StagingFolder,
+	})
+	assert.Error(err)
+
+	je, ok := err.(*jsonrpc2.Error)
+	if assert.True(ok, "should be a jsonrpc2 error") {
+		assert.EqualValues(butlerd.CodeUnsafeFilenames, je.Code)
+		if assert.NotNil(je.Data) {
+			data := string(*je.Data)
+			assert.Contains(data, longName)
+			assert.Contains(data, "component-too-long")
+			assert.NotContains(data, "readme.txt")
+		}
+	}
+}
+
+func Test_InstallUnsafePathsSanitized(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	longName := strings.Repeat("o", 300) + ".exe"
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Careless Developer")
+	_game := _developer.MakeGame("Long Names Simulator")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+		ac.Entry(".itch.toml").String(`
+[[actions]]
+name = "play"
+path = "bin/` + longName + `"
+`)
+		ac.Entry("readme.txt").String("Good luck!")
+		ac.Entry("bin/" + longName).String("Reachable after all")
+	})
+
+	_, err := messages.SettingsSet.TestCall(rc, butlerd.SettingsSetParams{
+		Key:   "install.sanitizeFilenames",
+		Value: true,
+	})
+	must(err)
+
+	queueRes := bi.Install(butlerd.InstallQueueParams{
+		Game:              bi.FetchGame(_game.ID),
+		InstallLocationID: "tmp",
+	})
+	caveID := queueRes.CaveID
+
+	caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{CaveID: caveID})
+	must(err)
+	installFolder := caveRes.Cave.InstallInfo.InstallFolder
+
+	entries, err := ioutil.ReadDir(filepath.Join(installFolder, "bin"))
+	must(err)
+	if assert.Len(entries, 1) {
+		sanitized := entries[0].Name()
+		assert.True(len(sanitized) < len(longName), "long name should be cut short")
+		assert.True(strings.HasSuffix(sanitized, ".exe"), "extension should be kept")
+
+		targetsRes, err := messages.CavesGetLaunchTargets.TestCall(rc, butlerd.CavesGetLaunchTargetsParams{
+			CaveID: caveID,
+		})
+		must(err)
+		if assert.Len(targetsRes.Targets, 1) {
+			assert.EqualValues("bin/"+sanitized, targetsRes.Targets[0].Path, "launch targets should follow the rename")
+		}
+	}
+
+	verifyRes, err := messages.CaveVerify.TestCall(rc, butlerd.CaveVerifyParams{
+		CaveID: caveID,
+	})
+	must(err)
+	assert.EqualValues(butlerd.CaveVerifyMethodArchive, verifyRes.Method)
+	assert.Empty(verifyRes.Missing)
+	assert.Empty(verifyRes.Added)
+	assert.Empty(verifyRes.Corrupted)
+}
@@ -0,0 +1,113 @@
+package integrate
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LaunchPrecomputePlans(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Couch Developer")
+
+	_htmlGame := _developer.MakeGame("Big Screen Simulator")
+	_htmlGame.Type = "html"
+	_htmlGame.Publish()
+	_htmlUpload := _htmlGame.MakeUpload("All platforms")
+	_htmlUpload.SetAllPlatforms()
+	_htmlUpload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+		ac.Entry("index.html").String("<p>Hi!</p>")
+	})
+
+	_brokenGame := _developer.MakeGame("Broken Manifest Simulator")
+	_brokenGame.Publish()
+	_brokenUpload := _brokenGame.MakeUpload("All platforms")
+	_brokenUpload.SetAllPlatforms()
+	_brokenUpload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+		ac.Entry(".itch.toml").String("[[actions]]\nname = \"play\"\npath = \"missing.exe\"\n")
+	})
+
+	htmlRes := bi.Install(butlerd.InstallQueueParams{
+		Game:              bi.FetchGame(_htmlGame.ID),
+		InstallLocationID: "tmp",
+	})
+	brokenRes := bi.Install(butlerd.InstallQueueParams{
+		Game:              bi.FetchGame(_brokenGame.ID),
+		InstallLocationID: "tmp",
+	})
+
+	precompute := func(params butlerd.LaunchPrecomputePlansParams) map[string]*butlerd.LaunchPlanStatus {
+		res, err := messages.LaunchPrecomputePlans.TestCall(rc, params)
+		must(err)
+		plans := make(map[string]*butlerd.LaunchPlanStatus)
+		for _, p := range res.Plans {
+			plans[p.CaveID] = p
+		}
+		return plans
+	}
+
+	plans := precompute(butlerd.LaunchPrecomputePlansParams{})
+	assert.Len(plans, 2)
+
+	htmlPlan := plans[htmlRes.CaveID]
+	if assert.NotNil(htmlPlan) {
+		assert.True(htmlPlan.OK)
+		assert.False(htmlPlan.Reused)
+		assert.EqualValues([]butlerd.LaunchStrategy{butlerd.LaunchStrategyHTML}, htmlPlan.Strategies)
+	}
+
+	brokenPlan := plans[brokenRes.CaveID]
+	if assert.NotNil(brokenPlan) {
+		assert.False(brokenPlan.OK)
+		assert.Contains(brokenPlan.Error, "missing.exe")
+	}
+
+	// nothing changed, plans are reused
+	plans = precompute(butlerd.LaunchPrecomputePlansParams{})
+	assert.True(plans[htmlRes.CaveID].Reused)
+
+	// ...unless asked not to
+	plans = precompute(butlerd.LaunchPrecomputePlansParams{Force: true})
+	assert.False(plans[htmlRes.CaveID].Reused)
+	assert.True(plans[htmlRes.CaveID].OK)
+
+	// filters restrict the caves considered
+	plans = precompute(butlerd.LaunchPrecomputePlansParams{Filter: `gameTitle contains "big screen"`})
+	assert.Len(plans, 1)
+	assert.Contains(plans, htmlRes.CaveID)
+
+	// launching uses the stored plan
+	messages.HTMLLaunch.TestRegister(h, func(rc *butlerd.RequestContext, params butlerd.HTMLLaunchParams) (*butlerd.HTMLLaunchResult, error) {
+		return &butlerd.HTMLLaunchResult{}, nil
+	})
+	_, err := messages.Launch.TestCall(rc, butlerd.LaunchParams{
+		CaveID:     htmlRes.CaveID,
+		PrereqsDir: "/tmp/prereqs",
+	})
+	must(err)
+
+	// fixing the manifest invalidates the stored plan
+	caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{CaveID: brokenRes.CaveID})
+	must(err)
+	installFolder := caveRes.Cave.InstallInfo.InstallFolder
+	must(ioutil.WriteFile(filepath.Join(installFolder, "index.html"), []byte("<p>Fixed</p>"), 0644))
+	must(ioutil.WriteFile(filepath.Join(installFolder, ".itch.toml"), []byte("[[actions]]\nname = \"play\"\npath = \"index.html\"\n"), 0644))
+
+	plans = precompute(butlerd.LaunchPrecomputePlansParams{})
+	assert.True(plans[htmlRes.CaveID].Reused)
+	assert.True(plans[brokenRes.CaveID].OK, "%s", plans[brokenRes.CaveID].Error)
+	assert.False(plans[brokenRes.CaveID].Reused)
+}
@@ -0,0 +1,45 @@
+package integrate
+
+import (
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LogDump(t *testing.T) {
+	assert := assert.New(t)
+
+	rc, _, cancel := newInstance(t).Unwrap()
+	defer cancel()
+
+	_, err := messages.SystemLogDump.TestCall(rc, butlerd.SystemLogDumpParams{
+		Lines: butlerd.SystemLogDumpMaxLines + 1,
+	})
+	assert.Error(err, "can't ask for more than the daemon remembers")
+
+	_, err = messages.SystemStatFS.TestCall(rc, butlerd.SystemStatFSParams{
+		Path: ".",
+	})
+	must(err)
+
+	res, err := messages.SystemLogDump.TestCall(rc, butlerd.SystemLogDumpParams{
+		Lines: 1,
+	})
+	must(err)
+	if assert.Len(res.Entries, 1) {
+		entry := res.Entries[0]
+		assert.EqualValues("System.StatFS", entry.Module)
+		assert.Contains(entry.Message, "free out of")
+		assert.NotNil(entry.Fields["requestId"])
+		assert.False(entry.Timestamp.IsZero())
+	}
+
+	res, err = messages.SystemLogDump.TestCall(rc, butlerd.SystemLogDumpParams{
+		Lines: butlerd.SystemLogDumpMaxLines,
+	})
+	must(err)
+	assert.NotEmpty(res.Entries)
+	assert.True(len(res.Entries) < butlerd.SystemLogDumpMaxLines, "returns what there is")
+}
@@ -37,4 +37,21 @@ func Test_Plan(t *testing.T) {
 	assert.NotNil(res.Info)
 	assert.NotNil(res.Info.Upload)
 	assert.NotEqual(_untaggedUpload.ID, res.Info.Upload.ID)
+	assert.True(res.Info.FreeSpace < 0, "no install location was given")
+
+	res, err = messages.InstallPlan.TestCall(rc, butlerd.InstallPlanParams{
+		GameID:            _game.ID,
+		InstallLocationID: "tmp",
+	})
+	must(err)
+	assert.True(res.Info.FreeSpace > 0)
+	assert.EqualValues(res.Info.Upload.Size, res.Info.DownloadSize)
+	assert.True(res.Info.EstimatedSize >= res.Info.DownloadSize, "archives are larger once extracted")
+	assert.NotNil(res.Info.DiskUsage)
+
+	_, err = messages.InstallPlan.TestCall(rc, butlerd.InstallPlanParams{
+		GameID:            _game.ID,
+		InstallLocationID: "nope",
+	})
+	assert.Error(err, "install location must exist")
 }
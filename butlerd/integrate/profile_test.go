@@ -2,6 +2,7 @@ package integrate
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/itchio/butler/butlerd"
@@ -51,4 +52,78 @@ func Test_Profile(t *testing.T) {
 	})
 	must(err)
 	assert.False(dgr.OK)
+
+	fr, err := messages.ProfileForget.TestCall(rc, butlerd.ProfileForgetParams{
+		ProfileID: prof.ID,
+	})
+	must(err)
+	assert.True(fr.Success)
+
+	r, err = messages.ProfileList.TestCall(rc, butlerd.ProfileListParams{})
+	must(err)
+	assert.Empty(r.Profiles)
+
+	fr, err = messages.ProfileForget.TestCall(rc, butlerd.ProfileForgetParams{
+		ProfileID: prof.ID,
+	})
+	must(err)
+	assert.False(fr.Success)
+}
+
+func Test_ProfileDataErase(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	prof := bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Privacy-minded Developer")
+	_game := _developer.MakeGame("Shared Computer Simulator")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContents()
+
+	game := bi.FetchGame(_game.ID)
+	bi.Install(butlerd.InstallQueueParams{
+		Game: game,
+	})
+
+	_, err := messages.ProfileDataPut.TestCall(rc, butlerd.ProfileDataPutParams{
+		ProfileID: prof.ID,
+		Key:       "@integrate/hello",
+		Value:     "world",
+	})
+	must(err)
+
+	res, err := messages.ProfileDataErase.TestCall(rc, butlerd.ProfileDataEraseParams{
+		ProfileID:     prof.ID,
+		AlsoUninstall: true,
+	})
+	must(err)
+	assert.True(res.Success)
+	assert.EqualValues(1, res.Removed.ProfileData)
+	assert.EqualValues(1, res.UninstallsQueued)
+	assert.EqualValues(0, res.CavesReassigned)
+
+	r, err := messages.ProfileList.TestCall(rc, butlerd.ProfileListParams{})
+	must(err)
+	assert.Empty(r.Profiles)
+
+	// uninstalls happen in the background
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		cavesRes, err := messages.FetchCaves.TestCall(rc, butlerd.FetchCavesParams{})
+		must(err)
+		if len(cavesRes.Items) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("cave still there after erasing profile with alsoUninstall")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
 }
@@ -0,0 +1,145 @@
+package integrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/hush/bfs"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RebuildReceipt(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Old Timer")
+
+	_game := _developer.MakeGame("Wharf Game")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.ChannelName = "default"
+	_upload.PushBuild(func(ac *mitch.ArchiveContext) {
+		ac.SetName("default.zip")
+		ac.Entry("game.exe").String("not really a game")
+		ac.Entry("data/one.dat").String("first level")
+		ac.Entry("data/two.dat").String("second level")
+	})
+
+	_plainGame := _developer.MakeGame("Plain Game")
+	_plainGame.Publish()
+	_plainUpload := _plainGame.MakeUpload("All platforms")
+	_plainUpload.SetAllPlatforms()
+	_plainUpload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+		ac.Entry("game.exe").String("not really a game either")
+	})
+
+	game := bi.FetchGame(_game.ID)
+	caveID := bi.Install(butlerd.InstallQueueParams{
+		Game: game,
+	}).CaveID
+	plainCaveID := bi.Install(butlerd.InstallQueueParams{
+		Game: bi.FetchGame(_plainGame.ID),
+	}).CaveID
+
+	installFolderOf := func(caveID string) string {
+		caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+			CaveID: caveID,
+		})
+		must(err)
+		return caveRes.Cave.InstallInfo.InstallFolder
+	}
+	writeFile := func(folder string, name string, contents string) {
+		p := filepath.Join(folder, filepath.FromSlash(name))
+		must(os.MkdirAll(filepath.Dir(p), 0o755))
+		must(ioutil.WriteFile(p, []byte(contents), 0o644))
+	}
+	statuses := func(rebuild *butlerd.ReceiptRebuild) map[string]butlerd.ReceiptFileStatus {
+		res := make(map[string]butlerd.ReceiptFileStatus)
+		for _, f := range rebuild.Files {
+			res[f.Path] = f.Status
+		}
+		return res
+	}
+
+	// as if installed by a version that didn't write receipts
+	installFolder := installFolderOf(caveID)
+	must(os.Remove(bfs.ReceiptPath(installFolder)))
+	writeFile(installFolder, "data/one.dat", "modded level")
+	must(os.Remove(filepath.Join(installFolder, "data", "two.dat")))
+	writeFile(installFolder, "notes.txt", "my notes")
+
+	rebuildRes, err := messages.CavesRebuildReceipt.TestCall(rc, butlerd.CavesRebuildReceiptParams{
+		CaveID: caveID,
+	})
+	must(err)
+	rebuild := rebuildRes.Rebuild
+	assert.True(rebuild.CheckedSignature)
+	assert.Empty(rebuild.BackupPath, "there was no receipt to back up")
+	assert.EqualValues(map[string]butlerd.ReceiptFileStatus{
+		"game.exe":     butlerd.ReceiptFileStatusOfficial,
+		"data/one.dat": butlerd.ReceiptFileStatusModified,
+		"notes.txt":    butlerd.ReceiptFileStatusLocal,
+	}, statuses(rebuild))
+	assert.EqualValues([]string{"data/two.dat"}, rebuild.Missing)
+
+	receipt, err := bfs.ReadReceipt(installFolder)
+	must(err)
+	assert.EqualValues([]string{"data/one.dat", "game.exe"}, receipt.Files)
+	assert.EqualValues(game.ID, receipt.Game.ID)
+
+	// a receipt that lists no files is as good as none
+	plainFolder := installFolderOf(plainCaveID)
+	must((&bfs.Receipt{Game: game}).WriteReceipt(plainFolder))
+	writeFile(plainFolder, "saves/slot1.sav", "level 99")
+
+	var suggested []butlerd.ReceiptRebuildSuggestedNotification
+	messages.ReceiptRebuildSuggested.Register(h, func(params butlerd.ReceiptRebuildSuggestedNotification) {
+		suggested = append(suggested, params)
+	})
+	bi.Install(butlerd.InstallQueueParams{
+		Game:   bi.FetchGame(_plainGame.ID),
+		CaveID: plainCaveID,
+	})
+	if assert.Len(suggested, 1) {
+		assert.EqualValues(plainCaveID, suggested[0].CaveID)
+	}
+	must((&bfs.Receipt{Game: game}).WriteReceipt(plainFolder))
+
+	rebuildsRes, err := messages.CavesRebuildReceipts.TestCall(rc, butlerd.CavesRebuildReceiptsParams{
+		CaveIDs: []string{plainCaveID, "not-a-cave"},
+	})
+	must(err)
+	if assert.Len(rebuildsRes.Rebuilds, 2) {
+		plain := rebuildsRes.Rebuilds[0]
+		assert.Empty(plain.Error)
+		assert.False(plain.CheckedSignature, "plain uploads have no signature")
+		assert.EqualValues(bfs.ReceiptPath(plainFolder)+".bak", plain.BackupPath)
+		assert.EqualValues(map[string]butlerd.ReceiptFileStatus{
+			"game.exe":        butlerd.ReceiptFileStatusUnchecked,
+			"saves/slot1.sav": butlerd.ReceiptFileStatusLocal,
+		}, statuses(plain))
+
+		assert.EqualValues("not-a-cave", rebuildsRes.Rebuilds[1].CaveID)
+		assert.NotEmpty(rebuildsRes.Rebuilds[1].Error)
+	}
+
+	receipt, err = bfs.ReadReceipt(plainFolder)
+	must(err)
+	assert.EqualValues([]string{"game.exe"}, receipt.Files)
+
+	rebuildsRes, err = messages.CavesRebuildReceipts.TestCall(rc, butlerd.CavesRebuildReceiptsParams{})
+	must(err)
+	assert.Len(rebuildsRes.Rebuilds, 2, "rebuilds all caves by default")
+}
@@ -0,0 +1,83 @@
+package integrate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Settings(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	otherRc, otherH, otherCancel := bi.Connect()
+	defer otherCancel()
+
+	changes := make(chan *butlerd.Setting, 4)
+	messages.SettingChanged.Register(otherH, func(params butlerd.SettingChangedNotification) {
+		changes <- params.Setting
+	})
+
+	listRes, err := messages.SettingsList.TestCall(otherRc, butlerd.SettingsListParams{})
+	must(err)
+	var keys []string
+	for _, s := range listRes.Settings {
+		keys = append(keys, s.Key)
+	}
+	assert.Contains(keys, "network.bandwidthLimit")
+	assert.Contains(keys, "network.simulateOffline")
+
+	// the older setter goes through settings too
+	_, err = messages.NetworkSetBandwidthThrottle.TestCall(rc, butlerd.NetworkSetBandwidthThrottleParams{
+		Enabled: true,
+		Rate:    500000,
+	})
+	must(err)
+
+	select {
+	case s := <-changes:
+		assert.EqualValues("network.bandwidthLimit", s.Key)
+		assert.EqualValues(500000, s.Value)
+		assert.False(s.IsDefault)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("other client wasn't notified")
+	}
+
+	getRes, err := messages.SettingsGet.TestCall(otherRc, butlerd.SettingsGetParams{
+		Key: "network.bandwidthLimit",
+	})
+	must(err)
+	assert.EqualValues(500000, getRes.Setting.Value)
+
+	_, err = messages.SettingsSet.TestCall(rc, butlerd.SettingsSetParams{
+		Key:   "network.bandwidthLimit",
+		Value: "fast",
+	})
+	assert.Error(err, "wrong type")
+
+	_, err = messages.SettingsSet.TestCall(rc, butlerd.SettingsSetParams{
+		Key:   "network.nope",
+		Value: 1,
+	})
+	assert.Error(err, "unknown key")
+
+	setRes, err := messages.SettingsSet.TestCall(rc, butlerd.SettingsSetParams{
+		Key:   "network.bandwidthLimit",
+		Value: 0,
+	})
+	must(err)
+	assert.True(setRes.Setting.IsDefault)
+
+	select {
+	case s := <-changes:
+		assert.EqualValues(0, s.Value)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("other client wasn't notified")
+	}
+}
@@ -0,0 +1,110 @@
+package integrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_UninstallPreserveUserData(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Quill Savesworth")
+	_game := _developer.MakeGame("Autosave Simulator")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+		ac.Entry("game.exe").String("MZ")
+		ac.Entry("data/level1.pak").String("level one")
+	})
+
+	game := bi.FetchGame(_game.ID)
+
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+	})
+	must(err)
+
+	_, err = messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+		ID:            queueRes.ID,
+		StagingFolder: queueRes.StagingFolder,
+	})
+	must(err)
+
+	// as if the game ran for a while
+	for name, contents := range map[string]string{
+		"saves/slot1.dat":  "level two, 3 lives",
+		"logs/output.log":  "all good",
+		"screenshot01.png": "pretty",
+	} {
+		fullPath := filepath.Join(queueRes.InstallFolder, filepath.FromSlash(name))
+		must(os.MkdirAll(filepath.Dir(fullPath), 0755))
+		must(ioutil.WriteFile(fullPath, []byte(contents), 0644))
+	}
+
+	categorized := make(chan []*butlerd.UninstallFile, 1)
+	messages.UninstallFilesCategorized.Register(h, func(params butlerd.UninstallFilesCategorizedNotification) {
+		categorized <- params.Files
+	})
+
+	res, err := messages.UninstallPerform.TestCall(rc, butlerd.UninstallPerformParams{
+		CaveID:           queueRes.CaveID,
+		PreserveUserData: []butlerd.UninstallFileCategory{butlerd.UninstallFileCategorySaves},
+	})
+	must(err)
+
+	var files []*butlerd.UninstallFile
+	select {
+	case files = <-categorized:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("never got UninstallFilesCategorized")
+	}
+
+	categories := make(map[string]butlerd.UninstallFileCategory)
+	for _, f := range files {
+		categories[f.Path] = f.Category
+	}
+	assert.EqualValues(map[string]butlerd.UninstallFileCategory{
+		"saves/slot1.dat":  butlerd.UninstallFileCategorySaves,
+		"logs/output.log":  butlerd.UninstallFileCategoryLogs,
+		"screenshot01.png": butlerd.UninstallFileCategoryUnknown,
+	}, categories)
+
+	if assert.Len(res.Preserved, 1) {
+		assert.EqualValues("saves/slot1.dat", res.Preserved[0].Path)
+	}
+	assert.Empty(res.LeftBehind)
+
+	var removed []string
+	for _, f := range res.Removed {
+		removed = append(removed, f.Path)
+	}
+	assert.Contains(removed, "game.exe")
+	assert.Contains(removed, "data/level1.pak")
+	assert.Contains(removed, "logs/output.log")
+	assert.Contains(removed, "screenshot01.png")
+
+	save, err := ioutil.ReadFile(filepath.Join(queueRes.InstallFolder, "saves", "slot1.dat"))
+	must(err)
+	assert.EqualValues("level two, 3 lives", string(save))
+	_, err = os.Stat(filepath.Join(queueRes.InstallFolder, "game.exe"))
+	assert.True(os.IsNotExist(err))
+
+	must(os.RemoveAll(queueRes.InstallFolder))
+}
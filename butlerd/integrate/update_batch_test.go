@@ -0,0 +1,137 @@
+package integrate
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CaveUpdateBatch(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Batch Processor")
+
+	installGame := func(title string, pushBuilds int) string {
+		return installOldestBuild(bi, _developer, title, pushBuilds)
+	}
+
+	outdated1 := installGame("Old and Busted", 2)
+	upToDate := installGame("New Hotness", 1)
+	outdated2 := installGame("Getting On", 3)
+
+	completions := make(chan butlerd.BatchUpdateCompleteNotification, 1)
+	messages.BatchUpdateComplete.Register(h, func(params butlerd.BatchUpdateCompleteNotification) {
+		completions <- params
+	})
+
+	_, err := messages.CaveUpdateBatch.TestCall(rc, butlerd.CaveUpdateBatchParams{
+		CaveIDs: []string{outdated1, "not-a-cave"},
+	})
+	assert.Error(err, "unknown caves are rejected")
+
+	batchRes, err := messages.CaveUpdateBatch.TestCall(rc, butlerd.CaveUpdateBatchParams{
+		CaveIDs:       []string{outdated1, upToDate, outdated2},
+		MaxConcurrent: 2,
+	})
+	must(err)
+
+	var complete butlerd.BatchUpdateCompleteNotification
+	select {
+	case complete = <-completions:
+	case <-time.After(30 * time.Second):
+		t.Fatal("batch didn't complete in time")
+	}
+	assert.EqualValues(batchRes.BatchID, complete.BatchID)
+
+	statusRes, err := messages.CaveBatchStatus.TestCall(rc, butlerd.CaveBatchStatusParams{
+		BatchID: batchRes.BatchID,
+	})
+	must(err)
+	assert.True(statusRes.Done)
+	assert.EqualValues(1, statusRes.Progress)
+
+	states := make(map[string]butlerd.CaveBatchItemState)
+	for _, item := range statusRes.Caves {
+		states[item.CaveID] = item.State
+		if item.State == butlerd.CaveBatchItemStateFailed {
+			t.Logf("cave %s failed: %s", item.CaveID, item.Error)
+		}
+	}
+	assert.EqualValues(map[string]butlerd.CaveBatchItemState{
+		outdated1: butlerd.CaveBatchItemStateUpdated,
+		upToDate:  butlerd.CaveBatchItemStateUpToDate,
+		outdated2: butlerd.CaveBatchItemStateUpdated,
+	}, states)
+	assert.Len(complete.Caves, 3)
+
+	for _, caveID := range []string{outdated1, outdated2} {
+		caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+			CaveID: caveID,
+		})
+		must(err)
+		checkRes, err := messages.CheckUpdate.TestCall(rc, butlerd.CheckUpdateParams{
+			CaveIDs: []string{caveID},
+		})
+		must(err)
+		assert.Empty(checkRes.Updates, "%s is now on the latest build (%d)", caveRes.Cave.Game.Title, caveRes.Cave.Build.ID)
+	}
+}
+
+// installOldestBuild publishes a game with pushBuilds builds,
+// and installs the oldest one. It returns the cave's ID.
+func installOldestBuild(bi *ButlerInstance, _developer *mitch.User, title string, pushBuilds int) string {
+	rc, _, _ := bi.Unwrap()
+
+	_game := _developer.MakeGame(title)
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.ChannelName = "default"
+	for i := 0; i < pushBuilds; i++ {
+		version := fmt.Sprintf("%s v%d", title, i+1)
+		_upload.PushBuild(func(ac *mitch.ArchiveContext) {
+			ac.SetName("default.zip")
+			ac.Entry("version.txt").String(version)
+		})
+	}
+
+	game := bi.FetchGame(_game.ID)
+	uploadsRes, err := bi.Client().ListGameUploads(rc.Ctx, itchio.ListGameUploadsParams{
+		GameID: game.ID,
+	})
+	must(err)
+	upload := uploadsRes.Uploads[0]
+
+	buildsRes, err := bi.Client().ListUploadBuilds(rc.Ctx, itchio.ListUploadBuildsParams{
+		UploadID: upload.ID,
+	})
+	must(err)
+	oldestBuild := buildsRes.Builds[len(buildsRes.Builds)-1]
+
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+		Upload:            upload,
+		Build:             oldestBuild,
+	})
+	must(err)
+	_, err = messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+		ID:            queueRes.ID,
+		StagingFolder: queueRes.StagingFolder,
+	})
+	must(err)
+	return queueRes.CaveID
+}
@@ -0,0 +1,64 @@
+package integrate
+
+import (
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_UpdateGameGone(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Vanishing Act")
+	_game := _developer.MakeGame("Here Today")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContents()
+
+	game := bi.FetchGame(_game.ID)
+	caveID := bi.Install(butlerd.InstallQueueParams{
+		Game: game,
+	}).CaveID
+
+	delisted := false
+	messages.GameDelisted.Register(h, func(params butlerd.GameDelistedNotification) {
+		delisted = true
+	})
+
+	checkUpdate := func() *butlerd.CheckUpdateResult {
+		res, err := messages.CheckUpdate.TestCall(rc, butlerd.CheckUpdateParams{
+			CaveIDs: []string{caveID},
+		})
+		must(err)
+		return res
+	}
+	caveDelisted := func() bool {
+		res, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+			CaveID: caveID,
+		})
+		must(err)
+		return res.Cave.InstallInfo.GameDelisted
+	}
+
+	// the game gets taken down for a bit
+	delete(store.Games, _game.ID)
+	for i := 0; i < 5; i++ {
+		assert.NotEmpty(checkUpdate().Warnings, "not found while it's gone")
+	}
+	assert.False(caveDelisted(), "a few failed checks in a row don't delist a game")
+	assert.False(delisted)
+
+	store.Games[_game.ID] = _game
+	assert.Empty(checkUpdate().Warnings)
+	assert.False(caveDelisted())
+}
@@ -0,0 +1,96 @@
+package integrate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CaveAutoUpdatePolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	_developer := bi.Server.Store().MakeUser("Policy Maker")
+	always := installOldestBuild(bi, _developer, "Always Fresh", 2)
+	never := installOldestBuild(bi, _developer, "Frozen in Time", 2)
+	accepted := installOldestBuild(bi, _developer, "Ask First", 2)
+	declined := installOldestBuild(bi, _developer, "Ask Again", 2)
+
+	caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{CaveID: always})
+	must(err)
+	assert.EqualValues(butlerd.CaveAutoUpdatePolicyAlways, caveRes.Cave.InstallInfo.AutoUpdatePolicy, "default policy")
+
+	_, err = messages.CavesSetAutoUpdatePolicy.TestCall(rc, butlerd.CavesSetAutoUpdatePolicyParams{
+		CaveID: always,
+		Policy: "sometimes",
+	})
+	assert.Error(err, "unknown policies are rejected")
+
+	setPolicy := func(caveID string, policy butlerd.CaveAutoUpdatePolicy) {
+		_, err := messages.CavesSetAutoUpdatePolicy.TestCall(rc, butlerd.CavesSetAutoUpdatePolicyParams{
+			CaveID: caveID,
+			Policy: policy,
+		})
+		must(err)
+	}
+	setPolicy(never, butlerd.CaveAutoUpdatePolicyNever)
+	setPolicy(accepted, butlerd.CaveAutoUpdatePolicyAsk)
+	setPolicy(declined, butlerd.CaveAutoUpdatePolicyAsk)
+
+	checkRes, err := messages.CheckUpdate.TestCall(rc, butlerd.CheckUpdateParams{
+		CaveIDs: []string{never},
+	})
+	must(err)
+	if assert.Len(checkRes.Updates, 1, "update checks ignore the policy") {
+		assert.EqualValues(butlerd.CaveAutoUpdatePolicyNever, checkRes.Updates[0].AutoUpdatePolicy)
+	}
+
+	asked := make(map[string]bool)
+	messages.UpdateAvailableAskUser.TestRegister(h, func(rc *butlerd.RequestContext, params butlerd.UpdateAvailableAskUserParams) (*butlerd.UpdateAvailableAskUserResult, error) {
+		asked[params.CaveID] = true
+		assert.NotNil(params.Choice.Build)
+		return &butlerd.UpdateAvailableAskUserResult{
+			Accept: params.CaveID == accepted,
+		}, nil
+	})
+
+	completions := make(chan butlerd.BatchUpdateCompleteNotification, 1)
+	messages.BatchUpdateComplete.Register(h, func(params butlerd.BatchUpdateCompleteNotification) {
+		completions <- params
+	})
+
+	_, err = messages.CaveUpdateBatch.TestCall(rc, butlerd.CaveUpdateBatchParams{
+		CaveIDs: []string{always, never, accepted, declined},
+	})
+	must(err)
+
+	var complete butlerd.BatchUpdateCompleteNotification
+	select {
+	case complete = <-completions:
+	case <-time.After(30 * time.Second):
+		t.Fatal("batch didn't complete in time")
+	}
+
+	states := make(map[string]butlerd.CaveBatchItemState)
+	for _, item := range complete.Caves {
+		states[item.CaveID] = item.State
+		if item.State == butlerd.CaveBatchItemStateFailed {
+			t.Logf("cave %s failed: %s", item.CaveID, item.Error)
+		}
+	}
+	assert.EqualValues(map[string]butlerd.CaveBatchItemState{
+		always:   butlerd.CaveBatchItemStateUpdated,
+		never:    butlerd.CaveBatchItemStateSkipped,
+		accepted: butlerd.CaveBatchItemStateUpdated,
+		declined: butlerd.CaveBatchItemStateSkipped,
+	}, states)
+	assert.EqualValues(map[string]bool{accepted: true, declined: true}, asked)
+}
@@ -0,0 +1,145 @@
+package integrate
+
+import (
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/jsonrpc2"
+	"github.com/itchio/butler/butlerd/messages"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_UpdateReplacedUpload(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Port Authority")
+	_game := _developer.MakeGame("Engine Hopper")
+	_game.Publish()
+
+	makeUpload := func(linuxOnly bool) *mitch.Upload {
+		_upload := _game.MakeUpload("Portable")
+		if linuxOnly {
+			_upload.PlatformLinux = true
+		} else {
+			_upload.SetAllPlatforms()
+		}
+		_upload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+			ac.Entry("readme.txt").String("Now on a different engine")
+		})
+		return _upload
+	}
+
+	_original := makeUpload(false)
+
+	game := bi.FetchGame(_game.ID)
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+	})
+	must(err)
+	_, err = messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+		ID:            queueRes.ID,
+		StagingFolder: queueRes.StagingFolder,
+	})
+	must(err)
+	caveID := queueRes.CaveID
+
+	checkUpdate := func() []*butlerd.GameUpdate {
+		res, err := messages.CheckUpdate.TestCall(rc, butlerd.CheckUpdateParams{
+			CaveIDs: []string{caveID},
+		})
+		must(err)
+		assert.Empty(res.Warnings)
+		return res.Updates
+	}
+
+	assert.Empty(checkUpdate(), "no updates while the upload exists")
+
+	// the developer deletes the upload and adds new ones
+	delete(store.Uploads, _original.ID)
+	_successor := makeUpload(false)
+	_soundtrack := makeUpload(false)
+	_soundtrack.Type = "soundtrack"
+	makeUpload(true)
+
+	var successor *itchio.Upload
+	updates := checkUpdate()
+	if assert.Len(updates, 1) {
+		update := updates[0]
+		assert.True(update.UploadReplaced)
+		if assert.Len(update.Choices, 1, "only uploads that look like the original are suggested") {
+			choice := update.Choices[0]
+			assert.EqualValues(_successor.ID, choice.Upload.ID)
+			assert.True(choice.UploadReplaced)
+			assert.False(choice.Confirmed)
+			successor = choice.Upload
+		}
+	}
+	if successor == nil {
+		return
+	}
+
+	queueUpdate := func() (*butlerd.InstallQueueResult, error) {
+		return messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+			CaveID: caveID,
+			Upload: successor,
+			Reason: butlerd.DownloadReasonUpdate,
+		})
+	}
+
+	_, err = queueUpdate()
+	if assert.Error(err, "switching upload requires confirmation") {
+		je, ok := err.(*jsonrpc2.Error)
+		if assert.True(ok, "should be a jsonrpc2 error") {
+			assert.EqualValues(butlerd.CodeUploadSuccessorNotConfirmed, je.Code)
+		}
+	}
+
+	_, err = messages.ConfirmUploadSuccessor.TestCall(rc, butlerd.ConfirmUploadSuccessorParams{
+		CaveID:   caveID,
+		UploadID: _successor.ID,
+	})
+	must(err)
+
+	updates = checkUpdate()
+	if assert.Len(updates, 1) && assert.Len(updates[0].Choices, 1) {
+		choice := updates[0].Choices[0]
+		assert.EqualValues(_successor.ID, choice.Upload.ID)
+		assert.True(choice.Confirmed, "the answer is remembered")
+	}
+
+	queueRes, err = queueUpdate()
+	must(err)
+	_, err = messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+		ID:            queueRes.ID,
+		StagingFolder: queueRes.StagingFolder,
+	})
+	must(err)
+
+	assert.Empty(checkUpdate(), "successor is now installed")
+
+	// replaced again, but this time the user says no
+	delete(store.Uploads, _successor.ID)
+	makeUpload(false)
+
+	updates = checkUpdate()
+	if assert.Len(updates, 1) {
+		assert.True(updates[0].UploadReplaced)
+	}
+
+	_, err = messages.ConfirmUploadSuccessor.TestCall(rc, butlerd.ConfirmUploadSuccessorParams{
+		CaveID: caveID,
+	})
+	must(err)
+
+	assert.Empty(checkUpdate(), "declined replacements aren't offered again")
+}
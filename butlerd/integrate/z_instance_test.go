@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"os/exec"
 	"sync"
@@ -17,6 +19,7 @@ import (
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/butlerd/jsonrpc2"
 	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/fakeapi"
 	"github.com/itchio/headway/state"
 	"github.com/itchio/mitch"
 	"github.com/pkg/errors"
@@ -44,10 +47,47 @@ type ButlerInstance struct {
 }
 
 type instanceOpts struct {
+	daemonArgs []string
+	behaviors  *fakeapi.Behaviors
 }
 
 type instanceOpt func(o *instanceOpts)
 
+// withMockTransfers starts the daemon with simulated downloads
+// and extractions going at the given speed
+func withMockTransfers(bytesPerSecond int64) instanceOpt {
+	return func(o *instanceOpts) {
+		o.daemonArgs = append(o.daemonArgs,
+			"--mock-transfers",
+			"--mock-transfers-speed", fmt.Sprintf("%d", bytesPerSecond),
+		)
+	}
+}
+
+// withFakeAPIBehaviors has the daemon talk to the mock
+// server through a proxy that misbehaves like b says
+func withFakeAPIBehaviors(b fakeapi.Behaviors) instanceOpt {
+	return func(o *instanceOpts) {
+		o.behaviors = &b
+	}
+}
+
+// withIdleTimeout starts the daemon with connections expiring
+// after being idle for the given duration
+func withIdleTimeout(d time.Duration) instanceOpt {
+	return func(o *instanceOpts) {
+		o.daemonArgs = append(o.daemonArgs, "--idle-timeout", d.String())
+	}
+}
+
+// withAllowParentTree starts the daemon only accepting connections
+// from the test process and its descendants
+func withAllowParentTree() instanceOpt {
+	return func(o *instanceOpts) {
+		o.daemonArgs = append(o.daemonArgs, "--allow-parent-tree")
+	}
+}
+
 func init() {
 	color.NoColor = false
 }
@@ -121,8 +161,19 @@ func newInstance(t *testing.T, options ...instanceOpt) *ButlerInstance {
 		"--destiny-pid", conf.PidString,
 		"--destiny-pid", conf.PpidString,
 	}
+	args = append(args, opts.daemonArgs...)
 	{
 		addressString := fmt.Sprintf("http://%s", server.Address())
+		if opts.behaviors != nil {
+			upstream, err := url.Parse(addressString)
+			must(err)
+			proxy := httptest.NewServer(fakeapi.Proxy(upstream, *opts.behaviors))
+			go func() {
+				<-ctx.Done()
+				proxy.Close()
+			}()
+			addressString = proxy.URL
+		}
 		args = append(args, "--address", addressString)
 		logf("Using mock server %s", addressString)
 	}
@@ -64,6 +64,9 @@ type OutgoingCall func(msg Message)
 
 type Conn interface {
 	Call(method string, params interface{}, result interface{}) error
+	// CallContext is like Call, but stops waiting for the response,
+	// and returns ctx.Err(), once ctx is done.
+	CallContext(ctx context.Context, method string, params interface{}, result interface{}) error
 	Notify(method string, params interface{}) error
 	Context() context.Context
 	Close()
@@ -298,6 +301,10 @@ func (c *connImpl) Notify(method string, params interface{}) error {
 }
 
 func (c *connImpl) Call(method string, params interface{}, result interface{}) error {
+	return c.CallContext(context.Background(), method, params, result)
+}
+
+func (c *connImpl) CallContext(ctx context.Context, method string, params interface{}, result interface{}) error {
 	paramsText, err := EncodeJSON(params)
 	if err != nil {
 		return err
@@ -310,20 +317,13 @@ func (c *connImpl) Call(method string, params interface{}, result interface{}) e
 		Params: &paramsText,
 	}
 
-	done := make(chan error)
+	// buffered, so a late response doesn't block the reader
+	// if the caller stopped waiting. It's only decoded into result
+	// on our side: by then, result may not be the caller's anymore.
+	done := make(chan Message, 1)
 
 	f := func(msg Message) {
-		done <- (func() error {
-			if msg.Error != nil {
-				return msg.Error
-			}
-
-			if msg.Result == nil {
-				return errors.New("json-rpc2: invalid response: no 'error' nor 'result' field")
-			}
-
-			return DecodeJSON(*msg.Result, result)
-		})()
+		done <- msg
 	}
 	c.outgoingCallsMutex.Lock()
 	c.outgoingCalls[id] = f
@@ -335,10 +335,23 @@ func (c *connImpl) Call(method string, params interface{}, result interface{}) e
 	}
 
 	select {
-	case err := <-done:
-		return err
+	case msg := <-done:
+		if msg.Error != nil {
+			return msg.Error
+		}
+
+		if msg.Result == nil {
+			return errors.New("json-rpc2: invalid response: no 'error' nor 'result' field")
+		}
+
+		return DecodeJSON(*msg.Result, result)
 	case <-c.ctx.Done():
 		return errors.New("json-rpc2: connection closed")
+	case <-ctx.Done():
+		c.outgoingCallsMutex.Lock()
+		delete(c.outgoingCalls, id)
+		c.outgoingCallsMutex.Unlock()
+		return ctx.Err()
 	}
 }
 
@@ -289,6 +289,46 @@ func (r *NetworkSetBandwidthThrottleType) TestCall(rc *butlerd.RequestContext, p
 
 var NetworkSetBandwidthThrottle *NetworkSetBandwidthThrottleType
 
+// Network.Diagnostics (Request)
+
+type NetworkDiagnosticsType struct {}
+
+var _ RequestMessage = (*NetworkDiagnosticsType)(nil)
+
+func (r *NetworkDiagnosticsType) Method() string {
+  return "Network.Diagnostics"
+}
+
+func (r *NetworkDiagnosticsType) Register(router router, f func(*butlerd.RequestContext, butlerd.NetworkDiagnosticsParams) (*butlerd.NetworkDiagnosticsResult, error)) {
+  router.Register("Network.Diagnostics", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.NetworkDiagnosticsParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Network.Diagnostics")
+    }
+    return res, nil
+  })
+}
+
+func (r *NetworkDiagnosticsType) TestCall(rc *butlerd.RequestContext, params butlerd.NetworkDiagnosticsParams) (*butlerd.NetworkDiagnosticsResult, error) {
+  var result butlerd.NetworkDiagnosticsResult
+  err := rc.Call("Network.Diagnostics", params, &result)
+  return &result, err
+}
+
+var NetworkDiagnostics *NetworkDiagnosticsType
+
 
 //==============================
 // Miscellaneous
@@ -352,6 +392,35 @@ func (r *DownloadsDriveStartedType) Register(router router, f func(butlerd.Downl
 
 var DownloadsDriveStarted *DownloadsDriveStartedType
 
+// Downloads.Drive.Preempted (Notification)
+
+type DownloadsDrivePreemptedType struct {}
+
+var _ NotificationMessage = (*DownloadsDrivePreemptedType)(nil)
+
+func (r *DownloadsDrivePreemptedType) Method() string {
+  return "Downloads.Drive.Preempted"
+}
+
+func (r *DownloadsDrivePreemptedType) Notify(rc *butlerd.RequestContext, params butlerd.DownloadsDrivePreemptedNotification) (error) {
+  return rc.Notify("Downloads.Drive.Preempted", params)
+}
+
+func (r *DownloadsDrivePreemptedType) Register(router router, f func(butlerd.DownloadsDrivePreemptedNotification)) {
+  router.RegisterNotification("Downloads.Drive.Preempted", func (notif jsonrpc2.Notification) {
+    var params butlerd.DownloadsDrivePreemptedNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var DownloadsDrivePreempted *DownloadsDrivePreemptedType
+
 // Downloads.Drive.Errored (Notification)
 
 type DownloadsDriveErroredType struct {}
@@ -468,6 +537,35 @@ func (r *DownloadsDriveNetworkStatusType) Register(router router, f func(butlerd
 
 var DownloadsDriveNetworkStatus *DownloadsDriveNetworkStatusType
 
+// Downloads.Drive.JitterHigh (Notification)
+
+type DownloadJitterHighType struct {}
+
+var _ NotificationMessage = (*DownloadJitterHighType)(nil)
+
+func (r *DownloadJitterHighType) Method() string {
+  return "Downloads.Drive.JitterHigh"
+}
+
+func (r *DownloadJitterHighType) Notify(rc *butlerd.RequestContext, params butlerd.DownloadJitterHighNotification) (error) {
+  return rc.Notify("Downloads.Drive.JitterHigh", params)
+}
+
+func (r *DownloadJitterHighType) Register(router router, f func(butlerd.DownloadJitterHighNotification)) {
+  router.RegisterNotification("Downloads.Drive.JitterHigh", func (notif jsonrpc2.Notification) {
+    var params butlerd.DownloadJitterHighNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var DownloadJitterHigh *DownloadJitterHighType
+
 // Log (Notification)
 
 type LogType struct {}
@@ -498,6 +596,160 @@ func (r *LogType) Register(router router, f func(butlerd.LogNotification)) {
 var Log *LogType
 
 
+//==============================
+// Settings
+//==============================
+
+// Settings.Get (Request)
+
+type SettingsGetType struct {}
+
+var _ RequestMessage = (*SettingsGetType)(nil)
+
+func (r *SettingsGetType) Method() string {
+  return "Settings.Get"
+}
+
+func (r *SettingsGetType) Register(router router, f func(*butlerd.RequestContext, butlerd.SettingsGetParams) (*butlerd.SettingsGetResult, error)) {
+  router.Register("Settings.Get", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.SettingsGetParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Settings.Get")
+    }
+    return res, nil
+  })
+}
+
+func (r *SettingsGetType) TestCall(rc *butlerd.RequestContext, params butlerd.SettingsGetParams) (*butlerd.SettingsGetResult, error) {
+  var result butlerd.SettingsGetResult
+  err := rc.Call("Settings.Get", params, &result)
+  return &result, err
+}
+
+var SettingsGet *SettingsGetType
+
+// Settings.List (Request)
+
+type SettingsListType struct {}
+
+var _ RequestMessage = (*SettingsListType)(nil)
+
+func (r *SettingsListType) Method() string {
+  return "Settings.List"
+}
+
+func (r *SettingsListType) Register(router router, f func(*butlerd.RequestContext, butlerd.SettingsListParams) (*butlerd.SettingsListResult, error)) {
+  router.Register("Settings.List", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.SettingsListParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Settings.List")
+    }
+    return res, nil
+  })
+}
+
+func (r *SettingsListType) TestCall(rc *butlerd.RequestContext, params butlerd.SettingsListParams) (*butlerd.SettingsListResult, error) {
+  var result butlerd.SettingsListResult
+  err := rc.Call("Settings.List", params, &result)
+  return &result, err
+}
+
+var SettingsList *SettingsListType
+
+// Settings.Set (Request)
+
+type SettingsSetType struct {}
+
+var _ RequestMessage = (*SettingsSetType)(nil)
+
+func (r *SettingsSetType) Method() string {
+  return "Settings.Set"
+}
+
+func (r *SettingsSetType) Register(router router, f func(*butlerd.RequestContext, butlerd.SettingsSetParams) (*butlerd.SettingsSetResult, error)) {
+  router.Register("Settings.Set", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.SettingsSetParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Settings.Set")
+    }
+    return res, nil
+  })
+}
+
+func (r *SettingsSetType) TestCall(rc *butlerd.RequestContext, params butlerd.SettingsSetParams) (*butlerd.SettingsSetResult, error) {
+  var result butlerd.SettingsSetResult
+  err := rc.Call("Settings.Set", params, &result)
+  return &result, err
+}
+
+var SettingsSet *SettingsSetType
+
+// SettingChanged (Notification)
+
+type SettingChangedType struct {}
+
+var _ NotificationMessage = (*SettingChangedType)(nil)
+
+func (r *SettingChangedType) Method() string {
+  return "SettingChanged"
+}
+
+func (r *SettingChangedType) Notify(rc *butlerd.RequestContext, params butlerd.SettingChangedNotification) (error) {
+  return rc.Notify("SettingChanged", params)
+}
+
+func (r *SettingChangedType) Register(router router, f func(butlerd.SettingChangedNotification)) {
+  router.RegisterNotification("SettingChanged", func (notif jsonrpc2.Notification) {
+    var params butlerd.SettingChangedNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var SettingChanged *SettingChangedType
+
+
 //==============================
 // Profile
 //==============================
@@ -753,7 +1005,7 @@ func (r *ProfileForgetType) Method() string {
 }
 
 func (r *ProfileForgetType) Register(router router, f func(*butlerd.RequestContext, butlerd.ProfileForgetParams) (*butlerd.ProfileForgetResult, error)) {
-  router.Register("Profile.Forget", func (rc *butlerd.RequestContext) (interface{}, error) {
+  router.Register("Profile.Forget", butlerd.TransactionMiddleware(func (rc *butlerd.RequestContext) (interface{}, error) {
     var params butlerd.ProfileForgetParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
@@ -771,7 +1023,7 @@ func (r *ProfileForgetType) Register(router router, f func(*butlerd.RequestConte
     	return nil, errors.New("internal error: nil result for Profile.Forget")
     }
     return res, nil
-  })
+  }))
 }
 
 func (r *ProfileForgetType) TestCall(rc *butlerd.RequestContext, params butlerd.ProfileForgetParams) (*butlerd.ProfileForgetResult, error) {
@@ -782,6 +1034,46 @@ func (r *ProfileForgetType) TestCall(rc *butlerd.RequestContext, params butlerd.
 
 var ProfileForget *ProfileForgetType
 
+// Profile.DataErase (Request)
+
+type ProfileDataEraseType struct {}
+
+var _ RequestMessage = (*ProfileDataEraseType)(nil)
+
+func (r *ProfileDataEraseType) Method() string {
+  return "Profile.DataErase"
+}
+
+func (r *ProfileDataEraseType) Register(router router, f func(*butlerd.RequestContext, butlerd.ProfileDataEraseParams) (*butlerd.ProfileDataEraseResult, error)) {
+  router.Register("Profile.DataErase", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.ProfileDataEraseParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Profile.DataErase")
+    }
+    return res, nil
+  })
+}
+
+func (r *ProfileDataEraseType) TestCall(rc *butlerd.RequestContext, params butlerd.ProfileDataEraseParams) (*butlerd.ProfileDataEraseResult, error) {
+  var result butlerd.ProfileDataEraseResult
+  err := rc.Call("Profile.DataErase", params, &result)
+  return &result, err
+}
+
+var ProfileDataErase *ProfileDataEraseType
+
 // Profile.Data.Put (Request)
 
 type ProfileDataPutType struct {}
@@ -1512,6 +1804,35 @@ func (r *FetchCavesType) TestCall(rc *butlerd.RequestContext, params butlerd.Fet
 
 var FetchCaves *FetchCavesType
 
+// Fetch.Chunk (Notification)
+
+type FetchChunkType struct {}
+
+var _ NotificationMessage = (*FetchChunkType)(nil)
+
+func (r *FetchChunkType) Method() string {
+  return "Fetch.Chunk"
+}
+
+func (r *FetchChunkType) Notify(rc *butlerd.RequestContext, params butlerd.FetchChunkNotification) (error) {
+  return rc.Notify("Fetch.Chunk", params)
+}
+
+func (r *FetchChunkType) Register(router router, f func(butlerd.FetchChunkNotification)) {
+  router.RegisterNotification("Fetch.Chunk", func (notif jsonrpc2.Notification) {
+    var params butlerd.FetchChunkNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var FetchChunk *FetchChunkType
+
 // Fetch.Cave (Request)
 
 type FetchCaveType struct {}
@@ -1637,6 +1958,46 @@ func (r *GameFindUploadsType) TestCall(rc *butlerd.RequestContext, params butler
 
 var GameFindUploads *GameFindUploadsType
 
+// Install.ExplainUploadChoice (Request)
+
+type InstallExplainUploadChoiceType struct {}
+
+var _ RequestMessage = (*InstallExplainUploadChoiceType)(nil)
+
+func (r *InstallExplainUploadChoiceType) Method() string {
+  return "Install.ExplainUploadChoice"
+}
+
+func (r *InstallExplainUploadChoiceType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallExplainUploadChoiceParams) (*butlerd.InstallExplainUploadChoiceResult, error)) {
+  router.Register("Install.ExplainUploadChoice", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.InstallExplainUploadChoiceParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Install.ExplainUploadChoice")
+    }
+    return res, nil
+  })
+}
+
+func (r *InstallExplainUploadChoiceType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallExplainUploadChoiceParams) (*butlerd.InstallExplainUploadChoiceResult, error) {
+  var result butlerd.InstallExplainUploadChoiceResult
+  err := rc.Call("Install.ExplainUploadChoice", params, &result)
+  return &result, err
+}
+
+var InstallExplainUploadChoice *InstallExplainUploadChoiceType
+
 // Install.Queue (Request)
 
 type InstallQueueType struct {}
@@ -1677,19 +2038,19 @@ func (r *InstallQueueType) TestCall(rc *butlerd.RequestContext, params butlerd.I
 
 var InstallQueue *InstallQueueType
 
-// Install.Plan (Request)
+// Install.QueueMany (Request)
 
-type InstallPlanType struct {}
+type InstallQueueManyType struct {}
 
-var _ RequestMessage = (*InstallPlanType)(nil)
+var _ RequestMessage = (*InstallQueueManyType)(nil)
 
-func (r *InstallPlanType) Method() string {
-  return "Install.Plan"
+func (r *InstallQueueManyType) Method() string {
+  return "Install.QueueMany"
 }
 
-func (r *InstallPlanType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallPlanParams) (*butlerd.InstallPlanResult, error)) {
-  router.Register("Install.Plan", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.InstallPlanParams
+func (r *InstallQueueManyType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallQueueManyParams) (*butlerd.InstallQueueManyResult, error)) {
+  router.Register("Install.QueueMany", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.InstallQueueManyParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -1703,33 +2064,33 @@ func (r *InstallPlanType) Register(router router, f func(*butlerd.RequestContext
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for Install.Plan")
+    	return nil, errors.New("internal error: nil result for Install.QueueMany")
     }
     return res, nil
   })
 }
 
-func (r *InstallPlanType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallPlanParams) (*butlerd.InstallPlanResult, error) {
-  var result butlerd.InstallPlanResult
-  err := rc.Call("Install.Plan", params, &result)
+func (r *InstallQueueManyType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallQueueManyParams) (*butlerd.InstallQueueManyResult, error) {
+  var result butlerd.InstallQueueManyResult
+  err := rc.Call("Install.QueueMany", params, &result)
   return &result, err
 }
 
-var InstallPlan *InstallPlanType
+var InstallQueueMany *InstallQueueManyType
 
-// Caves.SetPinned (Request)
+// PickUploads (Request)
 
-type CavesSetPinnedType struct {}
+type PickUploadsType struct {}
 
-var _ RequestMessage = (*CavesSetPinnedType)(nil)
+var _ RequestMessage = (*PickUploadsType)(nil)
 
-func (r *CavesSetPinnedType) Method() string {
-  return "Caves.SetPinned"
+func (r *PickUploadsType) Method() string {
+  return "PickUploads"
 }
 
-func (r *CavesSetPinnedType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesSetPinnedParams) (*butlerd.CavesSetPinnedResult, error)) {
-  router.Register("Caves.SetPinned", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.CavesSetPinnedParams
+func (r *PickUploadsType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.PickUploadsParams) (*butlerd.PickUploadsResult, error)) {
+  router.Register("PickUploads", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.PickUploadsParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -1743,33 +2104,33 @@ func (r *CavesSetPinnedType) Register(router router, f func(*butlerd.RequestCont
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for Caves.SetPinned")
+    	return nil, errors.New("internal error: nil result for PickUploads")
     }
     return res, nil
   })
 }
 
-func (r *CavesSetPinnedType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesSetPinnedParams) (*butlerd.CavesSetPinnedResult, error) {
-  var result butlerd.CavesSetPinnedResult
-  err := rc.Call("Caves.SetPinned", params, &result)
+func (r *PickUploadsType) Call(rc *butlerd.RequestContext, params butlerd.PickUploadsParams) (*butlerd.PickUploadsResult, error) {
+  var result butlerd.PickUploadsResult
+  err := rc.Call("PickUploads", params, &result)
   return &result, err
 }
 
-var CavesSetPinned *CavesSetPinnedType
+var PickUploads *PickUploadsType
 
-// Install.CreateShortcut (Request)
+// Collections.InstallAll (Request)
 
-type InstallCreateShortcutType struct {}
+type CollectionsInstallAllType struct {}
 
-var _ RequestMessage = (*InstallCreateShortcutType)(nil)
+var _ RequestMessage = (*CollectionsInstallAllType)(nil)
 
-func (r *InstallCreateShortcutType) Method() string {
-  return "Install.CreateShortcut"
+func (r *CollectionsInstallAllType) Method() string {
+  return "Collections.InstallAll"
 }
 
-func (r *InstallCreateShortcutType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallCreateShortcutParams) (*butlerd.InstallCreateShortcutResult, error)) {
-  router.Register("Install.CreateShortcut", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.InstallCreateShortcutParams
+func (r *CollectionsInstallAllType) Register(router router, f func(*butlerd.RequestContext, butlerd.CollectionsInstallAllParams) (*butlerd.CollectionsInstallAllResult, error)) {
+  router.Register("Collections.InstallAll", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CollectionsInstallAllParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -1783,33 +2144,33 @@ func (r *InstallCreateShortcutType) Register(router router, f func(*butlerd.Requ
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for Install.CreateShortcut")
+    	return nil, errors.New("internal error: nil result for Collections.InstallAll")
     }
     return res, nil
   })
 }
 
-func (r *InstallCreateShortcutType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallCreateShortcutParams) (*butlerd.InstallCreateShortcutResult, error) {
-  var result butlerd.InstallCreateShortcutResult
-  err := rc.Call("Install.CreateShortcut", params, &result)
+func (r *CollectionsInstallAllType) TestCall(rc *butlerd.RequestContext, params butlerd.CollectionsInstallAllParams) (*butlerd.CollectionsInstallAllResult, error) {
+  var result butlerd.CollectionsInstallAllResult
+  err := rc.Call("Collections.InstallAll", params, &result)
   return &result, err
 }
 
-var InstallCreateShortcut *InstallCreateShortcutType
+var CollectionsInstallAll *CollectionsInstallAllType
 
-// Install.Perform (Request)
+// Collections.InstallAll.Cancel (Request)
 
-type InstallPerformType struct {}
+type CollectionsInstallAllCancelType struct {}
 
-var _ RequestMessage = (*InstallPerformType)(nil)
+var _ RequestMessage = (*CollectionsInstallAllCancelType)(nil)
 
-func (r *InstallPerformType) Method() string {
-  return "Install.Perform"
+func (r *CollectionsInstallAllCancelType) Method() string {
+  return "Collections.InstallAll.Cancel"
 }
 
-func (r *InstallPerformType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallPerformParams) (*butlerd.InstallPerformResult, error)) {
-  router.Register("Install.Perform", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.InstallPerformParams
+func (r *CollectionsInstallAllCancelType) Register(router router, f func(*butlerd.RequestContext, butlerd.CollectionsInstallAllCancelParams) (*butlerd.CollectionsInstallAllCancelResult, error)) {
+  router.Register("Collections.InstallAll.Cancel", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CollectionsInstallAllCancelParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -1823,33 +2184,62 @@ func (r *InstallPerformType) Register(router router, f func(*butlerd.RequestCont
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for Install.Perform")
+    	return nil, errors.New("internal error: nil result for Collections.InstallAll.Cancel")
     }
     return res, nil
   })
 }
 
-func (r *InstallPerformType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallPerformParams) (*butlerd.InstallPerformResult, error) {
-  var result butlerd.InstallPerformResult
-  err := rc.Call("Install.Perform", params, &result)
+func (r *CollectionsInstallAllCancelType) TestCall(rc *butlerd.RequestContext, params butlerd.CollectionsInstallAllCancelParams) (*butlerd.CollectionsInstallAllCancelResult, error) {
+  var result butlerd.CollectionsInstallAllCancelResult
+  err := rc.Call("Collections.InstallAll.Cancel", params, &result)
   return &result, err
 }
 
-var InstallPerform *InstallPerformType
+var CollectionsInstallAllCancel *CollectionsInstallAllCancelType
 
-// Install.Cancel (Request)
+// GameReleased (Notification)
 
-type InstallCancelType struct {}
+type GameReleasedType struct {}
 
-var _ RequestMessage = (*InstallCancelType)(nil)
+var _ NotificationMessage = (*GameReleasedType)(nil)
 
-func (r *InstallCancelType) Method() string {
-  return "Install.Cancel"
+func (r *GameReleasedType) Method() string {
+  return "GameReleased"
 }
 
-func (r *InstallCancelType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallCancelParams) (*butlerd.InstallCancelResult, error)) {
-  router.Register("Install.Cancel", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.InstallCancelParams
+func (r *GameReleasedType) Notify(rc *butlerd.RequestContext, params butlerd.GameReleasedNotification) (error) {
+  return rc.Notify("GameReleased", params)
+}
+
+func (r *GameReleasedType) Register(router router, f func(butlerd.GameReleasedNotification)) {
+  router.RegisterNotification("GameReleased", func (notif jsonrpc2.Notification) {
+    var params butlerd.GameReleasedNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var GameReleased *GameReleasedType
+
+// Install.Plan (Request)
+
+type InstallPlanType struct {}
+
+var _ RequestMessage = (*InstallPlanType)(nil)
+
+func (r *InstallPlanType) Method() string {
+  return "Install.Plan"
+}
+
+func (r *InstallPlanType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallPlanParams) (*butlerd.InstallPlanResult, error)) {
+  router.Register("Install.Plan", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.InstallPlanParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -1863,33 +2253,33 @@ func (r *InstallCancelType) Register(router router, f func(*butlerd.RequestConte
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for Install.Cancel")
+    	return nil, errors.New("internal error: nil result for Install.Plan")
     }
     return res, nil
   })
 }
 
-func (r *InstallCancelType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallCancelParams) (*butlerd.InstallCancelResult, error) {
-  var result butlerd.InstallCancelResult
-  err := rc.Call("Install.Cancel", params, &result)
+func (r *InstallPlanType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallPlanParams) (*butlerd.InstallPlanResult, error) {
+  var result butlerd.InstallPlanResult
+  err := rc.Call("Install.Plan", params, &result)
   return &result, err
 }
 
-var InstallCancel *InstallCancelType
+var InstallPlan *InstallPlanType
 
-// Uninstall.Perform (Request)
+// Caves.SetPinned (Request)
 
-type UninstallPerformType struct {}
+type CavesSetPinnedType struct {}
 
-var _ RequestMessage = (*UninstallPerformType)(nil)
+var _ RequestMessage = (*CavesSetPinnedType)(nil)
 
-func (r *UninstallPerformType) Method() string {
-  return "Uninstall.Perform"
+func (r *CavesSetPinnedType) Method() string {
+  return "Caves.SetPinned"
 }
 
-func (r *UninstallPerformType) Register(router router, f func(*butlerd.RequestContext, butlerd.UninstallPerformParams) (*butlerd.UninstallPerformResult, error)) {
-  router.Register("Uninstall.Perform", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.UninstallPerformParams
+func (r *CavesSetPinnedType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesSetPinnedParams) (*butlerd.CavesSetPinnedResult, error)) {
+  router.Register("Caves.SetPinned", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesSetPinnedParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -1903,33 +2293,33 @@ func (r *UninstallPerformType) Register(router router, f func(*butlerd.RequestCo
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for Uninstall.Perform")
+    	return nil, errors.New("internal error: nil result for Caves.SetPinned")
     }
     return res, nil
   })
 }
 
-func (r *UninstallPerformType) TestCall(rc *butlerd.RequestContext, params butlerd.UninstallPerformParams) (*butlerd.UninstallPerformResult, error) {
-  var result butlerd.UninstallPerformResult
-  err := rc.Call("Uninstall.Perform", params, &result)
+func (r *CavesSetPinnedType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesSetPinnedParams) (*butlerd.CavesSetPinnedResult, error) {
+  var result butlerd.CavesSetPinnedResult
+  err := rc.Call("Caves.SetPinned", params, &result)
   return &result, err
 }
 
-var UninstallPerform *UninstallPerformType
+var CavesSetPinned *CavesSetPinnedType
 
-// Install.VersionSwitch.Queue (Request)
+// Caves.SetAutoUpdatePolicy (Request)
 
-type InstallVersionSwitchQueueType struct {}
+type CavesSetAutoUpdatePolicyType struct {}
 
-var _ RequestMessage = (*InstallVersionSwitchQueueType)(nil)
+var _ RequestMessage = (*CavesSetAutoUpdatePolicyType)(nil)
 
-func (r *InstallVersionSwitchQueueType) Method() string {
-  return "Install.VersionSwitch.Queue"
+func (r *CavesSetAutoUpdatePolicyType) Method() string {
+  return "Caves.SetAutoUpdatePolicy"
 }
 
-func (r *InstallVersionSwitchQueueType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallVersionSwitchQueueParams) (*butlerd.InstallVersionSwitchQueueResult, error)) {
-  router.Register("Install.VersionSwitch.Queue", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.InstallVersionSwitchQueueParams
+func (r *CavesSetAutoUpdatePolicyType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesSetAutoUpdatePolicyParams) (*butlerd.CavesSetAutoUpdatePolicyResult, error)) {
+  router.Register("Caves.SetAutoUpdatePolicy", butlerd.TransactionMiddleware(func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesSetAutoUpdatePolicyParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -1943,33 +2333,33 @@ func (r *InstallVersionSwitchQueueType) Register(router router, f func(*butlerd.
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for Install.VersionSwitch.Queue")
+    	return nil, errors.New("internal error: nil result for Caves.SetAutoUpdatePolicy")
     }
     return res, nil
-  })
+  }))
 }
 
-func (r *InstallVersionSwitchQueueType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallVersionSwitchQueueParams) (*butlerd.InstallVersionSwitchQueueResult, error) {
-  var result butlerd.InstallVersionSwitchQueueResult
-  err := rc.Call("Install.VersionSwitch.Queue", params, &result)
+func (r *CavesSetAutoUpdatePolicyType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesSetAutoUpdatePolicyParams) (*butlerd.CavesSetAutoUpdatePolicyResult, error) {
+  var result butlerd.CavesSetAutoUpdatePolicyResult
+  err := rc.Call("Caves.SetAutoUpdatePolicy", params, &result)
   return &result, err
 }
 
-var InstallVersionSwitchQueue *InstallVersionSwitchQueueType
+var CavesSetAutoUpdatePolicy *CavesSetAutoUpdatePolicyType
 
-// InstallVersionSwitchPick (Request)
+// Caves.GetLaunchTargets (Request)
 
-type InstallVersionSwitchPickType struct {}
+type CavesGetLaunchTargetsType struct {}
 
-var _ RequestMessage = (*InstallVersionSwitchPickType)(nil)
+var _ RequestMessage = (*CavesGetLaunchTargetsType)(nil)
 
-func (r *InstallVersionSwitchPickType) Method() string {
-  return "InstallVersionSwitchPick"
+func (r *CavesGetLaunchTargetsType) Method() string {
+  return "Caves.GetLaunchTargets"
 }
 
-func (r *InstallVersionSwitchPickType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.InstallVersionSwitchPickParams) (*butlerd.InstallVersionSwitchPickResult, error)) {
-  router.Register("InstallVersionSwitchPick", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.InstallVersionSwitchPickParams
+func (r *CavesGetLaunchTargetsType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesGetLaunchTargetsParams) (*butlerd.CavesGetLaunchTargetsResult, error)) {
+  router.Register("Caves.GetLaunchTargets", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesGetLaunchTargetsParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -1983,33 +2373,33 @@ func (r *InstallVersionSwitchPickType) TestRegister(router router, f func(*butle
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for InstallVersionSwitchPick")
+    	return nil, errors.New("internal error: nil result for Caves.GetLaunchTargets")
     }
     return res, nil
   })
 }
 
-func (r *InstallVersionSwitchPickType) Call(rc *butlerd.RequestContext, params butlerd.InstallVersionSwitchPickParams) (*butlerd.InstallVersionSwitchPickResult, error) {
-  var result butlerd.InstallVersionSwitchPickResult
-  err := rc.Call("InstallVersionSwitchPick", params, &result)
+func (r *CavesGetLaunchTargetsType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesGetLaunchTargetsParams) (*butlerd.CavesGetLaunchTargetsResult, error) {
+  var result butlerd.CavesGetLaunchTargetsResult
+  err := rc.Call("Caves.GetLaunchTargets", params, &result)
   return &result, err
 }
 
-var InstallVersionSwitchPick *InstallVersionSwitchPickType
+var CavesGetLaunchTargets *CavesGetLaunchTargetsType
 
-// PickUpload (Request)
+// Caves.SetLaunchTarget (Request)
 
-type PickUploadType struct {}
+type CavesSetLaunchTargetType struct {}
 
-var _ RequestMessage = (*PickUploadType)(nil)
+var _ RequestMessage = (*CavesSetLaunchTargetType)(nil)
 
-func (r *PickUploadType) Method() string {
-  return "PickUpload"
+func (r *CavesSetLaunchTargetType) Method() string {
+  return "Caves.SetLaunchTarget"
 }
 
-func (r *PickUploadType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.PickUploadParams) (*butlerd.PickUploadResult, error)) {
-  router.Register("PickUpload", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.PickUploadParams
+func (r *CavesSetLaunchTargetType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesSetLaunchTargetParams) (*butlerd.CavesSetLaunchTargetResult, error)) {
+  router.Register("Caves.SetLaunchTarget", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesSetLaunchTargetParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -2023,120 +2413,73 @@ func (r *PickUploadType) TestRegister(router router, f func(*butlerd.RequestCont
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for PickUpload")
+    	return nil, errors.New("internal error: nil result for Caves.SetLaunchTarget")
     }
     return res, nil
   })
 }
 
-func (r *PickUploadType) Call(rc *butlerd.RequestContext, params butlerd.PickUploadParams) (*butlerd.PickUploadResult, error) {
-  var result butlerd.PickUploadResult
-  err := rc.Call("PickUpload", params, &result)
+func (r *CavesSetLaunchTargetType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesSetLaunchTargetParams) (*butlerd.CavesSetLaunchTargetResult, error) {
+  var result butlerd.CavesSetLaunchTargetResult
+  err := rc.Call("Caves.SetLaunchTarget", params, &result)
   return &result, err
 }
 
-var PickUpload *PickUploadType
-
-// Progress (Notification)
+var CavesSetLaunchTarget *CavesSetLaunchTargetType
 
-type ProgressType struct {}
+// Caves.ListBuildHistory (Request)
 
-var _ NotificationMessage = (*ProgressType)(nil)
+type CavesListBuildHistoryType struct {}
 
-func (r *ProgressType) Method() string {
-  return "Progress"
-}
+var _ RequestMessage = (*CavesListBuildHistoryType)(nil)
 
-func (r *ProgressType) Notify(rc *butlerd.RequestContext, params butlerd.ProgressNotification) (error) {
-  return rc.Notify("Progress", params)
+func (r *CavesListBuildHistoryType) Method() string {
+  return "Caves.ListBuildHistory"
 }
 
-func (r *ProgressType) Register(router router, f func(butlerd.ProgressNotification)) {
-  router.RegisterNotification("Progress", func (notif jsonrpc2.Notification) {
-    var params butlerd.ProgressNotification
-    if notif.Params != nil {
-      err := json.Unmarshal(*notif.Params, &params)
-      if err != nil {
-        return
-      }
+func (r *CavesListBuildHistoryType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesListBuildHistoryParams) (*butlerd.CavesListBuildHistoryResult, error)) {
+  router.Register("Caves.ListBuildHistory", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesListBuildHistoryParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
     }
-    f(params)
-  })
-}
-
-var Progress *ProgressType
-
-// TaskStarted (Notification)
-
-type TaskStartedType struct {}
-
-var _ NotificationMessage = (*TaskStartedType)(nil)
-
-func (r *TaskStartedType) Method() string {
-  return "TaskStarted"
-}
-
-func (r *TaskStartedType) Notify(rc *butlerd.RequestContext, params butlerd.TaskStartedNotification) (error) {
-  return rc.Notify("TaskStarted", params)
-}
-
-func (r *TaskStartedType) Register(router router, f func(butlerd.TaskStartedNotification)) {
-  router.RegisterNotification("TaskStarted", func (notif jsonrpc2.Notification) {
-    var params butlerd.TaskStartedNotification
-    if notif.Params != nil {
-      err := json.Unmarshal(*notif.Params, &params)
-      if err != nil {
-        return
-      }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
     }
-    f(params)
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Caves.ListBuildHistory")
+    }
+    return res, nil
   })
 }
 
-var TaskStarted *TaskStartedType
-
-// TaskSucceeded (Notification)
-
-type TaskSucceededType struct {}
-
-var _ NotificationMessage = (*TaskSucceededType)(nil)
-
-func (r *TaskSucceededType) Method() string {
-  return "TaskSucceeded"
-}
-
-func (r *TaskSucceededType) Notify(rc *butlerd.RequestContext, params butlerd.TaskSucceededNotification) (error) {
-  return rc.Notify("TaskSucceeded", params)
-}
-
-func (r *TaskSucceededType) Register(router router, f func(butlerd.TaskSucceededNotification)) {
-  router.RegisterNotification("TaskSucceeded", func (notif jsonrpc2.Notification) {
-    var params butlerd.TaskSucceededNotification
-    if notif.Params != nil {
-      err := json.Unmarshal(*notif.Params, &params)
-      if err != nil {
-        return
-      }
-    }
-    f(params)
-  })
+func (r *CavesListBuildHistoryType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesListBuildHistoryParams) (*butlerd.CavesListBuildHistoryResult, error) {
+  var result butlerd.CavesListBuildHistoryResult
+  err := rc.Call("Caves.ListBuildHistory", params, &result)
+  return &result, err
 }
 
-var TaskSucceeded *TaskSucceededType
+var CavesListBuildHistory *CavesListBuildHistoryType
 
-// Install.Locations.List (Request)
+// Caves.Downgrade (Request)
 
-type InstallLocationsListType struct {}
+type CavesDowngradeType struct {}
 
-var _ RequestMessage = (*InstallLocationsListType)(nil)
+var _ RequestMessage = (*CavesDowngradeType)(nil)
 
-func (r *InstallLocationsListType) Method() string {
-  return "Install.Locations.List"
+func (r *CavesDowngradeType) Method() string {
+  return "Caves.Downgrade"
 }
 
-func (r *InstallLocationsListType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallLocationsListParams) (*butlerd.InstallLocationsListResult, error)) {
-  router.Register("Install.Locations.List", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.InstallLocationsListParams
+func (r *CavesDowngradeType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesDowngradeParams) (*butlerd.CavesDowngradeResult, error)) {
+  router.Register("Caves.Downgrade", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesDowngradeParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -2150,33 +2493,33 @@ func (r *InstallLocationsListType) Register(router router, f func(*butlerd.Reque
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for Install.Locations.List")
+    	return nil, errors.New("internal error: nil result for Caves.Downgrade")
     }
     return res, nil
   })
 }
 
-func (r *InstallLocationsListType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallLocationsListParams) (*butlerd.InstallLocationsListResult, error) {
-  var result butlerd.InstallLocationsListResult
-  err := rc.Call("Install.Locations.List", params, &result)
+func (r *CavesDowngradeType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesDowngradeParams) (*butlerd.CavesDowngradeResult, error) {
+  var result butlerd.CavesDowngradeResult
+  err := rc.Call("Caves.Downgrade", params, &result)
   return &result, err
 }
 
-var InstallLocationsList *InstallLocationsListType
+var CavesDowngrade *CavesDowngradeType
 
-// Install.Locations.Add (Request)
+// Caves.ByProfile (Request)
 
-type InstallLocationsAddType struct {}
+type CavesByProfileType struct {}
 
-var _ RequestMessage = (*InstallLocationsAddType)(nil)
+var _ RequestMessage = (*CavesByProfileType)(nil)
 
-func (r *InstallLocationsAddType) Method() string {
-  return "Install.Locations.Add"
+func (r *CavesByProfileType) Method() string {
+  return "Caves.ByProfile"
 }
 
-func (r *InstallLocationsAddType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallLocationsAddParams) (*butlerd.InstallLocationsAddResult, error)) {
-  router.Register("Install.Locations.Add", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.InstallLocationsAddParams
+func (r *CavesByProfileType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesByProfileParams) (*butlerd.CavesByProfileResult, error)) {
+  router.Register("Caves.ByProfile", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesByProfileParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -2190,33 +2533,33 @@ func (r *InstallLocationsAddType) Register(router router, f func(*butlerd.Reques
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for Install.Locations.Add")
+    	return nil, errors.New("internal error: nil result for Caves.ByProfile")
     }
     return res, nil
   })
 }
 
-func (r *InstallLocationsAddType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallLocationsAddParams) (*butlerd.InstallLocationsAddResult, error) {
-  var result butlerd.InstallLocationsAddResult
-  err := rc.Call("Install.Locations.Add", params, &result)
+func (r *CavesByProfileType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesByProfileParams) (*butlerd.CavesByProfileResult, error) {
+  var result butlerd.CavesByProfileResult
+  err := rc.Call("Caves.ByProfile", params, &result)
   return &result, err
 }
 
-var InstallLocationsAdd *InstallLocationsAddType
+var CavesByProfile *CavesByProfileType
 
-// Install.Locations.Remove (Request)
+// Caves.Filter (Request)
 
-type InstallLocationsRemoveType struct {}
+type CavesFilterType struct {}
 
-var _ RequestMessage = (*InstallLocationsRemoveType)(nil)
+var _ RequestMessage = (*CavesFilterType)(nil)
 
-func (r *InstallLocationsRemoveType) Method() string {
-  return "Install.Locations.Remove"
+func (r *CavesFilterType) Method() string {
+  return "Caves.Filter"
 }
 
-func (r *InstallLocationsRemoveType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallLocationsRemoveParams) (*butlerd.InstallLocationsRemoveResult, error)) {
-  router.Register("Install.Locations.Remove", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.InstallLocationsRemoveParams
+func (r *CavesFilterType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesFilterParams) (*butlerd.CavesFilterResult, error)) {
+  router.Register("Caves.Filter", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesFilterParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -2230,33 +2573,33 @@ func (r *InstallLocationsRemoveType) Register(router router, f func(*butlerd.Req
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for Install.Locations.Remove")
+    	return nil, errors.New("internal error: nil result for Caves.Filter")
     }
     return res, nil
   })
 }
 
-func (r *InstallLocationsRemoveType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallLocationsRemoveParams) (*butlerd.InstallLocationsRemoveResult, error) {
-  var result butlerd.InstallLocationsRemoveResult
-  err := rc.Call("Install.Locations.Remove", params, &result)
+func (r *CavesFilterType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesFilterParams) (*butlerd.CavesFilterResult, error) {
+  var result butlerd.CavesFilterResult
+  err := rc.Call("Caves.Filter", params, &result)
   return &result, err
 }
 
-var InstallLocationsRemove *InstallLocationsRemoveType
+var CavesFilter *CavesFilterType
 
-// Install.Locations.GetByID (Request)
+// Caves.FuzzySearch (Request)
 
-type InstallLocationsGetByIDType struct {}
+type CavesFuzzySearchType struct {}
 
-var _ RequestMessage = (*InstallLocationsGetByIDType)(nil)
+var _ RequestMessage = (*CavesFuzzySearchType)(nil)
 
-func (r *InstallLocationsGetByIDType) Method() string {
-  return "Install.Locations.GetByID"
+func (r *CavesFuzzySearchType) Method() string {
+  return "Caves.FuzzySearch"
 }
 
-func (r *InstallLocationsGetByIDType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallLocationsGetByIDParams) (*butlerd.InstallLocationsGetByIDResult, error)) {
-  router.Register("Install.Locations.GetByID", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.InstallLocationsGetByIDParams
+func (r *CavesFuzzySearchType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesFuzzySearchParams) (*butlerd.CavesFuzzySearchResult, error)) {
+  router.Register("Caves.FuzzySearch", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesFuzzySearchParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -2270,33 +2613,33 @@ func (r *InstallLocationsGetByIDType) Register(router router, f func(*butlerd.Re
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for Install.Locations.GetByID")
+    	return nil, errors.New("internal error: nil result for Caves.FuzzySearch")
     }
     return res, nil
   })
 }
 
-func (r *InstallLocationsGetByIDType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallLocationsGetByIDParams) (*butlerd.InstallLocationsGetByIDResult, error) {
-  var result butlerd.InstallLocationsGetByIDResult
-  err := rc.Call("Install.Locations.GetByID", params, &result)
+func (r *CavesFuzzySearchType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesFuzzySearchParams) (*butlerd.CavesFuzzySearchResult, error) {
+  var result butlerd.CavesFuzzySearchResult
+  err := rc.Call("Caves.FuzzySearch", params, &result)
   return &result, err
 }
 
-var InstallLocationsGetByID *InstallLocationsGetByIDType
+var CavesFuzzySearch *CavesFuzzySearchType
 
-// Install.Locations.Scan (Request)
+// Caves.ListFiles (Request)
 
-type InstallLocationsScanType struct {}
+type CavesListFilesType struct {}
 
-var _ RequestMessage = (*InstallLocationsScanType)(nil)
+var _ RequestMessage = (*CavesListFilesType)(nil)
 
-func (r *InstallLocationsScanType) Method() string {
-  return "Install.Locations.Scan"
+func (r *CavesListFilesType) Method() string {
+  return "Caves.ListFiles"
 }
 
-func (r *InstallLocationsScanType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallLocationsScanParams) (*butlerd.InstallLocationsScanResult, error)) {
-  router.Register("Install.Locations.Scan", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.InstallLocationsScanParams
+func (r *CavesListFilesType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesListFilesParams) (*butlerd.CavesListFilesResult, error)) {
+  router.Register("Caves.ListFiles", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesListFilesParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -2310,37 +2653,197 @@ func (r *InstallLocationsScanType) Register(router router, f func(*butlerd.Reque
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for Install.Locations.Scan")
+    	return nil, errors.New("internal error: nil result for Caves.ListFiles")
     }
     return res, nil
   })
 }
 
-func (r *InstallLocationsScanType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallLocationsScanParams) (*butlerd.InstallLocationsScanResult, error) {
-  var result butlerd.InstallLocationsScanResult
-  err := rc.Call("Install.Locations.Scan", params, &result)
+func (r *CavesListFilesType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesListFilesParams) (*butlerd.CavesListFilesResult, error) {
+  var result butlerd.CavesListFilesResult
+  err := rc.Call("Caves.ListFiles", params, &result)
   return &result, err
 }
 
-var InstallLocationsScan *InstallLocationsScanType
+var CavesListFiles *CavesListFilesType
 
-// Install.Locations.Scan.Yield (Notification)
+// Caves.ReadFile (Request)
 
-type InstallLocationsScanYieldType struct {}
+type CavesReadFileType struct {}
 
-var _ NotificationMessage = (*InstallLocationsScanYieldType)(nil)
+var _ RequestMessage = (*CavesReadFileType)(nil)
 
-func (r *InstallLocationsScanYieldType) Method() string {
-  return "Install.Locations.Scan.Yield"
+func (r *CavesReadFileType) Method() string {
+  return "Caves.ReadFile"
 }
 
-func (r *InstallLocationsScanYieldType) Notify(rc *butlerd.RequestContext, params butlerd.InstallLocationsScanYieldNotification) (error) {
-  return rc.Notify("Install.Locations.Scan.Yield", params)
+func (r *CavesReadFileType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesReadFileParams) (*butlerd.CavesReadFileResult, error)) {
+  router.Register("Caves.ReadFile", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesReadFileParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Caves.ReadFile")
+    }
+    return res, nil
+  })
 }
 
-func (r *InstallLocationsScanYieldType) Register(router router, f func(butlerd.InstallLocationsScanYieldNotification)) {
-  router.RegisterNotification("Install.Locations.Scan.Yield", func (notif jsonrpc2.Notification) {
-    var params butlerd.InstallLocationsScanYieldNotification
+func (r *CavesReadFileType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesReadFileParams) (*butlerd.CavesReadFileResult, error) {
+  var result butlerd.CavesReadFileResult
+  err := rc.Call("Caves.ReadFile", params, &result)
+  return &result, err
+}
+
+var CavesReadFile *CavesReadFileType
+
+// Caves.OpenInstallFolder (Request)
+
+type CavesOpenInstallFolderType struct {}
+
+var _ RequestMessage = (*CavesOpenInstallFolderType)(nil)
+
+func (r *CavesOpenInstallFolderType) Method() string {
+  return "Caves.OpenInstallFolder"
+}
+
+func (r *CavesOpenInstallFolderType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesOpenInstallFolderParams) (*butlerd.CavesOpenInstallFolderResult, error)) {
+  router.Register("Caves.OpenInstallFolder", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesOpenInstallFolderParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Caves.OpenInstallFolder")
+    }
+    return res, nil
+  })
+}
+
+func (r *CavesOpenInstallFolderType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesOpenInstallFolderParams) (*butlerd.CavesOpenInstallFolderResult, error) {
+  var result butlerd.CavesOpenInstallFolderResult
+  err := rc.Call("Caves.OpenInstallFolder", params, &result)
+  return &result, err
+}
+
+var CavesOpenInstallFolder *CavesOpenInstallFolderType
+
+// Caves.GetDLCs (Request)
+
+type CavesGetDLCsType struct {}
+
+var _ RequestMessage = (*CavesGetDLCsType)(nil)
+
+func (r *CavesGetDLCsType) Method() string {
+  return "Caves.GetDLCs"
+}
+
+func (r *CavesGetDLCsType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesGetDLCsParams) (*butlerd.CavesGetDLCsResult, error)) {
+  router.Register("Caves.GetDLCs", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesGetDLCsParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Caves.GetDLCs")
+    }
+    return res, nil
+  })
+}
+
+func (r *CavesGetDLCsType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesGetDLCsParams) (*butlerd.CavesGetDLCsResult, error) {
+  var result butlerd.CavesGetDLCsResult
+  err := rc.Call("Caves.GetDLCs", params, &result)
+  return &result, err
+}
+
+var CavesGetDLCs *CavesGetDLCsType
+
+// Caves.DetectGhosts (Request)
+
+type CavesDetectGhostsType struct {}
+
+var _ RequestMessage = (*CavesDetectGhostsType)(nil)
+
+func (r *CavesDetectGhostsType) Method() string {
+  return "Caves.DetectGhosts"
+}
+
+func (r *CavesDetectGhostsType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesDetectGhostsParams) (*butlerd.CavesDetectGhostsResult, error)) {
+  router.Register("Caves.DetectGhosts", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesDetectGhostsParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Caves.DetectGhosts")
+    }
+    return res, nil
+  })
+}
+
+func (r *CavesDetectGhostsType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesDetectGhostsParams) (*butlerd.CavesDetectGhostsResult, error) {
+  var result butlerd.CavesDetectGhostsResult
+  err := rc.Call("Caves.DetectGhosts", params, &result)
+  return &result, err
+}
+
+var CavesDetectGhosts *CavesDetectGhostsType
+
+// GhostCaveDetected (Notification)
+
+type GhostCaveDetectedType struct {}
+
+var _ NotificationMessage = (*GhostCaveDetectedType)(nil)
+
+func (r *GhostCaveDetectedType) Method() string {
+  return "GhostCaveDetected"
+}
+
+func (r *GhostCaveDetectedType) Notify(rc *butlerd.RequestContext, params butlerd.GhostCaveDetectedNotification) (error) {
+  return rc.Notify("GhostCaveDetected", params)
+}
+
+func (r *GhostCaveDetectedType) Register(router router, f func(butlerd.GhostCaveDetectedNotification)) {
+  router.RegisterNotification("GhostCaveDetected", func (notif jsonrpc2.Notification) {
+    var params butlerd.GhostCaveDetectedNotification
     if notif.Params != nil {
       err := json.Unmarshal(*notif.Params, &params)
       if err != nil {
@@ -2351,21 +2854,21 @@ func (r *InstallLocationsScanYieldType) Register(router router, f func(butlerd.I
   })
 }
 
-var InstallLocationsScanYield *InstallLocationsScanYieldType
+var GhostCaveDetected *GhostCaveDetectedType
 
-// Install.Locations.Scan.ConfirmImport (Request)
+// Caves.FindMoved (Request)
 
-type InstallLocationsScanConfirmImportType struct {}
+type CavesFindMovedType struct {}
 
-var _ RequestMessage = (*InstallLocationsScanConfirmImportType)(nil)
+var _ RequestMessage = (*CavesFindMovedType)(nil)
 
-func (r *InstallLocationsScanConfirmImportType) Method() string {
-  return "Install.Locations.Scan.ConfirmImport"
+func (r *CavesFindMovedType) Method() string {
+  return "Caves.FindMoved"
 }
 
-func (r *InstallLocationsScanConfirmImportType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.InstallLocationsScanConfirmImportParams) (*butlerd.InstallLocationsScanConfirmImportResult, error)) {
-  router.Register("Install.Locations.Scan.ConfirmImport", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.InstallLocationsScanConfirmImportParams
+func (r *CavesFindMovedType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesFindMovedParams) (*butlerd.CavesFindMovedResult, error)) {
+  router.Register("Caves.FindMoved", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesFindMovedParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -2379,38 +2882,73 @@ func (r *InstallLocationsScanConfirmImportType) TestRegister(router router, f fu
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for Install.Locations.Scan.ConfirmImport")
+    	return nil, errors.New("internal error: nil result for Caves.FindMoved")
     }
     return res, nil
   })
 }
 
-func (r *InstallLocationsScanConfirmImportType) Call(rc *butlerd.RequestContext, params butlerd.InstallLocationsScanConfirmImportParams) (*butlerd.InstallLocationsScanConfirmImportResult, error) {
-  var result butlerd.InstallLocationsScanConfirmImportResult
-  err := rc.Call("Install.Locations.Scan.ConfirmImport", params, &result)
+func (r *CavesFindMovedType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesFindMovedParams) (*butlerd.CavesFindMovedResult, error) {
+  var result butlerd.CavesFindMovedResult
+  err := rc.Call("Caves.FindMoved", params, &result)
   return &result, err
 }
 
-var InstallLocationsScanConfirmImport *InstallLocationsScanConfirmImportType
+var CavesFindMoved *CavesFindMovedType
 
+// Caves.Relink (Request)
 
-//==============================
-// Downloads
-//==============================
+type CavesRelinkType struct {}
 
-// Downloads.Queue (Request)
+var _ RequestMessage = (*CavesRelinkType)(nil)
 
-type DownloadsQueueType struct {}
+func (r *CavesRelinkType) Method() string {
+  return "Caves.Relink"
+}
 
-var _ RequestMessage = (*DownloadsQueueType)(nil)
+func (r *CavesRelinkType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesRelinkParams) (*butlerd.CavesRelinkResult, error)) {
+  router.Register("Caves.Relink", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesRelinkParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Caves.Relink")
+    }
+    return res, nil
+  })
+}
 
-func (r *DownloadsQueueType) Method() string {
-  return "Downloads.Queue"
+func (r *CavesRelinkType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesRelinkParams) (*butlerd.CavesRelinkResult, error) {
+  var result butlerd.CavesRelinkResult
+  err := rc.Call("Caves.Relink", params, &result)
+  return &result, err
 }
 
-func (r *DownloadsQueueType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsQueueParams) (*butlerd.DownloadsQueueResult, error)) {
-  router.Register("Downloads.Queue", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.DownloadsQueueParams
+var CavesRelink *CavesRelinkType
+
+// Caves.ListEvents (Request)
+
+type CavesListEventsType struct {}
+
+var _ RequestMessage = (*CavesListEventsType)(nil)
+
+func (r *CavesListEventsType) Method() string {
+  return "Caves.ListEvents"
+}
+
+func (r *CavesListEventsType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesListEventsParams) (*butlerd.CavesListEventsResult, error)) {
+  router.Register("Caves.ListEvents", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesListEventsParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -2424,33 +2962,33 @@ func (r *DownloadsQueueType) Register(router router, f func(*butlerd.RequestCont
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for Downloads.Queue")
+    	return nil, errors.New("internal error: nil result for Caves.ListEvents")
     }
     return res, nil
   })
 }
 
-func (r *DownloadsQueueType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsQueueParams) (*butlerd.DownloadsQueueResult, error) {
-  var result butlerd.DownloadsQueueResult
-  err := rc.Call("Downloads.Queue", params, &result)
+func (r *CavesListEventsType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesListEventsParams) (*butlerd.CavesListEventsResult, error) {
+  var result butlerd.CavesListEventsResult
+  err := rc.Call("Caves.ListEvents", params, &result)
   return &result, err
 }
 
-var DownloadsQueue *DownloadsQueueType
+var CavesListEvents *CavesListEventsType
 
-// Downloads.Prioritize (Request)
+// Caves.RebuildReceipt (Request)
 
-type DownloadsPrioritizeType struct {}
+type CavesRebuildReceiptType struct {}
 
-var _ RequestMessage = (*DownloadsPrioritizeType)(nil)
+var _ RequestMessage = (*CavesRebuildReceiptType)(nil)
 
-func (r *DownloadsPrioritizeType) Method() string {
-  return "Downloads.Prioritize"
+func (r *CavesRebuildReceiptType) Method() string {
+  return "Caves.RebuildReceipt"
 }
 
-func (r *DownloadsPrioritizeType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsPrioritizeParams) (*butlerd.DownloadsPrioritizeResult, error)) {
-  router.Register("Downloads.Prioritize", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.DownloadsPrioritizeParams
+func (r *CavesRebuildReceiptType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesRebuildReceiptParams) (*butlerd.CavesRebuildReceiptResult, error)) {
+  router.Register("Caves.RebuildReceipt", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesRebuildReceiptParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -2464,33 +3002,33 @@ func (r *DownloadsPrioritizeType) Register(router router, f func(*butlerd.Reques
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for Downloads.Prioritize")
+    	return nil, errors.New("internal error: nil result for Caves.RebuildReceipt")
     }
     return res, nil
   })
 }
 
-func (r *DownloadsPrioritizeType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsPrioritizeParams) (*butlerd.DownloadsPrioritizeResult, error) {
-  var result butlerd.DownloadsPrioritizeResult
-  err := rc.Call("Downloads.Prioritize", params, &result)
+func (r *CavesRebuildReceiptType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesRebuildReceiptParams) (*butlerd.CavesRebuildReceiptResult, error) {
+  var result butlerd.CavesRebuildReceiptResult
+  err := rc.Call("Caves.RebuildReceipt", params, &result)
   return &result, err
 }
 
-var DownloadsPrioritize *DownloadsPrioritizeType
+var CavesRebuildReceipt *CavesRebuildReceiptType
 
-// Downloads.List (Request)
+// Caves.RebuildReceipts (Request)
 
-type DownloadsListType struct {}
+type CavesRebuildReceiptsType struct {}
 
-var _ RequestMessage = (*DownloadsListType)(nil)
+var _ RequestMessage = (*CavesRebuildReceiptsType)(nil)
 
-func (r *DownloadsListType) Method() string {
-  return "Downloads.List"
+func (r *CavesRebuildReceiptsType) Method() string {
+  return "Caves.RebuildReceipts"
 }
 
-func (r *DownloadsListType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsListParams) (*butlerd.DownloadsListResult, error)) {
-  router.Register("Downloads.List", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.DownloadsListParams
+func (r *CavesRebuildReceiptsType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesRebuildReceiptsParams) (*butlerd.CavesRebuildReceiptsResult, error)) {
+  router.Register("Caves.RebuildReceipts", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesRebuildReceiptsParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -2504,33 +3042,2067 @@ func (r *DownloadsListType) Register(router router, f func(*butlerd.RequestConte
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for Downloads.List")
+    	return nil, errors.New("internal error: nil result for Caves.RebuildReceipts")
     }
     return res, nil
   })
 }
 
-func (r *DownloadsListType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsListParams) (*butlerd.DownloadsListResult, error) {
-  var result butlerd.DownloadsListResult
-  err := rc.Call("Downloads.List", params, &result)
+func (r *CavesRebuildReceiptsType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesRebuildReceiptsParams) (*butlerd.CavesRebuildReceiptsResult, error) {
+  var result butlerd.CavesRebuildReceiptsResult
+  err := rc.Call("Caves.RebuildReceipts", params, &result)
   return &result, err
 }
 
-var DownloadsList *DownloadsListType
+var CavesRebuildReceipts *CavesRebuildReceiptsType
 
-// Downloads.ClearFinished (Request)
+// Caves.ApplyNamingTemplate (Request)
 
-type DownloadsClearFinishedType struct {}
+type CavesApplyNamingTemplateType struct {}
 
-var _ RequestMessage = (*DownloadsClearFinishedType)(nil)
+var _ RequestMessage = (*CavesApplyNamingTemplateType)(nil)
 
-func (r *DownloadsClearFinishedType) Method() string {
-  return "Downloads.ClearFinished"
+func (r *CavesApplyNamingTemplateType) Method() string {
+  return "Caves.ApplyNamingTemplate"
+}
+
+func (r *CavesApplyNamingTemplateType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesApplyNamingTemplateParams) (*butlerd.CavesApplyNamingTemplateResult, error)) {
+  router.Register("Caves.ApplyNamingTemplate", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesApplyNamingTemplateParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Caves.ApplyNamingTemplate")
+    }
+    return res, nil
+  })
+}
+
+func (r *CavesApplyNamingTemplateType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesApplyNamingTemplateParams) (*butlerd.CavesApplyNamingTemplateResult, error) {
+  var result butlerd.CavesApplyNamingTemplateResult
+  err := rc.Call("Caves.ApplyNamingTemplate", params, &result)
+  return &result, err
+}
+
+var CavesApplyNamingTemplate *CavesApplyNamingTemplateType
+
+// ReceiptRebuildSuggested (Notification)
+
+type ReceiptRebuildSuggestedType struct {}
+
+var _ NotificationMessage = (*ReceiptRebuildSuggestedType)(nil)
+
+func (r *ReceiptRebuildSuggestedType) Method() string {
+  return "ReceiptRebuildSuggested"
+}
+
+func (r *ReceiptRebuildSuggestedType) Notify(rc *butlerd.RequestContext, params butlerd.ReceiptRebuildSuggestedNotification) (error) {
+  return rc.Notify("ReceiptRebuildSuggested", params)
+}
+
+func (r *ReceiptRebuildSuggestedType) Register(router router, f func(butlerd.ReceiptRebuildSuggestedNotification)) {
+  router.RegisterNotification("ReceiptRebuildSuggested", func (notif jsonrpc2.Notification) {
+    var params butlerd.ReceiptRebuildSuggestedNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var ReceiptRebuildSuggested *ReceiptRebuildSuggestedType
+
+// CaveVerify (Request)
+
+type CaveVerifyType struct {}
+
+var _ RequestMessage = (*CaveVerifyType)(nil)
+
+func (r *CaveVerifyType) Method() string {
+  return "CaveVerify"
+}
+
+func (r *CaveVerifyType) Register(router router, f func(*butlerd.RequestContext, butlerd.CaveVerifyParams) (*butlerd.CaveVerifyResult, error)) {
+  router.Register("CaveVerify", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CaveVerifyParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for CaveVerify")
+    }
+    return res, nil
+  })
+}
+
+func (r *CaveVerifyType) TestCall(rc *butlerd.RequestContext, params butlerd.CaveVerifyParams) (*butlerd.CaveVerifyResult, error) {
+  var result butlerd.CaveVerifyResult
+  err := rc.Call("CaveVerify", params, &result)
+  return &result, err
+}
+
+var CaveVerify *CaveVerifyType
+
+// CaveVerifyProgress (Notification)
+
+type CaveVerifyProgressType struct {}
+
+var _ NotificationMessage = (*CaveVerifyProgressType)(nil)
+
+func (r *CaveVerifyProgressType) Method() string {
+  return "CaveVerifyProgress"
+}
+
+func (r *CaveVerifyProgressType) Notify(rc *butlerd.RequestContext, params butlerd.CaveVerifyProgressNotification) (error) {
+  return rc.Notify("CaveVerifyProgress", params)
+}
+
+func (r *CaveVerifyProgressType) Register(router router, f func(butlerd.CaveVerifyProgressNotification)) {
+  router.RegisterNotification("CaveVerifyProgress", func (notif jsonrpc2.Notification) {
+    var params butlerd.CaveVerifyProgressNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var CaveVerifyProgress *CaveVerifyProgressType
+
+// Install.CreateShortcut (Request)
+
+type InstallCreateShortcutType struct {}
+
+var _ RequestMessage = (*InstallCreateShortcutType)(nil)
+
+func (r *InstallCreateShortcutType) Method() string {
+  return "Install.CreateShortcut"
+}
+
+func (r *InstallCreateShortcutType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallCreateShortcutParams) (*butlerd.InstallCreateShortcutResult, error)) {
+  router.Register("Install.CreateShortcut", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.InstallCreateShortcutParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Install.CreateShortcut")
+    }
+    return res, nil
+  })
+}
+
+func (r *InstallCreateShortcutType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallCreateShortcutParams) (*butlerd.InstallCreateShortcutResult, error) {
+  var result butlerd.InstallCreateShortcutResult
+  err := rc.Call("Install.CreateShortcut", params, &result)
+  return &result, err
+}
+
+var InstallCreateShortcut *InstallCreateShortcutType
+
+// Install.Perform (Request)
+
+type InstallPerformType struct {}
+
+var _ RequestMessage = (*InstallPerformType)(nil)
+
+func (r *InstallPerformType) Method() string {
+  return "Install.Perform"
+}
+
+func (r *InstallPerformType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallPerformParams) (*butlerd.InstallPerformResult, error)) {
+  router.Register("Install.Perform", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.InstallPerformParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Install.Perform")
+    }
+    return res, nil
+  })
+}
+
+func (r *InstallPerformType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallPerformParams) (*butlerd.InstallPerformResult, error) {
+  var result butlerd.InstallPerformResult
+  err := rc.Call("Install.Perform", params, &result)
+  return &result, err
+}
+
+var InstallPerform *InstallPerformType
+
+// Install.Cancel (Request)
+
+type InstallCancelType struct {}
+
+var _ RequestMessage = (*InstallCancelType)(nil)
+
+func (r *InstallCancelType) Method() string {
+  return "Install.Cancel"
+}
+
+func (r *InstallCancelType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallCancelParams) (*butlerd.InstallCancelResult, error)) {
+  router.Register("Install.Cancel", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.InstallCancelParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Install.Cancel")
+    }
+    return res, nil
+  })
+}
+
+func (r *InstallCancelType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallCancelParams) (*butlerd.InstallCancelResult, error) {
+  var result butlerd.InstallCancelResult
+  err := rc.Call("Install.Cancel", params, &result)
+  return &result, err
+}
+
+var InstallCancel *InstallCancelType
+
+// Uninstall.Perform (Request)
+
+type UninstallPerformType struct {}
+
+var _ RequestMessage = (*UninstallPerformType)(nil)
+
+func (r *UninstallPerformType) Method() string {
+  return "Uninstall.Perform"
+}
+
+func (r *UninstallPerformType) Register(router router, f func(*butlerd.RequestContext, butlerd.UninstallPerformParams) (*butlerd.UninstallPerformResult, error)) {
+  router.Register("Uninstall.Perform", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.UninstallPerformParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Uninstall.Perform")
+    }
+    return res, nil
+  })
+}
+
+func (r *UninstallPerformType) TestCall(rc *butlerd.RequestContext, params butlerd.UninstallPerformParams) (*butlerd.UninstallPerformResult, error) {
+  var result butlerd.UninstallPerformResult
+  err := rc.Call("Uninstall.Perform", params, &result)
+  return &result, err
+}
+
+var UninstallPerform *UninstallPerformType
+
+// CaveUninstallBatch (Request)
+
+type CaveUninstallBatchType struct {}
+
+var _ RequestMessage = (*CaveUninstallBatchType)(nil)
+
+func (r *CaveUninstallBatchType) Method() string {
+  return "CaveUninstallBatch"
+}
+
+func (r *CaveUninstallBatchType) Register(router router, f func(*butlerd.RequestContext, butlerd.CaveUninstallBatchParams) (*butlerd.CaveUninstallBatchResult, error)) {
+  router.Register("CaveUninstallBatch", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CaveUninstallBatchParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for CaveUninstallBatch")
+    }
+    return res, nil
+  })
+}
+
+func (r *CaveUninstallBatchType) TestCall(rc *butlerd.RequestContext, params butlerd.CaveUninstallBatchParams) (*butlerd.CaveUninstallBatchResult, error) {
+  var result butlerd.CaveUninstallBatchResult
+  err := rc.Call("CaveUninstallBatch", params, &result)
+  return &result, err
+}
+
+var CaveUninstallBatch *CaveUninstallBatchType
+
+// CaveUninstallProgress (Notification)
+
+type CaveUninstallProgressType struct {}
+
+var _ NotificationMessage = (*CaveUninstallProgressType)(nil)
+
+func (r *CaveUninstallProgressType) Method() string {
+  return "CaveUninstallProgress"
+}
+
+func (r *CaveUninstallProgressType) Notify(rc *butlerd.RequestContext, params butlerd.CaveUninstallProgressNotification) (error) {
+  return rc.Notify("CaveUninstallProgress", params)
+}
+
+func (r *CaveUninstallProgressType) Register(router router, f func(butlerd.CaveUninstallProgressNotification)) {
+  router.RegisterNotification("CaveUninstallProgress", func (notif jsonrpc2.Notification) {
+    var params butlerd.CaveUninstallProgressNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var CaveUninstallProgress *CaveUninstallProgressType
+
+// UninstallFilesCategorized (Notification)
+
+type UninstallFilesCategorizedType struct {}
+
+var _ NotificationMessage = (*UninstallFilesCategorizedType)(nil)
+
+func (r *UninstallFilesCategorizedType) Method() string {
+  return "UninstallFilesCategorized"
+}
+
+func (r *UninstallFilesCategorizedType) Notify(rc *butlerd.RequestContext, params butlerd.UninstallFilesCategorizedNotification) (error) {
+  return rc.Notify("UninstallFilesCategorized", params)
+}
+
+func (r *UninstallFilesCategorizedType) Register(router router, f func(butlerd.UninstallFilesCategorizedNotification)) {
+  router.RegisterNotification("UninstallFilesCategorized", func (notif jsonrpc2.Notification) {
+    var params butlerd.UninstallFilesCategorizedNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var UninstallFilesCategorized *UninstallFilesCategorizedType
+
+// Install.VersionSwitch.Queue (Request)
+
+type InstallVersionSwitchQueueType struct {}
+
+var _ RequestMessage = (*InstallVersionSwitchQueueType)(nil)
+
+func (r *InstallVersionSwitchQueueType) Method() string {
+  return "Install.VersionSwitch.Queue"
+}
+
+func (r *InstallVersionSwitchQueueType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallVersionSwitchQueueParams) (*butlerd.InstallVersionSwitchQueueResult, error)) {
+  router.Register("Install.VersionSwitch.Queue", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.InstallVersionSwitchQueueParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Install.VersionSwitch.Queue")
+    }
+    return res, nil
+  })
+}
+
+func (r *InstallVersionSwitchQueueType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallVersionSwitchQueueParams) (*butlerd.InstallVersionSwitchQueueResult, error) {
+  var result butlerd.InstallVersionSwitchQueueResult
+  err := rc.Call("Install.VersionSwitch.Queue", params, &result)
+  return &result, err
+}
+
+var InstallVersionSwitchQueue *InstallVersionSwitchQueueType
+
+// InstallVersionSwitchPick (Request)
+
+type InstallVersionSwitchPickType struct {}
+
+var _ RequestMessage = (*InstallVersionSwitchPickType)(nil)
+
+func (r *InstallVersionSwitchPickType) Method() string {
+  return "InstallVersionSwitchPick"
+}
+
+func (r *InstallVersionSwitchPickType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.InstallVersionSwitchPickParams) (*butlerd.InstallVersionSwitchPickResult, error)) {
+  router.Register("InstallVersionSwitchPick", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.InstallVersionSwitchPickParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for InstallVersionSwitchPick")
+    }
+    return res, nil
+  })
+}
+
+func (r *InstallVersionSwitchPickType) Call(rc *butlerd.RequestContext, params butlerd.InstallVersionSwitchPickParams) (*butlerd.InstallVersionSwitchPickResult, error) {
+  var result butlerd.InstallVersionSwitchPickResult
+  err := rc.Call("InstallVersionSwitchPick", params, &result)
+  return &result, err
+}
+
+var InstallVersionSwitchPick *InstallVersionSwitchPickType
+
+// PickUpload (Request)
+
+type PickUploadType struct {}
+
+var _ RequestMessage = (*PickUploadType)(nil)
+
+func (r *PickUploadType) Method() string {
+  return "PickUpload"
+}
+
+func (r *PickUploadType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.PickUploadParams) (*butlerd.PickUploadResult, error)) {
+  router.Register("PickUpload", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.PickUploadParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for PickUpload")
+    }
+    return res, nil
+  })
+}
+
+func (r *PickUploadType) Call(rc *butlerd.RequestContext, params butlerd.PickUploadParams) (*butlerd.PickUploadResult, error) {
+  var result butlerd.PickUploadResult
+  err := rc.Call("PickUpload", params, &result)
+  return &result, err
+}
+
+var PickUpload *PickUploadType
+
+// Progress (Notification)
+
+type ProgressType struct {}
+
+var _ NotificationMessage = (*ProgressType)(nil)
+
+func (r *ProgressType) Method() string {
+  return "Progress"
+}
+
+func (r *ProgressType) Notify(rc *butlerd.RequestContext, params butlerd.ProgressNotification) (error) {
+  return rc.Notify("Progress", params)
+}
+
+func (r *ProgressType) Register(router router, f func(butlerd.ProgressNotification)) {
+  router.RegisterNotification("Progress", func (notif jsonrpc2.Notification) {
+    var params butlerd.ProgressNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var Progress *ProgressType
+
+// Install.Queue.Progress (Notification)
+
+type InstallQueueProgressType struct {}
+
+var _ NotificationMessage = (*InstallQueueProgressType)(nil)
+
+func (r *InstallQueueProgressType) Method() string {
+  return "Install.Queue.Progress"
+}
+
+func (r *InstallQueueProgressType) Notify(rc *butlerd.RequestContext, params butlerd.InstallQueueProgressNotification) (error) {
+  return rc.Notify("Install.Queue.Progress", params)
+}
+
+func (r *InstallQueueProgressType) Register(router router, f func(butlerd.InstallQueueProgressNotification)) {
+  router.RegisterNotification("Install.Queue.Progress", func (notif jsonrpc2.Notification) {
+    var params butlerd.InstallQueueProgressNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var InstallQueueProgress *InstallQueueProgressType
+
+// Install.Prepare.Progress (Notification)
+
+type InstallPrepareProgressType struct {}
+
+var _ NotificationMessage = (*InstallPrepareProgressType)(nil)
+
+func (r *InstallPrepareProgressType) Method() string {
+  return "Install.Prepare.Progress"
+}
+
+func (r *InstallPrepareProgressType) Notify(rc *butlerd.RequestContext, params butlerd.InstallPrepareProgressNotification) (error) {
+  return rc.Notify("Install.Prepare.Progress", params)
+}
+
+func (r *InstallPrepareProgressType) Register(router router, f func(butlerd.InstallPrepareProgressNotification)) {
+  router.RegisterNotification("Install.Prepare.Progress", func (notif jsonrpc2.Notification) {
+    var params butlerd.InstallPrepareProgressNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var InstallPrepareProgress *InstallPrepareProgressType
+
+// Install.PatchProgress (Notification)
+
+type InstallPatchProgressType struct {}
+
+var _ NotificationMessage = (*InstallPatchProgressType)(nil)
+
+func (r *InstallPatchProgressType) Method() string {
+  return "Install.PatchProgress"
+}
+
+func (r *InstallPatchProgressType) Notify(rc *butlerd.RequestContext, params butlerd.InstallPatchProgressNotification) (error) {
+  return rc.Notify("Install.PatchProgress", params)
+}
+
+func (r *InstallPatchProgressType) Register(router router, f func(butlerd.InstallPatchProgressNotification)) {
+  router.RegisterNotification("Install.PatchProgress", func (notif jsonrpc2.Notification) {
+    var params butlerd.InstallPatchProgressNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var InstallPatchProgress *InstallPatchProgressType
+
+// TaskStarted (Notification)
+
+type TaskStartedType struct {}
+
+var _ NotificationMessage = (*TaskStartedType)(nil)
+
+func (r *TaskStartedType) Method() string {
+  return "TaskStarted"
+}
+
+func (r *TaskStartedType) Notify(rc *butlerd.RequestContext, params butlerd.TaskStartedNotification) (error) {
+  return rc.Notify("TaskStarted", params)
+}
+
+func (r *TaskStartedType) Register(router router, f func(butlerd.TaskStartedNotification)) {
+  router.RegisterNotification("TaskStarted", func (notif jsonrpc2.Notification) {
+    var params butlerd.TaskStartedNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var TaskStarted *TaskStartedType
+
+// TaskSucceeded (Notification)
+
+type TaskSucceededType struct {}
+
+var _ NotificationMessage = (*TaskSucceededType)(nil)
+
+func (r *TaskSucceededType) Method() string {
+  return "TaskSucceeded"
+}
+
+func (r *TaskSucceededType) Notify(rc *butlerd.RequestContext, params butlerd.TaskSucceededNotification) (error) {
+  return rc.Notify("TaskSucceeded", params)
+}
+
+func (r *TaskSucceededType) Register(router router, f func(butlerd.TaskSucceededNotification)) {
+  router.RegisterNotification("TaskSucceeded", func (notif jsonrpc2.Notification) {
+    var params butlerd.TaskSucceededNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var TaskSucceeded *TaskSucceededType
+
+// Install.Locations.List (Request)
+
+type InstallLocationsListType struct {}
+
+var _ RequestMessage = (*InstallLocationsListType)(nil)
+
+func (r *InstallLocationsListType) Method() string {
+  return "Install.Locations.List"
+}
+
+func (r *InstallLocationsListType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallLocationsListParams) (*butlerd.InstallLocationsListResult, error)) {
+  router.Register("Install.Locations.List", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.InstallLocationsListParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Install.Locations.List")
+    }
+    return res, nil
+  })
+}
+
+func (r *InstallLocationsListType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallLocationsListParams) (*butlerd.InstallLocationsListResult, error) {
+  var result butlerd.InstallLocationsListResult
+  err := rc.Call("Install.Locations.List", params, &result)
+  return &result, err
+}
+
+var InstallLocationsList *InstallLocationsListType
+
+// Install.Locations.Add (Request)
+
+type InstallLocationsAddType struct {}
+
+var _ RequestMessage = (*InstallLocationsAddType)(nil)
+
+func (r *InstallLocationsAddType) Method() string {
+  return "Install.Locations.Add"
+}
+
+func (r *InstallLocationsAddType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallLocationsAddParams) (*butlerd.InstallLocationsAddResult, error)) {
+  router.Register("Install.Locations.Add", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.InstallLocationsAddParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Install.Locations.Add")
+    }
+    return res, nil
+  })
+}
+
+func (r *InstallLocationsAddType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallLocationsAddParams) (*butlerd.InstallLocationsAddResult, error) {
+  var result butlerd.InstallLocationsAddResult
+  err := rc.Call("Install.Locations.Add", params, &result)
+  return &result, err
+}
+
+var InstallLocationsAdd *InstallLocationsAddType
+
+// Install.Locations.Update (Request)
+
+type InstallLocationsUpdateType struct {}
+
+var _ RequestMessage = (*InstallLocationsUpdateType)(nil)
+
+func (r *InstallLocationsUpdateType) Method() string {
+  return "Install.Locations.Update"
+}
+
+func (r *InstallLocationsUpdateType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallLocationsUpdateParams) (*butlerd.InstallLocationsUpdateResult, error)) {
+  router.Register("Install.Locations.Update", butlerd.TransactionMiddleware(func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.InstallLocationsUpdateParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Install.Locations.Update")
+    }
+    return res, nil
+  }))
+}
+
+func (r *InstallLocationsUpdateType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallLocationsUpdateParams) (*butlerd.InstallLocationsUpdateResult, error) {
+  var result butlerd.InstallLocationsUpdateResult
+  err := rc.Call("Install.Locations.Update", params, &result)
+  return &result, err
+}
+
+var InstallLocationsUpdate *InstallLocationsUpdateType
+
+// Install.Locations.Remove (Request)
+
+type InstallLocationsRemoveType struct {}
+
+var _ RequestMessage = (*InstallLocationsRemoveType)(nil)
+
+func (r *InstallLocationsRemoveType) Method() string {
+  return "Install.Locations.Remove"
+}
+
+func (r *InstallLocationsRemoveType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallLocationsRemoveParams) (*butlerd.InstallLocationsRemoveResult, error)) {
+  router.Register("Install.Locations.Remove", butlerd.TransactionMiddleware(func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.InstallLocationsRemoveParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Install.Locations.Remove")
+    }
+    return res, nil
+  }))
+}
+
+func (r *InstallLocationsRemoveType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallLocationsRemoveParams) (*butlerd.InstallLocationsRemoveResult, error) {
+  var result butlerd.InstallLocationsRemoveResult
+  err := rc.Call("Install.Locations.Remove", params, &result)
+  return &result, err
+}
+
+var InstallLocationsRemove *InstallLocationsRemoveType
+
+// Install.Locations.GetByID (Request)
+
+type InstallLocationsGetByIDType struct {}
+
+var _ RequestMessage = (*InstallLocationsGetByIDType)(nil)
+
+func (r *InstallLocationsGetByIDType) Method() string {
+  return "Install.Locations.GetByID"
+}
+
+func (r *InstallLocationsGetByIDType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallLocationsGetByIDParams) (*butlerd.InstallLocationsGetByIDResult, error)) {
+  router.Register("Install.Locations.GetByID", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.InstallLocationsGetByIDParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Install.Locations.GetByID")
+    }
+    return res, nil
+  })
+}
+
+func (r *InstallLocationsGetByIDType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallLocationsGetByIDParams) (*butlerd.InstallLocationsGetByIDResult, error) {
+  var result butlerd.InstallLocationsGetByIDResult
+  err := rc.Call("Install.Locations.GetByID", params, &result)
+  return &result, err
+}
+
+var InstallLocationsGetByID *InstallLocationsGetByIDType
+
+// Install.Locations.Scan (Request)
+
+type InstallLocationsScanType struct {}
+
+var _ RequestMessage = (*InstallLocationsScanType)(nil)
+
+func (r *InstallLocationsScanType) Method() string {
+  return "Install.Locations.Scan"
+}
+
+func (r *InstallLocationsScanType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallLocationsScanParams) (*butlerd.InstallLocationsScanResult, error)) {
+  router.Register("Install.Locations.Scan", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.InstallLocationsScanParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Install.Locations.Scan")
+    }
+    return res, nil
+  })
+}
+
+func (r *InstallLocationsScanType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallLocationsScanParams) (*butlerd.InstallLocationsScanResult, error) {
+  var result butlerd.InstallLocationsScanResult
+  err := rc.Call("Install.Locations.Scan", params, &result)
+  return &result, err
+}
+
+var InstallLocationsScan *InstallLocationsScanType
+
+// Install.Locations.Scan.Yield (Notification)
+
+type InstallLocationsScanYieldType struct {}
+
+var _ NotificationMessage = (*InstallLocationsScanYieldType)(nil)
+
+func (r *InstallLocationsScanYieldType) Method() string {
+  return "Install.Locations.Scan.Yield"
+}
+
+func (r *InstallLocationsScanYieldType) Notify(rc *butlerd.RequestContext, params butlerd.InstallLocationsScanYieldNotification) (error) {
+  return rc.Notify("Install.Locations.Scan.Yield", params)
+}
+
+func (r *InstallLocationsScanYieldType) Register(router router, f func(butlerd.InstallLocationsScanYieldNotification)) {
+  router.RegisterNotification("Install.Locations.Scan.Yield", func (notif jsonrpc2.Notification) {
+    var params butlerd.InstallLocationsScanYieldNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var InstallLocationsScanYield *InstallLocationsScanYieldType
+
+// Install.Locations.Scan.ConfirmImport (Request)
+
+type InstallLocationsScanConfirmImportType struct {}
+
+var _ RequestMessage = (*InstallLocationsScanConfirmImportType)(nil)
+
+func (r *InstallLocationsScanConfirmImportType) Method() string {
+  return "Install.Locations.Scan.ConfirmImport"
+}
+
+func (r *InstallLocationsScanConfirmImportType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.InstallLocationsScanConfirmImportParams) (*butlerd.InstallLocationsScanConfirmImportResult, error)) {
+  router.Register("Install.Locations.Scan.ConfirmImport", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.InstallLocationsScanConfirmImportParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Install.Locations.Scan.ConfirmImport")
+    }
+    return res, nil
+  })
+}
+
+func (r *InstallLocationsScanConfirmImportType) Call(rc *butlerd.RequestContext, params butlerd.InstallLocationsScanConfirmImportParams) (*butlerd.InstallLocationsScanConfirmImportResult, error) {
+  var result butlerd.InstallLocationsScanConfirmImportResult
+  err := rc.Call("Install.Locations.Scan.ConfirmImport", params, &result)
+  return &result, err
+}
+
+var InstallLocationsScanConfirmImport *InstallLocationsScanConfirmImportType
+
+// Install.Prereqs (Request)
+
+type InstallPrereqsType struct {}
+
+var _ RequestMessage = (*InstallPrereqsType)(nil)
+
+func (r *InstallPrereqsType) Method() string {
+  return "Install.Prereqs"
+}
+
+func (r *InstallPrereqsType) Register(router router, f func(*butlerd.RequestContext, butlerd.InstallPrereqsParams) (*butlerd.InstallPrereqsResult, error)) {
+  router.Register("Install.Prereqs", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.InstallPrereqsParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Install.Prereqs")
+    }
+    return res, nil
+  })
+}
+
+func (r *InstallPrereqsType) TestCall(rc *butlerd.RequestContext, params butlerd.InstallPrereqsParams) (*butlerd.InstallPrereqsResult, error) {
+  var result butlerd.InstallPrereqsResult
+  err := rc.Call("Install.Prereqs", params, &result)
+  return &result, err
+}
+
+var InstallPrereqs *InstallPrereqsType
+
+
+//==============================
+// Launch
+//==============================
+
+// Launch (Request)
+
+type LaunchType struct {}
+
+var _ RequestMessage = (*LaunchType)(nil)
+
+func (r *LaunchType) Method() string {
+  return "Launch"
+}
+
+func (r *LaunchType) Register(router router, f func(*butlerd.RequestContext, butlerd.LaunchParams) (*butlerd.LaunchResult, error)) {
+  router.Register("Launch", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.LaunchParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Launch")
+    }
+    return res, nil
+  })
+}
+
+func (r *LaunchType) TestCall(rc *butlerd.RequestContext, params butlerd.LaunchParams) (*butlerd.LaunchResult, error) {
+  var result butlerd.LaunchResult
+  err := rc.Call("Launch", params, &result)
+  return &result, err
+}
+
+var Launch *LaunchType
+
+// Launch.PrecomputePlans (Request)
+
+type LaunchPrecomputePlansType struct {}
+
+var _ RequestMessage = (*LaunchPrecomputePlansType)(nil)
+
+func (r *LaunchPrecomputePlansType) Method() string {
+  return "Launch.PrecomputePlans"
+}
+
+func (r *LaunchPrecomputePlansType) Register(router router, f func(*butlerd.RequestContext, butlerd.LaunchPrecomputePlansParams) (*butlerd.LaunchPrecomputePlansResult, error)) {
+  router.Register("Launch.PrecomputePlans", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.LaunchPrecomputePlansParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Launch.PrecomputePlans")
+    }
+    return res, nil
+  })
+}
+
+func (r *LaunchPrecomputePlansType) TestCall(rc *butlerd.RequestContext, params butlerd.LaunchPrecomputePlansParams) (*butlerd.LaunchPrecomputePlansResult, error) {
+  var result butlerd.LaunchPrecomputePlansResult
+  err := rc.Call("Launch.PrecomputePlans", params, &result)
+  return &result, err
+}
+
+var LaunchPrecomputePlans *LaunchPrecomputePlansType
+
+// LaunchRunning (Notification)
+
+type LaunchRunningType struct {}
+
+var _ NotificationMessage = (*LaunchRunningType)(nil)
+
+func (r *LaunchRunningType) Method() string {
+  return "LaunchRunning"
+}
+
+func (r *LaunchRunningType) Notify(rc *butlerd.RequestContext, params butlerd.LaunchRunningNotification) (error) {
+  return rc.Notify("LaunchRunning", params)
+}
+
+func (r *LaunchRunningType) Register(router router, f func(butlerd.LaunchRunningNotification)) {
+  router.RegisterNotification("LaunchRunning", func (notif jsonrpc2.Notification) {
+    var params butlerd.LaunchRunningNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var LaunchRunning *LaunchRunningType
+
+// LaunchExited (Notification)
+
+type LaunchExitedType struct {}
+
+var _ NotificationMessage = (*LaunchExitedType)(nil)
+
+func (r *LaunchExitedType) Method() string {
+  return "LaunchExited"
+}
+
+func (r *LaunchExitedType) Notify(rc *butlerd.RequestContext, params butlerd.LaunchExitedNotification) (error) {
+  return rc.Notify("LaunchExited", params)
+}
+
+func (r *LaunchExitedType) Register(router router, f func(butlerd.LaunchExitedNotification)) {
+  router.RegisterNotification("LaunchExited", func (notif jsonrpc2.Notification) {
+    var params butlerd.LaunchExitedNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var LaunchExited *LaunchExitedType
+
+// AcceptLicense (Request)
+
+type AcceptLicenseType struct {}
+
+var _ RequestMessage = (*AcceptLicenseType)(nil)
+
+func (r *AcceptLicenseType) Method() string {
+  return "AcceptLicense"
+}
+
+func (r *AcceptLicenseType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.AcceptLicenseParams) (*butlerd.AcceptLicenseResult, error)) {
+  router.Register("AcceptLicense", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.AcceptLicenseParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for AcceptLicense")
+    }
+    return res, nil
+  })
+}
+
+func (r *AcceptLicenseType) Call(rc *butlerd.RequestContext, params butlerd.AcceptLicenseParams) (*butlerd.AcceptLicenseResult, error) {
+  var result butlerd.AcceptLicenseResult
+  err := rc.Call("AcceptLicense", params, &result)
+  return &result, err
+}
+
+var AcceptLicense *AcceptLicenseType
+
+// PickManifestAction (Request)
+
+type PickManifestActionType struct {}
+
+var _ RequestMessage = (*PickManifestActionType)(nil)
+
+func (r *PickManifestActionType) Method() string {
+  return "PickManifestAction"
+}
+
+func (r *PickManifestActionType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.PickManifestActionParams) (*butlerd.PickManifestActionResult, error)) {
+  router.Register("PickManifestAction", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.PickManifestActionParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for PickManifestAction")
+    }
+    return res, nil
+  })
+}
+
+func (r *PickManifestActionType) Call(rc *butlerd.RequestContext, params butlerd.PickManifestActionParams) (*butlerd.PickManifestActionResult, error) {
+  var result butlerd.PickManifestActionResult
+  err := rc.Call("PickManifestAction", params, &result)
+  return &result, err
+}
+
+var PickManifestAction *PickManifestActionType
+
+// ShellLaunch (Request)
+
+type ShellLaunchType struct {}
+
+var _ RequestMessage = (*ShellLaunchType)(nil)
+
+func (r *ShellLaunchType) Method() string {
+  return "ShellLaunch"
+}
+
+func (r *ShellLaunchType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.ShellLaunchParams) (*butlerd.ShellLaunchResult, error)) {
+  router.Register("ShellLaunch", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.ShellLaunchParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for ShellLaunch")
+    }
+    return res, nil
+  })
+}
+
+func (r *ShellLaunchType) Call(rc *butlerd.RequestContext, params butlerd.ShellLaunchParams) (*butlerd.ShellLaunchResult, error) {
+  var result butlerd.ShellLaunchResult
+  err := rc.Call("ShellLaunch", params, &result)
+  return &result, err
+}
+
+var ShellLaunch *ShellLaunchType
+
+// HTMLLaunch (Request)
+
+type HTMLLaunchType struct {}
+
+var _ RequestMessage = (*HTMLLaunchType)(nil)
+
+func (r *HTMLLaunchType) Method() string {
+  return "HTMLLaunch"
+}
+
+func (r *HTMLLaunchType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.HTMLLaunchParams) (*butlerd.HTMLLaunchResult, error)) {
+  router.Register("HTMLLaunch", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.HTMLLaunchParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for HTMLLaunch")
+    }
+    return res, nil
+  })
+}
+
+func (r *HTMLLaunchType) Call(rc *butlerd.RequestContext, params butlerd.HTMLLaunchParams) (*butlerd.HTMLLaunchResult, error) {
+  var result butlerd.HTMLLaunchResult
+  err := rc.Call("HTMLLaunch", params, &result)
+  return &result, err
+}
+
+var HTMLLaunch *HTMLLaunchType
+
+// URLLaunch (Request)
+
+type URLLaunchType struct {}
+
+var _ RequestMessage = (*URLLaunchType)(nil)
+
+func (r *URLLaunchType) Method() string {
+  return "URLLaunch"
+}
+
+func (r *URLLaunchType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.URLLaunchParams) (*butlerd.URLLaunchResult, error)) {
+  router.Register("URLLaunch", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.URLLaunchParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for URLLaunch")
+    }
+    return res, nil
+  })
+}
+
+func (r *URLLaunchType) Call(rc *butlerd.RequestContext, params butlerd.URLLaunchParams) (*butlerd.URLLaunchResult, error) {
+  var result butlerd.URLLaunchResult
+  err := rc.Call("URLLaunch", params, &result)
+  return &result, err
+}
+
+var URLLaunch *URLLaunchType
+
+// AllowSandboxSetup (Request)
+
+type AllowSandboxSetupType struct {}
+
+var _ RequestMessage = (*AllowSandboxSetupType)(nil)
+
+func (r *AllowSandboxSetupType) Method() string {
+  return "AllowSandboxSetup"
+}
+
+func (r *AllowSandboxSetupType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.AllowSandboxSetupParams) (*butlerd.AllowSandboxSetupResult, error)) {
+  router.Register("AllowSandboxSetup", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.AllowSandboxSetupParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for AllowSandboxSetup")
+    }
+    return res, nil
+  })
+}
+
+func (r *AllowSandboxSetupType) Call(rc *butlerd.RequestContext, params butlerd.AllowSandboxSetupParams) (*butlerd.AllowSandboxSetupResult, error) {
+  var result butlerd.AllowSandboxSetupResult
+  err := rc.Call("AllowSandboxSetup", params, &result)
+  return &result, err
+}
+
+var AllowSandboxSetup *AllowSandboxSetupType
+
+// PrereqsStarted (Notification)
+
+type PrereqsStartedType struct {}
+
+var _ NotificationMessage = (*PrereqsStartedType)(nil)
+
+func (r *PrereqsStartedType) Method() string {
+  return "PrereqsStarted"
+}
+
+func (r *PrereqsStartedType) Notify(rc *butlerd.RequestContext, params butlerd.PrereqsStartedNotification) (error) {
+  return rc.Notify("PrereqsStarted", params)
+}
+
+func (r *PrereqsStartedType) Register(router router, f func(butlerd.PrereqsStartedNotification)) {
+  router.RegisterNotification("PrereqsStarted", func (notif jsonrpc2.Notification) {
+    var params butlerd.PrereqsStartedNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var PrereqsStarted *PrereqsStartedType
+
+// PrereqsTaskState (Notification)
+
+type PrereqsTaskStateType struct {}
+
+var _ NotificationMessage = (*PrereqsTaskStateType)(nil)
+
+func (r *PrereqsTaskStateType) Method() string {
+  return "PrereqsTaskState"
+}
+
+func (r *PrereqsTaskStateType) Notify(rc *butlerd.RequestContext, params butlerd.PrereqsTaskStateNotification) (error) {
+  return rc.Notify("PrereqsTaskState", params)
+}
+
+func (r *PrereqsTaskStateType) Register(router router, f func(butlerd.PrereqsTaskStateNotification)) {
+  router.RegisterNotification("PrereqsTaskState", func (notif jsonrpc2.Notification) {
+    var params butlerd.PrereqsTaskStateNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var PrereqsTaskState *PrereqsTaskStateType
+
+// PrereqsEnded (Notification)
+
+type PrereqsEndedType struct {}
+
+var _ NotificationMessage = (*PrereqsEndedType)(nil)
+
+func (r *PrereqsEndedType) Method() string {
+  return "PrereqsEnded"
+}
+
+func (r *PrereqsEndedType) Notify(rc *butlerd.RequestContext, params butlerd.PrereqsEndedNotification) (error) {
+  return rc.Notify("PrereqsEnded", params)
+}
+
+func (r *PrereqsEndedType) Register(router router, f func(butlerd.PrereqsEndedNotification)) {
+  router.RegisterNotification("PrereqsEnded", func (notif jsonrpc2.Notification) {
+    var params butlerd.PrereqsEndedNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var PrereqsEnded *PrereqsEndedType
+
+// PrereqsFailed (Request)
+
+type PrereqsFailedType struct {}
+
+var _ RequestMessage = (*PrereqsFailedType)(nil)
+
+func (r *PrereqsFailedType) Method() string {
+  return "PrereqsFailed"
+}
+
+func (r *PrereqsFailedType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.PrereqsFailedParams) (*butlerd.PrereqsFailedResult, error)) {
+  router.Register("PrereqsFailed", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.PrereqsFailedParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for PrereqsFailed")
+    }
+    return res, nil
+  })
+}
+
+func (r *PrereqsFailedType) Call(rc *butlerd.RequestContext, params butlerd.PrereqsFailedParams) (*butlerd.PrereqsFailedResult, error) {
+  var result butlerd.PrereqsFailedResult
+  err := rc.Call("PrereqsFailed", params, &result)
+  return &result, err
+}
+
+var PrereqsFailed *PrereqsFailedType
+
+
+//==============================
+// Downloads
+//==============================
+
+// Downloads.Queue (Request)
+
+type DownloadsQueueType struct {}
+
+var _ RequestMessage = (*DownloadsQueueType)(nil)
+
+func (r *DownloadsQueueType) Method() string {
+  return "Downloads.Queue"
+}
+
+func (r *DownloadsQueueType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsQueueParams) (*butlerd.DownloadsQueueResult, error)) {
+  router.Register("Downloads.Queue", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.DownloadsQueueParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Downloads.Queue")
+    }
+    return res, nil
+  })
+}
+
+func (r *DownloadsQueueType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsQueueParams) (*butlerd.DownloadsQueueResult, error) {
+  var result butlerd.DownloadsQueueResult
+  err := rc.Call("Downloads.Queue", params, &result)
+  return &result, err
+}
+
+var DownloadsQueue *DownloadsQueueType
+
+// Downloads.Prioritize (Request)
+
+type DownloadsPrioritizeType struct {}
+
+var _ RequestMessage = (*DownloadsPrioritizeType)(nil)
+
+func (r *DownloadsPrioritizeType) Method() string {
+  return "Downloads.Prioritize"
+}
+
+func (r *DownloadsPrioritizeType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsPrioritizeParams) (*butlerd.DownloadsPrioritizeResult, error)) {
+  router.Register("Downloads.Prioritize", butlerd.TransactionMiddleware(func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.DownloadsPrioritizeParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Downloads.Prioritize")
+    }
+    return res, nil
+  }))
+}
+
+func (r *DownloadsPrioritizeType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsPrioritizeParams) (*butlerd.DownloadsPrioritizeResult, error) {
+  var result butlerd.DownloadsPrioritizeResult
+  err := rc.Call("Downloads.Prioritize", params, &result)
+  return &result, err
+}
+
+var DownloadsPrioritize *DownloadsPrioritizeType
+
+// Downloads.List (Request)
+
+type DownloadsListType struct {}
+
+var _ RequestMessage = (*DownloadsListType)(nil)
+
+func (r *DownloadsListType) Method() string {
+  return "Downloads.List"
+}
+
+func (r *DownloadsListType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsListParams) (*butlerd.DownloadsListResult, error)) {
+  router.Register("Downloads.List", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.DownloadsListParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Downloads.List")
+    }
+    return res, nil
+  })
+}
+
+func (r *DownloadsListType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsListParams) (*butlerd.DownloadsListResult, error) {
+  var result butlerd.DownloadsListResult
+  err := rc.Call("Downloads.List", params, &result)
+  return &result, err
+}
+
+var DownloadsList *DownloadsListType
+
+// Downloads.GetByGameID (Request)
+
+type DownloadsGetByGameIDType struct {}
+
+var _ RequestMessage = (*DownloadsGetByGameIDType)(nil)
+
+func (r *DownloadsGetByGameIDType) Method() string {
+  return "Downloads.GetByGameID"
+}
+
+func (r *DownloadsGetByGameIDType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsGetByGameIDParams) (*butlerd.DownloadsGetByGameIDResult, error)) {
+  router.Register("Downloads.GetByGameID", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.DownloadsGetByGameIDParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Downloads.GetByGameID")
+    }
+    return res, nil
+  })
+}
+
+func (r *DownloadsGetByGameIDType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsGetByGameIDParams) (*butlerd.DownloadsGetByGameIDResult, error) {
+  var result butlerd.DownloadsGetByGameIDResult
+  err := rc.Call("Downloads.GetByGameID", params, &result)
+  return &result, err
+}
+
+var DownloadsGetByGameID *DownloadsGetByGameIDType
+
+// Downloads.ClearFinished (Request)
+
+type DownloadsClearFinishedType struct {}
+
+var _ RequestMessage = (*DownloadsClearFinishedType)(nil)
+
+func (r *DownloadsClearFinishedType) Method() string {
+  return "Downloads.ClearFinished"
+}
+
+func (r *DownloadsClearFinishedType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsClearFinishedParams) (*butlerd.DownloadsClearFinishedResult, error)) {
+  router.Register("Downloads.ClearFinished", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.DownloadsClearFinishedParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Downloads.ClearFinished")
+    }
+    return res, nil
+  })
+}
+
+func (r *DownloadsClearFinishedType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsClearFinishedParams) (*butlerd.DownloadsClearFinishedResult, error) {
+  var result butlerd.DownloadsClearFinishedResult
+  err := rc.Call("Downloads.ClearFinished", params, &result)
+  return &result, err
+}
+
+var DownloadsClearFinished *DownloadsClearFinishedType
+
+// Downloads.DrainQueue (Request)
+
+type DownloadsDrainQueueType struct {}
+
+var _ RequestMessage = (*DownloadsDrainQueueType)(nil)
+
+func (r *DownloadsDrainQueueType) Method() string {
+  return "Downloads.DrainQueue"
+}
+
+func (r *DownloadsDrainQueueType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsDrainQueueParams) (*butlerd.DownloadsDrainQueueResult, error)) {
+  router.Register("Downloads.DrainQueue", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.DownloadsDrainQueueParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Downloads.DrainQueue")
+    }
+    return res, nil
+  })
+}
+
+func (r *DownloadsDrainQueueType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsDrainQueueParams) (*butlerd.DownloadsDrainQueueResult, error) {
+  var result butlerd.DownloadsDrainQueueResult
+  err := rc.Call("Downloads.DrainQueue", params, &result)
+  return &result, err
+}
+
+var DownloadsDrainQueue *DownloadsDrainQueueType
+
+// Downloads.Drive (Request)
+
+type DownloadsDriveType struct {}
+
+var _ RequestMessage = (*DownloadsDriveType)(nil)
+
+func (r *DownloadsDriveType) Method() string {
+  return "Downloads.Drive"
+}
+
+func (r *DownloadsDriveType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsDriveParams) (*butlerd.DownloadsDriveResult, error)) {
+  router.Register("Downloads.Drive", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.DownloadsDriveParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Downloads.Drive")
+    }
+    return res, nil
+  })
+}
+
+func (r *DownloadsDriveType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsDriveParams) (*butlerd.DownloadsDriveResult, error) {
+  var result butlerd.DownloadsDriveResult
+  err := rc.Call("Downloads.Drive", params, &result)
+  return &result, err
+}
+
+var DownloadsDrive *DownloadsDriveType
+
+// Downloads.Drive.Cancel (Request)
+
+type DownloadsDriveCancelType struct {}
+
+var _ RequestMessage = (*DownloadsDriveCancelType)(nil)
+
+func (r *DownloadsDriveCancelType) Method() string {
+  return "Downloads.Drive.Cancel"
+}
+
+func (r *DownloadsDriveCancelType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsDriveCancelParams) (*butlerd.DownloadsDriveCancelResult, error)) {
+  router.Register("Downloads.Drive.Cancel", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.DownloadsDriveCancelParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Downloads.Drive.Cancel")
+    }
+    return res, nil
+  })
+}
+
+func (r *DownloadsDriveCancelType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsDriveCancelParams) (*butlerd.DownloadsDriveCancelResult, error) {
+  var result butlerd.DownloadsDriveCancelResult
+  err := rc.Call("Downloads.Drive.Cancel", params, &result)
+  return &result, err
+}
+
+var DownloadsDriveCancel *DownloadsDriveCancelType
+
+// Downloads.SetBandwidth (Request)
+
+type DownloadsSetBandwidthType struct {}
+
+var _ RequestMessage = (*DownloadsSetBandwidthType)(nil)
+
+func (r *DownloadsSetBandwidthType) Method() string {
+  return "Downloads.SetBandwidth"
+}
+
+func (r *DownloadsSetBandwidthType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsSetBandwidthParams) (*butlerd.DownloadsSetBandwidthResult, error)) {
+  router.Register("Downloads.SetBandwidth", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.DownloadsSetBandwidthParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Downloads.SetBandwidth")
+    }
+    return res, nil
+  })
+}
+
+func (r *DownloadsSetBandwidthType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsSetBandwidthParams) (*butlerd.DownloadsSetBandwidthResult, error) {
+  var result butlerd.DownloadsSetBandwidthResult
+  err := rc.Call("Downloads.SetBandwidth", params, &result)
+  return &result, err
+}
+
+var DownloadsSetBandwidth *DownloadsSetBandwidthType
+
+// Downloads.SetThrottle (Request)
+
+type DownloadsSetThrottleType struct {}
+
+var _ RequestMessage = (*DownloadsSetThrottleType)(nil)
+
+func (r *DownloadsSetThrottleType) Method() string {
+  return "Downloads.SetThrottle"
+}
+
+func (r *DownloadsSetThrottleType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsSetThrottleParams) (*butlerd.DownloadsSetThrottleResult, error)) {
+  router.Register("Downloads.SetThrottle", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.DownloadsSetThrottleParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Downloads.SetThrottle")
+    }
+    return res, nil
+  })
+}
+
+func (r *DownloadsSetThrottleType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsSetThrottleParams) (*butlerd.DownloadsSetThrottleResult, error) {
+  var result butlerd.DownloadsSetThrottleResult
+  err := rc.Call("Downloads.SetThrottle", params, &result)
+  return &result, err
+}
+
+var DownloadsSetThrottle *DownloadsSetThrottleType
+
+// Downloads.Pause (Request)
+
+type DownloadsPauseType struct {}
+
+var _ RequestMessage = (*DownloadsPauseType)(nil)
+
+func (r *DownloadsPauseType) Method() string {
+  return "Downloads.Pause"
+}
+
+func (r *DownloadsPauseType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsPauseParams) (*butlerd.DownloadsPauseResult, error)) {
+  router.Register("Downloads.Pause", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.DownloadsPauseParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Downloads.Pause")
+    }
+    return res, nil
+  })
+}
+
+func (r *DownloadsPauseType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsPauseParams) (*butlerd.DownloadsPauseResult, error) {
+  var result butlerd.DownloadsPauseResult
+  err := rc.Call("Downloads.Pause", params, &result)
+  return &result, err
+}
+
+var DownloadsPause *DownloadsPauseType
+
+// Downloads.Resume (Request)
+
+type DownloadsResumeType struct {}
+
+var _ RequestMessage = (*DownloadsResumeType)(nil)
+
+func (r *DownloadsResumeType) Method() string {
+  return "Downloads.Resume"
+}
+
+func (r *DownloadsResumeType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsResumeParams) (*butlerd.DownloadsResumeResult, error)) {
+  router.Register("Downloads.Resume", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.DownloadsResumeParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Downloads.Resume")
+    }
+    return res, nil
+  })
+}
+
+func (r *DownloadsResumeType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsResumeParams) (*butlerd.DownloadsResumeResult, error) {
+  var result butlerd.DownloadsResumeResult
+  err := rc.Call("Downloads.Resume", params, &result)
+  return &result, err
+}
+
+var DownloadsResume *DownloadsResumeType
+
+// Downloads.Retry (Request)
+
+type DownloadsRetryType struct {}
+
+var _ RequestMessage = (*DownloadsRetryType)(nil)
+
+func (r *DownloadsRetryType) Method() string {
+  return "Downloads.Retry"
 }
 
-func (r *DownloadsClearFinishedType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsClearFinishedParams) (*butlerd.DownloadsClearFinishedResult, error)) {
-  router.Register("Downloads.ClearFinished", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.DownloadsClearFinishedParams
+func (r *DownloadsRetryType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsRetryParams) (*butlerd.DownloadsRetryResult, error)) {
+  router.Register("Downloads.Retry", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.DownloadsRetryParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -2544,33 +5116,33 @@ func (r *DownloadsClearFinishedType) Register(router router, f func(*butlerd.Req
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for Downloads.ClearFinished")
+    	return nil, errors.New("internal error: nil result for Downloads.Retry")
     }
     return res, nil
   })
 }
 
-func (r *DownloadsClearFinishedType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsClearFinishedParams) (*butlerd.DownloadsClearFinishedResult, error) {
-  var result butlerd.DownloadsClearFinishedResult
-  err := rc.Call("Downloads.ClearFinished", params, &result)
+func (r *DownloadsRetryType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsRetryParams) (*butlerd.DownloadsRetryResult, error) {
+  var result butlerd.DownloadsRetryResult
+  err := rc.Call("Downloads.Retry", params, &result)
   return &result, err
 }
 
-var DownloadsClearFinished *DownloadsClearFinishedType
+var DownloadsRetry *DownloadsRetryType
 
-// Downloads.Drive (Request)
+// Downloads.Discard (Request)
 
-type DownloadsDriveType struct {}
+type DownloadsDiscardType struct {}
 
-var _ RequestMessage = (*DownloadsDriveType)(nil)
+var _ RequestMessage = (*DownloadsDiscardType)(nil)
 
-func (r *DownloadsDriveType) Method() string {
-  return "Downloads.Drive"
+func (r *DownloadsDiscardType) Method() string {
+  return "Downloads.Discard"
 }
 
-func (r *DownloadsDriveType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsDriveParams) (*butlerd.DownloadsDriveResult, error)) {
-  router.Register("Downloads.Drive", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.DownloadsDriveParams
+func (r *DownloadsDiscardType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsDiscardParams) (*butlerd.DownloadsDiscardResult, error)) {
+  router.Register("Downloads.Discard", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.DownloadsDiscardParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -2584,33 +5156,33 @@ func (r *DownloadsDriveType) Register(router router, f func(*butlerd.RequestCont
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for Downloads.Drive")
+    	return nil, errors.New("internal error: nil result for Downloads.Discard")
     }
     return res, nil
   })
 }
 
-func (r *DownloadsDriveType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsDriveParams) (*butlerd.DownloadsDriveResult, error) {
-  var result butlerd.DownloadsDriveResult
-  err := rc.Call("Downloads.Drive", params, &result)
+func (r *DownloadsDiscardType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsDiscardParams) (*butlerd.DownloadsDiscardResult, error) {
+  var result butlerd.DownloadsDiscardResult
+  err := rc.Call("Downloads.Discard", params, &result)
   return &result, err
 }
 
-var DownloadsDrive *DownloadsDriveType
+var DownloadsDiscard *DownloadsDiscardType
 
-// Downloads.Drive.Cancel (Request)
+// Downloads.GetHistory (Request)
 
-type DownloadsDriveCancelType struct {}
+type DownloadsGetHistoryType struct {}
 
-var _ RequestMessage = (*DownloadsDriveCancelType)(nil)
+var _ RequestMessage = (*DownloadsGetHistoryType)(nil)
 
-func (r *DownloadsDriveCancelType) Method() string {
-  return "Downloads.Drive.Cancel"
+func (r *DownloadsGetHistoryType) Method() string {
+  return "Downloads.GetHistory"
 }
 
-func (r *DownloadsDriveCancelType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsDriveCancelParams) (*butlerd.DownloadsDriveCancelResult, error)) {
-  router.Register("Downloads.Drive.Cancel", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.DownloadsDriveCancelParams
+func (r *DownloadsGetHistoryType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsGetHistoryParams) (*butlerd.DownloadsGetHistoryResult, error)) {
+  router.Register("Downloads.GetHistory", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.DownloadsGetHistoryParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -2624,33 +5196,33 @@ func (r *DownloadsDriveCancelType) Register(router router, f func(*butlerd.Reque
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for Downloads.Drive.Cancel")
+    	return nil, errors.New("internal error: nil result for Downloads.GetHistory")
     }
     return res, nil
   })
 }
 
-func (r *DownloadsDriveCancelType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsDriveCancelParams) (*butlerd.DownloadsDriveCancelResult, error) {
-  var result butlerd.DownloadsDriveCancelResult
-  err := rc.Call("Downloads.Drive.Cancel", params, &result)
+func (r *DownloadsGetHistoryType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsGetHistoryParams) (*butlerd.DownloadsGetHistoryResult, error) {
+  var result butlerd.DownloadsGetHistoryResult
+  err := rc.Call("Downloads.GetHistory", params, &result)
   return &result, err
 }
 
-var DownloadsDriveCancel *DownloadsDriveCancelType
+var DownloadsGetHistory *DownloadsGetHistoryType
 
-// Downloads.Retry (Request)
+// Downloads.GetNetworkStats (Request)
 
-type DownloadsRetryType struct {}
+type DownloadsGetNetworkStatsType struct {}
 
-var _ RequestMessage = (*DownloadsRetryType)(nil)
+var _ RequestMessage = (*DownloadsGetNetworkStatsType)(nil)
 
-func (r *DownloadsRetryType) Method() string {
-  return "Downloads.Retry"
+func (r *DownloadsGetNetworkStatsType) Method() string {
+  return "Downloads.GetNetworkStats"
 }
 
-func (r *DownloadsRetryType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsRetryParams) (*butlerd.DownloadsRetryResult, error)) {
-  router.Register("Downloads.Retry", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.DownloadsRetryParams
+func (r *DownloadsGetNetworkStatsType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsGetNetworkStatsParams) (*butlerd.DownloadsGetNetworkStatsResult, error)) {
+  router.Register("Downloads.GetNetworkStats", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.DownloadsGetNetworkStatsParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -2664,33 +5236,33 @@ func (r *DownloadsRetryType) Register(router router, f func(*butlerd.RequestCont
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for Downloads.Retry")
+    	return nil, errors.New("internal error: nil result for Downloads.GetNetworkStats")
     }
     return res, nil
   })
 }
 
-func (r *DownloadsRetryType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsRetryParams) (*butlerd.DownloadsRetryResult, error) {
-  var result butlerd.DownloadsRetryResult
-  err := rc.Call("Downloads.Retry", params, &result)
+func (r *DownloadsGetNetworkStatsType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsGetNetworkStatsParams) (*butlerd.DownloadsGetNetworkStatsResult, error) {
+  var result butlerd.DownloadsGetNetworkStatsResult
+  err := rc.Call("Downloads.GetNetworkStats", params, &result)
   return &result, err
 }
 
-var DownloadsRetry *DownloadsRetryType
+var DownloadsGetNetworkStats *DownloadsGetNetworkStatsType
 
-// Downloads.Discard (Request)
+// Downloads.GetSpeedHistory (Request)
 
-type DownloadsDiscardType struct {}
+type DownloadsGetSpeedHistoryType struct {}
 
-var _ RequestMessage = (*DownloadsDiscardType)(nil)
+var _ RequestMessage = (*DownloadsGetSpeedHistoryType)(nil)
 
-func (r *DownloadsDiscardType) Method() string {
-  return "Downloads.Discard"
+func (r *DownloadsGetSpeedHistoryType) Method() string {
+  return "Downloads.GetSpeedHistory"
 }
 
-func (r *DownloadsDiscardType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsDiscardParams) (*butlerd.DownloadsDiscardResult, error)) {
-  router.Register("Downloads.Discard", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.DownloadsDiscardParams
+func (r *DownloadsGetSpeedHistoryType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsGetSpeedHistoryParams) (*butlerd.DownloadsGetSpeedHistoryResult, error)) {
+  router.Register("Downloads.GetSpeedHistory", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.DownloadsGetSpeedHistoryParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -2704,19 +5276,19 @@ func (r *DownloadsDiscardType) Register(router router, f func(*butlerd.RequestCo
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for Downloads.Discard")
+    	return nil, errors.New("internal error: nil result for Downloads.GetSpeedHistory")
     }
     return res, nil
   })
 }
 
-func (r *DownloadsDiscardType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsDiscardParams) (*butlerd.DownloadsDiscardResult, error) {
-  var result butlerd.DownloadsDiscardResult
-  err := rc.Call("Downloads.Discard", params, &result)
+func (r *DownloadsGetSpeedHistoryType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsGetSpeedHistoryParams) (*butlerd.DownloadsGetSpeedHistoryResult, error) {
+  var result butlerd.DownloadsGetSpeedHistoryResult
+  err := rc.Call("Downloads.GetSpeedHistory", params, &result)
   return &result, err
 }
 
-var DownloadsDiscard *DownloadsDiscardType
+var DownloadsGetSpeedHistory *DownloadsGetSpeedHistoryType
 
 
 //==============================
@@ -2792,6 +5364,75 @@ func (r *GameUpdateAvailableType) Register(router router, f func(butlerd.GameUpd
 
 var GameUpdateAvailable *GameUpdateAvailableType
 
+// GameDelisted (Notification)
+
+type GameDelistedType struct {}
+
+var _ NotificationMessage = (*GameDelistedType)(nil)
+
+func (r *GameDelistedType) Method() string {
+  return "GameDelisted"
+}
+
+func (r *GameDelistedType) Notify(rc *butlerd.RequestContext, params butlerd.GameDelistedNotification) (error) {
+  return rc.Notify("GameDelisted", params)
+}
+
+func (r *GameDelistedType) Register(router router, f func(butlerd.GameDelistedNotification)) {
+  router.RegisterNotification("GameDelisted", func (notif jsonrpc2.Notification) {
+    var params butlerd.GameDelistedNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var GameDelisted *GameDelistedType
+
+// ConfirmUploadSuccessor (Request)
+
+type ConfirmUploadSuccessorType struct {}
+
+var _ RequestMessage = (*ConfirmUploadSuccessorType)(nil)
+
+func (r *ConfirmUploadSuccessorType) Method() string {
+  return "ConfirmUploadSuccessor"
+}
+
+func (r *ConfirmUploadSuccessorType) Register(router router, f func(*butlerd.RequestContext, butlerd.ConfirmUploadSuccessorParams) (*butlerd.ConfirmUploadSuccessorResult, error)) {
+  router.Register("ConfirmUploadSuccessor", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.ConfirmUploadSuccessorParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for ConfirmUploadSuccessor")
+    }
+    return res, nil
+  })
+}
+
+func (r *ConfirmUploadSuccessorType) TestCall(rc *butlerd.RequestContext, params butlerd.ConfirmUploadSuccessorParams) (*butlerd.ConfirmUploadSuccessorResult, error) {
+  var result butlerd.ConfirmUploadSuccessorResult
+  err := rc.Call("ConfirmUploadSuccessor", params, &result)
+  return &result, err
+}
+
+var ConfirmUploadSuccessor *ConfirmUploadSuccessorType
+
 // SnoozeCave (Request)
 
 type SnoozeCaveType struct {}
@@ -2832,29 +5473,19 @@ func (r *SnoozeCaveType) TestCall(rc *butlerd.RequestContext, params butlerd.Sno
 
 var SnoozeCave *SnoozeCaveType
 
+// CaveUpdateBatch (Request)
 
-//==============================
-// update
-//==============================
-
-
-//==============================
-// Launch
-//==============================
-
-// Launch (Request)
-
-type LaunchType struct {}
+type CaveUpdateBatchType struct {}
 
-var _ RequestMessage = (*LaunchType)(nil)
+var _ RequestMessage = (*CaveUpdateBatchType)(nil)
 
-func (r *LaunchType) Method() string {
-  return "Launch"
+func (r *CaveUpdateBatchType) Method() string {
+  return "CaveUpdateBatch"
 }
 
-func (r *LaunchType) Register(router router, f func(*butlerd.RequestContext, butlerd.LaunchParams) (*butlerd.LaunchResult, error)) {
-  router.Register("Launch", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.LaunchParams
+func (r *CaveUpdateBatchType) Register(router router, f func(*butlerd.RequestContext, butlerd.CaveUpdateBatchParams) (*butlerd.CaveUpdateBatchResult, error)) {
+  router.Register("CaveUpdateBatch", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CaveUpdateBatchParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -2868,91 +5499,73 @@ func (r *LaunchType) Register(router router, f func(*butlerd.RequestContext, but
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for Launch")
+    	return nil, errors.New("internal error: nil result for CaveUpdateBatch")
     }
     return res, nil
   })
 }
 
-func (r *LaunchType) TestCall(rc *butlerd.RequestContext, params butlerd.LaunchParams) (*butlerd.LaunchResult, error) {
-  var result butlerd.LaunchResult
-  err := rc.Call("Launch", params, &result)
+func (r *CaveUpdateBatchType) TestCall(rc *butlerd.RequestContext, params butlerd.CaveUpdateBatchParams) (*butlerd.CaveUpdateBatchResult, error) {
+  var result butlerd.CaveUpdateBatchResult
+  err := rc.Call("CaveUpdateBatch", params, &result)
   return &result, err
 }
 
-var Launch *LaunchType
-
-// LaunchRunning (Notification)
+var CaveUpdateBatch *CaveUpdateBatchType
 
-type LaunchRunningType struct {}
+// CaveBatchStatus (Request)
 
-var _ NotificationMessage = (*LaunchRunningType)(nil)
+type CaveBatchStatusType struct {}
 
-func (r *LaunchRunningType) Method() string {
-  return "LaunchRunning"
-}
+var _ RequestMessage = (*CaveBatchStatusType)(nil)
 
-func (r *LaunchRunningType) Notify(rc *butlerd.RequestContext, params butlerd.LaunchRunningNotification) (error) {
-  return rc.Notify("LaunchRunning", params)
+func (r *CaveBatchStatusType) Method() string {
+  return "CaveBatchStatus"
 }
 
-func (r *LaunchRunningType) Register(router router, f func(butlerd.LaunchRunningNotification)) {
-  router.RegisterNotification("LaunchRunning", func (notif jsonrpc2.Notification) {
-    var params butlerd.LaunchRunningNotification
-    if notif.Params != nil {
-      err := json.Unmarshal(*notif.Params, &params)
-      if err != nil {
-        return
-      }
+func (r *CaveBatchStatusType) Register(router router, f func(*butlerd.RequestContext, butlerd.CaveBatchStatusParams) (*butlerd.CaveBatchStatusResult, error)) {
+  router.Register("CaveBatchStatus", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CaveBatchStatusParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
     }
-    f(params)
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for CaveBatchStatus")
+    }
+    return res, nil
   })
 }
 
-var LaunchRunning *LaunchRunningType
-
-// LaunchExited (Notification)
-
-type LaunchExitedType struct {}
-
-var _ NotificationMessage = (*LaunchExitedType)(nil)
-
-func (r *LaunchExitedType) Method() string {
-  return "LaunchExited"
-}
-
-func (r *LaunchExitedType) Notify(rc *butlerd.RequestContext, params butlerd.LaunchExitedNotification) (error) {
-  return rc.Notify("LaunchExited", params)
-}
-
-func (r *LaunchExitedType) Register(router router, f func(butlerd.LaunchExitedNotification)) {
-  router.RegisterNotification("LaunchExited", func (notif jsonrpc2.Notification) {
-    var params butlerd.LaunchExitedNotification
-    if notif.Params != nil {
-      err := json.Unmarshal(*notif.Params, &params)
-      if err != nil {
-        return
-      }
-    }
-    f(params)
-  })
+func (r *CaveBatchStatusType) TestCall(rc *butlerd.RequestContext, params butlerd.CaveBatchStatusParams) (*butlerd.CaveBatchStatusResult, error) {
+  var result butlerd.CaveBatchStatusResult
+  err := rc.Call("CaveBatchStatus", params, &result)
+  return &result, err
 }
 
-var LaunchExited *LaunchExitedType
+var CaveBatchStatus *CaveBatchStatusType
 
-// AcceptLicense (Request)
+// UpdateAvailableAskUser (Request)
 
-type AcceptLicenseType struct {}
+type UpdateAvailableAskUserType struct {}
 
-var _ RequestMessage = (*AcceptLicenseType)(nil)
+var _ RequestMessage = (*UpdateAvailableAskUserType)(nil)
 
-func (r *AcceptLicenseType) Method() string {
-  return "AcceptLicense"
+func (r *UpdateAvailableAskUserType) Method() string {
+  return "UpdateAvailableAskUser"
 }
 
-func (r *AcceptLicenseType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.AcceptLicenseParams) (*butlerd.AcceptLicenseResult, error)) {
-  router.Register("AcceptLicense", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.AcceptLicenseParams
+func (r *UpdateAvailableAskUserType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.UpdateAvailableAskUserParams) (*butlerd.UpdateAvailableAskUserResult, error)) {
+  router.Register("UpdateAvailableAskUser", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.UpdateAvailableAskUserParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -2966,33 +5579,72 @@ func (r *AcceptLicenseType) TestRegister(router router, f func(*butlerd.RequestC
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for AcceptLicense")
+    	return nil, errors.New("internal error: nil result for UpdateAvailableAskUser")
     }
     return res, nil
   })
 }
 
-func (r *AcceptLicenseType) Call(rc *butlerd.RequestContext, params butlerd.AcceptLicenseParams) (*butlerd.AcceptLicenseResult, error) {
-  var result butlerd.AcceptLicenseResult
-  err := rc.Call("AcceptLicense", params, &result)
+func (r *UpdateAvailableAskUserType) Call(rc *butlerd.RequestContext, params butlerd.UpdateAvailableAskUserParams) (*butlerd.UpdateAvailableAskUserResult, error) {
+  var result butlerd.UpdateAvailableAskUserResult
+  err := rc.Call("UpdateAvailableAskUser", params, &result)
   return &result, err
 }
 
-var AcceptLicense *AcceptLicenseType
+var UpdateAvailableAskUser *UpdateAvailableAskUserType
 
-// PickManifestAction (Request)
+// BatchUpdateComplete (Notification)
 
-type PickManifestActionType struct {}
+type BatchUpdateCompleteType struct {}
+
+var _ NotificationMessage = (*BatchUpdateCompleteType)(nil)
+
+func (r *BatchUpdateCompleteType) Method() string {
+  return "BatchUpdateComplete"
+}
+
+func (r *BatchUpdateCompleteType) Notify(rc *butlerd.RequestContext, params butlerd.BatchUpdateCompleteNotification) (error) {
+  return rc.Notify("BatchUpdateComplete", params)
+}
+
+func (r *BatchUpdateCompleteType) Register(router router, f func(butlerd.BatchUpdateCompleteNotification)) {
+  router.RegisterNotification("BatchUpdateComplete", func (notif jsonrpc2.Notification) {
+    var params butlerd.BatchUpdateCompleteNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var BatchUpdateComplete *BatchUpdateCompleteType
+
+
+//==============================
+// update
+//==============================
+
+
+//==============================
+// Clean Downloads
+//==============================
+
+// CleanDownloads.Search (Request)
+
+type CleanDownloadsSearchType struct {}
 
-var _ RequestMessage = (*PickManifestActionType)(nil)
+var _ RequestMessage = (*CleanDownloadsSearchType)(nil)
 
-func (r *PickManifestActionType) Method() string {
-  return "PickManifestAction"
+func (r *CleanDownloadsSearchType) Method() string {
+  return "CleanDownloads.Search"
 }
 
-func (r *PickManifestActionType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.PickManifestActionParams) (*butlerd.PickManifestActionResult, error)) {
-  router.Register("PickManifestAction", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.PickManifestActionParams
+func (r *CleanDownloadsSearchType) Register(router router, f func(*butlerd.RequestContext, butlerd.CleanDownloadsSearchParams) (*butlerd.CleanDownloadsSearchResult, error)) {
+  router.Register("CleanDownloads.Search", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CleanDownloadsSearchParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -3006,33 +5658,33 @@ func (r *PickManifestActionType) TestRegister(router router, f func(*butlerd.Req
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for PickManifestAction")
+    	return nil, errors.New("internal error: nil result for CleanDownloads.Search")
     }
     return res, nil
   })
 }
 
-func (r *PickManifestActionType) Call(rc *butlerd.RequestContext, params butlerd.PickManifestActionParams) (*butlerd.PickManifestActionResult, error) {
-  var result butlerd.PickManifestActionResult
-  err := rc.Call("PickManifestAction", params, &result)
+func (r *CleanDownloadsSearchType) TestCall(rc *butlerd.RequestContext, params butlerd.CleanDownloadsSearchParams) (*butlerd.CleanDownloadsSearchResult, error) {
+  var result butlerd.CleanDownloadsSearchResult
+  err := rc.Call("CleanDownloads.Search", params, &result)
   return &result, err
 }
 
-var PickManifestAction *PickManifestActionType
+var CleanDownloadsSearch *CleanDownloadsSearchType
 
-// ShellLaunch (Request)
+// CleanDownloads.Apply (Request)
 
-type ShellLaunchType struct {}
+type CleanDownloadsApplyType struct {}
 
-var _ RequestMessage = (*ShellLaunchType)(nil)
+var _ RequestMessage = (*CleanDownloadsApplyType)(nil)
 
-func (r *ShellLaunchType) Method() string {
-  return "ShellLaunch"
+func (r *CleanDownloadsApplyType) Method() string {
+  return "CleanDownloads.Apply"
 }
 
-func (r *ShellLaunchType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.ShellLaunchParams) (*butlerd.ShellLaunchResult, error)) {
-  router.Register("ShellLaunch", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.ShellLaunchParams
+func (r *CleanDownloadsApplyType) Register(router router, f func(*butlerd.RequestContext, butlerd.CleanDownloadsApplyParams) (*butlerd.CleanDownloadsApplyResult, error)) {
+  router.Register("CleanDownloads.Apply", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CleanDownloadsApplyParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -3046,33 +5698,38 @@ func (r *ShellLaunchType) TestRegister(router router, f func(*butlerd.RequestCon
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for ShellLaunch")
+    	return nil, errors.New("internal error: nil result for CleanDownloads.Apply")
     }
     return res, nil
   })
 }
 
-func (r *ShellLaunchType) Call(rc *butlerd.RequestContext, params butlerd.ShellLaunchParams) (*butlerd.ShellLaunchResult, error) {
-  var result butlerd.ShellLaunchResult
-  err := rc.Call("ShellLaunch", params, &result)
+func (r *CleanDownloadsApplyType) TestCall(rc *butlerd.RequestContext, params butlerd.CleanDownloadsApplyParams) (*butlerd.CleanDownloadsApplyResult, error) {
+  var result butlerd.CleanDownloadsApplyResult
+  err := rc.Call("CleanDownloads.Apply", params, &result)
   return &result, err
 }
 
-var ShellLaunch *ShellLaunchType
+var CleanDownloadsApply *CleanDownloadsApplyType
 
-// HTMLLaunch (Request)
 
-type HTMLLaunchType struct {}
+//==============================
+// System
+//==============================
 
-var _ RequestMessage = (*HTMLLaunchType)(nil)
+// System.StatFS (Request)
 
-func (r *HTMLLaunchType) Method() string {
-  return "HTMLLaunch"
+type SystemStatFSType struct {}
+
+var _ RequestMessage = (*SystemStatFSType)(nil)
+
+func (r *SystemStatFSType) Method() string {
+  return "System.StatFS"
 }
 
-func (r *HTMLLaunchType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.HTMLLaunchParams) (*butlerd.HTMLLaunchResult, error)) {
-  router.Register("HTMLLaunch", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.HTMLLaunchParams
+func (r *SystemStatFSType) Register(router router, f func(*butlerd.RequestContext, butlerd.SystemStatFSParams) (*butlerd.SystemStatFSResult, error)) {
+  router.Register("System.StatFS", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.SystemStatFSParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -3086,33 +5743,33 @@ func (r *HTMLLaunchType) TestRegister(router router, f func(*butlerd.RequestCont
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for HTMLLaunch")
+    	return nil, errors.New("internal error: nil result for System.StatFS")
     }
     return res, nil
   })
 }
 
-func (r *HTMLLaunchType) Call(rc *butlerd.RequestContext, params butlerd.HTMLLaunchParams) (*butlerd.HTMLLaunchResult, error) {
-  var result butlerd.HTMLLaunchResult
-  err := rc.Call("HTMLLaunch", params, &result)
+func (r *SystemStatFSType) TestCall(rc *butlerd.RequestContext, params butlerd.SystemStatFSParams) (*butlerd.SystemStatFSResult, error) {
+  var result butlerd.SystemStatFSResult
+  err := rc.Call("System.StatFS", params, &result)
   return &result, err
 }
 
-var HTMLLaunch *HTMLLaunchType
+var SystemStatFS *SystemStatFSType
 
-// URLLaunch (Request)
+// System.Stats (Request)
 
-type URLLaunchType struct {}
+type SystemStatsType struct {}
 
-var _ RequestMessage = (*URLLaunchType)(nil)
+var _ RequestMessage = (*SystemStatsType)(nil)
 
-func (r *URLLaunchType) Method() string {
-  return "URLLaunch"
+func (r *SystemStatsType) Method() string {
+  return "System.Stats"
 }
 
-func (r *URLLaunchType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.URLLaunchParams) (*butlerd.URLLaunchResult, error)) {
-  router.Register("URLLaunch", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.URLLaunchParams
+func (r *SystemStatsType) Register(router router, f func(*butlerd.RequestContext, butlerd.SystemStatsParams) (*butlerd.SystemStatsResult, error)) {
+  router.Register("System.Stats", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.SystemStatsParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -3126,33 +5783,33 @@ func (r *URLLaunchType) TestRegister(router router, f func(*butlerd.RequestConte
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for URLLaunch")
+    	return nil, errors.New("internal error: nil result for System.Stats")
     }
     return res, nil
   })
 }
 
-func (r *URLLaunchType) Call(rc *butlerd.RequestContext, params butlerd.URLLaunchParams) (*butlerd.URLLaunchResult, error) {
-  var result butlerd.URLLaunchResult
-  err := rc.Call("URLLaunch", params, &result)
+func (r *SystemStatsType) TestCall(rc *butlerd.RequestContext, params butlerd.SystemStatsParams) (*butlerd.SystemStatsResult, error) {
+  var result butlerd.SystemStatsResult
+  err := rc.Call("System.Stats", params, &result)
   return &result, err
 }
 
-var URLLaunch *URLLaunchType
+var SystemStats *SystemStatsType
 
-// AllowSandboxSetup (Request)
+// System.ExportDiagnostics (Request)
 
-type AllowSandboxSetupType struct {}
+type SystemExportDiagnosticsType struct {}
 
-var _ RequestMessage = (*AllowSandboxSetupType)(nil)
+var _ RequestMessage = (*SystemExportDiagnosticsType)(nil)
 
-func (r *AllowSandboxSetupType) Method() string {
-  return "AllowSandboxSetup"
+func (r *SystemExportDiagnosticsType) Method() string {
+  return "System.ExportDiagnostics"
 }
 
-func (r *AllowSandboxSetupType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.AllowSandboxSetupParams) (*butlerd.AllowSandboxSetupResult, error)) {
-  router.Register("AllowSandboxSetup", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.AllowSandboxSetupParams
+func (r *SystemExportDiagnosticsType) Register(router router, f func(*butlerd.RequestContext, butlerd.SystemExportDiagnosticsParams) (*butlerd.SystemExportDiagnosticsResult, error)) {
+  router.Register("System.ExportDiagnostics", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.SystemExportDiagnosticsParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -3166,120 +5823,33 @@ func (r *AllowSandboxSetupType) TestRegister(router router, f func(*butlerd.Requ
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for AllowSandboxSetup")
+    	return nil, errors.New("internal error: nil result for System.ExportDiagnostics")
     }
     return res, nil
   })
 }
 
-func (r *AllowSandboxSetupType) Call(rc *butlerd.RequestContext, params butlerd.AllowSandboxSetupParams) (*butlerd.AllowSandboxSetupResult, error) {
-  var result butlerd.AllowSandboxSetupResult
-  err := rc.Call("AllowSandboxSetup", params, &result)
+func (r *SystemExportDiagnosticsType) TestCall(rc *butlerd.RequestContext, params butlerd.SystemExportDiagnosticsParams) (*butlerd.SystemExportDiagnosticsResult, error) {
+  var result butlerd.SystemExportDiagnosticsResult
+  err := rc.Call("System.ExportDiagnostics", params, &result)
   return &result, err
 }
 
-var AllowSandboxSetup *AllowSandboxSetupType
-
-// PrereqsStarted (Notification)
-
-type PrereqsStartedType struct {}
-
-var _ NotificationMessage = (*PrereqsStartedType)(nil)
-
-func (r *PrereqsStartedType) Method() string {
-  return "PrereqsStarted"
-}
-
-func (r *PrereqsStartedType) Notify(rc *butlerd.RequestContext, params butlerd.PrereqsStartedNotification) (error) {
-  return rc.Notify("PrereqsStarted", params)
-}
-
-func (r *PrereqsStartedType) Register(router router, f func(butlerd.PrereqsStartedNotification)) {
-  router.RegisterNotification("PrereqsStarted", func (notif jsonrpc2.Notification) {
-    var params butlerd.PrereqsStartedNotification
-    if notif.Params != nil {
-      err := json.Unmarshal(*notif.Params, &params)
-      if err != nil {
-        return
-      }
-    }
-    f(params)
-  })
-}
-
-var PrereqsStarted *PrereqsStartedType
-
-// PrereqsTaskState (Notification)
-
-type PrereqsTaskStateType struct {}
-
-var _ NotificationMessage = (*PrereqsTaskStateType)(nil)
-
-func (r *PrereqsTaskStateType) Method() string {
-  return "PrereqsTaskState"
-}
-
-func (r *PrereqsTaskStateType) Notify(rc *butlerd.RequestContext, params butlerd.PrereqsTaskStateNotification) (error) {
-  return rc.Notify("PrereqsTaskState", params)
-}
-
-func (r *PrereqsTaskStateType) Register(router router, f func(butlerd.PrereqsTaskStateNotification)) {
-  router.RegisterNotification("PrereqsTaskState", func (notif jsonrpc2.Notification) {
-    var params butlerd.PrereqsTaskStateNotification
-    if notif.Params != nil {
-      err := json.Unmarshal(*notif.Params, &params)
-      if err != nil {
-        return
-      }
-    }
-    f(params)
-  })
-}
-
-var PrereqsTaskState *PrereqsTaskStateType
-
-// PrereqsEnded (Notification)
-
-type PrereqsEndedType struct {}
-
-var _ NotificationMessage = (*PrereqsEndedType)(nil)
-
-func (r *PrereqsEndedType) Method() string {
-  return "PrereqsEnded"
-}
-
-func (r *PrereqsEndedType) Notify(rc *butlerd.RequestContext, params butlerd.PrereqsEndedNotification) (error) {
-  return rc.Notify("PrereqsEnded", params)
-}
-
-func (r *PrereqsEndedType) Register(router router, f func(butlerd.PrereqsEndedNotification)) {
-  router.RegisterNotification("PrereqsEnded", func (notif jsonrpc2.Notification) {
-    var params butlerd.PrereqsEndedNotification
-    if notif.Params != nil {
-      err := json.Unmarshal(*notif.Params, &params)
-      if err != nil {
-        return
-      }
-    }
-    f(params)
-  })
-}
-
-var PrereqsEnded *PrereqsEndedType
+var SystemExportDiagnostics *SystemExportDiagnosticsType
 
-// PrereqsFailed (Request)
+// System.LogDump (Request)
 
-type PrereqsFailedType struct {}
+type SystemLogDumpType struct {}
 
-var _ RequestMessage = (*PrereqsFailedType)(nil)
+var _ RequestMessage = (*SystemLogDumpType)(nil)
 
-func (r *PrereqsFailedType) Method() string {
-  return "PrereqsFailed"
+func (r *SystemLogDumpType) Method() string {
+  return "System.LogDump"
 }
 
-func (r *PrereqsFailedType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.PrereqsFailedParams) (*butlerd.PrereqsFailedResult, error)) {
-  router.Register("PrereqsFailed", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.PrereqsFailedParams
+func (r *SystemLogDumpType) Register(router router, f func(*butlerd.RequestContext, butlerd.SystemLogDumpParams) (*butlerd.SystemLogDumpResult, error)) {
+  router.Register("System.LogDump", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.SystemLogDumpParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -3293,38 +5863,33 @@ func (r *PrereqsFailedType) TestRegister(router router, f func(*butlerd.RequestC
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for PrereqsFailed")
+    	return nil, errors.New("internal error: nil result for System.LogDump")
     }
     return res, nil
   })
 }
 
-func (r *PrereqsFailedType) Call(rc *butlerd.RequestContext, params butlerd.PrereqsFailedParams) (*butlerd.PrereqsFailedResult, error) {
-  var result butlerd.PrereqsFailedResult
-  err := rc.Call("PrereqsFailed", params, &result)
+func (r *SystemLogDumpType) TestCall(rc *butlerd.RequestContext, params butlerd.SystemLogDumpParams) (*butlerd.SystemLogDumpResult, error) {
+  var result butlerd.SystemLogDumpResult
+  err := rc.Call("System.LogDump", params, &result)
   return &result, err
 }
 
-var PrereqsFailed *PrereqsFailedType
-
-
-//==============================
-// Clean Downloads
-//==============================
+var SystemLogDump *SystemLogDumpType
 
-// CleanDownloads.Search (Request)
+// System.ImportLegacyLibrary (Request)
 
-type CleanDownloadsSearchType struct {}
+type SystemImportLegacyLibraryType struct {}
 
-var _ RequestMessage = (*CleanDownloadsSearchType)(nil)
+var _ RequestMessage = (*SystemImportLegacyLibraryType)(nil)
 
-func (r *CleanDownloadsSearchType) Method() string {
-  return "CleanDownloads.Search"
+func (r *SystemImportLegacyLibraryType) Method() string {
+  return "System.ImportLegacyLibrary"
 }
 
-func (r *CleanDownloadsSearchType) Register(router router, f func(*butlerd.RequestContext, butlerd.CleanDownloadsSearchParams) (*butlerd.CleanDownloadsSearchResult, error)) {
-  router.Register("CleanDownloads.Search", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.CleanDownloadsSearchParams
+func (r *SystemImportLegacyLibraryType) Register(router router, f func(*butlerd.RequestContext, butlerd.SystemImportLegacyLibraryParams) (*butlerd.SystemImportLegacyLibraryResult, error)) {
+  router.Register("System.ImportLegacyLibrary", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.SystemImportLegacyLibraryParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -3338,33 +5903,33 @@ func (r *CleanDownloadsSearchType) Register(router router, f func(*butlerd.Reque
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for CleanDownloads.Search")
+    	return nil, errors.New("internal error: nil result for System.ImportLegacyLibrary")
     }
     return res, nil
   })
 }
 
-func (r *CleanDownloadsSearchType) TestCall(rc *butlerd.RequestContext, params butlerd.CleanDownloadsSearchParams) (*butlerd.CleanDownloadsSearchResult, error) {
-  var result butlerd.CleanDownloadsSearchResult
-  err := rc.Call("CleanDownloads.Search", params, &result)
+func (r *SystemImportLegacyLibraryType) TestCall(rc *butlerd.RequestContext, params butlerd.SystemImportLegacyLibraryParams) (*butlerd.SystemImportLegacyLibraryResult, error) {
+  var result butlerd.SystemImportLegacyLibraryResult
+  err := rc.Call("System.ImportLegacyLibrary", params, &result)
   return &result, err
 }
 
-var CleanDownloadsSearch *CleanDownloadsSearchType
+var SystemImportLegacyLibrary *SystemImportLegacyLibraryType
 
-// CleanDownloads.Apply (Request)
+// System.GetOperationDiagnostics (Request)
 
-type CleanDownloadsApplyType struct {}
+type SystemGetOperationDiagnosticsType struct {}
 
-var _ RequestMessage = (*CleanDownloadsApplyType)(nil)
+var _ RequestMessage = (*SystemGetOperationDiagnosticsType)(nil)
 
-func (r *CleanDownloadsApplyType) Method() string {
-  return "CleanDownloads.Apply"
+func (r *SystemGetOperationDiagnosticsType) Method() string {
+  return "System.GetOperationDiagnostics"
 }
 
-func (r *CleanDownloadsApplyType) Register(router router, f func(*butlerd.RequestContext, butlerd.CleanDownloadsApplyParams) (*butlerd.CleanDownloadsApplyResult, error)) {
-  router.Register("CleanDownloads.Apply", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.CleanDownloadsApplyParams
+func (r *SystemGetOperationDiagnosticsType) Register(router router, f func(*butlerd.RequestContext, butlerd.SystemGetOperationDiagnosticsParams) (*butlerd.SystemGetOperationDiagnosticsResult, error)) {
+  router.Register("System.GetOperationDiagnostics", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.SystemGetOperationDiagnosticsParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -3378,38 +5943,33 @@ func (r *CleanDownloadsApplyType) Register(router router, f func(*butlerd.Reques
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for CleanDownloads.Apply")
+    	return nil, errors.New("internal error: nil result for System.GetOperationDiagnostics")
     }
     return res, nil
   })
 }
 
-func (r *CleanDownloadsApplyType) TestCall(rc *butlerd.RequestContext, params butlerd.CleanDownloadsApplyParams) (*butlerd.CleanDownloadsApplyResult, error) {
-  var result butlerd.CleanDownloadsApplyResult
-  err := rc.Call("CleanDownloads.Apply", params, &result)
+func (r *SystemGetOperationDiagnosticsType) TestCall(rc *butlerd.RequestContext, params butlerd.SystemGetOperationDiagnosticsParams) (*butlerd.SystemGetOperationDiagnosticsResult, error) {
+  var result butlerd.SystemGetOperationDiagnosticsResult
+  err := rc.Call("System.GetOperationDiagnostics", params, &result)
   return &result, err
 }
 
-var CleanDownloadsApply *CleanDownloadsApplyType
-
-
-//==============================
-// System
-//==============================
+var SystemGetOperationDiagnostics *SystemGetOperationDiagnosticsType
 
-// System.StatFS (Request)
+// System.EnvironmentInfo (Request)
 
-type SystemStatFSType struct {}
+type SystemEnvironmentInfoType struct {}
 
-var _ RequestMessage = (*SystemStatFSType)(nil)
+var _ RequestMessage = (*SystemEnvironmentInfoType)(nil)
 
-func (r *SystemStatFSType) Method() string {
-  return "System.StatFS"
+func (r *SystemEnvironmentInfoType) Method() string {
+  return "System.EnvironmentInfo"
 }
 
-func (r *SystemStatFSType) Register(router router, f func(*butlerd.RequestContext, butlerd.SystemStatFSParams) (*butlerd.SystemStatFSResult, error)) {
-  router.Register("System.StatFS", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.SystemStatFSParams
+func (r *SystemEnvironmentInfoType) Register(router router, f func(*butlerd.RequestContext, butlerd.SystemEnvironmentInfoParams) (*butlerd.SystemEnvironmentInfoResult, error)) {
+  router.Register("System.EnvironmentInfo", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.SystemEnvironmentInfoParams
     err := json.Unmarshal(*rc.Params, &params)
     if err != nil {
     	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
@@ -3423,19 +5983,19 @@ func (r *SystemStatFSType) Register(router router, f func(*butlerd.RequestContex
     	return nil, err
     }
     if res == nil {
-    	return nil, errors.New("internal error: nil result for System.StatFS")
+    	return nil, errors.New("internal error: nil result for System.EnvironmentInfo")
     }
     return res, nil
   })
 }
 
-func (r *SystemStatFSType) TestCall(rc *butlerd.RequestContext, params butlerd.SystemStatFSParams) (*butlerd.SystemStatFSResult, error) {
-  var result butlerd.SystemStatFSResult
-  err := rc.Call("System.StatFS", params, &result)
+func (r *SystemEnvironmentInfoType) TestCall(rc *butlerd.RequestContext, params butlerd.SystemEnvironmentInfoParams) (*butlerd.SystemEnvironmentInfoResult, error) {
+  var result butlerd.SystemEnvironmentInfoResult
+  err := rc.Call("System.EnvironmentInfo", params, &result)
   return &result, err
 }
 
-var SystemStatFS *SystemStatFSType
+var SystemEnvironmentInfo *SystemEnvironmentInfoType
 
 
 //==============================
@@ -3530,11 +6090,16 @@ func EnsureAllRequests(router *butlerd.Router) {
   if _, ok := router.Handlers["Version.Get"]; !ok { panic("missing request handler for (Version.Get)") }
   if _, ok := router.Handlers["Network.SetSimulateOffline"]; !ok { panic("missing request handler for (Network.SetSimulateOffline)") }
   if _, ok := router.Handlers["Network.SetBandwidthThrottle"]; !ok { panic("missing request handler for (Network.SetBandwidthThrottle)") }
+  if _, ok := router.Handlers["Network.Diagnostics"]; !ok { panic("missing request handler for (Network.Diagnostics)") }
+  if _, ok := router.Handlers["Settings.Get"]; !ok { panic("missing request handler for (Settings.Get)") }
+  if _, ok := router.Handlers["Settings.List"]; !ok { panic("missing request handler for (Settings.List)") }
+  if _, ok := router.Handlers["Settings.Set"]; !ok { panic("missing request handler for (Settings.Set)") }
   if _, ok := router.Handlers["Profile.List"]; !ok { panic("missing request handler for (Profile.List)") }
   if _, ok := router.Handlers["Profile.LoginWithPassword"]; !ok { panic("missing request handler for (Profile.LoginWithPassword)") }
   if _, ok := router.Handlers["Profile.LoginWithAPIKey"]; !ok { panic("missing request handler for (Profile.LoginWithAPIKey)") }
   if _, ok := router.Handlers["Profile.UseSavedLogin"]; !ok { panic("missing request handler for (Profile.UseSavedLogin)") }
   if _, ok := router.Handlers["Profile.Forget"]; !ok { panic("missing request handler for (Profile.Forget)") }
+  if _, ok := router.Handlers["Profile.DataErase"]; !ok { panic("missing request handler for (Profile.DataErase)") }
   if _, ok := router.Handlers["Profile.Data.Put"]; !ok { panic("missing request handler for (Profile.Data.Put)") }
   if _, ok := router.Handlers["Profile.Data.Get"]; !ok { panic("missing request handler for (Profile.Data.Get)") }
   if _, ok := router.Handlers["Search.Games"]; !ok { panic("missing request handler for (Search.Games)") }
@@ -3556,33 +6121,79 @@ func EnsureAllRequests(router *butlerd.Router) {
   if _, ok := router.Handlers["Fetch.Cave"]; !ok { panic("missing request handler for (Fetch.Cave)") }
   if _, ok := router.Handlers["Fetch.ExpireAll"]; !ok { panic("missing request handler for (Fetch.ExpireAll)") }
   if _, ok := router.Handlers["Game.FindUploads"]; !ok { panic("missing request handler for (Game.FindUploads)") }
+  if _, ok := router.Handlers["Install.ExplainUploadChoice"]; !ok { panic("missing request handler for (Install.ExplainUploadChoice)") }
   if _, ok := router.Handlers["Install.Queue"]; !ok { panic("missing request handler for (Install.Queue)") }
+  if _, ok := router.Handlers["Install.QueueMany"]; !ok { panic("missing request handler for (Install.QueueMany)") }
+  if _, ok := router.Handlers["Collections.InstallAll"]; !ok { panic("missing request handler for (Collections.InstallAll)") }
+  if _, ok := router.Handlers["Collections.InstallAll.Cancel"]; !ok { panic("missing request handler for (Collections.InstallAll.Cancel)") }
   if _, ok := router.Handlers["Install.Plan"]; !ok { panic("missing request handler for (Install.Plan)") }
   if _, ok := router.Handlers["Caves.SetPinned"]; !ok { panic("missing request handler for (Caves.SetPinned)") }
+  if _, ok := router.Handlers["Caves.SetAutoUpdatePolicy"]; !ok { panic("missing request handler for (Caves.SetAutoUpdatePolicy)") }
+  if _, ok := router.Handlers["Caves.GetLaunchTargets"]; !ok { panic("missing request handler for (Caves.GetLaunchTargets)") }
+  if _, ok := router.Handlers["Caves.SetLaunchTarget"]; !ok { panic("missing request handler for (Caves.SetLaunchTarget)") }
+  if _, ok := router.Handlers["Caves.ListBuildHistory"]; !ok { panic("missing request handler for (Caves.ListBuildHistory)") }
+  if _, ok := router.Handlers["Caves.Downgrade"]; !ok { panic("missing request handler for (Caves.Downgrade)") }
+  if _, ok := router.Handlers["Caves.ByProfile"]; !ok { panic("missing request handler for (Caves.ByProfile)") }
+  if _, ok := router.Handlers["Caves.Filter"]; !ok { panic("missing request handler for (Caves.Filter)") }
+  if _, ok := router.Handlers["Caves.FuzzySearch"]; !ok { panic("missing request handler for (Caves.FuzzySearch)") }
+  if _, ok := router.Handlers["Caves.ListFiles"]; !ok { panic("missing request handler for (Caves.ListFiles)") }
+  if _, ok := router.Handlers["Caves.ReadFile"]; !ok { panic("missing request handler for (Caves.ReadFile)") }
+  if _, ok := router.Handlers["Caves.OpenInstallFolder"]; !ok { panic("missing request handler for (Caves.OpenInstallFolder)") }
+  if _, ok := router.Handlers["Caves.GetDLCs"]; !ok { panic("missing request handler for (Caves.GetDLCs)") }
+  if _, ok := router.Handlers["Caves.DetectGhosts"]; !ok { panic("missing request handler for (Caves.DetectGhosts)") }
+  if _, ok := router.Handlers["Caves.FindMoved"]; !ok { panic("missing request handler for (Caves.FindMoved)") }
+  if _, ok := router.Handlers["Caves.Relink"]; !ok { panic("missing request handler for (Caves.Relink)") }
+  if _, ok := router.Handlers["Caves.ListEvents"]; !ok { panic("missing request handler for (Caves.ListEvents)") }
+  if _, ok := router.Handlers["Caves.RebuildReceipt"]; !ok { panic("missing request handler for (Caves.RebuildReceipt)") }
+  if _, ok := router.Handlers["Caves.RebuildReceipts"]; !ok { panic("missing request handler for (Caves.RebuildReceipts)") }
+  if _, ok := router.Handlers["Caves.ApplyNamingTemplate"]; !ok { panic("missing request handler for (Caves.ApplyNamingTemplate)") }
+  if _, ok := router.Handlers["CaveVerify"]; !ok { panic("missing request handler for (CaveVerify)") }
   if _, ok := router.Handlers["Install.CreateShortcut"]; !ok { panic("missing request handler for (Install.CreateShortcut)") }
   if _, ok := router.Handlers["Install.Perform"]; !ok { panic("missing request handler for (Install.Perform)") }
   if _, ok := router.Handlers["Install.Cancel"]; !ok { panic("missing request handler for (Install.Cancel)") }
   if _, ok := router.Handlers["Uninstall.Perform"]; !ok { panic("missing request handler for (Uninstall.Perform)") }
+  if _, ok := router.Handlers["CaveUninstallBatch"]; !ok { panic("missing request handler for (CaveUninstallBatch)") }
   if _, ok := router.Handlers["Install.VersionSwitch.Queue"]; !ok { panic("missing request handler for (Install.VersionSwitch.Queue)") }
   if _, ok := router.Handlers["Install.Locations.List"]; !ok { panic("missing request handler for (Install.Locations.List)") }
   if _, ok := router.Handlers["Install.Locations.Add"]; !ok { panic("missing request handler for (Install.Locations.Add)") }
+  if _, ok := router.Handlers["Install.Locations.Update"]; !ok { panic("missing request handler for (Install.Locations.Update)") }
   if _, ok := router.Handlers["Install.Locations.Remove"]; !ok { panic("missing request handler for (Install.Locations.Remove)") }
   if _, ok := router.Handlers["Install.Locations.GetByID"]; !ok { panic("missing request handler for (Install.Locations.GetByID)") }
   if _, ok := router.Handlers["Install.Locations.Scan"]; !ok { panic("missing request handler for (Install.Locations.Scan)") }
+  if _, ok := router.Handlers["Install.Prereqs"]; !ok { panic("missing request handler for (Install.Prereqs)") }
+  if _, ok := router.Handlers["Launch"]; !ok { panic("missing request handler for (Launch)") }
+  if _, ok := router.Handlers["Launch.PrecomputePlans"]; !ok { panic("missing request handler for (Launch.PrecomputePlans)") }
   if _, ok := router.Handlers["Downloads.Queue"]; !ok { panic("missing request handler for (Downloads.Queue)") }
   if _, ok := router.Handlers["Downloads.Prioritize"]; !ok { panic("missing request handler for (Downloads.Prioritize)") }
   if _, ok := router.Handlers["Downloads.List"]; !ok { panic("missing request handler for (Downloads.List)") }
+  if _, ok := router.Handlers["Downloads.GetByGameID"]; !ok { panic("missing request handler for (Downloads.GetByGameID)") }
   if _, ok := router.Handlers["Downloads.ClearFinished"]; !ok { panic("missing request handler for (Downloads.ClearFinished)") }
+  if _, ok := router.Handlers["Downloads.DrainQueue"]; !ok { panic("missing request handler for (Downloads.DrainQueue)") }
   if _, ok := router.Handlers["Downloads.Drive"]; !ok { panic("missing request handler for (Downloads.Drive)") }
   if _, ok := router.Handlers["Downloads.Drive.Cancel"]; !ok { panic("missing request handler for (Downloads.Drive.Cancel)") }
+  if _, ok := router.Handlers["Downloads.SetBandwidth"]; !ok { panic("missing request handler for (Downloads.SetBandwidth)") }
+  if _, ok := router.Handlers["Downloads.SetThrottle"]; !ok { panic("missing request handler for (Downloads.SetThrottle)") }
+  if _, ok := router.Handlers["Downloads.Pause"]; !ok { panic("missing request handler for (Downloads.Pause)") }
+  if _, ok := router.Handlers["Downloads.Resume"]; !ok { panic("missing request handler for (Downloads.Resume)") }
   if _, ok := router.Handlers["Downloads.Retry"]; !ok { panic("missing request handler for (Downloads.Retry)") }
   if _, ok := router.Handlers["Downloads.Discard"]; !ok { panic("missing request handler for (Downloads.Discard)") }
+  if _, ok := router.Handlers["Downloads.GetHistory"]; !ok { panic("missing request handler for (Downloads.GetHistory)") }
+  if _, ok := router.Handlers["Downloads.GetNetworkStats"]; !ok { panic("missing request handler for (Downloads.GetNetworkStats)") }
+  if _, ok := router.Handlers["Downloads.GetSpeedHistory"]; !ok { panic("missing request handler for (Downloads.GetSpeedHistory)") }
   if _, ok := router.Handlers["CheckUpdate"]; !ok { panic("missing request handler for (CheckUpdate)") }
+  if _, ok := router.Handlers["ConfirmUploadSuccessor"]; !ok { panic("missing request handler for (ConfirmUploadSuccessor)") }
   if _, ok := router.Handlers["SnoozeCave"]; !ok { panic("missing request handler for (SnoozeCave)") }
-  if _, ok := router.Handlers["Launch"]; !ok { panic("missing request handler for (Launch)") }
+  if _, ok := router.Handlers["CaveUpdateBatch"]; !ok { panic("missing request handler for (CaveUpdateBatch)") }
+  if _, ok := router.Handlers["CaveBatchStatus"]; !ok { panic("missing request handler for (CaveBatchStatus)") }
   if _, ok := router.Handlers["CleanDownloads.Search"]; !ok { panic("missing request handler for (CleanDownloads.Search)") }
   if _, ok := router.Handlers["CleanDownloads.Apply"]; !ok { panic("missing request handler for (CleanDownloads.Apply)") }
   if _, ok := router.Handlers["System.StatFS"]; !ok { panic("missing request handler for (System.StatFS)") }
+  if _, ok := router.Handlers["System.Stats"]; !ok { panic("missing request handler for (System.Stats)") }
+  if _, ok := router.Handlers["System.ExportDiagnostics"]; !ok { panic("missing request handler for (System.ExportDiagnostics)") }
+  if _, ok := router.Handlers["System.LogDump"]; !ok { panic("missing request handler for (System.LogDump)") }
+  if _, ok := router.Handlers["System.ImportLegacyLibrary"]; !ok { panic("missing request handler for (System.ImportLegacyLibrary)") }
+  if _, ok := router.Handlers["System.GetOperationDiagnostics"]; !ok { panic("missing request handler for (System.GetOperationDiagnostics)") }
+  if _, ok := router.Handlers["System.EnvironmentInfo"]; !ok { panic("missing request handler for (System.EnvironmentInfo)") }
   if _, ok := router.Handlers["Test.DoubleTwice"]; !ok { panic("missing request handler for (Test.DoubleTwice)") }
 }
 
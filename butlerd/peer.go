@@ -0,0 +1,101 @@
+package butlerd
+
+import (
+	"net"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// maxProcessTreeDepth bounds how many ancestors are walked when
+// looking for the parent tree a peer should belong to.
+const maxProcessTreeDepth = 64
+
+// PeerPolicy restricts which local processes may connect to the daemon,
+// on top of knowing the secret.
+type PeerPolicy struct {
+	// Executables allowed to connect, as absolute paths
+	AllowedExecutables []string
+	// If non-zero, that process and all its descendants may connect
+	ParentTreePID int
+}
+
+// PeerProcess is the local process on the other end of a connection
+type PeerProcess struct {
+	PID        int
+	Executable string
+}
+
+// Enabled returns true if the policy restricts anything
+func (pp *PeerPolicy) Enabled() bool {
+	return pp != nil && (len(pp.AllowedExecutables) > 0 || pp.ParentTreePID != 0)
+}
+
+// Check finds the process on the other end of conn, and returns an
+// error if the policy doesn't let it connect. The process is returned
+// whenever it was found, so rejections can say who was turned away.
+// If it can't be found, say it belongs to another user, that's an
+// error too: connections are only let in once they're known to be allowed.
+func (pp *PeerPolicy) Check(conn net.Conn) (*PeerProcess, error) {
+	pid, err := peerPID(conn)
+	if err != nil {
+		return nil, errors.WithMessage(err, "finding peer process")
+	}
+
+	peer := &PeerProcess{PID: pid}
+	peer.Executable, err = processExecutable(pid)
+	if err != nil {
+		return peer, errors.WithMessage(err, "finding peer executable")
+	}
+
+	for _, allowed := range pp.AllowedExecutables {
+		if sameExecutable(allowed, peer.Executable) {
+			return peer, nil
+		}
+	}
+
+	if pp.ParentTreePID != 0 {
+		ancestor := pid
+		for depth := 0; depth < maxProcessTreeDepth; depth++ {
+			if ancestor == pp.ParentTreePID {
+				return peer, nil
+			}
+			parent, err := parentPID(ancestor)
+			if err != nil || parent == ancestor || parent <= 0 {
+				break
+			}
+			ancestor = parent
+		}
+	}
+
+	return peer, errors.Errorf("process %d (%s) isn't allowed to connect", peer.PID, peer.Executable)
+}
+
+func sameExecutable(a string, b string) bool {
+	clean := func(path string) string {
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			path = resolved
+		}
+		return filepath.Clean(path)
+	}
+	a, b = clean(a), clean(b)
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// tcpAddrs returns both ends of a TCP connection
+func tcpAddrs(conn net.Conn) (local *net.TCPAddr, remote *net.TCPAddr, err error) {
+	local, ok := conn.LocalAddr().(*net.TCPAddr)
+	if !ok {
+		return nil, nil, errors.Errorf("not a TCP connection: %v", conn.LocalAddr())
+	}
+	remote, ok = conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return nil, nil, errors.Errorf("not a TCP connection: %v", conn.RemoteAddr())
+	}
+	return local, remote, nil
+}
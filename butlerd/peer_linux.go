@@ -0,0 +1,175 @@
+//+build linux
+
+package butlerd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// PeerChecksSupported is true if PeerPolicy can find the process
+// on the other end of a connection on this platform.
+const PeerChecksSupported = true
+
+// peerPID finds the socket of the other end of conn in /proc/net/tcp
+// (or tcp6), then the process that has it open. SO_PEERCRED only works
+// for unix sockets, and butlerd listens on loopback TCP.
+func peerPID(conn net.Conn) (int, error) {
+	local, remote, err := tcpAddrs(conn)
+	if err != nil {
+		return 0, err
+	}
+
+	// the peer's socket sees our ends the other way around
+	inode, uid, err := findSocket(remote, local)
+	if err != nil {
+		return 0, err
+	}
+	return findSocketOwner(inode, uid)
+}
+
+// findSocket returns the inode of the TCP socket from local to remote,
+// and the uid it belongs to. IPv4 sockets are in /proc/net/tcp, unless
+// they're IPv6 sockets connected to IPv4 addresses: those are in
+// /proc/net/tcp6, like the others.
+func findSocket(local *net.TCPAddr, remote *net.TCPAddr) (inode string, uid string, err error) {
+	tables := []string{"/proc/net/tcp6"}
+	if local.IP.To4() != nil {
+		tables = append([]string{"/proc/net/tcp"}, tables...)
+	}
+
+	for _, table := range tables {
+		v6 := table == "/proc/net/tcp6"
+		inode, uid, err = scanProcNet(table, procNetAddr(local, v6), procNetAddr(remote, v6))
+		if err != nil || inode != "" {
+			return inode, uid, err
+		}
+	}
+	return "", "", errors.Errorf("no socket found for %v", local)
+}
+
+// scanProcNet looks for the socket from local to remote in table,
+// formatted like procNetAddr does. It returns an empty inode if
+// there's none, or no such table because IPv6 is disabled.
+func scanProcNet(table string, local string, remote string) (inode string, uid string, err error) {
+	f, err := os.Open(table)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "", nil
+		}
+		return "", "", errors.WithStack(err)
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		if fields[1] == local && fields[2] == remote {
+			return fields[9], fields[7], nil
+		}
+	}
+	return "", "", errors.WithStack(s.Err())
+}
+
+// findSocketOwner returns the process that has the socket inode open.
+// Only the processes of the socket's owner are looked into: those of
+// other users can't be, unless we're root, and they're not the ones
+// that connected anyway.
+func findSocketOwner(inode string, uid string) (int, error) {
+	procs, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	link := fmt.Sprintf("socket:[%s]", inode)
+	for _, proc := range procs {
+		pid, err := strconv.Atoi(proc.Name())
+		if err != nil {
+			continue
+		}
+		if stat, ok := proc.Sys().(*syscall.Stat_t); ok && strconv.FormatUint(uint64(stat.Uid), 10) != uid {
+			continue
+		}
+
+		fdDir := fmt.Sprintf("/proc/%d/fd", pid)
+		fds, err := readDirNames(fdDir)
+		if err != nil {
+			// gone already, or not ours to look into
+			continue
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd))
+			if err == nil && target == link {
+				return pid, nil
+			}
+		}
+	}
+	return 0, errors.Errorf("no process found for socket %s (owned by another user?)", inode)
+}
+
+func readDirNames(dir string) ([]string, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Readdirnames(-1)
+}
+
+// procNetAddr formats addr like /proc/net/tcp (or tcp6, if v6 is true)
+// does: the address as host-order words (all platforms we ship on are
+// little-endian), then the port. IPv4 addresses are IPv4-mapped in tcp6.
+func procNetAddr(addr *net.TCPAddr, v6 bool) string {
+	ip := addr.IP.To16()
+	if !v6 {
+		ip = addr.IP.To4()
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(ip); i += 4 {
+		fmt.Fprintf(&b, "%08X", binary.LittleEndian.Uint32(ip[i:i+4]))
+	}
+	fmt.Fprintf(&b, ":%04X", addr.Port)
+	return b.String()
+}
+
+func processExecutable(pid int) (string, error) {
+	exe, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", pid))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return exe, nil
+}
+
+func parentPID(pid int) (int, error) {
+	stat, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	// the command name is in parens and may contain spaces,
+	// the parent PID is the second field after it
+	s := string(stat)
+	fields := strings.Fields(s[strings.LastIndex(s, ")")+1:])
+	if len(fields) < 2 {
+		return 0, errors.Errorf("unexpected stat for process %d", pid)
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return ppid, nil
+}
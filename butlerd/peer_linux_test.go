@@ -0,0 +1,90 @@
+package butlerd
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PeerPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:")
+	assert.NoError(err)
+	defer listener.Close()
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	assert.NoError(err)
+	defer client.Close()
+
+	server, err := listener.Accept()
+	assert.NoError(err)
+	defer server.Close()
+
+	exe, err := os.Executable()
+	assert.NoError(err)
+
+	assert.False((&PeerPolicy{}).Enabled())
+
+	peer, err := (&PeerPolicy{AllowedExecutables: []string{exe}}).Check(server)
+	assert.NoError(err)
+	if assert.NotNil(peer) {
+		assert.EqualValues(os.Getpid(), peer.PID)
+	}
+
+	_, err = (&PeerPolicy{ParentTreePID: os.Getppid()}).Check(server)
+	assert.NoError(err, "we're a child of our parent")
+
+	peer, err = (&PeerPolicy{AllowedExecutables: []string{"/nonexistent/itch"}}).Check(server)
+	assert.Error(err)
+	if assert.NotNil(peer) {
+		assert.EqualValues(os.Getpid(), peer.PID, "rejections say who was turned away")
+	}
+
+	_, err = (&PeerPolicy{ParentTreePID: 1 << 30}).Check(server)
+	assert.Error(err)
+}
+
+func Test_PeerPolicyIPv6(t *testing.T) {
+	assert := assert.New(t)
+
+	exe, err := os.Executable()
+	assert.NoError(err)
+	policy := &PeerPolicy{AllowedExecutables: []string{exe}}
+
+	check := func(listenAddress string, dialHost string) {
+		t.Helper()
+
+		listener, err := net.Listen("tcp", listenAddress)
+		if err != nil {
+			t.Logf("Skipping (%s): %v", listenAddress, err)
+			return
+		}
+		defer listener.Close()
+
+		_, port, err := net.SplitHostPort(listener.Addr().String())
+		assert.NoError(err)
+		client, err := net.Dial("tcp", net.JoinHostPort(dialHost, port))
+		if err != nil {
+			t.Logf("Skipping (%s) to (%s): %v", dialHost, listenAddress, err)
+			return
+		}
+		defer client.Close()
+
+		server, err := listener.Accept()
+		assert.NoError(err)
+		defer server.Close()
+
+		peer, err := policy.Check(server)
+		assert.NoError(err, "(%s) to (%s)", dialHost, listenAddress)
+		if assert.NotNil(peer) {
+			assert.EqualValues(os.Getpid(), peer.PID)
+		}
+	}
+
+	check("[::1]:", "::1")
+	// IPv6 sockets connected to IPv4 addresses are in /proc/net/tcp6
+	check("[::]:", "127.0.0.1")
+}
@@ -0,0 +1,25 @@
+//+build !linux,!windows
+
+package butlerd
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// PeerChecksSupported is true if PeerPolicy can find the process
+// on the other end of a connection on this platform.
+const PeerChecksSupported = false
+
+func peerPID(conn net.Conn) (int, error) {
+	return 0, errors.New("peer checks are not supported on this platform")
+}
+
+func processExecutable(pid int) (string, error) {
+	return "", errors.New("peer checks are not supported on this platform")
+}
+
+func parentPID(pid int) (int, error) {
+	return 0, errors.New("peer checks are not supported on this platform")
+}
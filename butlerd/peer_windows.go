@@ -0,0 +1,138 @@
+//+build windows
+
+package butlerd
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows"
+)
+
+// PeerChecksSupported is true if PeerPolicy can find the process
+// on the other end of a connection on this platform.
+const PeerChecksSupported = true
+
+var (
+	modiphlpapi = windows.NewLazySystemDLL("iphlpapi.dll")
+	modkernel32 = windows.NewLazySystemDLL("kernel32.dll")
+
+	procGetExtendedTcpTable        = modiphlpapi.NewProc("GetExtendedTcpTable")
+	procQueryFullProcessImageNameW = modkernel32.NewProc("QueryFullProcessImageNameW")
+)
+
+const tcpTableOwnerPIDConnections = 4
+
+type mibTCPRowOwnerPID struct {
+	State      uint32
+	LocalAddr  uint32
+	LocalPort  uint32
+	RemoteAddr uint32
+	RemotePort uint32
+	OwningPID  uint32
+}
+
+// peerPID looks up the other end of conn in the TCP connection table,
+// which knows which process owns each connection. GetNamedPipeClientProcessId
+// would do for named pipes, but butlerd listens on loopback TCP.
+func peerPID(conn net.Conn) (int, error) {
+	local, remote, err := tcpAddrs(conn)
+	if err != nil {
+		return 0, err
+	}
+
+	var buf []byte
+	size := uint32(0)
+	for {
+		var bufPtr uintptr
+		if len(buf) > 0 {
+			bufPtr = uintptr(unsafe.Pointer(&buf[0]))
+		}
+		r, _, _ := procGetExtendedTcpTable.Call(
+			bufPtr,
+			uintptr(unsafe.Pointer(&size)),
+			0,
+			windows.AF_INET,
+			tcpTableOwnerPIDConnections,
+			0,
+		)
+		if r == uintptr(windows.ERROR_INSUFFICIENT_BUFFER) {
+			buf = make([]byte, size)
+			continue
+		}
+		if r != 0 {
+			return 0, errors.WithStack(syscall.Errno(r))
+		}
+		break
+	}
+	if len(buf) < 4 {
+		return 0, errors.Errorf("no socket found for %v", remote)
+	}
+
+	numEntries := int(*(*uint32)(unsafe.Pointer(&buf[0])))
+	rowSize := int(unsafe.Sizeof(mibTCPRowOwnerPID{}))
+	for i := 0; i < numEntries; i++ {
+		offset := 4 + i*rowSize
+		if offset+rowSize > len(buf) {
+			break
+		}
+		row := (*mibTCPRowOwnerPID)(unsafe.Pointer(&buf[offset]))
+		// the peer's row sees our ends the other way around
+		if sameTCPAddr(row.LocalAddr, row.LocalPort, remote) && sameTCPAddr(row.RemoteAddr, row.RemotePort, local) {
+			return int(row.OwningPID), nil
+		}
+	}
+	return 0, errors.Errorf("no socket found for %v", remote)
+}
+
+// sameTCPAddr compares an address and port from the TCP table, both
+// in network byte order, to addr.
+func sameTCPAddr(rowAddr uint32, rowPort uint32, addr *net.TCPAddr) bool {
+	var ip [4]byte
+	binary.LittleEndian.PutUint32(ip[:], rowAddr)
+	port := int((rowPort&0xff)<<8 | (rowPort>>8)&0xff)
+	return net.IP(ip[:]).Equal(addr.IP) && port == addr.Port
+}
+
+func processExecutable(pid int) (string, error) {
+	handle, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer windows.CloseHandle(handle)
+
+	buf := make([]uint16, windows.MAX_LONG_PATH)
+	size := uint32(len(buf))
+	r, _, err := procQueryFullProcessImageNameW.Call(
+		uintptr(handle),
+		0,
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(unsafe.Pointer(&size)),
+	)
+	if r == 0 {
+		return "", errors.WithStack(err)
+	}
+	return windows.UTF16ToString(buf[:size]), nil
+}
+
+func parentPID(pid int) (int, error) {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	defer windows.CloseHandle(snapshot)
+
+	var entry windows.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	err = windows.Process32First(snapshot, &entry)
+	for err == nil {
+		if entry.ProcessID == uint32(pid) {
+			return int(entry.ParentProcessID), nil
+		}
+		err = windows.Process32Next(snapshot, &entry)
+	}
+	return 0, errors.Errorf("process %d not found", pid)
+}
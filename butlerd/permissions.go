@@ -0,0 +1,91 @@
+package butlerd
+
+import (
+	"context"
+	"sync"
+)
+
+// downloadMethods queue, drive and manage downloads, on top
+// of what readOnlyMethods allow. Requests are read-only when they're
+// tagged `@readonly` in types.go, see readonly_methods.go.
+var downloadMethods = map[string]bool{
+	"Install.Queue":                 true,
+	"Install.QueueMany":             true,
+	"Install.Cancel":                true,
+	"Collections.InstallAll":        true,
+	"Collections.InstallAll.Cancel": true,
+	"Downloads.Queue":               true,
+	"Downloads.Prioritize":          true,
+	"Downloads.ClearFinished":       true,
+	"Downloads.DrainQueue":          true,
+	"Downloads.Drive":               true,
+	"Downloads.Drive.Cancel":        true,
+	"Downloads.SetBandwidth":        true,
+	"Downloads.SetThrottle":         true,
+	"Downloads.Pause":               true,
+	"Downloads.Resume":              true,
+	"Downloads.Retry":               true,
+	"Downloads.Discard":             true,
+}
+
+// MethodAllowed returns true if a connection with the given permission
+// level may call method. Methods that aren't listed need full access.
+func MethodAllowed(level PermissionLevel, method string) bool {
+	switch level {
+	case PermissionLevelFull:
+		return true
+	case PermissionLevelDownloadsOnly:
+		return readOnlyMethods[method] || downloadMethods[method]
+	case PermissionLevelReadOnly:
+		return readOnlyMethods[method]
+	}
+	return false
+}
+
+// permissionRank orders levels from least to most allowed
+func permissionRank(level PermissionLevel) int {
+	switch level {
+	case PermissionLevelReadOnly:
+		return 1
+	case PermissionLevelDownloadsOnly:
+		return 2
+	case PermissionLevelFull:
+		return 3
+	}
+	return 0
+}
+
+// connAccess is what a connection is currently allowed to call.
+// It changes when the client authenticates.
+type connAccess struct {
+	mu    sync.Mutex
+	level PermissionLevel
+}
+
+func (ca *connAccess) get() PermissionLevel {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	return ca.level
+}
+
+func (ca *connAccess) set(level PermissionLevel) {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	ca.level = level
+}
+
+type connAccessKey struct{}
+
+func withConnAccess(ctx context.Context, ca *connAccess) context.Context {
+	return context.WithValue(ctx, connAccessKey{}, ca)
+}
+
+// ConnPermissionLevel returns the permission level of the connection
+// ctx belongs to. Connections not made through the server (in tests,
+// for example) have full access.
+func ConnPermissionLevel(ctx context.Context) PermissionLevel {
+	if ca, ok := ctx.Value(connAccessKey{}).(*connAccess); ok {
+		return ca.get()
+	}
+	return PermissionLevelFull
+}
@@ -0,0 +1,87 @@
+package butlerd
+
+import (
+	"sync"
+	"time"
+)
+
+// RecentLog is a log message, as sent to a client
+type RecentLog struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+
+	// What logged the message: a request's method, or "router"
+	// for background tasks
+	Module string `json:"module,omitempty"`
+	// Context about the message, like the ID of the request it was
+	// logged for
+	Fields map[string]interface{} `json:"fields,omitempty"`
+}
+
+// LogRing keeps the last few log messages in memory
+type LogRing struct {
+	mu      sync.Mutex
+	entries []RecentLog
+	next    int
+	full    bool
+}
+
+// NewLogRing returns a LogRing that remembers up to size messages
+func NewLogRing(size int) *LogRing {
+	return &LogRing{
+		entries: make([]RecentLog, size),
+	}
+}
+
+// Add records a log message, possibly forgetting the oldest one
+func (lr *LogRing) Add(level string, msg string) {
+	lr.AddEntry(RecentLog{
+		Level:   level,
+		Message: msg,
+	})
+}
+
+// AddEntry is like Add, for messages with a module or fields.
+// The entry's time is set if it's zero.
+func (lr *LogRing) AddEntry(entry RecentLog) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+
+	lr.entries[lr.next] = entry
+	lr.next = (lr.next + 1) % len(lr.entries)
+	if lr.next == 0 {
+		lr.full = true
+	}
+}
+
+// Entries returns all remembered messages, oldest first
+func (lr *LogRing) Entries() []RecentLog {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+
+	var res []RecentLog
+	if lr.full {
+		res = append(res, lr.entries[lr.next:]...)
+	}
+	res = append(res, lr.entries[:lr.next]...)
+	return res
+}
+
+// Last returns the last n remembered messages (or fewer), oldest first
+func (lr *LogRing) Last(n int) []RecentLog {
+	res := lr.Entries()
+	if n < len(res) {
+		res = res[len(res)-n:]
+	}
+	return res
+}
+
+// RecentLogs holds the last messages logged by any request or background
+// task, so they can be included in diagnostics bundles, or dumped
+// with System.LogDump.
+var RecentLogs = NewLogRing(SystemLogDumpMaxLines)
@@ -14,6 +14,7 @@ import (
 	"github.com/itchio/butler/butlerd/jsonrpc2"
 	"github.com/itchio/butler/comm"
 	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/mansion/ratelimit"
 	itchio "github.com/itchio/go-itchio"
 	"github.com/itchio/headway/state"
 	"github.com/itchio/headway/tracker"
@@ -73,11 +74,16 @@ type Router struct {
 
 	backgroundTaskIDSeed BackgroundTaskID
 
+	conns     map[jsonrpc2.Conn]struct{}
+	connsLock sync.Mutex
+
 	globalConsumer *state.Consumer
 }
 
 func NewRouter(dbPool *sqlitex.Pool, getClient GetClientFunc, httpClient *http.Client, httpTransport *http.Transport) *Router {
 	backgroundContext, backgroundCancel := context.WithCancel(context.Background())
+	// background tasks yield to interactive requests when talking to the API
+	backgroundContext = ratelimit.WithClass(backgroundContext, ratelimit.ClassBackground)
 
 	return &Router{
 		Handlers:             make(map[string]RequestHandler),
@@ -95,6 +101,7 @@ func NewRouter(dbPool *sqlitex.Pool, getClient GetClientFunc, httpClient *http.C
 
 		inflightRequests:        make(map[jsonrpc2.ID]InFlightRequest),
 		inflightBackgroundTasks: make(map[BackgroundTaskID]InFlightBackgroundTask),
+		conns:                   make(map[jsonrpc2.Conn]struct{}),
 
 		Group:        &singleflight.Group{},
 		ShutdownChan: make(chan struct{}),
@@ -103,6 +110,11 @@ func NewRouter(dbPool *sqlitex.Pool, getClient GetClientFunc, httpClient *http.C
 
 		globalConsumer: &state.Consumer{
 			OnMessage: func(lvl string, msg string) {
+				RecentLogs.AddEntry(RecentLog{
+					Level:   lvl,
+					Message: msg,
+					Module:  "router",
+				})
 				comm.Logf("[router] [%s] %s", lvl, msg)
 			},
 		},
@@ -116,6 +128,13 @@ func (r *Router) Register(method string, rh RequestHandler) {
 	r.Handlers[method] = rh
 }
 
+// Shutdown shuts butlerd down gracefully, like Meta.Shutdown does:
+// once in-flight requests and background tasks are done, ShutdownChan
+// is closed.
+func (r *Router) Shutdown() {
+	r.initiateShutdown()
+}
+
 func (r *Router) initiateShutdown() {
 	r.initiateShutdownOnce.Do(func() {
 		r.Logf("Initiating graceful butlerd shutdown")
@@ -209,6 +228,8 @@ func (r *Router) HandleNotification(conn jsonrpc2.Conn, notif jsonrpc2.Notificat
 }
 
 func (r *Router) HandleRequest(conn jsonrpc2.Conn, req jsonrpc2.Request) (interface{}, error) {
+	r.trackConn(conn)
+
 	r.inflightLock.Lock()
 	r.onRequestStarted(req.ID, InFlightRequest{
 		DispatchedAt: time.Now().UTC(),
@@ -226,7 +247,11 @@ func (r *Router) HandleRequest(conn jsonrpc2.Conn, req jsonrpc2.Request) (interf
 	var res interface{}
 
 	consumer, cErr := NewStateConsumer(&NewStateConsumerParams{
-		Conn: conn,
+		Conn:   conn,
+		Module: req.Method,
+		Fields: map[string]interface{}{
+			"requestId": req.ID,
+		},
 	})
 	if cErr != nil {
 		return nil, cErr
@@ -258,9 +283,16 @@ func (r *Router) HandleRequest(conn jsonrpc2.Conn, req jsonrpc2.Request) (interf
 			Group:    r.Group,
 			Shutdown: r.initiateShutdown,
 
-			method: method,
+			method:    method,
+			requestID: req.ID,
 
 			QueueBackgroundTask: r.QueueBackgroundTask,
+			Broadcast:           r.Broadcast,
+		}
+
+		if level := ConnPermissionLevel(conn.Context()); !MethodAllowed(level, method) {
+			Connections.deny()
+			return errors.Wrapf(CodePermissionDenied, "%s isn't allowed for %s connections", method, level)
 		}
 
 		{
@@ -362,6 +394,49 @@ func (r *Router) HandleRequest(conn jsonrpc2.Conn, req jsonrpc2.Request) (interf
 	return nil, rpcErr
 }
 
+// HTTPTransport returns the transport shared by all requests
+func (r *Router) HTTPTransport() *http.Transport {
+	return r.httpTransport
+}
+
+// trackConn remembers conn until it's closed, so that
+// notifications can be broadcast to it.
+func (r *Router) trackConn(conn jsonrpc2.Conn) {
+	r.connsLock.Lock()
+	defer r.connsLock.Unlock()
+
+	if _, ok := r.conns[conn]; ok {
+		return
+	}
+	r.conns[conn] = struct{}{}
+
+	go func() {
+		<-conn.Context().Done()
+
+		r.connsLock.Lock()
+		defer r.connsLock.Unlock()
+		delete(r.conns, conn)
+	}()
+}
+
+// Broadcast sends a notification to every connected client, for
+// changes all of them should know about.
+func (r *Router) Broadcast(method string, params interface{}) {
+	r.connsLock.Lock()
+	var conns []jsonrpc2.Conn
+	for conn := range r.conns {
+		conns = append(conns, conn)
+	}
+	r.connsLock.Unlock()
+
+	for _, conn := range conns {
+		err := conn.Notify(method, params)
+		if err != nil {
+			r.Logf("Could not broadcast %s: %v", method, err)
+		}
+	}
+}
+
 func (r *Router) doBackgroundTask(id BackgroundTaskID, bt BackgroundTask) {
 	defer func() {
 		router := r
@@ -395,6 +470,7 @@ func (r *Router) doBackgroundTask(id BackgroundTaskID, bt BackgroundTask) {
 		method: "",
 
 		QueueBackgroundTask: r.QueueBackgroundTask,
+		Broadcast:           r.Broadcast,
 	}
 
 	err := func() (retErr error) {
@@ -430,6 +506,8 @@ type RequestContext struct {
 	Consumer            *state.Consumer
 	Client              GetClientFunc
 	QueueBackgroundTask func(bt BackgroundTask)
+	// Broadcast sends a notification to all connected clients
+	Broadcast func(method string, params interface{})
 
 	HTTPClient    *http.Client
 	HTTPTransport *http.Transport
@@ -444,8 +522,11 @@ type RequestContext struct {
 
 	notificationInterceptors map[string]NotificationInterceptor
 	tracker                  tracker.Tracker
+	// set by TransactionMiddleware, see requestTx
+	tx *requestTx
 
-	method string
+	method    string
+	requestID jsonrpc2.ID
 }
 
 type WithParamsFunc func() (interface{}, error)
@@ -456,6 +537,11 @@ func (rc *RequestContext) Call(method string, params interface{}, res interface{
 	return rc.Conn.Call(method, params, res)
 }
 
+// CallContext is like Call, but gives up on the response once ctx is done.
+func (rc *RequestContext) CallContext(ctx context.Context, method string, params interface{}, res interface{}) error {
+	return rc.Conn.CallContext(ctx, method, params, res)
+}
+
 func (rc *RequestContext) InterceptNotification(method string, interceptor NotificationInterceptor) {
 	if rc.notificationInterceptors == nil {
 		rc.notificationInterceptors = make(map[string]NotificationInterceptor)
@@ -535,6 +621,10 @@ func (rc *RequestContext) EndProgress() {
 }
 
 func (rc *RequestContext) GetConn() *sqlite.Conn {
+	if rc.tx != nil {
+		return rc.tx.conn
+	}
+
 	getCtx, cancel := context.WithTimeout(rc.Ctx, 3*time.Second)
 	defer cancel()
 	conn := rc.dbPool.Get(getCtx)
@@ -546,7 +636,36 @@ func (rc *RequestContext) GetConn() *sqlite.Conn {
 	return conn
 }
 
+// AfterCommit calls f once rc's transaction is committed, see
+// TransactionMiddleware, and never if it's rolled back. Outside of a
+// transaction, f is called right away. It's for things that must not
+// happen while the transaction holds the database, like waking up
+// other goroutines that will want to use it.
+func (rc *RequestContext) AfterCommit(f func()) {
+	if rc.tx != nil {
+		rc.tx.afterCommit = append(rc.tx.afterCommit, f)
+		return
+	}
+	f()
+}
+
+// OutsideTx returns a RequestContext that gets its connections from the
+// pool, even if rc's are a transaction's. It's what goroutines started
+// by a transactional handler must use.
+func (rc *RequestContext) OutsideTx() *RequestContext {
+	if rc.tx == nil {
+		return rc
+	}
+	res := *rc
+	res.tx = nil
+	return &res
+}
+
 func (rc *RequestContext) PutConn(conn *sqlite.Conn) {
+	if rc.tx != nil && rc.tx.conn == conn {
+		// TransactionMiddleware gives it back when the handler is done
+		return
+	}
 	rc.dbPool.Put(conn)
 }
 
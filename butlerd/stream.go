@@ -0,0 +1,106 @@
+package butlerd
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultStreamChunkSize is how many items each @@FetchChunkNotification
+// holds, unless the request asks for another size.
+const DefaultStreamChunkSize = 100
+
+// MaxStreamChunkSize is how many items a @@FetchChunkNotification
+// may hold at most, since each one is sent as a single message.
+const MaxStreamChunkSize = 1000
+
+// streamBufferSize is how many chunks may be waiting to be sent before
+// the query producing them is paused. Sending to a client that reads
+// slowly blocks, so this bounds how much a stream holds in memory.
+const streamBufferSize = 4
+
+// StreamPageFunc fetches up to limit items, starting at cursor, and
+// returns the cursor for the items after them, or an empty one if
+// there are none left.
+type StreamPageFunc func(cursor Cursor, limit int64) (items []interface{}, next Cursor, err error)
+
+// StreamResults sends what fetchPage returns as @@FetchChunkNotification,
+// page by page starting at cursor, until there's nothing left, or until
+// max items were sent, if max is positive. It returns once all chunks
+// were sent, along with the cursor for what wasn't sent, if anything.
+//
+// Pages are fetched while previous chunks are being sent, but only
+// a few chunks ahead: fetchPage isn't called again until there's room,
+// so it shouldn't hold on to a database connection between calls.
+func (rc *RequestContext) StreamResults(cursor Cursor, max int64, chunkSize int64, fetchPage StreamPageFunc) (*FetchStreamSummary, Cursor, error) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultStreamChunkSize
+	}
+
+	ctx, cancel := context.WithCancel(rc.Ctx)
+	defer cancel()
+
+	chunks := make(chan FetchChunkNotification, streamBufferSize)
+	sendDone := make(chan error, 1)
+	go func() {
+		for chunk := range chunks {
+			// cannot use autogenerated wrappers to avoid import cycles
+			err := rc.Notify("Fetch.Chunk", chunk)
+			if err != nil {
+				cancel()
+				sendDone <- errors.WithMessage(err, "while sending chunk")
+				return
+			}
+		}
+		sendDone <- nil
+	}()
+
+	summary := &FetchStreamSummary{}
+	err := func() error {
+		for {
+			limit := chunkSize
+			if max > 0 && max-summary.Items < limit {
+				limit = max - summary.Items
+			}
+			if limit <= 0 {
+				return nil
+			}
+
+			items, next, err := fetchPage(cursor, limit)
+			if err != nil {
+				return err
+			}
+
+			if len(items) > 0 {
+				chunk := FetchChunkNotification{
+					RequestID: rc.requestID,
+					Method:    rc.method,
+					Seq:       summary.Chunks,
+					Items:     items,
+				}
+				select {
+				case chunks <- chunk:
+				case <-ctx.Done():
+					return errors.WithStack(ctx.Err())
+				}
+				summary.Chunks++
+				summary.Items += int64(len(items))
+			}
+
+			cursor = next
+			if cursor == "" {
+				return nil
+			}
+		}
+	}()
+	close(chunks)
+
+	sendErr := <-sendDone
+	if sendErr != nil {
+		return nil, "", sendErr
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return summary, cursor, nil
+}
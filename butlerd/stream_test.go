@@ -0,0 +1,112 @@
+package butlerd
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_StreamResults(t *testing.T) {
+	assert := assert.New(t)
+
+	// numbers from 0 to 249, the cursor being the next one
+	var fetches int64
+	fetchPage := func(cursor Cursor, limit int64) ([]interface{}, Cursor, error) {
+		atomic.AddInt64(&fetches, 1)
+		start, _ := strconv.ParseInt(string(cursor), 10, 64)
+		var items []interface{}
+		for i := start; i < start+limit && i < 250; i++ {
+			items = append(items, i)
+		}
+		next := start + int64(len(items))
+		if next >= 250 {
+			return items, "", nil
+		}
+		return items, Cursor(strconv.FormatInt(next, 10)), nil
+	}
+
+	newContext := func(onChunk func(chunk FetchChunkNotification)) *RequestContext {
+		rc := &RequestContext{
+			Ctx:       context.Background(),
+			method:    "Fetch.Numbers",
+			requestID: 12,
+		}
+		rc.InterceptNotification("Fetch.Chunk", func(method string, params interface{}) error {
+			onChunk(params.(FetchChunkNotification))
+			return nil
+		})
+		return rc
+	}
+
+	{
+		var chunks []FetchChunkNotification
+		rc := newContext(func(chunk FetchChunkNotification) {
+			chunks = append(chunks, chunk)
+		})
+		summary, next, err := rc.StreamResults("", 0, 0, fetchPage)
+		assert.NoError(err)
+		assert.EqualValues("", next)
+		assert.EqualValues(&FetchStreamSummary{Chunks: 3, Items: 250}, summary)
+		if assert.Len(chunks, 3) {
+			for i, chunk := range chunks {
+				assert.EqualValues(i, chunk.Seq)
+				assert.EqualValues(12, chunk.RequestID)
+				assert.EqualValues("Fetch.Numbers", chunk.Method)
+			}
+			assert.Len(chunks[0].Items, DefaultStreamChunkSize)
+			assert.Len(chunks[2].Items, 50)
+			assert.EqualValues(249, chunks[2].Items[49])
+		}
+	}
+
+	{
+		var items []interface{}
+		rc := newContext(func(chunk FetchChunkNotification) {
+			items = append(items, chunk.Items...)
+		})
+		summary, next, err := rc.StreamResults("10", 25, 10, fetchPage)
+		assert.NoError(err)
+		assert.EqualValues(&FetchStreamSummary{Chunks: 3, Items: 25}, summary)
+		assert.EqualValues("35", next, "the rest can be fetched later")
+		if assert.Len(items, 25) {
+			assert.EqualValues(10, items[0])
+			assert.EqualValues(34, items[24])
+		}
+	}
+
+	{
+		// a client that doesn't read what's sent
+		release := make(chan struct{})
+		rc := newContext(func(chunk FetchChunkNotification) {
+			<-release
+		})
+		atomic.StoreInt64(&fetches, 0)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _, err := rc.StreamResults("", 0, 1, fetchPage)
+			assert.NoError(err)
+		}()
+
+		time.Sleep(100 * time.Millisecond)
+		// one chunk being sent, a full buffer, and one waiting for room
+		assert.EqualValues(streamBufferSize+2, atomic.LoadInt64(&fetches), "the query waits for the client")
+		close(release)
+		<-done
+		assert.EqualValues(250, atomic.LoadInt64(&fetches))
+	}
+
+	{
+		rc := newContext(func(chunk FetchChunkNotification) {})
+		rc.InterceptNotification("Fetch.Chunk", func(method string, params interface{}) error {
+			return errors.New("connection closed")
+		})
+		_, _, err := rc.StreamResults("", 0, 1, fetchPage)
+		assert.Error(err)
+	}
+}
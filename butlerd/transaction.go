@@ -0,0 +1,75 @@
+package butlerd
+
+import (
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/pkg/errors"
+)
+
+// requestTx is the transaction TransactionMiddleware opened for a request.
+// It only hangs off the RequestContext the handler is called with: a
+// sqlite connection can't be used concurrently, so goroutines the handler
+// starts must be given rc.OutsideTx(), which gets connections from the
+// pool, outside of the transaction.
+type requestTx struct {
+	conn        *sqlite.Conn
+	afterCommit []func()
+}
+
+// TransactionMiddleware runs h in a single `BEGIN IMMEDIATE` transaction,
+// so that all the database writes it does land together, or not at all.
+// It's what requests tagged `@transactional` in types.go are registered with.
+//
+// h is called with a copy of rc whose connections are all the transaction's.
+// Goroutines started by h must use OutsideTx to get their own connections:
+// they must not wait on h's writes, which only land once h returns.
+// The transaction is rolled back if h returns an error or panics,
+// and committed otherwise. Whatever h passed to RequestContext.AfterCommit
+// is only called once it's committed.
+func TransactionMiddleware(h RequestHandler) RequestHandler {
+	return func(rc *RequestContext) (res interface{}, err error) {
+		if rc.tx != nil {
+			// already in a transaction
+			return h(rc)
+		}
+
+		conn := rc.GetConn()
+		defer rc.PutConn(conn)
+
+		err = sqlitex.ExecTransient(conn, "BEGIN IMMEDIATE;", nil)
+		if err != nil {
+			return nil, errors.WithMessage(err, "while beginning transaction")
+		}
+
+		txrc := *rc
+		txrc.tx = &requestTx{conn: conn}
+		committed := false
+		defer func() {
+			afterCommit := txrc.tx.afterCommit
+			// whatever holds on to txrc goes through the pool from now on
+			txrc.tx = nil
+			if committed {
+				for _, f := range afterCommit {
+					f()
+				}
+				return
+			}
+			rbErr := sqlitex.ExecTransient(conn, "ROLLBACK;", nil)
+			if rbErr != nil {
+				rc.Consumer.Warnf("Could not roll back transaction: %v", rbErr)
+			}
+		}()
+
+		res, err = h(&txrc)
+		if err != nil {
+			return nil, err
+		}
+
+		err = sqlitex.ExecTransient(conn, "COMMIT;", nil)
+		if err != nil {
+			return nil, errors.WithMessage(err, "while committing transaction")
+		}
+		committed = true
+		return res, nil
+	}
+}
@@ -0,0 +1,143 @@
+package butlerd
+
+import (
+	"context"
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/itchio/headway/state"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TransactionMiddleware(t *testing.T) {
+	assert := assert.New(t)
+
+	pool, err := sqlitex.Open("file:transaction_test?mode=memory&cache=shared", 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	rc := &RequestContext{
+		Ctx:      context.Background(),
+		Consumer: &state.Consumer{},
+		dbPool:   pool,
+	}
+
+	rc.WithConn(func(conn *sqlite.Conn) {
+		if err := sqlitex.ExecTransient(conn, "CREATE TABLE things (name TEXT);", nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	insert := func(rc *RequestContext, name string) {
+		rc.WithConn(func(conn *sqlite.Conn) {
+			if err := sqlitex.Exec(conn, "INSERT INTO things (name) VALUES (?);", nil, name); err != nil {
+				panic(err)
+			}
+		})
+	}
+	names := func() []string {
+		var res []string
+		rc.WithConn(func(conn *sqlite.Conn) {
+			err := sqlitex.Exec(conn, "SELECT name FROM things ORDER BY name;", func(stmt *sqlite.Stmt) error {
+				res = append(res, stmt.ColumnText(0))
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+		return res
+	}
+
+	_, err = TransactionMiddleware(func(rc *RequestContext) (interface{}, error) {
+		insert(rc, "a")
+		insert(rc, "b")
+		return "ok", nil
+	})(rc)
+	assert.NoError(err)
+	assert.EqualValues([]string{"a", "b"}, names(), "writes are committed on success")
+
+	_, err = TransactionMiddleware(func(rc *RequestContext) (interface{}, error) {
+		insert(rc, "c")
+		return nil, errors.New("halfway through")
+	})(rc)
+	assert.Error(err)
+	assert.EqualValues([]string{"a", "b"}, names(), "writes are rolled back on error")
+
+	assert.Panics(func() {
+		TransactionMiddleware(func(rc *RequestContext) (interface{}, error) {
+			insert(rc, "d")
+			panic("halfway through")
+		})(rc)
+	})
+	assert.EqualValues([]string{"a", "b"}, names(), "writes are rolled back on panic")
+	assert.Nil(rc.tx)
+
+	_, err = TransactionMiddleware(func(rc *RequestContext) (interface{}, error) {
+		txConn := rc.GetConn()
+		defer rc.PutConn(txConn)
+		assert.True(txConn == rc.GetConn(), "same connection for the whole handler")
+
+		otherConn := make(chan *sqlite.Conn)
+		go func(rc *RequestContext) {
+			conn := rc.GetConn()
+			defer rc.PutConn(conn)
+			otherConn <- conn
+		}(rc.OutsideTx())
+		assert.False(txConn == <-otherConn, "goroutines get their own connection outside of it")
+		return nil, nil
+	})(rc)
+	assert.NoError(err)
+}
+
+func Test_AfterCommit(t *testing.T) {
+	assert := assert.New(t)
+
+	pool, err := sqlitex.Open("file:after_commit_test?mode=memory&cache=shared", 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	rc := &RequestContext{
+		Ctx:      context.Background(),
+		Consumer: &state.Consumer{},
+		dbPool:   pool,
+	}
+
+	var calls []string
+	record := func(name string) func() {
+		return func() {
+			calls = append(calls, name)
+		}
+	}
+
+	rc.AfterCommit(record("outside"))
+	assert.EqualValues([]string{"outside"}, calls, "called right away outside of transactions")
+
+	calls = nil
+	_, err = TransactionMiddleware(func(rc *RequestContext) (interface{}, error) {
+		rc.AfterCommit(record("first"))
+		_, err := TransactionMiddleware(func(rc *RequestContext) (interface{}, error) {
+			rc.AfterCommit(record("nested"))
+			return nil, nil
+		})(rc)
+		assert.NoError(err)
+		assert.Empty(calls, "not called before the commit")
+		return nil, nil
+	})(rc)
+	assert.NoError(err)
+	assert.EqualValues([]string{"first", "nested"}, calls, "called once committed")
+
+	calls = nil
+	_, err = TransactionMiddleware(func(rc *RequestContext) (interface{}, error) {
+		rc.AfterCommit(record("rolled back"))
+		return nil, errors.New("halfway through")
+	})(rc)
+	assert.Error(err)
+	assert.Empty(calls, "not called on rollback")
+}
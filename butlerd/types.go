@@ -5,6 +5,7 @@ import (
 
 	"github.com/itchio/hush"
 	"github.com/itchio/hush/manifest"
+	"github.com/itchio/ox"
 
 	validation "github.com/go-ozzo/ozzo-validation"
 	itchio "github.com/itchio/go-itchio"
@@ -12,19 +13,55 @@ import (
 
 // When using TCP transport, must be the first message sent
 //
+// If the daemon was started with an idle timeout, connections that
+// haven't sent a request in that long fail with `CodeAuthenticationExpired`
+// until they call this again.
+//
 // @name Meta.Authenticate
 // @category Utilities
 // @caller client
 type MetaAuthenticateParams struct {
 	Secret string `json:"secret"`
+
+	// Restrict this connection further than the permission level
+	// the daemon was started with. Asking for more than that fails.
+	//
+	// @optional
+	PermissionLevel PermissionLevel `json:"permissionLevel,omitempty"`
 }
 
 func (p MetaAuthenticateParams) Validate() error {
-	return nil
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.PermissionLevel, validation.In(PermissionLevelList...)),
+	)
 }
 
 type MetaAuthenticateResult struct {
 	OK bool `json:"ok"`
+
+	// What this connection is allowed to call
+	PermissionLevel PermissionLevel `json:"permissionLevel"`
+}
+
+// What a connection is allowed to call. The launching client picks
+// it when starting the daemon, with `--permission-level`.
+//
+// @category Utilities
+type PermissionLevel string
+
+const (
+	// Every request is allowed
+	PermissionLevelFull PermissionLevel = "full"
+	// Requests that queue and drive downloads, and read-only ones
+	PermissionLevelDownloadsOnly PermissionLevel = "downloads-only"
+	// Only requests that don't change anything: fetching, listing, searching
+	PermissionLevelReadOnly PermissionLevel = "read-only"
+)
+
+var PermissionLevelList = []interface{}{
+	PermissionLevelFull,
+	PermissionLevelDownloadsOnly,
+	PermissionLevelReadOnly,
 }
 
 // When called, defines the entire duration of the daemon's life.
@@ -42,6 +79,7 @@ type MetaAuthenticateResult struct {
 // @name Meta.Flow
 // @category Utilities
 // @caller client
+// @readonly
 type MetaFlowParams struct {
 }
 
@@ -89,6 +127,7 @@ type MetaFlowEstablishedNotification struct {
 // @category Utilities
 // @tags Offline
 // @caller client
+// @readonly
 type VersionGetParams struct{}
 
 type VersionGetResult struct {
@@ -103,6 +142,9 @@ func (p VersionGetParams) Validate() error {
 	return nil
 }
 
+// Sets the `network.simulateOffline` setting, kept for
+// compatibility, see @@SettingsSetParams.
+//
 // @name Network.SetSimulateOffline
 // @category Utilities
 // @caller client
@@ -118,6 +160,9 @@ func (p NetworkSetSimulateOfflineParams) Validate() error {
 
 type NetworkSetSimulateOfflineResult struct{}
 
+// Sets the `network.bandwidthLimit` setting, kept for
+// compatibility, see @@SettingsSetParams.
+//
 // @name Network.SetBandwidthThrottle
 // @category Utilities
 // @caller client
@@ -129,11 +174,152 @@ type NetworkSetBandwidthThrottleParams struct {
 }
 
 func (p NetworkSetBandwidthThrottleParams) Validate() error {
-	return nil
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Rate, validation.Min(0)),
+	)
 }
 
 type NetworkSetBandwidthThrottleResult struct{}
 
+//----------------------------------------------------------------------
+// Settings
+//----------------------------------------------------------------------
+
+// Retrieves a single daemon setting, see @@SettingsListParams.
+//
+// @name Settings.Get
+// @category Settings
+// @tags Offline
+// @caller client
+// @readonly
+type SettingsGetParams struct {
+	// Key of the setting, like `network.bandwidthLimit`
+	Key string `json:"key"`
+}
+
+func (p SettingsGetParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Key, validation.Required),
+	)
+}
+
+type SettingsGetResult struct {
+	Setting *Setting `json:"setting"`
+}
+
+// Lists all daemon settings, with their current values.
+//
+// @name Settings.List
+// @category Settings
+// @tags Offline
+// @caller client
+// @readonly
+type SettingsListParams struct{}
+
+func (p SettingsListParams) Validate() error {
+	return nil
+}
+
+type SettingsListResult struct {
+	// All settings, sorted by key
+	Settings []*Setting `json:"settings"`
+}
+
+// Changes a daemon setting. The value must have the same type as
+// the setting's default value, and is persisted unless the setting
+// is transient.
+//
+// If the value actually changed, @@SettingChangedNotification is
+// sent to all connected clients.
+//
+// @name Settings.Set
+// @category Settings
+// @tags Offline
+// @caller client
+type SettingsSetParams struct {
+	// Key of the setting, like `network.bandwidthLimit`
+	Key string `json:"key"`
+
+	// New value of the setting
+	Value interface{} `json:"value"`
+}
+
+func (p SettingsSetParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Key, validation.Required),
+		validation.Field(&p.Value, validation.NotNil),
+	)
+}
+
+type SettingsSetResult struct {
+	Setting *Setting `json:"setting"`
+}
+
+// Sent to all connected clients when a setting changes, whether
+// through @@SettingsSetParams or one of the older feature-specific
+// requests, like @@NetworkSetBandwidthThrottleParams.
+//
+// @category Settings
+type SettingChangedNotification struct {
+	Setting *Setting `json:"setting"`
+}
+
+// A daemon setting
+//
+// @category Settings
+type Setting struct {
+	// Key of the setting, like `network.bandwidthLimit`
+	Key string `json:"key"`
+
+	// Human-readable description of the setting
+	Description string `json:"description"`
+
+	// Current value of the setting
+	Value interface{} `json:"value"`
+
+	// Value of the setting when it was never set
+	Default interface{} `json:"default"`
+
+	// True if the current value is the default
+	IsDefault bool `json:"isDefault"`
+
+	// Transient settings are not persisted, and go back to
+	// their default value when the daemon restarts
+	Transient bool `json:"transient"`
+}
+
+// Test connectivity to itch.io services: DNS resolution and TCP
+// connection to the API and CDN, an HTTPS request to the API, and
+// a short download to measure speed.
+//
+// Meant to help diagnose connectivity issues, this never fails:
+// individual failures are listed in the result's errors.
+//
+// @name Network.Diagnostics
+// @category Utilities
+// @caller client
+// @readonly
+type NetworkDiagnosticsParams struct{}
+
+func (p NetworkDiagnosticsParams) Validate() error {
+	return nil
+}
+
+type NetworkDiagnosticsResult struct {
+	// True if both the API and CDN hostnames resolved
+	DNSResolved bool `json:"dnsResolved"`
+	// True if we could open a TCP connection to both the API and CDN
+	TCPConnected bool `json:"tcpConnected"`
+	// True if the API responded to an HTTPS request
+	APIReachable bool `json:"apiReachable"`
+	// True if the speed test download from the CDN succeeded
+	CDNReachable bool `json:"cdnReachable"`
+	// Download speed measured during the speed test, in KiB/s
+	MeasuredSpeedKBps int64 `json:"measuredSpeedKBps"`
+	// Human-readable descriptions of everything that failed
+	Errors []string `json:"errors"`
+}
+
 //----------------------------------------------------------------------
 // Profile
 //----------------------------------------------------------------------
@@ -143,6 +329,7 @@ type NetworkSetBandwidthThrottleResult struct{}
 // @name Profile.List
 // @category Profile
 // @caller client
+// @readonly
 type ProfileListParams struct {
 }
 
@@ -266,6 +453,8 @@ type ProfileRequestTOTPResult struct {
 }
 
 // Use saved login credentials to validate a profile.
+// When online, the stored user information (username, display
+// name, cover, etc.) is refreshed from the API.
 //
 // @name Profile.UseSavedLogin
 // @category Profile
@@ -291,6 +480,7 @@ type ProfileUseSavedLoginResult struct {
 // @name Profile.Forget
 // @category Profile
 // @caller client
+// @transactional
 type ProfileForgetParams struct {
 	ProfileID int64 `json:"profileId"`
 }
@@ -306,6 +496,62 @@ type ProfileForgetResult struct {
 	Success bool `json:"success"`
 }
 
+// Removes all local traces of a profile: like @@ProfileForgetParams,
+// but also erases its cached games, download keys, collections,
+// download history and profile data.
+//
+// Caves installed with that profile's credentials are kept, and
+// reassigned to no profile, unless `alsoUninstall` is set.
+//
+// Everything is erased in a single transaction: either it all
+// goes, or nothing does.
+//
+// @name Profile.DataErase
+// @category Profile
+// @caller client
+type ProfileDataEraseParams struct {
+	ProfileID int64 `json:"profileId"`
+
+	// If true, queue the uninstall of all caves installed with
+	// that profile's credentials.
+	// @optional
+	AlsoUninstall bool `json:"alsoUninstall"`
+}
+
+func (p ProfileDataEraseParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.ProfileID, validation.Required),
+	)
+}
+
+type ProfileDataEraseResult struct {
+	// True if the profile did exist (and was successfully erased)
+	Success bool `json:"success"`
+
+	// Number of records removed, by kind
+	Removed *ProfileDataEraseCounts `json:"removed"`
+
+	// Number of caves that were kept, but no longer belong to any profile
+	CavesReassigned int64 `json:"cavesReassigned"`
+
+	// Number of caves whose uninstall was queued
+	UninstallsQueued int64 `json:"uninstallsQueued"`
+}
+
+// How many rows of each kind were removed by @@ProfileDataEraseParams
+//
+// @category Profile
+type ProfileDataEraseCounts struct {
+	ProfileGames    int64 `json:"profileGames"`
+	Games           int64 `json:"games"`
+	DownloadKeys    int64 `json:"downloadKeys"`
+	Collections     int64 `json:"collections"`
+	CollectionGames int64 `json:"collectionGames"`
+	Downloads       int64 `json:"downloads"`
+	ProfileData     int64 `json:"profileData"`
+	FetchInfo       int64 `json:"fetchInfo"`
+}
+
 // Stores some data associated to a profile, by key.
 //
 // @name Profile.Data.Put
@@ -333,6 +579,7 @@ type ProfileDataPutResult struct {
 // @name Profile.Data.Get
 // @category Profile
 // @caller client
+// @readonly
 type ProfileDataGetParams struct {
 	ProfileID int64  `json:"profileId"`
 	Key       string `json:"key"`
@@ -360,6 +607,7 @@ type ProfileDataGetResult struct {
 // @name Search.Games
 // @category Search
 // @caller client
+// @readonly
 type SearchGamesParams struct {
 	ProfileID int64 `json:"profileId"`
 
@@ -382,6 +630,7 @@ type SearchGamesResult struct {
 // @name Search.Users
 // @category Search
 // @caller client
+// @readonly
 type SearchUsersParams struct {
 	ProfileID int64 `json:"profileId"`
 
@@ -408,6 +657,7 @@ type SearchUsersResult struct {
 // @name Fetch.Game
 // @category Fetch
 // @caller client
+// @readonly
 type FetchGameParams struct {
 	// Identifier of game to look for
 	GameID int64 `json:"gameId"`
@@ -463,6 +713,7 @@ type GameRecord struct {
 // @name Fetch.GameRecords
 // @category Fetch
 // @caller client
+// @readonly
 type FetchGameRecordsParams struct {
 	// Profile to use to fetch game
 	ProfileID int64 `json:"profileId"`
@@ -595,6 +846,7 @@ func (r *FetchGameRecordsResult) SetStale(stale bool) {
 // @name Fetch.DownloadKey
 // @category Fetch
 // @caller client
+// @readonly
 type FetchDownloadKeyParams struct {
 	DownloadKeyID int64 `json:"downloadKeyId"`
 
@@ -629,6 +881,7 @@ type FetchDownloadKeyResult struct {
 // @name Fetch.DownloadKeys
 // @category Fetch
 // @caller client
+// @readonly
 type FetchDownloadKeysParams struct {
 	ProfileID int64 `json:"profileId"`
 
@@ -679,6 +932,7 @@ type FetchDownloadKeysResult struct {
 // @name Fetch.GameUploads
 // @category Fetch
 // @caller client
+// @readonly
 type FetchGameUploadsParams struct {
 	// Identifier of the game whose uploads we should look for
 	GameID int64 `json:"gameId"`
@@ -720,6 +974,7 @@ func (r *FetchGameUploadsResult) SetStale(stale bool) {
 // @name Fetch.User
 // @category Fetch
 // @caller client
+// @readonly
 type FetchUserParams struct {
 	// Identifier of the user to look for
 	UserID int64 `json:"userId"`
@@ -763,6 +1018,7 @@ func (r *FetchUserResult) SetStale(stale bool) {
 // @name Fetch.Sale
 // @category Fetch
 // @caller client
+// @readonly
 type FetchSaleParams struct {
 	// Identifier of the game for which to look for a sale
 	GameID int64 `json:"gameId"`
@@ -785,6 +1041,7 @@ type FetchSaleResult struct {
 // @name Fetch.Collection
 // @category Fetch
 // @caller client
+// @readonly
 type FetchCollectionParams struct {
 	// Profile to use to fetch collection
 	ProfileID int64 `json:"profileId"`
@@ -829,6 +1086,7 @@ func (r *FetchCollectionResult) SetStale(stale bool) {
 // @name Fetch.Collection.Games
 // @category Fetch
 // @caller client
+// @readonly
 type FetchCollectionGamesParams struct {
 	// Profile to use to fetch collection
 	ProfileID int64 `json:"profileId"`
@@ -923,6 +1181,7 @@ func (r *FetchCollectionGamesResult) SetStale(stale bool) {
 // @name Fetch.ProfileCollections
 // @category Fetch
 // @caller client
+// @readonly
 type FetchProfileCollectionsParams struct {
 	// Profile for which to fetch collections
 	ProfileID int64 `json:"profileId"`
@@ -990,6 +1249,7 @@ func (r *FetchProfileCollectionsResult) SetStale(stale bool) {
 // @name Fetch.ProfileGames
 // @category Fetch
 // @caller client
+// @readonly
 type FetchProfileGamesParams struct {
 	// Profile for which to fetch games
 	ProfileID int64 `json:"profileId"`
@@ -1088,6 +1348,7 @@ func (r *FetchProfileGamesResult) SetStale(stale bool) {
 // @name Fetch.ProfileOwnedKeys
 // @category Fetch
 // @caller client
+// @readonly
 type FetchProfileOwnedKeysParams struct {
 	// Profile to use to fetch game
 	ProfileID int64 `json:"profileId"`
@@ -1175,6 +1436,7 @@ func (r *FetchProfileOwnedKeysResult) SetStale(stale bool) {
 // @name Fetch.Commons
 // @category Fetch
 // @caller client
+// @readonly
 type FetchCommonsParams struct{}
 
 func (p FetchCommonsParams) Validate() error {
@@ -1252,6 +1514,82 @@ type CaveInstallInfo struct {
 	InstallFolder string `json:"installFolder"`
 	// If true, this cave is ignored while checking for updates
 	Pinned bool `json:"pinned,omitempty"`
+	// If true, this cave's game isn't on itch.io anymore, see
+	// @@GameDelistedNotification
+	GameDelisted bool `json:"gameDelisted,omitempty"`
+	// If true, this cave was installed with `skipPrereqs`, and its
+	// prerequisites haven't been installed since, so launching it
+	// doesn't install them either. See @@InstallPrereqsParams
+	// @optional
+	PrereqsSkipped bool `json:"prereqsSkipped,omitempty"`
+	// If true, this cave was installed with `ignoreInstallers`, and
+	// is treated as an archive or a naked file until it's installed
+	// again with `ignoreInstallers` set to false
+	// @optional
+	IgnoreInstallers bool `json:"ignoreInstallers,omitempty"`
+	// ID of the profile whose credentials were used to install this cave,
+	// if known
+	// @optional
+	SourceProfileID int64 `json:"sourceProfileId,omitempty"`
+	// Whether updates to this cave are installed automatically,
+	// see @@CavesSetAutoUpdatePolicyParams
+	AutoUpdatePolicy CaveAutoUpdatePolicy `json:"autoUpdatePolicy"`
+	// What the game needs to run in, as declared in its manifest's
+	// prereqs. Empty if it runs natively.
+	// @optional
+	VirtualMachineRequired VirtualMachineType `json:"virtualMachineRequired,omitempty"`
+	// How many bytes of the cave are in each install location, if it was
+	// split across several, see @@InstallQueueParams
+	// @optional
+	LocationUsage []*CaveLocationUsage `json:"locationUsage,omitempty"`
+	// Launch target used without asking, see @@CavesSetLaunchTargetParams
+	// @optional
+	PreferredLaunchTarget string `json:"preferredLaunchTarget,omitempty"`
+	// ID of the cave this one is DLC for, see @@CavesGetDLCsParams
+	// @optional
+	DLCParentCaveID string `json:"dlcParentCaveId,omitempty"`
+	// If true, only the files the game needs to start are installed yet,
+	// the rest still is, see `playEarly` in @@InstallQueueParams. Missing
+	// optional files aren't made up: the game behaves as it would
+	// without them.
+	// @optional
+	PlayableEarly bool `json:"playableEarly,omitempty"`
+	// How many bytes are left to install, if playableEarly is set
+	// @optional
+	RemainingBytes int64 `json:"remainingBytes,omitempty"`
+}
+
+// How much of a cave is stored in an install location
+//
+// @category Install
+type CaveLocationUsage struct {
+	// ID of the install location
+	InstallLocationID string `json:"installLocationId"`
+	// Bytes of the cave stored there
+	Bytes int64 `json:"bytes"`
+}
+
+// Something a game runs in, rather than natively.
+//
+// Games ask for one by listing it in the `[[prereqs]]` of their
+// `.itch.toml` manifest.
+//
+// @category Launch
+type VirtualMachineType string
+
+const (
+	// DOSBox, for DOS games
+	VirtualMachineTypeDOSBox VirtualMachineType = "dosbox"
+	// Wine, for Windows games on Linux or macOS
+	VirtualMachineTypeWine VirtualMachineType = "wine"
+	// ScummVM, for point-and-click adventures
+	VirtualMachineTypeScummVM VirtualMachineType = "scummvm"
+)
+
+var VirtualMachineTypeList = []interface{}{
+	VirtualMachineTypeDOSBox,
+	VirtualMachineTypeWine,
+	VirtualMachineTypeScummVM,
 }
 
 type InstallLocationSummary struct {
@@ -1259,8 +1597,45 @@ type InstallLocationSummary struct {
 	ID string `json:"id"`
 	// Absolute path on disk for this install location
 	Path string `json:"path"`
+	// Human-friendly name, by default the name of the folder
+	Label string `json:"label"`
 	// Information about the size used and available at this install location
 	SizeInfo *InstallLocationSizeInfo `json:"sizeInfo,omitempty"`
+	// Who can access the install folders of caves in this location.
+	// Empty if butler doesn't manage permissions for this location.
+	AccessMode InstallLocationAccessMode `json:"accessMode,omitempty"`
+	// Absolute path of the folder staging folders of downloads to this
+	// location go in. Empty if they're kept inside the location itself.
+	StagingPath string `json:"stagingPath,omitempty"`
+	// True if the location is on a solid-state drive. False for
+	// spinning disks, and when butler couldn't tell.
+	SSD bool `json:"ssd"`
+	// Template install folder names of new caves in this location are
+	// made from, see @@InstallLocationsAddParams. Empty if they're made
+	// from the game's slug.
+	FolderNameTemplate string `json:"folderNameTemplate,omitempty"`
+}
+
+// Controls the permissions butler sets on the install folder of each
+// cave in an install location, for when several OS users share it.
+// Ignored on Windows.
+//
+// @category Install
+type InstallLocationAccessMode string
+
+const (
+	// Only the user running butler can access install folders (0700)
+	InstallLocationAccessModePrivate InstallLocationAccessMode = "private"
+	// Members of the folder's group can read and run games (0750)
+	InstallLocationAccessModeGroup InstallLocationAccessMode = "group"
+	// Everyone can read and run games (0755)
+	InstallLocationAccessModePublic InstallLocationAccessMode = "public"
+)
+
+var InstallLocationAccessModeList = []interface{}{
+	InstallLocationAccessModePrivate,
+	InstallLocationAccessModeGroup,
+	InstallLocationAccessModePublic,
 }
 
 type InstallLocationSizeInfo struct {
@@ -1274,11 +1649,14 @@ type InstallLocationSizeInfo struct {
 	TotalSize int64 `json:"totalSize"`
 }
 
-// Retrieve info for all caves.
+// Retrieve info for all caves, along with their game, upload and
+// build. Those are loaded in one query per table, however many
+// caves there are.
 //
 // @name Fetch.Caves
 // @category Fetch
 // @caller client
+// @readonly
 type FetchCavesParams struct {
 	// Maximum number of caves to return at a time.
 	// @optional
@@ -1288,6 +1666,10 @@ type FetchCavesParams struct {
 	// @optional
 	Search string `json:"search"`
 
+	// One of "installedAt" (default), which lists the most recently
+	// installed (or updated) caves first, "lastTouched", "title",
+	// "playTime" or "installedSize".
+	//
 	// @optional
 	SortBy string `json:"sortBy"`
 
@@ -1301,6 +1683,20 @@ type FetchCavesParams struct {
 	// Used for pagination, if specified
 	// @optional
 	Cursor Cursor `json:"cursor"`
+
+	// If true, caves are sent as @@FetchChunkNotification, as they're
+	// read from the database, and the result holds none, only totals and
+	// the cursor. `limit` is then how many caves are sent in all, or 0 for
+	// all of them.
+	//
+	// @optional
+	Stream bool `json:"stream"`
+
+	// How many caves each @@FetchChunkNotification holds when streaming,
+	// defaults to 100, at most 1000.
+	//
+	// @optional
+	ChunkSize int64 `json:"chunkSize"`
 }
 
 type CavesFilters struct {
@@ -1312,11 +1708,29 @@ type CavesFilters struct {
 
 	// @optional
 	InstallLocationID string `json:"installLocationId"`
+
+	// Only show caves that were installed or updated in the
+	// last N days
+	// @optional
+	UpdatedWithinDays int64 `json:"updatedWithinDays"`
+
+	// Only show caves that were installed once and never updated
+	// since. Caves installed by older versions of butler are never
+	// listed, since we don't know.
+	// @optional
+	NeverUpdated bool `json:"neverUpdated"`
+
+	// Only show caves whose game needs to run in this,
+	// see @@CaveInstallInfo
+	// @optional
+	VirtualMachineRequired VirtualMachineType `json:"virtualMachineRequired"`
 }
 
 func (p CavesFilters) Validate() error {
 	return validation.ValidateStruct(&p,
 		validation.Field(&p.Classification, validation.In(GameClassificationList...)),
+		validation.Field(&p.UpdatedWithinDays, validation.Min(0)),
+		validation.Field(&p.VirtualMachineRequired, validation.In(VirtualMachineTypeList...)),
 	)
 }
 
@@ -1324,6 +1738,7 @@ func (p FetchCavesParams) Validate() error {
 	return validation.ValidateStruct(&p,
 		validation.Field(&p.Filters),
 		validation.Field(&p.SortBy, validation.In("lastTouched", "playTime", "title", "installedSize", "installedAt")),
+		validation.Field(&p.ChunkSize, validation.Min(0), validation.Max(MaxStreamChunkSize)),
 	)
 }
 
@@ -1341,6 +1756,38 @@ type FetchCavesResult struct {
 	// Use to fetch the next 'page' of results
 	// @optional
 	NextCursor Cursor `json:"nextCursor,omitempty"`
+
+	// Set when the caves were streamed
+	// @optional
+	Stream *FetchStreamSummary `json:"stream,omitempty"`
+}
+
+// Sent for requests that stream their results, like @@FetchCavesParams
+// with `stream` set, before their result. Each chunk holds the next
+// items, in order. Clients may get them out of order, and should use
+// `seq` to put them back in order.
+//
+// @name Fetch.Chunk
+// @category Fetch
+type FetchChunkNotification struct {
+	// ID of the JSON-RPC request the items are for
+	RequestID int64 `json:"requestId"`
+	// Method of that request, like `Fetch.Caves`
+	Method string `json:"method"`
+	// Position of this chunk in the stream, starting at 0
+	Seq int64 `json:"seq"`
+	// Items, of the same type as the request's result would hold
+	Items []interface{} `json:"items"`
+}
+
+// What was sent, once a streamed request is done
+//
+// @category Fetch
+type FetchStreamSummary struct {
+	// Number of @@FetchChunkNotification sent
+	Chunks int64 `json:"chunks"`
+	// Number of items they held in all
+	Items int64 `json:"items"`
 }
 
 // Retrieve info on a cave by ID.
@@ -1348,6 +1795,7 @@ type FetchCavesResult struct {
 // @name Fetch.Cave
 // @category Fetch
 // @caller client
+// @readonly
 type FetchCaveParams struct {
 	CaveID string `json:"caveId"`
 }
@@ -1384,6 +1832,7 @@ type FetchExpireAllResult struct{}
 // @name Game.FindUploads
 // @category Install
 // @caller client
+// @readonly
 type GameFindUploadsParams struct {
 	// Which game to find uploads for
 	Game *itchio.Game `json:"game"`
@@ -1400,6 +1849,86 @@ type GameFindUploadsResult struct {
 	Uploads []*itchio.Upload `json:"uploads"`
 }
 
+// Explains how butler would pick an upload for a given game, without
+// installing anything: which uploads were excluded and why, and how
+// the remaining ones were ranked.
+//
+// @name Install.ExplainUploadChoice
+// @category Install
+// @caller client
+// @readonly
+type InstallExplainUploadChoiceParams struct {
+	// Which game to explain the upload choice for
+	Game *itchio.Game `json:"game"`
+}
+
+func (p InstallExplainUploadChoiceParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Game, validation.Required),
+	)
+}
+
+type InstallExplainUploadChoiceResult struct {
+	// All uploads of the game: compatible ones first (best first),
+	// then the ones that were excluded.
+	Candidates []*UploadCandidate `json:"candidates"`
+
+	// The upload that would be picked automatically, if any
+	// @optional
+	AutoSelected *itchio.Upload `json:"autoSelected,omitempty"`
+
+	// True if there is more than one compatible upload, in which case
+	// @@InstallQueueParams would send @@PickUploadParams.
+	NeedsPick bool `json:"needsPick"`
+}
+
+// How an upload fared during automatic upload selection
+//
+// @category Install
+type UploadCandidate struct {
+	Upload *itchio.Upload `json:"upload"`
+
+	// True if the upload made it through all filters
+	Compatible bool `json:"compatible"`
+
+	// If not compatible, which filter excluded it
+	// @optional
+	ExcludedBy UploadExclusion `json:"excludedBy,omitempty"`
+
+	// If compatible, score used to rank the upload. Higher is better.
+	// @optional
+	Score int64 `json:"score,omitempty"`
+
+	// If compatible, human-readable breakdown of the score
+	// @optional
+	ScoreReasons []string `json:"scoreReasons,omitempty"`
+}
+
+// Why an upload was left out when looking for compatible ones
+//
+// @category Install
+type UploadRejection struct {
+	Upload *itchio.Upload `json:"upload"`
+
+	// Which filter excluded it
+	Reason UploadExclusion `json:"reason"`
+}
+
+// @category Install
+type UploadExclusion string
+
+const (
+	// The upload is an executable that doesn't run on this platform
+	UploadExclusionPlatform UploadExclusion = "platform"
+	// The upload is in a format butler can't install (.deb, .rpm, etc.)
+	UploadExclusionFormat UploadExclusion = "format"
+	// A better-suited architecture is available for this platform
+	UploadExclusionArch UploadExclusion = "arch"
+	// The upload isn't in the channel that was asked for,
+	// see @@InstallQueueParams
+	UploadExclusionChannel UploadExclusion = "channel"
+)
+
 //----------------------------------------------------------------------
 // Install
 //----------------------------------------------------------------------
@@ -1452,10 +1981,31 @@ type InstallQueueParams struct {
 	// @optional
 	Build *itchio.Build `json:"build"`
 
+	// ID of a build of the upload to install, instead of its latest one.
+	// Takes precedence over build. The call fails with
+	// `CodeBuildNotFound` if the upload has no such build.
+	//
+	// The cave is pinned to that build, so update checks skip it
+	// until it's unpinned, see @@CavesSetPinnedParams.
+	// @optional
+	PreferredBuildID int64 `json:"preferredBuildId,omitempty"`
+
 	// If true, do not run windows installers, just extract
 	// whatever to the install folder.
+	//
+	// The cave remembers it, see `ignoreInstallers` in
+	// @@CaveInstallInfo: reinstalls and updates of that cave
+	// do the same as the last install when this isn't set.
+	// Set it to false to run installers again.
 	// @optional
-	IgnoreInstallers bool `json:"ignoreInstallers,omitempty"`
+	IgnoreInstallers *bool `json:"ignoreInstallers,omitempty"`
+
+	// If true, the game's prerequisites (redistributables, etc.) aren't
+	// installed when it's first launched. The cave remembers it, see
+	// `prereqsSkipped` in @@CaveInstallInfo, until they're installed
+	// with @@InstallPrereqsParams or a launch with `forcePrereqs`.
+	// @optional
+	SkipPrereqs bool `json:"skipPrereqs,omitempty"`
 
 	// A folder that butler can use to store temporary files, like
 	// partial downloads, checkpoint files, etc.
@@ -1471,10 +2021,190 @@ type InstallQueueParams struct {
 	// Don't run install prepare (assume we can just run it at perform time)
 	// @optional
 	FastQueue bool `json:"fastQueue"`
+
+	// ID of the profile whose credentials should be used. If unspecified,
+	// butler picks one, see @@AccessExplanation.
+	// @optional
+	ProfileID int64 `json:"profileId"`
+
+	// If the game isn't released yet (the call then fails with
+	// `CodeGameNotYetReleased`), keep checking in the background,
+	// and send @@GameReleasedNotification once it's out.
+	// @optional
+	NotifyOnRelease bool `json:"notifyOnRelease"`
+
+	// Path of an archive (zip, tar, 7z, etc.) on disk to install
+	// from, instead of downloading anything. itch.io is never contacted:
+	// the game is used as-is, or made up from the archive's name if
+	// unspecified, and the upload always describes the archive, with
+	// `local` as its storage.
+	//
+	// Made-up games and uploads have negative IDs. The cave is pinned,
+	// since there's nothing to update it from.
+	// @optional
+	LocalArchivePath string `json:"localArchivePath,omitempty"`
+
+	// ID of a second install location for large files that don't fit
+	// in the first one. Files of 8 MiB or more (except executables) are
+	// stored there, and symlinked into the install folder: archives are
+	// extracted straight there, and later upgrades and heals work on them
+	// where they are. Each location is checked for free space for its own
+	// share. If the install folder's filesystem can't hold symlinks,
+	// everything stays in one location, with a warning.
+	//
+	// If unspecified and caveId is specified, the cave keeps using its
+	// overflow location, if any.
+	// @optional
+	OverflowLocationID string `json:"overflowLocationId,omitempty"`
+
+	// How many names to try for a fresh cave's install folder, if the
+	// first one is taken, before failing with `CodeInstallFolderExhausted`.
+	// Defaults to 200.
+	// @optional
+	UniqueFolderMaxTries int64 `json:"uniqueFolderMaxTries,omitempty"`
+
+	// How to settle on an upload when several are compatible, and
+	// the client can't answer @@PickUploadParams or didn't in time.
+	// If unset, the client is asked and waited for.
+	// @optional
+	DefaultUploadStrategy DefaultUploadStrategy `json:"defaultUploadStrategy,omitempty"`
+
+	// How many seconds to wait for the client to answer
+	// @@PickUploadParams before using DefaultUploadStrategy.
+	// If unset, waits forever.
+	// @optional
+	PickUploadTimeoutSeconds int64 `json:"pickUploadTimeoutSeconds,omitempty"`
+
+	// If true, installs are queued even if the install location
+	// doesn't seem to have enough free space, instead of failing
+	// with `CodeNotEnoughSpace`. Fresh installs are checked against
+	// what itch.io says about the upload, then all installs are
+	// checked again once the upload has been looked into, unless
+	// `fastQueue` is set.
+	// @optional
+	IgnoreDiskSpace bool `json:"ignoreDiskSpace,omitempty"`
+
+	// If true, and a fresh install doesn't fit in the install location,
+	// other install locations are tried, most free space first.
+	// The one actually used is the result's `installLocationId`.
+	// Resumed installs stay where they were queued first.
+	// @optional
+	AllowFallbackLocation bool `json:"allowFallbackLocation,omitempty"`
+
+	// If true, nothing is queued: the upload and build are settled on,
+	// and sizes estimated, like for a real install, but no staging folder
+	// is kept and nothing is saved. See the result's `estimatedInstallSize`
+	// and `estimatedDownloadSize`. Can't be combined with queueDownload.
+	// @optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// When no upload is specified, only consider compatible uploads
+	// in that wharf channel (exact match). If exactly one is, it's
+	// picked without asking @@PickUploadParams. If none is, all
+	// compatible uploads are considered, with a warning.
+	// @optional
+	ChannelName string `json:"channelName,omitempty"`
+
+	// If true, and no compatible upload is in channelName, fail with
+	// `CodeNoCompatibleUploads` instead of considering the others.
+	// @optional
+	ChannelRequired bool `json:"channelRequired,omitempty"`
+
+	// Name of the install folder of the new cave, instead of one made
+	// from the game's slug or the install location's folder name template.
+	// It's sanitized like those are, and made unique if taken: the name
+	// actually used is the result's `installFolderName`. Can't be combined
+	// with caveId or noCave.
+	// @optional
+	InstallFolderName string `json:"installFolderName,omitempty"`
+
+	// Staging folder of an install that was queued before, but never
+	// finished (if butler exited while it was being performed, say).
+	// If it still has what it needs, the install is queued in it again,
+	// with the same ID, so whatever was downloaded already is kept.
+	// Otherwise, a fresh staging folder is used, with a warning.
+	//
+	// Game and upload, if specified, must be the ones the staging folder
+	// was used for, and caveId too, or the call fails with
+	// `CodeStagingFolderMismatch`. If unspecified, they're taken from the
+	// staging folder. Can't be combined with noCave or dryRun.
+	//
+	// Without it, if both game and upload are specified, staging folders
+	// are named after them and the install location, so queuing the same
+	// install again after butler exited resumes it too, as long as it
+	// wasn't queued for download with @@DownloadsQueueParams.
+	// @optional
+	ResumeStagingFolder string `json:"resumeStagingFolder,omitempty"`
+
+	// If true, a new install is queued even if there's already a
+	// download in progress for the same cave (or, without caveId, for
+	// a fresh install of the same game), with the same upload and build
+	// if specified. Otherwise, that download is returned, with
+	// `alreadyQueued` set, and nothing new is queued.
+	// @optional
+	Force bool `json:"force,omitempty"`
+
+	// ID of the cave the new cave is DLC for. If installLocationId is
+	// unspecified, the parent cave's is used. Can't be combined with
+	// caveId or noCave. See @@CavesGetDLCsParams
+	// @optional
+	ParentCaveID string `json:"parentCaveId,omitempty"`
+
+	// If true, the new cave is installed in a folder inside of the parent
+	// cave's install folder, named after the upload unless installFolderName
+	// is specified. Needs parentCaveId, and can't be combined with
+	// another installLocationId or overflowLocationId. Uninstalling
+	// the parent cave uninstalls it first.
+	// @optional
+	UseParentFolder bool `json:"useParentFolder,omitempty"`
+
+	// If true, and the game's app manifest (`.itch.toml`) lists optional
+	// files, the new cave is saved as soon as the files its game needs
+	// are installed, with `playableEarly` set in @@CaveInstallInfo. It can
+	// be launched then, with a warning, while the rest keeps installing,
+	// slower while the game runs. Only zip archives can be played early,
+	// others are installed whole. Ignored if caveId is specified.
+	// @optional
+	PlayEarly bool `json:"playEarly,omitempty"`
 }
 
 func (p InstallQueueParams) Validate() error {
-	return nil
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.UniqueFolderMaxTries, validation.Min(0)),
+		validation.Field(&p.DefaultUploadStrategy, validation.In(DefaultUploadStrategyList...)),
+		validation.Field(&p.PickUploadTimeoutSeconds, validation.Min(0)),
+	)
+}
+
+// How @@InstallQueueParams settles on an upload when several
+// are compatible and the client doesn't pick one.
+//
+// @category Install
+type DefaultUploadStrategy string
+
+const (
+	// Fail with `CodeOperationAborted`
+	DefaultUploadStrategyAbort DefaultUploadStrategy = "abort"
+	// Pick the first compatible upload
+	DefaultUploadStrategyFirst DefaultUploadStrategy = "first"
+	// Pick the first compatible upload that has builds (see @@Build),
+	// or the first one if none do
+	DefaultUploadStrategyPreferWharf DefaultUploadStrategy = "prefer-wharf"
+	// Pick the largest compatible upload
+	DefaultUploadStrategyLargest DefaultUploadStrategy = "largest"
+	// Pick the smallest compatible upload
+	DefaultUploadStrategySmallest DefaultUploadStrategy = "smallest"
+	// Fail with `CodeAmbiguousUpload`, which lists the compatible uploads
+	DefaultUploadStrategyFail DefaultUploadStrategy = "fail"
+)
+
+var DefaultUploadStrategyList = []interface{}{
+	DefaultUploadStrategyAbort,
+	DefaultUploadStrategyFirst,
+	DefaultUploadStrategyPreferWharf,
+	DefaultUploadStrategyLargest,
+	DefaultUploadStrategySmallest,
+	DefaultUploadStrategyFail,
 }
 
 type InstallQueueResult struct {
@@ -1487,73 +2217,1311 @@ type InstallQueueResult struct {
 	InstallFolder     string         `json:"installFolder"`
 	StagingFolder     string         `json:"stagingFolder"`
 	InstallLocationID string         `json:"installLocationId"`
-}
 
-// For modal-first install
-//
-// @name Install.Plan
-// @category Install
+	// Name of the install folder within the install location,
+	// empty with noCave
+	// @optional
+	InstallFolderName string `json:"installFolderName,omitempty"`
+
+	// Which credentials will be used for the install, and why
+	// @optional
+	Access *AccessExplanation `json:"access,omitempty"`
+
+	// Space the install should take up. Measured by looking inside
+	// the upload when possible (not with fastQueue), guessed from what
+	// itch.io says about it otherwise. Zero if unknown, like for
+	// external uploads.
+	// @optional
+	EstimatedInstallSize int64 `json:"estimatedInstallSize,omitempty"`
+
+	// Bytes that will be downloaded: the patches for `patch`, the whole
+	// upload (or build archive) otherwise. Zero if unknown, like for
+	// `heal` and external uploads, or if nothing needs to be downloaded.
+	// @optional
+	EstimatedDownloadSize int64 `json:"estimatedDownloadSize,omitempty"`
+
+	// How the install will get the upload's files. Empty if
+	// unknown, with fastQueue or for external uploads.
+	// @optional
+	Strategy InstallStrategy `json:"strategy,omitempty"`
+
+	// True if this is a download that was already in progress,
+	// see `force` in @@InstallQueueParams
+	// @optional
+	AlreadyQueued bool `json:"alreadyQueued,omitempty"`
+}
+
+// How an install gets the upload's files, as reported
+// by @@InstallQueueResult
+//
+// @category Install
+type InstallStrategy string
+
+const (
+	// Download the whole upload, then extract or run it
+	InstallStrategyArchive InstallStrategy = "archive"
+	// Fix up the existing install from the build's signature,
+	// downloading only what's missing or changed
+	InstallStrategyHeal InstallStrategy = "heal"
+	// Apply patches to the installed build
+	InstallStrategyPatch InstallStrategy = "patch"
+)
+
+// Queues install operations for several games at once, like
+// @@InstallQueueParams would for each of them. Items that fail
+// don't stop the others from being queued.
+//
+// @name Install.QueueMany
+// @category Install
+// @caller client
+type InstallQueueManyParams struct {
+	// Games to queue installs for
+	Items []*InstallQueueManyItem `json:"items"`
+
+	// If true, ask the user to pick uploads for all the items that have
+	// several compatible ones at once, with @@PickUploadsParams.
+	// Otherwise, those items are left out with the `needsAttention` status.
+	// @optional
+	PickUploads bool `json:"pickUploads"`
+
+	// If true, also queue downloads for all the items that were
+	// queued, see @@InstallQueueParams.
+	// @optional
+	QueueDownload bool `json:"queueDownload"`
+}
+
+func (p InstallQueueManyParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Items, validation.Required),
+	)
+}
+
+// @category Install
+type InstallQueueManyItem struct {
+	// Game to install
+	Game *itchio.Game `json:"game"`
+
+	// If unspecified, will pick one of the compatible uploads
+	// @optional
+	Upload *itchio.Upload `json:"upload"`
+
+	// If unspecified, will use the latest build of the upload
+	// @optional
+	Build *itchio.Build `json:"build"`
+
+	// ID of the install location to install to
+	InstallLocationID string `json:"installLocationId"`
+}
+
+func (p InstallQueueManyItem) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Game, validation.Required),
+		validation.Field(&p.InstallLocationID, validation.Required),
+	)
+}
+
+type InstallQueueManyResult struct {
+	// One entry per item, in the same order
+	Items []*InstallQueueManyItemResult `json:"items"`
+}
+
+// @category Install
+type InstallQueueManyItemResult struct {
+	Game *itchio.Game `json:"game"`
+
+	Status InstallQueueManyStatus `json:"status"`
+
+	// Same as @@InstallQueueResult, if the item was queued
+	// @optional
+	Queued *InstallQueueResult `json:"queued,omitempty"`
+
+	// Compatible uploads to pick from, if the item needs attention.
+	// Queue it again with one of them.
+	// @optional
+	Uploads []*itchio.Upload `json:"uploads,omitempty"`
+
+	// Error message, if the item failed
+	// @optional
+	Error string `json:"error,omitempty"`
+	// butlerd error code, if the item failed with one
+	// @optional
+	ErrorCode int64 `json:"errorCode,omitempty"`
+}
+
+// @category Install
+type InstallQueueManyStatus string
+
+const (
+	// The install was queued
+	InstallQueueManyStatusQueued InstallQueueManyStatus = "queued"
+	// The game has several compatible uploads, and none was picked
+	InstallQueueManyStatusNeedsAttention InstallQueueManyStatus = "needsAttention"
+	// The install couldn't be queued, see error
+	InstallQueueManyStatusFailed InstallQueueManyStatus = "failed"
+)
+
+// Asks the user to pick uploads for several games at once, during
+// @@InstallQueueManyParams.
+//
+// @category Install
+// @tags Dialog
+// @caller server
+type PickUploadsParams struct {
+	// Games that have several compatible uploads
+	Items []*PickUploadsItem `json:"items"`
+}
+
+func (p PickUploadsParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Items, validation.Required),
+	)
+}
+
+// @category Install
+type PickUploadsItem struct {
+	Game *itchio.Game `json:"game"`
+	// Upload objects to choose from
+	Uploads []*itchio.Upload `json:"uploads"`
+}
+
+type PickUploadsResult struct {
+	// For each item, the index (in its uploads array) of the upload that
+	// was picked, or a negative value to skip that game. Missing entries
+	// are skipped too.
+	Indices []int64 `json:"indices"`
+}
+
+// Queues installs for all the games of a collection, as last fetched
+// with @@FetchCollectionGamesParams. Games that are already installed,
+// or being downloaded, are skipped, as are those with no compatible
+// uploads. Uploads are picked without asking, with `uploadStrategy`.
+//
+// The downloads queued share a batch ID, see @@DownloadsListParams.
+// The batch can be stopped with @@CollectionsInstallAllCancelParams
+// while games are still being queued.
+//
+// @name Collections.InstallAll
+// @category Install
+// @caller client
+type CollectionsInstallAllParams struct {
+	CollectionID int64 `json:"collectionId"`
+
+	// ID of the install location to install to
+	InstallLocationID string `json:"installLocationId"`
+
+	// Which games of the collection to install
+	// @optional
+	Filters CollectionsInstallAllFilters `json:"filters,omitempty"`
+
+	// How to pick between several compatible uploads. Defaults to
+	// `first`. With `abort`, those games are skipped.
+	// @optional
+	UploadStrategy DefaultUploadStrategy `json:"uploadStrategy,omitempty"`
+
+	// Most bytes all the queued downloads may add up to, as estimated
+	// before queuing them. Games that would go over are skipped. Zero
+	// means no limit.
+	// @optional
+	MaxTotalSize int64 `json:"maxTotalSize,omitempty"`
+
+	// Identifier for the batch, like a UUID, so that it can be passed
+	// to @@CollectionsInstallAllCancelParams before this call returns.
+	// Generated if unset. Fails if another batch with that ID is
+	// still queuing games.
+	// @optional
+	BatchID string `json:"batchId,omitempty"`
+}
+
+func (p CollectionsInstallAllParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CollectionID, validation.Required),
+		validation.Field(&p.InstallLocationID, validation.Required),
+		validation.Field(&p.Filters),
+		validation.Field(&p.UploadStrategy, validation.In(DefaultUploadStrategyList...)),
+		validation.Field(&p.MaxTotalSize, validation.Min(0)),
+	)
+}
+
+// @category Install
+type CollectionsInstallAllFilters struct {
+	// Only install games of that classification
+	// @optional
+	Classification itchio.GameClassification `json:"classification,omitempty"`
+
+	// Only install games whose title contains this
+	// @optional
+	Search string `json:"search,omitempty"`
+}
+
+func (p CollectionsInstallAllFilters) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Classification, validation.In(GameClassificationList...)),
+	)
+}
+
+type CollectionsInstallAllResult struct {
+	// Shared by all the downloads queued, see @@DownloadsListParams
+	BatchID string `json:"batchId"`
+
+	// One entry per game, in collection order
+	Games []*CollectionInstallOutcome `json:"games"`
+
+	// Estimated size of all the downloads queued
+	TotalSize int64 `json:"totalSize"`
+}
+
+// @category Install
+type CollectionInstallOutcome struct {
+	Game *itchio.Game `json:"game"`
+
+	Status CollectionInstallStatus `json:"status"`
+
+	// Why the game was skipped
+	// @optional
+	SkipReason CollectionInstallSkipReason `json:"skipReason,omitempty"`
+
+	// ID of the download, if the game was queued
+	// @optional
+	DownloadID string `json:"downloadId,omitempty"`
+
+	// Upload picked, if the game was queued
+	// @optional
+	Upload *itchio.Upload `json:"upload,omitempty"`
+
+	// Estimated download size, if the game was queued or went over budget
+	// @optional
+	EstimatedSize int64 `json:"estimatedSize,omitempty"`
+
+	// Error message, if the game failed
+	// @optional
+	Error string `json:"error,omitempty"`
+	// butlerd error code, if the game failed with one
+	// @optional
+	ErrorCode int64 `json:"errorCode,omitempty"`
+}
+
+// @category Install
+type CollectionInstallStatus string
+
+const (
+	// A download was queued for the game
+	CollectionInstallStatusQueued CollectionInstallStatus = "queued"
+	// The game wasn't queued, see skipReason
+	CollectionInstallStatusSkipped CollectionInstallStatus = "skipped"
+	// The game couldn't be queued, see error
+	CollectionInstallStatusFailed CollectionInstallStatus = "failed"
+	// The batch was cancelled before the game's turn came
+	CollectionInstallStatusCancelled CollectionInstallStatus = "cancelled"
+)
+
+// @category Install
+type CollectionInstallSkipReason string
+
+const (
+	// The game already has a cave
+	CollectionInstallSkipReasonInstalled CollectionInstallSkipReason = "installed"
+	// The game already has a download in progress
+	CollectionInstallSkipReasonDownloading CollectionInstallSkipReason = "downloading"
+	// None of the game's uploads can be installed here
+	CollectionInstallSkipReasonIncompatible CollectionInstallSkipReason = "incompatible"
+	// Several uploads were compatible, and `uploadStrategy` is `abort`
+	CollectionInstallSkipReasonNotPicked CollectionInstallSkipReason = "notPicked"
+	// Queuing the game would have gone over `maxTotalSize`
+	CollectionInstallSkipReasonOverBudget CollectionInstallSkipReason = "overBudget"
+)
+
+// Stops a @@CollectionsInstallAllParams batch from queuing more games.
+// Games not reached yet are reported as cancelled.
+//
+// @name Collections.InstallAll.Cancel
+// @category Install
+// @caller client
+type CollectionsInstallAllCancelParams struct {
+	BatchID string `json:"batchId"`
+
+	// Also discard the downloads of the batch that aren't being
+	// performed yet. Those that finished, or are in progress, are kept.
+	// @optional
+	DiscardPending bool `json:"discardPending,omitempty"`
+}
+
+func (p CollectionsInstallAllCancelParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.BatchID, validation.Required),
+	)
+}
+
+type CollectionsInstallAllCancelResult struct {
+	// True if the batch was still queuing games
+	DidCancel bool `json:"didCancel"`
+
+	// How many downloads were discarded
+	Discarded int64 `json:"discarded"`
+}
+
+// Sent after @@InstallQueueParams failed because a game wasn't
+// released yet and `notifyOnRelease` was set: the game can now
+// be installed. Sent on the connection that made the call.
+//
+// @category Install
+type GameReleasedNotification struct {
+	// The game that just came out, with fresh info
+	Game *itchio.Game `json:"game"`
+}
+
+// For modal-first install
+//
+// @name Install.Plan
+// @category Install
+// @caller client
+// @readonly
+type InstallPlanParams struct {
+	// The ID of the game we're planning to install
+	GameID int64 `json:"gameId"`
+
+	// The download session ID to use for this install plan
+	// @optional
+	DownloadSessionID string `json:"downloadSessionId"`
+
+	// @optional
+	UploadID int64 `json:"uploadId"`
+
+	// ID of a build of the upload to plan for, instead of its latest one,
+	// like @@InstallQueueParams' preferredBuildId.
+	// @optional
+	PreferredBuildID int64 `json:"preferredBuildId,omitempty"`
+
+	// ID of the install location the game would go to. If set, its free
+	// space is part of the plan.
+	// @optional
+	InstallLocationID string `json:"installLocationId,omitempty"`
+}
+
+func (p InstallPlanParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.GameID, validation.Required),
+	)
+}
+
+type InstallPlanResult struct {
+	Game    *itchio.Game     `json:"game"`
+	Uploads []*itchio.Upload `json:"uploads"`
+
+	Info *InstallPlanInfo `json:"info"`
+}
+
+type InstallPlanInfo struct {
+	Upload    *itchio.Upload `json:"upload"`
+	Build     *itchio.Build  `json:"build"`
+	Type      string         `json:"type"`
+	DiskUsage *DiskUsageInfo `json:"diskUsage"`
+
+	// Bytes that will be downloaded: the build's archive for wharf-enabled
+	// uploads, the upload itself otherwise. Zero if unknown.
+	DownloadSize int64 `json:"downloadSize"`
+	// Space the install should take up, guessed from what itch.io says
+	// about the upload and build, without looking inside. Zero if unknown.
+	// Set even if diskUsage can't be computed.
+	EstimatedSize int64 `json:"estimatedSize"`
+	// Free space at the install location given in @@InstallPlanParams,
+	// or a negative value if there's none or we can't find it
+	FreeSpace int64 `json:"freeSpace"`
+
+	Error        string `json:"error,omitempty"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
+	ErrorCode    int64  `json:"errorCode,omitempty"`
+}
+
+type DiskUsageInfo struct {
+	FinalDiskUsage  int64  `json:"finalDiskUsage"`
+	NeededFreeSpace int64  `json:"neededFreeSpace"`
+	Accuracy        string `json:"accuracy"`
+}
+
+// @name Caves.SetPinned
+// @category Install
+// @caller client
+type CavesSetPinnedParams struct {
+	// ID of the cave to pin/unpin
+	CaveID string `json:"caveId"`
+
+	// Pinned state the cave should have after this call
+	Pinned bool `json:"pinned"`
+}
+
+func (p CavesSetPinnedParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+	)
+}
+
+type CavesSetPinnedResult struct{}
+
+// Changes whether updates to a cave are installed by
+// @@CaveUpdateBatchParams without the user's involvement.
+//
+// @name Caves.SetAutoUpdatePolicy
+// @category Install
+// @caller client
+// @transactional
+type CavesSetAutoUpdatePolicyParams struct {
+	// ID of the cave to change
+	CaveID string `json:"caveId"`
+
+	// Policy the cave should have after this call
+	Policy CaveAutoUpdatePolicy `json:"policy"`
+}
+
+func (p CavesSetAutoUpdatePolicyParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+		validation.Field(&p.Policy, validation.Required, validation.In(CaveAutoUpdatePolicyList...)),
+	)
+}
+
+type CavesSetAutoUpdatePolicyResult struct{}
+
+// Controls what @@CaveUpdateBatchParams does when it finds
+// an update for a cave. Update checks aren't affected.
+//
+// @category Install
+type CaveAutoUpdatePolicy string
+
+const (
+	// Install updates as soon as they're found. This is the default.
+	CaveAutoUpdatePolicyAlways CaveAutoUpdatePolicy = "always"
+	// Only install updates when on a Wi-Fi or wired connection.
+	// Where butler can't tell the type of connection (anywhere but
+	// Linux for now), updates are skipped.
+	CaveAutoUpdatePolicyWifiOnly CaveAutoUpdatePolicy = "wifi-only"
+	// Never install updates automatically
+	CaveAutoUpdatePolicyNever CaveAutoUpdatePolicy = "never"
+	// Ask the user first, see @@UpdateAvailableAskUserParams
+	CaveAutoUpdatePolicyAsk CaveAutoUpdatePolicy = "ask"
+)
+
+var CaveAutoUpdatePolicyList = []interface{}{
+	CaveAutoUpdatePolicyAlways,
+	CaveAutoUpdatePolicyWifiOnly,
+	CaveAutoUpdatePolicyNever,
+	CaveAutoUpdatePolicyAsk,
+}
+
+// Lists the launch targets declared in the `[[actions]]` of a
+// cave's `.itch.toml` manifest, for all platforms. Caves without
+// a manifest have none.
+//
+// @name Caves.GetLaunchTargets
+// @category Install
+// @caller client
+// @readonly
+type CavesGetLaunchTargetsParams struct {
+	CaveID string `json:"caveId"`
+}
+
+func (p CavesGetLaunchTargetsParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+	)
+}
+
+type CavesGetLaunchTargetsResult struct {
+	// In the order of the manifest
+	Targets []*CaveLaunchTarget `json:"targets"`
+
+	// Name of the target @@LaunchParams uses without asking,
+	// see @@CavesSetLaunchTargetParams
+	// @optional
+	PreferredLaunchTarget string `json:"preferredLaunchTarget,omitempty"`
+}
+
+// An action from a cave's manifest
+//
+// @category Install
+type CaveLaunchTarget struct {
+	// Name of the action, like `play` or `editor`
+	Name string `json:"name"`
+
+	// File path, relative to the install folder, or URL
+	Path string `json:"path"`
+
+	// Command-line arguments
+	// @optional
+	Args []string `json:"args,omitempty"`
+
+	// Platform the action is restricted to, if any
+	// @optional
+	Platform ox.Platform `json:"platform,omitempty"`
+}
+
+// Sets which of a cave's launch targets @@LaunchParams uses
+// without asking, when there are several.
+//
+// @name Caves.SetLaunchTarget
+// @category Install
+// @caller client
+type CavesSetLaunchTargetParams struct {
+	CaveID string `json:"caveId"`
+
+	// Name of one of the targets from @@CavesGetLaunchTargetsParams.
+	// Empty to ask again on every launch.
+	// @optional
+	TargetName string `json:"targetName,omitempty"`
+}
+
+func (p CavesSetLaunchTargetParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+	)
+}
+
+type CavesSetLaunchTargetResult struct{}
+
+// Lists the builds of a cave's upload, newest first, for example
+// to let the user pick one for @@CavesDowngradeParams.
+//
+// @name Caves.ListBuildHistory
+// @category Install
+// @caller client
+// @readonly
+type CavesListBuildHistoryParams struct {
+	CaveID string `json:"caveId"`
+}
+
+func (p CavesListBuildHistoryParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+	)
+}
+
+type CavesListBuildHistoryResult struct {
+	// Builds of the cave's upload, newest first
+	Builds []*BuildHistoryEntry `json:"builds"`
+}
+
+// A build in the history of a cave's upload
+//
+// @category Install
+type BuildHistoryEntry struct {
+	// Includes its version, user version and date
+	Build *itchio.Build `json:"build"`
+
+	// Size of the build's archive, 0 if unknown
+	ArchiveSize int64 `json:"archiveSize"`
+
+	// Size of the build once installed, 0 if unknown
+	UnpackedSize int64 `json:"unpackedSize"`
+
+	// True if this is the build the cave has installed
+	Current bool `json:"current"`
+
+	// True if the cave can be downgraded to this build,
+	// see @@CavesDowngradeParams
+	DowngradeEligible bool `json:"downgradeEligible"`
+
+	// True if saves made by the installed build may not work
+	// with this one, see @@CavesDowngradeParams
+	MayBreakSaves bool `json:"mayBreakSaves"`
+}
+
+// Queues the download of an older build of a cave's upload, for
+// when an update broke something. The cave is pinned, so that
+// @@CaveUpdateBatchParams doesn't upgrade it again right away.
+//
+// itch.io doesn't serve patches going backwards, so the install
+// folder is healed from the older build's archive. Like any other
+// download, the downgrade is performed by @@DownloadsDriveParams,
+// and shows up in the cave's downloads with the `downgrade` reason.
+//
+// Games can declare the oldest version that can read the saves
+// of a build with `minimum-save-version` in the build's `.itch.toml`
+// manifest. Downgrading to a build with an older (or no) user
+// version is still allowed, but warned about.
+//
+// @name Caves.Downgrade
+// @category Install
+// @caller client
+type CavesDowngradeParams struct {
+	CaveID string `json:"caveId"`
+
+	// ID of the build to downgrade to, must be older
+	// than the installed one, see @@CavesListBuildHistoryParams
+	BuildID int64 `json:"buildId"`
+}
+
+func (p CavesDowngradeParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+		validation.Field(&p.BuildID, validation.Required),
+	)
+}
+
+type CavesDowngradeResult struct {
+	// The queued download, see @@DownloadsDriveParams
+	DownloadID string `json:"downloadId"`
+
+	// Set if saves made by the installed build may not work
+	// with the older one
+	// @optional
+	SaveWarning string `json:"saveWarning,omitempty"`
+}
+
+// Lists caves that were installed with a given profile's credentials.
+//
+// @name Caves.ByProfile
+// @category Install
+// @caller client
+// @readonly
+type CavesByProfileParams struct {
+	ProfileID int64 `json:"profileId"`
+}
+
+func (p CavesByProfileParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.ProfileID, validation.Required),
+	)
+}
+
+type CavesByProfileResult struct {
+	Caves []*Cave `json:"caves"`
+}
+
+// Lists caves matching a filter expression, like:
+//
+// `gameTitle contains "dungeon" AND size > 1gb AND lastPlayed after 2024-01-01 AND NOT pinned`
+//
+// Expressions combine comparisons with AND, OR, NOT and parentheses.
+// Available fields are gameTitle, classification, installLocation,
+// gameId, size, playTime, lastPlayed, installedAt, pinned and snoozed.
+// See the cavefilter package for the full grammar. Invalid expressions
+// fail with `CodeInvalidFilter`.
+//
+// @name Caves.Filter
+// @category Install
+// @caller client
+// @readonly
+type CavesFilterParams struct {
+	Expression string `json:"expression"`
+}
+
+func (p CavesFilterParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Expression, validation.Required),
+	)
+}
+
+type CavesFilterResult struct {
+	Caves []*Cave `json:"caves"`
+}
+
+// Finds caves by approximate game title, so that typos like "dungen"
+// still find "Dungeon Crawler". A title matches if some run of
+// consecutive words in it is within maxDistance edits of the query
+// (case and punctuation are ignored).
+//
+// Results are sorted by distance, then title, and capped at 20.
+//
+// @name Caves.FuzzySearch
+// @category Install
+// @caller client
+// @readonly
+type CavesFuzzySearchParams struct {
+	// What to look for
+	Query string `json:"query"`
+
+	// How many edits (letters inserted, removed or substituted)
+	// a title may be away from the query. 0 means exact words only.
+	// @optional
+	MaxDistance int64 `json:"maxDistance"`
+}
+
+func (p CavesFuzzySearchParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Query, validation.Required),
+		validation.Field(&p.MaxDistance, validation.Min(0), validation.Max(5)),
+	)
+}
+
+type CavesFuzzySearchResult struct {
+	Results []*CaveFuzzyMatch `json:"results"`
+}
+
+// A cave whose game title is close to a @@CavesFuzzySearchParams query
+//
+// @category Install
+type CaveFuzzyMatch struct {
+	Cave *Cave `json:"cave"`
+	// Edit distance between the query and the closest part
+	// of the cave's game title
+	Distance int64 `json:"distance"`
+}
+
+// Lists the files installed for a cave, for companion tools
+// (mod managers, save editors) that need to find game files.
+//
+// The list comes from the cave's receipt, not from walking the install
+// folder, so files created by the game itself aren't part of it.
+//
+// @name Caves.ListFiles
+// @category Install
+// @caller client
+// @readonly
+type CavesListFilesParams struct {
+	CaveID string `json:"caveId"`
+
+	// Only list files matching this pattern, like `*.pak` or
+	// `saves/**/*.json`. Patterns are matched against slash-separated
+	// paths relative to the install folder: `*` matches within a path
+	// element, `**` matches any number of path elements.
+	// @optional
+	Glob string `json:"glob"`
+}
+
+func (p CavesListFilesParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+	)
+}
+
+type CavesListFilesResult struct {
+	Files []*CaveFile `json:"files"`
+}
+
+// A file of a cave's install folder, see @@CavesListFilesParams
+//
+// @category Install
+type CaveFile struct {
+	// Slash-separated path, relative to the install folder
+	Path string `json:"path"`
+	// Size of the file in bytes, or -1 if it's missing from disk
+	Size int64 `json:"size"`
+}
+
+// Reads part of a file installed for a cave. Only files listed
+// in the cave's receipt (see @@CavesListFilesParams) can be read,
+// and only if they resolve to somewhere inside the install folder.
+//
+// There is no way to write files through butlerd.
+//
+// @name Caves.ReadFile
+// @category Install
+// @caller client
+type CavesReadFileParams struct {
+	CaveID string `json:"caveId"`
+
+	// Slash-separated path, relative to the install folder
+	RelativePath string `json:"relativePath"`
+
+	// Where to start reading, in bytes
+	// @optional
+	Offset int64 `json:"offset"`
+
+	// How many bytes to read, at most 4MiB
+	Length int64 `json:"length"`
+}
+
+// CavesReadFileMaxLength is how many bytes a single
+// @@CavesReadFileParams call can read
+const CavesReadFileMaxLength = 4 * 1024 * 1024
+
+func (p CavesReadFileParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+		validation.Field(&p.RelativePath, validation.Required),
+		validation.Field(&p.Offset, validation.Min(0)),
+		validation.Field(&p.Length, validation.Required, validation.Min(1), validation.Max(CavesReadFileMaxLength)),
+	)
+}
+
+type CavesReadFileResult struct {
+	// The bytes read, base64-encoded. Shorter than the requested
+	// length if the end of the file was reached.
+	Data string `json:"data"`
+
+	// Size of the whole file, in bytes
+	Size int64 `json:"size"`
+
+	// True if the end of the file was reached
+	EOF bool `json:"eof"`
+}
+
+// Opens a cave's install folder in the file manager: Explorer
+// on Windows, Finder on macOS, and whatever `xdg-open` picks on Linux.
+//
+// Fails with `CodeInstallFolderMissing` if the folder isn't on disk.
+//
+// @name Caves.OpenInstallFolder
+// @category Install
+// @caller client
+type CavesOpenInstallFolderParams struct {
+	CaveID string `json:"caveId"`
+}
+
+func (p CavesOpenInstallFolderParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+	)
+}
+
+type CavesOpenInstallFolderResult struct {
+	// Absolute path of the folder that was opened
+	InstallFolder string `json:"installFolder"`
+}
+
+// Lists the caves that were installed as DLC for a cave,
+// with @@InstallQueueParams `parentCaveId`.
+//
+// @name Caves.GetDLCs
+// @category Install
+// @caller client
+// @readonly
+type CavesGetDLCsParams struct {
+	CaveID string `json:"caveId"`
+}
+
+func (p CavesGetDLCsParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+	)
+}
+
+type CavesGetDLCsResult struct {
+	Caves []*Cave `json:"caves"`
+}
+
+// Looks for caves whose install folder has gone missing from disk.
+//
+// A @@GhostCaveDetectedNotification is sent for every ghost cave found.
+// This is also done in the background shortly after @@MetaFlowParams is
+// established, but only for caves that haven't been checked in the last 24 hours.
+//
+// @name Caves.DetectGhosts
+// @category Install
+// @caller client
+type CavesDetectGhostsParams struct{}
+
+func (p CavesDetectGhostsParams) Validate() error {
+	return nil
+}
+
+type CavesDetectGhostsResult struct {
+	// All the ghost caves that were found
+	Ghosts []*GhostCave `json:"ghosts"`
+}
+
+// Sent during @@CavesDetectGhostsParams (and over @@MetaFlowParams)
+// whenever a cave is found whose install folder doesn't exist on disk.
+//
+// @category Install
+type GhostCaveDetectedNotification struct {
+	Ghost *GhostCave `json:"ghost"`
+}
+
+// A cave whose install folder does not exist on disk anymore
+//
+// @category Install
+type GhostCave struct {
+	// ID of the ghost cave
+	CaveID string `json:"caveId"`
+	// Game the ghost cave was for
+	Game *itchio.Game `json:"game"`
+	// The install folder that could not be found
+	InstallFolder string `json:"installFolder"`
+	// What the client can offer the user to do about it
+	SuggestedActions []GhostCaveAction `json:"suggestedActions"`
+}
+
+// @category Install
+type GhostCaveAction string
+
+const (
+	// Install the game again, in the same install folder
+	GhostCaveActionReinstall GhostCaveAction = "reinstall"
+	// Forget about the cave altogether
+	GhostCaveActionDeleteCave GhostCaveAction = "delete_cave"
+	// Point the cave to the folder it was moved to,
+	// see @@CavesFindMovedParams
+	GhostCaveActionUpdatePath GhostCaveAction = "update_path"
+)
+
+// Looks for the folder a cave was moved to, after its install folder
+// went missing (see @@GhostCave). The folders in all install locations
+// are searched, along with extraRoots and the folders in them.
+//
+// A folder is a candidate if its receipt is for the cave's game, upload
+// and build, and no other cave uses it. Candidates are never relinked
+// by this call, see @@CavesRelinkParams.
+//
+// @name Caves.FindMoved
+// @category Install
+// @caller client
+type CavesFindMovedParams struct {
+	CaveID string `json:"caveId"`
+
+	// Other folders to search, like the one the user thinks
+	// they moved the game to
+	// @optional
+	ExtraRoots []string `json:"extraRoots,omitempty"`
+}
+
+func (p CavesFindMovedParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+	)
+}
+
+type CavesFindMovedResult struct {
+	// Folders the cave may have been moved to. If there are several,
+	// the user should pick one.
+	Candidates []*MovedCaveCandidate `json:"candidates"`
+}
+
+// A folder a cave may have been moved to
+//
+// @category Install
+type MovedCaveCandidate struct {
+	// Absolute path of the folder
+	InstallFolder string `json:"installFolder"`
+
+	// ID of the install location the folder is in,
+	// empty if it's in none of them
+	// @optional
+	InstallLocationID string `json:"installLocationId,omitempty"`
+
+	// True if the folder's receipt is the one last written for the
+	// cave, and not just one for the same build
+	Exact bool `json:"exact"`
+}
+
+// Points a cave to the folder it was moved to, usually one of the
+// candidates found by @@CavesFindMovedParams. The folder's receipt
+// must be for the cave's game, upload and build, and some of the
+// files it lists are checked for.
+//
+// The move is recorded in the cave's history, see @@CavesListEventsParams.
+//
+// @name Caves.Relink
+// @category Install
+// @caller client
+type CavesRelinkParams struct {
+	CaveID string `json:"caveId"`
+
+	// Absolute path of the folder the cave is in now
+	InstallFolder string `json:"installFolder"`
+}
+
+func (p CavesRelinkParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+		validation.Field(&p.InstallFolder, validation.Required),
+	)
+}
+
+type CavesRelinkResult struct{}
+
+// Lists what happened to a cave, besides installs and launches
+//
+// @name Caves.ListEvents
+// @category Install
 // @caller client
-type InstallPlanParams struct {
-	// The ID of the game we're planning to install
-	GameID int64 `json:"gameId"`
+// @readonly
+type CavesListEventsParams struct {
+	CaveID string `json:"caveId"`
+}
 
-	// The download session ID to use for this install plan
+func (p CavesListEventsParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+	)
+}
+
+type CavesListEventsResult struct {
+	// Most recent first
+	Events []*CaveEvent `json:"events"`
+}
+
+// @category Install
+type CaveEvent struct {
+	Type      CaveEventType `json:"type"`
+	CreatedAt *time.Time    `json:"createdAt"`
+
+	// For `relinked` and `renamed` events, where the cave was before
 	// @optional
-	DownloadSessionID string `json:"downloadSessionId"`
+	FromInstallFolder string `json:"fromInstallFolder,omitempty"`
+	// For `relinked` events, where the cave was found, for `renamed`
+	// events, where it was moved to
+	// @optional
+	ToInstallFolder string `json:"toInstallFolder,omitempty"`
+}
+
+// @category Install
+type CaveEventType string
+
+const (
+	// The cave was pointed to the folder it was moved to,
+	// see @@CavesRelinkParams
+	CaveEventTypeRelinked CaveEventType = "relinked"
+	// The cave's install folder was renamed,
+	// see @@CavesApplyNamingTemplateParams
+	CaveEventTypeRenamed CaveEventType = "renamed"
+)
+
+// Writes a new receipt for a cave, from what's actually in its
+// install folder. Useful for caves installed by old versions of
+// the app, whose receipt lists no files, or that lost their receipt
+// altogether.
+//
+// When the signature of the cave's build can be fetched, every file
+// is checked against it, and only files that are part of the build
+// end up in the receipt. Otherwise, all files are listed, except those
+// that look like they were created by the game (saves, logs, etc.)
+//
+// The previous receipt, if any, is kept next to the new one,
+// as `.itch/receipt.json.gz.bak`.
+//
+// @name Caves.RebuildReceipt
+// @category Install
+// @caller client
+type CavesRebuildReceiptParams struct {
+	CaveID string `json:"caveId"`
+}
+
+func (p CavesRebuildReceiptParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+	)
+}
+
+type CavesRebuildReceiptResult struct {
+	Rebuild *ReceiptRebuild `json:"rebuild"`
+}
 
+// Rebuilds the receipts of several caves, one after the other,
+// see @@CavesRebuildReceiptParams. Failing to rebuild one receipt
+// doesn't stop the others from being rebuilt.
+//
+// @name Caves.RebuildReceipts
+// @category Install
+// @caller client
+type CavesRebuildReceiptsParams struct {
+	// Caves to rebuild the receipts of. If empty, rebuilds
+	// the receipts of all caves.
 	// @optional
-	UploadID int64 `json:"uploadId"`
+	CaveIDs []string `json:"caveIds"`
 }
 
-func (p InstallPlanParams) Validate() error {
+func (p CavesRebuildReceiptsParams) Validate() error {
 	return validation.ValidateStruct(&p,
-		validation.Field(&p.GameID, validation.Required),
+		validation.Field(&p.CaveIDs, validation.Each(validation.Required)),
 	)
 }
 
-type InstallPlanResult struct {
-	Game    *itchio.Game     `json:"game"`
-	Uploads []*itchio.Upload `json:"uploads"`
+type CavesRebuildReceiptsResult struct {
+	// One entry per cave, in the order they were rebuilt
+	Rebuilds []*ReceiptRebuild `json:"rebuilds"`
+}
 
-	Info *InstallPlanInfo `json:"info"`
+// Renames the install folders of caves in an install location so they
+// follow its folder name template, see @@InstallLocationsAddParams.
+// New installs follow the template right away, but caves installed
+// before it was set keep their folder until this is called.
+//
+// Folders are moved on disk, waiting for caves that are running.
+// Caves with a custom install folder are left alone, and caves with
+// a download in progress are skipped.
+//
+// @name Caves.ApplyNamingTemplate
+// @category Install
+// @caller client
+type CavesApplyNamingTemplateParams struct {
+	// ID of the install location whose template should be applied
+	InstallLocationID string `json:"installLocationId"`
+
+	// Caves to rename. If empty, renames all caves in the
+	// install location.
+	// @optional
+	CaveIDs []string `json:"caveIds"`
 }
 
-type InstallPlanInfo struct {
-	Upload    *itchio.Upload `json:"upload"`
-	Build     *itchio.Build  `json:"build"`
-	Type      string         `json:"type"`
-	DiskUsage *DiskUsageInfo `json:"diskUsage"`
+func (p CavesApplyNamingTemplateParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.InstallLocationID, validation.Required),
+		validation.Field(&p.CaveIDs, validation.Each(validation.Required)),
+	)
+}
 
-	Error        string `json:"error,omitempty"`
-	ErrorMessage string `json:"errorMessage,omitempty"`
-	ErrorCode    int64  `json:"errorCode,omitempty"`
+type CavesApplyNamingTemplateResult struct {
+	// One entry per cave, in the order they were renamed
+	Renames []*CaveRename `json:"renames"`
 }
 
-type DiskUsageInfo struct {
-	FinalDiskUsage  int64  `json:"finalDiskUsage"`
-	NeededFreeSpace int64  `json:"neededFreeSpace"`
-	Accuracy        string `json:"accuracy"`
+// What happened to a cave's install folder when applying
+// an install location's folder name template
+//
+// @category Install
+type CaveRename struct {
+	CaveID string `json:"caveId"`
+
+	// Name of the install folder before the call
+	OldFolderName string `json:"oldFolderName"`
+
+	// Name of the install folder after the call, same as
+	// oldFolderName if it was not renamed
+	NewFolderName string `json:"newFolderName"`
+
+	// True if the folder was moved on disk
+	Renamed bool `json:"renamed"`
+
+	// Set if this cave could not be renamed
+	// @optional
+	Error string `json:"error,omitempty"`
+
+	// Set if this cave could not be renamed, see @@Code
+	// @optional
+	ErrorCode int64 `json:"errorCode,omitempty"`
 }
 
-// @name Caves.SetPinned
+// What happened when rebuilding a cave's receipt
+//
 // @category Install
-// @caller client
-type CavesSetPinnedParams struct {
-	// ID of the cave to pin/unpin
+type ReceiptRebuild struct {
 	CaveID string `json:"caveId"`
 
-	// Pinned state the cave should have after this call
-	Pinned bool `json:"pinned"`
+	// Files found in the install folder (except for `.itch`),
+	// sorted by path
+	Files []*ReceiptRebuildFile `json:"files"`
+
+	// True if files were checked against the build's signature
+	CheckedSignature bool `json:"checkedSignature"`
+
+	// Files of the build that are missing from the install folder.
+	// Only set if the signature was checked.
+	// @optional
+	Missing []string `json:"missing,omitempty"`
+
+	// Where the previous receipt was moved, if there was one
+	// @optional
+	BackupPath string `json:"backupPath,omitempty"`
+
+	// Set if this receipt could not be rebuilt
+	// @optional
+	Error string `json:"error,omitempty"`
+
+	// Set if this receipt could not be rebuilt, see @@Code
+	// @optional
+	ErrorCode int64 `json:"errorCode,omitempty"`
 }
 
-func (p CavesSetPinnedParams) Validate() error {
+// A file found in the install folder while rebuilding a receipt
+//
+// @category Install
+type ReceiptRebuildFile struct {
+	// Slash-separated path, relative to the install folder
+	Path string `json:"path"`
+	// Size of the file in bytes
+	Size int64 `json:"size"`
+	// Whether the file is part of the build, and whether it still
+	// matches it. Only `local` files are left out of the rebuilt receipt.
+	Status ReceiptFileStatus `json:"status"`
+}
+
+// How a file compares to the build a cave was installed from,
+// see @@ReceiptRebuildFile
+//
+// @category Install
+type ReceiptFileStatus string
+
+const (
+	// File is part of the build, and matches its signature
+	ReceiptFileStatusOfficial ReceiptFileStatus = "official"
+	// File is part of the build, but its contents differ from the signature
+	ReceiptFileStatusModified ReceiptFileStatus = "modified"
+	// File is not part of the build (or looks like it was created by the game,
+	// when there's no signature to check against). Not listed in the receipt.
+	ReceiptFileStatusLocal ReceiptFileStatus = "local"
+	// No signature to check against, the file is assumed to be part of the build
+	ReceiptFileStatusUnchecked ReceiptFileStatus = "unchecked"
+)
+
+// Sent during @@InstallPerformParams when the receipt of the cave
+// being installed over is missing or lists no files, so files left over
+// by the previous version can't be told apart from files the game created.
+//
+// Rebuilding the receipt with @@CavesRebuildReceiptParams fixes that.
+//
+// @category Install
+type ReceiptRebuildSuggestedNotification struct {
+	CaveID string `json:"caveId"`
+	// Why the current receipt isn't good enough
+	Reason string `json:"reason"`
+}
+
+// Checks the files in a cave's install folder, for example before
+// launching it. Nothing is changed on disk: reinstalling the cave
+// (see @@InstallQueueParams) fixes missing or corrupted files.
+//
+// When the signature of the cave's build can be fetched, the contents
+// of every file are checked against it. Otherwise, if the cave's upload
+// is a zip archive, file sizes are compared to its entries. As a last
+// resort, files are only checked for presence against the receipt.
+//
+// @category Install
+// @caller client
+type CaveVerifyParams struct {
+	CaveID string `json:"caveId"`
+}
+
+func (p CaveVerifyParams) Validate() error {
 	return validation.ValidateStruct(&p,
 		validation.Field(&p.CaveID, validation.Required),
 	)
 }
 
-type CavesSetPinnedResult struct{}
+type CaveVerifyResult struct {
+	// What the files were checked against
+	Method CaveVerifyMethod `json:"method"`
+
+	// Files that should be in the install folder but aren't, sorted by path
+	Missing []string `json:"missing"`
+
+	// Files in the install folder that aren't part of what was installed,
+	// sorted by path. Includes files created by the game, like saves.
+	Added []string `json:"added"`
+
+	// Files whose contents (or size, depending on the method)
+	// don't match what was installed, sorted by path
+	Corrupted []string `json:"corrupted"`
+
+	// If true, the cave is playable early and still being installed
+	// (see @@CaveInstallInfo): only the files its game needs to start
+	// were checked, and none count as added.
+	// @optional
+	PlayableEarly bool `json:"playableEarly,omitempty"`
+}
+
+// @category Install
+type CaveVerifyMethod string
+
+const (
+	// Contents were checked against the build's signature
+	CaveVerifyMethodSignature CaveVerifyMethod = "signature"
+	// Sizes were checked against the entries of the upload's zip archive
+	CaveVerifyMethodArchive CaveVerifyMethod = "archive"
+	// Files were only checked for presence, against the receipt
+	CaveVerifyMethodReceipt CaveVerifyMethod = "receipt"
+)
+
+// Sent during @@CaveVerifyParams as files get checked
+//
+// @category Install
+type CaveVerifyProgressNotification struct {
+	CaveID string `json:"caveId"`
+	// Slash-separated path of the file that was just checked,
+	// relative to the install folder
+	Path string `json:"path"`
+	// Overall progress, between 0 and 1
+	Progress float64 `json:"progress"`
+}
 
 // Create a shortcut for an existing cave .
 //
@@ -1588,6 +3556,12 @@ type InstallPerformParams struct {
 
 	// The folder turned by @@InstallQueueParams
 	StagingFolder string `json:"stagingFolder"`
+
+	// If true, @@InstallPatchProgressNotification are sent while
+	// patches are being applied, in addition to @@ProgressNotification.
+	//
+	// @optional
+	PatchProgress bool `json:"patchProgress,omitempty"`
 }
 
 func (p InstallPerformParams) Validate() error {
@@ -1633,18 +3607,150 @@ type UninstallPerformParams struct {
 	CaveID string `json:"caveId"`
 
 	// If true, don't attempt to run any uninstallers, just
-	// remove the DB record and burn the install folder to the ground.
+	// remove the DB record and burn the install folder to the ground
+	// (except for preserved files, see preserveUserData).
 	// @optional
 	Hard bool `json:"hard"`
+
+	// Categories of files the game created at runtime that should be
+	// left in the install folder, like `saves` and `config`. Files from
+	// the receipt are always removed. If unspecified, the whole install
+	// folder is wiped.
+	// @optional
+	PreserveUserData []UninstallFileCategory `json:"preserveUserData,omitempty"`
 }
 
 func (p UninstallPerformParams) Validate() error {
 	return validation.ValidateStruct(&p,
 		validation.Field(&p.CaveID, validation.Required),
+		validation.Field(&p.PreserveUserData, validation.Each(validation.In(
+			UninstallFileCategorySaves,
+			UninstallFileCategoryConfig,
+			UninstallFileCategoryLogs,
+			UninstallFileCategoryCache,
+			UninstallFileCategoryUnknown,
+		))),
+	)
+}
+
+type UninstallPerformResult struct {
+	// Files that were removed from the install folder
+	Removed []*UninstallFile `json:"removed"`
+	// Files that were kept on purpose, see preserveUserData
+	Preserved []*UninstallFile `json:"preserved"`
+	// Files that should have been removed, but are still there
+	LeftBehind []*UninstallFile `json:"leftBehind"`
+}
+
+// Removes several caves, one at a time, like @@UninstallPerformParams
+// would for each of them. Unless dryRun is set, returns as soon as the
+// batch is queued: use @@CaveBatchStatusParams to follow it, or listen
+// for @@CaveUninstallProgressNotification.
+//
+// Caves whose game is running are waited for, whether or not their
+// files are deleted. Caves that fail to uninstall don't stop the others.
+//
+// @category Install
+// @caller client
+type CaveUninstallBatchParams struct {
+	// Caves to remove
+	CaveIDs []string `json:"caveIds"`
+
+	// If true, caves are uninstalled and their install folders wiped.
+	// Otherwise, butler only forgets about them, and their files are
+	// left on disk.
+	// @optional
+	DeleteFiles bool `json:"deleteFiles,omitempty"`
+
+	// If true, nothing is removed: the result says what would be.
+	// @optional
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+func (p CaveUninstallBatchParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveIDs, validation.Required),
 	)
 }
 
-type UninstallPerformResult struct{}
+type CaveUninstallBatchResult struct {
+	// Identifier to pass to @@CaveBatchStatusParams, empty for dry runs
+	// @optional
+	BatchID string `json:"batchId,omitempty"`
+
+	// For dry runs, disk space the caves take up, as recorded when they
+	// were installed. Zero if deleteFiles isn't set.
+	// @optional
+	TotalSizeToFree int64 `json:"totalSizeToFree,omitempty"`
+
+	// For dry runs, the caves that would be removed
+	// @optional
+	CavesToDelete []*Cave `json:"cavesToDelete,omitempty"`
+}
+
+// Sent during @@CaveUninstallBatchParams when a cave starts being
+// removed, and when it's done. Sent on the connection that started
+// the batch.
+//
+// @category Install
+type CaveUninstallProgressNotification struct {
+	BatchID string `json:"batchId"`
+	// State of the cave
+	Cave *CaveBatchItem `json:"cave"`
+	// Overall progress of the batch, between 0 and 1
+	Progress float64 `json:"progress"`
+}
+
+// Sent during @@UninstallPerformParams, before anything is removed,
+// with the files of the install folder that aren't in the receipt,
+// i.e. that the game (or the user) created.
+//
+// @category Install
+type UninstallFilesCategorizedNotification struct {
+	// The cave being uninstalled
+	CaveID string `json:"caveId"`
+	// Files not in the receipt, and what they look like
+	Files []*UninstallFile `json:"files"`
+}
+
+// A file found in an install folder while uninstalling
+//
+// @category Install
+type UninstallFile struct {
+	// Path of the file, slash-separated, relative to the install folder
+	Path string `json:"path"`
+	// What the file looks like
+	Category UninstallFileCategory `json:"category"`
+	// Size of the file, in bytes
+	Size int64 `json:"size"`
+}
+
+// @category Install
+type UninstallFileCategory string
+
+const (
+	// The file is listed in the receipt, it was installed by us
+	UninstallFileCategoryInstalled UninstallFileCategory = "installed"
+	// Looks like a saved game
+	UninstallFileCategorySaves UninstallFileCategory = "saves"
+	// Looks like settings or preferences
+	UninstallFileCategoryConfig UninstallFileCategory = "config"
+	// Looks like a log file
+	UninstallFileCategoryLogs UninstallFileCategory = "logs"
+	// Looks like cached data, that can be regenerated
+	UninstallFileCategoryCache UninstallFileCategory = "cache"
+	// Not in the receipt, and no idea what it is
+	UninstallFileCategoryUnknown UninstallFileCategory = "unknown"
+)
+
+var UninstallFileCategoryList = []interface{}{
+	UninstallFileCategoryInstalled,
+	UninstallFileCategorySaves,
+	UninstallFileCategoryConfig,
+	UninstallFileCategoryLogs,
+	UninstallFileCategoryCache,
+	UninstallFileCategoryUnknown,
+}
 
 // Prepare to queue a version switch. The client will
 // receive an @@InstallVersionSwitchPickParams.
@@ -1704,6 +3810,40 @@ func (gc *GameCredentials) JustAPIKey() *GameCredentials {
 	}
 }
 
+// Which rule decided the credentials used for a game
+//
+// @category Install
+type AccessRule string
+
+const (
+	// The profile was explicitly passed by the client
+	AccessRuleExplicit AccessRule = "explicit"
+	// The profile that originally installed the cave
+	AccessRuleCaveProfile AccessRule = "cave-profile"
+	// A profile that owns a download key for the game
+	AccessRuleDownloadKey AccessRule = "download-key"
+	// A profile that has admin access to the game
+	AccessRuleAuthor AccessRule = "author"
+	// No game-specific credentials: the press profile if any,
+	// the most recently used profile otherwise
+	AccessRuleAnonymous AccessRule = "anonymous"
+)
+
+// Explains which credentials were used to access a game, and why.
+//
+// @category Install
+type AccessExplanation struct {
+	// The rule that matched
+	Rule AccessRule `json:"rule"`
+	// The profile whose API key is used
+	ProfileID int64 `json:"profileId"`
+	// The download key used, if any
+	// @optional
+	DownloadKeyID int64 `json:"downloadKeyId,omitempty"`
+	// Every rule that was considered, in order, and what came of it
+	Steps []string `json:"steps"`
+}
+
 // Asks the user to pick between multiple available uploads
 //
 // @category Install
@@ -1739,6 +3879,113 @@ type ProgressNotification struct {
 	BPS float64 `json:"bps"`
 }
 
+// Sent during @@InstallQueueParams as it goes through the steps
+// that need the network, so clients can show what it's waiting on.
+// Not sent for dry runs. Clients are free to ignore it.
+//
+// @name Install.Queue.Progress
+// @category Install
+type InstallQueueProgressNotification struct {
+	// ID of the install, as in @@InstallQueueResult
+	ID string `json:"id"`
+	// ID of the game being queued, known before the result comes in
+	GameID int64 `json:"gameId"`
+	// ID of the cave being installed or updated, if any
+	//
+	// @optional
+	CaveID string `json:"caveId,omitempty"`
+	// What the queue is doing now
+	Phase InstallQueuePhase `json:"phase"`
+}
+
+// @category Install
+type InstallQueuePhase string
+
+const (
+	// Checking whether the game is released yet
+	InstallQueuePhaseRefreshingGame InstallQueuePhase = "refreshing-game"
+	// Looking for compatible uploads, or the latest build of the upload
+	InstallQueuePhaseResolvingUpload InstallQueuePhase = "resolving-upload"
+	// Probing the install source, to know what needs to be downloaded
+	// and how much disk space it takes
+	InstallQueuePhasePreparing InstallQueuePhase = "preparing"
+)
+
+// Sent during @@InstallQueueParams and @@InstallPerformParams while
+// butler settles on how to install: by applying patches, or from the
+// install source, and what that source is. Sent at most 4 times a
+// second, so fast stages may not show up at all, except for `ready`.
+//
+// @name Install.Prepare.Progress
+// @category Install
+type InstallPrepareProgressNotification struct {
+	// ID of the install, as in @@InstallQueueResult
+	ID string `json:"id"`
+	// What's being done now
+	Stage InstallPrepareStage `json:"stage"`
+	// How many bytes of the current stage are done
+	BytesDone int64 `json:"bytesDone"`
+	// How many bytes the current stage has to go through in all,
+	// or 0 if there's nothing to measure its progress against
+	TotalBytes int64 `json:"totalBytes"`
+}
+
+// @category Install
+type InstallPrepareStage string
+
+const (
+	// Looking up the upgrade path from the installed build: the
+	// patches and signatures of every build in between
+	InstallPrepareStageFetchingSignature InstallPrepareStage = "fetching-signature"
+	// Weighing patches against a full download, then probing the
+	// install source. Bytes are those of the source read so far,
+	// out of its size
+	InstallPrepareStageComputingPatch InstallPrepareStage = "computing-patch"
+	// Done preparing, the install goes on
+	InstallPrepareStageReady InstallPrepareStage = "ready"
+)
+
+// Sent periodically during @@InstallPerformParams while patches are
+// being applied, if `patchProgress` was set. The plain
+// @@ProgressNotification is still sent, so clients that only care about
+// the overall progress can ignore this one.
+//
+// @name Install.PatchProgress
+// @category Install
+type InstallPatchProgressNotification struct {
+	// ID of the install, as passed to @@InstallPerformParams
+	ID string `json:"id"`
+	// Path of the file currently being worked on,
+	// relative to the install folder
+	Path string `json:"path"`
+	// What's being done to that file
+	Operation PatchOperation `json:"operation"`
+	// Bytes of the file written so far
+	FileBytesDone int64 `json:"fileBytesDone"`
+	// Final size of the file, in bytes
+	FileBytesTotal int64 `json:"fileBytesTotal"`
+	// Number of files processed so far
+	OperationsDone int64 `json:"operationsDone"`
+	// Number of files the patch touches in total, including deletions.
+	// Renames are only found out about along the way, so this can go
+	// down a little as the patch is applied.
+	OperationsTotal int64 `json:"operationsTotal"`
+}
+
+// @category Install
+type PatchOperation string
+
+const (
+	// The file didn't exist in the previous build
+	PatchOperationCreate PatchOperation = "create"
+	// The file is being rebuilt from its previous version and the patch
+	PatchOperationPatch PatchOperation = "patch"
+	// The file is unchanged, but its path changed
+	PatchOperationRename PatchOperation = "rename"
+	// The file isn't part of the new build anymore
+	PatchOperationDelete PatchOperation = "delete"
+)
+
 // @category Install
 type TaskReason string
 
@@ -1811,9 +4058,13 @@ type InstallResult struct {
 	// TODO: verdict ?
 }
 
+// Lists install locations. Those on SSDs come first, so clients
+// picking the first one as a default get the fastest storage.
+//
 // @name Install.Locations.List
 // @category Install
 // @caller client
+// @readonly
 type InstallLocationsListParams struct {
 }
 
@@ -1825,6 +4076,9 @@ type InstallLocationsListResult struct {
 	InstallLocations []*InstallLocationSummary `json:"installLocations"`
 }
 
+// Adds an install location. Its path must be a writable folder,
+// and can't be inside of another install location, or contain one.
+//
 // @name Install.Locations.Add
 // @category Install
 // @caller client
@@ -1836,11 +4090,34 @@ type InstallLocationsAddParams struct {
 
 	// path of the new install location
 	Path string `json:"path"`
+
+	// who can access install folders in the new location.
+	// if not specified, butler won't change their permissions.
+	// @optional
+	AccessMode InstallLocationAccessMode `json:"accessMode"`
+
+	// folder to keep staging data in (downloads in progress, patches
+	// being applied), for example a fast disk used as scratch space.
+	// if not specified, it's kept inside the new location.
+	// @optional
+	StagingPath string `json:"stagingPath"`
+
+	// template for the install folder names of new caves, instead of
+	// the game's slug, for example `{game_id}-{channel}-{build_id}`.
+	// Variables are `{game_id}`, `{slug}`, `{title}`, `{creator}`,
+	// `{channel}`, `{build_id}` and `{upload_id}`. Unless the name has
+	// an upload or build ID in it, `-` and the upload ID are appended, so
+	// caves don't share folders. `/` makes subfolders, like in
+	// `{creator}/{slug}`: they're created as needed, and removed once
+	// they're empty. Installs from local archives aren't affected.
+	// @optional
+	FolderNameTemplate string `json:"folderNameTemplate,omitempty"`
 }
 
 func (p InstallLocationsAddParams) Validate() error {
 	return validation.ValidateStruct(&p,
 		validation.Field(&p.Path, validation.Required),
+		validation.Field(&p.AccessMode, validation.In(InstallLocationAccessModeList...)),
 	)
 }
 
@@ -1848,12 +4125,70 @@ type InstallLocationsAddResult struct {
 	InstallLocation *InstallLocationSummary `json:"installLocation"`
 }
 
+// Changes settings of an existing install location. The new
+// access mode is applied to the install folders of all caves
+// already in the location, and to those installed later.
+//
+// A new staging path only applies to downloads queued from now on,
+// those already queued keep their staging folder.
+//
+// @name Install.Locations.Update
+// @category Install
+// @caller client
+// @transactional
+type InstallLocationsUpdateParams struct {
+	// identifier of the install location to update
+	ID string `json:"id"`
+
+	// who can access install folders in this location.
+	// if empty, butler stops managing their permissions.
+	// @optional
+	AccessMode InstallLocationAccessMode `json:"accessMode"`
+
+	// folder to keep staging data in. if empty, staging data
+	// goes back to being kept inside the location.
+	// @optional
+	StagingPath string `json:"stagingPath"`
+
+	// template for the install folder names of new caves, see
+	// @@InstallLocationsAddParams. if empty, they're made from the
+	// game's slug again. caves already installed keep their folder,
+	// see @@CavesApplyNamingTemplateParams.
+	// @optional
+	FolderNameTemplate string `json:"folderNameTemplate,omitempty"`
+}
+
+func (p InstallLocationsUpdateParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.ID, validation.Required),
+		validation.Field(&p.AccessMode, validation.In(InstallLocationAccessModeList...)),
+	)
+}
+
+type InstallLocationsUpdateResult struct {
+	InstallLocation *InstallLocationSummary `json:"installLocation"`
+}
+
+// Removes an install location. Fails with `CodeInstallLocationNotEmpty`
+// if caves are still installed there, unless force is set.
+//
 // @name Install.Locations.Remove
 // @category Install
 // @caller client
+// @transactional
 type InstallLocationsRemoveParams struct {
 	// identifier of the install location to remove
 	ID string `json:"id"`
+
+	// If true, caves still installed in the location are moved over
+	// to the install location fallbackId. Their files stay where they are.
+	// @optional
+	Force bool `json:"force,omitempty"`
+
+	// identifier of the install location caves are moved over to,
+	// required if force is set
+	// @optional
+	FallbackID string `json:"fallbackId,omitempty"`
 }
 
 func (p InstallLocationsRemoveParams) Validate() error {
@@ -1868,6 +4203,7 @@ type InstallLocationsRemoveResult struct {
 // @name Install.Locations.GetByID
 // @category Install
 // @caller client
+// @readonly
 type InstallLocationsGetByIDParams struct {
 	// identifier of the install location to remove
 	ID string `json:"id"`
@@ -1935,29 +4271,47 @@ type InstallLocationsScanResult struct {
 //----------------------------------------------------------------------
 
 // Queue a download that will be performed later by
-// @@DownloadsDriveParams.
+// @@DownloadsDriveParams. Queuing an item that's already
+// in the queue, unfinished, does nothing.
 //
 // @name Downloads.Queue
 // @category Downloads
 // @caller client
 type DownloadsQueueParams struct {
 	Item *InstallQueueResult `json:"item"`
+
+	// Most bytes per second this download may go at, on top of the
+	// `network.bandwidthLimit` setting. Can be changed later with
+	// @@DownloadsSetBandwidthParams. Zero means unlimited.
+	// @optional
+	BandwidthBytesPerSecond int64 `json:"bandwidthBytesPerSecond,omitempty"`
+
+	// Groups this download with others, see @@DownloadsListParams
+	// @optional
+	BatchID string `json:"batchId,omitempty"`
 }
 
 func (p DownloadsQueueParams) Validate() error {
 	return validation.ValidateStruct(&p,
 		validation.Field(&p.Item, validation.Required),
+		validation.Field(&p.BandwidthBytesPerSecond, validation.Min(0)),
 	)
 }
 
 type DownloadsQueueResult struct {
 }
 
-// Put a download on top of the queue.
+// Put a download on top of the queue: it gets position 0, and the
+// others move back, keeping their order. If another download is being
+// driven, it's set aside (see @@DownloadsDrivePreemptedNotification),
+// unless the prioritized download is paused.
+//
+// Downloads that already finished, or were discarded, are left alone.
 //
 // @name Downloads.Prioritize
 // @category Downloads
 // @caller client
+// @transactional
 type DownloadsPrioritizeParams struct {
 	DownloadID string `json:"downloadId"`
 }
@@ -1976,7 +4330,12 @@ type DownloadsPrioritizeResult struct {
 // @name Downloads.List
 // @category Downloads
 // @caller client
+// @readonly
 type DownloadsListParams struct {
+	// Only list the downloads of this batch, like those queued by
+	// @@CollectionsInstallAllParams
+	// @optional
+	BatchID string `json:"batchId,omitempty"`
 }
 
 func (p DownloadsListParams) Validate() error {
@@ -1987,6 +4346,30 @@ type DownloadsListResult struct {
 	Downloads []*Download `json:"downloads"`
 }
 
+// List the downloads of a single game: those in progress or
+// pending, those that failed, and those that finished in the
+// last 7 days.
+//
+// @name Downloads.GetByGameID
+// @category Downloads
+// @caller client
+// @readonly
+type DownloadsGetByGameIDParams struct {
+	GameID int64 `json:"gameId"`
+}
+
+func (p DownloadsGetByGameIDParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.GameID, validation.Required),
+	)
+}
+
+type DownloadsGetByGameIDResult struct {
+	// In queue order. Completed downloads point at the cave
+	// they installed to with their CaveID.
+	Downloads []*Download `json:"downloads"`
+}
+
 // Removes all finished downloads from the queue.
 //
 // @name Downloads.ClearFinished
@@ -2002,13 +4385,59 @@ func (p DownloadsClearFinishedParams) Validate() error {
 type DownloadsClearFinishedResult struct {
 }
 
+// Removes all downloads that haven't finished from the queue,
+// paused ones included. The one being driven, if any, is stopped
+// first. Their staging folders are wiped, along with the install
+// folders of fresh installs. If the one being driven takes more than
+// 30 seconds to stop, it's left for @@DownloadsDriveParams to clean
+// up once it has. Finished (and errored) downloads are
+// left alone, see @@DownloadsClearFinishedParams.
+//
+// @name Downloads.DrainQueue
+// @category Downloads
+// @caller client
+type DownloadsDrainQueueParams struct {
+	// Why the queue is being drained, for the logs
+	// @optional
+	Reason string `json:"reason,omitempty"`
+}
+
+func (p DownloadsDrainQueueParams) Validate() error {
+	return nil
+}
+
+type DownloadsDrainQueueResult struct {
+	// How many downloads were removed from the queue
+	Drained int64 `json:"drained"`
+}
+
 // Drive downloads, which is: perform them one at a time,
 // until they're all finished.
 //
 // @name Downloads.Drive
 // @category Downloads
 // @caller client
-type DownloadsDriveParams struct{}
+type DownloadsDriveParams struct {
+	// How many times a download is silently retried in-place after
+	// a transient network error (like a connection reset), before it
+	// is reported as failed or we start waiting for the connection
+	// to come back. Defaults to 2, set to 0 to disable.
+	//
+	// @optional
+	GraceRetries *int64 `json:"graceRetries,omitempty"`
+
+	// How long to wait before each silent retry, in milliseconds.
+	// Defaults to 2000.
+	//
+	// @optional
+	GraceRetryDelayMs *int64 `json:"graceRetryDelayMs,omitempty"`
+
+	// If true, @@InstallPatchProgressNotification are sent while
+	// downloads are being applied as patches.
+	//
+	// @optional
+	PatchProgress bool `json:"patchProgress,omitempty"`
+}
 
 func (p DownloadsDriveParams) Validate() error {
 	return nil
@@ -2044,6 +4473,18 @@ type DownloadsDriveStartedNotification struct {
 	Download *Download `json:"download"`
 }
 
+// Sent when the download being driven is set aside, because another
+// one was put ahead of it with @@DownloadsPrioritizeParams. It stays in
+// the queue, and continues from where it left off once its turn comes.
+//
+// @name Downloads.Drive.Preempted
+type DownloadsDrivePreemptedNotification struct {
+	// The download that was set aside
+	Download *Download `json:"download"`
+	// ID of the download that's driven next
+	NextDownloadID string `json:"nextDownloadId"`
+}
+
 // @name Downloads.Drive.Errored
 type DownloadsDriveErroredNotification struct {
 	// The download that errored. It contains all the error
@@ -2085,6 +4526,7 @@ const (
 	DownloadReasonReinstall     DownloadReason = "reinstall"
 	DownloadReasonUpdate        DownloadReason = "update"
 	DownloadReasonVersionSwitch DownloadReason = "version-switch"
+	DownloadReasonDowngrade     DownloadReason = "downgrade"
 )
 
 // Represents a download queued, which will be
@@ -2103,6 +4545,25 @@ type Download struct {
 	StartedAt     *time.Time     `json:"startedAt"`
 	FinishedAt    *time.Time     `json:"finishedAt"`
 	StagingFolder string         `json:"stagingFolder"`
+
+	// If true, the download was paused with @@DownloadsPauseParams,
+	// and won't make progress until it's resumed
+	// @optional
+	Paused bool `json:"paused,omitempty"`
+
+	// Most bytes per second this download may go at, see
+	// @@DownloadsSetBandwidthParams. Zero means unlimited.
+	// @optional
+	BandwidthBytesPerSecond int64 `json:"bandwidthBytesPerSecond,omitempty"`
+
+	// Downloads queued together share a batch ID, see
+	// @@DownloadsListParams
+	// @optional
+	BatchID string `json:"batchId,omitempty"`
+
+	// Which credentials are used for this download, and why
+	// @optional
+	Access *AccessExplanation `json:"access,omitempty"`
 }
 
 type DownloadProgress struct {
@@ -2110,8 +4571,98 @@ type DownloadProgress struct {
 	Progress float64 `json:"progress"`
 	ETA      float64 `json:"eta"`
 	BPS      float64 `json:"bps"`
+
+	// Most bytes per second the download may currently go at: the
+	// lowest of its own limit, @@DownloadsSetThrottleParams and the
+	// `network.bandwidthLimit` setting. Zero if it's unlimited.
+	// @optional
+	LimitBPS int64 `json:"limitBps,omitempty"`
+}
+
+// Changes how fast a download may go. If it's being performed
+// by @@DownloadsDriveParams, the new rate applies right away.
+//
+// @name Downloads.SetBandwidth
+// @category Downloads
+// @caller client
+type DownloadsSetBandwidthParams struct {
+	DownloadID string `json:"downloadId"`
+
+	// Most bytes per second the download may go at. Zero means unlimited.
+	BandwidthBytesPerSecond int64 `json:"bandwidthBytesPerSecond"`
+}
+
+func (p DownloadsSetBandwidthParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.DownloadID, validation.Required),
+		validation.Field(&p.BandwidthBytesPerSecond, validation.Min(0)),
+	)
+}
+
+type DownloadsSetBandwidthResult struct{}
+
+// Caps how fast all downloads performed by @@DownloadsDriveParams
+// may go, together. Each download's own limit, see
+// @@DownloadsSetBandwidthParams, still applies on top of it.
+//
+// This sets the `downloads.maxBytesPerSecond` setting, so it's
+// persisted, see @@SettingsSetParams. Downloads in progress are
+// slowed down (or sped up) right away.
+//
+// @name Downloads.SetThrottle
+// @category Downloads
+// @caller client
+type DownloadsSetThrottleParams struct {
+	// Most bytes per second all downloads may go at.
+	// Zero means unlimited.
+	MaxBytesPerSecond int64 `json:"maxBytesPerSecond"`
+}
+
+func (p DownloadsSetThrottleParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.MaxBytesPerSecond, validation.Min(0)),
+	)
+}
+
+type DownloadsSetThrottleResult struct{}
+
+// Pauses a download, without discarding it. If it's being performed
+// by @@DownloadsDriveParams, it stops shortly after, keeping what was
+// downloaded so far, and the drive moves on to the next download.
+//
+// @name Downloads.Pause
+// @category Downloads
+// @caller client
+type DownloadsPauseParams struct {
+	DownloadID string `json:"downloadId"`
+}
+
+func (p DownloadsPauseParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.DownloadID, validation.Required),
+	)
+}
+
+type DownloadsPauseResult struct{}
+
+// Resumes a download paused with @@DownloadsPauseParams. It picks
+// up from where it stopped, next time @@DownloadsDriveParams gets to it.
+//
+// @name Downloads.Resume
+// @category Downloads
+// @caller client
+type DownloadsResumeParams struct {
+	DownloadID string `json:"downloadId"`
+}
+
+func (p DownloadsResumeParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.DownloadID, validation.Required),
+	)
 }
 
+type DownloadsResumeResult struct{}
+
 // Retries a download that has errored
 //
 // @name Downloads.Retry
@@ -2146,6 +4697,160 @@ func (p DownloadsDiscardParams) Validate() error {
 
 type DownloadsDiscardResult struct{}
 
+// Get per-chunk transfer metrics for a download, recorded while
+// it was being driven. Only the last 1000 chunks are kept, and
+// they are not persisted: they're lost when butler exits.
+//
+// @name Downloads.GetHistory
+// @category Downloads
+// @caller client
+// @readonly
+type DownloadsGetHistoryParams struct {
+	DownloadID string `json:"downloadId"`
+}
+
+func (p DownloadsGetHistoryParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.DownloadID, validation.Required),
+	)
+}
+
+type DownloadsGetHistoryResult struct {
+	// Chunks transferred, oldest first
+	Chunks []*DownloadChunk `json:"chunks"`
+	// Standard deviation of chunk transfer rates, relative to
+	// their mean: 0.5 means rates typically stray 50% from the
+	// average. Higher means a less stable connection.
+	// Only set once enough chunks were transferred.
+	//
+	// @optional
+	Jitter *float64 `json:"jitter,omitempty"`
+}
+
+// Metrics for the transfer of (at most) 1MiB of a download
+//
+// @category Downloads
+type DownloadChunk struct {
+	StartTime        time.Time `json:"startTime"`
+	EndTime          time.Time `json:"endTime"`
+	BytesTransferred int64     `json:"bytesTransferred"`
+
+	// IP of the server that sent the chunk, if known
+	ServerIP string `json:"serverIp"`
+	// How long the TLS handshake of the request that carried this chunk
+	// took, in milliseconds. 0 if the connection was re-used, or not TLS
+	TLSHandshakeDurationMs int64 `json:"tlsHandshakeDurationMs"`
+	// Time to first byte of the request that carried this chunk, in milliseconds
+	TTFBMs int64 `json:"ttfbMs"`
+}
+
+// Get live stats on the network connection of the download being
+// driven, to tell CDN issues from TCP window scaling or local network
+// problems. They're sampled every 2 seconds.
+//
+// @name Downloads.GetNetworkStats
+// @category Downloads
+// @caller client
+// @readonly
+type DownloadsGetNetworkStatsParams struct {
+	DownloadID string `json:"downloadId"`
+}
+
+func (p DownloadsGetNetworkStatsParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.DownloadID, validation.Required),
+	)
+}
+
+type DownloadsGetNetworkStatsResult struct {
+	// Not set if the download isn't being driven, or
+	// hasn't been sampled yet.
+	//
+	// @optional
+	Stats *DownloadNetworkStats `json:"stats,omitempty"`
+}
+
+// State of the connection a download is going through
+//
+// @category Downloads
+type DownloadNetworkStats struct {
+	// When these stats were sampled
+	SampledAt time.Time `json:"sampledAt"`
+	// Local address of the connection, "ip:port"
+	LocalAddr string `json:"localAddr"`
+	// Address of the server, "ip:port"
+	RemoteAddr string `json:"remoteAddr"`
+
+	// False if TCP stats aren't available on this platform
+	// (they are on Linux), in which case the fields below are 0.
+	TCPInfoAvailable bool `json:"tcpInfoAvailable"`
+	// Receive window, in bytes
+	TCPWindowSize int64 `json:"tcpWindowSize"`
+	// Bytes sent but not acknowledged yet
+	BytesInFlight int64 `json:"bytesInFlight"`
+	// Segments retransmitted since the connection was opened
+	RetransmitCount int64 `json:"retransmitCount"`
+	// Smoothed round-trip time, in milliseconds
+	RTTMs int64 `json:"rttMs"`
+}
+
+// Get the transfer speed of a download, sampled every second while
+// it's being driven, to draw a speed graph. Only the last 300 samples
+// are kept, and they are not persisted: they're lost when butler exits.
+//
+// @name Downloads.GetSpeedHistory
+// @category Downloads
+// @caller client
+// @readonly
+type DownloadsGetSpeedHistoryParams struct {
+	DownloadID string `json:"downloadId"`
+
+	// How many seconds of history to return, at most 300 (the default)
+	//
+	// @optional
+	LastNSeconds int64 `json:"lastNSeconds,omitempty"`
+}
+
+func (p DownloadsGetSpeedHistoryParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.DownloadID, validation.Required),
+		validation.Field(&p.LastNSeconds, validation.Min(0), validation.Max(DownloadsSpeedHistoryMaxSeconds)),
+	)
+}
+
+// How many seconds of speed history are kept per download
+const DownloadsSpeedHistoryMaxSeconds = 300
+
+type DownloadsGetSpeedHistoryResult struct {
+	// Speed samples, oldest first, one per second the download
+	// was driven
+	Samples []*DownloadSpeedSample `json:"samples"`
+	// Highest speed of the samples, in bytes per second
+	PeakSpeedBPS int64 `json:"peakSpeedBps"`
+	// Average speed of the samples, in bytes per second
+	AverageSpeedBPS int64 `json:"averageSpeedBps"`
+}
+
+// How fast a download went during the second before Timestamp
+//
+// @category Downloads
+type DownloadSpeedSample struct {
+	Timestamp time.Time `json:"timestamp"`
+	// In bytes per second
+	SpeedBPS int64 `json:"speedBps"`
+}
+
+// Sent during @@DownloadsDriveParams when the transfer rate of the
+// current download is unstable, see @@DownloadsGetHistoryParams.
+// It's sent again only if jitter goes back down, then up again.
+//
+// @name Downloads.Drive.JitterHigh
+type DownloadJitterHighNotification struct {
+	Download *Download `json:"download"`
+	// Current jitter, above 0.5
+	Jitter float64 `json:"jitter"`
+}
+
 //----------------------------------------------------------------------
 // CheckUpdate
 //----------------------------------------------------------------------
@@ -2212,6 +4917,15 @@ type GameUpdate struct {
 
 	// Available choice of updates
 	Choices []*GameUpdateChoice `json:"choices"`
+
+	// What @@CaveUpdateBatchParams would do with this update
+	AutoUpdatePolicy CaveAutoUpdatePolicy `json:"autoUpdatePolicy"`
+
+	// True if the installed upload was deleted by the developer, and
+	// the choices are uploads that look like its replacement. Installing
+	// one is effectively a reinstall, so the client must ask the user and
+	// report their answer with @@ConfirmUploadSuccessorParams first.
+	UploadReplaced bool `json:"uploadReplaced"`
 }
 
 // One possible upload/build choice to upgrade a cave
@@ -2224,30 +4938,237 @@ type GameUpdateChoice struct {
 	Build *itchio.Build `json:"build"`
 	// How confident we are that this is the right upgrade
 	Confidence float64 `json:"confidence"`
+	// True if this upload is a possible successor to an installed
+	// upload that doesn't exist anymore, see @@GameUpdate
+	UploadReplaced bool `json:"uploadReplaced"`
+	// True if the user already accepted this successor with
+	// @@ConfirmUploadSuccessorParams, so it can be installed
+	// without asking again.
+	Confirmed bool `json:"confirmed"`
+}
+
+// Sent during @@CheckUpdateParams when a cave's game has been gone from
+// itch.io for long enough that it was likely deleted by its developer
+// (several checks in a row over a week, so temporary takedowns don't
+// count). It's only sent once per cave.
+//
+// From then on, the cave is flagged `gameDelisted` in its install info,
+// and isn't checked for updates, only for whether the game comes back,
+// in which case the flag is cleared.
+//
+// Clients should ask the user whether to keep the install (it keeps
+// working, and there's nothing else to do) or to uninstall it with
+// @@UninstallPerformParams.
+//
+// @category Update
+type GameDelistedNotification struct {
+	Cave *Cave `json:"cave"`
+}
+
+// Records the user's answer to an update flagged with `uploadReplaced`,
+// so it isn't asked again on every @@CheckUpdateParams.
+//
+// Once a successor is accepted, it's offered as the only (confirmed)
+// choice for as long as it exists. Once declined, replacement uploads
+// aren't offered for this cave anymore.
+//
+// @category Update
+// @caller client
+type ConfirmUploadSuccessorParams struct {
+	// The cave whose upload was replaced
+	CaveID string `json:"caveId"`
+
+	// The successor the user picked, or 0 if they don't want to
+	// switch to any of the suggested uploads.
+	// @optional
+	UploadID int64 `json:"uploadId"`
+}
+
+func (p ConfirmUploadSuccessorParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+	)
+}
+
+type ConfirmUploadSuccessorResult struct {
+}
+
+// Snoozing a cave means we ignore all new uploads (that would
+// be potential updates) between the cave's last install operation
+// and now.
+//
+// This can be undone by calling @@CheckUpdateParams with this specific
+// cave identifier.
+//
+// @category Update
+// @caller client
+type SnoozeCaveParams struct {
+	CaveID string `json:"caveId"`
+}
+
+func (p SnoozeCaveParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+	)
+}
+
+type SnoozeCaveResult struct {
+}
+
+// Looks for updates to several caves and installs them, a few caves
+// at a time. Returns as soon as the batch is queued: use
+// @@CaveBatchStatusParams to follow it, or wait for
+// @@BatchUpdateCompleteNotification.
+//
+// Snooze is ignored. Caves whose upload was replaced and whose
+// successor wasn't confirmed (see @@GameUpdate) are skipped, and so
+// are caves whose @@CaveAutoUpdatePolicy doesn't allow installing
+// the update right now.
+//
+// @category Update
+// @caller client
+type CaveUpdateBatchParams struct {
+	// Caves to update
+	CaveIDs []string `json:"caveIds"`
+
+	// How many caves to update at the same time. Defaults to 1,
+	// and can't be more than butler's own limit (4).
+	// @optional
+	MaxConcurrent int64 `json:"maxConcurrent"`
+}
+
+func (p CaveUpdateBatchParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveIDs, validation.Required),
+		validation.Field(&p.MaxConcurrent, validation.Min(0)),
+	)
+}
+
+type CaveUpdateBatchResult struct {
+	// Identifier to pass to @@CaveBatchStatusParams
+	BatchID string `json:"batchId"`
+}
+
+// Returns the progress of a batch started by @@CaveUpdateBatchParams
+// or @@CaveUninstallBatchParams. Batches are forgotten 10 minutes
+// after they're done.
+//
+// @category Update
+// @caller client
+type CaveBatchStatusParams struct {
+	BatchID string `json:"batchId"`
 }
 
-// Snoozing a cave means we ignore all new uploads (that would
-// be potential updates) between the cave's last install operation
-// and now.
-//
-// This can be undone by calling @@CheckUpdateParams with this specific
-// cave identifier.
+func (p CaveBatchStatusParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.BatchID, validation.Required),
+	)
+}
+
+type CaveBatchStatusResult struct {
+	// State of each cave in the batch, in the order they were passed
+	Caves []*CaveBatchItem `json:"caves"`
+	// Overall progress of the batch, between 0 and 1
+	Progress float64 `json:"progress"`
+	// True once all caves are done (updated, skipped or failed)
+	Done bool `json:"done"`
+}
+
+// Sent during @@CaveUpdateBatchParams when an update was found for a
+// cave whose policy is @@CaveAutoUpdatePolicyAsk. The update is only
+// installed if the client accepts it. Sent on the connection that
+// started the batch.
 //
+// @name UpdateAvailableAskUser
 // @category Update
-// @caller client
-type SnoozeCaveParams struct {
+// @caller server
+type UpdateAvailableAskUserParams struct {
+	// Cave that can be updated
 	CaveID string `json:"caveId"`
+
+	// Game the cave is for
+	Game *itchio.Game `json:"game"`
+
+	// Update that would be installed
+	Choice *GameUpdateChoice `json:"choice"`
 }
 
-func (p SnoozeCaveParams) Validate() error {
+func (p UpdateAvailableAskUserParams) Validate() error {
 	return validation.ValidateStruct(&p,
 		validation.Field(&p.CaveID, validation.Required),
+		validation.Field(&p.Choice, validation.Required),
 	)
 }
 
-type SnoozeCaveResult struct {
+type UpdateAvailableAskUserResult struct {
+	// True if the update should be installed
+	Accept bool `json:"accept"`
+}
+
+// Sent during @@CaveUpdateBatchParams when all its caves are done.
+// Sent on the connection that started the batch, after the
+// @@CaveUpdateBatchParams request has returned.
+//
+// @category Update
+type BatchUpdateCompleteNotification struct {
+	BatchID string `json:"batchId"`
+	// Final state of each cave in the batch
+	Caves []*CaveBatchItem `json:"caves"`
+}
+
+// State of one cave in a batch started by @@CaveUpdateBatchParams
+// or @@CaveUninstallBatchParams
+//
+// @category Update
+type CaveBatchItem struct {
+	CaveID string `json:"caveId"`
+
+	State CaveBatchItemState `json:"state"`
+
+	// Progress of the current step, between 0 and 1
+	Progress float64 `json:"progress"`
+
+	// Upload the cave was updated to, if any
+	// @optional
+	Upload *itchio.Upload `json:"upload,omitempty"`
+	// Build the cave was updated to, if any
+	// @optional
+	Build *itchio.Build `json:"build,omitempty"`
+
+	// Why the cave was skipped, or the error message if it failed
+	// @optional
+	Error string `json:"error,omitempty"`
+	// butlerd error code, if the cave failed with one
+	// @optional
+	ErrorCode int64 `json:"errorCode,omitempty"`
 }
 
+// Where a cave is at in a batch, see @@CaveBatchItem
+//
+// @category Update
+type CaveBatchItemState string
+
+const (
+	// Waiting for its turn
+	CaveBatchItemStateQueued CaveBatchItemState = "queued"
+	// Looking for an update
+	CaveBatchItemStateChecking CaveBatchItemState = "checking"
+	// Downloading and installing the update
+	CaveBatchItemStateInstalling CaveBatchItemState = "installing"
+	// An update was installed
+	CaveBatchItemStateUpdated CaveBatchItemState = "updated"
+	// No update was available
+	CaveBatchItemStateUpToDate CaveBatchItemState = "up-to-date"
+	// An update was available but needs the user's input
+	CaveBatchItemStateSkipped CaveBatchItemState = "skipped"
+	// Checking or installing failed
+	CaveBatchItemStateFailed CaveBatchItemState = "failed"
+	// Being uninstalled
+	CaveBatchItemStateUninstalling CaveBatchItemState = "uninstalling"
+	// The cave was removed
+	CaveBatchItemStateUninstalled CaveBatchItemState = "uninstalled"
+)
+
 //----------------------------------------------------------------------
 // Launch
 //----------------------------------------------------------------------
@@ -2283,6 +5204,100 @@ func (p LaunchParams) Validate() error {
 type LaunchResult struct {
 }
 
+// Install the prerequisites of an installed game, without launching
+// it. Mostly useful for caves installed with `skipPrereqs`, see
+// @@InstallQueueParams: once this succeeds, launching them installs
+// prerequisites as usual again.
+//
+// Prerequisites are those of the launch target @@LaunchParams would
+// use, and only exist on Windows. Progress is reported the same way
+// as when launching, see @@PrereqsStartedNotification.
+//
+// @name Install.Prereqs
+// @category Install
+// @caller client
+type InstallPrereqsParams struct {
+	// The ID of the cave to install prerequisites for
+	CaveID string `json:"caveId"`
+
+	// The directory to use to store installer files for prerequisites
+	PrereqsDir string `json:"prereqsDir"`
+
+	// Install all prerequisites, even if they're already marked as installed
+	// @optional
+	Force bool `json:"force,omitempty"`
+}
+
+func (p InstallPrereqsParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+		validation.Field(&p.PrereqsDir, validation.Required),
+	)
+}
+
+type InstallPrereqsResult struct {
+}
+
+// Resolves launch targets (manifest actions, candidates, strategies)
+// for caves ahead of time, and stores them, so that @@LaunchParams
+// doesn't have to do it at click time.
+//
+// A stored plan is used by @@LaunchParams as long as the cave's receipt,
+// app manifest, upload type and available hosts (wine etc.) haven't
+// changed, and its targets still exist on disk. Otherwise, targets are
+// resolved again.
+//
+// @name Launch.PrecomputePlans
+// @category Launch
+// @caller client
+type LaunchPrecomputePlansParams struct {
+	// If set, only precompute plans for caves matching this
+	// expression, see @@CavesFilterParams.
+	// @optional
+	Filter string `json:"filter"`
+
+	// If set, resolve targets again even if the stored plan
+	// still looks valid.
+	// @optional
+	Force bool `json:"force"`
+}
+
+func (p LaunchPrecomputePlansParams) Validate() error {
+	return nil
+}
+
+type LaunchPrecomputePlansResult struct {
+	// One entry per cave considered
+	Plans []*LaunchPlanStatus `json:"plans"`
+}
+
+// Whether a cave's launch plan was precomputed, see @@LaunchPrecomputePlansParams
+//
+// @category Launch
+type LaunchPlanStatus struct {
+	CaveID string `json:"caveId"`
+
+	// True if a plan is ready for this cave
+	OK bool `json:"ok"`
+
+	// True if the stored plan was still valid, and was kept as-is
+	Reused bool `json:"reused"`
+
+	// Strategies of the launch targets found, in order. A cave with
+	// only a `shell` strategy has nothing to run, just a folder to open.
+	// @optional
+	Strategies []LaunchStrategy `json:"strategies,omitempty"`
+
+	// Why no plan could be computed (missing install folder,
+	// broken manifest, etc.)
+	// @optional
+	Error string `json:"error,omitempty"`
+
+	// Standard butlerd error code, if any
+	// @optional
+	ErrorCode int64 `json:"errorCode,omitempty"`
+}
+
 // Sent during @@LaunchParams, when the game is configured, prerequisites are installed
 // sandbox is set up (if enabled), and the game is actually running.
 //
@@ -2524,13 +5539,16 @@ type PrereqsFailedResult struct {
 
 // Look for folders we can clean up in various download folders.
 // This finds anything that doesn't correspond to any current downloads
-// we know about.
+// we know about, or that an install is still working in.
 //
 // @name CleanDownloads.Search
 // @category Clean Downloads
 // @caller client
+// @readonly
 type CleanDownloadsSearchParams struct {
-	// A list of folders to scan for potential subfolders to clean up
+	// A list of folders to scan for potential subfolders to clean up.
+	// The staging roots of install locations that keep their staging
+	// data elsewhere are always scanned as well.
 	Roots []string `json:"roots"`
 	// A list of subfolders to not consider when cleaning
 	// (staging folders for in-progress downloads)
@@ -2558,6 +5576,8 @@ type CleanDownloadsEntry struct {
 }
 
 // Remove the specified entries from disk, freeing up disk space.
+// Entries an install started working in since they were found
+// are left alone.
 //
 // @name CleanDownloads.Apply
 // @category Clean Downloads
@@ -2584,6 +5604,7 @@ type CleanDownloadsApplyResult struct{}
 // @name System.StatFS
 // @category System
 // @caller client
+// @readonly
 type SystemStatFSParams struct {
 	Path string `json:"path"`
 }
@@ -2599,6 +5620,403 @@ type SystemStatFSResult struct {
 	TotalSize int64 `json:"totalSize"`
 }
 
+// Get internal statistics about the daemon, for example
+// the state of the API rate limiter.
+//
+// @name System.Stats
+// @category System
+// @caller client
+// @readonly
+type SystemStatsParams struct{}
+
+func (p SystemStatsParams) Validate() error {
+	return nil
+}
+
+type SystemStatsResult struct {
+	RateLimiter *RateLimiterStats `json:"rateLimiter"`
+	Connections *ConnectionStats  `json:"connections"`
+}
+
+// What happened to the connections made to the daemon since it started
+//
+// @category System
+type ConnectionStats struct {
+	// Connections that made it past the peer checks
+	Accepted int64 `json:"accepted"`
+	// Connections closed by the peer checks, and failed authentications
+	Rejected int64 `json:"rejected"`
+	// Requests that failed because the connection was idle for too long
+	Expired int64 `json:"expired"`
+	// Requests that failed because of the connection's permission level
+	Denied int64 `json:"denied"`
+
+	// The last few rejections, oldest first
+	RecentRejections []*ConnectionRejection `json:"recentRejections"`
+}
+
+// A connection attempt the daemon turned away
+//
+// @category System
+type ConnectionRejection struct {
+	Time time.Time `json:"time"`
+	// Why it was rejected
+	Reason string `json:"reason"`
+	// Address the connection came from
+	RemoteAddress string `json:"remoteAddress"`
+	// Process that made the connection, if it could be found
+	//
+	// @optional
+	PID int64 `json:"pid,omitempty"`
+	// Executable of that process, if it could be found
+	//
+	// @optional
+	Executable string `json:"executable,omitempty"`
+}
+
+// State of the process-wide itch.io API rate limiter. All API
+// calls go through it: interactive calls go first, background
+// calls (update checks, collection syncs) get spaced out when
+// the server asks us to slow down.
+//
+// @category System
+type RateLimiterStats struct {
+	// Total number of API requests sent
+	Requests int64 `json:"requests"`
+	// Number of times the server responded with 429
+	RateLimited int64 `json:"rateLimited"`
+	// Number of requests that were retried after a 429
+	Retries int64 `json:"retries"`
+	// Number of 429 responses that could not be retried
+	Failures int64 `json:"failures"`
+
+	// Interactive calls currently waiting for their turn
+	WaitingInteractive int64 `json:"waitingInteractive"`
+	// Background calls currently waiting for their turn
+	WaitingBackground int64 `json:"waitingBackground"`
+
+	// Request limit reported by the server, -1 if unknown
+	Limit int64 `json:"limit"`
+	// Remaining requests reported by the server, -1 if unknown
+	Remaining int64 `json:"remaining"`
+	// When the server's rate limit window resets
+	//
+	// @optional
+	ResetAt *time.Time `json:"resetAt,omitempty"`
+	// If set, no API calls are sent until then
+	//
+	// @optional
+	PausedUntil *time.Time `json:"pausedUntil,omitempty"`
+	// Current minimum delay between two background calls, in milliseconds
+	BackgroundSpacingMs int64 `json:"backgroundSpacingMs"`
+}
+
+// Write a zip file with everything support usually needs to look
+// into a bug report: recent logs, a timeline of caves and downloads,
+// profiles and install locations, version information and the
+// environment the daemon runs in.
+//
+// API keys, and the query strings of any URLs (which may contain
+// signed download credentials) are redacted.
+//
+// @name System.ExportDiagnostics
+// @category System
+// @caller client
+type SystemExportDiagnosticsParams struct {
+	// Where to write the zip file. It is overwritten if it exists
+	Path string `json:"path"`
+}
+
+func (p SystemExportDiagnosticsParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Path, validation.Required),
+	)
+}
+
+type SystemExportDiagnosticsResult struct {
+	// Path of the zip file that was written
+	Path string `json:"path"`
+	// Names of the files included in the zip
+	Files []string `json:"files"`
+}
+
+// Returns the last log messages of the daemon, from all requests
+// and background tasks, for support to look at while debugging.
+//
+// Like every other request, this is only available to clients that
+// authenticated with the daemon's secret.
+//
+// @name System.LogDump
+// @category System
+// @tags Offline
+// @caller client
+type SystemLogDumpParams struct {
+	// How many messages to return, at most 10000
+	Lines int64 `json:"lines"`
+}
+
+func (p SystemLogDumpParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Lines, validation.Required, validation.Min(1), validation.Max(SystemLogDumpMaxLines)),
+	)
+}
+
+// How many messages the daemon remembers, see @@SystemLogDumpParams
+const SystemLogDumpMaxLines = 10000
+
+type SystemLogDumpResult struct {
+	// The messages, oldest first. There may be fewer than
+	// requested, if the daemon hasn't logged that much yet.
+	Entries []*LogEntry `json:"entries"`
+}
+
+// A message logged by the daemon
+//
+// @category System
+type LogEntry struct {
+	// When the message was logged
+	Timestamp time.Time `json:"timestamp"`
+	// Level of the message (debug, info, warning, error)
+	Level string `json:"level"`
+	// Method of the request the message was logged for,
+	// or `router` for background tasks
+	Module  string `json:"module"`
+	Message string `json:"message"`
+	// Context about the message, like the `requestId`
+	// @optional
+	Fields map[string]interface{} `json:"fields,omitempty"`
+}
+
+// Import games installed by the legacy itch app, or unpacked by hand,
+// from a folder butlerd doesn't know about yet.
+//
+// Every folder inside `rootPath` (or inside `rootPath/apps`, for legacy
+// install locations) is looked at: the ones with a receipt, either from
+// butler or from the legacy app, become caves, looking up their game,
+// upload and build on itch.io.
+//
+// The import can be interrupted and called again with the same
+// parameters: folders that were already dealt with are reported
+// as they were, and aren't looked at again.
+//
+// @name System.ImportLegacyLibrary
+// @category System
+// @caller client
+type SystemImportLegacyLibraryParams struct {
+	// Absolute path of the folder to import games from
+	RootPath string `json:"rootPath"`
+
+	// What to do with the folders of imported games
+	Mode LegacyLibraryImportMode `json:"mode"`
+
+	// Install location to move folders to, required
+	// if `mode` is "move"
+	//
+	// @optional
+	InstallLocationID string `json:"installLocationId"`
+}
+
+func (p SystemImportLegacyLibraryParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.RootPath, validation.Required),
+		validation.Field(&p.Mode, validation.Required, validation.In(LegacyLibraryImportModeMove, LegacyLibraryImportModeInPlace)),
+	)
+}
+
+type SystemImportLegacyLibraryResult struct {
+	// One item per folder found in the root path
+	Items []*LegacyLibraryImportItem `json:"items"`
+}
+
+// @category System
+type LegacyLibraryImportMode string
+
+const (
+	// Move game folders into an install location. It only works if
+	// they're on the same partition.
+	LegacyLibraryImportModeMove LegacyLibraryImportMode = "move"
+	// Leave game folders where they are, as custom install folders
+	LegacyLibraryImportModeInPlace LegacyLibraryImportMode = "inPlace"
+)
+
+// @category System
+type LegacyLibraryImportItem struct {
+	// Absolute path of the folder, as found in the root path
+	Folder string `json:"folder"`
+	// What happened to it
+	Status LegacyLibraryImportStatus `json:"status"`
+	// Why it wasn't imported, or why it needs verification
+	//
+	// @optional
+	Message string `json:"message,omitempty"`
+	// Set if the folder was imported
+	//
+	// @optional
+	CaveID string `json:"caveId,omitempty"`
+	// Where the game is installed now, if it was imported
+	//
+	// @optional
+	InstallFolder string `json:"installFolder,omitempty"`
+}
+
+// @category System
+type LegacyLibraryImportStatus string
+
+const (
+	// The folder is now a cave
+	LegacyLibraryImportStatusImported LegacyLibraryImportStatus = "imported"
+	// The folder is now a cave, but either its build or the list of
+	// its files is unknown: it should be verified (by installing over it)
+	// before it's updated.
+	LegacyLibraryImportStatusNeedsVerification LegacyLibraryImportStatus = "needsVerification"
+	// The folder has no receipt, or it refers to a game or upload that
+	// doesn't exist anymore. Clients may offer to adopt it manually.
+	LegacyLibraryImportStatusUnrecognized LegacyLibraryImportStatus = "unrecognized"
+	// Something went wrong, importing again will try again
+	LegacyLibraryImportStatusFailed LegacyLibraryImportStatus = "failed"
+)
+
+// Get the itch.io API calls made during the last attempt of a
+// download, if it failed. Only the last 50 calls are kept.
+//
+// URLs and response bodies are scrubbed of API keys, cookies,
+// signed download credentials and email addresses, and bodies
+// are truncated. Nothing is kept for attempts that succeed.
+//
+// @name System.GetOperationDiagnostics
+// @category System
+// @caller client
+// @readonly
+type SystemGetOperationDiagnosticsParams struct {
+	// ID of the download to get diagnostics for
+	DownloadID string `json:"downloadId"`
+}
+
+func (p SystemGetOperationDiagnosticsParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.DownloadID, validation.Required),
+	)
+}
+
+type SystemGetOperationDiagnosticsResult struct {
+	// Set if the download's last attempt failed, and
+	// its staging folder is still around
+	//
+	// @optional
+	Diagnostics *OperationDiagnostics `json:"diagnostics,omitempty"`
+}
+
+// What happened during an operation that failed
+//
+// @category System
+type OperationDiagnostics struct {
+	// When the operation failed
+	FailedAt time.Time `json:"failedAt"`
+	// The error the operation failed with, scrubbed
+	Error string `json:"error"`
+	// API calls made during the operation, oldest first
+	Calls []*APICallRecord `json:"calls"`
+	// Number of older calls that didn't fit
+	DroppedCalls int64 `json:"droppedCalls"`
+}
+
+// A scrubbed record of a call to the itch.io API
+//
+// @category System
+type APICallRecord struct {
+	// When the call was made
+	StartedAt time.Time `json:"startedAt"`
+	// HTTP method, like `GET`
+	Method string `json:"method"`
+	// URL, with credentials scrubbed
+	URL string `json:"url"`
+	// HTTP status, 0 if no response was received
+	Status int64 `json:"status"`
+	// Time until the whole response was received, in milliseconds
+	LatencyMs int64 `json:"latencyMs"`
+	// Set if no response was received
+	//
+	// @optional
+	Error string `json:"error,omitempty"`
+	// Response body, scrubbed and truncated
+	//
+	// @optional
+	Body string `json:"body,omitempty"`
+	// True if the body was cut short
+	BodyTruncated bool `json:"bodyTruncated"`
+}
+
+// Describe the environment the daemon runs in: platform, well-known
+// folders, hardware and process details. Meant to be attached to bug
+// reports, it is also part of @@SystemExportDiagnosticsParams.
+//
+// Only a few well-known environment variables are included,
+// like `PATH`, `LANG` or `HTTPS_PROXY`.
+//
+// @name System.EnvironmentInfo
+// @category System
+// @tags Offline
+// @caller client
+// @readonly
+type SystemEnvironmentInfoParams struct{}
+
+func (p SystemEnvironmentInfoParams) Validate() error {
+	return nil
+}
+
+type SystemEnvironmentInfoResult struct {
+	Environment *ButlerEnvironmentInfo `json:"environment"`
+}
+
+// The environment the daemon runs in, see @@SystemEnvironmentInfoParams
+//
+// @category System
+type ButlerEnvironmentInfo struct {
+	// Operating system, like `linux` or `windows`
+	OS string `json:"os"`
+	// CPU architecture, like `amd64` or `386`
+	Arch string `json:"arch"`
+	// Version of Go butler was built with, like `go1.14.4`
+	GoVersion string `json:"goVersion"`
+
+	// Where user-specific cache files go. Only set where
+	// the XDG base directory spec applies, or if overridden
+	// with `XDG_CACHE_HOME`.
+	//
+	// @optional
+	XDGCacheHome string `json:"xdgCacheHome,omitempty"`
+	// Where user-specific data files go, see xdgCacheHome
+	//
+	// @optional
+	XDGDataHome string `json:"xdgDataHome,omitempty"`
+	// Where user-specific configuration files go, see xdgCacheHome
+	//
+	// @optional
+	XDGConfigHome string `json:"xdgConfigHome,omitempty"`
+	// Folder used for temporary files
+	TempDir string `json:"tempDir"`
+	// Home folder of the user running butler, empty if unknown
+	HomeDir string `json:"homeDir"`
+
+	// Number of logical CPUs usable by butler
+	NumCPU int64 `json:"numCpu"`
+	// Physical memory installed, -1 if unknown
+	TotalRAMBytes int64 `json:"totalRamBytes"`
+
+	// Identifier of the daemon process
+	ProcessID int64 `json:"processId"`
+	// Full path to the butler executable, empty if unknown
+	ExecutablePath string `json:"executablePath"`
+	// Working directory of the daemon process, empty if unknown
+	WorkingDir string `json:"workingDir"`
+
+	// Well-known environment variables of the daemon process
+	// (locale, search path, temporary and XDG folders, proxies),
+	// sorted by name, as `NAME=value`. Other variables are left
+	// out, since they may hold credentials.
+	Variables []string `json:"variables"`
+}
+
 //----------------------------------------------------------------------
 // Misc.
 //----------------------------------------------------------------------
@@ -2689,12 +6107,61 @@ const (
 	// An operation was aborted by the user
 	CodeOperationAborted Code = 410
 
-	// We tried to launch something, but the install folder just wasn't there
+	// We tried to launch something, but the install folder just wasn't there.
+	// Folders the cave may have been moved to are in the error's data, as
+	// `candidates` (see @@MovedCaveCandidate). With the `caves.autoRelink`
+	// setting, a cave with a single, exact candidate is relinked instead.
 	CodeInstallFolderDisappeared Code = 404
 
-	// We tried to install something, but could not find compatible uploads
+	// We tried to install something, but could not find compatible uploads.
+	// Why each upload was left out is in the error's data, as `rejections`
+	// (see @@UploadRejection).
 	CodeNoCompatibleUploads Code = 2001
 
+	// We tried to install something, but some of its files can't be
+	// created on this platform (invalid characters, reserved names...).
+	// The offending entries are listed in the error's data, as `offenders`.
+	// With the `install.sanitizeFilenames` setting, entries of zip archives
+	// are installed under a sanitized name instead.
+	CodeUnsafeFilenames Code = 2002
+
+	// We tried to update a cave whose upload was deleted to a replacement
+	// upload, but the user hasn't confirmed it with @@ConfirmUploadSuccessorParams
+	CodeUploadSuccessorNotConfirmed Code = 2003
+
+	// We tried to install a game that was pre-ordered but isn't out
+	// yet. If known, the release date is in the error's data, as `releaseDate`.
+	CodeGameNotYetReleased Code = 2004
+
+	// We tried to install something, but the install location doesn't
+	// have enough free space for it. How many bytes we think are needed,
+	// and how many are available, are in the error's data, as `required`
+	// and `available`. If other install locations were tried, how short
+	// each of them is, is in `locations`.
+	CodeNotEnoughSpace Code = 2005
+
+	// We tried to install a specific build, but it doesn't exist,
+	// or belongs to a different upload
+	CodeBuildNotFound Code = 2006
+
+	// We tried to install something, but all the install folder names
+	// we tried were taken, see @@InstallQueueParams' uniqueFolderMaxTries
+	CodeInstallFolderExhausted Code = 2007
+
+	// We tried to resume an install in a staging folder that belongs to
+	// another install location, game, upload or cave, see
+	// @@InstallQueueParams' resumeStagingFolder
+	CodeStagingFolderMismatch Code = 2008
+
+	// We tried to install something, but several uploads were compatible,
+	// and none was picked, see @@InstallQueueParams' defaultUploadStrategy.
+	// The compatible uploads are in the error's data, as `uploads`.
+	CodeAmbiguousUpload Code = 2009
+
+	// We tried to open a cave's install folder, but it isn't on disk,
+	// see @@CavesOpenInstallFolderParams
+	CodeInstallFolderMissing Code = 2010
+
 	// This title is hosted on an incompatible third-party website
 	CodeUnsupportedHost Code = 3001
 
@@ -2715,6 +6182,23 @@ const (
 
 	// An install location could not be removed because it has active downloads
 	CodeCantRemoveLocationBecauseOfActiveDownloads Code = 18000
+
+	// An install location could not be removed because caves are still
+	// installed in it, see @@InstallLocationsRemoveParams
+	CodeInstallLocationNotEmpty Code = 18001
+
+	// The connection's permission level doesn't allow that request,
+	// see @@PermissionLevel
+	CodePermissionDenied Code = 19000
+
+	// The connection was idle for too long, and must send
+	// @@MetaAuthenticateParams again
+	CodeAuthenticationExpired Code = 19001
+
+	// A filter expression could not be parsed, or refers to unknown
+	// fields, see @@CavesFilterParams. Where the problem was found is
+	// in the error's data, as `position`.
+	CodeInvalidFilter Code = 20000
 )
 
 // Dates
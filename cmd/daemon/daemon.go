@@ -5,6 +5,8 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"regexp"
+	"time"
 
 	"github.com/itchio/butler/butlerd/horror"
 
@@ -12,11 +14,13 @@ import (
 	"github.com/google/gops/agent"
 	"github.com/google/uuid"
 	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
 	"github.com/itchio/butler/database"
 	"github.com/itchio/headway/state"
 
 	"github.com/itchio/butler/comm"
 	"github.com/itchio/butler/mansion"
+	"github.com/itchio/butler/mansion/settings"
 	"github.com/pkg/errors"
 )
 
@@ -25,6 +29,20 @@ var args = struct {
 	transport   string
 	keepAlive   bool
 	log         bool
+
+	mockTransfers      bool
+	mockTransfersSpeed int64
+
+	externalHostPatterns []string
+
+	allowExes       []string
+	allowParentTree bool
+	idleTimeout     time.Duration
+	permissionLevel string
+
+	service       string
+	serviceName   string
+	handshakeFile string
 }{}
 
 func Register(ctx *mansion.Context) {
@@ -33,10 +51,42 @@ func Register(ctx *mansion.Context) {
 	cmd.Flag("transport", "Which transport to use").Default("tcp").EnumVar(&args.transport, "http", "tcp")
 	cmd.Flag("keep-alive", "Accept multiple TCP connections, stay up until killed or a destiny PID shuts down").BoolVar(&args.keepAlive)
 	cmd.Flag("log", "Log all requests to stderr").BoolVar(&args.log)
+	cmd.Flag("mock-transfers", "Simulate downloads and extractions instead of performing them, for end-to-end tests").Hidden().BoolVar(&args.mockTransfers)
+	cmd.Flag("mock-transfers-speed", "Speed of simulated transfers, in bytes per second").Hidden().Default("10485760").Int64Var(&args.mockTransfersSpeed)
+	cmd.Flag("external-host-pattern", "Regular expression for hosts of external uploads we can't download from, in addition to the built-in ones").StringsVar(&args.externalHostPatterns)
+	cmd.Flag("allow-exe", "Only accept connections from processes running this executable (or another --allow-exe, or the parent tree if --allow-parent-tree is set)").StringsVar(&args.allowExes)
+	cmd.Flag("allow-parent-tree", "Only accept connections from the process that started the daemon, or its descendants (or an --allow-exe)").BoolVar(&args.allowParentTree)
+	cmd.Flag("idle-timeout", "Connections that haven't sent a request in that long must authenticate again").DurationVar(&args.idleTimeout)
+	cmd.Flag("permission-level", "What connections are allowed to call").Default(string(butlerd.PermissionLevelFull)).EnumVar(&args.permissionLevel,
+		string(butlerd.PermissionLevelFull), string(butlerd.PermissionLevelDownloadsOnly), string(butlerd.PermissionLevelReadOnly))
+	cmd.Flag("service", "Register butlerd as a Windows service (install, uninstall), or run as a service (run), under the Windows service manager or systemd").EnumVar(&args.service, "install", "uninstall", "run")
+	cmd.Flag("service-name", "Name of the Windows service, and of its event log source").Default(defaultServiceName).StringVar(&args.serviceName)
+	cmd.Flag("handshake-file", "Write the address and secret clients need to connect to that file, readable by its owner only, instead of just printing them").StringVar(&args.handshakeFile)
 	ctx.Register(cmd, do)
 }
 
 func do(ctx *mansion.Context) {
+	switch args.service {
+	case "install":
+		ctx.Must(installService(ctx))
+		return
+	case "uninstall":
+		ctx.Must(uninstallService())
+		return
+	case "run":
+		if args.handshakeFile == "" {
+			comm.Dief("--service run needs --handshake-file: there's no console to print the handshake to")
+		}
+		// clients come and go, the service stays up
+		args.keepAlive = true
+		ctx.Must(runService(func() { start(ctx) }))
+		return
+	}
+
+	start(ctx)
+}
+
+func start(ctx *mansion.Context) {
 	if !comm.JsonEnabled() {
 		comm.Notice("Hello from butler daemon", []string{"We can't do anything interesting without --json, bailing out", "", "Learn more: https://docs.itch.ovh/butlerd/master/"})
 		os.Exit(1)
@@ -52,6 +102,28 @@ func do(ctx *mansion.Context) {
 		comm.Warnf("butlerd: Could not start gops agent: %+v", err)
 	}
 
+	if args.mockTransfers {
+		if args.mockTransfersSpeed <= 0 {
+			comm.Dief("--mock-transfers-speed must be positive")
+		}
+		comm.Logf("butlerd: transfers are simulated (%d bytes/s)", args.mockTransfersSpeed)
+		operate.Simulation = &operate.SimulationSettings{
+			BytesPerSecond: args.mockTransfersSpeed,
+		}
+	}
+
+	for _, pattern := range args.externalHostPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			comm.Dief("Invalid --external-host-pattern (%s): %s", pattern, err.Error())
+		}
+		operate.RegisterExternalHostPattern(re)
+	}
+
+	if (len(args.allowExes) > 0 || args.allowParentTree) && !butlerd.PeerChecksSupported {
+		comm.Dief("--allow-exe and --allow-parent-tree are not supported on this platform")
+	}
+
 	for _, destinyPid := range args.destinyPids {
 		go tieDestiny(destinyPid)
 	}
@@ -106,19 +178,66 @@ func Do(mansionContext *mansion.Context, ctx context.Context, dbPool *sqlitex.Po
 	router := GetRouter(dbPool, mansionContext)
 	consumer := comm.NewStateConsumer()
 
+	err := func() (retErr error) {
+		defer horror.RecoverInto(&retErr)
+
+		conn := dbPool.Get(context.Background())
+		defer dbPool.Put(conn)
+		// subscribers are registered along with the endpoints
+		return settings.Load(consumer, conn)
+	}()
+	if err != nil {
+		consumer.Warnf("Could not load settings: %+v", err)
+	}
+
+	if args.service == "run" {
+		onServiceStop(router.Shutdown)
+	}
+
 	switch args.transport {
 	case "tcp":
-		listener, err := net.Listen("tcp", "127.0.0.1:")
-		if err != nil {
-			return err
+		var listener net.Listener
+		if args.service == "run" {
+			listener, err = serviceListener()
+			if err != nil {
+				return err
+			}
+		}
+		if listener == nil {
+			listener, err = net.Listen("tcp", "127.0.0.1:")
+			if err != nil {
+				return err
+			}
 		}
 
-		comm.Object("butlerd/listen-notification", map[string]interface{}{
+		handshake := map[string]interface{}{
 			"secret": secret,
 			"tcp": map[string]interface{}{
 				"address": listener.Addr().String(),
 			},
-		})
+		}
+		// services' stdout may end up anywhere (a log file, the journal),
+		// they only get the handshake file
+		if args.service != "run" {
+			comm.Object("butlerd/listen-notification", handshake)
+		}
+		if args.handshakeFile != "" {
+			err = writeHandshake(args.handshakeFile, handshake)
+			if err != nil {
+				return err
+			}
+			defer os.Remove(args.handshakeFile)
+		}
+		if args.service == "run" {
+			serviceReady()
+		}
+
+		peerPolicy := &butlerd.PeerPolicy{
+			AllowedExecutables: args.allowExes,
+		}
+		if args.allowParentTree {
+			peerPolicy.ParentTreePID = os.Getppid()
+		}
 
 		err = s.ServeTCP(ctx, butlerd.ServeTCPParams{
 			Handler:   router,
@@ -128,6 +247,10 @@ func Do(mansionContext *mansion.Context, ctx context.Context, dbPool *sqlitex.Po
 			Log:       args.log,
 			KeepAlive: args.keepAlive,
 
+			PeerPolicy:      peerPolicy,
+			IdleTimeout:     args.idleTimeout,
+			PermissionLevel: butlerd.PermissionLevel(args.permissionLevel),
+
 			ShutdownChan: router.ShutdownChan,
 		})
 		if err != nil {
@@ -0,0 +1,201 @@
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/itchio/butler/mansion"
+	"github.com/pkg/errors"
+)
+
+const defaultServiceName = "butlerd"
+
+// serviceCommandLine returns the arguments a service manager should
+// start butler with, so it serves like this invocation would
+func serviceCommandLine(ctx *mansion.Context) ([]string, error) {
+	if args.handshakeFile == "" {
+		return nil, errors.New("--handshake-file is required: services have no console to print the handshake to")
+	}
+	handshakeFile, err := filepath.Abs(args.handshakeFile)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	dbPath, err := filepath.Abs(ctx.DBPath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	cl := []string{
+		"--json",
+		"--dbpath", dbPath,
+		"daemon",
+		"--service", "run",
+		"--service-name", args.serviceName,
+		"--handshake-file", handshakeFile,
+		"--permission-level", args.permissionLevel,
+	}
+	for _, exe := range args.allowExes {
+		cl = append(cl, "--allow-exe", exe)
+	}
+	for _, pattern := range args.externalHostPatterns {
+		cl = append(cl, "--external-host-pattern", pattern)
+	}
+	if args.idleTimeout > 0 {
+		cl = append(cl, "--idle-timeout", args.idleTimeout.String())
+	}
+	if args.log {
+		cl = append(cl, "--log")
+	}
+	return cl, nil
+}
+
+// serviceStop is what stopping the service does, once the
+// daemon is far enough along to shut down gracefully
+var serviceStop = struct {
+	sync.Mutex
+	f func()
+}{}
+
+func onServiceStop(f func()) {
+	serviceStop.Lock()
+	defer serviceStop.Unlock()
+	serviceStop.f = f
+}
+
+// stopService shuts down gracefully, and returns false if it's
+// too early for that
+func stopService() bool {
+	serviceStop.Lock()
+	f := serviceStop.f
+	serviceStop.Unlock()
+
+	if f == nil {
+		return false
+	}
+	f()
+	return true
+}
+
+// serviceLogLevel is how important a message logged while running
+// as a service is, for the event log or the journal
+type serviceLogLevel int
+
+const (
+	serviceLogInfo serviceLogLevel = iota
+	serviceLogWarning
+	serviceLogError
+)
+
+// serviceLogWriter turns the JSON lines comm writes (and whatever else
+// is logged) into calls to sink, one per line. Only log messages and
+// errors go through: the handshake is written to its own file, and
+// must not end up in logs.
+type serviceLogWriter struct {
+	sink func(level serviceLogLevel, msg string)
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+var _ io.Writer = (*serviceLogWriter)(nil)
+
+func newServiceLogWriter(sink func(level serviceLogLevel, msg string)) *serviceLogWriter {
+	return &serviceLogWriter{sink: sink}
+}
+
+func (w *serviceLogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadBytes('\n')
+		if err != nil {
+			// incomplete line, wait for the rest
+			w.buf.Reset()
+			w.buf.Write(line)
+			break
+		}
+		w.handleLine(bytes.TrimRight(line, "\r\n"))
+	}
+	return len(p), nil
+}
+
+func (w *serviceLogWriter) handleLine(line []byte) {
+	if len(line) == 0 {
+		return
+	}
+
+	var msg struct {
+		Type    string `json:"type"`
+		Level   string `json:"level"`
+		Message string `json:"message"`
+	}
+	if line[0] != '{' || json.Unmarshal(line, &msg) != nil {
+		// not from comm, the standard logger for example
+		w.sink(serviceLogInfo, string(line))
+		return
+	}
+
+	switch msg.Type {
+	case "log":
+		switch msg.Level {
+		case "warning":
+			w.sink(serviceLogWarning, msg.Message)
+		case "error":
+			w.sink(serviceLogError, msg.Message)
+		default:
+			w.sink(serviceLogInfo, msg.Message)
+		}
+	case "error":
+		w.sink(serviceLogError, msg.Message)
+	}
+}
+
+// writeHandshake writes what clients need to connect (address and
+// secret) to path, readable by its owner only. The file is replaced
+// atomically, so clients never read half of it.
+func writeHandshake(path string, handshake interface{}) error {
+	contents, err := json.MarshalIndent(handshake, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	dir := filepath.Dir(path)
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// created with mode 0600. on windows, the folder's ACL applies
+	f, err := ioutil.TempFile(dir, ".butlerd-handshake-*")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	tmpPath := f.Name()
+	defer os.Remove(tmpPath)
+
+	w := bufio.NewWriter(f)
+	_, err = w.Write(contents)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "writing handshake")
+	}
+
+	return errors.WithStack(os.Rename(tmpPath, path))
+}
@@ -0,0 +1,187 @@
+//+build !windows
+
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/itchio/butler/comm"
+	"github.com/itchio/butler/mansion"
+	"github.com/pkg/errors"
+)
+
+// systemd passes activated sockets starting from that fd
+const listenFdsStart = 3
+
+func installService(ctx *mansion.Context) error {
+	cl, err := serviceCommandLine(ctx)
+	if err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		exe = "butler"
+	}
+	comm.Logf("--service install only registers with the Windows service manager.")
+	comm.Logf("With systemd, use a unit of Type=notify that runs:")
+	comm.Logf("  %s %s", exe, strings.Join(cl, " "))
+	return errors.New("not supported on this platform")
+}
+
+func uninstallService() error {
+	return errors.New("--service uninstall is only supported on Windows")
+}
+
+// runService runs start as a systemd service (of Type=notify, ideally).
+// Logs go to the journal with their priority, if it's attached, and
+// SIGTERM shuts down gracefully.
+func runService(start func()) error {
+	if os.Getenv("JOURNAL_STREAM") != "" {
+		w := newServiceLogWriter(journalSink(os.Stderr))
+		comm.SetOutput(w)
+		log.SetOutput(w)
+		// the journal has timestamps already
+		log.SetFlags(0)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		sig := <-sigs
+		log.Printf("Received %s, shutting down", sig)
+		_ = sdNotify("STOPPING=1")
+		if !stopService() {
+			// not serving yet, nothing to wait on
+			os.Exit(0)
+		}
+	}()
+
+	stopWatchdog := startWatchdog()
+	defer stopWatchdog()
+
+	start()
+	_ = sdNotify("STOPPING=1")
+	return nil
+}
+
+func serviceReady() {
+	err := sdNotify("READY=1")
+	if err != nil {
+		log.Printf("Could not notify service manager: %+v", err)
+	}
+}
+
+// journalSink writes messages with the priority prefixes
+// journald understands, see sd-daemon(3)
+func journalSink(w io.Writer) func(level serviceLogLevel, msg string) {
+	return func(level serviceLogLevel, msg string) {
+		prefix := "<6>"
+		switch level {
+		case serviceLogWarning:
+			prefix = "<4>"
+		case serviceLogError:
+			prefix = "<3>"
+		}
+		msg = strings.ReplaceAll(msg, "\n", "\n"+prefix)
+		fmt.Fprintf(w, "%s%s\n", prefix, msg)
+	}
+}
+
+// sdNotify sends state to the service manager, see sd_notify(3).
+// It does nothing if butler wasn't started by one.
+func sdNotify(state string) error {
+	socketPath := os.Getenv("NOTIFY_SOCKET")
+	if socketPath == "" {
+		return nil
+	}
+
+	// paths starting with @ are abstract sockets, which the net package
+	// handles by itself
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return errors.WithStack(err)
+}
+
+// startWatchdog pings the service manager twice as often as it
+// expects to (WatchdogSec=), if it does. The returned func stops.
+func startWatchdog() func() {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return func() {}
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return func() {}
+	}
+
+	interval := time.Duration(usec) * time.Microsecond / 2
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				err := sdNotify("WATCHDOG=1")
+				if err != nil {
+					log.Printf("Could not ping service manager watchdog: %+v", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+// serviceListener returns the socket systemd passed us, if it was
+// socket-activated (see sd_listen_fds(3)), and nil otherwise.
+func serviceListener() (net.Listener, error) {
+	defer func() {
+		// so child processes don't think they were socket-activated
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	numFds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || numFds < 1 {
+		return nil, nil
+	}
+	if numFds > 1 {
+		log.Printf("Got %d sockets from the service manager, only using the first one", numFds)
+	}
+
+	f := os.NewFile(uintptr(listenFdsStart), "socket-activation")
+	defer f.Close()
+
+	listener, err := net.FileListener(f)
+	if err != nil {
+		return nil, errors.Wrap(err, "using socket from service manager")
+	}
+	if _, ok := listener.(*net.TCPListener); !ok {
+		listener.Close()
+		return nil, errors.New("socket from service manager isn't a TCP socket, butlerd only serves TCP")
+	}
+	return listener, nil
+}
@@ -0,0 +1,49 @@
+//+build !windows
+
+package daemon
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SdNotify(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "butlerd-notify")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socketPath := filepath.Join(dir, "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	os.Unsetenv("NOTIFY_SOCKET")
+	assert.NoError(sdNotify("READY=1"), "no-op without a service manager")
+
+	os.Setenv("NOTIFY_SOCKET", socketPath)
+	defer os.Unsetenv("NOTIFY_SOCKET")
+	require.NoError(t, sdNotify("READY=1"))
+
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assert.EqualValues("READY=1", string(buf[:n]))
+}
+
+func Test_JournalSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := journalSink(&buf)
+	sink(serviceLogInfo, "hello")
+	sink(serviceLogWarning, "two\nlines")
+	sink(serviceLogError, "bad")
+	assert.EqualValues(t, "<6>hello\n<4>two\n<4>lines\n<3>bad\n", buf.String())
+}
@@ -0,0 +1,74 @@
+package daemon
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ServiceLogWriter(t *testing.T) {
+	assert := assert.New(t)
+
+	type entry struct {
+		level serviceLogLevel
+		msg   string
+	}
+	var entries []entry
+	w := newServiceLogWriter(func(level serviceLogLevel, msg string) {
+		entries = append(entries, entry{level, msg})
+	})
+
+	write := func(s string) {
+		_, err := w.Write([]byte(s))
+		require.NoError(t, err)
+	}
+
+	write(`{"type":"log","level":"info","message":"hello"}` + "\n")
+	write(`{"type":"log","level":"warning","mess`)
+	write(`age":"careful"}` + "\n" + `{"type":"butlerd/listen-notification","secret":"hunter2"}` + "\n")
+	write("2020/06/15 Closing TCP listener...\n")
+	write(`{"type":"error","message":"oh no"}` + "\n")
+
+	assert.EqualValues([]entry{
+		{serviceLogInfo, "hello"},
+		{serviceLogWarning, "careful"},
+		{serviceLogInfo, "2020/06/15 Closing TCP listener..."},
+		{serviceLogError, "oh no"},
+	}, entries, "the handshake is never logged")
+}
+
+func Test_WriteHandshake(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "butlerd-handshake")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "run", "handshake.json")
+	for _, secret := range []string{"first", "second"} {
+		err = writeHandshake(path, map[string]interface{}{"secret": secret})
+		require.NoError(t, err)
+
+		contents, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		var handshake map[string]interface{}
+		require.NoError(t, json.Unmarshal(contents, &handshake))
+		assert.EqualValues(secret, handshake["secret"])
+	}
+
+	if runtime.GOOS != "windows" {
+		stats, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.EqualValues(0o600, stats.Mode().Perm())
+	}
+
+	files, err := ioutil.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(files, 1, "no temporary files left behind")
+}
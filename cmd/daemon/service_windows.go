@@ -0,0 +1,178 @@
+//+build windows
+
+package daemon
+
+import (
+	"log"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/itchio/butler/comm"
+	"github.com/itchio/butler/mansion"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+func installService(ctx *mansion.Context) error {
+	ctx.EnsureDBPath()
+
+	cl, err := serviceCommandLine(ctx)
+	if err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return errors.Wrap(err, "connecting to service manager")
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(args.serviceName)
+	if err == nil {
+		s.Close()
+		return errors.Errorf("service %s already exists", args.serviceName)
+	}
+
+	s, err = m.CreateService(args.serviceName, exe, mgr.Config{
+		DisplayName: "butler daemon",
+		Description: "Installs, updates and launches itch.io games for clients that connect to it",
+		StartType:   mgr.StartAutomatic,
+	}, cl...)
+	if err != nil {
+		return errors.Wrap(err, "creating service")
+	}
+	defer s.Close()
+
+	err = eventlog.InstallAsEventCreate(args.serviceName, eventlog.Error|eventlog.Warning|eventlog.Info)
+	if err != nil {
+		s.Delete()
+		return errors.Wrap(err, "registering event log source")
+	}
+
+	comm.Statf("Installed service %s, its handshake will be written to %s", args.serviceName, args.handshakeFile)
+	return nil
+}
+
+func uninstallService() error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return errors.Wrap(err, "connecting to service manager")
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(args.serviceName)
+	if err != nil {
+		return errors.Errorf("service %s is not installed", args.serviceName)
+	}
+	defer s.Close()
+
+	err = s.Delete()
+	if err != nil {
+		return errors.Wrap(err, "deleting service")
+	}
+
+	err = eventlog.Remove(args.serviceName)
+	if err != nil {
+		comm.Warnf("Could not remove event log source: %s", err.Error())
+	}
+
+	comm.Statf("Uninstalled service %s", args.serviceName)
+	return nil
+}
+
+// runService runs start as a Windows service. The SCM is told
+// we're running once serviceReady is called.
+func runService(start func()) error {
+	elog, err := eventlog.Open(args.serviceName)
+	if err == nil {
+		defer elog.Close()
+		w := newServiceLogWriter(func(level serviceLogLevel, msg string) {
+			switch level {
+			case serviceLogError:
+				elog.Error(1, msg)
+			case serviceLogWarning:
+				elog.Warning(1, msg)
+			default:
+				elog.Info(1, msg)
+			}
+		})
+		comm.SetOutput(w)
+		log.SetOutput(w)
+	}
+
+	return svc.Run(args.serviceName, &serviceHandler{start: start})
+}
+
+// the channel status changes are sent to the SCM on, while running
+var serviceStatus = struct {
+	sync.Mutex
+	changes  chan<- svc.Status
+	stopping bool
+}{}
+
+type serviceHandler struct {
+	start func()
+}
+
+var _ svc.Handler = (*serviceHandler)(nil)
+
+func (h *serviceHandler) Execute(_ []string, requests <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	changes <- svc.Status{State: svc.StartPending}
+	serviceStatus.Lock()
+	serviceStatus.changes = changes
+	serviceStatus.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.start()
+	}()
+
+	for {
+		select {
+		case <-done:
+			return false, 0
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				changes <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				serviceStatus.Lock()
+				serviceStatus.stopping = true
+				changes <- svc.Status{State: svc.StopPending}
+				serviceStatus.Unlock()
+
+				if !stopService() {
+					// not serving yet, nothing to wait on
+					return false, 0
+				}
+			}
+		}
+	}
+}
+
+func serviceReady() {
+	serviceStatus.Lock()
+	defer serviceStatus.Unlock()
+
+	if serviceStatus.changes == nil || serviceStatus.stopping {
+		return
+	}
+	serviceStatus.changes <- svc.Status{
+		State:   svc.Running,
+		Accepts: svc.AcceptStop | svc.AcceptShutdown,
+	}
+}
+
+// serviceListener returns nil: the SCM doesn't pass sockets
+func serviceListener() (net.Listener, error) {
+	return nil, nil
+}
@@ -0,0 +1,78 @@
+package fakeapi
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/itchio/butler/comm"
+	"github.com/itchio/butler/fakeapi"
+	"github.com/itchio/butler/mansion"
+	"github.com/itchio/headway/state"
+)
+
+var args = struct {
+	port      int
+	behaviors fakeapi.Behaviors
+}{}
+
+func Register(ctx *mansion.Context) {
+	cmd := ctx.App.Command("fake-api", "(Advanced) Fake the itch.io API, to develop clients against")
+	serveCmd := cmd.Command("serve", "Serve a fake itch.io API, with a few games to install, until interrupted")
+	serveCmd.Flag("port", "Port to listen on, any free one if unspecified").Default("0").IntVar(&args.port)
+	serveCmd.Flag("latency", "Delay every response by that much").Default("0s").DurationVar(&args.behaviors.Latency)
+	serveCmd.Flag("rate-limit-every", "Answer every Nth API call with a 429").Default("0").Int64Var(&args.behaviors.RateLimitEvery)
+	serveCmd.Flag("retry-after", "How long 429 responses ask to wait").Default("1s").DurationVar(&args.behaviors.RetryAfter)
+	serveCmd.Flag("truncate-every", "Cut the response of every Nth API call in half").Default("0").Int64Var(&args.behaviors.TruncateEvery)
+	ctx.Register(serveCmd, func(ctx *mansion.Context) {
+		ctx.Must(serve())
+	})
+}
+
+type serveResult struct {
+	Address     string `json:"address"`
+	APIKey      string `json:"apiKey"`
+	ZipGameID   int64  `json:"zipGameId"`
+	WharfGameID int64  `json:"wharfGameId"`
+}
+
+func serve() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	consumer := &state.Consumer{
+		OnMessage: func(lvl string, msg string) {
+			comm.Loglf(lvl, "[fake-api] %s", msg)
+		},
+	}
+
+	s, err := fakeapi.Start(ctx, fakeapi.ServerParams{
+		Port:      args.port,
+		Behaviors: args.behaviors,
+		Consumer:  consumer,
+	})
+	if err != nil {
+		return err
+	}
+
+	comm.ResultOrPrint(&serveResult{
+		Address:     s.Address,
+		APIKey:      s.APIKey,
+		ZipGameID:   s.Fixtures.ZipGameID,
+		WharfGameID: s.Fixtures.WharfGameID,
+	}, func() {
+		comm.Notice("Fake itch.io API", []string{
+			fmt.Sprintf("Listening on %s", s.Address),
+			fmt.Sprintf("API key: %s", s.APIKey),
+			fmt.Sprintf("Zip game: %d, wharf game: %d", s.Fixtures.ZipGameID, s.Fixtures.WharfGameID),
+			fmt.Sprintf("Use with: butler --address %s daemon ...", s.Address),
+		})
+	})
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	<-interrupted
+	comm.Logf("Shutting down fake API")
+	return nil
+}
@@ -0,0 +1,273 @@
+package operate
+
+import (
+	"fmt"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/hades"
+	"github.com/itchio/headway/state"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
+)
+
+type GameAccess struct {
+	APIKey      string                 `json:"api_key"`
+	Credentials itchio.GameCredentials `json:"credentials"`
+
+	// Which rule picked those credentials, and why
+	Explanation *butlerd.AccessExplanation `json:"explanation,omitempty"`
+}
+
+func (ga *GameAccess) OnlyAPIKey() *GameAccess {
+	return &GameAccess{
+		APIKey: ga.APIKey,
+	}
+}
+
+// AccessStore is everything AccessResolver needs to know
+// about profiles and download keys.
+type AccessStore interface {
+	ProfileByID(profileID int64) *models.Profile
+	// Profiles that have admin access to a game
+	ProfileGamesByGameID(gameID int64) []*models.ProfileGame
+	DownloadKeysByGameID(gameID int64) []*itchio.DownloadKey
+	// All profiles, most recently connected first
+	Profiles() []*models.Profile
+}
+
+type dbAccessStore struct {
+	conn *sqlite.Conn
+}
+
+var _ AccessStore = (*dbAccessStore)(nil)
+
+// NewDBAccessStore returns an AccessStore backed by butler's database
+func NewDBAccessStore(conn *sqlite.Conn) AccessStore {
+	return &dbAccessStore{conn: conn}
+}
+
+func (s *dbAccessStore) ProfileByID(profileID int64) *models.Profile {
+	return models.ProfileByID(s.conn, profileID)
+}
+
+func (s *dbAccessStore) ProfileGamesByGameID(gameID int64) []*models.ProfileGame {
+	return models.ProfileGamesByGameID(s.conn, gameID)
+}
+
+func (s *dbAccessStore) DownloadKeysByGameID(gameID int64) []*itchio.DownloadKey {
+	return models.DownloadKeysByGameID(s.conn, gameID)
+}
+
+func (s *dbAccessStore) Profiles() []*models.Profile {
+	var profiles []*models.Profile
+	models.MustSelect(s.conn, &profiles, builder.NewCond(), hades.Search{}.OrderBy("last_connected DESC"))
+	return profiles
+}
+
+type AccessRequest struct {
+	GameID int64
+
+	// Profile explicitly asked for by the client, if any
+	ProfileID int64
+
+	// Profile that installed the cave we're dealing with, if any
+	CaveProfileID int64
+}
+
+// AccessResolver decides which API key and credentials to use for a game.
+// Rules are tried in order:
+//
+//   - the explicitly requested profile
+//   - the profile that installed the cave
+//   - any profile owning a download key for the game
+//   - any profile with admin access to the game
+//   - anonymous: the press profile if there is one, the most recent one otherwise
+type AccessResolver struct {
+	store AccessStore
+}
+
+func NewAccessResolver(store AccessStore) *AccessResolver {
+	return &AccessResolver{store: store}
+}
+
+type accessResolution struct {
+	ar          *AccessResolver
+	req         AccessRequest
+	explanation *butlerd.AccessExplanation
+}
+
+func (res *accessResolution) stepf(format string, args ...interface{}) {
+	res.explanation.Steps = append(res.explanation.Steps, fmt.Sprintf(format, args...))
+}
+
+// usableProfile returns the profile if it exists and has an API key
+func (res *accessResolution) usableProfile(profileID int64) *models.Profile {
+	profile := res.ar.store.ProfileByID(profileID)
+	if profile == nil {
+		res.stepf("profile %d not found", profileID)
+		return nil
+	}
+	if profile.APIKey == "" {
+		res.stepf("profile %d lacks API key", profileID)
+		return nil
+	}
+	return profile
+}
+
+func (res *accessResolution) match(rule butlerd.AccessRule, profile *models.Profile, downloadKeyID int64) *GameAccess {
+	res.explanation.Rule = rule
+	res.explanation.ProfileID = profile.ID
+	res.explanation.DownloadKeyID = downloadKeyID
+	if downloadKeyID != 0 {
+		res.stepf("%s: using profile %d with download key %d", rule, profile.ID, downloadKeyID)
+	} else {
+		res.stepf("%s: using profile %d", rule, profile.ID)
+	}
+
+	return &GameAccess{
+		APIKey: profile.APIKey,
+		Credentials: itchio.GameCredentials{
+			DownloadKeyID: downloadKeyID,
+		},
+		Explanation: res.explanation,
+	}
+}
+
+// ownedKeyID returns the ID of a download key for the game owned by
+// the given profile, or 0 if there's none.
+func (res *accessResolution) ownedKeyID(profileID int64) int64 {
+	for _, dk := range res.ar.store.DownloadKeysByGameID(res.req.GameID) {
+		if dk.OwnerID == profileID {
+			return dk.ID
+		}
+	}
+	return 0
+}
+
+func (res *accessResolution) tryProfile(rule butlerd.AccessRule, profileID int64) *GameAccess {
+	if profileID == 0 {
+		res.stepf("%s: not specified", rule)
+		return nil
+	}
+
+	profile := res.usableProfile(profileID)
+	if profile == nil {
+		res.stepf("%s: skipped", rule)
+		return nil
+	}
+	return res.match(rule, profile, res.ownedKeyID(profile.ID))
+}
+
+func (ar *AccessResolver) Resolve(req AccessRequest) *GameAccess {
+	res := &accessResolution{
+		ar:          ar,
+		req:         req,
+		explanation: &butlerd.AccessExplanation{},
+	}
+
+	if access := res.tryProfile(butlerd.AccessRuleExplicit, req.ProfileID); access != nil {
+		return access
+	}
+
+	if access := res.tryProfile(butlerd.AccessRuleCaveProfile, req.CaveProfileID); access != nil {
+		return access
+	}
+
+	// look for a download key
+	{
+		dks := ar.store.DownloadKeysByGameID(req.GameID)
+		for _, dk := range dks {
+			if profile := res.usableProfile(dk.OwnerID); profile != nil {
+				return res.match(butlerd.AccessRuleDownloadKey, profile, dk.ID)
+			}
+			res.stepf("%s: ignoring download key %d", butlerd.AccessRuleDownloadKey, dk.ID)
+		}
+		if len(dks) == 0 {
+			res.stepf("%s: no download keys for game %d", butlerd.AccessRuleDownloadKey, req.GameID)
+		}
+	}
+
+	// look for owner access
+	{
+		pgs := ar.store.ProfileGamesByGameID(req.GameID)
+		for _, pg := range pgs {
+			if profile := res.usableProfile(pg.ProfileID); profile != nil {
+				return res.match(butlerd.AccessRuleAuthor, profile, 0)
+			}
+		}
+		if len(pgs) == 0 {
+			res.stepf("%s: no profile has admin access to game %d", butlerd.AccessRuleAuthor, req.GameID)
+		}
+	}
+
+	// no special credentials
+	{
+		var profiles []*models.Profile
+		for _, profile := range ar.store.Profiles() {
+			if profile.APIKey != "" {
+				profiles = append(profiles, profile)
+			}
+		}
+		if len(profiles) == 0 {
+			panic(errors.New("No profiles found"))
+		}
+
+		// prefer press user
+		for _, profile := range profiles {
+			if profile.PressUser {
+				return res.match(butlerd.AccessRuleAnonymous, profile, 0)
+			}
+		}
+
+		// just take the most recent then
+		return res.match(butlerd.AccessRuleAnonymous, profiles[0], 0)
+	}
+}
+
+// ResolveAccess picks credentials for a game using butler's database,
+// see AccessResolver for the rules.
+func ResolveAccess(conn *sqlite.Conn, req AccessRequest) *GameAccess {
+	return NewAccessResolver(NewDBAccessStore(conn)).Resolve(req)
+}
+
+func AccessForGameID(conn *sqlite.Conn, gameID int64) *GameAccess {
+	return ResolveAccess(conn, AccessRequest{GameID: gameID})
+}
+
+// AccessForCave prefers the credentials of the profile that installed the cave
+func AccessForCave(conn *sqlite.Conn, cave *models.Cave) *GameAccess {
+	if IsLocalGame(cave.GameID) {
+		// there might not even be a profile
+		return &GameAccess{}
+	}
+	return ResolveAccess(conn, AccessRequest{
+		GameID:        cave.GameID,
+		CaveProfileID: cave.SourceProfileID,
+	})
+}
+
+// IsLocalGame returns true for games made up when installing from
+// a local archive, which itch.io knows nothing about.
+func IsLocalGame(gameID int64) bool {
+	return gameID < 0
+}
+
+// UploadStorageLocal is the storage of uploads made up when installing
+// from a local archive, to tell them apart from the ones on itch.io.
+const UploadStorageLocal itchio.UploadStorage = "local"
+
+// LogAccess prints which credentials will be used, and why
+func LogAccess(consumer *state.Consumer, access *GameAccess) {
+	ae := access.Explanation
+	if ae == nil {
+		return
+	}
+
+	consumer.Infof("Using credentials from rule (%s)", ae.Rule)
+	for _, step := range ae.Steps {
+		consumer.Infof("  - %s", step)
+	}
+}
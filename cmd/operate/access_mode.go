@@ -0,0 +1,56 @@
+package operate
+
+import (
+	"os"
+	"runtime"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/headway/state"
+	"github.com/pkg/errors"
+)
+
+// AccessModePerm returns the permissions install folders should have
+// in an install location with the given access mode. It returns false
+// if butler shouldn't touch them (no access mode set, or on Windows,
+// where unix permissions don't mean much).
+func AccessModePerm(mode butlerd.InstallLocationAccessMode) (os.FileMode, bool) {
+	if runtime.GOOS == "windows" {
+		return 0, false
+	}
+
+	switch mode {
+	case butlerd.InstallLocationAccessModePrivate:
+		return 0o700, true
+	case butlerd.InstallLocationAccessModeGroup:
+		return 0o750, true
+	case butlerd.InstallLocationAccessModePublic:
+		return 0o755, true
+	}
+	return 0, false
+}
+
+// ApplyAccessMode sets the permissions of an install folder according
+// to the access mode of its install location. Only the folder itself
+// is changed: that's enough to keep other users out of it, and files
+// inside keep whatever permissions the build gave them.
+func ApplyAccessMode(consumer *state.Consumer, installFolder string, mode butlerd.InstallLocationAccessMode) error {
+	perm, ok := AccessModePerm(mode)
+	if !ok {
+		return nil
+	}
+
+	stats, err := os.Stat(installFolder)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if stats.Mode().Perm() == perm {
+		return nil
+	}
+
+	consumer.Infof("Setting permissions of (%s) to %o (%s access)", installFolder, perm, mode)
+	err = os.Chmod(installFolder, perm)
+	if err != nil {
+		return errors.Wrapf(err, "while applying %s access mode", mode)
+	}
+	return nil
+}
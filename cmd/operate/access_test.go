@@ -0,0 +1,163 @@
+package operate_test
+
+import (
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeAccessStore struct {
+	profiles     []*models.Profile
+	profileGames []*models.ProfileGame
+	downloadKeys []*itchio.DownloadKey
+}
+
+var _ operate.AccessStore = (*fakeAccessStore)(nil)
+
+func (s *fakeAccessStore) ProfileByID(profileID int64) *models.Profile {
+	for _, p := range s.profiles {
+		if p.ID == profileID {
+			return p
+		}
+	}
+	return nil
+}
+
+func (s *fakeAccessStore) ProfileGamesByGameID(gameID int64) []*models.ProfileGame {
+	var res []*models.ProfileGame
+	for _, pg := range s.profileGames {
+		if pg.GameID == gameID {
+			res = append(res, pg)
+		}
+	}
+	return res
+}
+
+func (s *fakeAccessStore) DownloadKeysByGameID(gameID int64) []*itchio.DownloadKey {
+	var res []*itchio.DownloadKey
+	for _, dk := range s.downloadKeys {
+		if dk.GameID == gameID {
+			res = append(res, dk)
+		}
+	}
+	return res
+}
+
+func (s *fakeAccessStore) Profiles() []*models.Profile {
+	return s.profiles
+}
+
+const gameID int64 = 42
+
+func newFakeAccessStore() *fakeAccessStore {
+	return &fakeAccessStore{
+		// most recently connected first
+		profiles: []*models.Profile{
+			{ID: 1, APIKey: "recent"},
+			{ID: 2, APIKey: "buyer"},
+			{ID: 3, APIKey: "author"},
+			{ID: 4, APIKey: "press", PressUser: true},
+			{ID: 5, APIKey: "other-buyer"},
+		},
+		profileGames: []*models.ProfileGame{
+			{GameID: gameID, ProfileID: 3},
+		},
+		downloadKeys: []*itchio.DownloadKey{
+			{ID: 200, GameID: gameID, OwnerID: 2},
+			{ID: 500, GameID: gameID, OwnerID: 5},
+		},
+	}
+}
+
+func resolve(store operate.AccessStore, req operate.AccessRequest) *operate.GameAccess {
+	req.GameID = gameID
+	return operate.NewAccessResolver(store).Resolve(req)
+}
+
+func assertAccess(t *testing.T, access *operate.GameAccess, rule butlerd.AccessRule, apiKey string, downloadKeyID int64) {
+	t.Helper()
+	assert.EqualValues(t, rule, access.Explanation.Rule)
+	assert.EqualValues(t, apiKey, access.APIKey)
+	assert.EqualValues(t, downloadKeyID, access.Credentials.DownloadKeyID)
+	assert.EqualValues(t, downloadKeyID, access.Explanation.DownloadKeyID)
+	assert.NotEmpty(t, access.Explanation.Steps)
+}
+
+func Test_Access_Explicit(t *testing.T) {
+	store := newFakeAccessStore()
+
+	// explicit wins over everything, and uses its own key if it has one
+	assertAccess(t, resolve(store, operate.AccessRequest{ProfileID: 5, CaveProfileID: 2}), butlerd.AccessRuleExplicit, "other-buyer", 500)
+
+	// explicit profile without a key for the game
+	assertAccess(t, resolve(store, operate.AccessRequest{ProfileID: 1}), butlerd.AccessRuleExplicit, "recent", 0)
+
+	// unknown explicit profile falls through
+	assertAccess(t, resolve(store, operate.AccessRequest{ProfileID: 99}), butlerd.AccessRuleDownloadKey, "buyer", 200)
+}
+
+func Test_Access_CaveProfile(t *testing.T) {
+	store := newFakeAccessStore()
+
+	// cave profile wins over other key owners
+	assertAccess(t, resolve(store, operate.AccessRequest{CaveProfileID: 5}), butlerd.AccessRuleCaveProfile, "other-buyer", 500)
+
+	// cave profile that was forgotten since falls through
+	assertAccess(t, resolve(store, operate.AccessRequest{CaveProfileID: 99}), butlerd.AccessRuleDownloadKey, "buyer", 200)
+}
+
+func Test_Access_DownloadKey(t *testing.T) {
+	store := newFakeAccessStore()
+
+	// first key owner wins over author
+	assertAccess(t, resolve(store, operate.AccessRequest{}), butlerd.AccessRuleDownloadKey, "buyer", 200)
+
+	// revoked: first key owner lost their API key, use the next owner
+	store.profiles[1].APIKey = ""
+	assertAccess(t, resolve(store, operate.AccessRequest{}), butlerd.AccessRuleDownloadKey, "other-buyer", 500)
+
+	// revoked: no key owner is usable anymore, fall back to author
+	store.profiles = store.profiles[:4]
+	assertAccess(t, resolve(store, operate.AccessRequest{}), butlerd.AccessRuleAuthor, "author", 0)
+}
+
+func Test_Access_Author(t *testing.T) {
+	store := newFakeAccessStore()
+	store.downloadKeys = nil
+
+	assertAccess(t, resolve(store, operate.AccessRequest{}), butlerd.AccessRuleAuthor, "author", 0)
+
+	// multiple admins, first one is gone
+	store.profileGames = []*models.ProfileGame{
+		{GameID: gameID, ProfileID: 99},
+		{GameID: gameID, ProfileID: 3},
+	}
+	assertAccess(t, resolve(store, operate.AccessRequest{}), butlerd.AccessRuleAuthor, "author", 0)
+}
+
+func Test_Access_Anonymous(t *testing.T) {
+	store := newFakeAccessStore()
+	store.downloadKeys = nil
+	store.profileGames = nil
+
+	// press user is preferred
+	assertAccess(t, resolve(store, operate.AccessRequest{}), butlerd.AccessRuleAnonymous, "press", 0)
+
+	// most recent otherwise
+	store.profiles[3].PressUser = false
+	assertAccess(t, resolve(store, operate.AccessRequest{}), butlerd.AccessRuleAnonymous, "recent", 0)
+
+	// profiles without API keys are skipped
+	store.profiles[0].APIKey = ""
+	assertAccess(t, resolve(store, operate.AccessRequest{}), butlerd.AccessRuleAnonymous, "buyer", 0)
+
+	// no profiles at all is a hard error
+	store.profiles = nil
+	assert.Panics(t, func() {
+		resolve(store, operate.AccessRequest{})
+	})
+}
@@ -0,0 +1,157 @@
+package operate
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/itchio/boar"
+	"github.com/itchio/hush"
+	"github.com/itchio/hush/bfs"
+	"github.com/itchio/hush/intervalsaveconsumer"
+	"github.com/itchio/savior"
+	"github.com/pkg/errors"
+)
+
+// SinkWrapper lets an archive install change where (and under which
+// name) entries are extracted, see installArchive.
+type SinkWrapper func(sink savior.Sink) savior.Sink
+
+// installArchive does what hush's archive installer does, except
+// entries go through wrap before they reach the install folder.
+// The files it returns are the ones the wrapped sink was asked for,
+// renamed by rename if it's set.
+func installArchive(params hush.InstallParams, wrap SinkWrapper, rename func(name string) string) (*hush.InstallResult, error) {
+	consumer := params.Consumer
+	f := params.File
+
+	archiveInfo := params.InstallerInfo.ArchiveInfo
+	if archiveInfo == nil {
+		consumer.Infof("Missing archive info, probing...")
+		var err error
+		archiveInfo, err = boar.Probe(boar.ProbeParams{
+			File:     f,
+			Consumer: consumer,
+		})
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	if archiveInfo.Features.ResumeSupport == savior.ResumeSupportNone {
+		consumer.Infof("Forcing local for %s", archiveInfo.Features)
+		localFile, err := hush.AsLocalFile(f)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		f = localFile
+	}
+
+	ex, err := archiveInfo.GetExtractor(f, consumer)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	ex.SetConsumer(consumer)
+
+	statePath := filepath.Join(params.StageFolderPath, "install-state.dat")
+	sc := intervalsaveconsumer.New(statePath, intervalsaveconsumer.DefaultInterval, consumer, params.Context)
+	ex.SetSaveConsumer(sc)
+
+	cancelled := false
+	defer func() {
+		if !cancelled {
+			os.Remove(statePath)
+		}
+	}()
+
+	checkpoint, err := sc.Load()
+	if err != nil {
+		consumer.Warnf("Could not load checkpoint: %s", err.Error())
+	}
+
+	var sink savior.Sink = &savior.FolderSink{
+		Directory: params.InstallFolderPath,
+		Consumer:  consumer,
+	}
+	if wrap != nil {
+		sink = wrap(sink)
+	}
+	var closeSinkOnce sync.Once
+	defer closeSinkOnce.Do(func() {
+		sink.Close()
+	})
+
+	aRes, err := ex.Resume(checkpoint, sink)
+	if err != nil {
+		if errors.Cause(err) == savior.ErrStop {
+			cancelled = true
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	closeSinkOnce.Do(func() {
+		err = sink.Close()
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	res := &hush.InstallResult{
+		Files: []string{},
+	}
+	for _, entry := range aRes.Entries {
+		name := entry.CanonicalPath
+		if rename != nil {
+			name = rename(name)
+		}
+		res.Files = append(res.Files, name)
+	}
+
+	consumer.Opf("Busting ghosts...")
+	err = bfs.BustGhosts(bfs.BustGhostsParams{
+		Folder:   params.InstallFolderPath,
+		NewFiles: res.Files,
+		Receipt:  params.ReceiptIn,
+
+		Consumer: consumer,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	err = params.EventSink.PostGhostBusting("install::archive", bfs.BustGhostStats{})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// renamingSink extracts entries under another name, see pathsafety.Renames
+type renamingSink struct {
+	savior.Sink
+	rename func(name string) string
+}
+
+var _ savior.Sink = (*renamingSink)(nil)
+
+func (rs *renamingSink) renamed(entry *savior.Entry) *savior.Entry {
+	e := *entry
+	e.CanonicalPath = rs.rename(entry.CanonicalPath)
+	return &e
+}
+
+func (rs *renamingSink) Mkdir(entry *savior.Entry) error {
+	return rs.Sink.Mkdir(rs.renamed(entry))
+}
+
+func (rs *renamingSink) Symlink(entry *savior.Entry, linkname string) error {
+	return rs.Sink.Symlink(rs.renamed(entry), linkname)
+}
+
+func (rs *renamingSink) GetWriter(entry *savior.Entry) (savior.EntryWriter, error) {
+	return rs.Sink.GetWriter(rs.renamed(entry))
+}
+
+func (rs *renamingSink) Preallocate(entry *savior.Entry) error {
+	return rs.Sink.Preallocate(rs.renamed(entry))
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/itchio/hush"
 	"github.com/itchio/hush/bfs"
 	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/savior"
 	"github.com/pkg/errors"
 )
 
@@ -38,11 +39,18 @@ type DiskUsageInfo struct {
 	// Space we need to perform that operation
 	NeededFreeSpace int64
 
+	// Part of NeededFreeSpace that's needed in the overflow location,
+	// for installs split across two, see OverflowMap
+	OverflowNeededFreeSpace int64
+
 	// Accuracy of our information
 	Accuracy Accuracy
 }
 
-func AssessDiskUsage(sourceFile eos.File, receiptIn *bfs.Receipt, installFolder string, installerInfo *hush.InstallerInfo) (*DiskUsageInfo, error) {
+// AssessDiskUsage looks at how much space installing sourceFile over
+// what receiptIn lists takes. If split is true, large files of archives
+// are counted as going to the overflow location.
+func AssessDiskUsage(sourceFile eos.File, receiptIn *bfs.Receipt, installFolder string, installerInfo *hush.InstallerInfo, split bool) (*DiskUsageInfo, error) {
 	dui := &DiskUsageInfo{
 		Accuracy: AccuracyNone,
 	}
@@ -74,6 +82,7 @@ func AssessDiskUsage(sourceFile eos.File, receiptIn *bfs.Receipt, installFolder
 
 			var existingSize int64
 			if _, ok := existingFiles[e.CanonicalPath]; ok {
+				// follows symlinks to the overflow location
 				rfs, err := os.Stat(filepath.Join(installFolder, e.CanonicalPath))
 				if err == nil {
 					// oh well
@@ -81,6 +90,9 @@ func AssessDiskUsage(sourceFile eos.File, receiptIn *bfs.Receipt, installFolder
 				}
 			}
 			dui.NeededFreeSpace += e.UncompressedSize - existingSize
+			if split && installerInfo.Type == hush.InstallerTypeArchive && e.Kind == savior.EntryKindFile && overflowBound(e.CanonicalPath, e.UncompressedSize, e.Mode) {
+				dui.OverflowNeededFreeSpace += e.UncompressedSize - existingSize
+			}
 		}
 		return dui, nil
 	}
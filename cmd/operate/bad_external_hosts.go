@@ -1,6 +1,10 @@
 package operate
 
-import "strings"
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
 
 // We can't download silently from any of these hosts.
 // This maps host names to fun trivia.
@@ -41,8 +45,40 @@ var BadExternalHosts = map[string]string{
 	"youtube.com": "Why??",
 }
 
+var (
+	externalHostPatternsLock sync.RWMutex
+
+	// Hosts that come in too many variants to list in BadExternalHosts
+	externalHostPatterns = []*regexp.Regexp{
+		// drive.usercontent.google.com, docs.google.com etc.
+		regexp.MustCompile(`^(drive|docs|drive\.usercontent)\.google\.com$`),
+		// dl.dropboxusercontent.com, www.dropbox.com etc.
+		regexp.MustCompile(`(^|\.)dropbox(usercontent)?\.com$`),
+		// download1234.mediafire.com etc.
+		regexp.MustCompile(`(^|\.)mediafire\.com$`),
+	}
+)
+
+// RegisterExternalHostPattern adds a pattern for hosts we can't download
+// from silently. Patterns are matched against lowercase host names.
+func RegisterExternalHostPattern(pattern *regexp.Regexp) {
+	externalHostPatternsLock.Lock()
+	defer externalHostPatternsLock.Unlock()
+	externalHostPatterns = append(externalHostPatterns, pattern)
+}
+
 func IsBadExternalHost(host string) bool {
-	host = strings.TrimPrefix(host, "www.")
-	_, bad := BadExternalHosts[host]
-	return bad
+	host = strings.ToLower(host)
+	if _, bad := BadExternalHosts[strings.TrimPrefix(host, "www.")]; bad {
+		return true
+	}
+
+	externalHostPatternsLock.RLock()
+	defer externalHostPatternsLock.RUnlock()
+	for _, pattern := range externalHostPatterns {
+		if pattern.MatchString(host) {
+			return true
+		}
+	}
+	return false
 }
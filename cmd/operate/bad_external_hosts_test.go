@@ -0,0 +1,26 @@
+package operate
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_IsBadExternalHost(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(IsBadExternalHost("www.dropbox.com"))
+	assert.True(IsBadExternalHost("MEGA.NZ"), "case doesn't matter")
+	assert.True(IsBadExternalHost("dl.dropboxusercontent.com"))
+	assert.True(IsBadExternalHost("drive.usercontent.google.com"))
+	assert.True(IsBadExternalHost("download1234.mediafire.com"))
+
+	assert.False(IsBadExternalHost("github.com"))
+	assert.False(IsBadExternalHost("notdropbox.com"))
+	assert.False(IsBadExternalHost("google.com"))
+
+	assert.False(IsBadExternalHost("files.example.org"))
+	RegisterExternalHostPattern(regexp.MustCompile(`(^|\.)example\.org$`))
+	assert.True(IsBadExternalHost("files.example.org"))
+}
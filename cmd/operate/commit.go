@@ -1,12 +1,18 @@
 package operate
 
 import (
+	"strings"
+
+	"crawshaw.io/sqlite"
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/database/models"
 	"github.com/itchio/butler/manager"
 	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
 	"github.com/itchio/hush"
 	"github.com/itchio/hush/bfs"
+	"github.com/itchio/hush/manifest"
 	"github.com/itchio/ox"
 	"github.com/pkg/errors"
 )
@@ -20,6 +26,14 @@ type CommitInstallParams struct {
 	Build  *itchio.Build
 
 	InstallResult *hush.InstallResult
+	// Files installed under a sanitized name, see pathsafety.Renames
+	Renames map[string]string
+
+	// Set when InstallResult only has the files the game needs to start,
+	// see PlanPlayEarly. The rest, RemainingBytes of it, is still being
+	// installed, and committed again once it's done.
+	PlayableEarly  bool
+	RemainingBytes int64
 }
 
 func commitInstall(oc *OperationContext, params *CommitInstallParams) error {
@@ -27,16 +41,18 @@ func commitInstall(oc *OperationContext, params *CommitInstallParams) error {
 
 	res := params.InstallResult
 
-	err := messages.TaskSucceeded.Notify(oc.rc, butlerd.TaskSucceededNotification{
-		Type: butlerd.TaskTypeInstall,
-		InstallResult: &butlerd.InstallResult{
-			Game:   params.Game,
-			Upload: params.Upload,
-			Build:  params.Build,
-		},
-	})
-	if err != nil {
-		return errors.WithStack(err)
+	if !params.PlayableEarly {
+		err := messages.TaskSucceeded.Notify(oc.rc, butlerd.TaskSucceededNotification{
+			Type: butlerd.TaskTypeInstall,
+			InstallResult: &butlerd.InstallResult{
+				Game:   params.Game,
+				Upload: params.Upload,
+				Build:  params.Build,
+			},
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
 	}
 
 	consumer.Opf("Writing receipt...")
@@ -49,7 +65,7 @@ func commitInstall(oc *OperationContext, params *CommitInstallParams) error {
 		Files: res.Files,
 	}
 
-	err = receipt.WriteReceipt(params.InstallFolder)
+	err := writeReceipt(params.InstallFolder, receipt, params.Renames)
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -62,15 +78,102 @@ func commitInstall(oc *OperationContext, params *CommitInstallParams) error {
 			return errors.WithStack(err)
 		}
 
+		// files are still being written to the install folder, and
+		// into the overflow location, until the rest is installed.
+		if !params.PlayableEarly {
+			var accessMode butlerd.InstallLocationAccessMode
+			oc.rc.WithConn(func(conn *sqlite.Conn) {
+				if il := models.InstallLocationByID(conn, cave.InstallLocationID); il != nil {
+					accessMode = butlerd.InstallLocationAccessMode(il.AccessMode)
+				}
+			})
+			err = ApplyAccessMode(consumer, params.InstallFolder, accessMode)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			if cave.OverflowLocationID != "" {
+				cave.OverflowLocationID = splitCave(oc, cave, params.InstallFolder, res.Files)
+			}
+		}
+
+		cave.VirtualMachineRequired = string(virtualMachineRequired(consumer, params.InstallFolder))
+		if cave.VirtualMachineRequired != "" {
+			consumer.Infof("Game needs to run in (%s)", cave.VirtualMachineRequired)
+		}
+
 		consumer.Opf("Saving cave...")
 		cave.SetVerdict(verdict)
 		cave.InstalledSize = verdict.TotalSize
+		var uploadID, buildID int64
+		if params.Upload != nil {
+			uploadID = params.Upload.ID
+		}
+		if params.Build != nil {
+			buildID = params.Build.ID
+		}
+		if cave.InstallCount == 0 || cave.UploadID != uploadID || cave.BuildID != buildID {
+			// heals and reinstalls of the same build don't count
+			cave.InstallCount++
+		}
 		cave.Game = params.Game
 		cave.Upload = params.Upload
 		cave.Build = params.Build
+		cave.ReceiptHash = ReceiptHash(receipt)
+		cave.PlayableEarly = params.PlayableEarly
+		cave.RemainingBytes = params.RemainingBytes
 		cave.UpdateInstallTime()
 		oc.rc.WithConn(cave.SaveWithAssocs)
 	}
 
 	return nil
 }
+
+// splitCave makes sure the large files of cave, and only those, are in
+// its overflow location, and returns the ID of that location, or an empty
+// string if the cave is now in one piece.
+func splitCave(oc *OperationContext, cave *models.Cave, installFolder string, files []string) string {
+	consumer := oc.Consumer()
+
+	var il *models.InstallLocation
+	oc.rc.WithConn(func(conn *sqlite.Conn) {
+		il = models.InstallLocationByID(conn, cave.OverflowLocationID)
+	})
+	if il == nil {
+		consumer.Warnf("Overflow location (%s) not found, keeping everything in one location", cave.OverflowLocationID)
+		err := rejoinInstall(consumer, installFolder)
+		if err != nil {
+			consumer.Warnf("Could not move files back from overflow location: %s", err.Error())
+		}
+		return ""
+	}
+
+	om := syncOverflow(consumer, installFolder, il.ID, il.GetOverflowFolder(cave.ID), files)
+	if om == nil {
+		return ""
+	}
+	return il.ID
+}
+
+// virtualMachineRequired looks for a virtual machine in the prereqs
+// of the manifest in installFolder, if there's one.
+func virtualMachineRequired(consumer *state.Consumer, installFolder string) butlerd.VirtualMachineType {
+	appManifest, err := manifest.Read(installFolder)
+	if err != nil {
+		consumer.Warnf("Could not read manifest: %s", err.Error())
+		return ""
+	}
+	if appManifest == nil {
+		return ""
+	}
+
+	for _, prereq := range appManifest.Prereqs {
+		vm := butlerd.VirtualMachineType(strings.ToLower(prereq.Name))
+		for _, known := range butlerd.VirtualMachineTypeList {
+			if vm == known {
+				return vm
+			}
+		}
+	}
+	return ""
+}
@@ -34,6 +34,13 @@ type OperationContext struct {
 	loaded map[string]struct{}
 
 	pidFilePath string
+
+	// unregisters the staging folder, see acquireStageFolder
+	releaseStage func()
+
+	// set by InstallPerform (or SetInstallID), only used for notifications
+	installID     string
+	patchProgress bool
 }
 
 type PidFileContents struct {
@@ -43,10 +50,19 @@ type PidFileContents struct {
 func LoadContext(ctx context.Context, rc *butlerd.RequestContext, stageFolder string) (*OperationContext, error) {
 	parentConsumer := rc.Consumer
 
+	// wait for any cleanup of that folder to be done first
+	releaseStageFolder := acquireStageFolder(stageFolder)
+
 	err := os.MkdirAll(stageFolder, 0o755)
 	if err != nil {
+		releaseStageFolder()
 		return nil, errors.WithMessage(err, "creating staging folder")
 	}
+	stopHeartbeat := startHeartbeat(stageFolder)
+	releaseStage := func() {
+		stopHeartbeat()
+		releaseStageFolder()
+	}
 
 	pidFilePath := filepath.Join(stageFolder, "operate-pid.json")
 	pidContents := &PidFileContents{
@@ -54,6 +70,7 @@ func LoadContext(ctx context.Context, rc *butlerd.RequestContext, stageFolder st
 	}
 	pidBytes, err := json.Marshal(pidContents)
 	if err != nil {
+		releaseStage()
 		return nil, errors.WithMessage(err, "marshalling pid file")
 	}
 	err = ioutil.WriteFile(pidFilePath, pidBytes, 0o644)
@@ -76,8 +93,11 @@ func LoadContext(ctx context.Context, rc *butlerd.RequestContext, stageFolder st
 		loaded:      make(map[string]struct{}),
 
 		pidFilePath: pidFilePath,
+
+		releaseStage: releaseStage,
 	}
 	if err != nil {
+		releaseStage()
 		return nil, errors.WithStack(err)
 	}
 
@@ -112,20 +132,9 @@ func (oc *OperationContext) Load(s Subcontext) {
 
 	// only load if there's actually something there
 	if val, ok := oc.root[s.Key()]; ok {
-		dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
-			TagName:          "json",
-			Result:           s.GetData(),
-			WeaklyTypedInput: true,
-			DecodeHook:       mapstructure.StringToTimeHookFunc(time.RFC3339Nano),
-		})
-		if err != nil {
-			oc.consumer.Warnf("Could not load subcontext %s: while configuring decoder, %s", s.Key(), err.Error())
-			return
-		}
-
-		err = dec.Decode(val)
+		err := decodeSubcontext(val, s)
 		if err != nil {
-			oc.consumer.Warnf("Could not load subcontext %s: while decoding, %s", s.Key(), err.Error())
+			oc.consumer.Warnf("Could not load subcontext %s: %s", s.Key(), err.Error())
 			return
 		}
 	}
@@ -133,6 +142,56 @@ func (oc *OperationContext) Load(s Subcontext) {
 	oc.loaded[s.Key()] = struct{}{}
 }
 
+func decodeSubcontext(val interface{}, s Subcontext) error {
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		TagName:          "json",
+		Result:           s.GetData(),
+		WeaklyTypedInput: true,
+		DecodeHook:       mapstructure.StringToTimeHookFunc(time.RFC3339Nano),
+	})
+	if err != nil {
+		return errors.WithMessage(err, "while configuring decoder")
+	}
+
+	err = dec.Decode(val)
+	if err != nil {
+		return errors.WithMessage(err, "while decoding")
+	}
+	return nil
+}
+
+// ReadMeta returns the install params saved in the context of
+// stageFolder, without taking over the folder like LoadContext does.
+// It returns an error if there's no context there, or if no install
+// was queued with it.
+func ReadMeta(stageFolder string) (*InstallParams, error) {
+	f, err := os.Open(contextPath(stageFolder))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer f.Close()
+
+	root := make(map[string]interface{})
+	err = json.NewDecoder(f).Decode(&root)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	meta := NewMetaSubcontext()
+	val, ok := root[meta.Key()]
+	if !ok {
+		return nil, errors.Errorf("no install params in context of (%s)", stageFolder)
+	}
+	err = decodeSubcontext(val, meta)
+	if err != nil {
+		return nil, err
+	}
+	if meta.Data.Game == nil || meta.Data.Upload == nil {
+		return nil, errors.Errorf("incomplete install params in context of (%s)", stageFolder)
+	}
+	return meta.Data, nil
+}
+
 func (oc *OperationContext) Save(s Subcontext) error {
 	oc.root[s.Key()] = s.GetData()
 
@@ -162,6 +221,10 @@ func (oc *OperationContext) Release() {
 	if oc.pidFilePath != "" {
 		os.Remove(oc.pidFilePath)
 	}
+	if oc.releaseStage != nil {
+		oc.releaseStage()
+		oc.releaseStage = nil
+	}
 
 	oc.logFile.Close()
 }
@@ -190,6 +253,12 @@ func (oc *OperationContext) Ctx() context.Context {
 	return oc.ctx
 }
 
+// SetInstallID sets the install ID notifications are sent
+// with, for operations that aren't started by InstallPerform
+func (oc *OperationContext) SetInstallID(id string) {
+	oc.installID = id
+}
+
 func contextPath(stageFolder string) string {
 	return filepath.Join(stageFolder, "operate-context.json")
 }
@@ -0,0 +1,81 @@
+package operate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dchest/safefile"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/mansion/apitrail"
+	"github.com/pkg/errors"
+)
+
+func diagnosticsPath(stageFolder string) string {
+	return filepath.Join(stageFolder, "operate-diagnostics.json")
+}
+
+// writeDiagnostics saves the API calls made during a failed operation
+// next to its log, so they can be looked at with ReadDiagnostics.
+func writeDiagnostics(oc *OperationContext, trail *apitrail.Trail, opErr error) error {
+	diag := &butlerd.OperationDiagnostics{
+		FailedAt:     time.Now().UTC(),
+		Error:        (&apitrail.Scrubber{}).Text(fmt.Sprintf("%+v", opErr)),
+		Calls:        FormatTrail(trail),
+		DroppedCalls: trail.Dropped(),
+	}
+
+	f, err := safefile.Create(diagnosticsPath(oc.StageFolder()), 0o644)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(diag)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(f.Commit())
+}
+
+// ReadDiagnostics returns what was saved about the last failed attempt
+// of the operation staged in stageFolder, or nil if there's nothing.
+func ReadDiagnostics(stageFolder string) (*butlerd.OperationDiagnostics, error) {
+	payload, err := ioutil.ReadFile(diagnosticsPath(stageFolder))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	var diag butlerd.OperationDiagnostics
+	err = json.Unmarshal(payload, &diag)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &diag, nil
+}
+
+func FormatTrail(trail *apitrail.Trail) []*butlerd.APICallRecord {
+	var res []*butlerd.APICallRecord
+	for _, c := range trail.Calls() {
+		res = append(res, &butlerd.APICallRecord{
+			StartedAt:     c.StartedAt,
+			Method:        c.Method,
+			URL:           c.URL,
+			Status:        int64(c.Status),
+			LatencyMs:     int64(c.Latency / time.Millisecond),
+			Error:         c.Error,
+			Body:          c.Body,
+			BodyTruncated: c.BodyTruncated,
+		})
+	}
+	return res
+}
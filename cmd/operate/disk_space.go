@@ -0,0 +1,176 @@
+package operate
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/itchio/butler/butlerd"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/united"
+)
+
+// NotEnoughSpaceError is returned when an install location doesn't
+// have enough free space for an upload, see EstimateRequiredSpace.
+type NotEnoughSpaceError struct {
+	// Path we checked the free space of
+	Path string
+	// How many bytes we think the install needs
+	Required int64
+	// How many bytes are free at Path
+	Available int64
+
+	// When other install locations were tried too, why
+	// none of them fit, in the order they were tried.
+	Locations []*LocationSpace
+}
+
+// LocationSpace is why an install location can't fit an install.
+type LocationSpace struct {
+	InstallLocationID string
+	Path              string
+	Required          int64
+	Available         int64
+}
+
+// Deficit is how many more bytes would need to be free.
+func (ls *LocationSpace) Deficit() int64 {
+	return ls.Required - ls.Available
+}
+
+var _ butlerd.Error = (*NotEnoughSpaceError)(nil)
+
+func (e *NotEnoughSpaceError) RpcErrorCode() int64 {
+	return int64(butlerd.CodeNotEnoughSpace)
+}
+
+func (e *NotEnoughSpaceError) RpcErrorMessage() string {
+	msg := fmt.Sprintf("%s (%s needed, %s available)",
+		butlerd.CodeNotEnoughSpace.RpcErrorMessage(),
+		united.FormatBytes(e.Required), united.FormatBytes(e.Available))
+	if len(e.Locations) > 0 {
+		var deficits []string
+		for _, ls := range e.Locations {
+			deficits = append(deficits, fmt.Sprintf("%s is %s short", ls.InstallLocationID, united.FormatBytes(ls.Deficit())))
+		}
+		msg += fmt.Sprintf(", no install location fits: %s", strings.Join(deficits, ", "))
+	}
+	return msg
+}
+
+func (e *NotEnoughSpaceError) RpcErrorData() map[string]interface{} {
+	data := map[string]interface{}{
+		"required":  e.Required,
+		"available": e.Available,
+	}
+	if len(e.Locations) > 0 {
+		var locations []map[string]interface{}
+		for _, ls := range e.Locations {
+			locations = append(locations, map[string]interface{}{
+				"installLocationId": ls.InstallLocationID,
+				"path":              ls.Path,
+				"required":          ls.Required,
+				"available":         ls.Available,
+				"deficit":           ls.Deficit(),
+			})
+		}
+		data["locations"] = locations
+	}
+	return data
+}
+
+func (e *NotEnoughSpaceError) Error() string {
+	return e.RpcErrorMessage()
+}
+
+// ArchiveSizeMultiplier is how much larger than an archive we assume
+// its contents are, when we have no way of knowing.
+const ArchiveSizeMultiplier = 2
+
+var archiveExtensions = []string{
+	".zip", ".7z", ".rar", ".tar", ".gz", ".tgz", ".bz2", ".tbz2", ".xz", ".txz", ".dmg",
+}
+
+// EstimateRequiredSpace returns how many bytes installing upload (and
+// build, for wharf-enabled uploads) should take up, before we've
+// downloaded anything. It returns 0 if it has no idea.
+//
+// Single-file builds and uploads take up their own size. For archives,
+// the size of the extracted contents isn't known until we look inside,
+// so it errs on the side of caution.
+func EstimateRequiredSpace(upload *itchio.Upload, build *itchio.Build) int64 {
+	if build != nil {
+		for _, f := range build.Files {
+			if f.Type == itchio.BuildFileTypeUnpacked && f.Size > 0 {
+				return f.Size
+			}
+		}
+	}
+
+	if upload.Size <= 0 {
+		return 0
+	}
+
+	if upload.Storage == itchio.UploadStorageBuild || isArchiveName(upload.Filename) {
+		return upload.Size * ArchiveSizeMultiplier
+	}
+	return upload.Size
+}
+
+// EstimateDownloadSize returns how many bytes installing upload (and
+// build, for wharf-enabled uploads) downloads, or 0 if it has no idea.
+func EstimateDownloadSize(upload *itchio.Upload, build *itchio.Build) int64 {
+	if build != nil {
+		for _, f := range build.Files {
+			if f.Type == itchio.BuildFileTypeArchive && f.Size > 0 {
+				return f.Size
+			}
+		}
+	}
+
+	if upload.Size <= 0 {
+		return 0
+	}
+	return upload.Size
+}
+
+// EstimatePatchesSize returns how many bytes applying the patches of
+// upgradePath from index on downloads, picking optimized patches when
+// there are some, like upgrade does.
+func EstimatePatchesSize(upgradePath *itchio.UpgradePath, index int) int64 {
+	var size int64
+	for i := index; i < len(upgradePath.Builds); i++ {
+		files := upgradePath.Builds[i].Files
+		f := FindBuildFile(files, itchio.BuildFileTypePatch, itchio.BuildFileSubTypeOptimized)
+		if f == nil {
+			f = FindBuildFile(files, itchio.BuildFileTypePatch, itchio.BuildFileSubTypeDefault)
+		}
+		if f != nil {
+			size += f.Size
+		}
+	}
+	return size
+}
+
+func isArchiveName(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	for _, archiveExt := range archiveExtensions {
+		if ext == archiveExt {
+			return true
+		}
+	}
+	return false
+}
+
+// TrimArchiveExtensions strips archive extensions off the end of name,
+// so "Some.Game.v1.2.tar.gz" becomes "Some.Game.v1.2". Other dots are kept.
+func TrimArchiveExtensions(name string) string {
+	for isArchiveName(name) {
+		trimmed := strings.TrimSuffix(name, path.Ext(name))
+		if trimmed == "" {
+			break
+		}
+		name = trimmed
+	}
+	return name
+}
@@ -0,0 +1,60 @@
+package operate_test
+
+import (
+	"testing"
+
+	"github.com/itchio/butler/cmd/operate"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateRequiredSpace(t *testing.T) {
+	mb := int64(1024 * 1024)
+
+	single := &itchio.Upload{Filename: "setup.exe", Size: 10 * mb}
+	assert.EqualValues(t, 10*mb, operate.EstimateRequiredSpace(single, nil), "single files take up their own size")
+
+	archive := &itchio.Upload{Filename: "Game-Linux.TAR.GZ", Size: 10 * mb}
+	assert.EqualValues(t, 10*mb*operate.ArchiveSizeMultiplier, operate.EstimateRequiredSpace(archive, nil), "archives get the multiplier")
+
+	wharf := &itchio.Upload{Filename: "default.zip", Size: 10 * mb, Storage: itchio.UploadStorageBuild}
+	assert.EqualValues(t, 20*mb, operate.EstimateRequiredSpace(wharf, &itchio.Build{
+		Files: []*itchio.BuildFile{
+			{Type: itchio.BuildFileTypeArchive, Size: 10 * mb},
+			{Type: itchio.BuildFileTypeSignature, Size: mb},
+		},
+	}), "wharf archives get the multiplier")
+
+	assert.EqualValues(t, 12*mb, operate.EstimateRequiredSpace(wharf, &itchio.Build{
+		Files: []*itchio.BuildFile{
+			{Type: itchio.BuildFileTypeArchive, Size: 10 * mb},
+			{Type: itchio.BuildFileTypeUnpacked, Size: 12 * mb},
+		},
+	}), "single-file builds take up the size of that file")
+
+	assert.EqualValues(t, 0, operate.EstimateRequiredSpace(&itchio.Upload{Filename: "game.zip"}, nil), "unknown size")
+}
+
+func TestEstimateDownloadSize(t *testing.T) {
+	mb := int64(1024 * 1024)
+
+	single := &itchio.Upload{Filename: "setup.exe", Size: 10 * mb}
+	assert.EqualValues(t, 10*mb, operate.EstimateDownloadSize(single, nil), "uploads are downloaded as-is")
+
+	wharf := &itchio.Upload{Filename: "default.zip", Size: 10 * mb, Storage: itchio.UploadStorageBuild}
+	assert.EqualValues(t, 8*mb, operate.EstimateDownloadSize(wharf, &itchio.Build{
+		Files: []*itchio.BuildFile{
+			{Type: itchio.BuildFileTypeSignature, Size: mb},
+			{Type: itchio.BuildFileTypeArchive, Size: 8 * mb},
+		},
+	}), "wharf builds download their archive")
+
+	assert.EqualValues(t, 0, operate.EstimateDownloadSize(&itchio.Upload{Filename: "game.zip"}, nil), "unknown size")
+}
+
+func TestTrimArchiveExtensions(t *testing.T) {
+	assert.EqualValues(t, "Some.Game.v1.2", operate.TrimArchiveExtensions("Some.Game.v1.2.tar.gz"))
+	assert.EqualValues(t, "Kiosk Game", operate.TrimArchiveExtensions("Kiosk Game.ZIP"))
+	assert.EqualValues(t, "game.exe", operate.TrimArchiveExtensions("game.exe"), "not an archive")
+	assert.EqualValues(t, ".zip", operate.TrimArchiveExtensions(".zip"), "names aren't left empty")
+}
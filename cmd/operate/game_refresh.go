@@ -0,0 +1,68 @@
+package operate
+
+import (
+	"context"
+	"net"
+	"time"
+
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/pkg/errors"
+)
+
+// GameGetter is the part of *itchio.Client RetryGetGame needs
+type GameGetter interface {
+	GetGame(ctx context.Context, p itchio.GetGameParams) (*itchio.GetGameResponse, error)
+}
+
+// how long to wait before each retry, swapped out in tests
+var getGameRetryDelays = []time.Duration{
+	500 * time.Millisecond,
+	1 * time.Second,
+	2 * time.Second,
+}
+
+// getGameRetryWindow is how long RetryGetGame may take, all attempts included
+const getGameRetryWindow = 10 * time.Second
+
+// RetryGetGame fetches a game, retrying a few times with increasing
+// delays if the API has a server error or times out. Other errors,
+// like 404s, are returned right away.
+func RetryGetGame(ctx context.Context, consumer *state.Consumer, client GameGetter, params itchio.GetGameParams) (*itchio.GetGameResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, getGameRetryWindow)
+	defer cancel()
+
+	for attempt := 0; ; attempt++ {
+		res, err := client.GetGame(ctx, params)
+		if err == nil {
+			return res, nil
+		}
+		if attempt >= len(getGameRetryDelays) || !isTransientAPIError(err) {
+			return nil, err
+		}
+
+		delay := getGameRetryDelays[attempt]
+		consumer.Infof("Could not fetch game %d, retrying in %s: %v", params.GameID, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, err
+		}
+	}
+}
+
+// isTransientAPIError returns true for errors that may go away by
+// themselves: server errors and timeouts.
+func isTransientAPIError(err error) bool {
+	if ae, ok := itchio.AsAPIError(err); ok {
+		return ae.StatusCode >= 500
+	}
+	cause := errors.Cause(err)
+	if cause == context.DeadlineExceeded {
+		return true
+	}
+	if ne, ok := cause.(net.Error); ok && ne.Timeout() {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package operate
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeGameGetter struct {
+	errs  []error
+	calls int
+}
+
+func (f *fakeGameGetter) GetGame(ctx context.Context, p itchio.GetGameParams) (*itchio.GetGameResponse, error) {
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	return &itchio.GetGameResponse{Game: &itchio.Game{ID: p.GameID}}, nil
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func Test_RetryGetGame(t *testing.T) {
+	assert := assert.New(t)
+	consumer := &state.Consumer{}
+	ctx := context.Background()
+	params := itchio.GetGameParams{GameID: 42}
+
+	defer func(delays []time.Duration) { getGameRetryDelays = delays }(getGameRetryDelays)
+	getGameRetryDelays = []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond}
+
+	serverError := &itchio.APIError{StatusCode: 502, Path: "/games/42"}
+	notFound := &itchio.APIError{StatusCode: 404, Path: "/games/42"}
+	timeout := &url.Error{Op: "Get", URL: "https://api.itch.io/games/42", Err: timeoutError{}}
+
+	f := &fakeGameGetter{}
+	res, err := RetryGetGame(ctx, consumer, f, params)
+	assert.NoError(err)
+	assert.EqualValues(42, res.Game.ID)
+	assert.EqualValues(1, f.calls)
+
+	f = &fakeGameGetter{errs: []error{serverError, errors.WithStack(timeout)}}
+	res, err = RetryGetGame(ctx, consumer, f, params)
+	assert.NoError(err, "server errors and timeouts are retried")
+	assert.EqualValues(42, res.Game.ID)
+	assert.EqualValues(3, f.calls)
+
+	f = &fakeGameGetter{errs: []error{serverError, serverError, serverError, serverError}}
+	_, err = RetryGetGame(ctx, consumer, f, params)
+	assert.Error(err)
+	assert.EqualValues(4, f.calls, "gives up after three retries")
+
+	f = &fakeGameGetter{errs: []error{errors.WithStack(notFound)}}
+	_, err = RetryGetGame(ctx, consumer, f, params)
+	assert.Error(err)
+	assert.EqualValues(1, f.calls, "404s aren't retried")
+
+	getGameRetryDelays = []time.Duration{time.Hour, time.Hour, time.Hour}
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	f = &fakeGameGetter{errs: []error{serverError}}
+	_, err = RetryGetGame(cancelledCtx, consumer, f, params)
+	assert.Error(err)
+	assert.EqualValues(1, f.calls, "doesn't wait past the context")
+}
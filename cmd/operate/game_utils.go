@@ -5,13 +5,11 @@ import (
 	"strings"
 
 	"crawshaw.io/sqlite"
-	"xorm.io/builder"
 
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/database/models"
 	"github.com/itchio/butler/manager"
 
-	"github.com/itchio/hades"
 	"github.com/itchio/headway/state"
 	"github.com/itchio/headway/united"
 
@@ -27,9 +25,7 @@ func GameToString(game *itchio.Game) string {
 	return fmt.Sprintf("%s - %s", game.Title, game.URL)
 }
 
-func GetFilteredUploads(rc *butlerd.RequestContext, game *itchio.Game) (*manager.NarrowDownUploadsResult, error) {
-	consumer := rc.Consumer
-
+func listGameUploads(rc *butlerd.RequestContext, game *itchio.Game) ([]*itchio.Upload, error) {
 	var access *GameAccess
 	rc.WithConn(func(conn *sqlite.Conn) {
 		access = AccessForGameID(conn, game.ID)
@@ -43,19 +39,60 @@ func GetFilteredUploads(rc *butlerd.RequestContext, game *itchio.Game) (*manager
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	consumer.Debugf("API returned %d uploads", len(uploads.Uploads))
+	rc.Consumer.Debugf("API returned %d uploads", len(uploads.Uploads))
+	return uploads.Uploads, nil
+}
+
+// ExplainFilteredUploads is like GetFilteredUploads, but also reports
+// how each compatible upload was scored.
+func ExplainFilteredUploads(rc *butlerd.RequestContext, game *itchio.Game) (*manager.ExplainUploadsResult, error) {
+	uploads, err := listGameUploads(rc, game)
+	if err != nil {
+		return nil, err
+	}
+
+	return manager.ExplainUploads(rc.Consumer, game, uploads, rc.HostEnumerator())
+}
+
+// FilteredUploads is what's left of a game's uploads once incompatible
+// ones are filtered out, and why those were.
+type FilteredUploads struct {
+	*manager.NarrowDownUploadsResult
 
-	numInputs := len(uploads.Uploads)
+	// One per upload that was filtered out, in the order they were listed
+	Rejections []*butlerd.UploadRejection
+}
+
+func GetFilteredUploads(rc *butlerd.RequestContext, game *itchio.Game) (*FilteredUploads, error) {
+	consumer := rc.Consumer
+
+	uploads, err := listGameUploads(rc, game)
+	if err != nil {
+		return nil, err
+	}
+
+	numInputs := len(uploads)
 	if numInputs == 0 {
 		consumer.Infof("No uploads found at all (that we can access)")
 	}
-	uploadsFilterResult, err := manager.NarrowDownUploads(consumer, game, uploads.Uploads, rc.HostEnumerator())
+	explainRes, err := manager.ExplainUploads(consumer, game, uploads, rc.HostEnumerator())
 	if err != nil {
 		return nil, err
 	}
-	consumer.Debugf("Narrow returned %d uploads", len(uploadsFilterResult.Uploads))
+	res := &FilteredUploads{
+		NarrowDownUploadsResult: explainRes.Narrowed,
+	}
+	for _, e := range explainRes.Explanations {
+		if e.ExcludedBy != "" {
+			res.Rejections = append(res.Rejections, &butlerd.UploadRejection{
+				Upload: e.Upload,
+				Reason: butlerd.UploadExclusion(e.ExcludedBy),
+			})
+		}
+	}
+	consumer.Debugf("Narrow returned %d uploads", len(res.Uploads))
 
-	numResults := len(uploadsFilterResult.Uploads)
+	numResults := len(res.Uploads)
 
 	if numInputs > 0 {
 		if numResults == 0 {
@@ -67,12 +104,68 @@ func GetFilteredUploads(rc *butlerd.RequestContext, game *itchio.Game) (*manager
 		}
 
 		consumer.Infof("→ Narrowed %d uploads down to %s: ", numInputs, qualif)
-		for _, u := range uploadsFilterResult.Uploads {
+		for _, u := range res.Uploads {
 			LogUpload(consumer, u, u.Build)
 		}
 	}
 
-	return uploadsFilterResult, nil
+	return res, nil
+}
+
+// NoCompatibleUploadsError is returned when all of a game's
+// uploads were filtered out, see GetFilteredUploads.
+type NoCompatibleUploadsError struct {
+	Rejections []*butlerd.UploadRejection
+}
+
+var _ butlerd.Error = (*NoCompatibleUploadsError)(nil)
+
+func (e *NoCompatibleUploadsError) RpcErrorCode() int64 {
+	return int64(butlerd.CodeNoCompatibleUploads)
+}
+
+func (e *NoCompatibleUploadsError) RpcErrorMessage() string {
+	return butlerd.CodeNoCompatibleUploads.RpcErrorMessage()
+}
+
+func (e *NoCompatibleUploadsError) RpcErrorData() map[string]interface{} {
+	rejections := e.Rejections
+	if rejections == nil {
+		rejections = []*butlerd.UploadRejection{}
+	}
+	return map[string]interface{}{
+		"rejections": rejections,
+	}
+}
+
+func (e *NoCompatibleUploadsError) Error() string {
+	return e.RpcErrorMessage()
+}
+
+// AmbiguousUploadError is returned when several uploads are compatible,
+// and none could be picked.
+type AmbiguousUploadError struct {
+	Uploads []*itchio.Upload
+}
+
+var _ butlerd.Error = (*AmbiguousUploadError)(nil)
+
+func (e *AmbiguousUploadError) RpcErrorCode() int64 {
+	return int64(butlerd.CodeAmbiguousUpload)
+}
+
+func (e *AmbiguousUploadError) RpcErrorMessage() string {
+	return butlerd.CodeAmbiguousUpload.RpcErrorMessage()
+}
+
+func (e *AmbiguousUploadError) RpcErrorData() map[string]interface{} {
+	return map[string]interface{}{
+		"uploads": e.Uploads,
+	}
+}
+
+func (e *AmbiguousUploadError) Error() string {
+	return e.RpcErrorMessage()
 }
 
 func LogUpload(consumer *state.Consumer, u *itchio.Upload, b *itchio.Build) {
@@ -181,83 +274,6 @@ func formatUploadType(uploadType itchio.UploadType) string {
 	}
 }
 
-type GameAccess struct {
-	APIKey      string                 `json:"api_key"`
-	Credentials itchio.GameCredentials `json:"credentials"`
-}
-
-func (ga *GameAccess) OnlyAPIKey() *GameAccess {
-	return &GameAccess{
-		APIKey: ga.APIKey,
-	}
-}
-
-func AccessForGameID(conn *sqlite.Conn, gameID int64) *GameAccess {
-	// TODO: write unit test for this
-
-	// look for owner access
-	{
-		pgs := models.ProfileGamesByGameID(conn, gameID)
-		if len(pgs) > 0 {
-			pg := pgs[0]
-			profile := models.ProfileByID(conn, pg.ProfileID)
-
-			if profile != nil {
-				access := &GameAccess{
-					APIKey: profile.APIKey,
-				}
-				return access
-			}
-		}
-	}
-
-	// look for a download key
-	{
-		dks := models.DownloadKeysByGameID(conn, gameID)
-
-		for _, dk := range dks {
-			profile := models.ProfileByID(conn, dk.OwnerID)
-			if profile == nil {
-				continue
-			}
-
-			access := &GameAccess{
-				APIKey: profile.APIKey,
-				Credentials: itchio.GameCredentials{
-					DownloadKeyID: dk.ID,
-				},
-			}
-			return access
-		}
-	}
-
-	// no special credentials
-	{
-		var profiles []*models.Profile
-		models.MustSelect(conn, &profiles, builder.NewCond(), hades.Search{}.OrderBy("last_connected DESC"))
-		if len(profiles) == 0 {
-			panic(errors.New("No profiles found"))
-		}
-
-		// prefer press user
-		for _, profile := range profiles {
-			if profile.PressUser {
-				access := &GameAccess{
-					APIKey: profile.APIKey,
-				}
-				return access
-			}
-		}
-
-		// just take the most recent then
-		profile := profiles[0]
-		access := &GameAccess{
-			APIKey: profile.APIKey,
-		}
-		return access
-	}
-}
-
 func ValidateCave(rc *butlerd.RequestContext, caveID string) *models.Cave {
 	if caveID == "" {
 		panic(errors.New("caveId must be set"))
@@ -6,6 +6,7 @@ import (
 
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/pathsafety"
 	"github.com/itchio/hush"
 	"github.com/itchio/hush/bfs"
 
@@ -85,6 +86,11 @@ func heal(oc *OperationContext, meta *MetaSubcontext, isub *InstallSubcontext, r
 		united.FormatBytes(sigInfo.Container.Size),
 	)
 
+	err = CheckPathSafety(consumer, params.InstallFolder, pathsafety.ContainerPaths(sigInfo.Container))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
 	consumer.Infof("Healing container...")
 
 	timeBeforeHeal := time.Now()
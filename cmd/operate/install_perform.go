@@ -12,6 +12,8 @@ import (
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/butlerd/messages"
 	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/mansion/apitrail"
+	"github.com/itchio/butler/mansion/throttle"
 	"github.com/itchio/httpkit/eos"
 	"github.com/itchio/httpkit/eos/option"
 	"github.com/itchio/wharf/pwr/patcher"
@@ -19,6 +21,7 @@ import (
 	"github.com/itchio/hush"
 	"github.com/itchio/hush/download"
 	"github.com/itchio/hush/installers"
+	"github.com/itchio/savior"
 
 	"github.com/pkg/errors"
 )
@@ -28,11 +31,22 @@ func InstallPerform(ctx context.Context, rc *butlerd.RequestContext, performPara
 		return nil, errors.New("No staging folder specified")
 	}
 
+	// record API calls, in case we fail
+	trail := apitrail.New(apitrail.DefaultCapacity)
+	ctx = apitrail.WithTrail(ctx, trail)
+	parentCtx := rc.Ctx
+	rc.Ctx = apitrail.WithTrail(parentCtx, trail)
+	defer func() {
+		rc.Ctx = parentCtx
+	}()
+
 	oc, err := LoadContext(ctx, rc, performParams.StagingFolder)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 	defer oc.Release()
+	oc.installID = performParams.ID
+	oc.patchProgress = performParams.PatchProgress
 
 	meta := NewMetaSubcontext()
 	oc.Load(meta)
@@ -40,6 +54,9 @@ func InstallPerform(ctx context.Context, rc *butlerd.RequestContext, performPara
 	res, err := doInstallPerform(oc, meta)
 	if err != nil {
 		oc.Consumer().Errorf("%+v", err)
+		if diagErr := writeDiagnostics(oc, trail, err); diagErr != nil {
+			oc.Consumer().Warnf("Could not write diagnostics: %+v", diagErr)
+		}
 		return nil, errors.WithStack(err)
 	}
 
@@ -152,6 +169,46 @@ func doInstallPerform(oc *OperationContext, meta *MetaSubcontext) (*butlerd.Inst
 	return res, nil
 }
 
+// attachCave sets the cave the install operation is for,
+// creating it if it's a fresh install.
+func attachCave(oc *OperationContext, params *InstallParams) {
+	if params.NoCave {
+		return
+	}
+
+	var cave *models.Cave
+	oc.rc.WithConn(func(conn *sqlite.Conn) {
+		cave = models.CaveByID(conn, params.CaveID)
+	})
+	if cave == nil {
+		cave = &models.Cave{
+			ID:                params.CaveID,
+			InstallFolderName: params.InstallFolderName,
+			InstallLocationID: params.InstallLocationID,
+			DLCParentCaveID:   params.ParentCaveID,
+		}
+		if params.UseParentFolder {
+			cave.CustomInstallFolder = params.InstallFolder
+		}
+	}
+	if params.Access != nil && params.Access.Explanation != nil {
+		cave.SourceProfileID = params.Access.Explanation.ProfileID
+	}
+	if params.Pinned {
+		cave.Pinned = true
+	}
+	if params.SkipPrereqs {
+		cave.PrereqsSkipped = true
+	}
+	// the queue already settled on the cave's or the client's value
+	cave.IgnoreInstallers = params.IgnoreInstallers
+	if params.OverflowLocationID != "" {
+		cave.OverflowLocationID = params.OverflowLocationID
+	}
+
+	oc.cave = cave
+}
+
 func doInstallPerformInner(oc *OperationContext, meta *MetaSubcontext, isub *InstallSubcontext) error {
 	rc := oc.rc
 	params := meta.Data
@@ -163,7 +220,8 @@ func doInstallPerformInner(oc *OperationContext, meta *MetaSubcontext, isub *Ins
 	}
 
 	{
-		if !istate.RefreshedGame {
+		// simulated transfers and local archives never talk to itch.io
+		if !istate.RefreshedGame && Simulation == nil && params.LocalArchivePath == "" {
 			client := rc.Client(params.Access.APIKey)
 			istate.RefreshedGame = true
 			err := oc.Save(isub)
@@ -199,23 +257,24 @@ func doInstallPerformInner(oc *OperationContext, meta *MetaSubcontext, isub *Ins
 	}
 	defer rlock.Unlock()
 
-	return InstallPrepare(oc, meta, isub, true, func(prepareRes *InstallPrepareResult) error {
-		if !params.NoCave {
-			var cave *models.Cave
-			rc.WithConn(func(conn *sqlite.Conn) {
-				cave = models.CaveByID(conn, params.CaveID)
-			})
-			if cave == nil {
-				cave = &models.Cave{
-					ID:                params.CaveID,
-					InstallFolderName: params.InstallFolderName,
-					InstallLocationID: params.InstallLocationID,
-				}
-			}
+	if Simulation != nil {
+		attachCave(oc, params)
+		return simulateInstall(oc, meta)
+	}
 
-			oc.cave = cave
-		}
+	if params.PlayEarly && !params.NoCave {
+		// slowed down while the game is running, see BeginPlayEarlySession
+		limiter, untrack := trackPlayEarly(params.CaveID)
+		defer untrack()
+		oc.ctx = throttle.WithLimiter(oc.ctx, limiter)
+	}
 
+	return InstallPrepare(oc, meta, isub, true, func(prepareRes *InstallPrepareResult) error {
+		attachCave(oc, params)
+		SuggestReceiptRebuild(rc, params.CaveID, params.InstallFolder, prepareRes.ReceiptIn)
+
+		// the files of split installs are patched and healed through their
+		// symlinks, commitInstall moves whatever got written whole.
 		if prepareRes.Strategy == InstallPerformStrategyUpgrade {
 			err := upgrade(oc, meta, isub, prepareRes.ReceiptIn)
 			if err == nil || errors.Cause(err) == patcher.ErrStop {
@@ -302,8 +361,41 @@ func doInstallPerformInner(oc *OperationContext, meta *MetaSubcontext, isub *Ins
 				return nil, errors.WithStack(err)
 			}
 
+			var wrap SinkWrapper
+			var rename func(name string) string
+			if installerInfo.Type == hush.InstallerTypeArchive {
+				wrap = overflowSinkWrapper(oc, oc.cave, params.InstallFolder)
+			}
+			if len(istate.Renames) > 0 {
+				rename = renamer(istate.Renames)
+				inner := wrap
+				wrap = func(sink savior.Sink) savior.Sink {
+					if inner != nil {
+						sink = inner(sink)
+					}
+					return &renamingSink{Sink: sink, rename: rename}
+				}
+			}
+			if params.PlayEarly && oc.cave != nil && installerInfo.Type == hush.InstallerTypeArchive {
+				// sees entries under their name in the archive
+				if early := playEarlySinkWrapper(oc, params, installerInfo, managerInstallParams.File, istate.Renames); early != nil {
+					inner := wrap
+					wrap = func(sink savior.Sink) savior.Sink {
+						if inner != nil {
+							sink = inner(sink)
+						}
+						return early(sink)
+					}
+				}
+			}
+
 			oc.rc.StartProgress()
-			res, err := manager.Install(managerInstallParams)
+			var res *hush.InstallResult
+			if wrap != nil {
+				res, err = installArchive(managerInstallParams, wrap, rename)
+			} else {
+				res, err = manager.Install(managerInstallParams)
+			}
 			oc.rc.EndProgress()
 
 			if err != nil {
@@ -318,7 +410,12 @@ func doInstallPerformInner(oc *OperationContext, meta *MetaSubcontext, isub *Ins
 		if installResult != nil {
 			consumer.Infof("First install already completed (%d files)", len(installResult.Files))
 		} else {
-			var err error
+			renames, err := checkArchivePathSafety(consumer, installerInfo, prepareRes.File, params.InstallFolder)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			istate.Renames = renames
+
 			installResult, err = tryInstall()
 			if err != nil && errors.Cause(err) == hush.ErrNeedLocal {
 				lf, localErr := doForceLocal(prepareRes.File, oc, meta, isub)
@@ -366,6 +463,7 @@ func doInstallPerformInner(oc *OperationContext, meta *MetaSubcontext, isub *Ins
 			Build:         params.Build,
 
 			InstallResult: installResult,
+			Renames:       istate.Renames,
 		})
 
 	})
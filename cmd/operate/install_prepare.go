@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/mansion/telemetry"
+	"github.com/itchio/butler/mansion/throttle"
 	"github.com/itchio/hush"
 	"github.com/itchio/hush/bfs"
 	itchio "github.com/itchio/go-itchio"
@@ -27,11 +29,24 @@ type InstallPrepareResult struct {
 	File      eos.File
 	ReceiptIn *bfs.Receipt
 	Strategy  InstallPerformStrategy
+	// Set if the source was sniffed just now. Nil when installing over
+	// the same upload, or if source information was cached.
+	DiskUsage *DiskUsageInfo
 }
 
 type InstallTask func(res *InstallPrepareResult) error
 
 func InstallPrepare(oc *OperationContext, meta *MetaSubcontext, isub *InstallSubcontext, allowDownloads bool, task InstallTask) error {
+	pp := newPrepareProgress(oc)
+	defer pp.stop()
+
+	return installPrepare(oc, meta, isub, allowDownloads, pp, func(res *InstallPrepareResult) error {
+		pp.ready()
+		return task(res)
+	})
+}
+
+func installPrepare(oc *OperationContext, meta *MetaSubcontext, isub *InstallSubcontext, allowDownloads bool, pp *prepareProgress, task InstallTask) error {
 	rc := oc.rc
 	params := meta.Data
 	consumer := oc.Consumer()
@@ -54,7 +69,9 @@ func InstallPrepare(oc *OperationContext, meta *MetaSubcontext, isub *InstallSub
 
 	istate := isub.Data
 
-	if istate.DownloadSessionID == "" {
+	if params.LocalArchivePath != "" {
+		consumer.Infof("→ Installing from local archive (%s)", params.LocalArchivePath)
+	} else if istate.DownloadSessionID == "" {
 		res, err := client.NewDownloadSession(rc.Ctx, itchio.NewDownloadSessionParams{
 			GameID:      params.Game.ID,
 			Credentials: params.Access.Credentials,
@@ -96,6 +113,7 @@ func InstallPrepare(oc *OperationContext, meta *MetaSubcontext, isub *InstallSub
 					return task(res)
 				}
 
+				pp.setStage(butlerd.InstallPrepareStageFetchingSignature, 0)
 				upgradeRes, err := client.GetBuildUpgradePath(rc.Ctx, itchio.GetBuildUpgradePathParams{
 					CurrentBuildID: oldID,
 					TargetBuildID:  newID,
@@ -108,6 +126,7 @@ func InstallPrepare(oc *OperationContext, meta *MetaSubcontext, isub *InstallSub
 					return task(res)
 				}
 
+				pp.setStage(butlerd.InstallPrepareStageComputingPatch, 0)
 				upgradePath := upgradeRes.UpgradePath
 				// skip the current build, we're not interested in it
 				upgradePath.Builds = upgradePath.Builds[1:]
@@ -185,7 +204,19 @@ func InstallPrepare(oc *OperationContext, meta *MetaSubcontext, isub *InstallSub
 	installSourceURL := MakeSourceURL(client, consumer, istate.DownloadSessionID, params, installSourceFileType)
 
 	beforeOpen := time.Now()
-	file, err := eos.Open(installSourceURL, option.WithConsumer(consumer))
+	openOpts := []option.Option{option.WithConsumer(consumer)}
+	if httpClient := rc.HTTPClient; httpClient != nil {
+		if dt := telemetry.FromContext(oc.ctx); dt != nil {
+			httpClient = dt.Client(httpClient)
+		}
+		for _, l := range throttle.FromContext(oc.ctx) {
+			httpClient = l.Client(httpClient)
+		}
+		if httpClient != rc.HTTPClient {
+			openOpts = append(openOpts, option.WithHTTPClient(httpClient))
+		}
+	}
+	file, err := eos.Open(installSourceURL, openOpts...)
 	consumer.Infof("(opening file took %s)", time.Since(beforeOpen))
 	if err != nil {
 		return errors.WithStack(err)
@@ -220,7 +251,14 @@ func InstallPrepare(oc *OperationContext, meta *MetaSubcontext, isub *InstallSub
 	if istate.InstallerInfo == nil || istate.InstallerInfo.Type == hush.InstallerTypeUnknown {
 		consumer.Infof("Determining source information...")
 
-		installerInfo, err := hush.GetInstallerInfo(consumer, file)
+		var sourceSize int64
+		if stats, err := file.Stat(); err == nil {
+			sourceSize = stats.Size()
+		}
+		pp.setStage(butlerd.InstallPrepareStageComputingPatch, sourceSize)
+		probedFile := pp.wrap(file)
+
+		installerInfo, err := hush.GetInstallerInfo(consumer, probedFile)
 		if err != nil {
 			return errors.WithStack(err)
 		}
@@ -243,14 +281,18 @@ func InstallPrepare(oc *OperationContext, meta *MetaSubcontext, isub *InstallSub
 			}
 		}
 
-		dui, err := AssessDiskUsage(file, receiptIn, params.InstallFolder, installerInfo)
+		dui, err := AssessDiskUsage(probedFile, receiptIn, params.InstallFolder, installerInfo, params.OverflowLocationID != "")
 		if err != nil {
 			return errors.WithMessage(err, "assessing disk usage")
 		}
 
 		consumer.Infof("Estimated disk usage (accuracy: %s)", dui.Accuracy)
 		consumer.Infof("  ✓ %s needed free space", united.FormatBytes(dui.NeededFreeSpace))
+		if dui.OverflowNeededFreeSpace > 0 {
+			consumer.Infof("  ✓ %s of which in overflow location", united.FormatBytes(dui.OverflowNeededFreeSpace))
+		}
 		consumer.Infof("  ✓ %s final disk usage", united.FormatBytes(dui.FinalDiskUsage))
+		res.DiskUsage = dui
 
 		istate.InstallerInfo = installerInfo
 		err = oc.Save(isub)
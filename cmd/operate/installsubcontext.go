@@ -10,6 +10,7 @@ type InstallSubcontextState struct {
 	InstallerInfo       *hush.InstallerInfo `json:"installerInfo,omitempty"`
 	IsAvailableLocally  bool                `json:"isAvailableLocally,omitempty"`
 	FirstInstallResult  *hush.InstallResult `json:"firstInstallResult,omitempty"`
+	Renames             map[string]string   `json:"renames,omitempty"`
 	SecondInstallerInfo *hush.InstallerInfo `json:"secondInstallerInfo,omitempty"`
 	UpgradePath         *itchio.UpgradePath `json:"upgradePath,omitempty"`
 	UpgradePathIndex    int                 `json:"upgradePathIndex,omitempty"`
@@ -7,6 +7,10 @@ import (
 )
 
 func MakeSourceURL(client *itchio.Client, consumer *state.Consumer, sessionID string, params *InstallParams, fileType string) string {
+	if params.LocalArchivePath != "" {
+		return params.LocalArchivePath
+	}
+
 	build := params.Build
 	if build != nil {
 		if fileType == "" {
@@ -25,6 +25,26 @@ type InstallParams struct {
 
 	IgnoreInstallers bool `json:"ignoreInstallers,omitempty"`
 
+	// Don't install prerequisites at first launch
+	SkipPrereqs bool `json:"skipPrereqs,omitempty"`
+
+	// Pin the cave to the installed build
+	Pinned bool `json:"pinned,omitempty"`
+
+	// Install from this archive on disk instead of itch.io
+	LocalArchivePath string `json:"localArchivePath,omitempty"`
+
+	// Move large files to this install location once installed
+	OverflowLocationID string `json:"overflowLocationId,omitempty"`
+
+	// Cave this one is DLC for, if any
+	ParentCaveID string `json:"parentCaveId,omitempty"`
+	// Whether InstallFolder is inside the parent cave's install folder
+	UseParentFolder bool `json:"useParentFolder,omitempty"`
+
+	// Commit the cave as soon as the game can start, see PlanPlayEarly
+	PlayEarly bool `json:"playEarly,omitempty"`
+
 	Access *GameAccess `json:"credentials"`
 }
 
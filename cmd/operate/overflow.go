@@ -0,0 +1,499 @@
+package operate
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/headway/united"
+	"github.com/itchio/hush/bfs"
+	"github.com/itchio/savior"
+	"github.com/pkg/errors"
+)
+
+// OverflowMinSize is the size from which installed files are moved
+// to a cave's overflow location.
+const OverflowMinSize = 8 * 1024 * 1024
+
+// OverflowMap records which files of an install folder were moved
+// to an overflow location, and symlinked back in their place. Files
+// keep the same relative path in the overflow folder.
+type OverflowMap struct {
+	LocationID string `json:"locationId"`
+	Folder     string `json:"folder"`
+
+	// Slash-separated paths, relative to both folders
+	Files []string `json:"files"`
+}
+
+func overflowMapPath(installFolder string) string {
+	return filepath.Join(installFolder, ".itch", "overflow.json")
+}
+
+// ReadOverflowMap returns the overflow map of installFolder,
+// or nil if it isn't split.
+func ReadOverflowMap(installFolder string) (*OverflowMap, error) {
+	payload, err := ioutil.ReadFile(overflowMapPath(installFolder))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	om := &OverflowMap{}
+	err = json.Unmarshal(payload, om)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing overflow map")
+	}
+	return om, nil
+}
+
+func (om *OverflowMap) write(installFolder string) error {
+	payload, err := json.Marshal(om)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	path := overflowMapPath(installFolder)
+	err = bfs.Mkdir(filepath.Dir(path))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return ioutil.WriteFile(path, payload, 0o644)
+}
+
+// Usage returns how many bytes of the install are in the overflow folder.
+func (om *OverflowMap) Usage() int64 {
+	var total int64
+	for _, f := range om.Files {
+		if stats, err := os.Stat(filepath.Join(om.Folder, filepath.FromSlash(f))); err == nil {
+			total += stats.Size()
+		}
+	}
+	return total
+}
+
+// overflowBound returns true if a file goes to the overflow location
+// of a split install: large files, except executables, since some games
+// look at where their executable really is.
+func overflowBound(name string, size int64, mode os.FileMode) bool {
+	return size >= OverflowMinSize && !isExecutableFile(name, mode)
+}
+
+// overflowSink extracts the files that go to an overflow location
+// straight there, and symlinks them into the install folder, so the
+// install location only ever holds its own share.
+type overflowSink struct {
+	savior.Sink
+	installFolder string
+	overflow      *savior.FolderSink
+}
+
+var _ savior.Sink = (*overflowSink)(nil)
+
+func (s *overflowSink) routes(entry *savior.Entry) bool {
+	return entry.Kind == savior.EntryKindFile && overflowBound(entry.CanonicalPath, entry.UncompressedSize, entry.Mode)
+}
+
+func (s *overflowSink) link(entry *savior.Entry) error {
+	src := filepath.Join(s.installFolder, filepath.FromSlash(entry.CanonicalPath))
+	dst := filepath.Join(s.overflow.Directory, filepath.FromSlash(entry.CanonicalPath))
+	if target, err := os.Readlink(src); err == nil && target == dst {
+		// resuming
+		return nil
+	}
+
+	err := bfs.Mkdir(filepath.Dir(src))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = os.RemoveAll(src)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Symlink(dst, src))
+}
+
+func (s *overflowSink) GetWriter(entry *savior.Entry) (savior.EntryWriter, error) {
+	if !s.routes(entry) {
+		return s.Sink.GetWriter(entry)
+	}
+	err := s.link(entry)
+	if err != nil {
+		return nil, err
+	}
+	return s.overflow.GetWriter(entry)
+}
+
+func (s *overflowSink) Preallocate(entry *savior.Entry) error {
+	if !s.routes(entry) {
+		return s.Sink.Preallocate(entry)
+	}
+	err := s.link(entry)
+	if err != nil {
+		return err
+	}
+	return s.overflow.Preallocate(entry)
+}
+
+func (s *overflowSink) Nuke() error {
+	err := s.overflow.Nuke()
+	if err != nil {
+		return err
+	}
+	return s.Sink.Nuke()
+}
+
+func (s *overflowSink) Close() error {
+	err := s.overflow.Close()
+	if err != nil {
+		s.Sink.Close()
+		return err
+	}
+	return s.Sink.Close()
+}
+
+// overflowSinkWrapper returns a SinkWrapper that extracts the large files
+// of cave straight to its overflow location, or nil if it doesn't have one,
+// or if they couldn't be symlinked into installFolder. syncOverflow takes
+// care of what's left at commit time.
+func overflowSinkWrapper(oc *OperationContext, cave *models.Cave, installFolder string) SinkWrapper {
+	if cave == nil || cave.OverflowLocationID == "" {
+		return nil
+	}
+	consumer := oc.Consumer()
+
+	var il *models.InstallLocation
+	oc.rc.WithConn(func(conn *sqlite.Conn) {
+		il = models.InstallLocationByID(conn, cave.OverflowLocationID)
+	})
+	if il == nil {
+		return nil
+	}
+	overflowFolder := il.GetOverflowFolder(cave.ID)
+
+	err := bfs.Mkdir(overflowFolder)
+	if err != nil {
+		consumer.Warnf("Could not create overflow folder, extracting everything to the install folder: %s", err.Error())
+		return nil
+	}
+	if !canSymlink(installFolder, overflowFolder) {
+		consumer.Warnf("Can't create symlinks in (%s), extracting everything to the install folder", installFolder)
+		return nil
+	}
+
+	consumer.Infof("Extracting large files to overflow location (%s)", il.ID)
+	return func(sink savior.Sink) savior.Sink {
+		return &overflowSink{
+			Sink:          sink,
+			installFolder: installFolder,
+			overflow: &savior.FolderSink{
+				Directory: overflowFolder,
+				Consumer:  consumer,
+			},
+		}
+	}
+}
+
+// syncOverflow brings the overflow folder of a split install in line with
+// files, the files of the build now in installFolder. Files that are already
+// in the overflow folder stay there (patches and heals work on them through
+// their symlink), new large files that ended up in installFolder are moved
+// there, and files that are no longer part of the build are removed.
+//
+// If that can't be done, the install is put back in one piece, and nil
+// is returned.
+func syncOverflow(consumer *state.Consumer, installFolder string, locationID string, overflowFolder string, files []string) *OverflowMap {
+	previous, err := ReadOverflowMap(installFolder)
+	if err != nil {
+		consumer.Warnf("Could not read overflow map: %s", err.Error())
+		previous = nil
+	}
+
+	om := &OverflowMap{
+		LocationID: locationID,
+		Folder:     overflowFolder,
+	}
+	kept := make(map[string]bool)
+	fail := func() *OverflowMap {
+		if previous != nil {
+			// some of those may not have been looked at yet
+			rest := &OverflowMap{Folder: previous.Folder}
+			for _, f := range previous.Files {
+				if !kept[f] {
+					rest.Files = append(rest.Files, f)
+				}
+			}
+			if previous.Folder == overflowFolder {
+				om.Files = append(om.Files, rest.Files...)
+			} else {
+				rejoinFiles(consumer, installFolder, rest)
+			}
+		}
+		rejoinFiles(consumer, installFolder, om)
+		os.Remove(overflowMapPath(installFolder))
+		return nil
+	}
+
+	err = bfs.Mkdir(overflowFolder)
+	if err != nil {
+		consumer.Warnf("Could not create overflow folder, keeping everything in one location: %s", err.Error())
+		return fail()
+	}
+	if !canSymlink(installFolder, overflowFolder) {
+		consumer.Warnf("Can't create symlinks in (%s), keeping everything in one location", installFolder)
+		return fail()
+	}
+
+	var movedFiles int
+	var moved int64
+	for _, f := range files {
+		src := filepath.Join(installFolder, filepath.FromSlash(f))
+		dst := filepath.Join(overflowFolder, filepath.FromSlash(f))
+		stats, err := os.Lstat(src)
+		if err != nil {
+			continue
+		}
+
+		if stats.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(src)
+			if err != nil || !isOverflowFile(target, previous, overflowFolder) {
+				// one of the game's own symlinks
+				continue
+			}
+			if target != dst {
+				// renamed by a patch, or the overflow location changed
+				err = moveFile(target, dst)
+				if err == nil {
+					err = os.Remove(src)
+				}
+				if err == nil {
+					err = os.Symlink(dst, src)
+				}
+				if err != nil {
+					consumer.Warnf("Could not move (%s) in overflow location, keeping everything in one location: %s", f, err.Error())
+					return fail()
+				}
+			}
+			kept[f] = true
+			om.Files = append(om.Files, f)
+			continue
+		}
+
+		if !stats.Mode().IsRegular() || !overflowBound(f, stats.Size(), stats.Mode()) {
+			continue
+		}
+
+		// written whole by an installer, a patch or a heal
+		err = moveFile(src, dst)
+		if err == nil {
+			err = os.Symlink(dst, src)
+			if err != nil {
+				_ = moveFile(dst, src)
+			}
+		}
+		if err != nil {
+			consumer.Warnf("Could not move (%s) to overflow location, keeping everything in one location: %s", f, err.Error())
+			return fail()
+		}
+		kept[f] = true
+		om.Files = append(om.Files, f)
+		movedFiles++
+		moved += stats.Size()
+	}
+
+	if previous != nil {
+		if previous.Folder != overflowFolder {
+			err := os.RemoveAll(previous.Folder)
+			if err != nil {
+				consumer.Warnf("Could not remove previous overflow folder: %s", err.Error())
+			}
+		} else {
+			for _, f := range previous.Files {
+				if !kept[f] {
+					os.Remove(filepath.Join(overflowFolder, filepath.FromSlash(f)))
+				}
+			}
+		}
+	}
+
+	if len(om.Files) == 0 {
+		consumer.Infof("No files are large enough to go to the overflow location")
+		os.RemoveAll(overflowFolder)
+		os.Remove(overflowMapPath(installFolder))
+		return nil
+	}
+
+	sort.Strings(om.Files)
+	err = om.write(installFolder)
+	if err != nil {
+		consumer.Warnf("Could not write overflow map, keeping everything in one location: %s", err.Error())
+		return fail()
+	}
+
+	if movedFiles > 0 {
+		consumer.Statf("Moved %d files (%s) to overflow location (%s)", movedFiles, united.FormatBytes(moved), locationID)
+	}
+	consumer.Infof("%d files are in overflow location (%s)", len(om.Files), locationID)
+	return om
+}
+
+// isOverflowFile returns true if target is in the overflow folder of
+// an install, either the one it had or the one it's getting.
+func isOverflowFile(target string, previous *OverflowMap, overflowFolder string) bool {
+	inside := func(folder string) bool {
+		rel, err := filepath.Rel(folder, target)
+		if err != nil || rel == "." || rel == ".." {
+			return false
+		}
+		return !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+	}
+	if previous != nil && inside(previous.Folder) {
+		return true
+	}
+	return inside(overflowFolder)
+}
+
+// rejoinInstall moves files back from the overflow location of
+// installFolder, if it was split, so it's in one piece again.
+func rejoinInstall(consumer *state.Consumer, installFolder string) error {
+	om, err := ReadOverflowMap(installFolder)
+	if err != nil {
+		return err
+	}
+	if om == nil {
+		return nil
+	}
+
+	consumer.Infof("Moving %d files back from overflow location (%s)", len(om.Files), om.LocationID)
+	err = rejoinFiles(consumer, installFolder, om)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(overflowMapPath(installFolder))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// rejoinFiles moves the files of om back where their symlinks are,
+// then removes the overflow folder. Files that were replaced in the
+// install folder since they were moved are left alone.
+func rejoinFiles(consumer *state.Consumer, installFolder string, om *OverflowMap) error {
+	for _, f := range om.Files {
+		src := filepath.Join(om.Folder, filepath.FromSlash(f))
+		dst := filepath.Join(installFolder, filepath.FromSlash(f))
+		if _, err := os.Lstat(src); err != nil {
+			consumer.Warnf("(%s) is missing from overflow location", f)
+			continue
+		}
+
+		if stats, err := os.Lstat(dst); err == nil {
+			if stats.Mode()&os.ModeSymlink == 0 {
+				// the overflow copy is stale
+				continue
+			}
+			err = os.Remove(dst)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		err := moveFile(src, dst)
+		if err != nil {
+			return errors.Wrapf(err, "moving (%s) back from overflow location", f)
+		}
+	}
+
+	err := os.RemoveAll(om.Folder)
+	if err != nil {
+		consumer.Warnf("Could not remove overflow folder: %s", err.Error())
+	}
+	return nil
+}
+
+// canSymlink returns true if a symlink to target can be made in folder.
+func canSymlink(folder string, target string) bool {
+	probePath := filepath.Join(folder, ".itch", "overflow-probe")
+	err := bfs.Mkdir(filepath.Dir(probePath))
+	if err != nil {
+		return false
+	}
+	os.Remove(probePath)
+
+	err = os.Symlink(target, probePath)
+	if err != nil {
+		return false
+	}
+	os.Remove(probePath)
+	return true
+}
+
+var executableExtensions = []string{".exe", ".dll", ".so", ".dylib", ".sh", ".bat", ".x86", ".x86_64"}
+
+func isExecutableFile(name string, mode os.FileMode) bool {
+	if mode&0o111 != 0 {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range executableExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// moveFile renames src to dst, copying it over if they're
+// not on the same device.
+func moveFile(src string, dst string) error {
+	err := bfs.Mkdir(filepath.Dir(dst))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if os.Rename(src, dst) == nil {
+		return nil
+	}
+
+	err = copyFile(src, dst)
+	if err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return os.Remove(src)
+}
+
+func copyFile(src string, dst string) error {
+	stats, err := os.Stat(src)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	reader, err := os.Open(src)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer reader.Close()
+
+	writer, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, stats.Mode().Perm())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	_, err = io.Copy(writer, reader)
+	if err != nil {
+		writer.Close()
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(writer.Close())
+}
@@ -0,0 +1,165 @@
+package operate
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/headway/state"
+	"github.com/itchio/savior"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncOverflow(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "split-install")
+	must(t, err)
+	defer os.RemoveAll(dir)
+
+	installFolder := filepath.Join(dir, "install")
+	overflowFolder := filepath.Join(dir, "overflow", "cave")
+	levelPath := filepath.Join(installFolder, "data", "level1.pak")
+	overflowLevelPath := filepath.Join(overflowFolder, "data", "level1.pak")
+
+	big := func(b byte) []byte {
+		return bytes.Repeat([]byte{b}, OverflowMinSize)
+	}
+	write := func(name string, contents []byte, mode os.FileMode) {
+		fullPath := filepath.Join(installFolder, filepath.FromSlash(name))
+		must(t, os.MkdirAll(filepath.Dir(fullPath), 0o755))
+		must(t, ioutil.WriteFile(fullPath, contents, mode))
+	}
+	isSymlink := func(path string) bool {
+		stats, err := os.Lstat(path)
+		must(t, err)
+		return stats.Mode()&os.ModeSymlink != 0
+	}
+	write("game.exe", big('e'), 0o644)
+	write("game.x86_64", big('x'), 0o755)
+	write("data/level1.pak", big('a'), 0o644)
+	write("data/small.pak", []byte("small"), 0o644)
+	files := []string{"game.exe", "game.x86_64", "data/level1.pak", "data/small.pak"}
+
+	consumer := &state.Consumer{}
+	om := syncOverflow(consumer, installFolder, "second", overflowFolder, files)
+	if !assert.NotNil(om) {
+		return
+	}
+	assert.EqualValues("second", om.LocationID)
+	assert.EqualValues([]string{"data/level1.pak"}, om.Files, "executables and small files stay")
+	assert.EqualValues(OverflowMinSize, om.Usage())
+	assert.True(isSymlink(levelPath))
+	contents, err := ioutil.ReadFile(levelPath)
+	must(t, err)
+	assert.EqualValues(big('a'), contents, "files can be read through their symlink")
+
+	readOM, err := ReadOverflowMap(installFolder)
+	must(t, err)
+	assert.EqualValues(om, readOM)
+
+	// patches write through the symlink
+	must(t, ioutil.WriteFile(levelPath, big('b'), 0o644))
+	assert.NotNil(syncOverflow(consumer, installFolder, "second", overflowFolder, files))
+	assert.True(isSymlink(levelPath))
+	contents, err = ioutil.ReadFile(overflowLevelPath)
+	must(t, err)
+	assert.EqualValues(big('b'), contents, "patched in place")
+
+	// heals and some patches replace the symlink with a whole file
+	must(t, os.Remove(levelPath))
+	write("data/level1.pak", big('c'), 0o644)
+	assert.NotNil(syncOverflow(consumer, installFolder, "second", overflowFolder, files))
+	assert.True(isSymlink(levelPath))
+	contents, err = ioutil.ReadFile(overflowLevelPath)
+	must(t, err)
+	assert.EqualValues(big('c'), contents, "whole files replace the overflow copy")
+
+	// patches may rename files
+	must(t, os.Rename(levelPath, filepath.Join(installFolder, "data", "level2.pak")))
+	renamed := []string{"game.exe", "game.x86_64", "data/level2.pak", "data/small.pak"}
+	om = syncOverflow(consumer, installFolder, "second", overflowFolder, renamed)
+	if assert.NotNil(om) {
+		assert.EqualValues([]string{"data/level2.pak"}, om.Files)
+	}
+	assert.NoFileExists(overflowLevelPath)
+	assert.FileExists(filepath.Join(overflowFolder, "data", "level2.pak"))
+
+	// moving to another overflow location
+	otherFolder := filepath.Join(dir, "other", "cave")
+	om = syncOverflow(consumer, installFolder, "third", otherFolder, renamed)
+	if assert.NotNil(om) {
+		assert.EqualValues("third", om.LocationID)
+	}
+	assert.NoDirExists(overflowFolder)
+	contents, err = ioutil.ReadFile(filepath.Join(installFolder, "data", "level2.pak"))
+	must(t, err)
+	assert.EqualValues(big('c'), contents)
+
+	must(t, rejoinInstall(consumer, installFolder))
+	assert.False(isSymlink(filepath.Join(installFolder, "data", "level2.pak")))
+	assert.NoDirExists(otherFolder)
+	readOM, err = ReadOverflowMap(installFolder)
+	must(t, err)
+	assert.Nil(readOM)
+	must(t, rejoinInstall(consumer, installFolder))
+
+	// files that leave the build leave the overflow location
+	assert.NotNil(syncOverflow(consumer, installFolder, "second", overflowFolder, renamed))
+	must(t, os.Remove(filepath.Join(installFolder, "data", "level2.pak")))
+	assert.Nil(syncOverflow(consumer, installFolder, "second", overflowFolder, []string{"data/small.pak"}), "nothing left to split")
+	assert.NoDirExists(overflowFolder)
+	readOM, err = ReadOverflowMap(installFolder)
+	must(t, err)
+	assert.Nil(readOM)
+}
+
+func TestOverflowSink(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "overflow-sink")
+	must(t, err)
+	defer os.RemoveAll(dir)
+
+	installFolder := filepath.Join(dir, "install")
+	overflowFolder := filepath.Join(dir, "overflow", "cave")
+	sink := &overflowSink{
+		Sink:          &savior.FolderSink{Directory: installFolder},
+		installFolder: installFolder,
+		overflow:      &savior.FolderSink{Directory: overflowFolder},
+	}
+
+	extract := func(name string, contents []byte, mode os.FileMode) {
+		entry := &savior.Entry{
+			CanonicalPath:    name,
+			Kind:             savior.EntryKindFile,
+			Mode:             mode,
+			UncompressedSize: int64(len(contents)),
+		}
+		w, err := sink.GetWriter(entry)
+		must(t, err)
+		_, err = w.Write(contents)
+		must(t, err)
+		must(t, w.Close())
+	}
+	extract("game.x86_64", make([]byte, OverflowMinSize), 0o755)
+	extract("data/level1.pak", make([]byte, OverflowMinSize), 0o644)
+	extract("data/small.pak", []byte("small"), 0o644)
+	must(t, sink.Close())
+
+	stats, err := os.Lstat(filepath.Join(installFolder, "data", "level1.pak"))
+	must(t, err)
+	assert.True(stats.Mode()&os.ModeSymlink != 0, "large files are symlinked")
+	stats, err = os.Stat(filepath.Join(overflowFolder, "data", "level1.pak"))
+	must(t, err)
+	assert.EqualValues(OverflowMinSize, stats.Size(), "and extracted straight to the overflow folder")
+
+	for _, name := range []string{"game.x86_64", "data/small.pak"} {
+		stats, err := os.Lstat(filepath.Join(installFolder, filepath.FromSlash(name)))
+		must(t, err)
+		assert.True(stats.Mode().IsRegular(), "(%s) stays in the install folder", name)
+		assert.NoFileExists(filepath.Join(overflowFolder, filepath.FromSlash(name)))
+	}
+}
@@ -0,0 +1,212 @@
+package operate
+
+import (
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/headway/united"
+	"github.com/itchio/lake/tlc"
+	"github.com/itchio/wharf/pwr/bowl"
+)
+
+const (
+	patchProgressNotifyInterval = 500 * time.Millisecond
+	patchProgressLogInterval    = 5 * time.Second
+)
+
+// patchProgress follows what the patcher does to individual files,
+// by watching the bowl it writes to. It sends it to the client as
+// @@InstallPatchProgressNotification (if asked to), and to the
+// operation log, less often.
+type patchProgress struct {
+	rc       *butlerd.RequestContext
+	consumer *state.Consumer
+	notify   bool
+
+	source      *tlc.Container
+	target      *tlc.Container
+	sourcePaths map[string]struct{}
+	targetPaths map[string]struct{}
+	deleted     []string
+	renamed     map[string]struct{}
+
+	current    butlerd.InstallPatchProgressNotification
+	lastNotify time.Time
+	lastLog    time.Time
+}
+
+func newPatchProgress(oc *OperationContext, source *tlc.Container, target *tlc.Container) *patchProgress {
+	sourcePaths := make(map[string]struct{})
+	for _, f := range source.Files {
+		sourcePaths[f.Path] = struct{}{}
+	}
+	targetPaths := make(map[string]struct{})
+	var deleted []string
+	for _, f := range target.Files {
+		targetPaths[f.Path] = struct{}{}
+		if _, ok := sourcePaths[f.Path]; !ok {
+			deleted = append(deleted, f.Path)
+		}
+	}
+
+	pp := &patchProgress{
+		rc:          oc.rc,
+		consumer:    oc.Consumer(),
+		notify:      oc.patchProgress,
+		source:      source,
+		target:      target,
+		sourcePaths: sourcePaths,
+		targetPaths: targetPaths,
+		deleted:     deleted,
+		renamed:     make(map[string]struct{}),
+		lastLog:     time.Now(),
+	}
+	pp.current.ID = oc.installID
+	pp.current.OperationsTotal = int64(len(source.Files) + len(deleted))
+	return pp
+}
+
+// Wrap returns a bowl that reports to pp whatever is done through b.
+func (pp *patchProgress) Wrap(b bowl.Bowl) bowl.Bowl {
+	return &observedBowl{Bowl: b, pp: pp}
+}
+
+func (pp *patchProgress) startFile(index int64, op butlerd.PatchOperation) {
+	f := pp.source.Files[index]
+	pp.current.Path = f.Path
+	pp.current.Operation = op
+	pp.current.FileBytesDone = 0
+	pp.current.FileBytesTotal = f.Size
+	// the patcher goes through source files in order
+	pp.current.OperationsDone = index
+	pp.send(false)
+}
+
+func (pp *patchProgress) fileProgress(bytesDone int64) {
+	pp.current.FileBytesDone = bytesDone
+	pp.send(false)
+}
+
+func (pp *patchProgress) fileDone(index int64) {
+	pp.current.OperationsDone = index + 1
+	pp.send(false)
+}
+
+// finish is called once the bowl has been committed, which
+// is when files absent from the new build are removed.
+func (pp *patchProgress) finish() {
+	done := int64(len(pp.source.Files))
+	for _, path := range pp.deleted {
+		if _, ok := pp.renamed[path]; ok {
+			continue
+		}
+		done++
+		pp.current.Path = path
+		pp.current.Operation = butlerd.PatchOperationDelete
+		pp.current.FileBytesDone = 0
+		pp.current.FileBytesTotal = 0
+		pp.current.OperationsDone = done
+		pp.send(false)
+	}
+	pp.current.OperationsDone = pp.current.OperationsTotal
+	pp.send(true)
+}
+
+func (pp *patchProgress) send(force bool) {
+	if pp.notify && (force || time.Since(pp.lastNotify) >= patchProgressNotifyInterval) {
+		pp.lastNotify = time.Now()
+		_ = messages.InstallPatchProgress.Notify(pp.rc, pp.current)
+	}
+
+	if force || time.Since(pp.lastLog) >= patchProgressLogInterval {
+		pp.lastLog = time.Now()
+		c := pp.current
+		pp.consumer.Infof("Patching: %d/%d files done, %s (%s, %s / %s)",
+			c.OperationsDone, c.OperationsTotal, c.Path, c.Operation,
+			united.FormatBytes(c.FileBytesDone), united.FormatBytes(c.FileBytesTotal))
+	}
+}
+
+type observedBowl struct {
+	bowl.Bowl
+	pp *patchProgress
+}
+
+var _ bowl.Bowl = (*observedBowl)(nil)
+
+func (ob *observedBowl) GetWriter(index int64) (bowl.EntryWriter, error) {
+	w, err := ob.Bowl.GetWriter(index)
+	if err != nil {
+		return nil, err
+	}
+
+	op := butlerd.PatchOperationCreate
+	if _, ok := ob.pp.targetPaths[ob.pp.source.Files[index].Path]; ok {
+		op = butlerd.PatchOperationPatch
+	}
+	ob.pp.startFile(index, op)
+
+	return &observedWriter{EntryWriter: w, pp: ob.pp, index: index}, nil
+}
+
+func (ob *observedBowl) Transpose(t bowl.Transposition) error {
+	err := ob.Bowl.Transpose(t)
+	if err != nil {
+		return err
+	}
+
+	targetPath := ob.pp.target.Files[t.TargetIndex].Path
+	if ob.pp.source.Files[t.SourceIndex].Path != targetPath {
+		_, kept := ob.pp.sourcePaths[targetPath]
+		_, seen := ob.pp.renamed[targetPath]
+		if !kept && !seen {
+			// looked like a deletion until now
+			ob.pp.renamed[targetPath] = struct{}{}
+			ob.pp.current.OperationsTotal--
+		}
+		ob.pp.startFile(t.SourceIndex, butlerd.PatchOperationRename)
+		ob.pp.fileProgress(ob.pp.current.FileBytesTotal)
+	}
+	ob.pp.fileDone(t.SourceIndex)
+	return nil
+}
+
+func (ob *observedBowl) Commit() error {
+	err := ob.Bowl.Commit()
+	if err != nil {
+		return err
+	}
+
+	ob.pp.finish()
+	return nil
+}
+
+type observedWriter struct {
+	bowl.EntryWriter
+	pp    *patchProgress
+	index int64
+}
+
+func (ow *observedWriter) Resume(checkpoint *bowl.WriterCheckpoint) (int64, error) {
+	offset, err := ow.EntryWriter.Resume(checkpoint)
+	if err == nil {
+		ow.pp.fileProgress(offset)
+	}
+	return offset, err
+}
+
+func (ow *observedWriter) Write(buf []byte) (int, error) {
+	n, err := ow.EntryWriter.Write(buf)
+	ow.pp.fileProgress(ow.EntryWriter.Tell())
+	return n, err
+}
+
+func (ow *observedWriter) Finalize() error {
+	err := ow.EntryWriter.Finalize()
+	if err == nil {
+		ow.pp.fileDone(ow.index)
+	}
+	return err
+}
@@ -0,0 +1,188 @@
+package operate
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/itchio/boar"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/mansion/settings"
+	"github.com/itchio/butler/pathsafety"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/hush"
+	"github.com/itchio/hush/manifest"
+	"github.com/itchio/ox"
+)
+
+// UnsafeFilenamesError is returned when a build contains files that
+// can't be created on this platform. It lists all of them, so the
+// user (or developer) doesn't have to find out one by one.
+type UnsafeFilenamesError struct {
+	Platform  ox.Platform
+	Offenders []pathsafety.Offender
+}
+
+var _ butlerd.Error = (*UnsafeFilenamesError)(nil)
+
+func (e *UnsafeFilenamesError) RpcErrorCode() int64 {
+	return int64(butlerd.CodeUnsafeFilenames)
+}
+
+func (e *UnsafeFilenamesError) RpcErrorMessage() string {
+	const maxListed = 5
+
+	var names []string
+	for i, o := range e.Offenders {
+		if i >= maxListed {
+			names = append(names, fmt.Sprintf("and %d more", len(e.Offenders)-maxListed))
+			break
+		}
+		names = append(names, o.String())
+	}
+	return fmt.Sprintf("%s (%d entries can't be created on %s: %s)",
+		butlerd.CodeUnsafeFilenames.RpcErrorMessage(),
+		len(e.Offenders),
+		e.Platform,
+		strings.Join(names, "; "),
+	)
+}
+
+func (e *UnsafeFilenamesError) RpcErrorData() map[string]interface{} {
+	return map[string]interface{}{
+		"platform":  e.Platform,
+		"offenders": e.Offenders,
+	}
+}
+
+func (e *UnsafeFilenamesError) Error() string {
+	return e.RpcErrorMessage()
+}
+
+var sanitizeFilenames = settings.Register(settings.Setting{
+	Key:         "install.sanitizeFilenames",
+	Description: "Install files whose names can't be created on this platform under a sanitized name, instead of failing. Only applies to zip archives.",
+	Default:     false,
+})
+
+// CheckPathSafety makes sure all paths can be created in installFolder
+// on the current platform. Non-fatal problems (like long paths) are only
+// logged as warnings.
+func CheckPathSafety(consumer *state.Consumer, installFolder string, paths []string) error {
+	platform := ox.CurrentRuntime().Platform
+	report := pathsafety.Scan(platform, installFolder, paths)
+	if len(report.Offenders) == 0 {
+		return nil
+	}
+
+	for _, o := range report.Offenders {
+		consumer.Warnf("Unsafe path on %s: %s", platform, o)
+	}
+
+	fatal := report.Fatal()
+	if len(fatal) == 0 {
+		return nil
+	}
+
+	return &UnsafeFilenamesError{
+		Platform:  platform,
+		Offenders: fatal,
+	}
+}
+
+// checkArchivePathSafety scans the entries of an archive before it is
+// extracted. Only zip files are supported: their central directory tells
+// us about all entries without decompressing anything.
+//
+// If some entries can't be created and install.sanitizeFilenames is
+// set, it returns the names they should be extracted as instead.
+func checkArchivePathSafety(consumer *state.Consumer, installerInfo *hush.InstallerInfo, file eos.File, installFolder string) (map[string]string, error) {
+	if installerInfo == nil || installerInfo.Type != hush.InstallerTypeArchive {
+		return nil, nil
+	}
+	ai := installerInfo.ArchiveInfo
+	if ai == nil || (ai.Strategy != boar.StrategyZip && ai.Strategy != boar.StrategyZipUnsure) {
+		return nil, nil
+	}
+
+	stats, err := file.Stat()
+	if err != nil {
+		consumer.Warnf("Could not stat archive, skipping path safety check: %s", err.Error())
+		return nil, nil
+	}
+
+	paths, err := pathsafety.ZipPaths(file, stats.Size())
+	if err != nil {
+		// the archive installer will have a better error to show, if any
+		consumer.Warnf("Could not list archive entries, skipping path safety check: %s", err.Error())
+		return nil, nil
+	}
+
+	consumer.Infof("Checking %d archive entries for unsafe paths...", len(paths))
+	err = CheckPathSafety(consumer, installFolder, paths)
+	if err == nil || !sanitizeFilenames.Bool() {
+		return nil, err
+	}
+
+	platform := ox.CurrentRuntime().Platform
+	renames := pathsafety.Renames(platform, paths)
+	var sanitized []string
+	for _, p := range paths {
+		if np, ok := renames[strings.TrimSuffix(p, "/")]; ok {
+			sanitized = append(sanitized, np)
+		} else {
+			sanitized = append(sanitized, p)
+		}
+	}
+	if len(pathsafety.Scan(platform, installFolder, sanitized).Fatal()) > 0 {
+		// shouldn't happen, but better to fail than to extract half of it
+		return nil, err
+	}
+
+	for from, to := range renames {
+		consumer.Infof("Will install (%s) as (%s)", from, to)
+	}
+	consumer.Warnf("Installing %d entries under sanitized names", len(renames))
+	return renames, nil
+}
+
+// renamer returns a func that gives the name entries should be
+// extracted as, according to renames.
+func renamer(renames map[string]string) func(name string) string {
+	return func(name string) string {
+		if np, ok := renames[strings.TrimSuffix(name, "/")]; ok {
+			return np
+		}
+		return name
+	}
+}
+
+// ReadAppManifest reads the app manifest in installFolder, if any.
+// Actions that point to files installed under a sanitized name
+// point to where those files are instead.
+func ReadAppManifest(consumer *state.Consumer, installFolder string) (*manifest.Manifest, error) {
+	appManifest, err := manifest.Read(installFolder)
+	if err != nil || appManifest == nil {
+		return appManifest, err
+	}
+
+	renames, err := ReadReceiptRenames(installFolder)
+	if err != nil {
+		consumer.Warnf("Could not read renamed files from receipt: %s", err.Error())
+		return appManifest, nil
+	}
+	if len(renames) == 0 {
+		return appManifest, nil
+	}
+
+	for i := range appManifest.Actions {
+		action := &appManifest.Actions[i]
+		if np, ok := renames[path.Clean(filepath.ToSlash(action.Path))]; ok {
+			consumer.Infof("Action (%s) points to (%s), installed as (%s)", action.Name, action.Path, np)
+			action.Path = np
+		}
+	}
+	return appManifest, nil
+}
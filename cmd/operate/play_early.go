@@ -0,0 +1,429 @@
+package operate
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/itchio/boar"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/mansion/settings"
+	"github.com/itchio/butler/mansion/throttle"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/headway/united"
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/hush"
+	"github.com/itchio/savior"
+	"github.com/pkg/errors"
+)
+
+// PlayEarlyPlan says which files of an archive a game needs to start,
+// going by the optional components its app manifest declares.
+type PlayEarlyPlan struct {
+	// Names of the optional components
+	Components []string
+	// Files (slash-separated, relative to the archive's root)
+	// the game needs before it can start
+	Required map[string]bool
+	// Uncompressed size of the files it can start without
+	OptionalSize int64
+
+	// every entry of the archive, in the order they're extracted
+	entries []playEarlyEntry
+}
+
+type playEarlyEntry struct {
+	name     string
+	size     int64
+	required bool
+}
+
+// optionalManifest is what butler reads from an app manifest
+// (.itch.toml) to tell which files a game can start without:
+//
+//	[[optional]]
+//	name = "soundtrack"
+//	paths = ["Soundtrack", "Movies/*.webm"]
+//
+// A path matches a file if it's that file, a folder the file is in,
+// or a pattern (see path.Match) for that file.
+type optionalManifest struct {
+	Optional []optionalComponent `toml:"optional"`
+}
+
+type optionalComponent struct {
+	Name  string   `toml:"name"`
+	Paths []string `toml:"paths"`
+}
+
+func (oc optionalComponent) matches(file string) bool {
+	for _, p := range oc.Paths {
+		p = strings.Trim(p, "/")
+		if p == "" {
+			continue
+		}
+		if file == p || strings.HasPrefix(file, p+"/") {
+			return true
+		}
+		if ok, _ := path.Match(p, file); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// PlanPlayEarly reads the app manifest of a zip archive, without
+// extracting anything. It returns nil if the archive has no manifest,
+// or if the manifest declares no optional files.
+func PlanPlayEarly(r io.ReaderAt, size int64) (*PlayEarlyPlan, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var om optionalManifest
+	for _, f := range zr.File {
+		if f.Name != ".itch.toml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		_, err = toml.DecodeReader(rc, &om)
+		rc.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "reading optional components from app manifest")
+		}
+	}
+	if len(om.Optional) == 0 {
+		return nil, nil
+	}
+
+	plan := &PlayEarlyPlan{
+		Required: make(map[string]bool),
+	}
+	for _, oc := range om.Optional {
+		plan.Components = append(plan.Components, oc.Name)
+	}
+	for _, f := range zr.File {
+		entry := playEarlyEntry{
+			name: f.Name,
+			size: int64(f.UncompressedSize64),
+		}
+		if strings.HasSuffix(f.Name, "/") {
+			// folders are created along with their files
+			plan.entries = append(plan.entries, entry)
+			continue
+		}
+		optional := false
+		for _, oc := range om.Optional {
+			if oc.matches(f.Name) {
+				optional = true
+				break
+			}
+		}
+		if optional {
+			plan.OptionalSize += entry.size
+		} else {
+			plan.Required[f.Name] = true
+			entry.required = true
+		}
+		plan.entries = append(plan.entries, entry)
+	}
+	if plan.OptionalSize == 0 {
+		// nothing matched, there's nothing to gain
+		return nil, nil
+	}
+	return plan, nil
+}
+
+var playEarlyBytesPerSecond = settings.Register(settings.Setting{
+	Key:         "install.playEarlyBytesPerSecond",
+	Description: "Bandwidth left to the rest of a play-early install while its game is running, in bytes per second. 0 means unlimited.",
+	Default:     int64(1024 * 1024),
+	Validate: func(value interface{}) error {
+		if value.(int64) < 0 {
+			return errors.New("must be positive")
+		}
+		return nil
+	},
+})
+
+// playEarlyCave is a cave whose game can start before it's fully
+// installed, for as long as it's being installed or played.
+type playEarlyCave struct {
+	// throttles the install while the game is running
+	limiter *throttle.Limiter
+
+	installing bool
+	remaining  int64
+	sessions   int
+}
+
+var playEarlyCaves = struct {
+	sync.Mutex
+	byID map[string]*playEarlyCave
+}{
+	byID: make(map[string]*playEarlyCave),
+}
+
+// getPlayEarlyCave must be called with playEarlyCaves locked
+func getPlayEarlyCave(caveID string) *playEarlyCave {
+	pc := playEarlyCaves.byID[caveID]
+	if pc == nil {
+		pc = &playEarlyCave{
+			limiter: throttle.New(0),
+		}
+		playEarlyCaves.byID[caveID] = pc
+	}
+	return pc
+}
+
+// forgetPlayEarlyCave must be called with playEarlyCaves locked
+func forgetPlayEarlyCave(caveID string, pc *playEarlyCave) {
+	if !pc.installing && pc.sessions == 0 {
+		delete(playEarlyCaves.byID, caveID)
+	}
+}
+
+// trackPlayEarly returns the limiter of a play-early install,
+// which is slowed down while the game is running.
+func trackPlayEarly(caveID string) (limiter *throttle.Limiter, untrack func()) {
+	playEarlyCaves.Lock()
+	defer playEarlyCaves.Unlock()
+
+	pc := getPlayEarlyCave(caveID)
+	pc.installing = true
+	return pc.limiter, func() {
+		playEarlyCaves.Lock()
+		defer playEarlyCaves.Unlock()
+		pc.installing = false
+		forgetPlayEarlyCave(caveID, pc)
+	}
+}
+
+func setPlayEarlyRemaining(caveID string, remaining int64) {
+	playEarlyCaves.Lock()
+	defer playEarlyCaves.Unlock()
+	if pc := playEarlyCaves.byID[caveID]; pc != nil {
+		pc.remaining = remaining
+	}
+}
+
+// PlayEarlyRemainingBytes returns how much of cave is left to install,
+// if it's playable early: as the install goes if it's running, or as of
+// when the cave was saved otherwise.
+func PlayEarlyRemainingBytes(cave *models.Cave) int64 {
+	if !cave.PlayableEarly {
+		return 0
+	}
+
+	playEarlyCaves.Lock()
+	defer playEarlyCaves.Unlock()
+	if pc := playEarlyCaves.byID[cave.ID]; pc != nil && pc.installing {
+		return pc.remaining
+	}
+	return cave.RemainingBytes
+}
+
+// BeginPlayEarlySession slows down the rest of the install of a cave
+// that's playable early while its game is running, so it doesn't get in
+// the way. The install goes back to full speed once every session
+// is ended.
+func BeginPlayEarlySession(consumer *state.Consumer, caveID string) (end func()) {
+	playEarlyCaves.Lock()
+	defer playEarlyCaves.Unlock()
+
+	pc := getPlayEarlyCave(caveID)
+	pc.sessions++
+	if pc.sessions == 1 {
+		rate := playEarlyBytesPerSecond.Int64()
+		if rate > 0 {
+			consumer.Infof("Limiting the rest of the install to %s/s while the game runs", united.FormatBytes(rate))
+		}
+		pc.limiter.SetRate(rate)
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			playEarlyCaves.Lock()
+			defer playEarlyCaves.Unlock()
+
+			pc.sessions--
+			if pc.sessions == 0 {
+				pc.limiter.SetRate(0)
+			}
+			forgetPlayEarlyCave(caveID, pc)
+		})
+	}
+}
+
+// playEarlySinkWrapper plans a play-early install of the zip archive
+// file, and returns a SinkWrapper that commits the cave as playable
+// once the files its game needs are extracted. It returns nil if the
+// archive doesn't declare any optional files.
+func playEarlySinkWrapper(oc *OperationContext, params *InstallParams, installerInfo *hush.InstallerInfo, file eos.File, renames map[string]string) SinkWrapper {
+	consumer := oc.Consumer()
+
+	if ai := installerInfo.ArchiveInfo; ai == nil || ai.Strategy != boar.StrategyZip {
+		consumer.Infof("Only zip archives can be played early, installing it whole")
+		return nil
+	}
+
+	stats, err := file.Stat()
+	if err != nil {
+		consumer.Warnf("Can't play early: %s", err.Error())
+		return nil
+	}
+	plan, err := PlanPlayEarly(file, stats.Size())
+	if err != nil {
+		consumer.Warnf("Can't play early: %s", err.Error())
+		return nil
+	}
+	if plan == nil {
+		consumer.Infof("App manifest has no optional files, can't play early")
+		return nil
+	}
+	consumer.Infof("Can play early, without (%s), %s",
+		strings.Join(plan.Components, ", "), united.FormatBytes(plan.OptionalSize))
+
+	committed := false
+	if oc.cave != nil && oc.cave.PlayableEarly {
+		consumer.Infof("Already playable, resuming install of the rest")
+		committed = true
+	}
+
+	return func(sink savior.Sink) savior.Sink {
+		return newPlayEarlySink(sink, plan, committed, func(remaining int64) {
+			setPlayEarlyRemaining(params.CaveID, remaining)
+		}, func(remaining int64) {
+			commitPlayableEarly(oc, params, plan, renames, remaining)
+		})
+	}
+}
+
+// commitPlayableEarly commits the files of plan the game needs to
+// start, once they're all the size they should be.
+func commitPlayableEarly(oc *OperationContext, params *InstallParams, plan *PlayEarlyPlan, renames map[string]string, remaining int64) {
+	consumer := oc.Consumer()
+	rename := renamer(renames)
+
+	var files []string
+	for _, entry := range plan.entries {
+		if !entry.required {
+			continue
+		}
+		name := rename(entry.name)
+		stats, err := os.Stat(filepath.Join(params.InstallFolder, filepath.FromSlash(name)))
+		if err != nil || stats.Size() != entry.size {
+			consumer.Warnf("(%s) isn't fully installed, can't play early", name)
+			return
+		}
+		files = append(files, name)
+	}
+
+	consumer.Opf("Game can start now, %s left to install", united.FormatBytes(remaining))
+	err := commitInstall(oc, &CommitInstallParams{
+		InstallFolder: params.InstallFolder,
+
+		InstallerName: string(hush.InstallerTypeArchive),
+		Game:          params.Game,
+		Upload:        params.Upload,
+		Build:         params.Build,
+
+		InstallResult: &hush.InstallResult{
+			Files: files,
+		},
+		Renames: renames,
+
+		PlayableEarly:  true,
+		RemainingBytes: remaining,
+	})
+	if err != nil {
+		consumer.Warnf("Could not commit playable files: %+v", err)
+	}
+}
+
+// playEarlySink keeps track of which entries of an archive are extracted,
+// going by which one it's asked for: entries are extracted in order,
+// and a resumed extraction starts where it left off.
+type playEarlySink struct {
+	savior.Sink
+
+	plan  *PlayEarlyPlan
+	index map[string]int
+
+	// entries before next are extracted
+	next      int
+	missing   int
+	remaining int64
+
+	playable   bool
+	onProgress func(remaining int64)
+	onPlayable func(remaining int64)
+}
+
+var _ savior.Sink = (*playEarlySink)(nil)
+
+func newPlayEarlySink(sink savior.Sink, plan *PlayEarlyPlan, playable bool, onProgress func(remaining int64), onPlayable func(remaining int64)) *playEarlySink {
+	ps := &playEarlySink{
+		Sink:       sink,
+		plan:       plan,
+		index:      make(map[string]int),
+		playable:   playable,
+		onProgress: onProgress,
+		onPlayable: onPlayable,
+	}
+	for i, entry := range plan.entries {
+		ps.index[entry.name] = i
+		ps.remaining += entry.size
+		if entry.required {
+			ps.missing++
+		}
+	}
+	return ps
+}
+
+func (ps *playEarlySink) reach(entry *savior.Entry) {
+	i, ok := ps.index[entry.CanonicalPath]
+	if !ok || i < ps.next {
+		return
+	}
+	for ; ps.next < i; ps.next++ {
+		done := ps.plan.entries[ps.next]
+		ps.remaining -= done.size
+		if done.required {
+			ps.missing--
+		}
+	}
+	ps.onProgress(ps.remaining)
+
+	if !ps.playable && ps.missing == 0 {
+		// only tried once: if the files don't check out,
+		// the cave is committed once everything's installed.
+		ps.playable = true
+		ps.onPlayable(ps.remaining)
+	}
+}
+
+func (ps *playEarlySink) Mkdir(entry *savior.Entry) error {
+	ps.reach(entry)
+	return ps.Sink.Mkdir(entry)
+}
+
+func (ps *playEarlySink) Symlink(entry *savior.Entry, linkname string) error {
+	ps.reach(entry)
+	return ps.Sink.Symlink(entry, linkname)
+}
+
+func (ps *playEarlySink) GetWriter(entry *savior.Entry) (savior.EntryWriter, error) {
+	ps.reach(entry)
+	return ps.Sink.GetWriter(entry)
+}
@@ -0,0 +1,163 @@
+package operate
+
+import (
+	"archive/zip"
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/savior"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeZip(t *testing.T, files map[string]string) *bytes.Reader {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(files[name]))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestPlanPlayEarly(t *testing.T) {
+	assert := assert.New(t)
+
+	plan := func(files map[string]string) *PlayEarlyPlan {
+		t.Helper()
+		r := makeZip(t, files)
+		p, err := PlanPlayEarly(r, r.Size())
+		require.NoError(t, err)
+		return p
+	}
+
+	assert.Nil(plan(map[string]string{
+		"game.exe": "MZ",
+	}), "no manifest")
+
+	assert.Nil(plan(map[string]string{
+		".itch.toml": "[[actions]]\nname = \"play\"\npath = \"game.exe\"\n",
+		"game.exe":   "MZ",
+	}), "no optional components")
+
+	assert.Nil(plan(map[string]string{
+		".itch.toml": "[[optional]]\nname = \"music\"\npaths = [\"Music\"]\n",
+		"game.exe":   "MZ",
+	}), "optional components with no files")
+
+	p := plan(map[string]string{
+		".itch.toml":             "[[optional]]\nname = \"music\"\npaths = [\"Music\", \"Movies/*.webm\"]\n",
+		"game.exe":               "MZ",
+		"Music/theme.ogg":        "0123456789",
+		"Music/MusicNotes.txt":   "abc",
+		"Movies/intro.webm":      "01234",
+		"Movies/intro.srt":       "1",
+		"MusicBox/required.dat":  "x",
+		"data/Music/not-top.ogg": "x",
+	})
+	if assert.NotNil(p) {
+		assert.EqualValues([]string{"music"}, p.Components)
+		assert.EqualValues(18, p.OptionalSize)
+		assert.EqualValues(map[string]bool{
+			".itch.toml":             true,
+			"game.exe":               true,
+			"Movies/intro.srt":       true,
+			"MusicBox/required.dat":  true,
+			"data/Music/not-top.ogg": true,
+		}, p.Required)
+	}
+
+	_, err := PlanPlayEarly(bytes.NewReader([]byte("not a zip")), 9)
+	assert.Error(err)
+}
+
+func TestPlayEarlySink(t *testing.T) {
+	assert := assert.New(t)
+
+	r := makeZip(t, map[string]string{
+		".itch.toml":        "[[optional]]\nname = \"extras\"\npaths = [\"music\", \"videos\"]\n",
+		"data/level.pak":    "0123",
+		"game.exe":          "MZ",
+		"music/theme.ogg":   "0123456789",
+		"videos/intro.webm": "01234",
+	})
+	plan, err := PlanPlayEarly(r, r.Size())
+	require.NoError(t, err)
+	require.NotNil(t, plan)
+	const optionalSize = 15
+
+	type extraction struct {
+		progress []int64
+		playable []int64
+	}
+	extract := func(from int, playable bool) *extraction {
+		ex := &extraction{}
+		sink := newPlayEarlySink(&savior.NopSink{}, plan, playable, func(remaining int64) {
+			ex.progress = append(ex.progress, remaining)
+		}, func(remaining int64) {
+			ex.playable = append(ex.playable, remaining)
+		})
+		for _, entry := range plan.entries[from:] {
+			w, err := sink.GetWriter(&savior.Entry{
+				CanonicalPath:    entry.name,
+				Kind:             savior.EntryKindFile,
+				UncompressedSize: entry.size,
+			})
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+		}
+		return ex
+	}
+
+	ex := extract(0, false)
+	assert.EqualValues([]int64{optionalSize}, ex.playable, "playable once the required files are done")
+	assert.Len(ex.progress, len(plan.entries))
+	assert.EqualValues(optionalSize-10, ex.progress[len(ex.progress)-1])
+
+	ex = extract(3, false)
+	assert.EqualValues([]int64{optionalSize}, ex.playable, "resumed extractions skip the entries that are done")
+
+	ex = extract(4, false)
+	assert.EqualValues([]int64{5}, ex.playable)
+
+	ex = extract(0, true)
+	assert.Empty(ex.playable, "committed only once")
+}
+
+func TestPlayEarlySession(t *testing.T) {
+	assert := assert.New(t)
+	consumer := &state.Consumer{}
+
+	limiter, untrack := trackPlayEarly("cave")
+	setPlayEarlyRemaining("cave", 1234)
+	assert.EqualValues(1234, PlayEarlyRemainingBytes(&models.Cave{ID: "cave", PlayableEarly: true, RemainingBytes: 5678}))
+	assert.EqualValues(0, PlayEarlyRemainingBytes(&models.Cave{ID: "cave"}))
+
+	end := BeginPlayEarlySession(consumer, "cave")
+	endOther := BeginPlayEarlySession(consumer, "cave")
+	assert.EqualValues(playEarlyBytesPerSecond.Int64(), limiter.Rate(), "slowed down while the game runs")
+	end()
+	end()
+	assert.EqualValues(playEarlyBytesPerSecond.Int64(), limiter.Rate(), "until every session is done")
+	endOther()
+	assert.EqualValues(0, limiter.Rate())
+
+	untrack()
+	assert.EqualValues(5678, PlayEarlyRemainingBytes(&models.Cave{ID: "cave", PlayableEarly: true, RemainingBytes: 5678}),
+		"saved value once the install is done")
+	playEarlyCaves.Lock()
+	assert.Empty(playEarlyCaves.byID)
+	playEarlyCaves.Unlock()
+}
@@ -0,0 +1,134 @@
+package operate
+
+import (
+	"sync"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/httpkit/eos"
+)
+
+// 4 times a second at most
+const prepareProgressInterval = 250 * time.Millisecond
+
+// prepareProgress sends @@InstallPrepareProgressNotification while
+// InstallPrepare runs. Updates are coalesced, and sent on every tick
+// if there were any, so clients don't get flooded.
+type prepareProgress struct {
+	rc *butlerd.RequestContext
+
+	mu      sync.Mutex
+	current butlerd.InstallPrepareProgressNotification
+	dirty   bool
+
+	done     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+func newPrepareProgress(oc *OperationContext) *prepareProgress {
+	pp := &prepareProgress{
+		rc:      oc.rc,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	pp.current.ID = oc.installID
+	go pp.run()
+	return pp
+}
+
+func (pp *prepareProgress) run() {
+	defer close(pp.stopped)
+
+	ticker := time.NewTicker(prepareProgressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			pp.flush()
+		case <-pp.done:
+			return
+		}
+	}
+}
+
+func (pp *prepareProgress) flush() {
+	pp.mu.Lock()
+	if !pp.dirty {
+		pp.mu.Unlock()
+		return
+	}
+	pp.dirty = false
+	n := pp.current
+	pp.mu.Unlock()
+
+	_ = messages.InstallPrepareProgress.Notify(pp.rc, n)
+}
+
+func (pp *prepareProgress) setStage(stage butlerd.InstallPrepareStage, totalBytes int64) {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+
+	pp.current.Stage = stage
+	pp.current.BytesDone = 0
+	pp.current.TotalBytes = totalBytes
+	pp.dirty = true
+}
+
+func (pp *prepareProgress) addBytes(n int64) {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+
+	pp.current.BytesDone += n
+	// sniffing seeks around, and may read some parts twice
+	if pp.current.TotalBytes > 0 && pp.current.BytesDone > pp.current.TotalBytes {
+		pp.current.BytesDone = pp.current.TotalBytes
+	}
+	pp.dirty = true
+}
+
+// ready stops periodic updates, and lets the client know right away
+// that preparing is done.
+func (pp *prepareProgress) ready() {
+	pp.stop()
+
+	pp.mu.Lock()
+	pp.current.Stage = butlerd.InstallPrepareStageReady
+	pp.current.BytesDone = pp.current.TotalBytes
+	pp.dirty = false
+	n := pp.current
+	pp.mu.Unlock()
+
+	_ = messages.InstallPrepareProgress.Notify(pp.rc, n)
+}
+
+func (pp *prepareProgress) stop() {
+	pp.stopOnce.Do(func() {
+		close(pp.done)
+	})
+	<-pp.stopped
+}
+
+// wrap returns a file that reports bytes read through it to pp
+func (pp *prepareProgress) wrap(file eos.File) eos.File {
+	return &progressFile{File: file, pp: pp}
+}
+
+type progressFile struct {
+	eos.File
+	pp *prepareProgress
+}
+
+func (pf *progressFile) Read(p []byte) (int, error) {
+	n, err := pf.File.Read(p)
+	pf.pp.addBytes(int64(n))
+	return n, err
+}
+
+func (pf *progressFile) ReadAt(p []byte, off int64) (int, error) {
+	n, err := pf.File.ReadAt(p, off)
+	pf.pp.addBytes(int64(n))
+	return n, err
+}
@@ -0,0 +1,410 @@
+package operate
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/hades"
+	"github.com/itchio/headway/united"
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/httpkit/eos/option"
+	"github.com/itchio/hush/bfs"
+	"github.com/itchio/lake/tlc"
+	"github.com/itchio/savior/seeksource"
+	"github.com/itchio/wharf/pwr"
+	"github.com/itchio/wharf/wire"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
+)
+
+// RebuildReceipt writes a new receipt for a cave from the contents of its
+// install folder, checking them against the build's signature when possible.
+func RebuildReceipt(rc *butlerd.RequestContext, caveID string) (*butlerd.ReceiptRebuild, error) {
+	consumer := rc.Consumer
+
+	var cave *models.Cave
+	var installFolder string
+	var access *GameAccess
+	rc.WithConn(func(conn *sqlite.Conn) {
+		cave = models.CaveByID(conn, caveID)
+		if cave != nil {
+			cave.Preload(conn)
+			installFolder = cave.GetInstallFolder(conn)
+			access = AccessForCave(conn, cave)
+		}
+	})
+	if cave == nil {
+		return nil, errors.Errorf("cave not found: (%s)", caveID)
+	}
+
+	stats, err := os.Stat(installFolder)
+	if err != nil || !stats.IsDir() {
+		return nil, errors.Errorf("install folder (%s) does not exist", installFolder)
+	}
+
+	consumer.Infof("Rebuilding receipt for cave (%s) in (%s)", caveID, installFolder)
+
+	receiptIn, err := bfs.ReadReceipt(installFolder)
+	if err != nil {
+		consumer.Warnf("Could not read existing receipt: %s", err.Error())
+		receiptIn = nil
+	}
+
+	renames, err := ReadReceiptRenames(installFolder)
+	if err != nil {
+		consumer.Warnf("Could not read renamed files from existing receipt: %s", err.Error())
+		renames = nil
+	}
+
+	om, err := ReadOverflowMap(installFolder)
+	if err != nil {
+		consumer.Warnf("Could not read overflow map: %s", err.Error())
+		om = nil
+	}
+
+	sizes, err := scanReceiptFiles(installFolder, om)
+	if err != nil {
+		return nil, err
+	}
+	consumer.Infof("Found %d files on disk", len(sizes))
+
+	res := &butlerd.ReceiptRebuild{
+		CaveID: caveID,
+		Files:  []*butlerd.ReceiptRebuildFile{},
+	}
+	var files []string
+
+	var sigInfo *pwr.SignatureInfo
+	if cave.Build != nil && len(renames) > 0 {
+		consumer.Infof("%d files were installed under sanitized names, not checking files against the build's signature", len(renames))
+	} else if cave.Build != nil {
+		sigInfo, err = fetchBuildSignature(rc, access, cave.Build)
+		if err != nil {
+			consumer.Warnf("Could not fetch signature for build %d, not checking files: %s", cave.Build.ID, err.Error())
+			sigInfo = nil
+		}
+	}
+
+	if sigInfo != nil {
+		wounded, err := findWoundedFiles(rc, installFolder, sigInfo, nil)
+		if err != nil {
+			return nil, err
+		}
+		if om != nil {
+			// overflowed files are symlinks here, the real ones get checked
+			// where they are instead.
+			overflowWounded, err := findWoundedFiles(rc, om.Folder, sigInfo, nil)
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range om.Files {
+				wounded[f] = overflowWounded[f]
+			}
+		}
+		res.CheckedSignature = true
+
+		official := make(map[string]bool)
+		for _, f := range resultForContainer(sigInfo.Container).Files {
+			official[f] = true
+			if _, ok := sizes[f]; !ok {
+				res.Missing = append(res.Missing, f)
+				continue
+			}
+			files = append(files, f)
+		}
+
+		for name, size := range sizes {
+			status := butlerd.ReceiptFileStatusOfficial
+			switch {
+			case !official[name]:
+				status = butlerd.ReceiptFileStatusLocal
+			case wounded[name]:
+				status = butlerd.ReceiptFileStatusModified
+			}
+			res.Files = append(res.Files, &butlerd.ReceiptRebuildFile{
+				Path:   name,
+				Size:   size,
+				Status: status,
+			})
+		}
+	} else {
+		for name, size := range sizes {
+			status := butlerd.ReceiptFileStatusUnchecked
+			if categorizeUserFile(name) != butlerd.UninstallFileCategoryUnknown {
+				status = butlerd.ReceiptFileStatusLocal
+			} else {
+				files = append(files, name)
+			}
+			res.Files = append(res.Files, &butlerd.ReceiptRebuildFile{
+				Path:   name,
+				Size:   size,
+				Status: status,
+			})
+		}
+	}
+
+	sort.Strings(files)
+	sort.Strings(res.Missing)
+	sort.Slice(res.Files, func(i, j int) bool {
+		return res.Files[i].Path < res.Files[j].Path
+	})
+
+	receiptPath := bfs.ReceiptPath(installFolder)
+	if _, err := os.Stat(receiptPath); err == nil {
+		backupPath := receiptPath + ".bak"
+		err = os.Rename(receiptPath, backupPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "backing up previous receipt")
+		}
+		res.BackupPath = backupPath
+		consumer.Infof("Previous receipt moved to (%s)", backupPath)
+	}
+
+	receipt := &bfs.Receipt{
+		Game:   cave.Game,
+		Upload: cave.Upload,
+		Build:  cave.Build,
+		Files:  files,
+	}
+	switch {
+	case receiptIn != nil:
+		receipt.InstallerName = receiptIn.InstallerName
+	case cave.Build != nil:
+		// wharf-enabled uploads are always installed with "archive"
+		receipt.InstallerName = "archive"
+	}
+
+	err = writeReceipt(installFolder, receipt, renames)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	rc.WithConn(func(conn *sqlite.Conn) {
+		models.MustUpdate(conn, &models.Cave{},
+			hades.Where(builder.Eq{"id": cave.ID}),
+			builder.Eq{"receipt_hash": ReceiptHash(receipt)},
+		)
+	})
+
+	consumer.Statf("Rebuilt receipt for cave (%s) with %d files (%d on disk, %d missing)",
+		caveID, len(files), len(sizes), len(res.Missing))
+	return res, nil
+}
+
+// scanReceiptFiles returns the sizes of all files (and symlinks) in
+// installFolder, by slash-separated path, except for those in `.itch`.
+// Files moved to an overflow location count with their real size.
+func scanReceiptFiles(installFolder string, om *OverflowMap) (map[string]int64, error) {
+	sizes := make(map[string]int64)
+	err := filepath.Walk(installFolder, func(fullPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(installFolder, fullPath)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		if info.IsDir() {
+			if name == ".itch" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		sizes[name] = info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "scanning install folder")
+	}
+
+	if om != nil {
+		for _, f := range om.Files {
+			if _, ok := sizes[f]; !ok {
+				continue
+			}
+			if stats, err := os.Stat(filepath.Join(om.Folder, filepath.FromSlash(f))); err == nil {
+				sizes[f] = stats.Size()
+			}
+		}
+	}
+	return sizes, nil
+}
+
+func fetchBuildSignature(rc *butlerd.RequestContext, access *GameAccess, build *itchio.Build) (*pwr.SignatureInfo, error) {
+	consumer := rc.Consumer
+	client := rc.Client(access.APIKey)
+
+	signatureURL := client.MakeBuildDownloadURL(itchio.MakeBuildDownloadURLParams{
+		BuildID:     build.ID,
+		Credentials: access.Credentials,
+		Type:        itchio.BuildFileTypeSignature,
+	})
+
+	signatureFile, err := eos.Open(signatureURL, option.WithConsumer(consumer))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer signatureFile.Close()
+
+	timeBeforeSig := time.Now()
+
+	signatureSource := seeksource.FromFile(signatureFile)
+	_, err = signatureSource.Resume(nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	sigInfo, err := pwr.ReadSignature(rc.Ctx, signatureSource)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	consumer.Infof("✓ Fetched signature in %s, dealing with %s container",
+		time.Since(timeBeforeSig),
+		united.FormatBytes(sigInfo.Container.Size),
+	)
+	return sigInfo, nil
+}
+
+// findWoundedFiles validates installFolder against sigInfo, and returns
+// the paths of files and symlinks that don't match it, missing ones included.
+// onProgress, if set, is called with the validation progress, as well.
+func findWoundedFiles(rc *butlerd.RequestContext, installFolder string, sigInfo *pwr.SignatureInfo, onProgress func(progress float64)) (map[string]bool, error) {
+	woundsPath := filepath.Join(installFolder, ".itch", "rebuild-receipt.pww")
+	err := bfs.Mkdir(filepath.Dir(woundsPath))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer os.Remove(woundsPath)
+
+	consumer := rc.Consumer
+	if onProgress != nil {
+		withProgress := *rc.Consumer
+		withProgress.OnProgress = func(progress float64) {
+			if rc.Consumer.OnProgress != nil {
+				rc.Consumer.OnProgress(progress)
+			}
+			onProgress(progress)
+		}
+		consumer = &withProgress
+	}
+
+	vc := &pwr.ValidatorContext{
+		Consumer:   consumer,
+		WoundsPath: woundsPath,
+	}
+
+	rc.Consumer.Infof("Checking files against signature...")
+	rc.StartProgress()
+	err = vc.Validate(rc.Ctx, installFolder, sigInfo)
+	rc.EndProgress()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	wounded := make(map[string]bool)
+	if !vc.WoundsConsumer.HasWounds() {
+		return wounded, nil
+	}
+
+	rc.Consumer.Infof("%s don't match the signature",
+		united.FormatBytes(vc.WoundsConsumer.TotalCorrupted()),
+	)
+
+	reader, err := os.Open(woundsPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening wounds")
+	}
+	defer reader.Close()
+
+	source := seeksource.FromFile(reader)
+	_, err = source.Resume(nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading wounds")
+	}
+
+	rctx := wire.NewReadContext(source)
+	err = rctx.ExpectMagic(pwr.WoundsMagic)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading wounds magic")
+	}
+
+	err = rctx.ReadMessage(&pwr.WoundsHeader{})
+	if err != nil {
+		return nil, errors.Wrap(err, "reading wounds header")
+	}
+
+	container := &tlc.Container{}
+	err = rctx.ReadMessage(container)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading container from wounds file")
+	}
+
+	for {
+		wound := &pwr.Wound{}
+		err = rctx.ReadMessage(wound)
+		if err != nil {
+			if errors.Cause(err) == io.EOF {
+				break
+			}
+			return nil, errors.Wrap(err, "reading wound")
+		}
+
+		switch wound.Kind {
+		case pwr.WoundKind_FILE:
+			if wound.Index < int64(len(container.Files)) {
+				wounded[container.Files[wound.Index].Path] = true
+			}
+		case pwr.WoundKind_SYMLINK:
+			if wound.Index < int64(len(container.Symlinks)) {
+				wounded[container.Symlinks[wound.Index].Path] = true
+			}
+		}
+	}
+	return wounded, nil
+}
+
+// SuggestReceiptRebuild sends @@ReceiptRebuildSuggestedNotification if
+// receipt can't tell which files in installFolder were installed.
+func SuggestReceiptRebuild(rc *butlerd.RequestContext, caveID string, installFolder string, receipt *bfs.Receipt) {
+	if caveID == "" || receipt.HasFiles() {
+		return
+	}
+
+	entries, err := ioutil.ReadDir(installFolder)
+	if err != nil {
+		return
+	}
+	empty := true
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), ".itch") {
+			empty = false
+			break
+		}
+	}
+	if empty {
+		return
+	}
+
+	reason := "cave has no receipt"
+	if receipt != nil {
+		reason = "cave's receipt lists no files"
+	}
+	rc.Consumer.Infof("Suggesting a receipt rebuild: %s", reason)
+	messages.ReceiptRebuildSuggested.Notify(rc, butlerd.ReceiptRebuildSuggestedNotification{
+		CaveID: caveID,
+		Reason: reason,
+	})
+}
@@ -0,0 +1,81 @@
+package operate
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/itchio/hush/bfs"
+	"github.com/pkg/errors"
+)
+
+// receiptWithRenames is a receipt that also records which files were
+// installed under a sanitized name (see pathsafety.Renames). Readers
+// that don't know about renames, like bfs.ReadReceipt, read the rest
+// of it just fine.
+type receiptWithRenames struct {
+	*bfs.Receipt
+
+	// Sanitized names, by slash-separated path in the build
+	Renames map[string]string `json:"renames,omitempty"`
+}
+
+// writeReceipt writes receipt to installFolder, along with renames if any.
+func writeReceipt(installFolder string, receipt *bfs.Receipt, renames map[string]string) error {
+	if len(renames) == 0 {
+		return receipt.WriteReceipt(installFolder)
+	}
+
+	if _, err := os.Stat(installFolder); err != nil {
+		return errors.Errorf("Refusing to write receipt to non-existent directory: %s", err.Error())
+	}
+
+	path := bfs.ReceiptPath(installFolder)
+	err := bfs.Mkdir(filepath.Dir(path))
+	if err != nil {
+		return errors.Wrap(err, "creating receipt parent directory")
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "creating receipt file")
+	}
+	defer f.Close()
+
+	gzw := gzip.NewWriter(f)
+	err = json.NewEncoder(gzw).Encode(&receiptWithRenames{
+		Receipt: receipt,
+		Renames: renames,
+	})
+	if err != nil {
+		return errors.Wrap(err, "encoding receipt")
+	}
+	return errors.WithStack(gzw.Close())
+}
+
+// ReadReceiptRenames returns which files of installFolder were
+// installed under a sanitized name, by their path in the build.
+// It returns nil if there's no receipt, or if nothing was renamed.
+func ReadReceiptRenames(installFolder string) (map[string]string, error) {
+	f, err := os.Open(bfs.ReceiptPath(installFolder))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "reading receipt")
+	}
+	defer f.Close()
+
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating decompressor for receipt")
+	}
+
+	var rr receiptWithRenames
+	err = json.NewDecoder(gzr).Decode(&rr)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding receipt")
+	}
+	return rr.Renames, nil
+}
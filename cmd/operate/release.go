@@ -0,0 +1,95 @@
+package operate
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/pkg/errors"
+)
+
+// GameNotYetReleasedError is returned when trying to install a game
+// that can be pre-ordered, but isn't out yet.
+type GameNotYetReleasedError struct {
+	Game *itchio.Game
+	// When the game comes out, if known
+	ReleaseDate *time.Time
+}
+
+var _ butlerd.Error = (*GameNotYetReleasedError)(nil)
+
+func (e *GameNotYetReleasedError) RpcErrorCode() int64 {
+	return int64(butlerd.CodeGameNotYetReleased)
+}
+
+func (e *GameNotYetReleasedError) RpcErrorMessage() string {
+	msg := butlerd.CodeGameNotYetReleased.RpcErrorMessage()
+	if e.ReleaseDate != nil {
+		msg = fmt.Sprintf("%s (it comes out on %s)", msg, e.ReleaseDate.Format("2006-01-02"))
+	}
+	return msg
+}
+
+func (e *GameNotYetReleasedError) RpcErrorData() map[string]interface{} {
+	return map[string]interface{}{
+		"releaseDate": e.ReleaseDate,
+	}
+}
+
+func (e *GameNotYetReleasedError) Error() string {
+	return e.RpcErrorMessage()
+}
+
+// ReleaseState tells whether game is out, given its uploads (if
+// they've been listed). It isn't when it's scheduled to be published
+// in the future, or when all its uploads are pre-order placeholders.
+// releaseDate is only set if the former.
+func ReleaseState(game *itchio.Game, uploads []*itchio.Upload, now time.Time) (released bool, releaseDate *time.Time) {
+	if game.PublishedAt != nil && game.PublishedAt.After(now) {
+		return false, game.PublishedAt
+	}
+
+	if len(uploads) == 0 {
+		return true, nil
+	}
+	for _, u := range uploads {
+		if !u.Preorder {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CheckGameReleased fetches fresh info about a game and its uploads,
+// and returns a *GameNotYetReleasedError if it's not out yet. The
+// fresh game is returned either way.
+func CheckGameReleased(rc *butlerd.RequestContext, access *GameAccess, game *itchio.Game) (*itchio.Game, error) {
+	client := rc.Client(access.APIKey)
+
+	gameRes, err := client.GetGame(rc.Ctx, itchio.GetGameParams{
+		GameID:      game.ID,
+		Credentials: access.Credentials,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	game = gameRes.Game
+
+	uploadsRes, err := client.ListGameUploads(rc.Ctx, itchio.ListGameUploadsParams{
+		GameID:      game.ID,
+		Credentials: access.Credentials,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	released, releaseDate := ReleaseState(game, uploadsRes.Uploads, time.Now())
+	if !released {
+		return game, &GameNotYetReleasedError{
+			Game:        game,
+			ReleaseDate: releaseDate,
+		}
+	}
+	return game, nil
+}
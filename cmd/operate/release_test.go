@@ -0,0 +1,41 @@
+package operate_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/cmd/operate"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReleaseState(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-24 * time.Hour)
+	future := now.Add(24 * time.Hour)
+
+	preorder := &itchio.Upload{ID: 1, Preorder: true}
+	regular := &itchio.Upload{ID: 2}
+
+	released, date := operate.ReleaseState(&itchio.Game{PublishedAt: &past}, []*itchio.Upload{regular}, now)
+	assert.True(t, released)
+	assert.Nil(t, date)
+
+	released, date = operate.ReleaseState(&itchio.Game{}, nil, now)
+	assert.True(t, released, "no publication date and no uploads listed")
+	assert.Nil(t, date)
+
+	released, date = operate.ReleaseState(&itchio.Game{PublishedAt: &future}, nil, now)
+	assert.False(t, released, "published in the future")
+	assert.EqualValues(t, &future, date)
+
+	released, date = operate.ReleaseState(&itchio.Game{PublishedAt: &past}, []*itchio.Upload{preorder}, now)
+	assert.False(t, released, "only pre-order uploads")
+	assert.Nil(t, date)
+
+	released, _ = operate.ReleaseState(&itchio.Game{}, []*itchio.Upload{preorder, regular}, now)
+	assert.True(t, released, "some uploads are out")
+
+	released, _ = operate.ReleaseState(&itchio.Game{InPressSystem: true}, []*itchio.Upload{regular}, now)
+	assert.True(t, released, "press access doesn't make a game unreleased")
+}
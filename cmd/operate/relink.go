@@ -0,0 +1,264 @@
+package operate
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/google/uuid"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/hades"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/hush/bfs"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
+)
+
+// relinkSampleSize is how many of the files a receipt lists are
+// checked for, before relinking a cave to the folder it's in
+const relinkSampleSize = 32
+
+// ReceiptHash identifies what a receipt says was installed: game, upload,
+// build and files. It doesn't depend on the order files are listed in.
+func ReceiptHash(receipt *bfs.Receipt) string {
+	var gameID, uploadID, buildID int64
+	if receipt.Game != nil {
+		gameID = receipt.Game.ID
+	}
+	if receipt.Upload != nil {
+		uploadID = receipt.Upload.ID
+	}
+	if receipt.Build != nil {
+		buildID = receipt.Build.ID
+	}
+
+	files := append([]string(nil), receipt.Files...)
+	sort.Strings(files)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%d/%d/%d\n", gameID, uploadID, buildID)
+	for _, f := range files {
+		fmt.Fprintf(h, "%s\n", f)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// receiptMatchesCave returns true if receipt is for
+// the game, upload and build cave has installed
+func receiptMatchesCave(receipt *bfs.Receipt, cave *models.Cave) bool {
+	if receipt.Game == nil || receipt.Game.ID != cave.GameID {
+		return false
+	}
+	if receipt.Upload == nil || receipt.Upload.ID != cave.UploadID {
+		return false
+	}
+	var buildID int64
+	if receipt.Build != nil {
+		buildID = receipt.Build.ID
+	}
+	return buildID == cave.BuildID
+}
+
+// relinkState is what's needed to tell which folders
+// a cave may have been moved to
+type relinkState struct {
+	locations []*models.InstallLocation
+	// install folders of all other caves
+	taken map[string]bool
+}
+
+func newRelinkState(conn *sqlite.Conn, cave *models.Cave) *relinkState {
+	rs := &relinkState{
+		taken: make(map[string]bool),
+	}
+	models.MustSelect(conn, &rs.locations, builder.NewCond(), hades.Search{})
+
+	var caves []*models.Cave
+	models.MustSelect(conn, &caves, builder.Neq{"id": cave.ID}, hades.Search{})
+	for _, c := range caves {
+		if folder := rs.installFolder(c); folder != "" {
+			rs.taken[folder] = true
+		}
+	}
+	return rs
+}
+
+// installFolder is cave.GetInstallFolder, except it returns
+// an empty string if the cave's install location is gone.
+func (rs *relinkState) installFolder(cave *models.Cave) string {
+	if cave.CustomInstallFolder != "" {
+		return filepath.Clean(cave.CustomInstallFolder)
+	}
+	for _, il := range rs.locations {
+		if il.ID == cave.InstallLocationID {
+			return filepath.Clean(il.GetInstallFolder(cave.InstallFolderName))
+		}
+	}
+	return ""
+}
+
+// locationOf returns the install location folder is directly in, if any
+func (rs *relinkState) locationOf(folder string) *models.InstallLocation {
+	parent := filepath.Dir(folder)
+	for _, il := range rs.locations {
+		if filepath.Clean(il.Path) == parent {
+			return il
+		}
+	}
+	return nil
+}
+
+// FindMovedCave returns the folders cave may have been moved to, looking
+// in all install locations and extraRoots, see butlerd.CavesFindMovedParams.
+func FindMovedCave(conn *sqlite.Conn, consumer *state.Consumer, cave *models.Cave, extraRoots []string) []*butlerd.MovedCaveCandidate {
+	rs := newRelinkState(conn, cave)
+
+	candidates := []*butlerd.MovedCaveCandidate{}
+	seen := make(map[string]bool)
+	check := func(folder string) {
+		folder = filepath.Clean(folder)
+		if seen[folder] || rs.taken[folder] {
+			return
+		}
+		seen[folder] = true
+
+		receipt, err := bfs.ReadReceipt(folder)
+		if err != nil {
+			consumer.Warnf("While reading receipt in (%s): %s", folder, err.Error())
+			return
+		}
+		if receipt == nil || !receiptMatchesCave(receipt, cave) {
+			return
+		}
+
+		candidate := &butlerd.MovedCaveCandidate{
+			InstallFolder: folder,
+			Exact:         cave.ReceiptHash != "" && ReceiptHash(receipt) == cave.ReceiptHash,
+		}
+		if il := rs.locationOf(folder); il != nil {
+			candidate.InstallLocationID = il.ID
+		}
+		consumer.Infof("Cave (%s) may have been moved to (%s) (exact: %v)", cave.ID, folder, candidate.Exact)
+		candidates = append(candidates, candidate)
+	}
+	checkChildren := func(root string) {
+		entries, err := ioutil.ReadDir(root)
+		if err != nil {
+			consumer.Warnf("Could not look for moved cave in (%s): %s", root, err.Error())
+			return
+		}
+		for _, entry := range entries {
+			if models.IsReservedFolderName(entry.Name()) {
+				continue
+			}
+			if entry.IsDir() {
+				check(filepath.Join(root, entry.Name()))
+			}
+		}
+	}
+
+	for _, il := range rs.locations {
+		checkChildren(il.Path)
+	}
+	for _, root := range extraRoots {
+		root, err := filepath.Abs(root)
+		if err != nil {
+			consumer.Warnf("Skipping extra root (%s): %s", root, err.Error())
+			continue
+		}
+		if stats, err := os.Stat(root); err == nil && stats.IsDir() {
+			check(root)
+		}
+		checkChildren(root)
+	}
+	return candidates
+}
+
+// RelinkCave points cave to installFolder, after making sure it was
+// moved there, and records the move in the cave's history.
+func RelinkCave(conn *sqlite.Conn, consumer *state.Consumer, cave *models.Cave, installFolder string) error {
+	if !filepath.IsAbs(installFolder) {
+		return errors.Errorf("(%s) must be an absolute path", installFolder)
+	}
+	installFolder = filepath.Clean(installFolder)
+
+	rs := newRelinkState(conn, cave)
+	if rs.taken[installFolder] {
+		return errors.Errorf("(%s) is the install folder of another cave", installFolder)
+	}
+
+	receipt, err := bfs.ReadReceipt(installFolder)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if receipt == nil {
+		return errors.Errorf("(%s) has no receipt, it wasn't installed by butler", installFolder)
+	}
+	if !receiptMatchesCave(receipt, cave) {
+		return errors.Errorf("(%s) has another game, upload or build installed than cave (%s)", installFolder, cave.ID)
+	}
+
+	missing := missingReceiptSample(installFolder, receipt.Files, relinkSampleSize)
+	if len(missing) > 0 {
+		return errors.Errorf("(%s) is missing files the cave should have, like (%s)", installFolder, missing[0])
+	}
+
+	eventID, err := uuid.NewRandom()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	oldFolder := rs.installFolder(cave)
+	update := builder.Eq{
+		"receipt_hash": ReceiptHash(receipt),
+	}
+	if il := rs.locationOf(installFolder); il != nil {
+		update["install_location_id"] = il.ID
+		update["install_folder_name"] = filepath.Base(installFolder)
+		update["custom_install_folder"] = ""
+	} else {
+		// the install location is still used for staging
+		update["custom_install_folder"] = installFolder
+	}
+	models.MustUpdate(conn, &models.Cave{},
+		hades.Where(builder.Eq{"id": cave.ID}),
+		update,
+	)
+
+	now := time.Now().UTC()
+	models.MustSave(conn, &models.CaveEvent{
+		ID:                eventID.String(),
+		CaveID:            cave.ID,
+		Type:              string(butlerd.CaveEventTypeRelinked),
+		CreatedAt:         &now,
+		FromInstallFolder: oldFolder,
+		ToInstallFolder:   installFolder,
+	})
+
+	consumer.Statf("Relinked cave (%s) from (%s) to (%s)", cave.ID, oldFolder, installFolder)
+	return nil
+}
+
+// missingReceiptSample checks that up to n of files, spread over the
+// whole list, are in installFolder, and returns those that aren't.
+func missingReceiptSample(installFolder string, files []string, n int) []string {
+	step := 1
+	if len(files) > n {
+		step = len(files) / n
+	}
+
+	var missing []string
+	for i := 0; i < len(files); i += step {
+		_, err := os.Lstat(filepath.Join(installFolder, filepath.FromSlash(files[i])))
+		if err != nil {
+			missing = append(missing, files[i])
+		}
+	}
+	return missing
+}
@@ -0,0 +1,184 @@
+package operate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/mansion/telemetry"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/hush"
+	"github.com/pkg/errors"
+)
+
+// SimulationSettings configures simulated transfers, see Simulation.
+type SimulationSettings struct {
+	// How fast simulated downloads and extractions go
+	BytesPerSecond int64
+}
+
+// Simulation, when set, makes install operations fabricate their
+// download and extraction instead of talking to itch.io and writing
+// the actual build. Everything else (queueing, downloads driver, progress
+// and task notifications, receipts, caves) runs for real.
+//
+// It's set by `butler daemon --mock-transfers`, for end-to-end tests
+// of clients.
+var Simulation *SimulationSettings
+
+// used when the upload or build doesn't tell us how big it is
+const defaultSimulatedSize = 4 * 1024 * 1024
+
+// how often simulated progress is reported
+const simulationTick = 100 * time.Millisecond
+
+// SimulatedSize returns how many bytes a simulated install of this
+// upload (and build, if any) pretends to transfer.
+func SimulatedSize(upload *itchio.Upload, build *itchio.Build) int64 {
+	if build != nil {
+		for _, f := range build.Files {
+			if f.Type == itchio.BuildFileTypeArchive && f.Size > 0 {
+				return f.Size
+			}
+		}
+	}
+	if upload != nil && upload.Size > 0 {
+		return upload.Size
+	}
+	return defaultSimulatedSize
+}
+
+func simulateInstall(oc *OperationContext, meta *MetaSubcontext) error {
+	params := meta.Data
+	consumer := oc.Consumer()
+
+	size := SimulatedSize(params.Upload, params.Build)
+	consumer.Infof("Simulating transfer of %d bytes at %d bytes/s", size, Simulation.BytesPerSecond)
+
+	for _, taskType := range []butlerd.TaskType{butlerd.TaskTypeDownload, butlerd.TaskTypeInstall} {
+		err := messages.TaskStarted.Notify(oc.rc, butlerd.TaskStartedNotification{
+			Reason:    butlerd.TaskReasonInstall,
+			Type:      taskType,
+			Game:      params.Game,
+			Upload:    params.Upload,
+			Build:     params.Build,
+			TotalSize: size,
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		var dt *telemetry.DownloadTelemetry
+		if taskType == butlerd.TaskTypeDownload {
+			dt = telemetry.FromContext(oc.ctx)
+		}
+
+		oc.rc.StartProgressWithTotalBytes(size)
+		err = simulateTransfer(oc, size, dt)
+		oc.rc.EndProgress()
+		if err != nil {
+			return err
+		}
+
+		if taskType == butlerd.TaskTypeDownload {
+			err = messages.TaskSucceeded.Notify(oc.rc, butlerd.TaskSucceededNotification{
+				Type: butlerd.TaskTypeDownload,
+			})
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+
+	files, err := writePlaceholderFiles(params)
+	if err != nil {
+		return err
+	}
+
+	return commitInstall(oc, &CommitInstallParams{
+		InstallFolder: params.InstallFolder,
+
+		InstallerName: string(hush.InstallerTypeArchive),
+		Game:          params.Game,
+		Upload:        params.Upload,
+		Build:         params.Build,
+
+		InstallResult: &hush.InstallResult{
+			Files: files,
+		},
+	})
+}
+
+// simulateTransfer pretends to transfer size bytes. If dt is set,
+// they're counted there, as if they were downloaded.
+func simulateTransfer(oc *OperationContext, size int64, dt *telemetry.DownloadTelemetry) error {
+	consumer := oc.Consumer()
+	start := time.Now()
+	var counted int64
+
+	ticker := time.NewTicker(simulationTick)
+	defer ticker.Stop()
+
+	for {
+		done := int64(time.Since(start).Seconds() * float64(Simulation.BytesPerSecond))
+		if done > size {
+			done = size
+		}
+		if dt != nil {
+			dt.AddBytes(done - counted)
+			counted = done
+		}
+		if done >= size {
+			consumer.Progress(1)
+			return nil
+		}
+		consumer.Progress(float64(done) / float64(size))
+
+		select {
+		case <-oc.ctx.Done():
+			return errors.WithStack(butlerd.CodeOperationCancelled)
+		case <-ticker.C:
+			// keep going!
+		}
+	}
+}
+
+// writePlaceholderFiles fills the install folder with a few small
+// files, so that configure, launch and uninstall have something to
+// work with. Returns their paths, relative to the install folder.
+func writePlaceholderFiles(params *InstallParams) ([]string, error) {
+	var buildID int64
+	if params.Build != nil {
+		buildID = params.Build.ID
+	}
+
+	placeholders := []struct {
+		name     string
+		contents string
+	}{
+		{"README.txt", fmt.Sprintf("Simulated install of %s\nupload %d, build %d\n",
+			GameToString(params.Game), params.Upload.ID, buildID)},
+		{"index.html", "<p>This game was installed with simulated transfers.</p>\n"},
+		{"data/dummy.dat", "placeholder\n"},
+	}
+
+	var files []string
+	for _, p := range placeholders {
+		name, content := p.name, p.contents
+		path := filepath.Join(params.InstallFolder, filepath.FromSlash(name))
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		err = ioutil.WriteFile(path, []byte(content), 0o644)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		files = append(files, name)
+	}
+	return files, nil
+}
@@ -0,0 +1,157 @@
+package operate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Staging folders are written to by operations (queue, perform) and
+// wiped by CleanDownloads. Operations register their staging folder here
+// for as long as their context is loaded, and cleanup only wipes folders
+// it managed to lock, see TryLockStageFolder.
+//
+// Operations running in other processes aren't in the registry, so they
+// also touch a heartbeat file, which cleanup respects as long as it's
+// fresh.
+const (
+	stageHeartbeatInterval = 5 * time.Second
+	stageHeartbeatTimeout  = 30 * time.Second
+)
+
+type stageLock struct {
+	users    int
+	cleaning bool
+}
+
+var (
+	stageLocksMutex sync.Mutex
+	stageLocksCond  = sync.NewCond(&stageLocksMutex)
+	stageLocks      = make(map[string]*stageLock)
+)
+
+func stageLockKey(folder string) string {
+	return filepath.Clean(folder)
+}
+
+func heartbeatPath(stageFolder string) string {
+	return filepath.Join(stageFolder, "operate-heartbeat")
+}
+
+// acquireStageFolder registers an operation on folder, waiting for any
+// cleanup of it to finish first. Several operations may hold the same
+// folder. The returned func releases it, and must be called exactly once.
+func acquireStageFolder(folder string) func() {
+	key := stageLockKey(folder)
+
+	stageLocksMutex.Lock()
+	for {
+		l := stageLocks[key]
+		if l == nil {
+			l = &stageLock{}
+			stageLocks[key] = l
+		}
+		if !l.cleaning {
+			l.users++
+			break
+		}
+		stageLocksCond.Wait()
+	}
+	stageLocksMutex.Unlock()
+
+	return func() {
+		stageLocksMutex.Lock()
+		defer stageLocksMutex.Unlock()
+
+		l := stageLocks[key]
+		l.users--
+		if l.users == 0 {
+			delete(stageLocks, key)
+		}
+	}
+}
+
+// startHeartbeat touches the heartbeat file of stageFolder until the
+// returned func is called, which also removes it.
+func startHeartbeat(stageFolder string) func() {
+	path := heartbeatPath(stageFolder)
+	beat := func() {
+		_ = ioutil.WriteFile(path, []byte(time.Now().UTC().Format(time.RFC3339)), 0o644)
+	}
+	beat()
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(stageHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				beat()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		os.Remove(path)
+	}
+}
+
+func hasFreshHeartbeat(stageFolder string) bool {
+	stats, err := os.Stat(heartbeatPath(stageFolder))
+	if err != nil {
+		return false
+	}
+	return time.Since(stats.ModTime()) < stageHeartbeatTimeout
+}
+
+// IsStageFolderBusy returns true if an operation is using folder,
+// in this process or (as far as we can tell) in another one.
+func IsStageFolderBusy(folder string) bool {
+	stageLocksMutex.Lock()
+	l := stageLocks[stageLockKey(folder)]
+	busy := l != nil
+	stageLocksMutex.Unlock()
+
+	return busy || hasFreshHeartbeat(folder)
+}
+
+// TryLockStageFolder locks folder for cleanup. It fails if any operation
+// is using the folder. Until unlock is called, operations wanting to use
+// the folder wait.
+func TryLockStageFolder(folder string) (unlock func(), ok bool) {
+	key := stageLockKey(folder)
+
+	stageLocksMutex.Lock()
+	if stageLocks[key] != nil {
+		stageLocksMutex.Unlock()
+		return nil, false
+	}
+	l := &stageLock{cleaning: true}
+	stageLocks[key] = l
+	stageLocksMutex.Unlock()
+
+	unlock = func() {
+		stageLocksMutex.Lock()
+		defer stageLocksMutex.Unlock()
+
+		delete(stageLocks, key)
+		stageLocksCond.Broadcast()
+	}
+
+	// checked with the lock held, so an operation of this process
+	// can't start in between.
+	if hasFreshHeartbeat(folder) {
+		unlock()
+		return nil, false
+	}
+	return unlock, true
+}
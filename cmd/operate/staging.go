@@ -0,0 +1,54 @@
+package operate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/itchio/butler/cmd/sizeof"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/headway/united"
+)
+
+// sameDevice returns true if files can be renamed from folder a
+// to folder b, which is only the case if they're on the same device.
+func sameDevice(a string, b string) bool {
+	probe, err := ioutil.TempFile(a, ".butler-rename-probe-")
+	if err != nil {
+		return false
+	}
+	probe.Close()
+	defer os.Remove(probe.Name())
+
+	target := filepath.Join(b, fmt.Sprintf(".butler-rename-probe-%d", os.Getpid()))
+	err = os.Rename(probe.Name(), target)
+	if err != nil {
+		return false
+	}
+	os.Remove(target)
+	return true
+}
+
+// noteCrossDeviceCommit lets the user know when staged files can't
+// just be renamed into the install folder, because the staging folder
+// is on another device (see models.InstallLocation.StagingPath). The
+// bowl falls back to copying them then, which takes longer, and needs
+// as much room on the install device as the staged files take.
+func noteCrossDeviceCommit(consumer *state.Consumer, stageFolder string, installFolder string) {
+	_, err := os.Stat(stageFolder)
+	if err != nil {
+		// nothing was staged
+		return
+	}
+	if sameDevice(stageFolder, installFolder) {
+		return
+	}
+
+	stagedSize, err := sizeof.Do(stageFolder)
+	if err != nil {
+		consumer.Warnf("Could not determine size of staged files: %+v", err)
+		return
+	}
+	consumer.Infof("Staging folder is on another device, copying %s of staged files into place", united.FormatBytes(stagedSize))
+}
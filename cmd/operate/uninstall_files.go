@@ -0,0 +1,218 @@
+package operate
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/wipe"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/hush/bfs"
+	"github.com/pkg/errors"
+)
+
+// file extensions that give away what a file is, checked
+// before the folders it's in.
+var uninstallExtCategories = map[string]butlerd.UninstallFileCategory{
+	".sav":   butlerd.UninstallFileCategorySaves,
+	".save":  butlerd.UninstallFileCategorySaves,
+	".log":   butlerd.UninstallFileCategoryLogs,
+	".tmp":   butlerd.UninstallFileCategoryCache,
+	".ini":   butlerd.UninstallFileCategoryConfig,
+	".cfg":   butlerd.UninstallFileCategoryConfig,
+	".conf":  butlerd.UninstallFileCategoryConfig,
+	".prefs": butlerd.UninstallFileCategoryConfig,
+}
+
+// folder names (lower-case) that give away what's in them. Engines
+// nest them (UE4 has Saved/Logs, Saved/Config...), so the innermost
+// one wins.
+var uninstallDirCategories = map[string]butlerd.UninstallFileCategory{
+	"save":        butlerd.UninstallFileCategorySaves,
+	"saves":       butlerd.UninstallFileCategorySaves,
+	"saved":       butlerd.UninstallFileCategorySaves,
+	"savegame":    butlerd.UninstallFileCategorySaves,
+	"savegames":   butlerd.UninstallFileCategorySaves,
+	"savedata":    butlerd.UninstallFileCategorySaves,
+	"saved games": butlerd.UninstallFileCategorySaves,
+	"userdata":    butlerd.UninstallFileCategorySaves,
+	"config":      butlerd.UninstallFileCategoryConfig,
+	"configs":     butlerd.UninstallFileCategoryConfig,
+	"settings":    butlerd.UninstallFileCategoryConfig,
+	"preferences": butlerd.UninstallFileCategoryConfig,
+	"prefs":       butlerd.UninstallFileCategoryConfig,
+	"log":         butlerd.UninstallFileCategoryLogs,
+	"logs":        butlerd.UninstallFileCategoryLogs,
+	"cache":       butlerd.UninstallFileCategoryCache,
+	"caches":      butlerd.UninstallFileCategoryCache,
+	"shadercache": butlerd.UninstallFileCategoryCache,
+	"gpucache":    butlerd.UninstallFileCategoryCache,
+	"temp":        butlerd.UninstallFileCategoryCache,
+	"tmp":         butlerd.UninstallFileCategoryCache,
+}
+
+// categorizeUserFile guesses what a file that isn't in the receipt is,
+// from its slash-separated path relative to the install folder.
+func categorizeUserFile(name string) butlerd.UninstallFileCategory {
+	if category, ok := uninstallExtCategories[strings.ToLower(path.Ext(name))]; ok {
+		return category
+	}
+
+	dirs := strings.Split(path.Dir(name), "/")
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if category, ok := uninstallDirCategories[strings.ToLower(dirs[i])]; ok {
+			return category
+		}
+	}
+	return butlerd.UninstallFileCategoryUnknown
+}
+
+// scanInstallFolder lists all the files in installFolder, sorted by path,
+// and categorizes those that aren't in the receipt. Without a receipt,
+// files that don't look like user data are assumed to have been installed.
+// Folders in skip (slash-separated, relative to installFolder) belong to
+// other caves, and aren't listed.
+func scanInstallFolder(installFolder string, receipt *bfs.Receipt, skip []string) ([]*butlerd.UninstallFile, error) {
+	skipped := make(map[string]bool)
+	for _, name := range skip {
+		skipped[name] = true
+	}
+
+	installed := make(map[string]bool)
+	if receipt != nil {
+		for _, f := range receipt.Files {
+			installed[f] = true
+		}
+	}
+
+	var files []*butlerd.UninstallFile
+	err := filepath.Walk(installFolder, func(fullPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			if fullPath == installFolder && os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		rel, err := filepath.Rel(installFolder, fullPath)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if info.IsDir() {
+			if skipped[name] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		var category butlerd.UninstallFileCategory
+		switch {
+		case installed[name], strings.HasPrefix(name, ".itch/"):
+			category = butlerd.UninstallFileCategoryInstalled
+		default:
+			category = categorizeUserFile(name)
+			if receipt == nil && category == butlerd.UninstallFileCategoryUnknown {
+				category = butlerd.UninstallFileCategoryInstalled
+			}
+		}
+
+		files = append(files, &butlerd.UninstallFile{
+			Path:     name,
+			Category: category,
+			Size:     info.Size(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "scanning install folder")
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
+
+// wipeInstallFolder removes the install folder, except for files whose
+// category should be preserved, and folders in skip, see scanInstallFolder.
+// With nothing to preserve or skip, the whole folder goes, including files
+// that weren't there when it was scanned.
+func wipeInstallFolder(consumer *state.Consumer, installFolder string, files []*butlerd.UninstallFile, preserve []butlerd.UninstallFileCategory, skip []string) error {
+	if len(preserve) == 0 && len(skip) == 0 {
+		return wipe.Do(consumer, installFolder)
+	}
+
+	preserved := make(map[butlerd.UninstallFileCategory]bool)
+	for _, category := range preserve {
+		preserved[category] = true
+	}
+
+	for _, f := range files {
+		if preserved[f.Category] {
+			continue
+		}
+		err := os.Remove(filepath.Join(installFolder, filepath.FromSlash(f.Path)))
+		if err != nil && !os.IsNotExist(err) {
+			consumer.Warnf("Could not remove %s: %s", f.Path, err.Error())
+		}
+	}
+
+	// now remove empty folders, deepest first
+	var dirs []string
+	filepath.Walk(installFolder, func(fullPath string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() {
+			dirs = append(dirs, fullPath)
+		}
+		return nil
+	})
+	for i := len(dirs) - 1; i >= 0; i-- {
+		// fails for non-empty folders, which is what we want
+		os.Remove(dirs[i])
+	}
+	return nil
+}
+
+// RemoveEmptyParents removes the folders between root and folder that
+// are empty, deepest first. It stops at the first one that isn't, and
+// never removes root itself, or anything outside of it.
+func RemoveEmptyParents(folder string, root string) {
+	root = filepath.Clean(root)
+	dir := filepath.Dir(filepath.Clean(folder))
+	for dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)) {
+		// fails for non-empty folders, which is what we want
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// summarizeUninstall checks which of the scanned files are still in
+// the install folder, to tell what was removed, preserved or left behind.
+func summarizeUninstall(installFolder string, files []*butlerd.UninstallFile, preserve []butlerd.UninstallFileCategory) *butlerd.UninstallPerformResult {
+	preserved := make(map[butlerd.UninstallFileCategory]bool)
+	for _, category := range preserve {
+		preserved[category] = true
+	}
+
+	res := &butlerd.UninstallPerformResult{
+		Removed:    []*butlerd.UninstallFile{},
+		Preserved:  []*butlerd.UninstallFile{},
+		LeftBehind: []*butlerd.UninstallFile{},
+	}
+	for _, f := range files {
+		_, err := os.Lstat(filepath.Join(installFolder, filepath.FromSlash(f.Path)))
+		switch {
+		case os.IsNotExist(err):
+			res.Removed = append(res.Removed, f)
+		case preserved[f.Category]:
+			res.Preserved = append(res.Preserved, f)
+		default:
+			res.LeftBehind = append(res.LeftBehind, f)
+		}
+	}
+	return res
+}
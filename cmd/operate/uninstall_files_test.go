@@ -0,0 +1,151 @@
+package operate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/hush/bfs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategorizeUserFile(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.EqualValues(butlerd.UninstallFileCategorySaves, categorizeUserFile("slot1.sav"))
+	assert.EqualValues(butlerd.UninstallFileCategorySaves, categorizeUserFile("SaveGames/slot1.dat"))
+	assert.EqualValues(butlerd.UninstallFileCategoryConfig, categorizeUserFile("Game/Saved/Config/Windows/Input.dat"), "innermost folder wins")
+	assert.EqualValues(butlerd.UninstallFileCategoryConfig, categorizeUserFile("saves/options.ini"), "extension wins")
+	assert.EqualValues(butlerd.UninstallFileCategoryLogs, categorizeUserFile("Game/Saved/Logs/Game.log"))
+	assert.EqualValues(butlerd.UninstallFileCategoryLogs, categorizeUserFile("logs/run.txt"))
+	assert.EqualValues(butlerd.UninstallFileCategoryCache, categorizeUserFile("ShaderCache/0001.bin"))
+	assert.EqualValues(butlerd.UninstallFileCategoryUnknown, categorizeUserFile("screenshot.png"))
+}
+
+func TestUninstallFiles(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "uninstall-files")
+	must(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(name string) {
+		fullPath := filepath.Join(dir, filepath.FromSlash(name))
+		must(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		must(t, ioutil.WriteFile(fullPath, []byte(name), 0644))
+	}
+	for _, name := range []string{"game.exe", "data/level1.pak", "saves/slot1.dat", "logs/run.log", "notes.txt"} {
+		write(name)
+	}
+
+	receipt := &bfs.Receipt{Files: []string{"game.exe", "data/level1.pak"}}
+	files, err := scanInstallFolder(dir, receipt, nil)
+	must(t, err)
+
+	categories := make(map[string]butlerd.UninstallFileCategory)
+	for _, f := range files {
+		categories[f.Path] = f.Category
+	}
+	assert.EqualValues(map[string]butlerd.UninstallFileCategory{
+		"data/level1.pak": butlerd.UninstallFileCategoryInstalled,
+		"game.exe":        butlerd.UninstallFileCategoryInstalled,
+		"logs/run.log":    butlerd.UninstallFileCategoryLogs,
+		"notes.txt":       butlerd.UninstallFileCategoryUnknown,
+		"saves/slot1.dat": butlerd.UninstallFileCategorySaves,
+	}, categories)
+
+	noReceiptFiles, err := scanInstallFolder(dir, nil, nil)
+	must(t, err)
+	for _, f := range noReceiptFiles {
+		if f.Path == "notes.txt" {
+			assert.EqualValues(butlerd.UninstallFileCategoryInstalled, f.Category, "without a receipt, unknown files are considered installed")
+		}
+	}
+
+	preserve := []butlerd.UninstallFileCategory{butlerd.UninstallFileCategorySaves, butlerd.UninstallFileCategoryUnknown}
+	must(t, wipeInstallFolder(&state.Consumer{}, dir, files, preserve, nil))
+
+	res := summarizeUninstall(dir, files, preserve)
+	paths := func(files []*butlerd.UninstallFile) []string {
+		var res []string
+		for _, f := range files {
+			res = append(res, f.Path)
+		}
+		return res
+	}
+	assert.EqualValues([]string{"data/level1.pak", "game.exe", "logs/run.log"}, paths(res.Removed))
+	assert.EqualValues([]string{"notes.txt", "saves/slot1.dat"}, paths(res.Preserved))
+	assert.Empty(res.LeftBehind)
+
+	_, err = os.Stat(filepath.Join(dir, "data"))
+	assert.True(os.IsNotExist(err), "empty folders are removed")
+
+	must(t, wipeInstallFolder(&state.Consumer{}, dir, files, nil, nil))
+	_, err = os.Stat(dir)
+	assert.True(os.IsNotExist(err), "nothing preserved, whole folder is wiped")
+}
+
+func TestUninstallFilesSkipsDLC(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "uninstall-files-dlc")
+	must(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(name string) {
+		fullPath := filepath.Join(dir, filepath.FromSlash(name))
+		must(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		must(t, ioutil.WriteFile(fullPath, []byte(name), 0644))
+	}
+	for _, name := range []string{"game.exe", "expansion/saves/slot1.dat", "expansion-notes.txt"} {
+		write(name)
+	}
+
+	skip := []string{"expansion"}
+	files, err := scanInstallFolder(dir, &bfs.Receipt{Files: []string{"game.exe"}}, skip)
+	must(t, err)
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	assert.EqualValues([]string{"expansion-notes.txt", "game.exe"}, paths, "DLC folders aren't scanned")
+
+	must(t, wipeInstallFolder(&state.Consumer{}, dir, files, nil, skip))
+	assert.NoFileExists(filepath.Join(dir, "game.exe"))
+	assert.NoFileExists(filepath.Join(dir, "expansion-notes.txt"))
+	assert.FileExists(filepath.Join(dir, "expansion", "saves", "slot1.dat"), "nor wiped")
+}
+
+func TestRemoveEmptyParents(t *testing.T) {
+	assert := assert.New(t)
+
+	root, err := ioutil.TempDir("", "remove-empty-parents")
+	must(t, err)
+	defer os.RemoveAll(root)
+
+	must(t, os.MkdirAll(filepath.Join(root, "finji", "overland"), 0755))
+	must(t, os.MkdirAll(filepath.Join(root, "finji", "windosill"), 0755))
+	must(t, os.MkdirAll(filepath.Join(root, "sokpop", "bamboo", "1234"), 0755))
+
+	must(t, os.Remove(filepath.Join(root, "finji", "overland")))
+	RemoveEmptyParents(filepath.Join(root, "finji", "overland"), root)
+	assert.DirExists(filepath.Join(root, "finji", "windosill"), "parents with other games are kept")
+
+	must(t, os.Remove(filepath.Join(root, "sokpop", "bamboo", "1234")))
+	RemoveEmptyParents(filepath.Join(root, "sokpop", "bamboo", "1234"), root)
+	assert.NoDirExists(filepath.Join(root, "sokpop"))
+	assert.DirExists(root, "root is never removed")
+
+	must(t, os.Remove(filepath.Join(root, "finji", "windosill")))
+	RemoveEmptyParents(filepath.Join(root, "finji", "windosill"), filepath.Join(root, "elsewhere"))
+	assert.DirExists(filepath.Join(root, "finji"), "nothing outside of root is removed")
+}
+
+func must(t *testing.T, err error) {
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
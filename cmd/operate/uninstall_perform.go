@@ -2,10 +2,14 @@ package operate
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"crawshaw.io/sqlite"
 
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/butlerd/messages"
-	"github.com/itchio/butler/cmd/wipe"
 	"github.com/itchio/butler/database/models"
 	"github.com/itchio/hush"
 	"github.com/itchio/hush/bfs"
@@ -13,26 +17,60 @@ import (
 	"github.com/pkg/errors"
 )
 
-func UninstallPerform(ctx context.Context, rc *butlerd.RequestContext, params butlerd.UninstallPerformParams) error {
+func UninstallPerform(ctx context.Context, rc *butlerd.RequestContext, params butlerd.UninstallPerformParams) (*butlerd.UninstallPerformResult, error) {
 	consumer := rc.Consumer
 	conn := rc.GetConn()
 	defer rc.PutConn(conn)
 
 	cave := ValidateCave(rc, params.CaveID)
+	installFolder := cave.GetInstallFolder(conn)
+	var locationPath string
+	if cave.CustomInstallFolder == "" {
+		locationPath = cave.GetInstallLocation(conn).Path
+	}
+
+	dlcFolders, err := uninstallNestedDLCs(ctx, rc, cave, installFolder, params)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt, err := bfs.ReadReceipt(installFolder)
+	if err != nil {
+		consumer.Warnf("Could not read receipt: %s", err.Error())
+	}
+
+	// read now, since the wipe takes it away
+	om, err := ReadOverflowMap(installFolder)
+	if err != nil {
+		consumer.Warnf("Could not read overflow map: %s", err.Error())
+	}
+
+	files, err := scanInstallFolder(installFolder, receipt, dlcFolders)
+	if err != nil {
+		consumer.Warnf("Could not scan install folder: %s", err.Error())
+	}
+
+	notif := butlerd.UninstallFilesCategorizedNotification{
+		CaveID: cave.ID,
+		Files:  []*butlerd.UninstallFile{},
+	}
+	for _, f := range files {
+		if f.Category != butlerd.UninstallFileCategoryInstalled {
+			consumer.Infof("Found %s file: %s", f.Category, f.Path)
+			notif.Files = append(notif.Files, f)
+		}
+	}
+	err = messages.UninstallFilesCategorized.Notify(rc, notif)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
 
 	if params.Hard {
 		consumer.Opf("Performing hard uninstall for (%s)", cave.ID)
 	} else {
 		consumer.Opf("Performing graceful uninstall for (%s)", cave.ID)
-		installFolder := cave.GetInstallFolder(conn)
 
 		var installerType = hush.InstallerTypeUnknown
-
-		receipt, err := bfs.ReadReceipt(installFolder)
-		if err != nil {
-			consumer.Warnf("Could not read receipt: %s", err.Error())
-		}
-
 		if receipt != nil && receipt.InstallerName != "" {
 			installerType = (hush.InstallerType)(receipt.InstallerName)
 		}
@@ -46,7 +84,7 @@ func UninstallPerform(ctx context.Context, rc *butlerd.RequestContext, params bu
 
 			manager = installers.GetManager("archive")
 			if manager == nil {
-				return errors.New("archive install manager not found, can't uninstall")
+				return nil, errors.New("archive install manager not found, can't uninstall")
 			}
 		}
 
@@ -61,7 +99,7 @@ func UninstallPerform(ctx context.Context, rc *butlerd.RequestContext, params bu
 			Type:   butlerd.TaskTypeUninstall,
 		})
 		if err != nil {
-			return errors.WithStack(err)
+			return nil, errors.WithStack(err)
 		}
 
 		consumer.Infof("Running uninstall manager...")
@@ -70,14 +108,14 @@ func UninstallPerform(ctx context.Context, rc *butlerd.RequestContext, params bu
 		rc.EndProgress()
 
 		if err != nil {
-			return errors.WithStack(err)
+			return nil, errors.WithStack(err)
 		}
 
 		err = messages.TaskSucceeded.Notify(rc, butlerd.TaskSucceededNotification{
 			Type: butlerd.TaskTypeUninstall,
 		})
 		if err != nil {
-			return errors.WithStack(err)
+			return nil, errors.WithStack(err)
 		}
 	}
 
@@ -93,11 +131,70 @@ func UninstallPerform(ctx context.Context, rc *butlerd.RequestContext, params bu
 				consumer.Warnf("While wiping install folder: %+v", r)
 			}
 		}()
-		consumer.Infof("Wiping install folder...")
+		if len(params.PreserveUserData) > 0 {
+			consumer.Infof("Wiping install folder, preserving %v...", params.PreserveUserData)
+		} else {
+			consumer.Infof("Wiping install folder...")
+		}
 
-		installFolder := cave.GetInstallFolder(conn)
-		models.Must(wipe.Do(consumer, installFolder))
+		models.Must(wipeInstallFolder(consumer, installFolder, files, params.PreserveUserData, dlcFolders))
+		if locationPath != "" {
+			// folder name templates may have put it in subfolders
+			RemoveEmptyParents(installFolder, locationPath)
+		}
 	}()
 
-	return nil
+	if om != nil {
+		consumer.Infof("Wiping overflow folder (%s)...", om.Folder)
+		err = os.RemoveAll(om.Folder)
+		if err != nil {
+			consumer.Warnf("Could not wipe overflow folder: %s", err.Error())
+		}
+	}
+
+	res := summarizeUninstall(installFolder, files, params.PreserveUserData)
+	for _, f := range res.Preserved {
+		consumer.Infof("Preserved %s file: %s", f.Category, f.Path)
+	}
+	for _, f := range res.LeftBehind {
+		consumer.Warnf("Left behind %s file: %s", f.Category, f.Path)
+	}
+	consumer.Statf("Removed %d files, preserved %d, left behind %d", len(res.Removed), len(res.Preserved), len(res.LeftBehind))
+
+	return res, nil
+}
+
+// uninstallNestedDLCs uninstalls the caves that were installed as DLC
+// of cave with useParentFolder, since they can't outlive the folder
+// they're in. It returns their folders, slash-separated and relative to
+// installFolder: whatever they preserved is left alone.
+func uninstallNestedDLCs(ctx context.Context, rc *butlerd.RequestContext, cave *models.Cave, installFolder string, params butlerd.UninstallPerformParams) ([]string, error) {
+	consumer := rc.Consumer
+
+	var dlcs []*models.Cave
+	var dlcFolders []string
+	rc.WithConn(func(conn *sqlite.Conn) {
+		for _, dlc := range models.CavesByDLCParentCaveID(conn, cave.ID) {
+			rel, err := filepath.Rel(installFolder, dlc.GetInstallFolder(conn))
+			if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				// has a folder of its own
+				continue
+			}
+			dlcs = append(dlcs, dlc)
+			dlcFolders = append(dlcFolders, filepath.ToSlash(rel))
+		}
+	})
+
+	for i, dlc := range dlcs {
+		consumer.Infof("Uninstalling DLC (%s) in (%s) first", dlc.ID, dlcFolders[i])
+		_, err := UninstallPerform(ctx, rc, butlerd.UninstallPerformParams{
+			CaveID:           dlc.ID,
+			Hard:             params.Hard,
+			PreserveUserData: params.PreserveUserData,
+		})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "uninstalling DLC (%s)", dlc.ID)
+		}
+	}
+	return dlcFolders, nil
 }
@@ -249,6 +249,9 @@ func applyPatch(oc *OperationContext, meta *MetaSubcontext, isub *InstallSubcont
 		return err
 	}
 
+	pp := newPatchProgress(oc, p.GetSourceContainer(), p.GetTargetContainer())
+	bowl = pp.Wrap(bowl)
+
 	err = p.Resume(checkpoint, targetPool, bowl)
 	if err != nil {
 		return errors.WithMessage(err, "while applying patch")
@@ -256,6 +259,7 @@ func applyPatch(oc *OperationContext, meta *MetaSubcontext, isub *InstallSubcont
 
 	os.RemoveAll(checkpointPath)
 
+	noteCrossDeviceCommit(consumer, stageFolder, params.InstallFolder)
 	err = bowl.Commit()
 	if err != nil {
 		return errors.WithMessage(err, "while committing patch")
@@ -0,0 +1,317 @@
+package operate
+
+import (
+	"archive/zip"
+	"path"
+	"sort"
+	"strings"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/httpkit/eos/option"
+	"github.com/itchio/hush/bfs"
+	"github.com/itchio/wharf/pwr"
+	"github.com/pkg/errors"
+)
+
+// unknownSize is what verifyAgainst expects of files
+// it can only check the presence of.
+const unknownSize = -1
+
+// VerifyCave checks the files in a cave's install folder against its
+// build's signature, or failing that, against its upload's zip archive
+// or its receipt. It doesn't change anything on disk.
+func VerifyCave(rc *butlerd.RequestContext, caveID string) (*butlerd.CaveVerifyResult, error) {
+	consumer := rc.Consumer
+
+	var cave *models.Cave
+	var installFolder string
+	var access *GameAccess
+	rc.WithConn(func(conn *sqlite.Conn) {
+		cave = models.CaveByID(conn, caveID)
+		if cave != nil {
+			cave.Preload(conn)
+			installFolder = cave.GetInstallFolder(conn)
+			access = AccessForCave(conn, cave)
+		}
+	})
+	if cave == nil {
+		return nil, errors.Errorf("cave not found: (%s)", caveID)
+	}
+
+	consumer.Infof("Verifying cave (%s) in (%s)", caveID, installFolder)
+
+	om, err := ReadOverflowMap(installFolder)
+	if err != nil {
+		consumer.Warnf("Could not read overflow map: %s", err.Error())
+		om = nil
+	}
+
+	sizes, err := scanReceiptFiles(installFolder, om)
+	if err != nil {
+		return nil, err
+	}
+	consumer.Infof("Found %d files on disk", len(sizes))
+
+	notify := func(path string, progress float64) {
+		messages.CaveVerifyProgress.Notify(rc, butlerd.CaveVerifyProgressNotification{
+			CaveID:   caveID,
+			Path:     path,
+			Progress: progress,
+		})
+	}
+
+	renames, err := ReadReceiptRenames(installFolder)
+	if err != nil {
+		consumer.Warnf("Could not read renamed files from receipt: %s", err.Error())
+		renames = nil
+	}
+
+	var res *butlerd.CaveVerifyResult
+	if cave.PlayableEarly {
+		res, err = verifyPlayableEarly(rc, access, cave, installFolder, renames, sizes, notify)
+		if err != nil {
+			return nil, err
+		}
+	} else if cave.Build != nil && len(renames) > 0 {
+		// the signature only knows about the original names
+		consumer.Infof("%d files were installed under sanitized names, not checking against the build's signature", len(renames))
+	} else if cave.Build != nil {
+		sigInfo, err := fetchBuildSignature(rc, access, cave.Build)
+		if err != nil {
+			consumer.Warnf("Could not fetch signature for build %d, not checking contents: %s", cave.Build.ID, err.Error())
+		} else {
+			res, err = verifyAgainstSignature(rc, installFolder, om, sizes, sigInfo, notify)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if res == nil {
+		expected, err := listUploadArchive(rc, access, cave.Upload)
+		if err != nil {
+			consumer.Warnf("Could not list upload archive, not checking sizes: %s", err.Error())
+		}
+		if expected != nil {
+			rename := renamer(renames)
+			renamed := make(map[string]int64)
+			for name, size := range expected {
+				renamed[rename(name)] = size
+			}
+			res = verifyAgainst(butlerd.CaveVerifyMethodArchive, renamed, sizes, notify)
+		}
+	}
+
+	if res == nil {
+		receipt, err := bfs.ReadReceipt(installFolder)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if !receipt.HasFiles() {
+			return nil, errors.Errorf("nothing to verify cave (%s) against: no signature, and its receipt lists no files", caveID)
+		}
+		expected := make(map[string]int64)
+		for _, f := range receipt.Files {
+			expected[f] = unknownSize
+		}
+		res = verifyAgainst(butlerd.CaveVerifyMethodReceipt, expected, sizes, notify)
+	}
+
+	consumer.Statf("Verified cave (%s) against its %s: %d missing, %d added, %d corrupted",
+		caveID, res.Method, len(res.Missing), len(res.Added), len(res.Corrupted))
+	return res, nil
+}
+
+// verifyPlayableEarly checks the files of a cave that's playable early
+// against its receipt, which only lists the ones its game needs to start.
+// The rest is still being installed, so nothing counts as added.
+func verifyPlayableEarly(rc *butlerd.RequestContext, access *GameAccess, cave *models.Cave, installFolder string, renames map[string]string, sizes map[string]int64, notify func(path string, progress float64)) (*butlerd.CaveVerifyResult, error) {
+	consumer := rc.Consumer
+	consumer.Infof("Cave is still being installed, only checking the files it needs to start")
+
+	receipt, err := bfs.ReadReceipt(installFolder)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !receipt.HasFiles() {
+		return nil, errors.Errorf("nothing to verify cave (%s) against: its receipt lists no files", cave.ID)
+	}
+
+	archiveSizes, err := listUploadArchive(rc, access, cave.Upload)
+	if err != nil {
+		consumer.Warnf("Could not list upload archive, not checking sizes: %s", err.Error())
+	}
+	method := butlerd.CaveVerifyMethodReceipt
+	rename := renamer(renames)
+	renamedSizes := make(map[string]int64)
+	for name, size := range archiveSizes {
+		renamedSizes[rename(name)] = size
+	}
+
+	expected := make(map[string]int64)
+	installed := make(map[string]int64)
+	for _, f := range receipt.Files {
+		expected[f] = unknownSize
+		if size, ok := renamedSizes[f]; ok {
+			expected[f] = size
+			method = butlerd.CaveVerifyMethodArchive
+		}
+		if size, ok := sizes[f]; ok {
+			installed[f] = size
+		}
+	}
+
+	res := verifyAgainst(method, expected, installed, notify)
+	res.PlayableEarly = true
+	return res, nil
+}
+
+func verifyAgainstSignature(rc *butlerd.RequestContext, installFolder string, om *OverflowMap, sizes map[string]int64, sigInfo *pwr.SignatureInfo, notify func(path string, progress float64)) (*butlerd.CaveVerifyResult, error) {
+	container := sigInfo.Container
+
+	// files are validated one after the other, so how far along
+	// validation is tells which ones are done.
+	ends := make([]int64, len(container.Files))
+	var offset int64
+	for i, f := range container.Files {
+		offset += f.Size
+		ends[i] = offset
+	}
+	next := 0
+	onProgress := func(progress float64) {
+		done := int64(progress * float64(container.Size))
+		for next < len(ends) && ends[next] <= done {
+			notify(container.Files[next].Path, progress)
+			next++
+		}
+	}
+
+	wounded, err := findWoundedFiles(rc, installFolder, sigInfo, onProgress)
+	if err != nil {
+		return nil, err
+	}
+	for ; next < len(ends); next++ {
+		notify(container.Files[next].Path, 1)
+	}
+	if om != nil {
+		// overflowed files are symlinks here, the real ones get checked
+		// where they are instead.
+		overflowWounded, err := findWoundedFiles(rc, om.Folder, sigInfo, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range om.Files {
+			wounded[f] = overflowWounded[f]
+		}
+	}
+
+	res := newVerifyResult(butlerd.CaveVerifyMethodSignature)
+	official := make(map[string]bool)
+	for _, f := range resultForContainer(container).Files {
+		official[f] = true
+		_, onDisk := sizes[f]
+		switch {
+		case !onDisk:
+			res.Missing = append(res.Missing, f)
+		case wounded[f]:
+			res.Corrupted = append(res.Corrupted, f)
+		}
+	}
+	for name := range sizes {
+		if !official[name] {
+			res.Added = append(res.Added, name)
+		}
+	}
+	sortVerifyResult(res)
+	return res, nil
+}
+
+// verifyAgainst compares the files on disk to the expected ones,
+// both given as sizes by slash-separated path. Expected files of
+// unknownSize are only checked for presence.
+func verifyAgainst(method butlerd.CaveVerifyMethod, expected map[string]int64, sizes map[string]int64, notify func(path string, progress float64)) *butlerd.CaveVerifyResult {
+	var names []string
+	for name := range expected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	res := newVerifyResult(method)
+	for i, name := range names {
+		notify(name, float64(i+1)/float64(len(names)))
+		size, ok := sizes[name]
+		switch {
+		case !ok:
+			res.Missing = append(res.Missing, name)
+		case expected[name] != unknownSize && expected[name] != size:
+			res.Corrupted = append(res.Corrupted, name)
+		}
+	}
+	for name := range sizes {
+		if _, ok := expected[name]; !ok {
+			res.Added = append(res.Added, name)
+		}
+	}
+	sortVerifyResult(res)
+	return res
+}
+
+func newVerifyResult(method butlerd.CaveVerifyMethod) *butlerd.CaveVerifyResult {
+	return &butlerd.CaveVerifyResult{
+		Method:    method,
+		Missing:   []string{},
+		Added:     []string{},
+		Corrupted: []string{},
+	}
+}
+
+func sortVerifyResult(res *butlerd.CaveVerifyResult) {
+	sort.Strings(res.Missing)
+	sort.Strings(res.Added)
+	sort.Strings(res.Corrupted)
+}
+
+// listUploadArchive returns the sizes of the files in upload, by
+// slash-separated path, if it's a zip archive. It only reads the
+// central directory. It returns nil if upload isn't a zip.
+func listUploadArchive(rc *butlerd.RequestContext, access *GameAccess, upload *itchio.Upload) (map[string]int64, error) {
+	if upload == nil || strings.ToLower(path.Ext(upload.Filename)) != ".zip" {
+		return nil, nil
+	}
+
+	client := rc.Client(access.APIKey)
+	uploadURL := client.MakeUploadDownloadURL(itchio.MakeUploadDownloadURLParams{
+		UploadID:    upload.ID,
+		Credentials: access.Credentials,
+	})
+
+	file, err := eos.Open(uploadURL, option.WithConsumer(rc.Consumer))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer file.Close()
+
+	stats, err := file.Stat()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	zr, err := zip.NewReader(file, stats.Size())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	sizes := make(map[string]int64)
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		sizes[strings.TrimPrefix(f.Name, "./")] = int64(f.UncompressedSize64)
+	}
+	return sizes, nil
+}
@@ -111,6 +111,7 @@ func (h *handler) FetchPrereqs(tsc *TaskStateConsumer, names []string) error {
 		rcc.Conn = conn
 		rcc.Consumer = taskConsumer
 
+		ignoreInstallers := true
 		_, err = install.InstallQueue(&rcc, butlerd.InstallQueueParams{
 			Game:   RedistsGame,
 			Upload: upload,
@@ -120,7 +121,7 @@ func (h *handler) FetchPrereqs(tsc *TaskStateConsumer, names []string) error {
 			StagingFolder: stagingFolder,
 			InstallFolder: destDir,
 
-			IgnoreInstallers: true,
+			IgnoreInstallers: &ignoreInstallers,
 		})
 		if err != nil {
 			return errors.Wrapf(err, "queueing download+extract for prereq %s", name)
@@ -17,6 +17,7 @@ import (
 	"github.com/itchio/butler/comm"
 	"github.com/itchio/butler/filtering"
 	"github.com/itchio/butler/mansion"
+	"github.com/itchio/butler/pathsafety"
 
 	"github.com/itchio/headway/counter"
 	"github.com/itchio/headway/state"
@@ -26,6 +27,7 @@ import (
 
 	"github.com/itchio/lake"
 	"github.com/itchio/lake/tlc"
+	"github.com/itchio/ox"
 
 	"github.com/itchio/wharf/pwr"
 	"github.com/itchio/wharf/wsync"
@@ -60,7 +62,7 @@ var args = struct {
 
 func Register(ctx *mansion.Context) {
 	cmd := ctx.App.Command("push", "Upload a new build to itch.io. See `butler help push`.")
-	cmd.Arg("src", "Directory to upload. May also be a zip archive (slower)").Required().StringVar(&args.src)
+	cmd.Arg("src", "Directory to upload. May also be a zip archive (slower), a .tar or .tar.zst archive, or - to read a tar stream from stdin").Required().StringVar(&args.src)
 	cmd.Arg("target", "Where to push, for example 'leafo/x-moon:win-64'. Targets are of the form project:channel, where project is username/game or game_id.").Required().StringVar(&args.target)
 	cmd.Flag("userversion", "A user-supplied version number that you can later query builds by").StringVar(&args.userVersion)
 	cmd.Flag("userversion-file", "A file containing a user-supplied version number that you can later query builds by").StringVar(&args.userVersionFile)
@@ -107,18 +109,40 @@ func Do(ctx *mansion.Context, buildPath string, specStr string, userVersion stri
 
 	go doWalk(buildPath, sourceContainerChan, walkErrs, fixPerms, walkOpts)
 
+	var walked *walkResult
+	var walkErr error
+	waitWalk := func() (*walkResult, error) {
+		if walked == nil && walkErr == nil {
+			select {
+			case err := <-walkErrs:
+				walkErr = errors.Wrap(err, "walking directory to push")
+			case walkies := <-sourceContainerChan:
+				walked = &walkies
+			}
+		}
+		return walked, walkErr
+	}
+	defer func() {
+		if walked == nil && isTarSource(buildPath) {
+			// don't leave spilled files behind
+			waitWalk()
+		}
+		if walked != nil {
+			walked.release()
+		}
+	}()
+
 	if args.dryRun {
 		comm.Opf("Dry run, listing files we would push...")
-		select {
-		case walkErr := <-walkErrs:
-			return errors.Wrap(walkErr, "walking directory to push")
-		case walkies := <-sourceContainerChan:
-			log := func(line string) {
-				comm.Logf(line)
-			}
-			walkies.container.Print(log)
-			comm.Statf("Would push %s", walkies.container)
+		walkies, err := waitWalk()
+		if err != nil {
+			return err
 		}
+		log := func(line string) {
+			comm.Logf(line)
+		}
+		walkies.container.Print(log)
+		comm.Statf("Would push %s", walkies.container)
 		return nil
 	}
 
@@ -137,59 +161,32 @@ func Do(ctx *mansion.Context, buildPath string, specStr string, userVersion stri
 		return errors.Wrap(err, "authenticating")
 	}
 
-	getSignature := func(ID int64) (*pwr.SignatureInfo, error) {
-		buildFiles, err := client.ListBuildFiles(ctx.DefaultCtx(), ID)
-		if err != nil {
-			return nil, errors.Wrap(err, "listing build files")
-		}
-
-		signatureFile := itchio.FindBuildFile(itchio.BuildFileTypeSignature, buildFiles.Files)
-		if signatureFile == nil {
-			comm.Dief("Could not find signature for parent build %d, aborting", ID)
-		}
-
-		signatureURL := client.MakeBuildFileDownloadURL(itchio.MakeBuildFileDownloadURLParams{
-			BuildID: ID,
-			FileID:  signatureFile.ID,
-		})
-
-		signatureReader, err := eos.Open(signatureURL, option.WithConsumer(consumer))
-		if err != nil {
-			return nil, errors.Wrap(err, "opening signature")
-		}
-		defer signatureReader.Close()
-
-		signatureSource := seeksource.FromFile(signatureReader)
-
-		_, err = signatureSource.Resume(nil)
-		if err != nil {
-			return nil, errors.Wrap(err, "opening signature")
-		}
-
-		signature, err := pwr.ReadSignature(context.Background(), signatureSource)
-		if err != nil {
-			return nil, errors.Wrap(err, "reading signature")
-		}
-
-		return signature, nil
-	}
-
 	if ifChanged {
 		chanInfo, err := client.GetChannel(ctx.DefaultCtx(), spec.Target, spec.Channel)
 		if err == nil && chanInfo != nil && chanInfo.Channel != nil && chanInfo.Channel.Head != nil {
 			comm.Opf("Comparing against previous build...")
-			sig, err := getSignature(chanInfo.Channel.Head.ID)
+			sig, err := FetchBuildSignature(ctx, client, chanInfo.Channel.Head.ID)
 			if err != nil {
 				return errors.Wrap(err, "getting previous build signature")
 			}
 
-			err = pwr.AssertValid(buildPath, sig)
-			if err == nil {
+			if isTarSource(buildPath) {
+				walkies, err := waitWalk()
+				if err != nil {
+					return err
+				}
+				same, err := matchesSignature(walkies.container, walkies.pool, sig)
+				if err != nil {
+					return errors.Wrap(err, "checking for differences")
+				}
+				if same {
+					comm.Statf("No changes and --if-changed used, not pushing anything")
+					return nil
+				}
+			} else if err = pwr.AssertValid(buildPath, sig); err == nil {
 				comm.Statf("No changes and --if-changed used, not pushing anything")
 				return nil
-			}
-
-			if _, ok := err.(*pwr.ErrHasWound); ok {
+			} else if _, ok := err.(*pwr.ErrHasWound); ok {
 				// cool, that's what we expected
 			} else {
 				return errors.Wrap(err, "checking for differences")
@@ -222,7 +219,7 @@ func Do(ctx *mansion.Context, buildPath string, specStr string, userVersion stri
 	} else {
 		comm.Opf("For channel `%s`: last build is %d, downloading its signature", spec.Channel, parentID)
 		var err error
-		targetSignature, err = getSignature(parentID)
+		targetSignature, err = FetchBuildSignature(ctx, client, parentID)
 		if err != nil {
 			return errors.Wrap(err, "searching for parent build signature")
 		}
@@ -256,16 +253,15 @@ func Do(ctx *mansion.Context, buildPath string, specStr string, userVersion stri
 	var sourcePool lake.Pool
 
 	comm.Debugf("Waiting for source container")
-	select {
-	case walkErr := <-walkErrs:
-		return errors.Wrap(walkErr, "walking directory to push")
-	case walkies := <-sourceContainerChan:
-		sourceContainer = walkies.container
-		sourcePool = walkies.pool
-		break
+	walkies, err := waitWalk()
+	if err != nil {
+		return err
 	}
+	sourceContainer = walkies.container
+	sourcePool = walkies.pool
 
 	showSingleFileWarningIfNecessary(sourceContainer)
+	showUnsafePathsWarningIfNecessary(sourceContainer)
 
 	err = sourceContainer.Validate()
 	if err != nil {
@@ -474,3 +470,66 @@ func showSingleFileWarningIfNecessary(sourceContainer *tlc.Container) {
 		"For more information, see https://itch.io/docs/butler/single-files.html",
 	})
 }
+
+// a typical install folder for the itch app on Windows, so we can
+// warn about paths that will end up too long once installed.
+const typicalWindowsInstallFolder = `C:\Users\Username\AppData\Roaming\itch\apps\game-title`
+
+func showUnsafePathsWarningIfNecessary(sourceContainer *tlc.Container) {
+	report := pathsafety.Scan(ox.PlatformWindows, typicalWindowsInstallFolder, pathsafety.ContainerPaths(sourceContainer))
+	if len(report.Offenders) == 0 {
+		return
+	}
+
+	const maxListed = 20
+	lines := []string{
+		fmt.Sprintf("%d paths in this build will cause trouble on Windows. Players on Windows may not be able to install it.", len(report.Offenders)),
+		"",
+	}
+	for i, o := range report.Offenders {
+		if i >= maxListed {
+			lines = append(lines, fmt.Sprintf("...and %d more", len(report.Offenders)-maxListed))
+			break
+		}
+		lines = append(lines, o.String())
+	}
+	comm.Notice("Some paths are unsafe on Windows", lines)
+}
+
+// FetchBuildSignature downloads and parses the signature of a build
+func FetchBuildSignature(ctx *mansion.Context, client *itchio.Client, buildID int64) (*pwr.SignatureInfo, error) {
+	buildFiles, err := client.ListBuildFiles(ctx.DefaultCtx(), buildID)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing build files")
+	}
+
+	signatureFile := itchio.FindBuildFile(itchio.BuildFileTypeSignature, buildFiles.Files)
+	if signatureFile == nil {
+		return nil, errors.Errorf("could not find signature for build %d", buildID)
+	}
+
+	signatureURL := client.MakeBuildFileDownloadURL(itchio.MakeBuildFileDownloadURLParams{
+		BuildID: buildID,
+		FileID:  signatureFile.ID,
+	})
+
+	signatureReader, err := eos.Open(signatureURL, option.WithConsumer(comm.NewStateConsumer()))
+	if err != nil {
+		return nil, errors.Wrap(err, "opening signature")
+	}
+	defer signatureReader.Close()
+
+	signatureSource := seeksource.FromFile(signatureReader)
+
+	_, err = signatureSource.Resume(nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening signature")
+	}
+
+	signature, err := pwr.ReadSignature(context.Background(), signatureSource)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading signature")
+	}
+
+	return signature, nil
+}
@@ -0,0 +1,586 @@
+package push
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/itchio/headway/state"
+	"github.com/itchio/lake"
+	"github.com/itchio/lake/tlc"
+	"github.com/itchio/wharf/pwr"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+// stdinSource can be pushed instead of a path, to read a tar
+// stream from standard input
+const stdinSource = "-"
+
+var tarSourceSuffixes = []string{".tar", ".tar.zst", ".tzst"}
+
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// isTarSource returns true if buildPath should be read as a tar
+// archive, possibly compressed with zstd, rather than walked.
+func isTarSource(buildPath string) bool {
+	if buildPath == stdinSource {
+		return true
+	}
+
+	lower := strings.ToLower(buildPath)
+	for _, suffix := range tarSourceSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// tarArchive can read a tar archive from its start as many times
+// as needed. Since standard input can only be read once, tar streams
+// from there are spilled to a temporary file first, as they come.
+type tarArchive struct {
+	path       string
+	compressed bool
+	spilled    bool
+}
+
+func openTarArchive(buildPath string) (*tarArchive, error) {
+	ta := &tarArchive{path: buildPath}
+
+	if buildPath == stdinSource {
+		f, err := ioutil.TempFile("", "butler-push-*.tar")
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		ta.path = f.Name()
+		ta.spilled = true
+
+		_, err = io.Copy(f, os.Stdin)
+		if err == nil {
+			err = f.Close()
+		} else {
+			f.Close()
+		}
+		if err != nil {
+			ta.release()
+			return nil, errors.Wrap(err, "reading tar stream from stdin")
+		}
+	}
+
+	f, err := os.Open(ta.path)
+	if err != nil {
+		ta.release()
+		return nil, errors.WithStack(err)
+	}
+	defer f.Close()
+
+	magic := make([]byte, len(zstdMagic))
+	n, _ := io.ReadFull(f, magic)
+	ta.compressed = bytes.Equal(magic[:n], zstdMagic)
+	return ta, nil
+}
+
+// open returns the uncompressed contents of the archive, from the start
+func (ta *tarArchive) open() (io.ReadCloser, error) {
+	f, err := os.Open(ta.path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !ta.compressed {
+		return f, nil
+	}
+
+	dec, err := zstd.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, errors.Wrap(err, "opening zstd stream")
+	}
+	return &zstdReadCloser{dec: dec, file: f}, nil
+}
+
+func (ta *tarArchive) release() {
+	if ta.spilled {
+		os.Remove(ta.path)
+	}
+}
+
+type zstdReadCloser struct {
+	dec  *zstd.Decoder
+	file *os.File
+}
+
+func (zrc *zstdReadCloser) Read(p []byte) (int, error) {
+	return zrc.dec.Read(p)
+}
+
+func (zrc *zstdReadCloser) Close() error {
+	zrc.dec.Close()
+	return zrc.file.Close()
+}
+
+type countingReader struct {
+	r     io.Reader
+	count int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.count += int64(n)
+	return n, err
+}
+
+// tarFileRef locates the contents of a container file in a tar archive
+type tarFileRef struct {
+	// Index of the entry, counting all entries of the archive
+	entry int
+	// Where the contents start in the uncompressed archive,
+	// or -1 if they can't be read from there as-is
+	offset int64
+}
+
+type tarFile struct {
+	mode uint32
+	size int64
+	ref  tarFileRef
+}
+
+// walkTarSource returns the container of the tar archive at buildPath,
+// and a pool to read its files from. The pool must be released.
+func walkTarSource(buildPath string, opts tlc.WalkOpts) (*tlc.Container, *tarPool, error) {
+	ta, err := openTarArchive(buildPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	container, refs, err := walkTar(ta, opts)
+	if err != nil {
+		ta.release()
+		return nil, nil, err
+	}
+
+	pool, err := newTarPool(ta, container, refs)
+	if err != nil {
+		ta.release()
+		return nil, nil, err
+	}
+	return container, pool, nil
+}
+
+// walkTar reads the headers of all entries of a tar archive, and returns
+// the container a directory with the same contents would walk to: same
+// ordering, same modes, same filters. The refs say where the contents of
+// each file of the container are in the archive.
+func walkTar(ta *tarArchive, opts tlc.WalkOpts) (*tlc.Container, []tarFileRef, error) {
+	if opts.Dereference {
+		return nil, nil, errors.New("Dereference is not supported when pushing a tar archive")
+	}
+	filter := opts.GetFilter()
+
+	r, err := ta.open()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer r.Close()
+
+	cr := &countingReader{r: r}
+	tr := tar.NewReader(cr)
+
+	dirs := make(map[string]uint32)
+	symlinks := make(map[string]*tlc.Symlink)
+	files := make(map[string]*tarFile)
+
+	entry := -1
+eachEntry:
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "reading tar archive")
+		}
+		entry++
+
+		name := cleanTarName(hdr.Name)
+		if name == "" {
+			continue
+		}
+		for _, token := range strings.Split(name, "/") {
+			if filter(token) == tlc.FilterIgnore {
+				continue eachEntry
+			}
+		}
+
+		// like zip files, tar files don't always have entries
+		// for all directories
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if _, ok := dirs[dir]; !ok {
+				dirs[dir] = uint32(os.ModeDir | 0o755 | tlc.ModeMask)
+			}
+		}
+
+		// later entries replace earlier ones, as they would when extracting
+		delete(dirs, name)
+		delete(symlinks, name)
+		delete(files, name)
+
+		// don't end up with files we (the patcher) can't modify
+		mode := uint32(hdr.FileInfo().Mode() | tlc.ModeMask)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			dirs[name] = mode
+		case tar.TypeSymlink:
+			symlinks[name] = &tlc.Symlink{
+				Path: name,
+				Mode: mode,
+				Dest: hdr.Linkname,
+			}
+		case tar.TypeReg, tar.TypeRegA, tar.TypeGNUSparse:
+			offset := cr.count
+			if isSparse(hdr) {
+				// contents are expanded by the tar reader
+				offset = -1
+			}
+			files[name] = &tarFile{
+				mode: mode,
+				size: hdr.Size,
+				ref:  tarFileRef{entry: entry, offset: offset},
+			}
+		case tar.TypeLink:
+			target := files[cleanTarName(hdr.Linkname)]
+			if target == nil {
+				return nil, nil, errors.Errorf("in tar archive, (%s) is a hard link to unknown file (%s)", name, hdr.Linkname)
+			}
+			files[name] = &tarFile{
+				mode: mode,
+				size: target.size,
+				ref:  target.ref,
+			}
+		default:
+			// devices, fifos etc. aren't walked in directories either
+		}
+	}
+
+	container := &tlc.Container{}
+	for dirPath, dirMode := range dirs {
+		container.Dirs = append(container.Dirs, &tlc.Dir{
+			Path: dirPath,
+			Mode: dirMode,
+		})
+	}
+	sort.Slice(container.Dirs, func(i, j int) bool {
+		return walkOrderLess(container.Dirs[i].Path, container.Dirs[j].Path)
+	})
+
+	for _, s := range symlinks {
+		container.Symlinks = append(container.Symlinks, s)
+	}
+	sort.Slice(container.Symlinks, func(i, j int) bool {
+		return walkOrderLess(container.Symlinks[i].Path, container.Symlinks[j].Path)
+	})
+
+	var filePaths []string
+	for filePath := range files {
+		filePaths = append(filePaths, filePath)
+	}
+	sort.Slice(filePaths, func(i, j int) bool {
+		return walkOrderLess(filePaths[i], filePaths[j])
+	})
+
+	var refs []tarFileRef
+	for _, filePath := range filePaths {
+		f := files[filePath]
+		container.Files = append(container.Files, &tlc.File{
+			Path:   filePath,
+			Mode:   f.mode,
+			Size:   f.size,
+			Offset: container.Size,
+		})
+		container.Size += f.size
+		refs = append(refs, f.ref)
+	}
+
+	return container, refs, nil
+}
+
+// cleanTarName returns the slash-separated path of a tar entry,
+// relative to the root of the archive, or "" for the root itself.
+func cleanTarName(name string) string {
+	name = path.Clean(strings.TrimLeft(filepath.ToSlash(name), "/"))
+	if name == "." {
+		return ""
+	}
+	return name
+}
+
+func isSparse(hdr *tar.Header) bool {
+	if hdr.Typeflag == tar.TypeGNUSparse {
+		return true
+	}
+	for key := range hdr.PAXRecords {
+		if strings.HasPrefix(key, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
+}
+
+// walkOrderLess sorts paths the way tlc.WalkDir encounters them:
+// directories before their contents, siblings sorted by name.
+func walkOrderLess(a, b string) bool {
+	as := strings.Split(a, "/")
+	bs := strings.Split(b, "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] != bs[i] {
+			return as[i] < bs[i]
+		}
+	}
+	return len(as) < len(bs)
+}
+
+// tarPool reads the files of a container from a tar archive.
+//
+// Plain archives are read in place. Compressed archives are streamed
+// from the start, and entries that get skipped over while they're
+// still needed are spilled to a temporary folder, so that diffing
+// in container order doesn't decompress the archive over and over.
+type tarPool struct {
+	archive   *tarArchive
+	container *tlc.Container
+	refs      []tarFileRef
+
+	// set if the contents of all files can be read in place
+	file *os.File
+
+	stream    io.ReadCloser
+	tr        *tar.Reader
+	nextEntry int
+
+	// how many files of the container each entry holds the contents of
+	needed   map[int]int
+	spillDir string
+	spilled  map[int]string
+
+	current io.Closer
+}
+
+var _ lake.Pool = (*tarPool)(nil)
+
+func newTarPool(ta *tarArchive, container *tlc.Container, refs []tarFileRef) (*tarPool, error) {
+	tp := &tarPool{
+		archive:   ta,
+		container: container,
+		refs:      refs,
+		needed:    make(map[int]int),
+		spilled:   make(map[int]string),
+	}
+
+	inPlace := !ta.compressed
+	for _, ref := range refs {
+		tp.needed[ref.entry]++
+		if ref.offset < 0 {
+			inPlace = false
+		}
+	}
+
+	if inPlace {
+		f, err := os.Open(ta.path)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		tp.file = f
+	}
+	return tp, nil
+}
+
+func (tp *tarPool) GetSize(fileIndex int64) int64 {
+	return tp.container.Files[fileIndex].Size
+}
+
+func (tp *tarPool) GetReader(fileIndex int64) (io.Reader, error) {
+	err := tp.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	ref := tp.refs[fileIndex]
+	if tp.file != nil {
+		return io.NewSectionReader(tp.file, ref.offset, tp.GetSize(fileIndex)), nil
+	}
+
+	if spillPath, ok := tp.spilled[ref.entry]; ok {
+		return tp.openSpilled(spillPath)
+	}
+
+	if tp.needed[ref.entry] > 1 {
+		// hard links share their contents, which will be read again
+		return tp.GetReadSeeker(fileIndex)
+	}
+
+	err = tp.seek(ref.entry)
+	if err != nil {
+		return nil, err
+	}
+	return tp.tr, nil
+}
+
+func (tp *tarPool) GetReadSeeker(fileIndex int64) (io.ReadSeeker, error) {
+	err := tp.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	ref := tp.refs[fileIndex]
+	if tp.file != nil {
+		return io.NewSectionReader(tp.file, ref.offset, tp.GetSize(fileIndex)), nil
+	}
+
+	spillPath, ok := tp.spilled[ref.entry]
+	if !ok {
+		err = tp.seek(ref.entry)
+		if err != nil {
+			return nil, err
+		}
+		spillPath, err = tp.spill(ref.entry)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tp.openSpilled(spillPath)
+}
+
+// seek positions the stream at the start of the contents of an entry,
+// spilling the needed entries it skips over.
+func (tp *tarPool) seek(entry int) error {
+	if tp.stream == nil || entry < tp.nextEntry {
+		if tp.stream != nil {
+			tp.stream.Close()
+		}
+		stream, err := tp.archive.open()
+		if err != nil {
+			return err
+		}
+		tp.stream = stream
+		tp.tr = tar.NewReader(stream)
+		tp.nextEntry = 0
+	}
+
+	for {
+		_, err := tp.tr.Next()
+		if err != nil {
+			return errors.Wrap(err, "reading tar archive")
+		}
+		current := tp.nextEntry
+		tp.nextEntry++
+
+		if current == entry {
+			return nil
+		}
+		if _, ok := tp.spilled[current]; !ok && tp.needed[current] > 0 {
+			_, err := tp.spill(current)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// spill copies the contents of the entry the stream is at
+// to a temporary file
+func (tp *tarPool) spill(entry int) (string, error) {
+	if tp.spillDir == "" {
+		dir, err := ioutil.TempDir("", "butler-push-spill")
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		tp.spillDir = dir
+	}
+
+	spillPath := filepath.Join(tp.spillDir, strconv.Itoa(entry))
+	f, err := os.Create(spillPath)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	_, err = io.Copy(f, tp.tr)
+	if err != nil {
+		f.Close()
+		return "", errors.Wrap(err, "spilling tar entry")
+	}
+	err = f.Close()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	tp.spilled[entry] = spillPath
+	return spillPath, nil
+}
+
+func (tp *tarPool) openSpilled(spillPath string) (io.ReadSeeker, error) {
+	f, err := os.Open(spillPath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	tp.current = f
+	return f, nil
+}
+
+func (tp *tarPool) Close() error {
+	if tp.current != nil {
+		err := tp.current.Close()
+		tp.current = nil
+		return err
+	}
+	return nil
+}
+
+// Release closes the archive, and removes everything that was spilled
+func (tp *tarPool) Release() {
+	tp.Close()
+	if tp.file != nil {
+		tp.file.Close()
+	}
+	if tp.stream != nil {
+		tp.stream.Close()
+	}
+	if tp.spillDir != "" {
+		os.RemoveAll(tp.spillDir)
+	}
+	tp.archive.release()
+}
+
+// matchesSignature returns true if the files of a tar source are
+// exactly those sig was computed from.
+func matchesSignature(container *tlc.Container, pool lake.Pool, sig *pwr.SignatureInfo) (bool, error) {
+	if sig.Container.EnsureEqual(container) != nil {
+		return false, nil
+	}
+
+	hashes, err := pwr.ComputeSignature(context.Background(), container, pool, &state.Consumer{})
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	if len(hashes) != len(sig.Hashes) {
+		return false, nil
+	}
+	for i, h := range hashes {
+		sh := sig.Hashes[i]
+		if h.FileIndex != sh.FileIndex || h.BlockIndex != sh.BlockIndex ||
+			h.WeakHash != sh.WeakHash || h.ShortSize != sh.ShortSize ||
+			!bytes.Equal(h.StrongHash, sh.StrongHash) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
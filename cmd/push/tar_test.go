@@ -0,0 +1,247 @@
+package push
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/filtering"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/lake"
+	"github.com/itchio/lake/pools"
+	"github.com/itchio/lake/tlc"
+	"github.com/itchio/savior/seeksource"
+	"github.com/itchio/wharf/pwr"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+
+	_ "github.com/itchio/wharf/compressors/cbrotli"
+	_ "github.com/itchio/wharf/decompressors/cbrotli"
+)
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
+
+func Test_IsTarSource(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(isTarSource("-"))
+	assert.True(isTarSource("build.tar"))
+	assert.True(isTarSource("build.TAR.zst"))
+	assert.True(isTarSource("build.tzst"))
+	assert.False(isTarSource("build.zip"))
+	assert.False(isTarSource("build"))
+}
+
+func Test_WalkOrderLess(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(walkOrderLess("a", "a.txt"))
+	assert.True(walkOrderLess("a/z", "a.txt"), "directories are walked before their next sibling")
+	assert.True(walkOrderLess("a", "a/b"))
+	assert.False(walkOrderLess("b", "a/b"))
+	assert.False(walkOrderLess("a", "a"))
+}
+
+// pushed is what pushing a build writes: its patch
+// against an empty build, and its signature.
+type pushed struct {
+	patch     []byte
+	signature []byte
+}
+
+func pushFrom(t *testing.T, container *tlc.Container, pool lake.Pool) pushed {
+	must(t, container.FixPermissions(pool))
+
+	var patch, signature bytes.Buffer
+	dctx := &pwr.DiffContext{
+		Compression: &pwr.CompressionSettings{
+			Algorithm: pwr.CompressionAlgorithm_BROTLI,
+			Quality:   1,
+		},
+		SourceContainer: container,
+		Pool:            pool,
+		TargetContainer: &tlc.Container{},
+		Consumer:        &state.Consumer{},
+	}
+	must(t, dctx.WritePatch(context.Background(), &patch, &signature))
+	return pushed{patch.Bytes(), signature.Bytes()}
+}
+
+func makeFixture(t *testing.T, dir string) {
+	write := func(name string, contents []byte, mode os.FileMode) {
+		fullPath := filepath.Join(dir, filepath.FromSlash(name))
+		must(t, os.MkdirAll(filepath.Dir(fullPath), 0o755))
+		must(t, ioutil.WriteFile(fullPath, contents, mode))
+		must(t, os.Chmod(fullPath, mode))
+	}
+
+	big := make([]byte, 300*1024)
+	rand.New(rand.NewSource(0xfaced)).Read(big)
+
+	write("a.txt", []byte("sorted after a/"), 0o644)
+	write("a/z.txt", []byte("sorted before a.txt"), 0o644)
+	write("a/b/deep.txt", []byte("deep"), 0o600)
+	write("data.bin", big, 0o644)
+	write("empty.txt", nil, 0o644)
+	write("launch.sh", []byte("#!/bin/sh\necho hi\n"), 0o755)
+	write("game", []byte("\x7fELF not really, but enough to be marked executable"), 0o644)
+	write(".git/config", []byte("ignored"), 0o644)
+	write("._resource_fork", []byte("ignored"), 0o644)
+	must(t, os.MkdirAll(filepath.Join(dir, "saves"), 0o755))
+	must(t, os.Symlink("data.bin", filepath.Join(dir, "data-link.bin")))
+	must(t, os.Link(filepath.Join(dir, "data.bin"), filepath.Join(dir, "copy.bin")))
+}
+
+// writeTar archives dir the way tar(1) might: in no particular
+// order, with a "./" prefix, hard links as such, and no entry
+// for some directories.
+func writeTar(t *testing.T, dir string, w io.Writer) {
+	tw := tar.NewWriter(w)
+
+	var paths []string
+	must(t, filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		paths = append(paths, p)
+		return err
+	}))
+	rand.New(rand.NewSource(4)).Shuffle(len(paths), func(i, j int) {
+		paths[i], paths[j] = paths[j], paths[i]
+	})
+
+	for _, p := range paths {
+		rel, err := filepath.Rel(dir, p)
+		must(t, err)
+		info, err := os.Lstat(p)
+		must(t, err)
+
+		if rel == filepath.Join("a", "b") || rel == "copy.bin" {
+			continue
+		}
+
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err = os.Readlink(p)
+			must(t, err)
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		must(t, err)
+		hdr.Name = "./" + filepath.ToSlash(rel)
+
+		must(t, tw.WriteHeader(hdr))
+		if hdr.Typeflag == tar.TypeReg {
+			f, err := os.Open(p)
+			must(t, err)
+			_, err = io.Copy(tw, f)
+			f.Close()
+			must(t, err)
+		}
+	}
+
+	must(t, tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeLink,
+		Name:     "./copy.bin",
+		Linkname: "./data.bin",
+		Mode:     0o644,
+	}))
+	must(t, tw.Close())
+}
+
+func Test_TarSourceMatchesDirectory(t *testing.T) {
+	assert := assert.New(t)
+
+	tmp, err := ioutil.TempDir("", "push-tar")
+	must(t, err)
+	defer os.RemoveAll(tmp)
+
+	dir := filepath.Join(tmp, "build")
+	makeFixture(t, dir)
+
+	walkOpts := tlc.WalkOpts{Filter: filtering.FilterPaths}
+	dirContainer, err := tlc.WalkDir(dir, walkOpts)
+	must(t, err)
+	dirPool, err := pools.New(dirContainer, dir)
+	must(t, err)
+	fromDir := pushFrom(t, dirContainer, dirPool)
+
+	var plain bytes.Buffer
+	writeTar(t, dir, &plain)
+	tarPath := filepath.Join(tmp, "build.tar")
+	must(t, ioutil.WriteFile(tarPath, plain.Bytes(), 0o644))
+
+	var compressed bytes.Buffer
+	enc, err := zstd.NewWriter(&compressed)
+	must(t, err)
+	_, err = enc.Write(plain.Bytes())
+	must(t, err)
+	must(t, enc.Close())
+	zstPath := filepath.Join(tmp, "build.tar.zst")
+	must(t, ioutil.WriteFile(zstPath, compressed.Bytes(), 0o644))
+
+	pushTar := func(buildPath string) (pushed, *tarPool) {
+		container, pool, err := walkTarSource(buildPath, walkOpts)
+		must(t, err)
+		assert.EqualValues(dirContainer.Size, container.Size)
+		assert.NoError(dirContainer.EnsureEqual(container))
+		return pushFrom(t, container, pool), pool
+	}
+
+	fromTar, pool := pushTar(tarPath)
+	assert.NotNil(pool.file, "plain archives are read in place")
+	assert.Empty(pool.spillDir)
+	pool.Release()
+	assert.True(fromDir.patch != nil && bytes.Equal(fromDir.patch, fromTar.patch), "same patch as the directory")
+	assert.True(bytes.Equal(fromDir.signature, fromTar.signature), "same signature as the directory")
+
+	fromZst, pool := pushTar(zstPath)
+	assert.Nil(pool.file)
+	assert.NotEmpty(pool.spilled, "shuffled entries get spilled")
+	spillDir := pool.spillDir
+	pool.Release()
+	assert.NoDirExists(spillDir)
+	assert.True(bytes.Equal(fromDir.patch, fromZst.patch))
+	assert.True(bytes.Equal(fromDir.signature, fromZst.signature))
+
+	stdin, err := os.Open(zstPath)
+	must(t, err)
+	defer func(f *os.File) { os.Stdin = f }(os.Stdin)
+	os.Stdin = stdin
+	fromStdin, pool := pushTar(stdinSource)
+	stdin.Close()
+	spilledStream := pool.archive.path
+	assert.FileExists(spilledStream)
+	pool.Release()
+	assert.NoFileExists(spilledStream)
+	assert.True(bytes.Equal(fromDir.patch, fromStdin.patch))
+	assert.True(bytes.Equal(fromDir.signature, fromStdin.signature))
+
+	sigSource := seeksource.FromBytes(fromDir.signature)
+	_, err = sigSource.Resume(nil)
+	must(t, err)
+	sig, err := pwr.ReadSignature(context.Background(), sigSource)
+	must(t, err)
+	container, pool, err := walkTarSource(zstPath, walkOpts)
+	must(t, err)
+	defer pool.Release()
+	same, err := matchesSignature(container, pool, sig)
+	must(t, err)
+	assert.True(same, "--if-changed sees no changes")
+
+	var modified bytes.Buffer
+	must(t, ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("changed"), 0o644))
+	writeTar(t, dir, &modified)
+	must(t, ioutil.WriteFile(tarPath, modified.Bytes(), 0o644))
+	container, otherPool, err := walkTarSource(tarPath, walkOpts)
+	must(t, err)
+	defer otherPool.Release()
+	same, err = matchesSignature(container, otherPool, sig)
+	must(t, err)
+	assert.False(same)
+}
@@ -11,29 +11,44 @@ import (
 type walkResult struct {
 	container *tlc.Container
 	pool      lake.Pool
+	// cleans up after the pool, once it's no longer needed
+	release func()
 }
 
 func doWalk(path string, out chan walkResult, errs chan error, fixPerms bool, walkOpts tlc.WalkOpts) {
-	container, err := tlc.WalkAny(path, walkOpts)
-	if err != nil {
-		errs <- errors.WithStack(err)
-		return
+	result := walkResult{
+		release: func() {},
 	}
 
-	pool, err := pools.New(container, path)
-	if err != nil {
-		errs <- errors.WithStack(err)
-		return
-	}
+	if isTarSource(path) {
+		container, pool, err := walkTarSource(path, walkOpts)
+		if err != nil {
+			errs <- errors.WithStack(err)
+			return
+		}
+		result.container = container
+		result.pool = pool
+		result.release = pool.Release
+	} else {
+		container, err := tlc.WalkAny(path, walkOpts)
+		if err != nil {
+			errs <- errors.WithStack(err)
+			return
+		}
 
-	result := walkResult{
-		container: container,
-		pool:      pool,
+		pool, err := pools.New(container, path)
+		if err != nil {
+			errs <- errors.WithStack(err)
+			return
+		}
+		result.container = container
+		result.pool = pool
 	}
 
 	if fixPerms {
 		err := result.container.FixPermissions(result.pool)
 		if err != nil {
+			result.release()
 			errs <- errors.WithStack(err)
 			return
 		}
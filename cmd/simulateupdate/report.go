@@ -0,0 +1,371 @@
+package simulateupdate
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/itchio/butler/comm"
+
+	"github.com/itchio/headway/united"
+
+	"github.com/itchio/lake/tlc"
+
+	"github.com/itchio/savior"
+
+	"github.com/itchio/wharf/pwr"
+	"github.com/itchio/wharf/wire"
+
+	"github.com/pkg/errors"
+)
+
+// FileChangeKind says what happened to a file between both builds
+type FileChangeKind string
+
+const (
+	FileAdded     FileChangeKind = "added"
+	FileRemoved   FileChangeKind = "removed"
+	FileChanged   FileChangeKind = "changed"
+	FileUnchanged FileChangeKind = "unchanged"
+)
+
+// HintKind identifies a pathological case
+type HintKind string
+
+const (
+	// HintRepackedArchive is a compressed archive that is almost entirely
+	// new data, although it was in the old build too
+	HintRepackedArchive HintKind = "repacked-archive"
+	// HintMostlyFresh is an update that costs nearly as much as a fresh install
+	HintMostlyFresh HintKind = "mostly-fresh"
+	// HintNoChanges is an update that changes nothing
+	HintNoChanges HintKind = "no-changes"
+)
+
+const (
+	// maxLargestChanges is how many files the report lists individually
+	maxLargestChanges = 10
+	// repackMinSize is the smallest archive worth a repack hint
+	repackMinSize = 1024 * 1024
+	// repackFreshRatio is how much of an archive must be new data to
+	// look repacked rather than edited
+	repackFreshRatio = 0.9
+	// mostlyFreshRatio is how much of the new build must be new data
+	// for the update to be about as costly as a fresh install
+	mostlyFreshRatio = 0.8
+)
+
+// compressedArchiveExtensions change entirely when a single thing
+// inside them changes, so the diff can't re-use any of them.
+var compressedArchiveExtensions = map[string]bool{
+	".zip":     true,
+	".7z":      true,
+	".rar":     true,
+	".gz":      true,
+	".tgz":     true,
+	".bz2":     true,
+	".xz":      true,
+	".jar":     true,
+	".pak":     true,
+	".pck":     true,
+	".unity3d": true,
+	".cab":     true,
+}
+
+// HardwareProfile is a rough throughput model of a player's disk
+type HardwareProfile struct {
+	Name           string
+	ReadBPS        float64
+	WriteBPS       float64
+	PerFileLatency time.Duration
+}
+
+// HardwareProfiles are the reference disks apply times are estimated for
+var HardwareProfiles = []HardwareProfile{
+	{Name: "hdd", ReadBPS: 120 * 1024 * 1024, WriteBPS: 100 * 1024 * 1024, PerFileLatency: 8 * time.Millisecond},
+	{Name: "ssd", ReadBPS: 500 * 1024 * 1024, WriteBPS: 450 * 1024 * 1024, PerFileLatency: 300 * time.Microsecond},
+	{Name: "nvme", ReadBPS: 2500 * 1024 * 1024, WriteBPS: 2000 * 1024 * 1024, PerFileLatency: 50 * time.Microsecond},
+}
+
+// Report is what updating from one build to another would be like
+type Report struct {
+	OldSize     int64 `json:"oldSize"`
+	NewSize     int64 `json:"newSize"`
+	PatchSize   int64 `json:"patchSize"`
+	FreshBytes  int64 `json:"freshBytes"`
+	ReusedBytes int64 `json:"reusedBytes"`
+
+	Files          FileSummary     `json:"files"`
+	LargestChanges []*FileChange   `json:"largestChanges"`
+	ApplyEstimates []ApplyEstimate `json:"applyEstimates"`
+	Hints          []*Hint         `json:"hints"`
+}
+
+// FileSummary counts files by what happened to them
+type FileSummary struct {
+	Added     int `json:"added"`
+	Removed   int `json:"removed"`
+	Changed   int `json:"changed"`
+	Unchanged int `json:"unchanged"`
+}
+
+// FileChange is a file of the new build that players need to write
+type FileChange struct {
+	Path       string         `json:"path"`
+	Kind       FileChangeKind `json:"kind"`
+	Size       int64          `json:"size"`
+	FreshBytes int64          `json:"freshBytes"`
+}
+
+// ApplyEstimate is how long applying the patch would take on a
+// reference disk, not counting the download.
+type ApplyEstimate struct {
+	Profile string  `json:"profile"`
+	Seconds float64 `json:"seconds"`
+}
+
+// Hint flags an update that is much costlier than it needs to be
+type Hint struct {
+	Kind    HintKind `json:"kind"`
+	Path    string   `json:"path,omitempty"`
+	Message string   `json:"message"`
+}
+
+// fileStat is what the patch does for one file of the new build
+type fileStat struct {
+	path        string
+	kind        FileChangeKind
+	size        int64
+	freshBytes  int64
+	reusedBytes int64
+}
+
+// analyzePatch walks the ops of an rsync patch, like `butler probe` does,
+// to find out how much of each new file is fresh data.
+func analyzePatch(patchSource savior.Source, oldContainer *tlc.Container, newContainer *tlc.Container) ([]*fileStat, error) {
+	rctx := wire.NewReadContext(patchSource)
+	err := rctx.ExpectMagic(pwr.PatchMagic)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	header := &pwr.PatchHeader{}
+	err = rctx.ReadMessage(header)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	rctx, err = pwr.DecompressWire(rctx, header.Compression)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// the patch starts with both containers, we already have them
+	err = rctx.ReadMessage(&tlc.Container{})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	err = rctx.ReadMessage(&tlc.Container{})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	oldFiles := make(map[string]*tlc.File)
+	for _, f := range oldContainer.Files {
+		oldFiles[f.Path] = f
+	}
+
+	var stats []*fileStat
+	sh := &pwr.SyncHeader{}
+	rop := &pwr.SyncOp{}
+
+	for fileIndex, f := range newContainer.Files {
+		sh.Reset()
+		err = rctx.ReadMessage(sh)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if sh.FileIndex != int64(fileIndex) {
+			return nil, errors.Errorf("malformed patch: expected file %d, got %d", fileIndex, sh.FileIndex)
+		}
+		if sh.Type != pwr.SyncHeader_RSYNC {
+			return nil, errors.Errorf("unexpected %s series for %s", sh.Type, f.Path)
+		}
+
+		stat := &fileStat{
+			path: f.Path,
+			kind: FileAdded,
+			size: f.Size,
+		}
+		oldFile, existed := oldFiles[f.Path]
+		if existed {
+			stat.kind = FileChanged
+		}
+
+		// a file is unchanged if it's copied whole from the old file
+		// with the same path, which apply doesn't need to touch.
+		numOps := 0
+		wholeCopy := false
+		readingOps := true
+		for readingOps {
+			rop.Reset()
+			err = rctx.ReadMessage(rop)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+
+			switch rop.Type {
+			case pwr.SyncOp_BLOCK_RANGE:
+				numOps++
+				tf := oldContainer.Files[rop.FileIndex]
+				lastIndex := rop.BlockIndex + rop.BlockSpan - 1
+				stat.reusedBytes += (rop.BlockSpan-1)*pwr.BlockSize + pwr.ComputeBlockSize(tf.Size, lastIndex)
+				wholeCopy = existed && tf == oldFile && rop.BlockIndex == 0 &&
+					rop.BlockSpan == pwr.ComputeNumBlocks(tf.Size) && tf.Size == f.Size
+			case pwr.SyncOp_DATA:
+				numOps++
+				stat.freshBytes += int64(len(rop.Data))
+			case pwr.SyncOp_HEY_YOU_DID_IT:
+				readingOps = false
+			}
+		}
+
+		if existed && ((numOps == 1 && wholeCopy) || (numOps == 0 && oldFile.Size == 0 && f.Size == 0)) {
+			stat.kind = FileUnchanged
+		}
+		stats = append(stats, stat)
+	}
+
+	newPaths := make(map[string]bool)
+	for _, f := range newContainer.Files {
+		newPaths[f.Path] = true
+	}
+	for _, f := range oldContainer.Files {
+		if !newPaths[f.Path] {
+			stats = append(stats, &fileStat{
+				path: f.Path,
+				kind: FileRemoved,
+				size: f.Size,
+			})
+		}
+	}
+
+	return stats, nil
+}
+
+func makeReport(oldContainer *tlc.Container, newContainer *tlc.Container, patchSize int64, files []*fileStat) *Report {
+	report := &Report{
+		OldSize:   oldContainer.Size,
+		NewSize:   newContainer.Size,
+		PatchSize: patchSize,
+	}
+
+	var readBytes, writeBytes int64
+	var touchedFiles int64
+	var changes []*FileChange
+
+	for _, f := range files {
+		report.FreshBytes += f.freshBytes
+		report.ReusedBytes += f.reusedBytes
+
+		switch f.kind {
+		case FileAdded:
+			report.Files.Added++
+		case FileRemoved:
+			report.Files.Removed++
+		case FileChanged:
+			report.Files.Changed++
+		case FileUnchanged:
+			report.Files.Unchanged++
+		}
+
+		switch f.kind {
+		case FileAdded, FileChanged:
+			readBytes += f.reusedBytes
+			writeBytes += f.size
+			touchedFiles++
+			changes = append(changes, &FileChange{
+				Path:       f.path,
+				Kind:       f.kind,
+				Size:       f.size,
+				FreshBytes: f.freshBytes,
+			})
+		case FileRemoved:
+			touchedFiles++
+		}
+
+		if f.kind == FileChanged && f.size >= repackMinSize &&
+			compressedArchiveExtensions[strings.ToLower(filepath.Ext(f.path))] &&
+			float64(f.freshBytes) >= repackFreshRatio*float64(f.size) {
+			report.Hints = append(report.Hints, &Hint{
+				Kind: HintRepackedArchive,
+				Path: f.path,
+				Message: fmt.Sprintf("%s looks repacked: %s of its %s is new data. Compressed archives change entirely when anything inside them does, ship its contents unpacked or store it uncompressed to keep patches small.",
+					f.path, united.FormatBytes(f.freshBytes), united.FormatBytes(f.size)),
+			})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].FreshBytes > changes[j].FreshBytes
+	})
+	if len(changes) > maxLargestChanges {
+		changes = changes[:maxLargestChanges]
+	}
+	report.LargestChanges = changes
+
+	for _, hp := range HardwareProfiles {
+		seconds := float64(readBytes)/hp.ReadBPS +
+			float64(writeBytes)/hp.WriteBPS +
+			float64(touchedFiles)*hp.PerFileLatency.Seconds()
+		report.ApplyEstimates = append(report.ApplyEstimates, ApplyEstimate{
+			Profile: hp.Name,
+			Seconds: seconds,
+		})
+	}
+
+	if touchedFiles == 0 {
+		report.Hints = append(report.Hints, &Hint{
+			Kind:    HintNoChanges,
+			Message: "Both builds are identical, `butler push --if-changed` would skip this one.",
+		})
+	} else if oldContainer.Size > 0 && newContainer.Size > 0 &&
+		float64(report.FreshBytes) >= mostlyFreshRatio*float64(newContainer.Size) {
+		report.Hints = append(report.Hints, &Hint{
+			Kind: HintMostlyFresh,
+			Message: fmt.Sprintf("%s of the new build's %s is new data, players will download nearly as much as a fresh install.",
+				united.FormatBytes(report.FreshBytes), united.FormatBytes(newContainer.Size)),
+		})
+	}
+
+	return report
+}
+
+func printReport(report *Report) {
+	comm.Statf("%s patch: %s of fresh data, %s re-used from the old build",
+		united.FormatBytes(report.PatchSize), united.FormatBytes(report.FreshBytes), united.FormatBytes(report.ReusedBytes))
+	comm.Logf("%s -> %s", united.FormatBytes(report.OldSize), united.FormatBytes(report.NewSize))
+	comm.Logf("%d added, %d removed, %d changed, %d unchanged files",
+		report.Files.Added, report.Files.Removed, report.Files.Changed, report.Files.Unchanged)
+
+	if len(report.LargestChanges) > 0 {
+		comm.Logf("")
+		comm.Logf("Largest changes:")
+		for _, fc := range report.LargestChanges {
+			comm.Logf("  %-9s %s (%s fresh of %s)", fc.Kind, fc.Path, united.FormatBytes(fc.FreshBytes), united.FormatBytes(fc.Size))
+		}
+	}
+
+	comm.Logf("")
+	comm.Logf("Estimated apply time, not counting the download:")
+	for _, ae := range report.ApplyEstimates {
+		d := time.Duration(ae.Seconds * float64(time.Second))
+		comm.Logf("  %-4s %s", ae.Profile, d.Round(time.Millisecond))
+	}
+
+	for _, hint := range report.Hints {
+		comm.Warnf("%s", hint.Message)
+	}
+}
@@ -0,0 +1,264 @@
+package simulateupdate
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/alecthomas/units"
+	itchio "github.com/itchio/go-itchio"
+
+	"github.com/itchio/butler/cmd/push"
+	"github.com/itchio/butler/comm"
+	"github.com/itchio/butler/filtering"
+	"github.com/itchio/butler/mansion"
+
+	"github.com/itchio/headway/counter"
+	"github.com/itchio/headway/united"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/httpkit/eos/option"
+
+	"github.com/itchio/savior/seeksource"
+
+	"github.com/itchio/lake"
+	"github.com/itchio/lake/pools"
+	"github.com/itchio/lake/tlc"
+
+	"github.com/itchio/wharf/pwr"
+	"github.com/itchio/wharf/wire"
+	"github.com/itchio/wharf/wsync"
+
+	"github.com/pkg/errors"
+)
+
+var args = struct {
+	old          string
+	channel      string
+	new          string
+	maxPatchSize *units.Base2Bytes
+}{}
+
+type Params struct {
+	// Old is the build players have: a directory, an archive, or its signature
+	Old string
+	// OldSignature is used instead of Old when set
+	OldSignature *pwr.SignatureInfo
+	// New is the build about to be pushed: a directory or an archive
+	New         string
+	Compression pwr.CompressionSettings
+}
+
+func Register(ctx *mansion.Context) {
+	cmd := ctx.App.Command("simulate-update", "Estimate what players on an older build would download and apply when updating to a newer one")
+	cmd.Flag("old", "Directory or .zip archive with the build players have, or its signature file").StringVar(&args.old)
+	cmd.Flag("channel", "Channel players are on, as user/game:channel. Its latest build's signature is downloaded and used instead of --old").StringVar(&args.channel)
+	cmd.Flag("new", "Directory or .zip archive with the build about to be pushed").Required().StringVar(&args.new)
+	args.maxPatchSize = cmd.Flag("max-patch-size", "Fail if the patch is larger than this, for example 50MB").Bytes()
+	ctx.Register(cmd, do)
+}
+
+func do(ctx *mansion.Context) {
+	if (args.old == "") == (args.channel == "") {
+		comm.Dief("simulate-update: specify exactly one of --old or --channel")
+	}
+
+	params := Params{
+		Old:         args.old,
+		New:         args.new,
+		Compression: ctx.CompressionSettings(),
+	}
+	if args.channel != "" {
+		sig, err := channelSignature(ctx, args.channel)
+		ctx.Must(err)
+		params.OldSignature = sig
+	}
+
+	report, err := Do(params)
+	ctx.Must(err)
+
+	comm.ResultOrPrint(report, func() {
+		printReport(report)
+	})
+
+	maxPatchSize := int64(*args.maxPatchSize)
+	if maxPatchSize > 0 && report.PatchSize > maxPatchSize {
+		comm.Dief("%s patch is over the %s budget", united.FormatBytes(report.PatchSize), united.FormatBytes(maxPatchSize))
+	}
+}
+
+// channelSignature returns the signature of the latest build in a channel,
+// or an empty one if nothing was pushed to it yet.
+func channelSignature(ctx *mansion.Context, specStr string) (*pwr.SignatureInfo, error) {
+	spec, err := itchio.ParseSpec(specStr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing channel '%s'", specStr)
+	}
+
+	err = spec.EnsureChannel()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := ctx.AuthenticateViaOauth()
+	if err != nil {
+		return nil, errors.Wrap(err, "authenticating")
+	}
+
+	chanInfo, err := client.GetChannel(ctx.DefaultCtx(), spec.Target, spec.Channel)
+	if err != nil {
+		return nil, errors.Wrap(err, "looking up channel")
+	}
+	if chanInfo.Channel == nil || chanInfo.Channel.Head == nil {
+		comm.Opf("Nothing pushed to `%s` yet, simulating a fresh install", spec.Channel)
+		return &pwr.SignatureInfo{
+			Container: &tlc.Container{},
+			Hashes:    make([]wsync.BlockHash, 0),
+		}, nil
+	}
+
+	buildID := chanInfo.Channel.Head.ID
+	comm.Opf("For channel `%s`: last build is %d, downloading its signature", spec.Channel, buildID)
+	return push.FetchBuildSignature(ctx, client, buildID)
+}
+
+// Do diffs the new build against the old one, without writing the patch
+// anywhere but a temporary file, and reports what updating would be like.
+func Do(params Params) (*Report, error) {
+	if params.New == "" {
+		return nil, errors.New("simulate-update: must specify New")
+	}
+
+	oldSignature := params.OldSignature
+	if oldSignature == nil {
+		if params.Old == "" {
+			return nil, errors.New("simulate-update: must specify Old")
+		}
+
+		var err error
+		oldSignature, err = readOld(params.Old)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	newContainer, err := tlc.WalkAny(params.New, tlc.WalkOpts{Filter: filtering.FilterPaths})
+	if err != nil {
+		return nil, errors.Wrap(err, "walking new build")
+	}
+
+	var newPool lake.Pool
+	newPool, err = pools.New(newContainer, params.New)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening new build")
+	}
+	defer newPool.Close()
+
+	patchFile, err := ioutil.TempFile("", "butler-simulate-update-*.pwr")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		patchFile.Close()
+		os.Remove(patchFile.Name())
+	}()
+
+	patchCounter := counter.NewWriter(patchFile)
+
+	dctx := &pwr.DiffContext{
+		SourceContainer: newContainer,
+		Pool:            newPool,
+
+		TargetContainer: oldSignature.Container,
+		TargetSignature: oldSignature.Hashes,
+
+		Consumer:    comm.NewStateConsumer(),
+		Compression: &params.Compression,
+	}
+
+	startTime := time.Now()
+	comm.Opf("Diffing %s", params.New)
+	comm.StartProgress()
+	err = dctx.WritePatch(context.Background(), patchCounter, ioutil.Discard)
+	comm.EndProgress()
+	if err != nil {
+		return nil, errors.Wrap(err, "computing patch")
+	}
+	comm.Debugf("Diffed in %s", time.Since(startTime))
+
+	patchSource := seeksource.FromFile(patchFile)
+	_, err = patchSource.Resume(nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading patch")
+	}
+
+	files, err := analyzePatch(patchSource, oldSignature.Container, newContainer)
+	if err != nil {
+		return nil, errors.Wrap(err, "analyzing patch")
+	}
+
+	return makeReport(oldSignature.Container, newContainer, patchCounter.Count(), files), nil
+}
+
+// readOld reads path as a signature if it is one, and computes the
+// signature of the build it points to otherwise.
+func readOld(path string) (*pwr.SignatureInfo, error) {
+	sig, err := readSignature(path)
+	if err == nil {
+		comm.Opf("Read signature from %s", path)
+		return sig, nil
+	}
+	if errors.Cause(err) != wire.ErrFormat && errors.Cause(err) != io.EOF {
+		return nil, errors.Wrap(err, "determining if old build is signature or directory")
+	}
+
+	container, err := tlc.WalkAny(path, tlc.WalkOpts{Filter: filtering.FilterPaths})
+	if err != nil {
+		return nil, errors.Wrap(err, "walking old build")
+	}
+
+	pool, err := pools.New(container, path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening old build")
+	}
+	defer pool.Close()
+
+	comm.Opf("Hashing %s", path)
+	comm.StartProgress()
+	hashes, err := pwr.ComputeSignature(context.Background(), container, pool, comm.NewStateConsumer())
+	comm.EndProgress()
+	if err != nil {
+		return nil, errors.Wrap(err, "computing old build signature")
+	}
+
+	return &pwr.SignatureInfo{
+		Container: container,
+		Hashes:    hashes,
+	}, nil
+}
+
+func readSignature(path string) (*pwr.SignatureInfo, error) {
+	reader, err := eos.Open(path, option.WithConsumer(comm.NewStateConsumer()))
+	if err != nil {
+		return nil, errors.Wrap(err, "opening old build")
+	}
+	defer reader.Close()
+
+	stats, err := reader.Stat()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if stats.IsDir() {
+		return nil, wire.ErrFormat
+	}
+
+	source := seeksource.FromFile(reader)
+	_, err = source.Resume(nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening old build")
+	}
+
+	return pwr.ReadSignature(context.Background(), source)
+}
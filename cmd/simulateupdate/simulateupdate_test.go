@@ -0,0 +1,122 @@
+package simulateupdate_test
+
+import (
+	"archive/zip"
+	"encoding/hex"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/cmd/simulateupdate"
+	"github.com/itchio/wharf/pwr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, ioutil.WriteFile(path, data, 0o644))
+}
+
+func writeZip(t *testing.T, path string, method uint16, name string, data []byte) {
+	t.Helper()
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+	require.NoError(t, err)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+}
+
+func simulate(t *testing.T, oldDir string, newDir string) *simulateupdate.Report {
+	t.Helper()
+	report, err := simulateupdate.Do(simulateupdate.Params{
+		Old: oldDir,
+		New: newDir,
+		Compression: pwr.CompressionSettings{
+			Algorithm: pwr.CompressionAlgorithm_NONE,
+		},
+	})
+	require.NoError(t, err)
+	return report
+}
+
+func Test_SimulateUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "simulate-update")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	oldDir := filepath.Join(dir, "old")
+	newDir := filepath.Join(dir, "new")
+
+	rng := rand.New(rand.NewSource(0xf00d))
+	randomHex := func(n int) []byte {
+		raw := make([]byte, n/2)
+		rng.Read(raw)
+		return []byte(hex.EncodeToString(raw))
+	}
+
+	binary := randomHex(8 * 1024 * 1024)
+	writeFile(t, filepath.Join(oldDir, "game.bin"), binary)
+	writeFile(t, filepath.Join(newDir, "game.bin"), binary)
+	writeFile(t, filepath.Join(oldDir, "version.txt"), []byte("1.0"))
+	writeFile(t, filepath.Join(newDir, "version.txt"), []byte("1.1"))
+	writeFile(t, filepath.Join(oldDir, "old-notes.txt"), []byte("old"))
+	writeFile(t, filepath.Join(newDir, "new-notes.txt"), randomHex(1024))
+
+	// same contents, stored then deflated: nothing in common
+	assets := randomHex(4 * 1024 * 1024)
+	writeZip(t, filepath.Join(oldDir, "assets.zip"), zip.Store, "assets.dat", assets)
+	writeZip(t, filepath.Join(newDir, "assets.zip"), zip.Deflate, "assets.dat", assets)
+
+	t.Run("changes", func(t *testing.T) {
+		report := simulate(t, oldDir, newDir)
+
+		assert.EqualValues(t, simulateupdate.FileSummary{
+			Added:     1,
+			Removed:   1,
+			Changed:   2,
+			Unchanged: 1,
+		}, report.Files)
+		assert.True(t, report.PatchSize > 0)
+		assert.True(t, report.ReusedBytes >= int64(len(binary)))
+
+		require.NotEmpty(t, report.LargestChanges)
+		assert.EqualValues(t, "assets.zip", report.LargestChanges[0].Path)
+		assert.EqualValues(t, simulateupdate.FileChanged, report.LargestChanges[0].Kind)
+
+		require.Len(t, report.ApplyEstimates, len(simulateupdate.HardwareProfiles))
+		hdd, nvme := report.ApplyEstimates[0], report.ApplyEstimates[len(report.ApplyEstimates)-1]
+		assert.EqualValues(t, "hdd", hdd.Profile)
+		assert.True(t, hdd.Seconds > nvme.Seconds)
+
+		require.Len(t, report.Hints, 1)
+		assert.EqualValues(t, simulateupdate.HintRepackedArchive, report.Hints[0].Kind)
+		assert.EqualValues(t, "assets.zip", report.Hints[0].Path)
+	})
+
+	t.Run("no changes", func(t *testing.T) {
+		report := simulate(t, newDir, newDir)
+
+		assert.EqualValues(t, 4, report.Files.Unchanged)
+		assert.Empty(t, report.LargestChanges)
+		require.Len(t, report.Hints, 1)
+		assert.EqualValues(t, simulateupdate.HintNoChanges, report.Hints[0].Kind)
+	})
+
+	t.Run("fresh install", func(t *testing.T) {
+		emptyDir := filepath.Join(dir, "empty")
+		require.NoError(t, os.MkdirAll(emptyDir, 0o755))
+
+		report := simulate(t, emptyDir, newDir)
+		assert.EqualValues(t, 4, report.Files.Added)
+		assert.Empty(t, report.Hints)
+	})
+}
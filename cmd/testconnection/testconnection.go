@@ -0,0 +1,258 @@
+package testconnection
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/itchio/butler/comm"
+	"github.com/itchio/butler/mansion"
+	"github.com/itchio/headway/united"
+	"github.com/pkg/errors"
+)
+
+const (
+	dialTimeout      = 5 * time.Second
+	requestTimeout   = 10 * time.Second
+	speedTestTimeout = 10 * time.Second
+	// we stop reading the speed test file after that
+	speedTestSize = 4 * 1024 * 1024
+)
+
+var args = struct {
+	cdnURL string
+}{}
+
+func Register(ctx *mansion.Context) {
+	cmd := ctx.App.Command("test-connection", "Check that butler can reach and authenticate with itch.io, with the current network settings")
+	cmd.Flag("cdn-url", "File to download for the CDN speed test").Hidden().Default("https://broth.itch.ovh/butler/linux-amd64/LATEST/archive/default").StringVar(&args.cdnURL)
+	ctx.Register(cmd, do)
+}
+
+type CheckStatus string
+
+const (
+	CheckOK      CheckStatus = "ok"
+	CheckFailed  CheckStatus = "failed"
+	CheckSkipped CheckStatus = "skipped"
+)
+
+// Check is the outcome of one step of the connection test
+type Check struct {
+	Name   string      `json:"name"`
+	Status CheckStatus `json:"status"`
+	Detail string      `json:"detail,omitempty"`
+	Error  string      `json:"error,omitempty"`
+
+	err error
+}
+
+func (c *Check) String() string {
+	switch c.Status {
+	case CheckOK:
+		return fmt.Sprintf("OK (%s)", c.Detail)
+	case CheckSkipped:
+		return fmt.Sprintf("SKIPPED (%s)", c.Detail)
+	default:
+		return "FAILED"
+	}
+}
+
+func do(ctx *mansion.Context) {
+	checks := Do(ctx, args.cdnURL)
+
+	var firstFailed *Check
+	numFailed := 0
+	for _, c := range checks {
+		if c.Status == CheckFailed {
+			if firstFailed == nil {
+				firstFailed = c
+			}
+			numFailed++
+		}
+	}
+
+	comm.ResultOrPrint(checks, func() {
+		for _, c := range checks {
+			comm.Logf("%-5s %s", c.Name+":", c)
+		}
+		if firstFailed != nil {
+			comm.Logf("")
+			if ctx.Verbose {
+				comm.Logf("%s failed: %+v", firstFailed.Name, firstFailed.err)
+			} else {
+				comm.Logf("%s failed: %v", firstFailed.Name, firstFailed.err)
+			}
+		}
+	})
+
+	if numFailed > 0 {
+		comm.Dief("%d of %d checks failed", numFailed, len(checks))
+	}
+}
+
+// Do runs all checks in order. Checks that need an earlier one to pass
+// are skipped if it didn't.
+func Do(ctx *mansion.Context, cdnURL string) []*Check {
+	var checks []*Check
+	run := func(name string, f func() (string, error)) *Check {
+		c := &Check{Name: name}
+		detail, err := f()
+		if err != nil {
+			c.Status = CheckFailed
+			c.err = err
+			c.Error = err.Error()
+		} else {
+			c.Status = CheckOK
+			c.Detail = detail
+		}
+		checks = append(checks, c)
+		return c
+	}
+	skip := func(name string, reason string) *Check {
+		c := &Check{Name: name, Status: CheckSkipped, Detail: reason}
+		checks = append(checks, c)
+		return c
+	}
+	after := func(dep *Check, name string, f func() (string, error)) *Check {
+		if dep.Status != CheckOK {
+			return skip(name, dep.Name+" didn't pass")
+		}
+		return run(name, f)
+	}
+
+	apiURL, err := url.Parse(ctx.APIAddress())
+	if err != nil {
+		run("API", func() (string, error) {
+			return "", errors.Wrap(err, "parsing API address")
+		})
+		return checks
+	}
+
+	// with a proxy, that's who we need to resolve and reach
+	dialURL := apiURL
+	proxyURL, err := http.ProxyFromEnvironment(&http.Request{URL: apiURL})
+	if err == nil && proxyURL != nil {
+		comm.Opf("Using proxy %s", proxyURL.Host)
+		dialURL = proxyURL
+	}
+	dialHost := dialURL.Hostname()
+	dialPort := dialURL.Port()
+	if dialPort == "" {
+		dialPort = "443"
+		if dialURL.Scheme == "http" {
+			dialPort = "80"
+		}
+	}
+
+	dns := run("DNS", func() (string, error) {
+		before := time.Now()
+		_, err := net.DefaultResolver.LookupHost(ctx.DefaultCtx(), dialHost)
+		if err != nil {
+			return "", errors.Wrapf(err, "resolving %s", dialHost)
+		}
+		return formatDuration(time.Since(before)), nil
+	})
+
+	tcp := after(dns, "TCP", func() (string, error) {
+		dialer := &net.Dialer{Timeout: dialTimeout}
+		address := net.JoinHostPort(dialHost, dialPort)
+		before := time.Now()
+		conn, err := dialer.DialContext(ctx.DefaultCtx(), "tcp", address)
+		if err != nil {
+			return "", errors.Wrapf(err, "connecting to %s", address)
+		}
+		conn.Close()
+		return formatDuration(time.Since(before)), nil
+	})
+
+	// without TLS, authenticating only needs the connection
+	authDep := tcp
+	if apiURL.Scheme != "https" {
+		skip("TLS", "plain HTTP")
+	} else {
+		authDep = after(tcp, "TLS", func() (string, error) {
+			reqCtx, cancel := context.WithTimeout(ctx.DefaultCtx(), requestTimeout)
+			defer cancel()
+
+			req, err := http.NewRequestWithContext(reqCtx, "HEAD", apiURL.String(), nil)
+			if err != nil {
+				return "", errors.WithStack(err)
+			}
+			res, err := ctx.HTTPClient.Do(req)
+			if err != nil {
+				return "", errors.Wrapf(err, "requesting %s", apiURL)
+			}
+			res.Body.Close()
+
+			if res.TLS == nil || len(res.TLS.PeerCertificates) == 0 {
+				return "", errors.Errorf("%s didn't present a certificate", apiURL.Host)
+			}
+			cert := res.TLS.PeerCertificates[0]
+			name := cert.Subject.CommonName
+			if name == "" && len(cert.DNSNames) > 0 {
+				name = cert.DNSNames[0]
+			}
+			return "CN=" + name, nil
+		})
+	}
+
+	after(authDep, "Auth", func() (string, error) {
+		if !ctx.HasSavedCredentials() {
+			return "", errors.New("not logged in, run `butler login` first")
+		}
+		client, err := ctx.AuthenticateViaOauth()
+		if err != nil {
+			return "", errors.Wrap(err, "authenticating")
+		}
+		profile, err := client.GetProfile(ctx.DefaultCtx())
+		if err != nil {
+			return "", errors.Wrap(err, "fetching profile")
+		}
+		return "user: " + profile.User.Username, nil
+	})
+
+	run("CDN", func() (string, error) {
+		reqCtx, cancel := context.WithTimeout(ctx.DefaultCtx(), speedTestTimeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(reqCtx, "GET", cdnURL, nil)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+
+		before := time.Now()
+		res, err := ctx.HTTPClient.Do(req)
+		if err != nil {
+			return "", errors.Wrapf(err, "requesting %s", cdnURL)
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode/100 != 2 {
+			return "", errors.Errorf("requesting %s: HTTP %s", cdnURL, res.Status)
+		}
+
+		// running out of time is fine, we measure what we got
+		n, err := io.Copy(ioutil.Discard, io.LimitReader(res.Body, speedTestSize))
+		if err != nil && reqCtx.Err() == nil {
+			return "", errors.Wrap(err, "downloading speed test file")
+		}
+		elapsed := time.Since(before)
+		if elapsed <= 0 {
+			elapsed = time.Millisecond
+		}
+		bps := float64(n) / elapsed.Seconds()
+		return fmt.Sprintf("%s/s", united.FormatBytes(int64(bps))), nil
+	})
+
+	return checks
+}
+
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%dms", d.Milliseconds())
+}
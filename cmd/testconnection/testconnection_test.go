@@ -0,0 +1,77 @@
+package testconnection_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/cmd/testconnection"
+	"github.com/itchio/butler/mansion"
+	"github.com/stretchr/testify/assert"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+func newContext(t *testing.T, address string) *mansion.Context {
+	t.Helper()
+	ctx := mansion.NewContext(kingpin.New("butler", ""))
+	ctx.SetAddress(address)
+	ctx.ContextTimeout = 10
+	// nobody logged in
+	ctx.Identity = filepath.Join(os.TempDir(), "butler-test-connection", "butler_creds")
+	return ctx
+}
+
+func statuses(checks []*testconnection.Check) map[string]testconnection.CheckStatus {
+	res := make(map[string]testconnection.CheckStatus)
+	for _, c := range checks {
+		res[c.Name] = c.Status
+	}
+	return res
+}
+
+func Test_Failures(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	checks := testconnection.Do(newContext(t, server.URL), server.URL+"/cdn")
+	assert.EqualValues(map[string]testconnection.CheckStatus{
+		"DNS":  testconnection.CheckOK,
+		"TCP":  testconnection.CheckOK,
+		"TLS":  testconnection.CheckSkipped,
+		"Auth": testconnection.CheckFailed,
+		"CDN":  testconnection.CheckFailed,
+	}, statuses(checks))
+	for _, c := range checks {
+		if c.Status == testconnection.CheckFailed {
+			assert.NotEmpty(c.Error, "(%s) says why it failed", c.Name)
+		}
+	}
+}
+
+func Test_Unreachable(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	address := server.URL
+	server.Close()
+
+	checks := testconnection.Do(newContext(t, address), address+"/cdn")
+	assert.EqualValues(map[string]testconnection.CheckStatus{
+		"DNS":  testconnection.CheckOK,
+		"TCP":  testconnection.CheckFailed,
+		"TLS":  testconnection.CheckSkipped,
+		"Auth": testconnection.CheckSkipped,
+		"CDN":  testconnection.CheckFailed,
+	}, statuses(checks))
+	for _, c := range checks {
+		if c.Name == "Auth" {
+			assert.EqualValues("TCP didn't pass", c.Detail)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"runtime"
@@ -34,6 +35,15 @@ var settings = &struct {
 	false,
 }
 
+// where JSON messages go, see SetOutput
+var output io.Writer = os.Stdout
+
+// SetOutput sends JSON messages to w instead of stdout, one per
+// line, for when there's nobody reading stdout (services, say).
+func SetOutput(w io.Writer) {
+	output = w
+}
+
 // Configure sets all logging options in one go
 func Configure(noProgress, quiet, verbose, json, panic bool, assumeYes bool, adamLovesBeeps bool) {
 	settings.noProgress = noProgress
@@ -293,5 +303,5 @@ func showLogin(uri string) {
 // sends a JSON-encoded message to the client
 func sendJSON(obj JsonMessage) {
 	json, _ := json.Marshal(obj)
-	fmt.Println(string(json))
+	fmt.Fprintln(output, string(json))
 }
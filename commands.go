@@ -15,6 +15,7 @@ import (
 	"github.com/itchio/butler/cmd/elfprops"
 	"github.com/itchio/butler/cmd/exeprops"
 	"github.com/itchio/butler/cmd/extract"
+	"github.com/itchio/butler/cmd/fakeapi"
 	"github.com/itchio/butler/cmd/fetch"
 	"github.com/itchio/butler/cmd/file"
 	"github.com/itchio/butler/cmd/fujicmd"
@@ -34,9 +35,11 @@ import (
 	"github.com/itchio/butler/cmd/repack"
 	"github.com/itchio/butler/cmd/run"
 	"github.com/itchio/butler/cmd/sign"
+	"github.com/itchio/butler/cmd/simulateupdate"
 	"github.com/itchio/butler/cmd/singlediff"
 	"github.com/itchio/butler/cmd/sizeof"
 	"github.com/itchio/butler/cmd/status"
+	"github.com/itchio/butler/cmd/testconnection"
 	"github.com/itchio/butler/cmd/unsz"
 	"github.com/itchio/butler/cmd/untar"
 	"github.com/itchio/butler/cmd/unzip"
@@ -61,6 +64,7 @@ func registerCommands(ctx *mansion.Context) {
 	push.Register(ctx)
 	fetch.Register(ctx)
 	status.Register(ctx)
+	testconnection.Register(ctx)
 
 	file.Register(ctx)
 	ls.Register(ctx)
@@ -74,6 +78,7 @@ func registerCommands(ctx *mansion.Context) {
 	diff.Register(ctx)
 	apply.Register(ctx)
 	heal.Register(ctx)
+	simulateupdate.Register(ctx)
 
 	// hidden commands
 
@@ -119,4 +124,5 @@ func registerCommands(ctx *mansion.Context) {
 
 	ratetest.Register(ctx)
 	diag.Register(ctx)
+	fakeapi.Register(ctx)
 }
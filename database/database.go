@@ -18,6 +18,11 @@ func Prepare(consumer *state.Consumer, conn *sqlite.Conn, justCreated bool) (ret
 		return errors.WithMessage(err, "performing automatic DB migration")
 	}
 
+	// automatic migrations drop and recreate tables, losing their indices
+	for _, index := range indices {
+		models.MustExecRaw(conn, index, nil)
+	}
+
 	if justCreated {
 		models.SetSchemaVersion(conn, migrations.LatestSchemaVersion())
 	} else {
@@ -29,3 +34,10 @@ func Prepare(consumer *state.Consumer, conn *sqlite.Conn, justCreated bool) (ret
 
 	return nil
 }
+
+var indices = []string{
+	// for Fetch.Caves sorted by "installedAt", newest installs first
+	`CREATE INDEX IF NOT EXISTS caves_installed_at ON caves (installed_at DESC)`,
+	// for Fetch.Caves filtered with "neverUpdated"
+	`CREATE INDEX IF NOT EXISTS caves_install_count ON caves (install_count)`,
+}
@@ -26,4 +26,6 @@ var AllModels = []interface{}{
 	&FetchInfo{},
 	&GameUpload{},
 	&CaveHistoricalPlayTime{},
+	&CaveEvent{},
+	&Setting{},
 }
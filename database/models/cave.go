@@ -30,6 +30,11 @@ type Cave struct {
 	LastTouchedAt *time.Time `json:"lastTouchedAt"`
 	SecondsRun    int64      `json:"secondsRun"`
 
+	// How many times a different build (or upload) was installed
+	// to this cave, 1 if it was never updated. Zero for caves
+	// installed before this was tracked.
+	InstallCount int64 `json:"installCount"`
+
 	SnoozedAt *time.Time `json:"snoozedAt"`
 
 	Verdict       JSON  `json:"verdict"`
@@ -43,6 +48,70 @@ type Cave struct {
 	// If set, InstallLocationID is empty and this is used
 	// for all operations instead
 	CustomInstallFolder string `json:"customInstallFolder"`
+
+	// ID of the profile whose credentials were used to install
+	// this cave, or 0 if unknown
+	SourceProfileID int64 `json:"sourceProfileId"`
+
+	// Launch targets resolved ahead of time, see Launch.PrecomputePlans.
+	// Only valid as long as LaunchPlanKey matches.
+	LaunchPlan    JSON   `json:"launchPlan"`
+	LaunchPlanKey string `json:"launchPlanKey"`
+
+	// Set when an update check finds that the upload this cave was
+	// installed from got deleted, and suggests replacements.
+	ReplacedUploadID int64 `json:"replacedUploadId"`
+	// The replacement the user accepted, if any (see ConfirmUploadSuccessor)
+	SuccessorUploadID int64 `json:"successorUploadId"`
+	// True if the user doesn't want to switch to a replacement
+	SuccessorDeclined bool `json:"successorDeclined"`
+
+	// How many update checks in a row found the game gone from
+	// itch.io, and when the first of them happened.
+	GameGoneChecks int64      `json:"gameGoneChecks"`
+	GameGoneSince  *time.Time `json:"gameGoneSince"`
+	// Set once the game has been gone for long enough that it was
+	// likely deleted for good. Delisted caves aren't checked for
+	// updates anymore, only for whether their game came back.
+	GameDelisted bool `json:"gameDelisted"`
+
+	// One of butlerd.CaveAutoUpdatePolicy, or empty for the default
+	AutoUpdatePolicy string `json:"autoUpdatePolicy"`
+
+	// One of butlerd.VirtualMachineType, or empty if the game runs natively.
+	// Set from the manifest on every install.
+	VirtualMachineRequired string `json:"virtualMachineRequired"`
+
+	// ID of the install location large files are moved to, if the cave
+	// is split across two. See operate.OverflowMap.
+	OverflowLocationID string `json:"overflowLocationId"`
+
+	// Name of the manifest action to launch without asking, if
+	// the manifest has several. Empty to ask every time.
+	PreferredLaunchTarget string `json:"preferredLaunchTarget"`
+
+	// Set when installed with butlerd.InstallQueueParams.SkipPrereqs,
+	// cleared once prerequisites are installed.
+	PrereqsSkipped bool `json:"prereqsSkipped"`
+
+	// Last value of butlerd.InstallQueueParams.IgnoreInstallers it was
+	// installed with, so reinstalls and updates extract it too.
+	IgnoreInstallers bool `json:"ignoreInstallers"`
+
+	// ID of the cave this one is DLC for, if any, see
+	// butlerd.InstallQueueParams.ParentCaveID
+	DLCParentCaveID string `json:"dlcParentCaveId"`
+
+	// Hash of the receipt last written to the install folder, see
+	// operate.ReceiptHash. Empty for caves installed before it was
+	// recorded.
+	ReceiptHash string `json:"receiptHash"`
+
+	// Set while the cave is only partly installed, but its game can
+	// start already, see butlerd.InstallQueueParams.PlayEarly. Cleared
+	// once the rest, RemainingBytes of it, is installed.
+	PlayableEarly  bool  `json:"playableEarly"`
+	RemainingBytes int64 `json:"remainingBytes"`
 }
 
 func (c *Cave) SetVerdict(verdict *dash.Verdict) {
@@ -73,6 +142,19 @@ func CavesByGameID(conn *sqlite.Conn, gameID int64) []*Cave {
 	return cs
 }
 
+// CavesByDLCParentCaveID returns the caves that are DLC for parentCaveID
+func CavesByDLCParentCaveID(conn *sqlite.Conn, parentCaveID string) []*Cave {
+	var cs []*Cave
+	MustSelect(conn, &cs, builder.Eq{"dlc_parent_cave_id": parentCaveID}, hades.Search{})
+	return cs
+}
+
+func CavesByProfileID(conn *sqlite.Conn, profileID int64) []*Cave {
+	var cs []*Cave
+	MustSelect(conn, &cs, builder.Eq{"source_profile_id": profileID}, hades.Search{})
+	return cs
+}
+
 func (c *Cave) Touch() {
 	lastTouchedAt := time.Now().UTC()
 	c.LastTouchedAt = &lastTouchedAt
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/hades"
+	"xorm.io/builder"
+)
+
+// CaveEvent is something that happened to a cave, besides installs
+// and launches, that the user may want to know about later.
+type CaveEvent struct {
+	// An UUID
+	ID string `json:"id" hades:"primary_key"`
+
+	CaveID string `json:"caveId"`
+
+	// One of butlerd.CaveEventType
+	Type      string     `json:"type"`
+	CreatedAt *time.Time `json:"createdAt"`
+
+	// For relinks and renames, where the cave was before, and where it is now
+	FromInstallFolder string `json:"fromInstallFolder"`
+	ToInstallFolder   string `json:"toInstallFolder"`
+}
+
+// CaveEventsByCaveID returns the events of a cave, most recent first
+func CaveEventsByCaveID(conn *sqlite.Conn, caveID string) []*CaveEvent {
+	var events []*CaveEvent
+	MustSelect(conn, &events, builder.Eq{"cave_id": caveID}, hades.Search{}.OrderBy("created_at DESC"))
+	return events
+}
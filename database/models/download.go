@@ -43,8 +43,17 @@ type Download struct {
 
 	InstallLocationID string `json:"installLocationId"`
 
+	// Which credentials were picked for this download, and why
+	AccessExplanation JSON `json:"accessExplanation"`
+
 	Discarded bool `json:"discarded"`
 	Fresh     bool `json:"fresh"`
+	// Paused downloads are skipped by the drive until resumed
+	Paused bool `json:"paused"`
+	// Bytes per second the drive lets this download go at, 0 for unlimited
+	BandwidthBytesPerSecond int64 `json:"bandwidthBytesPerSecond"`
+	// Shared by downloads queued together, empty otherwise
+	BatchID string `json:"batchId"`
 }
 
 func AllDownloads(conn *sqlite.Conn) []*Download {
@@ -1,11 +1,17 @@
 package models
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"crawshaw.io/sqlite"
-	"xorm.io/builder"
+	"github.com/google/uuid"
 	"github.com/itchio/hades"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
 )
 
 type InstallLocation struct {
@@ -13,9 +19,109 @@ type InstallLocation struct {
 
 	Path string `json:"path"`
 
+	// Human-friendly name, the last component of Path by default
+	Label string `json:"label"`
+
+	// One of butlerd.InstallLocationAccessMode, or empty if
+	// butler doesn't manage install folder permissions here
+	AccessMode string `json:"accessMode"`
+
+	// Where staging folders for this location go, if not inside
+	// Path. Several locations may share the same staging path.
+	StagingPath string `json:"stagingPath"`
+
+	// Whether Path is on a solid-state drive, detected when the
+	// location is added or updated. False when unsure.
+	SSD bool `json:"ssd"`
+
+	// Template the install folder names of new caves are made from,
+	// instead of the game's slug, if not empty.
+	FolderNameTemplate string `json:"folderNameTemplate"`
+
+	// How many bytes were free at Path when the location was
+	// created, -1 if unknown. Not persisted.
+	FreeBytes int64 `json:"-" hades:"-"`
+
 	Caves []*Cave `json:"caves"`
 }
 
+// Disk probes used by NewInstallLocationFromPath. They're provided
+// by the system endpoints, so models doesn't depend on them.
+var (
+	DetectSSD        func(path string) (bool, error)
+	MeasureFreeBytes func(path string) (int64, error)
+)
+
+// NewInstallLocationFromPath returns an install location for the folder
+// at path, with a fresh ID, its canonical path, a label and what can be
+// told about its disk. It fails if the folder can't be written to.
+// The location isn't saved.
+func NewInstallLocationFromPath(path string) (*InstallLocation, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	canonicalPath, err := filepath.EvalSymlinks(absPath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = CheckWritableFolder(canonicalPath)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	il := &InstallLocation{
+		ID:        id.String(),
+		Path:      canonicalPath,
+		Label:     filepath.Base(canonicalPath),
+		FreeBytes: -1,
+	}
+	if il.Label == string(filepath.Separator) || il.Label == "." {
+		// volume roots have nothing better to go by
+		il.Label = canonicalPath
+	}
+
+	if DetectSSD != nil {
+		if ssd, err := DetectSSD(canonicalPath); err == nil {
+			il.SSD = ssd
+		}
+	}
+	if MeasureFreeBytes != nil {
+		if freeBytes, err := MeasureFreeBytes(canonicalPath); err == nil {
+			il.FreeBytes = freeBytes
+		}
+	}
+	return il, nil
+}
+
+// CheckWritableFolder returns an error if path isn't
+// a folder we can create files in.
+func CheckWritableFolder(path string) error {
+	stats, err := os.Stat(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if !stats.IsDir() {
+		return errors.Errorf("(%s) is not a directory", path)
+	}
+
+	// try writing a file
+	testFileName := fmt.Sprintf(".butler-test-file-%d", os.Getpid())
+	testFilePath := filepath.Join(path, testFileName)
+	defer os.Remove(testFilePath)
+	err = ioutil.WriteFile(testFilePath, []byte{}, os.FileMode(0o644))
+	if err != nil {
+		return errors.Errorf("Can't write to (%s): %s", path, err.Error())
+	}
+	return nil
+}
+
 func InstallLocationByID(conn *sqlite.Conn, id string) *InstallLocation {
 	var il InstallLocation
 	if MustSelectOne(conn, &il, builder.Eq{"id": id}) {
@@ -24,12 +130,105 @@ func InstallLocationByID(conn *sqlite.Conn, id string) *InstallLocation {
 	return nil
 }
 
+// InstallLocationByPath returns the install location at path, if any.
+// Both path and the locations' paths are made absolute, cleaned and
+// have their symlinks resolved before being compared.
+func InstallLocationByPath(conn *sqlite.Conn, path string) *InstallLocation {
+	path = normalizeLocationPath(path)
+
+	var locations []*InstallLocation
+	MustSelect(conn, &locations, builder.NewCond(), hades.Search{})
+	for _, il := range locations {
+		if normalizeLocationPath(il.Path) == path {
+			return il
+		}
+	}
+	return nil
+}
+
+// InstallLocationByPathPrefix returns the install location path is in,
+// or is the path of. If locations are nested, the innermost one wins.
+// Paths are compared like in InstallLocationByPath.
+func InstallLocationByPathPrefix(conn *sqlite.Conn, path string) *InstallLocation {
+	path = normalizeLocationPath(path)
+
+	var locations []*InstallLocation
+	MustSelect(conn, &locations, builder.NewCond(), hades.Search{})
+
+	var best *InstallLocation
+	var bestPath string
+	for _, il := range locations {
+		ilPath := normalizeLocationPath(il.Path)
+		rel, err := filepath.Rel(ilPath, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if best == nil || len(ilPath) > len(bestPath) {
+			best = il
+			bestPath = ilPath
+		}
+	}
+	return best
+}
+
+// normalizeLocationPath returns path absolute and clean, with symlinks
+// resolved, so two paths to the same folder compare equal. If path
+// doesn't exist, symlinks are resolved in its closest existing parent.
+func normalizeLocationPath(path string) string {
+	if absPath, err := filepath.Abs(path); err == nil {
+		path = absPath
+	}
+	path = filepath.Clean(path)
+
+	var rest []string
+	for existing := path; ; {
+		if resolvedPath, err := filepath.EvalSymlinks(existing); err == nil {
+			return filepath.Join(append([]string{resolvedPath}, rest...)...)
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return path
+		}
+		rest = append([]string{filepath.Base(existing)}, rest...)
+		existing = parent
+	}
+}
+
 func (il *InstallLocation) GetInstallFolder(folderName string) string {
 	return filepath.Join(il.Path, folderName)
 }
 
+// Folders butler keeps next to install folders, in the path of an
+// install location. No cave may be installed in one of those.
+const (
+	StagingFolderName  = "downloads"
+	OverflowFolderName = "overflow"
+)
+
+// IsReservedFolderName returns true if name is one of the folders butler
+// keeps in install locations, and can't be the install folder of a cave.
+func IsReservedFolderName(name string) bool {
+	// some filesystems don't care about case
+	return strings.EqualFold(name, StagingFolderName) || strings.EqualFold(name, OverflowFolderName)
+}
+
+// GetStagingRoot returns the folder that contains the staging
+// folders of downloads to this location.
+func (il *InstallLocation) GetStagingRoot() string {
+	if il.StagingPath != "" {
+		return filepath.Join(il.StagingPath, StagingFolderName)
+	}
+	return filepath.Join(il.Path, StagingFolderName)
+}
+
 func (il *InstallLocation) GetStagingFolder(installID string) string {
-	return filepath.Join(il.Path, "downloads", installID)
+	return filepath.Join(il.GetStagingRoot(), installID)
+}
+
+// GetOverflowFolder returns the folder that holds the large files of
+// a cave split across two locations, when this is the second one.
+func (il *InstallLocation) GetOverflowFolder(caveID string) string {
+	return filepath.Join(il.Path, OverflowFolderName, caveID)
 }
 
 func (il *InstallLocation) GetCaves(conn *sqlite.Conn) []*Cave {
@@ -0,0 +1,58 @@
+package models_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/database"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/headway/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_InstallLocationByPath(t *testing.T) {
+	assert := assert.New(t)
+
+	conn, err := sqlite.OpenConn("file:install_location_test?mode=memory", 0)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, database.Prepare(&state.Consumer{}, conn, true))
+
+	dir, err := ioutil.TempDir("", "install-location-by-path")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dir, err = filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+
+	games := filepath.Join(dir, "games")
+	nested := filepath.Join(games, "more-games")
+	require.NoError(t, os.MkdirAll(nested, 0o755))
+	require.NoError(t, os.Symlink(games, filepath.Join(dir, "link")))
+
+	require.NoError(t, models.Save(conn, &models.InstallLocation{ID: "games", Path: games + string(filepath.Separator)}))
+	require.NoError(t, models.Save(conn, &models.InstallLocation{ID: "nested", Path: nested}))
+
+	idOf := func(il *models.InstallLocation) string {
+		if il == nil {
+			return ""
+		}
+		return il.ID
+	}
+
+	assert.EqualValues("games", idOf(models.InstallLocationByPath(conn, games)), "trailing slashes don't matter")
+	assert.EqualValues("games", idOf(models.InstallLocationByPath(conn, filepath.Join(dir, "link"))), "symlinks are resolved")
+	assert.EqualValues("nested", idOf(models.InstallLocationByPath(conn, nested+"/.")))
+	assert.Nil(models.InstallLocationByPath(conn, dir))
+	assert.Nil(models.InstallLocationByPath(conn, filepath.Join(games, "some-game")))
+
+	assert.EqualValues("games", idOf(models.InstallLocationByPathPrefix(conn, filepath.Join(games, "some-game"))))
+	assert.EqualValues("games", idOf(models.InstallLocationByPathPrefix(conn, filepath.Join(dir, "link", "some-game"))))
+	assert.EqualValues("games", idOf(models.InstallLocationByPathPrefix(conn, games)))
+	assert.EqualValues("nested", idOf(models.InstallLocationByPathPrefix(conn, filepath.Join(nested, "some-game"))), "innermost location wins")
+	assert.Nil(models.InstallLocationByPathPrefix(conn, dir))
+	assert.Nil(models.InstallLocationByPathPrefix(conn, games+"-and-more"))
+}
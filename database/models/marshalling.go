@@ -29,3 +29,22 @@ func MarshalVerdict(in *dash.Verdict, out *JSON) error {
 	*out = JSON(contents)
 	return nil
 }
+
+// Any
+
+func UnmarshalJSON(in JSON, out interface{}) error {
+	err := json.Unmarshal([]byte(in), out)
+	if err != nil {
+		return errors.Wrap(err, "unmarshalling JSON column")
+	}
+	return nil
+}
+
+func MarshalJSON(in interface{}, out *JSON) error {
+	contents, err := json.Marshal(in)
+	if err != nil {
+		return errors.Wrap(err, "marshalling JSON column")
+	}
+	*out = JSON(contents)
+	return nil
+}
@@ -0,0 +1,11 @@
+package models
+
+// Setting is the persisted value of a daemon setting, see
+// the mansion/settings package.
+type Setting struct {
+	// Key of the setting, like `network.bandwidthLimit`
+	ID string `hades:"primary_key"`
+
+	// JSON-encoded value
+	Value JSON
+}
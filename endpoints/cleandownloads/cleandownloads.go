@@ -7,8 +7,12 @@ import (
 
 	"github.com/itchio/butler/butlerd/messages"
 	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
 	"github.com/itchio/butler/cmd/sizeof"
 	"github.com/itchio/butler/cmd/wipe"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/hades"
+	"xorm.io/builder"
 
 	"github.com/itchio/headway/united"
 )
@@ -27,9 +31,33 @@ func CleanDownloadsSearch(rc *butlerd.RequestContext, params butlerd.CleanDownlo
 		whitemap[whitelistPath] = struct{}{}
 	}
 
+	roots := append([]string(nil), params.Roots...)
+	func() {
+		conn := rc.GetConn()
+		defer rc.PutConn(conn)
+
+		// clients only know about the downloads folder inside each
+		// install location, staging data may be kept somewhere else.
+		var locations []*models.InstallLocation
+		models.MustSelect(conn, &locations, builder.Neq{"staging_path": ""}, hades.Search{})
+		for _, il := range locations {
+			roots = appendUnique(roots, il.GetStagingRoot())
+		}
+
+		// staging roots may be shared by several install locations,
+		// never touch a download that's still in progress.
+		var downloads []*models.Download
+		models.MustSelect(conn, &downloads, builder.IsNull{"finished_at"}, hades.Search{})
+		for _, download := range downloads {
+			if download.StagingFolder != "" {
+				whitemap[filepath.Base(download.StagingFolder)] = struct{}{}
+			}
+		}
+	}()
+
 	var entries []*butlerd.CleanDownloadsEntry
 
-	for _, root := range params.Roots {
+	for _, root := range roots {
 		folders, err := ioutil.ReadDir(root)
 		if err != nil {
 			if os.IsNotExist(err) {
@@ -51,6 +79,12 @@ func CleanDownloadsSearch(rc *butlerd.RequestContext, params butlerd.CleanDownlo
 				continue
 			}
 
+			if operate.IsStageFolderBusy(absoluteFolderPath) {
+				// no download record, but something's working in there
+				consumer.Debugf("Ignoring busy (%s)", base)
+				continue
+			}
+
 			// ey that's a candidate!
 			folderSize, err := sizeof.Do(absoluteFolderPath)
 			if err != nil {
@@ -70,12 +104,29 @@ func CleanDownloadsSearch(rc *butlerd.RequestContext, params butlerd.CleanDownlo
 	return res, nil
 }
 
+func appendUnique(paths []string, path string) []string {
+	for _, p := range paths {
+		if filepath.Clean(p) == filepath.Clean(path) {
+			return paths
+		}
+	}
+	return append(paths, path)
+}
+
 func CleanDownloadsApply(rc *butlerd.RequestContext, params butlerd.CleanDownloadsApplyParams) (*butlerd.CleanDownloadsApplyResult, error) {
 	consumer := rc.Consumer
 
 	for _, entry := range params.Entries {
+		// things may have changed since the search
+		unlock, ok := operate.TryLockStageFolder(entry.Path)
+		if !ok {
+			consumer.Infof("Not wiping (%s), it's in use", entry.Path)
+			continue
+		}
+
 		consumer.Infof("Wiping (%s) - %s", entry.Path, united.FormatBytes(entry.Size))
 		err := wipe.Do(consumer, entry.Path)
+		unlock()
 		if err != nil {
 			consumer.Warnf("Could not wipe (%s): %s", entry.Path, err.Error())
 		}
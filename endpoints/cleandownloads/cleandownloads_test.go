@@ -0,0 +1,147 @@
+package cleandownloads
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/headway/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CleanDownloadsRacingInstalls(t *testing.T) {
+	assert := assert.New(t)
+
+	root, err := ioutil.TempDir("", "cleandownloads")
+	must(t, err)
+	defer os.RemoveAll(root)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rc := &butlerd.RequestContext{
+		Ctx:      ctx,
+		Consumer: &state.Consumer{},
+	}
+
+	var entries []*butlerd.CleanDownloadsEntry
+	for i := 0; i < 8; i++ {
+		entries = append(entries, &butlerd.CleanDownloadsEntry{
+			Path: filepath.Join(root, fmt.Sprintf("download-%d", i)),
+		})
+	}
+
+	var violations int64
+	var wg sync.WaitGroup
+	for _, entry := range entries {
+		wg.Add(1)
+		go func(folder string) {
+			defer wg.Done()
+
+			for i := 0; i < 20; i++ {
+				oc, err := operate.LoadContext(ctx, rc, folder)
+				if err != nil {
+					t.Errorf("%+v", err)
+					return
+				}
+
+				// simulate an install writing to its staging folder
+				marker := filepath.Join(folder, "data.bin")
+				err = ioutil.WriteFile(marker, []byte("precious"), 0o644)
+				if err != nil {
+					atomic.AddInt64(&violations, 1)
+				}
+				time.Sleep(time.Millisecond)
+				if _, err := os.Stat(marker); err != nil {
+					atomic.AddInt64(&violations, 1)
+				}
+
+				oc.Release()
+			}
+		}(entry.Path)
+	}
+
+	done := make(chan struct{})
+	var cleaned sync.WaitGroup
+	cleaned.Add(1)
+	go func() {
+		defer cleaned.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				_, err := CleanDownloadsApply(rc, butlerd.CleanDownloadsApplyParams{Entries: entries})
+				if err != nil {
+					t.Errorf("%+v", err)
+					return
+				}
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(done)
+	cleaned.Wait()
+	assert.EqualValues(0, violations, "cleanup never touches a folder that's in use")
+
+	// once everything is done, it all goes away
+	_, err = CleanDownloadsApply(rc, butlerd.CleanDownloadsApplyParams{Entries: entries})
+	must(t, err)
+	for _, entry := range entries {
+		_, err := os.Stat(entry.Path)
+		assert.True(os.IsNotExist(err), "(%s) was wiped", entry.Path)
+	}
+}
+
+func Test_CleanDownloadsHeartbeat(t *testing.T) {
+	assert := assert.New(t)
+
+	root, err := ioutil.TempDir("", "cleandownloads")
+	must(t, err)
+	defer os.RemoveAll(root)
+
+	rc := &butlerd.RequestContext{
+		Ctx:      context.Background(),
+		Consumer: &state.Consumer{},
+	}
+
+	// an operation of another process is using that folder
+	folder := filepath.Join(root, "elsewhere")
+	must(t, os.MkdirAll(folder, 0o755))
+	heartbeat := filepath.Join(folder, "operate-heartbeat")
+	must(t, ioutil.WriteFile(heartbeat, []byte("alive"), 0o644))
+
+	assert.True(operate.IsStageFolderBusy(folder))
+	_, err = CleanDownloadsApply(rc, butlerd.CleanDownloadsApplyParams{
+		Entries: []*butlerd.CleanDownloadsEntry{{Path: folder}},
+	})
+	must(t, err)
+	_, err = os.Stat(folder)
+	assert.NoError(err, "fresh heartbeats are respected")
+
+	// ...until that process dies
+	stale := time.Now().Add(-time.Hour)
+	must(t, os.Chtimes(heartbeat, stale, stale))
+
+	assert.False(operate.IsStageFolderBusy(folder))
+	_, err = CleanDownloadsApply(rc, butlerd.CleanDownloadsApplyParams{
+		Entries: []*butlerd.CleanDownloadsEntry{{Path: folder}},
+	})
+	must(t, err)
+	_, err = os.Stat(folder)
+	assert.True(os.IsNotExist(err), "stale heartbeats aren't")
+}
+
+func must(t *testing.T, err error) {
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
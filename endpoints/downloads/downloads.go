@@ -6,12 +6,23 @@ import (
 )
 
 func Register(router *butlerd.Router) {
+	registerThrottle()
+
 	messages.DownloadsQueue.Register(router, DownloadsQueue)
 	messages.DownloadsPrioritize.Register(router, DownloadsPrioritize)
 	messages.DownloadsList.Register(router, DownloadsList)
+	messages.DownloadsGetByGameID.Register(router, DownloadsGetByGameID)
 	messages.DownloadsDrive.Register(router, DownloadsDrive)
 	messages.DownloadsDriveCancel.Register(router, DownloadsDriveCancel)
 	messages.DownloadsClearFinished.Register(router, DownloadsClearFinished)
+	messages.DownloadsDrainQueue.Register(router, DownloadsDrainQueue)
 	messages.DownloadsDiscard.Register(router, DownloadsDiscard)
 	messages.DownloadsRetry.Register(router, DownloadsRetry)
+	messages.DownloadsPause.Register(router, DownloadsPause)
+	messages.DownloadsResume.Register(router, DownloadsResume)
+	messages.DownloadsSetBandwidth.Register(router, DownloadsSetBandwidth)
+	messages.DownloadsSetThrottle.Register(router, DownloadsSetThrottle)
+	messages.DownloadsGetHistory.Register(router, DownloadsGetHistory)
+	messages.DownloadsGetNetworkStats.Register(router, DownloadsGetNetworkStats)
+	messages.DownloadsGetSpeedHistory.Register(router, DownloadsGetSpeedHistory)
 }
@@ -5,7 +5,9 @@ import (
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/cmd/operate"
 	"github.com/itchio/butler/database/models"
+	"github.com/itchio/hades"
 	"github.com/pkg/errors"
+	"xorm.io/builder"
 )
 
 func DownloadsDiscard(rc *butlerd.RequestContext, params butlerd.DownloadsDiscardParams) (*butlerd.DownloadsDiscardResult, error) {
@@ -39,3 +41,29 @@ func ValidateDownload(conn *sqlite.Conn, downloadID string) *models.Download {
 
 	return download
 }
+
+// DiscardBatch discards the downloads of a batch that haven't finished
+// and aren't being performed, and returns how many it discarded.
+func DiscardBatch(rc *butlerd.RequestContext, batchID string) int64 {
+	var count int64
+	rc.WithConn(func(conn *sqlite.Conn) {
+		// so that the drive doesn't move on to another one of
+		// them while they're being discarded
+		performing.Lock()
+		defer performing.Unlock()
+
+		cond := builder.And(
+			builder.Eq{"batch_id": batchID},
+			builder.IsNull{"finished_at"},
+			builder.Not{builder.Expr("discarded")},
+			builder.Neq{"id": performing.downloadID},
+		)
+		count = models.MustCount(conn, &models.Download{}, cond)
+		models.MustUpdate(conn, &models.Download{},
+			hades.Where(cond),
+			builder.Eq{"discarded": true},
+		)
+	})
+	rc.Consumer.Statf("Discarded %d downloads of batch %s", count, batchID)
+	return count
+}
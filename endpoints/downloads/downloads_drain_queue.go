@@ -0,0 +1,123 @@
+package downloads
+
+import (
+	"context"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/horror"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/hades"
+	"github.com/itchio/headway/state"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
+)
+
+// How long to wait for the download being driven to stop. If it
+// takes longer than that, it's left for the drive to clean up.
+const drainStopTimeout = 30 * time.Second
+
+func DownloadsDrainQueue(rc *butlerd.RequestContext, params butlerd.DownloadsDrainQueueParams) (*butlerd.DownloadsDrainQueueResult, error) {
+	consumer := rc.Consumer
+
+	reason := params.Reason
+	if reason == "" {
+		reason = "no reason given"
+	}
+	consumer.Opf("Draining download queue (%s)", reason)
+
+	var drained []*models.Download
+	var err error
+	rc.WithConn(func(conn *sqlite.Conn) {
+		drained, err = discardPendingDownloads(conn)
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// they're discarded, so the drive won't pick them up again:
+	// only the one it's working on needs stopping.
+	drainedIDs := make(map[string]bool)
+	for _, download := range drained {
+		drainedIDs[download.ID] = true
+	}
+	stuckID := stopPerforming(rc.Ctx, consumer, drainedIDs)
+
+	var ids []string
+	for _, download := range drained {
+		if download.ID == stuckID {
+			continue
+		}
+		ids = append(ids, download.ID)
+	}
+	if len(ids) > 0 {
+		rc.WithConn(func(conn *sqlite.Conn) {
+			models.MustDelete(conn, &models.Download{}, builder.In("id", ids))
+		})
+	}
+
+	for _, download := range drained {
+		if download.ID == stuckID {
+			continue
+		}
+		consumer.Opf("Cleaning up download for %s", operate.GameToString(download.Game))
+		wipeDownloadFolders(rc, download)
+		forgetTelemetry(download.ID)
+		forgetLimiter(download.ID)
+	}
+	consumer.Statf("Drained %d downloads from the queue", len(drained))
+
+	res := &butlerd.DownloadsDrainQueueResult{
+		Drained: int64(len(drained)),
+	}
+	return res, nil
+}
+
+// discardPendingDownloads marks all downloads that haven't finished
+// as discarded, within a single savepoint, and returns them.
+func discardPendingDownloads(conn *sqlite.Conn) (downloads []*models.Download, retErr error) {
+	defer horror.RecoverInto(&retErr)
+	defer sqlitex.Save(conn)(&retErr)
+
+	cond := builder.IsNull{"finished_at"}
+	models.MustSelect(conn, &downloads, cond, hades.Search{}.OrderBy("position ASC"))
+	models.PreloadDownloads(conn, downloads)
+	if len(downloads) > 0 {
+		models.MustUpdate(conn, &models.Download{},
+			hades.Where(cond),
+			builder.Eq{"discarded": true},
+		)
+	}
+	return downloads, nil
+}
+
+// stopPerforming cancels the download being driven if it's one of
+// downloadIDs, and waits for it to stop. If it doesn't stop in time,
+// its ID is returned: its folders may still be in use, so they must
+// be left alone. The drive cleans it up once it's done, since it's
+// discarded.
+func stopPerforming(ctx context.Context, consumer *state.Consumer, downloadIDs map[string]bool) string {
+	performing.Lock()
+	if !downloadIDs[performing.downloadID] {
+		performing.Unlock()
+		return ""
+	}
+	downloadID := performing.downloadID
+	cancel := performing.cancel
+	done := performing.done
+	performing.Unlock()
+
+	consumer.Infof("Stopping download %s", downloadID)
+	cancel()
+	select {
+	case <-done:
+		return ""
+	case <-ctx.Done():
+	case <-time.After(drainStopTimeout):
+	}
+	consumer.Warnf("Download %s is taking a while to stop, leaving it for the drive to clean up", downloadID)
+	return downloadID
+}
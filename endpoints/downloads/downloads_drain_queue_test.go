@@ -0,0 +1,58 @@
+package downloads
+
+import (
+	"context"
+	"testing"
+
+	"github.com/itchio/headway/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_StopPerforming(t *testing.T) {
+	assert := assert.New(t)
+	consumer := &state.Consumer{}
+
+	perform := func(downloadID string) (done chan struct{}, cancelled chan struct{}) {
+		done = make(chan struct{})
+		cancelled = make(chan struct{})
+		performing.Lock()
+		performing.downloadID = downloadID
+		performing.cancel = func() { close(cancelled) }
+		performing.done = done
+		performing.Unlock()
+		return done, cancelled
+	}
+	defer func() {
+		performing.Lock()
+		performing.downloadID = ""
+		performing.cancel = nil
+		performing.done = nil
+		performing.Unlock()
+	}()
+
+	assert.Empty(stopPerforming(context.Background(), consumer, map[string]bool{"a": true}), "nothing being driven")
+
+	_, cancelled := perform("b")
+	assert.Empty(stopPerforming(context.Background(), consumer, map[string]bool{"a": true}), "not one of ours")
+	select {
+	case <-cancelled:
+		assert.Fail("other downloads shouldn't be stopped")
+	default:
+	}
+
+	done, cancelled := perform("a")
+	go func() {
+		<-cancelled
+		close(done)
+	}()
+	assert.Empty(stopPerforming(context.Background(), consumer, map[string]bool{"a": true}), "stopped in time")
+
+	_, cancelled = perform("a")
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-cancelled
+		// never lets go of its folders
+		cancel()
+	}()
+	assert.EqualValues("a", stopPerforming(ctx, consumer, map[string]bool{"a": true}), "stuck downloads are left alone")
+}
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"log"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/itchio/wharf/werrors"
@@ -22,6 +23,8 @@ import (
 	"github.com/itchio/butler/cmd/operate"
 	"github.com/itchio/butler/cmd/wipe"
 	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/mansion/telemetry"
+	"github.com/itchio/butler/mansion/throttle"
 
 	"crawshaw.io/sqlite"
 	"github.com/itchio/hades"
@@ -56,6 +59,7 @@ func DownloadsDrive(rc *butlerd.RequestContext, params butlerd.DownloadsDrivePar
 	status := &Status{
 		Online: true,
 	}
+	grace := gracePolicyFromParams(params)
 
 poll:
 	for {
@@ -73,7 +77,7 @@ poll:
 			consumer.Warnf("%+v", errors.WithMessage(err, "while cleaning discarded:"))
 		}
 
-		err = performOne(ctx, rc)
+		err = performOne(ctx, rc, grace, params.PatchProgress)
 		if err != nil {
 			if err == butlerd.CodeNetworkDisconnected {
 				err = waitForInternet(rc, status)
@@ -141,32 +145,13 @@ func cleanDiscarded(rc *butlerd.RequestContext) error {
 	})
 	for _, download := range discardedDownloads {
 		consumer.Opf("Cleaning up download for %s", operate.GameToString(download.Game))
-
-		if download.StagingFolder == "" {
-			consumer.Warnf("No staging folder specified, can't wipe")
-		} else {
-			consumer.Opf("Wiping staging folder...")
-			err := wipe.Do(consumer, download.StagingFolder)
-			if err != nil {
-				consumer.Warnf("While wiping staging folder: %s", err.Error())
-			}
-		}
-
-		if download.Fresh && (download.FinishedAt == nil || download.Error != nil) {
-			if download.InstallFolder == "" {
-				consumer.Warnf("No (fresh) install folder specified, can't wipe")
-			} else {
-				consumer.Opf("Wiping (fresh) install folder...")
-				err := wipe.Do(consumer, download.InstallFolder)
-				if err != nil {
-					consumer.Warnf("While wiping (fresh) install folder: %s", err.Error())
-				}
-			}
-		}
+		wipeDownloadFolders(rc, download)
 
 		rc.WithConn(func(conn *sqlite.Conn) {
 			models.MustDelete(conn, download, builder.Eq{"id": download.ID})
 		})
+		forgetTelemetry(download.ID)
+		forgetLimiter(download.ID)
 
 		messages.DownloadsDriveDiscarded.Notify(rc, butlerd.DownloadsDriveDiscardedNotification{
 			Download: formatDownload(download),
@@ -175,7 +160,68 @@ func cleanDiscarded(rc *butlerd.RequestContext) error {
 	return nil
 }
 
-func performOne(parentCtx context.Context, rc *butlerd.RequestContext) error {
+// wipeDownloadFolders removes the staging folder of download, and its
+// install folder if it was a fresh install that didn't go through,
+// along with its cave if it was playable early.
+func wipeDownloadFolders(rc *butlerd.RequestContext, download *models.Download) {
+	consumer := rc.Consumer
+
+	if download.StagingFolder == "" {
+		consumer.Warnf("No staging folder specified, can't wipe")
+	} else {
+		consumer.Opf("Wiping staging folder...")
+		err := wipe.Do(consumer, download.StagingFolder)
+		if err != nil {
+			consumer.Warnf("While wiping staging folder: %s", err.Error())
+		}
+	}
+
+	if download.Fresh && (download.FinishedAt == nil || download.Error != nil) {
+		if download.InstallFolder == "" {
+			consumer.Warnf("No (fresh) install folder specified, can't wipe")
+		} else {
+			consumer.Opf("Wiping (fresh) install folder...")
+			err := wipe.Do(consumer, download.InstallFolder)
+			if err != nil {
+				consumer.Warnf("While wiping (fresh) install folder: %s", err.Error())
+			}
+		}
+
+		rc.WithConn(func(conn *sqlite.Conn) {
+			cave := models.CaveByID(conn, download.CaveID)
+			if cave != nil && cave.PlayableEarly {
+				consumer.Opf("Forgetting cave (%s), it was playable early", cave.ID)
+				cave.Delete(conn)
+			}
+		})
+	}
+}
+
+// performing is the download performOne is working on, if any, so
+// that other requests can stop it and wait for it to let go.
+var performing struct {
+	sync.Mutex
+	downloadID string
+	cancel     context.CancelFunc
+	done       chan struct{}
+	// ID of the download that was put ahead of it, if any
+	preemptedBy string
+}
+
+// preemptPerforming stops the download being driven, unless it's
+// downloadID, so that the drive moves on to downloadID without waiting
+// for its next check. The download that was stopped stays in the queue.
+func preemptPerforming(downloadID string) {
+	performing.Lock()
+	defer performing.Unlock()
+	if performing.downloadID == "" || performing.downloadID == downloadID {
+		return
+	}
+	performing.preemptedBy = downloadID
+	performing.cancel()
+}
+
+func performOne(parentCtx context.Context, rc *butlerd.RequestContext, grace gracePolicy, patchProgress bool) error {
 	consumer := rc.Consumer
 
 	var pendingDownloads []*models.Download
@@ -185,6 +231,7 @@ func performOne(parentCtx context.Context, rc *butlerd.RequestContext) error {
 			builder.And(
 				builder.IsNull{"finished_at"},
 				builder.Not{builder.Expr("discarded")},
+				builder.Not{builder.Expr("paused")},
 			),
 			hades.Search{}.OrderBy("position ASC"),
 		)
@@ -203,15 +250,32 @@ func performOne(parentCtx context.Context, rc *butlerd.RequestContext) error {
 	ctx, cancelFunc := context.WithCancel(parentCtx)
 	defer cancelFunc()
 
+	performDone := make(chan struct{})
+	performing.Lock()
+	performing.downloadID = download.ID
+	performing.cancel = cancelFunc
+	performing.done = performDone
+	performing.Unlock()
+	defer func() {
+		performing.Lock()
+		performing.downloadID = ""
+		performing.cancel = nil
+		performing.done = nil
+		performing.preemptedBy = ""
+		performing.Unlock()
+		close(performDone)
+	}()
+
 	wasDiscarded := func() bool {
-		// have we been discarded?
+		// have we been discarded or paused?
 		{
-			var discarded bool
+			var discarded, paused bool
 			rc.WithConn(func(conn *sqlite.Conn) {
 				models.MustExec(conn,
-					builder.Select("discarded").From("downloads").Where(builder.Eq{"id": download.ID}),
+					builder.Select("discarded", "paused").From("downloads").Where(builder.Eq{"id": download.ID}),
 					func(stmt *sqlite.Stmt) error {
 						discarded = stmt.ColumnInt(0) == 1
+						paused = stmt.ColumnInt(1) == 1
 						return nil
 					},
 				)
@@ -220,6 +284,10 @@ func performOne(parentCtx context.Context, rc *butlerd.RequestContext) error {
 				consumer.Infof("Download was cancelled from under us, bailing out!")
 				return true
 			}
+			if paused {
+				consumer.Infof("Download was paused, bailing out!")
+				return true
+			}
 		}
 
 		// has something else been prioritized?
@@ -231,9 +299,10 @@ func performOne(parentCtx context.Context, rc *butlerd.RequestContext) error {
 						builder.And(
 							builder.IsNull{"finished_at"},
 							builder.Not{builder.Expr("discarded")},
+							builder.Not{builder.Expr("paused")},
 						),
 					),
-					hades.Search{}.Limit(1),
+					hades.Search{}.OrderBy("position ASC").Limit(1),
 					func(stmt *sqlite.Stmt) error {
 						priorityDownloadID = stmt.ColumnText(0)
 						return nil
@@ -242,6 +311,9 @@ func performOne(parentCtx context.Context, rc *butlerd.RequestContext) error {
 			})
 			if priorityDownloadID != download.ID {
 				consumer.Infof("%s deprioritized (for %s), bailing out!", download.ID, priorityDownloadID)
+				performing.Lock()
+				performing.preemptedBy = priorityDownloadID
+				performing.Unlock()
 				return true
 			}
 		}
@@ -267,6 +339,10 @@ func performOne(parentCtx context.Context, rc *butlerd.RequestContext) error {
 	speedHistory := make([]float64, maxSpeedDatapoints)
 
 	lastProgress := time.Now()
+	limiter := getLimiter(download.ID, download.BandwidthBytesPerSecond)
+	// its rate is saved with the download, so it's made again
+	// if the download is driven again after being set aside
+	defer forgetLimiter(download.ID)
 
 	sendProgress := func() error {
 		if time.Since(lastProgress).Seconds() < 0.5 {
@@ -286,6 +362,7 @@ func performOne(parentCtx context.Context, rc *butlerd.RequestContext) error {
 				Progress: progress,
 				ETA:      eta,
 				BPS:      bps,
+				LimitBPS: effectiveLimit(limiter),
 			},
 			SpeedHistory: speedHistory,
 		})
@@ -312,7 +389,26 @@ func performOne(parentCtx context.Context, rc *butlerd.RequestContext) error {
 		return nil
 	})
 
-	err := func() (err error) {
+	_ = messages.DownloadsDriveStarted.Notify(rc, butlerd.DownloadsDriveStartedNotification{
+		Download: formatDownload(download),
+	})
+
+	dt := getTelemetry(download.ID, true)
+	dt.SetOnHighJitter(func(jitter float64) {
+		consumer.Warnf("Connection looks unstable (jitter %.0f%%)", jitter*100)
+		_ = messages.DownloadJitterHigh.Notify(rc, butlerd.DownloadJitterHighNotification{
+			Download: formatDownload(download),
+			Jitter:   jitter,
+		})
+	})
+	defer dt.SetOnHighJitter(nil)
+	stopSampling := dt.StartSampling()
+	defer stopSampling()
+	performCtx := telemetry.WithTelemetry(ctx, dt)
+	performCtx = throttle.WithLimiter(performCtx, sharedLimiter)
+	performCtx = throttle.WithLimiter(performCtx, limiter)
+
+	err := withGraceRetries(ctx, consumer, grace, func() (err error) {
 		defer func() {
 			if r := recover(); r != nil {
 				consumer.Warnf("Recovered from panic!")
@@ -324,18 +420,30 @@ func performOne(parentCtx context.Context, rc *butlerd.RequestContext) error {
 			}
 		}()
 
-		_ = messages.DownloadsDriveStarted.Notify(rc, butlerd.DownloadsDriveStartedNotification{
-			Download: formatDownload(download),
-		})
-
-		_, err = operate.InstallPerform(ctx, rc, butlerd.InstallPerformParams{
+		_, err = operate.InstallPerform(performCtx, rc, butlerd.InstallPerformParams{
 			ID:            download.ID,
 			StagingFolder: download.StagingFolder,
+			PatchProgress: patchProgress,
 		})
 		return
-	}()
+	})
 	if err != nil {
-		if wasDiscarded() {
+		discarded := wasDiscarded()
+
+		performing.Lock()
+		preemptedBy := performing.preemptedBy
+		performing.Unlock()
+		if preemptedBy != "" {
+			// it'll be picked up again once its turn comes
+			consumer.Infof("Setting %s aside for %s", download.ID, preemptedBy)
+			_ = messages.DownloadsDrivePreempted.Notify(rc, butlerd.DownloadsDrivePreemptedNotification{
+				Download:       formatDownload(download),
+				NextDownloadID: preemptedBy,
+			})
+			return nil
+		}
+
+		if discarded {
 			// download errored, but it was already discarded, ignoring.
 			return nil
 		}
@@ -0,0 +1,44 @@
+package downloads
+
+import (
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/hades"
+	"xorm.io/builder"
+)
+
+// recentDownloadsAge is how long finished downloads are still
+// returned by DownloadsGetByGameID
+const recentDownloadsAge = 7 * 24 * time.Hour
+
+func DownloadsGetByGameID(rc *butlerd.RequestContext, params butlerd.DownloadsGetByGameIDParams) (*butlerd.DownloadsGetByGameIDResult, error) {
+	finishedSince := time.Now().Add(-recentDownloadsAge).UTC()
+
+	var downloads []*models.Download
+	rc.WithConn(func(conn *sqlite.Conn) {
+		models.MustSelect(conn, &downloads,
+			builder.And(
+				builder.Eq{"game_id": params.GameID},
+				builder.Not{builder.Expr("discarded")},
+				builder.Or(
+					builder.IsNull{"finished_at"},
+					builder.NotNull{"error"},
+					builder.Gt{"finished_at": finishedSince.Format(time.RFC3339Nano)},
+				),
+			),
+			hades.Search{}.OrderBy("position ASC"),
+		)
+		models.PreloadDownloads(conn, downloads)
+	})
+
+	res := &butlerd.DownloadsGetByGameIDResult{
+		Downloads: []*butlerd.Download{},
+	}
+	for _, d := range downloads {
+		res.Downloads = append(res.Downloads, formatDownload(d))
+	}
+	return res, nil
+}
@@ -0,0 +1,127 @@
+package downloads
+
+import (
+	"sync"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/mansion/telemetry"
+)
+
+// transfer metrics, kept in memory for as long as downloads are around
+var downloadTelemetries = struct {
+	sync.Mutex
+	byID map[string]*telemetry.DownloadTelemetry
+}{
+	byID: make(map[string]*telemetry.DownloadTelemetry),
+}
+
+func getTelemetry(downloadID string, create bool) *telemetry.DownloadTelemetry {
+	downloadTelemetries.Lock()
+	defer downloadTelemetries.Unlock()
+
+	t := downloadTelemetries.byID[downloadID]
+	if t == nil && create {
+		t = telemetry.New()
+		downloadTelemetries.byID[downloadID] = t
+	}
+	return t
+}
+
+func forgetTelemetry(downloadID string) {
+	downloadTelemetries.Lock()
+	defer downloadTelemetries.Unlock()
+	delete(downloadTelemetries.byID, downloadID)
+}
+
+func DownloadsGetHistory(rc *butlerd.RequestContext, params butlerd.DownloadsGetHistoryParams) (*butlerd.DownloadsGetHistoryResult, error) {
+	res := &butlerd.DownloadsGetHistoryResult{
+		Chunks: []*butlerd.DownloadChunk{},
+	}
+
+	t := getTelemetry(params.DownloadID, false)
+	if t == nil {
+		// might not have been driven yet
+		rc.WithConn(func(conn *sqlite.Conn) {
+			ValidateDownload(conn, params.DownloadID)
+		})
+		return res, nil
+	}
+
+	for _, c := range t.Chunks() {
+		res.Chunks = append(res.Chunks, &butlerd.DownloadChunk{
+			StartTime:              c.StartTime,
+			EndTime:                c.EndTime,
+			BytesTransferred:       c.BytesTransferred,
+			ServerIP:               c.ServerIP,
+			TLSHandshakeDurationMs: int64(c.TLSHandshake / time.Millisecond),
+			TTFBMs:                 int64(c.TTFB / time.Millisecond),
+		})
+	}
+	if jitter, ok := t.Jitter(); ok {
+		res.Jitter = &jitter
+	}
+	return res, nil
+}
+
+func DownloadsGetNetworkStats(rc *butlerd.RequestContext, params butlerd.DownloadsGetNetworkStatsParams) (*butlerd.DownloadsGetNetworkStatsResult, error) {
+	res := &butlerd.DownloadsGetNetworkStatsResult{}
+
+	t := getTelemetry(params.DownloadID, false)
+	if t == nil {
+		rc.WithConn(func(conn *sqlite.Conn) {
+			ValidateDownload(conn, params.DownloadID)
+		})
+		return res, nil
+	}
+
+	if stats := t.NetworkStats(); stats != nil {
+		res.Stats = &butlerd.DownloadNetworkStats{
+			SampledAt:        stats.SampledAt,
+			LocalAddr:        stats.LocalAddr,
+			RemoteAddr:       stats.RemoteAddr,
+			TCPInfoAvailable: stats.TCPInfo,
+			TCPWindowSize:    stats.TCPWindowSize,
+			BytesInFlight:    stats.BytesInFlight,
+			RetransmitCount:  stats.RetransmitCount,
+			RTTMs:            int64(stats.RTT / time.Millisecond),
+		}
+	}
+	return res, nil
+}
+
+func DownloadsGetSpeedHistory(rc *butlerd.RequestContext, params butlerd.DownloadsGetSpeedHistoryParams) (*butlerd.DownloadsGetSpeedHistoryResult, error) {
+	res := &butlerd.DownloadsGetSpeedHistoryResult{
+		Samples: []*butlerd.DownloadSpeedSample{},
+	}
+
+	t := getTelemetry(params.DownloadID, false)
+	if t == nil {
+		rc.WithConn(func(conn *sqlite.Conn) {
+			ValidateDownload(conn, params.DownloadID)
+		})
+		return res, nil
+	}
+
+	lastN := params.LastNSeconds
+	if lastN == 0 {
+		lastN = butlerd.DownloadsSpeedHistoryMaxSeconds
+	}
+
+	var total int64
+	for _, s := range t.SpeedSamples(int(lastN)) {
+		res.Samples = append(res.Samples, &butlerd.DownloadSpeedSample{
+			Timestamp: s.Timestamp,
+			SpeedBPS:  s.BytesPerSecond,
+		})
+		total += s.BytesPerSecond
+		if s.BytesPerSecond > res.PeakSpeedBPS {
+			res.PeakSpeedBPS = s.BytesPerSecond
+		}
+	}
+	if len(res.Samples) > 0 {
+		res.AverageSpeedBPS = total / int64(len(res.Samples))
+	}
+	return res, nil
+}
@@ -4,12 +4,21 @@ import (
 	"crawshaw.io/sqlite"
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/database/models"
+	"github.com/itchio/hades"
+	"xorm.io/builder"
 )
 
 func DownloadsList(rc *butlerd.RequestContext, params butlerd.DownloadsListParams) (*butlerd.DownloadsListResult, error) {
 	var downloads []*models.Download
 	rc.WithConn(func(conn *sqlite.Conn) {
-		downloads = models.AllDownloads(conn)
+		if params.BatchID != "" {
+			models.MustSelect(conn, &downloads, builder.And(
+				builder.Not{builder.Expr("discarded")},
+				builder.Eq{"batch_id": params.BatchID},
+			), hades.Search{}.OrderBy("position ASC"))
+		} else {
+			downloads = models.AllDownloads(conn)
+		}
 		models.PreloadDownloads(conn, downloads)
 	})
 
@@ -25,6 +34,15 @@ func DownloadsList(rc *butlerd.RequestContext, params butlerd.DownloadsListParam
 }
 
 func formatDownload(download *models.Download) *butlerd.Download {
+	var access *butlerd.AccessExplanation
+	if download.AccessExplanation != "" {
+		access = &butlerd.AccessExplanation{}
+		err := models.UnmarshalJSON(download.AccessExplanation, access)
+		if err != nil {
+			access = nil
+		}
+	}
+
 	return &butlerd.Download{
 		ID:            download.ID,
 		Error:         download.Error,
@@ -39,5 +57,10 @@ func formatDownload(download *models.Download) *butlerd.Download {
 		FinishedAt:    download.FinishedAt,
 		StagingFolder: download.StagingFolder,
 		Reason:        butlerd.DownloadReason(download.Reason),
+		Paused:        download.Paused,
+		Access:        access,
+
+		BandwidthBytesPerSecond: download.BandwidthBytesPerSecond,
+		BatchID:                 download.BatchID,
 	}
 }
@@ -0,0 +1,58 @@
+package downloads
+
+import (
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/hades"
+	"xorm.io/builder"
+)
+
+// Pausing only flags the download: the drive notices and cancels it,
+// which checkpoints the transfer in the staging folder, just like when
+// another download gets prioritized. Resuming later picks up from
+// that checkpoint with range requests.
+
+func DownloadsPause(rc *butlerd.RequestContext, params butlerd.DownloadsPauseParams) (*butlerd.DownloadsPauseResult, error) {
+	consumer := rc.Consumer
+	rc.WithConn(func(conn *sqlite.Conn) {
+		download := ValidateDownload(conn, params.DownloadID)
+		switch {
+		case download.FinishedAt != nil:
+			consumer.Warnf("Download already finished, can't pause")
+		case download.Paused:
+			consumer.Warnf("Download already paused")
+		default:
+			setPaused(conn, download, true)
+			consumer.Statf("Paused download for %s", operate.GameToString(download.Game))
+		}
+	})
+
+	res := &butlerd.DownloadsPauseResult{}
+	return res, nil
+}
+
+func DownloadsResume(rc *butlerd.RequestContext, params butlerd.DownloadsResumeParams) (*butlerd.DownloadsResumeResult, error) {
+	consumer := rc.Consumer
+	rc.WithConn(func(conn *sqlite.Conn) {
+		download := ValidateDownload(conn, params.DownloadID)
+		if !download.Paused {
+			consumer.Warnf("Download isn't paused")
+		} else {
+			setPaused(conn, download, false)
+			consumer.Statf("Resumed download for %s", operate.GameToString(download.Game))
+		}
+	})
+
+	res := &butlerd.DownloadsResumeResult{}
+	return res, nil
+}
+
+func setPaused(conn *sqlite.Conn, download *models.Download, paused bool) {
+	download.Paused = paused
+	models.MustUpdate(conn, &models.Download{},
+		hades.Where(builder.Eq{"id": download.ID}),
+		builder.Eq{"paused": paused},
+	)
+}
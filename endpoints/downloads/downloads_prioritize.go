@@ -3,17 +3,67 @@ package downloads
 import (
 	"crawshaw.io/sqlite"
 	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
 	"github.com/itchio/butler/database/models"
+	"github.com/itchio/hades"
+	"xorm.io/builder"
 )
 
 func DownloadsPrioritize(rc *butlerd.RequestContext, params butlerd.DownloadsPrioritizeParams) (*butlerd.DownloadsPrioritizeResult, error) {
+	consumer := rc.Consumer
+
 	var download *models.Download
 	rc.WithConn(func(conn *sqlite.Conn) {
 		download = ValidateDownload(conn, params.DownloadID)
-		download.Position = models.DownloadMinPosition(conn) - 1
-		download.Save(conn)
+		switch {
+		case download.FinishedAt != nil:
+			// the drive may have gotten to it first
+			consumer.Warnf("Download already finished, not prioritizing it")
+			download = nil
+		case download.Discarded:
+			consumer.Warnf("Download was discarded, not prioritizing it")
+			download = nil
+		default:
+			moveToFront(conn, download)
+			consumer.Statf("Prioritized download for %s", operate.GameToString(download.Game))
+		}
 	})
 
+	if download != nil && !download.Paused {
+		// the drive must see it at the front when it's stopped, and
+		// whoever holds the performing lock may be waiting on the
+		// database this transaction holds
+		rc.AfterCommit(func() {
+			preemptPerforming(download.ID)
+		})
+	}
+
 	res := &butlerd.DownloadsPrioritizeResult{}
 	return res, nil
 }
+
+// moveToFront gives download position 0, and all other downloads
+// the positions after it, in the order they were in.
+func moveToFront(conn *sqlite.Conn, download *models.Download) {
+	var otherIDs []string
+	models.MustExecWithSearch(conn,
+		builder.Select("id").From("downloads").Where(builder.Neq{"id": download.ID}),
+		hades.Search{}.OrderBy("position ASC"),
+		func(stmt *sqlite.Stmt) error {
+			otherIDs = append(otherIDs, stmt.ColumnText(0))
+			return nil
+		},
+	)
+
+	setPosition := func(downloadID string, position int64) {
+		models.MustUpdate(conn, &models.Download{},
+			hades.Where(builder.Eq{"id": downloadID}),
+			builder.Eq{"position": position},
+		)
+	}
+	download.Position = 0
+	setPosition(download.ID, download.Position)
+	for i, id := range otherIDs {
+		setPosition(id, int64(i+1))
+	}
+}
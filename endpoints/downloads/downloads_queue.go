@@ -4,10 +4,13 @@ import (
 	"os"
 	"time"
 
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
 	"xorm.io/builder"
 	"github.com/pkg/errors"
 
 	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/horror"
 	"github.com/itchio/butler/cmd/operate"
 	"github.com/itchio/butler/database/models"
 	"github.com/itchio/hades"
@@ -23,6 +26,13 @@ func DownloadsQueue(rc *butlerd.RequestContext, params butlerd.DownloadsQueuePar
 		return nil, errors.Errorf("item cannot be nil")
 	}
 
+	if existing := models.DownloadByID(conn, item.ID); existing != nil && existing.FinishedAt == nil && !existing.Discarded {
+		// two clients that called Install.Queue at the same time
+		// get the same item, and both queue it
+		consumer.Infof("Download %s is already queued, nothing to do", item.ID)
+		return &butlerd.DownloadsQueueResult{}, nil
+	}
+
 	startedAt := time.Now().UTC()
 
 	Fresh := false
@@ -54,19 +64,10 @@ func DownloadsQueue(rc *butlerd.RequestContext, params butlerd.DownloadsQueuePar
 		}
 	}
 
-	// remove other downloads for this cave or this upload
-	models.MustDelete(conn, &models.Download{},
-		builder.Or(
-			builder.Eq{"cave_id": item.CaveID},
-			builder.Eq{"upload_id": item.Upload.ID},
-		),
-	)
-
 	d := &models.Download{
 		ID:                item.ID,
 		Reason:            string(item.Reason),
 		CaveID:            item.CaveID,
-		Position:          models.DownloadMaxPosition(conn) + 1,
 		Game:              item.Game,
 		Upload:            item.Upload,
 		Build:             item.Build,
@@ -75,8 +76,41 @@ func DownloadsQueue(rc *butlerd.RequestContext, params butlerd.DownloadsQueuePar
 		InstallLocationID: item.InstallLocationID,
 		StartedAt:         &startedAt,
 		Fresh:             Fresh,
+
+		BandwidthBytesPerSecond: params.BandwidthBytesPerSecond,
+		BatchID:                 params.BatchID,
+	}
+	if item.Access != nil {
+		err := models.MarshalJSON(item.Access, &d.AccessExplanation)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	err = saveQueuedDownload(conn, item, d)
+	if err != nil {
+		return nil, errors.WithStack(err)
 	}
 
+	res := &butlerd.DownloadsQueueResult{}
+	return res, nil
+}
+
+// saveQueuedDownload saves d in a single savepoint, so that a crash
+// doesn't leave a half-queued download behind.
+func saveQueuedDownload(conn *sqlite.Conn, item *butlerd.InstallQueueResult, d *models.Download) (retErr error) {
+	defer horror.RecoverInto(&retErr)
+	defer sqlitex.Save(conn)(&retErr)
+
+	// remove other downloads for this cave or this upload
+	models.MustDelete(conn, &models.Download{},
+		builder.Or(
+			builder.Eq{"cave_id": item.CaveID},
+			builder.Eq{"upload_id": item.Upload.ID},
+		),
+	)
+
+	d.Position = models.DownloadMaxPosition(conn) + 1
 	models.MustSave(conn, d,
 		hades.Assoc("Game"),
 		hades.Assoc("Upload"),
@@ -93,13 +127,12 @@ func DownloadsQueue(rc *butlerd.RequestContext, params butlerd.DownloadsQueuePar
 		)
 	}
 
-	if params.Item.CaveID != "" && params.Item.Reason == butlerd.DownloadReasonVersionSwitch {
+	if item.CaveID != "" && (item.Reason == butlerd.DownloadReasonVersionSwitch || item.Reason == butlerd.DownloadReasonDowngrade) {
 		// if reverting, mark cave as pinned
-		cave := models.CaveByID(conn, params.Item.CaveID)
+		cave := models.CaveByID(conn, item.CaveID)
 		cave.Pinned = true
 		cave.Save(conn)
 	}
 
-	res := &butlerd.DownloadsQueueResult{}
-	return res, nil
+	return nil
 }
@@ -0,0 +1,69 @@
+package downloads
+
+import (
+	"sync"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/mansion/throttle"
+	"github.com/itchio/hades"
+	"xorm.io/builder"
+)
+
+// per-download throttles, kept in memory so their rate can be
+// changed while the download is being driven
+var downloadLimiters = struct {
+	sync.Mutex
+	byID map[string]*throttle.Limiter
+}{
+	byID: make(map[string]*throttle.Limiter),
+}
+
+// getLimiter returns the limiter for a download, creating it
+// with bytesPerSecond if there's none yet.
+func getLimiter(downloadID string, bytesPerSecond int64) *throttle.Limiter {
+	downloadLimiters.Lock()
+	defer downloadLimiters.Unlock()
+
+	l := downloadLimiters.byID[downloadID]
+	if l == nil {
+		l = throttle.New(bytesPerSecond)
+		downloadLimiters.byID[downloadID] = l
+	}
+	return l
+}
+
+func forgetLimiter(downloadID string) {
+	downloadLimiters.Lock()
+	defer downloadLimiters.Unlock()
+	delete(downloadLimiters.byID, downloadID)
+}
+
+func DownloadsSetBandwidth(rc *butlerd.RequestContext, params butlerd.DownloadsSetBandwidthParams) (*butlerd.DownloadsSetBandwidthResult, error) {
+	consumer := rc.Consumer
+	rc.WithConn(func(conn *sqlite.Conn) {
+		download := ValidateDownload(conn, params.DownloadID)
+		models.MustUpdate(conn, &models.Download{},
+			hades.Where(builder.Eq{"id": download.ID}),
+			builder.Eq{"bandwidth_bytes_per_second": params.BandwidthBytesPerSecond},
+		)
+
+		if params.BandwidthBytesPerSecond > 0 {
+			consumer.Statf("Limiting download for %s to %d bytes per second", operate.GameToString(download.Game), params.BandwidthBytesPerSecond)
+		} else {
+			consumer.Statf("No longer limiting download for %s", operate.GameToString(download.Game))
+		}
+	})
+
+	downloadLimiters.Lock()
+	l := downloadLimiters.byID[params.DownloadID]
+	downloadLimiters.Unlock()
+	if l != nil {
+		l.SetRate(params.BandwidthBytesPerSecond)
+	}
+
+	res := &butlerd.DownloadsSetBandwidthResult{}
+	return res, nil
+}
@@ -0,0 +1,60 @@
+package downloads
+
+import (
+	"github.com/efarrer/iothrottler"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/endpoints/utilities"
+	"github.com/itchio/butler/mansion/settings"
+	"github.com/itchio/butler/mansion/throttle"
+	"github.com/pkg/errors"
+)
+
+var maxBytesPerSecond = settings.Register(settings.Setting{
+	Key:         "downloads.maxBytesPerSecond",
+	Description: "Maximum bandwidth of all downloads together, in bytes per second. 0 means unlimited.",
+	Default:     int64(0),
+	Validate: func(value interface{}) error {
+		if value.(int64) < 0 {
+			return errors.New("must be positive")
+		}
+		return nil
+	},
+})
+
+// sharedLimiter throttles all downloads being driven, on top of
+// their own limiter, see getLimiter
+var sharedLimiter = throttle.New(0)
+
+func registerThrottle() {
+	settings.Subscribe(maxBytesPerSecond.Key, func(value interface{}) {
+		sharedLimiter.SetRate(value.(int64))
+	})
+}
+
+func DownloadsSetThrottle(rc *butlerd.RequestContext, params butlerd.DownloadsSetThrottleParams) (*butlerd.DownloadsSetThrottleResult, error) {
+	err := utilities.ChangeSetting(rc, maxBytesPerSecond, params.MaxBytesPerSecond)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &butlerd.DownloadsSetThrottleResult{}
+	return res, nil
+}
+
+// effectiveLimit returns the most bytes per second a download
+// limited by own may go at, or 0 if it's unlimited.
+func effectiveLimit(own *throttle.Limiter) int64 {
+	var limit int64
+	consider := func(rate int64) {
+		if rate > 0 && (limit == 0 || rate < limit) {
+			limit = rate
+		}
+	}
+
+	consider(own.Rate())
+	consider(sharedLimiter.Rate())
+	if entry, err := settings.Get("network.bandwidthLimit"); err == nil {
+		consider(entry.Value.(int64) * int64(iothrottler.Kbps))
+	}
+	return limit
+}
@@ -0,0 +1,71 @@
+package downloads
+
+import (
+	"context"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/httpkit/neterr"
+)
+
+const (
+	defaultGraceRetries    = 2
+	defaultGraceRetryDelay = 2 * time.Second
+)
+
+// gracePolicy describes how many times a download is silently retried
+// in-place after a transient network error, before anyone hears about it.
+type gracePolicy struct {
+	retries int64
+	delay   time.Duration
+}
+
+func gracePolicyFromParams(params butlerd.DownloadsDriveParams) gracePolicy {
+	policy := gracePolicy{
+		retries: defaultGraceRetries,
+		delay:   defaultGraceRetryDelay,
+	}
+	if params.GraceRetries != nil {
+		policy.retries = *params.GraceRetries
+	}
+	if params.GraceRetryDelayMs != nil {
+		policy.delay = time.Duration(*params.GraceRetryDelayMs) * time.Millisecond
+	}
+	if policy.retries < 0 {
+		policy.retries = 0
+	}
+	if policy.delay < 0 {
+		policy.delay = 0
+	}
+	return policy
+}
+
+func isTransientError(err error) bool {
+	if be, ok := butlerd.AsButlerdError(err); ok {
+		return butlerd.Code(be.RpcErrorCode()) == butlerd.CodeNetworkDisconnected
+	}
+	return neterr.IsNetworkError(err)
+}
+
+// withGraceRetries calls perform, and calls it again (after policy.delay)
+// if it fails with a transient error, up to policy.retries times. Retries
+// are only logged at debug level: if the last attempt still fails, its
+// error is returned and handled as usual.
+func withGraceRetries(ctx context.Context, consumer *state.Consumer, policy gracePolicy, perform func() error) error {
+	var attempt int64
+	for {
+		err := perform()
+		if err == nil || !isTransientError(err) || attempt >= policy.retries {
+			return err
+		}
+		attempt++
+
+		consumer.Debugf("Transient error, silently retrying (%d/%d) in %v: %v", attempt, policy.retries, policy.delay, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(policy.delay):
+		}
+	}
+}
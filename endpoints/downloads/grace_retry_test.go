@@ -0,0 +1,115 @@
+package downloads
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/headway/state"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func connectionReset() error {
+	return errors.WithStack(&url.Error{Op: "Get", URL: "https://example.org/file.zip", Err: io.EOF})
+}
+
+func newTestConsumer(t *testing.T, messages *[]string) *state.Consumer {
+	return &state.Consumer{
+		OnMessage: func(lvl string, msg string) {
+			t.Logf("[%s] %s", lvl, msg)
+			if lvl != "debug" {
+				*messages = append(*messages, msg)
+			}
+		},
+	}
+}
+
+func Test_GraceRetrySilentlyRecovers(t *testing.T) {
+	var messages []string
+	consumer := newTestConsumer(t, &messages)
+	policy := gracePolicy{retries: 2, delay: time.Millisecond}
+
+	var calls int
+	err := withGraceRetries(context.Background(), consumer, policy, func() error {
+		calls++
+		if calls < 3 {
+			return connectionReset()
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, calls)
+	assert.Empty(t, messages, "retries should only be logged at debug level")
+}
+
+func Test_GraceRetryGivesUp(t *testing.T) {
+	var messages []string
+	consumer := newTestConsumer(t, &messages)
+	policy := gracePolicy{retries: 2, delay: time.Millisecond}
+
+	var calls int
+	err := withGraceRetries(context.Background(), consumer, policy, func() error {
+		calls++
+		return connectionReset()
+	})
+	assert.Error(t, err)
+	assert.True(t, isTransientError(err))
+	assert.EqualValues(t, 3, calls)
+}
+
+func Test_GraceRetryIgnoresPersistentErrors(t *testing.T) {
+	var messages []string
+	consumer := newTestConsumer(t, &messages)
+	policy := gracePolicy{retries: 2, delay: time.Millisecond}
+
+	var calls int
+	err := withGraceRetries(context.Background(), consumer, policy, func() error {
+		calls++
+		return errors.WithStack(butlerd.CodeNoCompatibleUploads)
+	})
+	assert.Error(t, err)
+	assert.EqualValues(t, 1, calls)
+
+	calls = 0
+	err = withGraceRetries(context.Background(), consumer, policy, func() error {
+		calls++
+		return errors.WithStack(butlerd.CodeNetworkDisconnected)
+	})
+	assert.Error(t, err)
+	assert.EqualValues(t, 3, calls)
+}
+
+func Test_GraceRetryStopsWhenCancelled(t *testing.T) {
+	var messages []string
+	consumer := newTestConsumer(t, &messages)
+	policy := gracePolicy{retries: 5, delay: time.Hour}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int
+	err := withGraceRetries(ctx, consumer, policy, func() error {
+		calls++
+		cancel()
+		return connectionReset()
+	})
+	assert.Error(t, err)
+	assert.EqualValues(t, 1, calls)
+}
+
+func Test_GracePolicyFromParams(t *testing.T) {
+	policy := gracePolicyFromParams(butlerd.DownloadsDriveParams{})
+	assert.EqualValues(t, defaultGraceRetries, policy.retries)
+	assert.EqualValues(t, defaultGraceRetryDelay, policy.delay)
+
+	var retries int64 = 0
+	var delayMs int64 = 500
+	policy = gracePolicyFromParams(butlerd.DownloadsDriveParams{
+		GraceRetries:      &retries,
+		GraceRetryDelayMs: &delayMs,
+	})
+	assert.EqualValues(t, 0, policy.retries)
+	assert.EqualValues(t, 500*time.Millisecond, policy.delay)
+}
@@ -0,0 +1,179 @@
+// Package cavefilter parses filter expressions for caves, like:
+//
+//	gameTitle contains "dungeon" AND size > 1gb AND lastPlayed after 2024-01-01 AND NOT pinned
+//
+// and turns them into SQL conditions on the caves table (joined with games).
+//
+// Grammar (keywords and field names are case-insensitive):
+//
+//	expression := or
+//	or         := and ( "OR" and )*
+//	and        := not ( "AND" not )*
+//	not        := "NOT" not | primary
+//	primary    := "(" expression ")" | comparison | flag
+//	comparison := field operator value
+//	flag       := boolean field, alone, meaning "field = true"
+//	operator   := "=" | "!=" | "<" | "<=" | ">" | ">="
+//	            | "contains" | "before" | "after"
+//	value      := string | number | date | "true" | "false"
+//	string     := '"' characters, with \" and \\ escapes '"'
+//	number     := digits [ "." digits ] [ unit ]
+//	date       := YYYY-MM-DD
+//
+// NOT binds tighter than AND, which binds tighter than OR, so
+// `a OR b AND NOT c` means `a OR (b AND (NOT c))`.
+//
+// Fields, and what they accept:
+//
+//	gameTitle        text: =, !=, contains (all case-insensitive)
+//	classification   text: =, != (game, tool, assets, etc.)
+//	installLocation  text: =, != (install location ID)
+//	gameId           number: = != < <= > >=
+//	size             size: = != < <= > >=, units b, kb, mb, gb, tb (powers of 1024)
+//	playTime         duration: = != < <= > >=, units s, m, h, d (default: seconds)
+//	lastPlayed       date: before, after, = (whole days, UTC)
+//	installedAt      date: before, after, = (whole days, UTC)
+//	pinned           boolean: alone, or = / != true / false
+//	snoozed          boolean: alone, or = / != true / false
+//
+// `before D` excludes day D, `after D` excludes it as well, `= D`
+// matches any time on that day. Caves that were never played never
+// match a condition on lastPlayed (but do match `NOT lastPlayed after ...`).
+package cavefilter
+
+import (
+	"strings"
+
+	"xorm.io/builder"
+)
+
+// Parse turns an expression into a condition. The condition refers
+// to the `caves` and `games` tables, so the query it's used in must
+// join caves with games.
+func Parse(expression string) (builder.Cond, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, errorf(0, "empty expression")
+	}
+
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, errorf(t.pos, "unexpected %s", t)
+	}
+	return cond, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) parseOr() (builder.Cond, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().is("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = builder.Or(left, right)
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (builder.Cond, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().is("and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = builder.And(left, right)
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (builder.Cond, error) {
+	if p.peek().is("not") {
+		p.next()
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		sql, args, err := builder.ToSQL(inner)
+		if err != nil {
+			return nil, err
+		}
+		// NULL columns (never played, etc.) would make
+		// NOT (x) NULL as well, let them match instead.
+		return builder.Expr("NOT coalesce(("+sql+"), 0)", args...), nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (builder.Cond, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokenLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, errorf(closing.pos, "expected ')' to match '(' at position %d, got %s", t.pos, closing)
+		}
+		return inner, nil
+
+	case tokenIdent:
+		f := lookupField(t.text)
+		if f == nil {
+			return nil, errorf(t.pos, "unknown field '%s' (known fields: %s)", t.text, strings.Join(fieldNames(), ", "))
+		}
+
+		op := p.peek()
+		isOperator := op.kind == tokenOperator || op.is("contains") || op.is("before") || op.is("after")
+		if !isOperator {
+			if f.kind == kindBool {
+				return f.flag(true), nil
+			}
+			return nil, errorf(op.pos, "expected operator after '%s', got %s", t.text, op)
+		}
+		p.next()
+
+		value := p.next()
+		return f.compare(op, value)
+	}
+
+	return nil, errorf(t.pos, "expected field name or '(', got %s", t)
+}
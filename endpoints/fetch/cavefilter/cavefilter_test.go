@@ -0,0 +1,169 @@
+package cavefilter_test
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/endpoints/fetch/cavefilter"
+	"github.com/itchio/go-itchio"
+	"github.com/itchio/hades"
+	"github.com/itchio/headway/state"
+	"github.com/stretchr/testify/assert"
+)
+
+const gb = 1024 * 1024 * 1024
+
+func date(s string) *time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+func prepareCaves(t *testing.T) *sqlite.Conn {
+	conn, err := sqlite.OpenConn("file:cavefilter_test?mode=memory", 0)
+	must(t, err)
+	must(t, database.Prepare(&state.Consumer{}, conn, true))
+
+	save := func(record interface{}) {
+		must(t, models.Save(conn, record))
+	}
+
+	games := []*itchio.Game{
+		{ID: 1, Title: "Dungeon Crawler", Classification: itchio.GameClassificationGame},
+		{ID: 2, Title: "Deep DUNGEON \"Deluxe\"", Classification: itchio.GameClassificationGame},
+		{ID: 3, Title: "Sprite Editor", Classification: itchio.GameClassificationTool},
+		{ID: 4, Title: "Tiny Dungeon", Classification: itchio.GameClassificationGame},
+	}
+	for _, g := range games {
+		save(g)
+	}
+
+	caves := []*models.Cave{
+		{
+			ID: "crawler", GameID: 1, InstalledSize: 3 * gb, SecondsRun: 7200,
+			InstalledAt:   date("2023-06-01T10:00:00Z"),
+			LastTouchedAt: date("2024-03-15T20:30:00.123456Z"),
+		},
+		{
+			ID: "deluxe", GameID: 2, InstalledSize: 2 * gb, SecondsRun: 60, Pinned: true,
+			InstalledAt:   date("2023-12-31T23:00:00Z"),
+			LastTouchedAt: date("2024-01-01T12:00:00Z"),
+		},
+		{
+			ID: "editor", GameID: 3, InstalledSize: 50 * 1024 * 1024,
+			InstalledAt:       date("2024-02-01T00:00:00Z"),
+			InstallLocationID: "external",
+			SnoozedAt:         date("2024-02-02T00:00:00Z"),
+		},
+		{
+			ID: "tiny", GameID: 4, InstalledSize: 512 * 1024 * 1024,
+			InstalledAt: date("2024-05-01T00:00:00Z"),
+		},
+	}
+	for _, c := range caves {
+		save(c)
+	}
+	return conn
+}
+
+func filter(t *testing.T, conn *sqlite.Conn, expression string) []string {
+	cond, err := cavefilter.Parse(expression)
+	must(t, err)
+
+	var caves []*models.Cave
+	models.MustSelect(conn, &caves, cond, hades.Search{}.InnerJoin("games", "games.id = caves.game_id"))
+
+	ids := []string{}
+	for _, c := range caves {
+		ids = append(ids, c.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func Test_Filter(t *testing.T) {
+	conn := prepareCaves(t)
+	defer conn.Close()
+
+	cases := map[string][]string{
+		`gameTitle contains "dungeon"`: {"crawler", "deluxe", "tiny"},
+		`gameTitle contains "dungeon" AND size > 1gb AND lastPlayed after 2024-01-01`: {"crawler"},
+		`gameTitle = "tiny dungeon"`:                              {"tiny"},
+		`gameTitle contains "\"deluxe\""`:                         {"deluxe"},
+		`gameTitle != "tiny dungeon" AND classification = "game"`: {"crawler", "deluxe"},
+		`size >= 2gb`:                     {"crawler", "deluxe"},
+		`size < 0.5GB`:                    {"editor"},
+		`size <= 512mb`:                   {"editor", "tiny"},
+		`playTime > 1h`:                   {"crawler"},
+		`playTime = 60`:                   {"deluxe"},
+		`playTime >= 1m`:                  {"crawler", "deluxe"},
+		`gameId = 3`:                      {"editor"},
+		`lastPlayed = 2024-01-01`:         {"deluxe"},
+		`lastPlayed before 2024-01-01`:    {},
+		`NOT lastPlayed after 2024-01-01`: {"deluxe", "editor", "tiny"},
+		`installedAt before 2024-01-01`:   {"crawler", "deluxe"},
+		`installedAt after 2024-02-01`:    {"tiny"},
+		`pinned`:                          {"deluxe"},
+		`NOT pinned`:                      {"crawler", "editor", "tiny"},
+		`pinned = false`:                  {"crawler", "editor", "tiny"},
+		`snoozed`:                         {"editor"},
+		`snoozed != true`:                 {"crawler", "deluxe", "tiny"},
+		`installLocation = "external"`:    {"editor"},
+		`pinned OR snoozed AND classification = "tool"`:   {"deluxe", "editor"},
+		`(pinned OR snoozed) AND classification = "tool"`: {"editor"},
+		`NOT (pinned OR snoozed)`:                         {"crawler", "tiny"},
+		`NOT NOT pinned`:                                  {"deluxe"},
+		`gametitle CONTAINS "sprite" or PLAYTIME > 1d`:    {"editor"},
+	}
+
+	for expression, expected := range cases {
+		assert.EqualValues(t, expected, filter(t, conn, expression), "for %s", expression)
+	}
+}
+
+func Test_FilterErrors(t *testing.T) {
+	cases := map[string]int{
+		``:                               0,
+		`   `:                            0,
+		`hidden`:                         0,
+		`NOT hidden`:                     4,
+		`size > 1zb`:                     7,
+		`size contains 3`:                5,
+		`gameTitle > "a"`:                10,
+		`gameTitle contains 3`:           19,
+		`lastPlayed after "yesterday"`:   17,
+		`lastPlayed after 2024-13-01`:    17,
+		`pinned = maybe`:                 9,
+		`(pinned OR snoozed`:             18,
+		`pinned snoozed`:                 7,
+		`gameTitle contains "unfinished`: 19,
+		`size ! 3`:                       5,
+		`size > 3 AND`:                   12,
+		`gameTitle`:                      9,
+		`size > 3 @`:                     9,
+	}
+
+	for expression, pos := range cases {
+		_, err := cavefilter.Parse(expression)
+		if assert.Error(t, err, "for %s", expression) {
+			se, ok := err.(*cavefilter.SyntaxError)
+			if assert.True(t, ok, "for %s: %+v", expression, err) {
+				assert.EqualValues(t, pos, se.Pos, "for %s: %s", expression, se.Msg)
+				assert.EqualValues(t, butlerd.CodeInvalidFilter, se.RpcErrorCode())
+			}
+		}
+	}
+}
+
+func must(t *testing.T, err error) {
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package cavefilter
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"xorm.io/builder"
+)
+
+type fieldKind int
+
+const (
+	kindText fieldKind = iota
+	kindNumber
+	kindSize
+	kindDuration
+	kindDate
+	kindBool
+)
+
+type field struct {
+	name string
+	kind fieldKind
+	// SQL expression for the field's value
+	column string
+}
+
+var fields = []*field{
+	{name: "gameTitle", kind: kindText, column: "games.title"},
+	{name: "classification", kind: kindText, column: "games.classification"},
+	{name: "installLocation", kind: kindText, column: "caves.install_location_id"},
+	{name: "gameId", kind: kindNumber, column: "caves.game_id"},
+	{name: "size", kind: kindSize, column: "caves.installed_size"},
+	{name: "playTime", kind: kindDuration, column: "caves.seconds_run"},
+	{name: "lastPlayed", kind: kindDate, column: "caves.last_touched_at"},
+	{name: "installedAt", kind: kindDate, column: "caves.installed_at"},
+	{name: "pinned", kind: kindBool, column: "caves.pinned"},
+	{name: "snoozed", kind: kindBool, column: "(caves.snoozed_at IS NOT NULL)"},
+}
+
+func lookupField(name string) *field {
+	for _, f := range fields {
+		if strings.EqualFold(f.name, name) {
+			return f
+		}
+	}
+	return nil
+}
+
+func fieldNames() []string {
+	var names []string
+	for _, f := range fields {
+		names = append(names, f.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var sizeUnits = map[string]float64{
+	"":   1,
+	"b":  1,
+	"kb": 1024,
+	"mb": 1024 * 1024,
+	"gb": 1024 * 1024 * 1024,
+	"tb": 1024 * 1024 * 1024 * 1024,
+}
+
+var durationUnits = map[string]float64{
+	"":  1,
+	"s": 1,
+	"m": 60,
+	"h": 60 * 60,
+	"d": 24 * 60 * 60,
+}
+
+var numericOperators = map[string]bool{
+	"=": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true,
+}
+
+func (f *field) flag(value bool) builder.Cond {
+	if value {
+		return builder.Expr(f.column)
+	}
+	return builder.Expr("NOT " + f.column)
+}
+
+func (f *field) compare(op token, value token) (builder.Cond, error) {
+	opText := strings.ToLower(op.text)
+	unsupported := func() error {
+		return errorf(op.pos, "operator '%s' can't be used with %s", op.text, f.name)
+	}
+
+	switch f.kind {
+	case kindText:
+		if value.kind != tokenString {
+			return nil, errorf(value.pos, "expected string after '%s %s', got %s", f.name, op.text, value)
+		}
+		switch opText {
+		case "=":
+			return builder.Expr("lower("+f.column+") = lower(?)", value.text), nil
+		case "!=":
+			return builder.Expr("lower("+f.column+") != lower(?)", value.text), nil
+		case "contains":
+			return builder.Expr("instr(lower("+f.column+"), lower(?)) > 0", value.text), nil
+		}
+		return nil, unsupported()
+
+	case kindNumber, kindSize, kindDuration:
+		if !numericOperators[opText] {
+			return nil, unsupported()
+		}
+		n, err := f.parseNumber(value)
+		if err != nil {
+			return nil, err
+		}
+		return builder.Expr(f.column+" "+opText+" ?", n), nil
+
+	case kindDate:
+		if value.kind != tokenDate {
+			return nil, errorf(value.pos, "expected date (YYYY-MM-DD) after '%s %s', got %s", f.name, op.text, value)
+		}
+		day, err := time.Parse("2006-01-02", value.text)
+		if err != nil {
+			return nil, errorf(value.pos, "invalid date '%s', expected YYYY-MM-DD", value.text)
+		}
+		start := day.Format(time.RFC3339)
+		end := day.AddDate(0, 0, 1).Format(time.RFC3339)
+		col := "julianday(" + f.column + ")"
+
+		switch opText {
+		case "before":
+			return builder.Expr(col+" < julianday(?)", start), nil
+		case "after":
+			return builder.Expr(col+" >= julianday(?)", end), nil
+		case "=":
+			return builder.Expr(col+" >= julianday(?) AND "+col+" < julianday(?)", start, end), nil
+		}
+		return nil, unsupported()
+
+	case kindBool:
+		var b bool
+		switch {
+		case value.is("true"):
+			b = true
+		case value.is("false"):
+			b = false
+		default:
+			return nil, errorf(value.pos, "expected true or false after '%s %s', got %s", f.name, op.text, value)
+		}
+		switch opText {
+		case "=":
+			return f.flag(b), nil
+		case "!=":
+			return f.flag(!b), nil
+		}
+		return nil, unsupported()
+	}
+
+	return nil, unsupported()
+}
+
+func (f *field) parseNumber(value token) (int64, error) {
+	if value.kind != tokenNumber {
+		return 0, errorf(value.pos, "expected number after '%s', got %s", f.name, value)
+	}
+
+	i := strings.IndexFunc(value.text, func(r rune) bool {
+		return !(r >= '0' && r <= '9') && r != '.'
+	})
+	digits, unit := value.text, ""
+	if i >= 0 {
+		digits, unit = value.text[:i], strings.ToLower(value.text[i:])
+	}
+
+	var units map[string]float64
+	switch f.kind {
+	case kindSize:
+		units = sizeUnits
+	case kindDuration:
+		units = durationUnits
+	default:
+		units = map[string]float64{"": 1}
+	}
+	multiplier, ok := units[unit]
+	if !ok {
+		return 0, errorf(value.pos, "unknown unit '%s' for %s", unit, f.name)
+	}
+
+	n, err := strconv.ParseFloat(digits, 64)
+	if err != nil {
+		return 0, errorf(value.pos, "invalid number '%s'", digits)
+	}
+	return int64(math.Round(n * multiplier)), nil
+}
@@ -0,0 +1,191 @@
+package cavefilter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/itchio/butler/butlerd"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	// field names, keywords, operators spelled as words, true/false
+	tokenIdent
+	// "double-quoted", with \" and \\ escapes
+	tokenString
+	// 42, 1.5, 1gb, 2h - the unit, if any, is kept in the token text
+	tokenNumber
+	// 2024-01-31
+	tokenDate
+	tokenLParen
+	tokenRParen
+	// = != < <= > >=
+	tokenOperator
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokenEOF:
+		return "end of expression"
+	case tokenIdent:
+		return "identifier"
+	case tokenString:
+		return "string"
+	case tokenNumber:
+		return "number"
+	case tokenDate:
+		return "date"
+	case tokenLParen:
+		return "'('"
+	case tokenRParen:
+		return "')'"
+	case tokenOperator:
+		return "operator"
+	}
+	return "unknown token"
+}
+
+type token struct {
+	kind tokenKind
+	text string
+	// offset of the first character of the token in the expression
+	pos int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF, tokenLParen, tokenRParen:
+		return t.kind.String()
+	case tokenString:
+		return fmt.Sprintf("string %q", t.text)
+	}
+	return fmt.Sprintf("%s '%s'", t.kind, t.text)
+}
+
+// is returns true if t is the given keyword, case-insensitively
+func (t token) is(keyword string) bool {
+	return t.kind == tokenIdent && strings.EqualFold(t.text, keyword)
+}
+
+// SyntaxError is returned when an expression can't be parsed,
+// or refers to unknown fields.
+type SyntaxError struct {
+	// Offset in the expression where the problem was found
+	Pos int
+	Msg string
+}
+
+var _ butlerd.Error = (*SyntaxError)(nil)
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid filter at position %d: %s", e.Pos, e.Msg)
+}
+
+func (e *SyntaxError) RpcErrorCode() int64 {
+	return int64(butlerd.CodeInvalidFilter)
+}
+
+func (e *SyntaxError) RpcErrorMessage() string {
+	return fmt.Sprintf("%s (at position %d: %s)", butlerd.CodeInvalidFilter.RpcErrorMessage(), e.Pos, e.Msg)
+}
+
+func (e *SyntaxError) RpcErrorData() map[string]interface{} {
+	return map[string]interface{}{
+		"position": e.Pos,
+		"message":  e.Msg,
+	}
+}
+
+func errorf(pos int, format string, args ...interface{}) error {
+	return &SyntaxError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+	i := 0
+
+	for i < len(runes) {
+		r := runes[i]
+		start := i
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: start})
+			i++
+
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: start})
+			i++
+
+		case r == '=':
+			tokens = append(tokens, token{kind: tokenOperator, text: "=", pos: start})
+			i++
+
+		case r == '!' || r == '<' || r == '>':
+			i++
+			if i < len(runes) && runes[i] == '=' {
+				i++
+			} else if r == '!' {
+				return nil, errorf(start, "expected '=' after '!'")
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: string(runes[start:i]), pos: start})
+
+		case r == '"':
+			var sb strings.Builder
+			i++
+			closed := false
+			for i < len(runes) {
+				c := runes[i]
+				i++
+				if c == '"' {
+					closed = true
+					break
+				}
+				if c == '\\' && i < len(runes) {
+					c = runes[i]
+					i++
+				}
+				sb.WriteRune(c)
+			}
+			if !closed {
+				return nil, errorf(start, "unterminated string")
+			}
+			tokens = append(tokens, token{kind: tokenString, text: sb.String(), pos: start})
+
+		case unicode.IsDigit(r):
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == '-') {
+				i++
+			}
+			text := string(runes[start:i])
+			if strings.Contains(text, "-") {
+				tokens = append(tokens, token{kind: tokenDate, text: text, pos: start})
+				continue
+			}
+			// unit suffix
+			for i < len(runes) && unicode.IsLetter(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i]), pos: start})
+
+		case unicode.IsLetter(r) || r == '_':
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[start:i]), pos: start})
+
+		default:
+			return nil, errorf(start, "unexpected character %q", r)
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(runes)})
+	return tokens, nil
+}
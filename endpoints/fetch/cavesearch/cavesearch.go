@@ -0,0 +1,184 @@
+// Package cavesearch finds games by approximate title, so that
+// "dungen" finds "Dungeon Crawler".
+//
+// Titles and queries are compared word by word: a title matches if some
+// run of consecutive words in it is within a given edit distance
+// (Levenshtein, case-insensitive) of the query. Punctuation is ignored.
+//
+// To avoid computing edit distances against every title, titles are kept
+// in a trigram index. A string within edit distance d of the query shares
+// at least (number of query trigrams - 3d) trigrams with it, since every
+// edit breaks at most 3 of them, which rules out most titles right away.
+package cavesearch
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/arbovm/levenshtein"
+)
+
+// An Index holds titles to search through.
+type Index struct {
+	entries []entry
+	grams   map[string][]int
+}
+
+type entry struct {
+	id         string
+	title      string
+	normalized string
+	words      []string
+}
+
+// A Match is a title found by Search
+type Match struct {
+	ID    string
+	Title string
+
+	// Edit distance between the query and the closest part of the title
+	Distance int
+}
+
+// NewIndex returns an empty index
+func NewIndex() *Index {
+	return &Index{
+		grams: make(map[string][]int),
+	}
+}
+
+// Add indexes title, to be returned as id by Search
+func (ix *Index) Add(id string, title string) {
+	normalized := normalize(title)
+	index := len(ix.entries)
+	ix.entries = append(ix.entries, entry{
+		id:         id,
+		title:      title,
+		normalized: normalized,
+		words:      strings.Fields(normalized),
+	})
+	for gram := range trigramSet(normalized) {
+		ix.grams[gram] = append(ix.grams[gram], index)
+	}
+}
+
+// Search returns at most limit titles within maxDistance edits of
+// query, closest first. Titles at the same distance are sorted
+// alphabetically.
+func (ix *Index) Search(query string, maxDistance int, limit int) []Match {
+	query = normalize(query)
+	if query == "" {
+		return nil
+	}
+
+	var matches []Match
+	for _, index := range ix.candidates(query, maxDistance) {
+		e := ix.entries[index]
+		d := distance(query, e.normalized, e.words)
+		if d <= maxDistance {
+			matches = append(matches, Match{
+				ID:       e.id,
+				Title:    e.title,
+				Distance: d,
+			})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Distance != b.Distance {
+			return a.Distance < b.Distance
+		}
+		return strings.ToLower(a.Title) < strings.ToLower(b.Title)
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// candidates returns the indices of entries that share enough
+// trigrams with query to possibly be within maxDistance of it.
+func (ix *Index) candidates(query string, maxDistance int) []int {
+	queryGrams := trigrams(query)
+	needed := len(queryGrams) - 3*maxDistance
+	if needed <= 0 {
+		// too few trigrams to tell anything, check everything
+		all := make([]int, len(ix.entries))
+		for i := range all {
+			all[i] = i
+		}
+		return all
+	}
+
+	shared := make(map[int]int)
+	for _, gram := range queryGrams {
+		for _, index := range ix.grams[gram] {
+			shared[index]++
+		}
+	}
+
+	var res []int
+	for index, count := range shared {
+		if count >= needed {
+			res = append(res, index)
+		}
+	}
+	sort.Ints(res)
+	return res
+}
+
+// Distance returns the edit distance between query and the run of
+// consecutive words of title that's closest to it.
+func Distance(query string, title string) int {
+	normalized := normalize(title)
+	return distance(normalize(query), normalized, strings.Fields(normalized))
+}
+
+func distance(query string, title string, words []string) int {
+	best := levenshtein.Distance(query, title)
+
+	// typos can merge or split words, so also try
+	// runs of one more or one less word than the query.
+	n := len(strings.Fields(query))
+	for size := n - 1; size <= n+1; size++ {
+		if size < 1 || size >= len(words) {
+			continue
+		}
+		for start := 0; start+size <= len(words); start++ {
+			d := levenshtein.Distance(query, strings.Join(words[start:start+size], " "))
+			if d < best {
+				best = d
+			}
+		}
+	}
+	return best
+}
+
+// normalize lowercases s, and turns anything that isn't a
+// letter or a digit into single spaces.
+func normalize(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// trigrams returns all the trigrams of s, duplicates included
+func trigrams(s string) []string {
+	runes := []rune(s)
+	var res []string
+	for i := 0; i+3 <= len(runes); i++ {
+		res = append(res, string(runes[i:i+3]))
+	}
+	return res
+}
+
+func trigramSet(s string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, gram := range trigrams(s) {
+		set[gram] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,63 @@
+package cavesearch_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/itchio/butler/endpoints/fetch/cavesearch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Distance(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.EqualValues(0, cavesearch.Distance("dungeon", "Dungeon Crawler"))
+	assert.EqualValues(1, cavesearch.Distance("dungen", "Dungeon Crawler"))
+	assert.EqualValues(1, cavesearch.Distance("dungeon crawlers", "Dungeon: Crawler"), "punctuation doesn't count")
+	assert.EqualValues(1, cavesearch.Distance("dungeoncrawler", "Dungeon Crawler"), "words may be merged")
+	assert.EqualValues(0, cavesearch.Distance("star", "Overland, Star Edition"))
+}
+
+func titles(matches []cavesearch.Match) []string {
+	var res []string
+	for _, m := range matches {
+		res = append(res, m.Title)
+	}
+	return res
+}
+
+func Test_Search(t *testing.T) {
+	assert := assert.New(t)
+
+	ix := cavesearch.NewIndex()
+	ix.Add("a", "Dungeon Crawler")
+	ix.Add("b", "Dungeons of Dredmor")
+	ix.Add("c", "Super Hexagon")
+	ix.Add("d", "A Short Hike")
+	ix.Add("e", "dungeon")
+
+	matches := ix.Search("dungen", 1, 20)
+	assert.EqualValues([]string{"dungeon", "Dungeon Crawler"}, titles(matches), "closest first, then by title")
+	assert.EqualValues("e", matches[0].ID)
+	assert.EqualValues(1, matches[0].Distance)
+
+	assert.EqualValues([]string{"dungeon", "Dungeon Crawler", "Dungeons of Dredmor"}, titles(ix.Search("DUNGEN", 2, 20)))
+	assert.EqualValues([]string{"Super Hexagon"}, titles(ix.Search("hexagn", 2, 20)))
+	assert.EqualValues([]string{"A Short Hike"}, titles(ix.Search("hike", 0, 20)))
+	assert.Len(ix.Search("zzz", 1, 20), 0)
+	assert.Len(ix.Search("  ", 2, 20), 0)
+
+	// short queries don't have enough trigrams to filter on
+	assert.EqualValues([]string{"Super Hexagon"}, titles(ix.Search("supr", 1, 20)))
+}
+
+func Test_SearchLimit(t *testing.T) {
+	ix := cavesearch.NewIndex()
+	for i := 0; i < 30; i++ {
+		ix.Add(fmt.Sprintf("%d", i), fmt.Sprintf("Dungeon %02d", i))
+	}
+
+	matches := ix.Search("dungeon", 0, 20)
+	assert.Len(t, matches, 20)
+	assert.EqualValues(t, "Dungeon 00", matches[0].Title)
+}
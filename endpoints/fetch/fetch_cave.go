@@ -3,6 +3,7 @@ package fetch
 import (
 	"crawshaw.io/sqlite"
 	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
 	"github.com/itchio/butler/database/models"
 )
 
@@ -18,11 +19,22 @@ func FetchCave(rc *butlerd.RequestContext, params butlerd.FetchCaveParams) (*but
 	return res, nil
 }
 
+// CaveAutoUpdatePolicy returns the policy of cave, which
+// is butlerd.CaveAutoUpdatePolicyAlways unless set.
+func CaveAutoUpdatePolicy(cave *models.Cave) butlerd.CaveAutoUpdatePolicy {
+	if cave.AutoUpdatePolicy == "" {
+		return butlerd.CaveAutoUpdatePolicyAlways
+	}
+	return butlerd.CaveAutoUpdatePolicy(cave.AutoUpdatePolicy)
+}
+
 func FormatCave(conn *sqlite.Conn, cave *models.Cave) *butlerd.Cave {
 	if cave == nil {
 		return nil
 	}
 
+	installFolder := cave.GetInstallFolder(conn)
+
 	return &butlerd.Cave{
 		ID: cave.ID,
 
@@ -31,10 +43,22 @@ func FormatCave(conn *sqlite.Conn, cave *models.Cave) *butlerd.Cave {
 		Build:  cave.Build,
 
 		InstallInfo: &butlerd.CaveInstallInfo{
-			InstallFolder:   cave.GetInstallFolder(conn),
-			InstalledSize:   cave.InstalledSize,
-			InstallLocation: cave.InstallLocationID,
-			Pinned:          cave.Pinned,
+			InstallFolder:    installFolder,
+			InstalledSize:    cave.InstalledSize,
+			InstallLocation:  cave.InstallLocationID,
+			Pinned:           cave.Pinned,
+			GameDelisted:     cave.GameDelisted,
+			PrereqsSkipped:   cave.PrereqsSkipped,
+			IgnoreInstallers: cave.IgnoreInstallers,
+			SourceProfileID:  cave.SourceProfileID,
+
+			AutoUpdatePolicy:       CaveAutoUpdatePolicy(cave),
+			VirtualMachineRequired: butlerd.VirtualMachineType(cave.VirtualMachineRequired),
+			LocationUsage:          caveLocationUsage(cave, installFolder),
+			PreferredLaunchTarget:  cave.PreferredLaunchTarget,
+			DLCParentCaveID:        cave.DLCParentCaveID,
+			PlayableEarly:          cave.PlayableEarly,
+			RemainingBytes:         operate.PlayEarlyRemainingBytes(cave),
 		},
 
 		Stats: &butlerd.CaveStats{
@@ -44,3 +68,26 @@ func FormatCave(conn *sqlite.Conn, cave *models.Cave) *butlerd.Cave {
 		},
 	}
 }
+
+// caveLocationUsage returns how much of cave is in each of its install
+// locations, if it's split across two, or nil otherwise.
+func caveLocationUsage(cave *models.Cave, installFolder string) []*butlerd.CaveLocationUsage {
+	if cave.OverflowLocationID == "" {
+		return nil
+	}
+
+	om, err := operate.ReadOverflowMap(installFolder)
+	if err != nil || om == nil {
+		return nil
+	}
+
+	overflowBytes := om.Usage()
+	primaryBytes := cave.InstalledSize - overflowBytes
+	if primaryBytes < 0 {
+		primaryBytes = 0
+	}
+	return []*butlerd.CaveLocationUsage{
+		{InstallLocationID: cave.InstallLocationID, Bytes: primaryBytes},
+		{InstallLocationID: om.LocationID, Bytes: overflowBytes},
+	}
+}
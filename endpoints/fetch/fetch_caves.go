@@ -1,6 +1,8 @@
 package fetch
 
 import (
+	"time"
+
 	"crawshaw.io/sqlite"
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/database/models"
@@ -10,61 +12,108 @@ import (
 )
 
 func FetchCaves(rc *butlerd.RequestContext, params butlerd.FetchCavesParams) (*butlerd.FetchCavesResult, error) {
-	res := &butlerd.FetchCavesResult{}
-
-	rc.WithConn(func(conn *sqlite.Conn) {
-		var cond = builder.NewCond()
-		joinGames := false
-		search := hades.Search{}
-
-		switch params.SortBy {
-		case "title":
-			ordering := pager.Ordering("ASC", params.Reverse)
-			search = search.OrderBy("lower(games.title) " + ordering)
-			joinGames = true
-		case "playTime":
-			ordering := pager.Ordering("DESC", params.Reverse)
-			search = search.OrderBy("caves.seconds_run " + ordering)
-		case "installedAt":
-			ordering := pager.Ordering("DESC", params.Reverse)
-			search = search.OrderBy("caves.installed_at " + ordering)
-		case "installedSize":
-			ordering := pager.Ordering("DESC", params.Reverse)
-			search = search.OrderBy("caves.installed_size " + ordering)
-		case "lastTouched", "":
-			ordering := pager.Ordering("DESC", params.Reverse)
-			search = search.OrderBy("coalesce(caves.last_touched_at, caves.installed_at) " + ordering)
+	cond, search := cavesQuery(params)
+	fetchPage := func(conn *sqlite.Conn, pg pager.Pager) ([]*butlerd.Cave, butlerd.Cursor) {
+		var items []*models.Cave
+		nextCursor := pg.Fetch(conn, &items, cond, search)
+		models.PreloadCaves(conn, items)
+		var caves []*butlerd.Cave
+		for _, cave := range items {
+			caves = append(caves, FormatCave(conn, cave))
 		}
+		return caves, nextCursor
+	}
 
-		if params.Filters.Classification != "" {
-			cond = builder.And(cond, builder.Eq{"games.classification": params.Filters.Classification})
-			joinGames = true
+	if params.Stream {
+		summary, nextCursor, err := rc.StreamResults(params.Cursor, params.Limit, params.ChunkSize, func(cursor butlerd.Cursor, limit int64) ([]interface{}, butlerd.Cursor, error) {
+			var items []interface{}
+			var next butlerd.Cursor
+			rc.WithConn(func(conn *sqlite.Conn) {
+				var caves []*butlerd.Cave
+				caves, next = fetchPage(conn, pager.New(pager.Page{Limit: limit, Cursor: cursor}))
+				for _, cave := range caves {
+					items = append(items, cave)
+				}
+			})
+			return items, next, nil
+		})
+		if err != nil {
+			return nil, err
 		}
-
-		if params.Filters.InstallLocationID != "" {
-			cond = builder.And(cond, builder.Eq{"caves.install_location_id": params.Filters.InstallLocationID})
+		res := &butlerd.FetchCavesResult{
+			Items:      []*butlerd.Cave{},
+			NextCursor: nextCursor,
+			Stream:     summary,
 		}
+		return res, nil
+	}
 
-		if params.Filters.GameID != 0 {
-			cond = builder.And(cond, builder.Eq{"caves.game_id": params.Filters.GameID})
-		}
+	res := &butlerd.FetchCavesResult{}
+	rc.WithConn(func(conn *sqlite.Conn) {
+		res.Items, res.NextCursor = fetchPage(conn, pager.New(params))
+	})
+	return res, nil
+}
 
-		if params.Search != "" {
-			cond = builder.And(cond, builder.Like{"games.title", params.Search})
-			joinGames = true
-		}
+// cavesQuery returns what to select caves with, for params
+func cavesQuery(params butlerd.FetchCavesParams) (builder.Cond, hades.Search) {
+	var cond = builder.NewCond()
+	joinGames := false
+	search := hades.Search{}
 
-		if joinGames {
-			search = search.InnerJoin("games", "games.id = caves.game_id")
-		}
+	switch params.SortBy {
+	case "title":
+		ordering := pager.Ordering("ASC", params.Reverse)
+		search = search.OrderBy("lower(games.title) " + ordering)
+		joinGames = true
+	case "playTime":
+		ordering := pager.Ordering("DESC", params.Reverse)
+		search = search.OrderBy("caves.seconds_run " + ordering)
+	case "installedAt", "":
+		ordering := pager.Ordering("DESC", params.Reverse)
+		search = search.OrderBy("caves.installed_at " + ordering)
+	case "installedSize":
+		ordering := pager.Ordering("DESC", params.Reverse)
+		search = search.OrderBy("caves.installed_size " + ordering)
+	case "lastTouched":
+		ordering := pager.Ordering("DESC", params.Reverse)
+		search = search.OrderBy("coalesce(caves.last_touched_at, caves.installed_at) " + ordering)
+	}
 
-		var items []*models.Cave
-		pg := pager.New(params)
-		res.NextCursor = pg.Fetch(conn, &items, cond, search)
-		models.PreloadCaves(conn, items)
-		for _, cave := range items {
-			res.Items = append(res.Items, FormatCave(conn, cave))
-		}
-	})
-	return res, nil
+	if params.Filters.Classification != "" {
+		cond = builder.And(cond, builder.Eq{"games.classification": params.Filters.Classification})
+		joinGames = true
+	}
+
+	if params.Filters.InstallLocationID != "" {
+		cond = builder.And(cond, builder.Eq{"caves.install_location_id": params.Filters.InstallLocationID})
+	}
+
+	if params.Filters.GameID != 0 {
+		cond = builder.And(cond, builder.Eq{"caves.game_id": params.Filters.GameID})
+	}
+
+	if params.Filters.UpdatedWithinDays > 0 {
+		since := time.Now().UTC().Add(-time.Duration(params.Filters.UpdatedWithinDays) * 24 * time.Hour)
+		cond = builder.And(cond, builder.Gt{"caves.installed_at": since.Format(time.RFC3339Nano)})
+	}
+
+	if params.Filters.NeverUpdated {
+		cond = builder.And(cond, builder.Eq{"caves.install_count": 1})
+	}
+
+	if params.Filters.VirtualMachineRequired != "" {
+		cond = builder.And(cond, builder.Eq{"caves.virtual_machine_required": params.Filters.VirtualMachineRequired})
+	}
+
+	if params.Search != "" {
+		cond = builder.And(cond, builder.Like{"games.title", params.Search})
+		joinGames = true
+	}
+
+	if joinGames {
+		search = search.InnerJoin("games", "games.id = caves.game_id")
+	}
+
+	return cond, search
 }
@@ -0,0 +1,59 @@
+package fetch
+
+import (
+	"testing"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/headway/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CavesQueryUpdatedWithinDays(t *testing.T) {
+	assert := assert.New(t)
+
+	conn, err := sqlite.OpenConn("file:fetch_caves_test?mode=memory", 0)
+	must(t, err)
+	defer conn.Close()
+	must(t, database.Prepare(&state.Consumer{}, conn, true))
+
+	now := time.Now().UTC()
+	save := func(id string, installedAt time.Time) {
+		must(t, models.Save(conn, &models.Cave{
+			ID:           id,
+			GameID:       1,
+			InstalledAt:  &installedAt,
+			InstallCount: 2,
+		}))
+	}
+	save("yesterday", now.Add(-24*time.Hour))
+	save("last-week", now.Add(-6*24*time.Hour))
+	save("last-month", now.Add(-30*24*time.Hour))
+
+	fetchIDs := func(days int64) []string {
+		cond, search := cavesQuery(butlerd.FetchCavesParams{
+			SortBy:  "installedAt",
+			Filters: butlerd.CavesFilters{UpdatedWithinDays: days},
+		})
+		var caves []*models.Cave
+		must(t, models.Select(conn, &caves, cond, search))
+		var ids []string
+		for _, cave := range caves {
+			ids = append(ids, cave.ID)
+		}
+		return ids
+	}
+
+	assert.EqualValues([]string{"yesterday", "last-week"}, fetchIDs(7))
+	assert.EqualValues([]string{"yesterday"}, fetchIDs(2))
+	assert.EqualValues([]string{"yesterday", "last-week", "last-month"}, fetchIDs(0), "no filter")
+}
+
+func must(t *testing.T, err error) {
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
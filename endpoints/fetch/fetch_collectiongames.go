@@ -6,6 +6,7 @@ import (
 	"github.com/itchio/butler/database/models"
 	"github.com/itchio/butler/endpoints/fetch/lazyfetch"
 	"github.com/itchio/butler/endpoints/fetch/pager"
+	"github.com/itchio/butler/mansion/ratelimit"
 	itchio "github.com/itchio/go-itchio"
 	"github.com/itchio/hades"
 	"github.com/pkg/errors"
@@ -22,11 +23,15 @@ func LazyFetchCollectionGames(rc *butlerd.RequestContext, params lazyfetch.Profi
 		}
 		var collectionGames []*itchio.CollectionGame
 
+		// syncing a large collection takes many calls, let
+		// interactive API calls go first
+		ctx := ratelimit.WithClass(rc.Ctx, ratelimit.ClassBackground)
+
 		var offset int64
 		for page := int64(1); ; page++ {
 			rc.Consumer.Infof("Fetching page %d (of unknown)", page)
 
-			gamesRes, err := client.GetCollectionGames(rc.Ctx, itchio.GetCollectionGamesParams{
+			gamesRes, err := client.GetCollectionGames(ctx, itchio.GetCollectionGamesParams{
 				CollectionID: collectionID,
 				Page:         page,
 			})
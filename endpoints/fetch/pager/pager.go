@@ -18,6 +18,21 @@ type PagedRequest interface {
 	GetCursor() butlerd.Cursor
 }
 
+// Page is a PagedRequest for callers that go through
+// results page by page themselves, like streamed ones
+type Page struct {
+	Limit  int64
+	Cursor butlerd.Cursor
+}
+
+func (p Page) GetLimit() int64 {
+	return p.Limit
+}
+
+func (p Page) GetCursor() butlerd.Cursor {
+	return p.Cursor
+}
+
 type Pager interface {
 	Fetch(conn *sqlite.Conn, result interface{}, cond builder.Cond, search hades.Search) butlerd.Cursor
 }
@@ -0,0 +1,174 @@
+package install
+
+import (
+	"fmt"
+	"sync"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/headway/state"
+	"xorm.io/builder"
+)
+
+// queueKeys serializes the checks installQueue calls that could queue
+// the same download make, and their queuing it, so that the second one
+// finds the first one's download instead of queuing another.
+var queueKeys = struct {
+	sync.Mutex
+	byKey map[string]*queueKey
+}{
+	byKey: make(map[string]*queueKey),
+}
+
+// queueKey is what installQueue calls with the same alreadyQueuedKey
+// share, for as long as any of them runs.
+type queueKey struct {
+	sync.Mutex
+	key  string
+	refs int
+	// the staging folder the last of them readied an install in,
+	// in case its client queues the download itself
+	readied string
+}
+
+// acquireQueueKey returns the queueKey of queueParams, see alreadyQueuedKey,
+// or nil if it shouldn't be compared to downloads in progress. It must be
+// given back with releaseQueueKey.
+func acquireQueueKey(queueParams butlerd.InstallQueueParams) *queueKey {
+	key := alreadyQueuedKey(queueParams)
+	if key == "" {
+		return nil
+	}
+
+	queueKeys.Lock()
+	defer queueKeys.Unlock()
+	k := queueKeys.byKey[key]
+	if k == nil {
+		k = &queueKey{key: key}
+		queueKeys.byKey[key] = k
+	}
+	k.refs++
+	return k
+}
+
+func releaseQueueKey(k *queueKey) {
+	if k == nil {
+		return
+	}
+
+	queueKeys.Lock()
+	defer queueKeys.Unlock()
+	k.refs--
+	if k.refs == 0 {
+		delete(queueKeys.byKey, k.key)
+	}
+}
+
+// alreadyQueuedKey returns what installQueue calls that could queue the
+// same download have in common, or an empty string if queueParams
+// shouldn't be compared to downloads in progress. It's only the cave or
+// the game: calls that don't specify the upload or build have to wait
+// for those that do, since they may well end up picking the same ones.
+func alreadyQueuedKey(queueParams butlerd.InstallQueueParams) string {
+	if queueParams.Force || queueParams.DryRun || queueParams.LocalArchivePath != "" {
+		return ""
+	}
+
+	if queueParams.CaveID != "" {
+		return fmt.Sprintf("cave:%s", queueParams.CaveID)
+	}
+	if queueParams.Game == nil {
+		return ""
+	}
+	return fmt.Sprintf("game:%d", queueParams.Game.ID)
+}
+
+// lockAlreadyQueued locks k, if it's not nil, and looks for a download in
+// progress queueParams would duplicate. The lock must be held until the
+// download is queued, if it is, so that other calls find it: unlock lets
+// go of it. It must not be held while waiting on the client or the
+// server, see installQueueThen. If a download was found, it's returned
+// as queued, and nothing else should be queued.
+func lockAlreadyQueued(rc *butlerd.RequestContext, k *queueKey, queueParams butlerd.InstallQueueParams) (unlock func(), queued *butlerd.InstallQueueResult) {
+	if k == nil {
+		return func() {}, nil
+	}
+	k.Lock()
+
+	var download *models.Download
+	rc.WithConn(func(conn *sqlite.Conn) {
+		download = findQueuedDownload(conn, queueParams)
+	})
+	if download != nil {
+		rc.Consumer.Infof("Download %s for %s is already in progress, not queuing another", download.ID, operate.GameToString(download.Game))
+		queued = alreadyQueuedResult(rc.Consumer, download)
+	}
+	return k.Unlock, queued
+}
+
+// findQueuedDownload returns the download in progress that
+// queueParams would duplicate, if any.
+func findQueuedDownload(conn *sqlite.Conn, queueParams butlerd.InstallQueueParams) *models.Download {
+	cond := builder.And(
+		builder.IsNull{"finished_at"},
+		builder.Not{builder.Expr("discarded")},
+	)
+	if queueParams.CaveID != "" {
+		cond = cond.And(builder.Eq{"cave_id": queueParams.CaveID})
+	} else {
+		// only fresh installs, other caves of the
+		// game may well be updating
+		cond = cond.And(
+			builder.Eq{"game_id": queueParams.Game.ID},
+			builder.Expr("not exists (select 1 from caves where caves.id = downloads.cave_id)"),
+		)
+	}
+	if queueParams.Upload != nil {
+		cond = cond.And(builder.Eq{"upload_id": queueParams.Upload.ID})
+	}
+	if queueParams.Build != nil {
+		cond = cond.And(builder.Eq{"build_id": queueParams.Build.ID})
+	}
+
+	var download models.Download
+	if !models.MustSelectOne(conn, &download, cond) {
+		return nil
+	}
+	download.Preload(conn)
+	return &download
+}
+
+// alreadyQueuedResult describes download like installQueue would
+// have when it was queued.
+func alreadyQueuedResult(consumer *state.Consumer, download *models.Download) *butlerd.InstallQueueResult {
+	res := &butlerd.InstallQueueResult{
+		ID:                download.ID,
+		Reason:            butlerd.DownloadReason(download.Reason),
+		CaveID:            download.CaveID,
+		Game:              download.Game,
+		Upload:            download.Upload,
+		Build:             download.Build,
+		InstallFolder:     download.InstallFolder,
+		StagingFolder:     download.StagingFolder,
+		InstallLocationID: download.InstallLocationID,
+		AlreadyQueued:     true,
+	}
+
+	if download.AccessExplanation != "" {
+		access := &butlerd.AccessExplanation{}
+		err := models.UnmarshalJSON(download.AccessExplanation, access)
+		if err == nil {
+			res.Access = access
+		}
+	}
+
+	meta, err := operate.ReadMeta(download.StagingFolder)
+	if err != nil {
+		consumer.Warnf("Could not read install params of download %s: %+v", download.ID, err)
+	} else {
+		res.InstallFolderName = meta.InstallFolderName
+	}
+	return res
+}
@@ -0,0 +1,10 @@
+package install
+
+import (
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+)
+
+func CaveVerify(rc *butlerd.RequestContext, params butlerd.CaveVerifyParams) (*butlerd.CaveVerifyResult, error) {
+	return operate.VerifyCave(rc, params.CaveID)
+}
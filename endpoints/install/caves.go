@@ -4,6 +4,12 @@ import (
 	"crawshaw.io/sqlite"
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/endpoints/fetch"
+	"github.com/itchio/butler/endpoints/fetch/cavefilter"
+	"github.com/itchio/butler/endpoints/fetch/cavesearch"
+	"github.com/itchio/hades"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
 )
 
 func CavesSetPinned(rc *butlerd.RequestContext, params butlerd.CavesSetPinnedParams) (*butlerd.CavesSetPinnedResult, error) {
@@ -15,3 +21,102 @@ func CavesSetPinned(rc *butlerd.RequestContext, params butlerd.CavesSetPinnedPar
 
 	return &butlerd.CavesSetPinnedResult{}, nil
 }
+
+func CavesSetAutoUpdatePolicy(rc *butlerd.RequestContext, params butlerd.CavesSetAutoUpdatePolicyParams) (*butlerd.CavesSetAutoUpdatePolicyResult, error) {
+	var found bool
+	rc.WithConn(func(conn *sqlite.Conn) {
+		if models.CaveByID(conn, params.CaveID) == nil {
+			return
+		}
+		found = true
+		models.MustUpdate(conn, &models.Cave{},
+			hades.Where(builder.Eq{"id": params.CaveID}),
+			builder.Eq{"auto_update_policy": string(params.Policy)},
+		)
+	})
+	if !found {
+		return nil, errors.Errorf("No such cave (%s)", params.CaveID)
+	}
+
+	return &butlerd.CavesSetAutoUpdatePolicyResult{}, nil
+}
+
+func CavesByProfile(rc *butlerd.RequestContext, params butlerd.CavesByProfileParams) (*butlerd.CavesByProfileResult, error) {
+	res := &butlerd.CavesByProfileResult{}
+	rc.WithConn(func(conn *sqlite.Conn) {
+		caves := models.CavesByProfileID(conn, params.ProfileID)
+		models.PreloadCaves(conn, caves)
+		for _, cave := range caves {
+			res.Caves = append(res.Caves, fetch.FormatCave(conn, cave))
+		}
+	})
+
+	return res, nil
+}
+
+func CavesGetDLCs(rc *butlerd.RequestContext, params butlerd.CavesGetDLCsParams) (*butlerd.CavesGetDLCsResult, error) {
+	res := &butlerd.CavesGetDLCsResult{
+		Caves: []*butlerd.Cave{},
+	}
+	rc.WithConn(func(conn *sqlite.Conn) {
+		caves := models.CavesByDLCParentCaveID(conn, params.CaveID)
+		models.PreloadCaves(conn, caves)
+		for _, cave := range caves {
+			res.Caves = append(res.Caves, fetch.FormatCave(conn, cave))
+		}
+	})
+
+	return res, nil
+}
+
+func CavesFilter(rc *butlerd.RequestContext, params butlerd.CavesFilterParams) (*butlerd.CavesFilterResult, error) {
+	cond, err := cavefilter.Parse(params.Expression)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	res := &butlerd.CavesFilterResult{
+		Caves: []*butlerd.Cave{},
+	}
+	rc.WithConn(func(conn *sqlite.Conn) {
+		var caves []*models.Cave
+		search := hades.Search{}.InnerJoin("games", "games.id = caves.game_id").OrderBy("lower(games.title) ASC")
+		models.MustSelect(conn, &caves, cond, search)
+		models.PreloadCaves(conn, caves)
+		for _, cave := range caves {
+			res.Caves = append(res.Caves, fetch.FormatCave(conn, cave))
+		}
+	})
+
+	return res, nil
+}
+
+const fuzzySearchMaxResults = 20
+
+func CavesFuzzySearch(rc *butlerd.RequestContext, params butlerd.CavesFuzzySearchParams) (*butlerd.CavesFuzzySearchResult, error) {
+	res := &butlerd.CavesFuzzySearchResult{
+		Results: []*butlerd.CaveFuzzyMatch{},
+	}
+	rc.WithConn(func(conn *sqlite.Conn) {
+		ix := cavesearch.NewIndex()
+		models.MustExecRaw(conn, `
+			SELECT caves.id, games.title
+			FROM caves
+			INNER JOIN games ON games.id = caves.game_id
+		`, func(stmt *sqlite.Stmt) error {
+			ix.Add(stmt.ColumnText(0), stmt.ColumnText(1))
+			return nil
+		})
+
+		for _, match := range ix.Search(params.Query, int(params.MaxDistance), fuzzySearchMaxResults) {
+			cave := models.CaveByID(conn, match.ID)
+			models.PreloadCaves(conn, cave)
+			res.Results = append(res.Results, &butlerd.CaveFuzzyMatch{
+				Cave:     fetch.FormatCave(conn, cave),
+				Distance: int64(match.Distance),
+			})
+		}
+	})
+
+	return res, nil
+}
@@ -0,0 +1,170 @@
+package install
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/google/uuid"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/manager/runlock"
+	"github.com/itchio/hades"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
+)
+
+func CavesApplyNamingTemplate(rc *butlerd.RequestContext, params butlerd.CavesApplyNamingTemplateParams) (*butlerd.CavesApplyNamingTemplateResult, error) {
+	consumer := rc.Consumer
+
+	var il *models.InstallLocation
+	var caves []*models.Cave
+	rc.WithConn(func(conn *sqlite.Conn) {
+		il = models.InstallLocationByID(conn, params.InstallLocationID)
+		if il == nil {
+			return
+		}
+
+		cond := builder.NewCond().And(builder.Eq{"install_location_id": il.ID})
+		if len(params.CaveIDs) > 0 {
+			cond = cond.And(builder.In("id", params.CaveIDs))
+		}
+		models.MustSelect(conn, &caves, cond, hades.Search{})
+		models.PreloadCaves(conn, caves)
+	})
+	if il == nil {
+		return nil, errors.Errorf("install location (%s) not found", params.InstallLocationID)
+	}
+	if il.FolderNameTemplate == "" {
+		return nil, errors.Errorf("install location (%s) has no folder name template", il.ID)
+	}
+	consumer.Infof("Applying folder name template (%s) to %d caves", il.FolderNameTemplate, len(caves))
+
+	res := &butlerd.CavesApplyNamingTemplateResult{
+		Renames: []*butlerd.CaveRename{},
+	}
+	for _, cave := range caves {
+		if cave.CustomInstallFolder != "" {
+			continue
+		}
+
+		rename := &butlerd.CaveRename{
+			CaveID:        cave.ID,
+			OldFolderName: cave.InstallFolderName,
+			NewFolderName: cave.InstallFolderName,
+		}
+		err := renameCaveFolder(rc, il, cave, rename)
+		if err != nil {
+			consumer.Warnf("Could not rename install folder of cave (%s): %+v", cave.ID, err)
+			rename.NewFolderName = rename.OldFolderName
+			rename.Error = err.Error()
+			if be, ok := butlerd.AsButlerdError(err); ok {
+				rename.ErrorCode = be.RpcErrorCode()
+				rename.Error = be.RpcErrorMessage()
+			}
+		}
+		res.Renames = append(res.Renames, rename)
+	}
+	return res, nil
+}
+
+func renameCaveFolder(rc *butlerd.RequestContext, il *models.InstallLocation, cave *models.Cave, rename *butlerd.CaveRename) error {
+	consumer := rc.Consumer
+
+	if cave.Upload == nil {
+		return errors.Errorf("cave (%s) has no upload", cave.ID)
+	}
+
+	name := renderFolderNameTemplate(il.FolderNameTemplate, cave.Game, cave.Upload, cave.Build, consumer)
+	var others []string
+	rc.WithConn(func(conn *sqlite.Conn) {
+		others = caveFolderNames(conn, il, cave.ID)
+	})
+	if parent := parentFolderName(others, name); parent != "" {
+		return errors.Errorf("install folder name (%s) is inside the install folder of another cave (%s)", name, parent)
+	}
+	// the cave's own folder doesn't count as taken, so that
+	// applying the same template twice doesn't move anything
+	name, err := uniqueFolderName(name, defaultUniqueFolderMaxTries, func(name string) bool {
+		if name == cave.InstallFolderName {
+			return false
+		}
+		if containsFolderName(others, name) {
+			return true
+		}
+		_, err := os.Stat(il.GetInstallFolder(name))
+		return err == nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "in (%s)", il.Path)
+	}
+	if name == cave.InstallFolderName {
+		return nil
+	}
+
+	var downloadsCount int64
+	rc.WithConn(func(conn *sqlite.Conn) {
+		downloadsCount = models.MustCount(conn, &models.Download{}, builder.And(
+			builder.IsNull{"finished_at"},
+			builder.Eq{"cave_id": cave.ID},
+		))
+	})
+	if downloadsCount > 0 {
+		return errors.Errorf("cave (%s) has a download in progress", cave.ID)
+	}
+
+	eventID, err := uuid.NewRandom()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	oldFolder := il.GetInstallFolder(cave.InstallFolderName)
+	newFolder := il.GetInstallFolder(name)
+
+	_, err = os.Stat(oldFolder)
+	if err != nil {
+		return errors.Wrapf(butlerd.CodeInstallFolderDisappeared, "(%s)", oldFolder)
+	}
+
+	// don't pull the rug from under games that are running
+	rlock := runlock.New(consumer, oldFolder)
+	err = rlock.Lock(rc.Ctx, "rename")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	consumer.Infof("Renaming install folder (%s) to (%s)", oldFolder, newFolder)
+	// templates may have subfolders
+	err = os.MkdirAll(filepath.Dir(newFolder), 0o755)
+	if err == nil {
+		err = os.Rename(oldFolder, newFolder)
+	}
+	if err != nil {
+		rlock.Unlock()
+		return errors.WithStack(err)
+	}
+	// the lock file moved along with the folder
+	runlock.New(consumer, newFolder).Unlock()
+	operate.RemoveEmptyParents(oldFolder, il.Path)
+
+	now := time.Now().UTC()
+	rc.WithConn(func(conn *sqlite.Conn) {
+		models.MustUpdate(conn, &models.Cave{},
+			hades.Where(builder.Eq{"id": cave.ID}),
+			builder.Eq{"install_folder_name": name},
+		)
+		models.MustSave(conn, &models.CaveEvent{
+			ID:                eventID.String(),
+			CaveID:            cave.ID,
+			Type:              string(butlerd.CaveEventTypeRenamed),
+			CreatedAt:         &now,
+			FromInstallFolder: oldFolder,
+			ToInstallFolder:   newFolder,
+		})
+	})
+	rename.NewFolderName = name
+	rename.Renamed = true
+	return nil
+}
@@ -0,0 +1,30 @@
+package install
+
+import (
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/endpoints/tasks"
+	"github.com/pkg/errors"
+)
+
+func CavesDetectGhosts(rc *butlerd.RequestContext, params butlerd.CavesDetectGhostsParams) (*butlerd.CavesDetectGhostsResult, error) {
+	var ghosts []*butlerd.GhostCave
+	rc.WithConn(func(conn *sqlite.Conn) {
+		ghosts = tasks.DetectGhostCaves(conn, rc.Consumer, false)
+	})
+
+	for _, ghost := range ghosts {
+		err := messages.GhostCaveDetected.Notify(rc, butlerd.GhostCaveDetectedNotification{
+			Ghost: ghost,
+		})
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	res := &butlerd.CavesDetectGhostsResult{
+		Ghosts: ghosts,
+	}
+	return res, nil
+}
@@ -0,0 +1,185 @@
+package install
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"crawshaw.io/sqlite"
+	"github.com/BurntSushi/toml"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/hush/manifest"
+	"github.com/pkg/errors"
+)
+
+func CavesListBuildHistory(rc *butlerd.RequestContext, params butlerd.CavesListBuildHistoryParams) (*butlerd.CavesListBuildHistoryResult, error) {
+	cave := operate.ValidateCave(rc, params.CaveID)
+	if cave.Upload == nil {
+		return nil, errors.Errorf("Cave (%s) has no upload", cave.ID)
+	}
+
+	var access *operate.GameAccess
+	rc.WithConn(func(conn *sqlite.Conn) {
+		access = operate.AccessForCave(conn, cave)
+	})
+	client := rc.Client(access.APIKey)
+
+	buildsRes, err := client.ListUploadBuilds(rc.Ctx, itchio.ListUploadBuildsParams{
+		UploadID:    cave.Upload.ID,
+		Credentials: access.Credentials,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	minimumSaveVersion := caveMinimumSaveVersion(rc, cave)
+
+	res := &butlerd.CavesListBuildHistoryResult{
+		Builds: []*butlerd.BuildHistoryEntry{},
+	}
+	for _, b := range buildsRes.Builds {
+		entry := &butlerd.BuildHistoryEntry{
+			Build: b,
+		}
+		if f := operate.FindBuildFile(b.Files, itchio.BuildFileTypeArchive, itchio.BuildFileSubTypeDefault); f != nil {
+			entry.ArchiveSize = f.Size
+		}
+		if f := operate.FindBuildFile(b.Files, itchio.BuildFileTypeUnpacked, itchio.BuildFileSubTypeDefault); f != nil {
+			entry.UnpackedSize = f.Size
+		}
+		if cave.BuildID != 0 {
+			entry.Current = b.ID == cave.BuildID
+			entry.DowngradeEligible = b.ID < cave.BuildID
+			entry.MayBreakSaves = entry.DowngradeEligible && mayBreakSaves(minimumSaveVersion, b)
+		}
+		res.Builds = append(res.Builds, entry)
+	}
+	sort.SliceStable(res.Builds, func(i, j int) bool {
+		return res.Builds[i].Build.ID > res.Builds[j].Build.ID
+	})
+	return res, nil
+}
+
+func CavesDowngrade(rc *butlerd.RequestContext, params butlerd.CavesDowngradeParams) (*butlerd.CavesDowngradeResult, error) {
+	consumer := rc.Consumer
+
+	cave := operate.ValidateCave(rc, params.CaveID)
+	if cave.Upload == nil || cave.BuildID == 0 {
+		return nil, errors.Errorf("Cave (%s) isn't wharf-enabled, it has no older builds", cave.ID)
+	}
+	if params.BuildID >= cave.BuildID {
+		return nil, errors.Errorf("Build %d isn't older than installed build %d", params.BuildID, cave.BuildID)
+	}
+
+	var access *operate.GameAccess
+	rc.WithConn(func(conn *sqlite.Conn) {
+		access = operate.AccessForCave(conn, cave)
+	})
+	client := rc.Client(access.APIKey)
+
+	build, err := fetchPreferredBuild(rc, client, access, cave.Upload, params.BuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &butlerd.CavesDowngradeResult{}
+	minimumSaveVersion := caveMinimumSaveVersion(rc, cave)
+	if mayBreakSaves(minimumSaveVersion, build) {
+		res.SaveWarning = fmt.Sprintf("Saves of the installed build need version %s or later, build %d is version (%s)", minimumSaveVersion, build.ID, build.UserVersion)
+		consumer.Warnf("%s", res.SaveWarning)
+	}
+
+	queueRes, err := InstallQueue(rc, butlerd.InstallQueueParams{
+		CaveID:        cave.ID,
+		Game:          cave.Game,
+		Upload:        cave.Upload,
+		Build:         build,
+		Reason:        butlerd.DownloadReasonDowngrade,
+		QueueDownload: true,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	consumer.Infof("Queued downgrade of cave (%s) from build %d to build %d", cave.ID, cave.BuildID, build.ID)
+
+	res.DownloadID = queueRes.ID
+	return res, nil
+}
+
+// saveManifest is the part of a build's manifest that says which
+// versions can read its saves. hush's manifest package doesn't
+// know about it.
+type saveManifest struct {
+	MinimumSaveVersion string `toml:"minimum-save-version"`
+}
+
+// caveMinimumSaveVersion returns the minimum-save-version declared by
+// the manifest of the build installed in cave, or an empty string.
+func caveMinimumSaveVersion(rc *butlerd.RequestContext, cave *models.Cave) string {
+	var installFolder string
+	rc.WithConn(func(conn *sqlite.Conn) {
+		installFolder = cave.GetInstallFolder(conn)
+	})
+
+	var m saveManifest
+	_, err := toml.DecodeFile(manifest.Path(installFolder), &m)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			rc.Consumer.Warnf("Could not read manifest of cave (%s): %+v", cave.ID, err)
+		}
+		return ""
+	}
+	return m.MinimumSaveVersion
+}
+
+// mayBreakSaves returns true if build can't be told apart from
+// versions older than minimumSaveVersion.
+func mayBreakSaves(minimumSaveVersion string, build *itchio.Build) bool {
+	if minimumSaveVersion == "" {
+		return false
+	}
+	if build.UserVersion == "" {
+		return true
+	}
+	return compareVersions(build.UserVersion, minimumSaveVersion) < 0
+}
+
+var versionPartRegexp = regexp.MustCompile(`\d+|\D+`)
+
+// compareVersions compares user versions like `1.10.2` or `v2-beta`,
+// numbers by value and everything else as text. It returns -1 if a
+// is older than b, 1 if it's newer, and 0 if they're the same.
+func compareVersions(a string, b string) int {
+	ap := versionPartRegexp.FindAllString(strings.TrimPrefix(a, "v"), -1)
+	bp := versionPartRegexp.FindAllString(strings.TrimPrefix(b, "v"), -1)
+	for i := 0; i < len(ap) && i < len(bp); i++ {
+		an, aErr := strconv.ParseInt(ap[i], 10, 64)
+		bn, bErr := strconv.ParseInt(bp[i], 10, 64)
+		if aErr == nil && bErr == nil {
+			if an < bn {
+				return -1
+			}
+			if an > bn {
+				return 1
+			}
+			continue
+		}
+		if c := strings.Compare(ap[i], bp[i]); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case len(ap) < len(bp):
+		return -1
+	case len(ap) > len(bp):
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,29 @@
+package install
+
+import (
+	"testing"
+
+	itchio "github.com/itchio/go-itchio"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CompareVersions(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.EqualValues(0, compareVersions("1.2.3", "1.2.3"))
+	assert.EqualValues(0, compareVersions("v1.2.3", "1.2.3"))
+	assert.EqualValues(-1, compareVersions("1.9", "1.10"), "numbers are compared by value")
+	assert.EqualValues(1, compareVersions("2.0", "1.99"))
+	assert.EqualValues(-1, compareVersions("1.2", "1.2.1"))
+	assert.EqualValues(-1, compareVersions("1.0-alpha", "1.0-beta"))
+}
+
+func Test_MayBreakSaves(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.False(mayBreakSaves("", &itchio.Build{UserVersion: "0.1"}), "nothing declared")
+	assert.True(mayBreakSaves("2.0", &itchio.Build{}), "no user version")
+	assert.True(mayBreakSaves("2.0", &itchio.Build{UserVersion: "1.5"}))
+	assert.False(mayBreakSaves("2.0", &itchio.Build{UserVersion: "2.0"}))
+	assert.False(mayBreakSaves("2.0", &itchio.Build{UserVersion: "2.1"}))
+}
@@ -0,0 +1,185 @@
+package install
+
+import (
+	"encoding/base64"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/hush/bfs"
+	"github.com/pkg/errors"
+)
+
+func CavesListFiles(rc *butlerd.RequestContext, params butlerd.CavesListFilesParams) (*butlerd.CavesListFilesResult, error) {
+	installFolder, receipt, err := caveReceipt(rc, params.CaveID)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Glob != "" {
+		// catch malformed patterns before matching anything
+		_, err := path.Match(params.Glob, "")
+		if err != nil {
+			return nil, errors.Errorf("invalid glob (%s): %v", params.Glob, err)
+		}
+	}
+
+	res := &butlerd.CavesListFilesResult{
+		Files: []*butlerd.CaveFile{},
+	}
+	for _, file := range receipt.Files {
+		if params.Glob != "" && !matchGlob(params.Glob, file) {
+			continue
+		}
+
+		size := int64(-1)
+		if stats, err := os.Lstat(filepath.Join(installFolder, filepath.FromSlash(file))); err == nil && stats.Mode().IsRegular() {
+			size = stats.Size()
+		}
+		res.Files = append(res.Files, &butlerd.CaveFile{
+			Path: file,
+			Size: size,
+		})
+	}
+	return res, nil
+}
+
+func CavesReadFile(rc *butlerd.RequestContext, params butlerd.CavesReadFileParams) (*butlerd.CavesReadFileResult, error) {
+	installFolder, receipt, err := caveReceipt(rc, params.CaveID)
+	if err != nil {
+		return nil, err
+	}
+
+	filePath, err := resolveReceiptFile(installFolder, receipt, params.RelativePath)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer f.Close()
+
+	stats, err := f.Stat()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !stats.Mode().IsRegular() {
+		return nil, errors.Errorf("(%s) is not a regular file", params.RelativePath)
+	}
+
+	length := params.Length
+	if length > butlerd.CavesReadFileMaxLength {
+		length = butlerd.CavesReadFileMaxLength
+	}
+	buf := make([]byte, length)
+	n, err := f.ReadAt(buf, params.Offset)
+	if err != nil && err != io.EOF {
+		return nil, errors.WithStack(err)
+	}
+
+	res := &butlerd.CavesReadFileResult{
+		Data: base64.StdEncoding.EncodeToString(buf[:n]),
+		Size: stats.Size(),
+		EOF:  params.Offset+int64(n) >= stats.Size(),
+	}
+	return res, nil
+}
+
+func caveReceipt(rc *butlerd.RequestContext, caveID string) (string, *bfs.Receipt, error) {
+	var installFolder string
+	rc.WithConn(func(conn *sqlite.Conn) {
+		cave := models.CaveByID(conn, caveID)
+		if cave != nil {
+			installFolder = cave.GetInstallFolder(conn)
+		}
+	})
+	if installFolder == "" {
+		return "", nil, errors.Errorf("cave not found: (%s)", caveID)
+	}
+
+	receipt, err := bfs.ReadReceipt(installFolder)
+	if err != nil {
+		return "", nil, errors.WithStack(err)
+	}
+	if receipt == nil {
+		return "", nil, errors.Errorf("cave (%s) has no receipt, its files are unknown", caveID)
+	}
+	return installFolder, receipt, nil
+}
+
+// resolveReceiptFile returns the absolute path of relativePath, as long
+// as it's listed in the receipt and actually lives inside installFolder,
+// symlinks included.
+func resolveReceiptFile(installFolder string, receipt *bfs.Receipt, relativePath string) (string, error) {
+	if path.IsAbs(relativePath) || filepath.IsAbs(relativePath) || filepath.VolumeName(relativePath) != "" {
+		return "", errors.Errorf("(%s) must be relative to the install folder", relativePath)
+	}
+	if strings.Contains(relativePath, "\\") {
+		return "", errors.Errorf("(%s) must be slash-separated", relativePath)
+	}
+	for _, element := range strings.Split(relativePath, "/") {
+		if element == ".." {
+			return "", errors.Errorf("(%s) must not refer to parent folders", relativePath)
+		}
+	}
+
+	clean := path.Clean(relativePath)
+	listed := false
+	for _, file := range receipt.Files {
+		if file == clean {
+			listed = true
+			break
+		}
+	}
+	if !listed {
+		return "", errors.Errorf("(%s) is not one of the installed files", relativePath)
+	}
+
+	root, err := filepath.EvalSymlinks(installFolder)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Join(installFolder, filepath.FromSlash(clean)))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf("(%s) resolves to outside of the install folder", relativePath)
+	}
+	return resolved, nil
+}
+
+// matchGlob is path.Match, except `**` matches any number of path elements
+func matchGlob(pattern string, name string) bool {
+	return matchElements(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchElements(pattern []string, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchElements(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	ok, err := path.Match(pattern[0], name[0])
+	if err != nil || !ok {
+		return false
+	}
+	return matchElements(pattern[1:], name[1:])
+}
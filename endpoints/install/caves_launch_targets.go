@@ -0,0 +1,92 @@
+package install
+
+import (
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/hades"
+	"github.com/itchio/hush/manifest"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
+)
+
+func CavesGetLaunchTargets(rc *butlerd.RequestContext, params butlerd.CavesGetLaunchTargetsParams) (*butlerd.CavesGetLaunchTargetsResult, error) {
+	cave := operate.ValidateCave(rc, params.CaveID)
+
+	appManifest, err := readCaveManifest(rc, cave)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &butlerd.CavesGetLaunchTargetsResult{
+		Targets:               []*butlerd.CaveLaunchTarget{},
+		PreferredLaunchTarget: cave.PreferredLaunchTarget,
+	}
+	if appManifest != nil {
+		for _, action := range appManifest.Actions {
+			res.Targets = append(res.Targets, &butlerd.CaveLaunchTarget{
+				Name:     action.Name,
+				Path:     action.Path,
+				Args:     action.Args,
+				Platform: action.Platform,
+			})
+		}
+	}
+	return res, nil
+}
+
+func CavesSetLaunchTarget(rc *butlerd.RequestContext, params butlerd.CavesSetLaunchTargetParams) (*butlerd.CavesSetLaunchTargetResult, error) {
+	consumer := rc.Consumer
+	cave := operate.ValidateCave(rc, params.CaveID)
+
+	if params.TargetName != "" {
+		appManifest, err := readCaveManifest(rc, cave)
+		if err != nil {
+			return nil, err
+		}
+		if appManifest == nil {
+			return nil, errors.Errorf("Cave (%s) has no manifest, so no launch targets", cave.ID)
+		}
+		if findManifestAction(appManifest, params.TargetName) == nil {
+			return nil, errors.Errorf("Cave (%s) has no launch target named (%s)", cave.ID, params.TargetName)
+		}
+	}
+
+	rc.WithConn(func(conn *sqlite.Conn) {
+		models.MustUpdate(conn, &models.Cave{},
+			hades.Where(builder.Eq{"id": cave.ID}),
+			builder.Eq{"preferred_launch_target": params.TargetName},
+		)
+	})
+	if params.TargetName == "" {
+		consumer.Infof("Cave (%s) has no preferred launch target anymore", cave.ID)
+	} else {
+		consumer.Infof("Cave (%s) now launches (%s) by default", cave.ID, params.TargetName)
+	}
+	return &butlerd.CavesSetLaunchTargetResult{}, nil
+}
+
+// readCaveManifest returns the manifest in cave's install folder,
+// or nil if it has none.
+func readCaveManifest(rc *butlerd.RequestContext, cave *models.Cave) (*manifest.Manifest, error) {
+	var installFolder string
+	rc.WithConn(func(conn *sqlite.Conn) {
+		installFolder = cave.GetInstallFolder(conn)
+	})
+
+	appManifest, err := operate.ReadAppManifest(rc.Consumer, installFolder)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading manifest of cave (%s)", cave.ID)
+	}
+	return appManifest, nil
+}
+
+func findManifestAction(appManifest *manifest.Manifest, name string) *manifest.Action {
+	for i := range appManifest.Actions {
+		if appManifest.Actions[i].Name == name {
+			return &appManifest.Actions[i]
+		}
+	}
+	return nil
+}
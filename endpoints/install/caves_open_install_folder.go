@@ -0,0 +1,58 @@
+package install
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database/models"
+	"github.com/pkg/errors"
+)
+
+func CavesOpenInstallFolder(rc *butlerd.RequestContext, params butlerd.CavesOpenInstallFolderParams) (*butlerd.CavesOpenInstallFolderResult, error) {
+	var installFolder string
+	rc.WithConn(func(conn *sqlite.Conn) {
+		cave := models.CaveByID(conn, params.CaveID)
+		if cave != nil {
+			installFolder = cave.GetInstallFolder(conn)
+		}
+	})
+	if installFolder == "" {
+		return nil, errors.Errorf("cave not found: (%s)", params.CaveID)
+	}
+
+	stats, err := os.Stat(installFolder)
+	if err != nil || !stats.IsDir() {
+		return nil, errors.Wrapf(butlerd.CodeInstallFolderMissing, "(%s)", installFolder)
+	}
+
+	cmd := exec.Command(fileManagerCommand(runtime.GOOS), installFolder)
+	err = cmd.Start()
+	if err != nil {
+		return nil, errors.Wrap(err, "opening file manager")
+	}
+	// explorer.exe exits with status 1 even when it worked, so
+	// there's nothing useful to wait for, apart from reaping it
+	go cmd.Wait()
+
+	rc.Consumer.Infof("Opened (%s) in the file manager", installFolder)
+	res := &butlerd.CavesOpenInstallFolderResult{
+		InstallFolder: installFolder,
+	}
+	return res, nil
+}
+
+// fileManagerCommand returns what opens a folder in the
+// OS-default file manager, given the folder as only argument
+func fileManagerCommand(goos string) string {
+	switch goos {
+	case "windows":
+		return "explorer.exe"
+	case "darwin":
+		return "open"
+	default:
+		return "xdg-open"
+	}
+}
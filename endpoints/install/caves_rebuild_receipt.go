@@ -0,0 +1,59 @@
+package install
+
+import (
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/hades"
+	"xorm.io/builder"
+)
+
+func CavesRebuildReceipt(rc *butlerd.RequestContext, params butlerd.CavesRebuildReceiptParams) (*butlerd.CavesRebuildReceiptResult, error) {
+	rebuild, err := operate.RebuildReceipt(rc, params.CaveID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &butlerd.CavesRebuildReceiptResult{
+		Rebuild: rebuild,
+	}
+	return res, nil
+}
+
+func CavesRebuildReceipts(rc *butlerd.RequestContext, params butlerd.CavesRebuildReceiptsParams) (*butlerd.CavesRebuildReceiptsResult, error) {
+	consumer := rc.Consumer
+
+	caveIDs := params.CaveIDs
+	if len(caveIDs) == 0 {
+		rc.WithConn(func(conn *sqlite.Conn) {
+			var caves []*models.Cave
+			models.MustSelect(conn, &caves, builder.NewCond(), hades.Search{})
+			for _, cave := range caves {
+				caveIDs = append(caveIDs, cave.ID)
+			}
+		})
+	}
+	consumer.Infof("Rebuilding receipts for %d caves", len(caveIDs))
+
+	res := &butlerd.CavesRebuildReceiptsResult{
+		Rebuilds: []*butlerd.ReceiptRebuild{},
+	}
+	for _, caveID := range caveIDs {
+		rebuild, err := operate.RebuildReceipt(rc, caveID)
+		if err != nil {
+			consumer.Warnf("Could not rebuild receipt for cave (%s): %+v", caveID, err)
+			rebuild = &butlerd.ReceiptRebuild{
+				CaveID: caveID,
+				Files:  []*butlerd.ReceiptRebuildFile{},
+				Error:  err.Error(),
+			}
+			if be, ok := butlerd.AsButlerdError(err); ok {
+				rebuild.ErrorCode = be.RpcErrorCode()
+				rebuild.Error = be.RpcErrorMessage()
+			}
+		}
+		res.Rebuilds = append(res.Rebuilds, rebuild)
+	}
+	return res, nil
+}
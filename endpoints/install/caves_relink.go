@@ -0,0 +1,52 @@
+package install
+
+import (
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+)
+
+func CavesFindMoved(rc *butlerd.RequestContext, params butlerd.CavesFindMovedParams) (*butlerd.CavesFindMovedResult, error) {
+	cave := operate.ValidateCave(rc, params.CaveID)
+
+	res := &butlerd.CavesFindMovedResult{}
+	rc.WithConn(func(conn *sqlite.Conn) {
+		res.Candidates = operate.FindMovedCave(conn, rc.Consumer, cave, params.ExtraRoots)
+	})
+	return res, nil
+}
+
+func CavesRelink(rc *butlerd.RequestContext, params butlerd.CavesRelinkParams) (*butlerd.CavesRelinkResult, error) {
+	cave := operate.ValidateCave(rc, params.CaveID)
+
+	var err error
+	rc.WithConn(func(conn *sqlite.Conn) {
+		err = operate.RelinkCave(conn, rc.Consumer, cave, params.InstallFolder)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &butlerd.CavesRelinkResult{}
+	return res, nil
+}
+
+func CavesListEvents(rc *butlerd.RequestContext, params butlerd.CavesListEventsParams) (*butlerd.CavesListEventsResult, error) {
+	cave := operate.ValidateCave(rc, params.CaveID)
+
+	res := &butlerd.CavesListEventsResult{
+		Events: []*butlerd.CaveEvent{},
+	}
+	rc.WithConn(func(conn *sqlite.Conn) {
+		for _, event := range models.CaveEventsByCaveID(conn, cave.ID) {
+			res.Events = append(res.Events, &butlerd.CaveEvent{
+				Type:              butlerd.CaveEventType(event.Type),
+				CreatedAt:         event.CreatedAt,
+				FromInstallFolder: event.FromInstallFolder,
+				ToInstallFolder:   event.ToInstallFolder,
+			})
+		}
+	})
+	return res, nil
+}
@@ -0,0 +1,256 @@
+package install
+
+import (
+	"sync"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/google/uuid"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/horror"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/endpoints/fetch"
+	"github.com/itchio/butler/manager/runlock"
+	"github.com/pkg/errors"
+)
+
+type uninstallBatch struct {
+	id          string
+	deleteFiles bool
+
+	// protects items
+	lock  sync.Mutex
+	items []*butlerd.CaveBatchItem
+	done  bool
+}
+
+var uninstallBatches = struct {
+	sync.Mutex
+	byID map[string]*uninstallBatch
+}{
+	byID: make(map[string]*uninstallBatch),
+}
+
+// finishedBatchRetention is how long a finished batch can still be
+// looked up with CaveBatchStatus, before it's forgotten.
+var finishedBatchRetention = 10 * time.Minute
+
+// UninstallBatchStatus returns the state of the uninstall batch with
+// the given ID, or nil if there's no such batch.
+func UninstallBatchStatus(batchID string) *butlerd.CaveBatchStatusResult {
+	uninstallBatches.Lock()
+	b := uninstallBatches.byID[batchID]
+	uninstallBatches.Unlock()
+
+	if b == nil {
+		return nil
+	}
+	return b.status()
+}
+
+func CaveUninstallBatch(rc *butlerd.RequestContext, params butlerd.CaveUninstallBatchParams) (*butlerd.CaveUninstallBatchResult, error) {
+	consumer := rc.Consumer
+
+	var caves []*models.Cave
+	var err error
+	rc.WithConn(func(conn *sqlite.Conn) {
+		seen := make(map[string]bool)
+		for _, caveID := range params.CaveIDs {
+			if seen[caveID] {
+				continue
+			}
+			seen[caveID] = true
+
+			cave := models.CaveByID(conn, caveID)
+			if cave == nil {
+				err = errors.Errorf("No such cave (%s)", caveID)
+				return
+			}
+			caves = append(caves, cave)
+		}
+		models.PreloadCaves(conn, caves)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if params.DryRun {
+		res := &butlerd.CaveUninstallBatchResult{
+			CavesToDelete: []*butlerd.Cave{},
+		}
+		rc.WithConn(func(conn *sqlite.Conn) {
+			for _, cave := range caves {
+				res.CavesToDelete = append(res.CavesToDelete, fetch.FormatCave(conn, cave))
+				if params.DeleteFiles {
+					res.TotalSizeToFree += cave.InstalledSize
+				}
+			}
+		})
+		consumer.Infof("Would remove %d caves, freeing %d bytes", len(res.CavesToDelete), res.TotalSizeToFree)
+		return res, nil
+	}
+
+	batchID, err := uuid.NewRandom()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	b := &uninstallBatch{
+		id:          batchID.String(),
+		deleteFiles: params.DeleteFiles,
+	}
+	for _, cave := range caves {
+		b.items = append(b.items, &butlerd.CaveBatchItem{
+			CaveID: cave.ID,
+			State:  butlerd.CaveBatchItemStateQueued,
+		})
+	}
+
+	uninstallBatches.Lock()
+	uninstallBatches.byID[b.id] = b
+	uninstallBatches.Unlock()
+
+	notifyRC := rc
+	rc.QueueBackgroundTask(butlerd.BackgroundTask{
+		Desc: "uninstall batch " + b.id,
+		Do: func(rc *butlerd.RequestContext) error {
+			rc.Conn = notifyRC.Conn
+			return b.run(rc)
+		},
+	})
+
+	consumer.Infof("Queued uninstall batch %s (%d caves)", b.id, len(b.items))
+	return &butlerd.CaveUninstallBatchResult{
+		BatchID: b.id,
+	}, nil
+}
+
+// status returns a copy of the batch's state, safe to send over the wire
+func (b *uninstallBatch) status() *butlerd.CaveBatchStatusResult {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	res := &butlerd.CaveBatchStatusResult{
+		Done: b.done,
+	}
+	var finished int
+	for _, item := range b.items {
+		itemCopy := *item
+		res.Caves = append(res.Caves, &itemCopy)
+
+		switch item.State {
+		case butlerd.CaveBatchItemStateQueued, butlerd.CaveBatchItemStateUninstalling:
+			// not done yet
+		default:
+			finished++
+		}
+	}
+	if len(b.items) > 0 {
+		res.Progress = float64(finished) / float64(len(b.items))
+	}
+	return res
+}
+
+// update calls f with the batch locked, then notifies about item
+func (b *uninstallBatch) update(rc *butlerd.RequestContext, item *butlerd.CaveBatchItem, f func(item *butlerd.CaveBatchItem)) {
+	b.lock.Lock()
+	f(item)
+	itemCopy := *item
+	b.lock.Unlock()
+
+	err := messages.CaveUninstallProgress.Notify(rc, butlerd.CaveUninstallProgressNotification{
+		BatchID:  b.id,
+		Cave:     &itemCopy,
+		Progress: b.status().Progress,
+	})
+	if err != nil {
+		rc.Consumer.Warnf("Could not notify uninstall progress: %v", err)
+	}
+}
+
+func (b *uninstallBatch) run(rc *butlerd.RequestContext) error {
+	for _, item := range b.items {
+		b.uninstallOne(rc, item)
+	}
+
+	b.lock.Lock()
+	b.done = true
+	b.lock.Unlock()
+	time.AfterFunc(finishedBatchRetention, func() {
+		uninstallBatches.Lock()
+		delete(uninstallBatches.byID, b.id)
+		uninstallBatches.Unlock()
+	})
+
+	rc.Consumer.Statf("Uninstall batch %s done", b.id)
+	return nil
+}
+
+func (b *uninstallBatch) uninstallOne(rc *butlerd.RequestContext, item *butlerd.CaveBatchItem) {
+	b.update(rc, item, func(item *butlerd.CaveBatchItem) {
+		item.State = butlerd.CaveBatchItemStateUninstalling
+	})
+
+	err := func() (retErr error) {
+		defer horror.RecoverInto(&retErr)
+
+		select {
+		case <-rc.Ctx.Done():
+			return errors.WithStack(butlerd.CodeOperationCancelled)
+		default:
+			// keep going!
+		}
+
+		var cave *models.Cave
+		var installFolder string
+		rc.WithConn(func(conn *sqlite.Conn) {
+			cave = models.CaveByID(conn, item.CaveID)
+			if cave == nil {
+				panic(errors.Errorf("No such cave (%s)", item.CaveID))
+			}
+			installFolder = cave.GetInstallFolder(conn)
+		})
+
+		// don't pull the rug from under games that are running,
+		// even if their files are left alone
+		rlock := runlock.New(rc.Consumer, installFolder)
+		err := rlock.Lock(rc.Ctx, "uninstall")
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer rlock.Unlock()
+
+		if b.deleteFiles {
+			_, err := operate.UninstallPerform(rc.Ctx, rc, butlerd.UninstallPerformParams{
+				CaveID: item.CaveID,
+			})
+			return err
+		}
+
+		rc.Consumer.Infof("Forgetting cave (%s), leaving its files alone", cave.ID)
+		rc.WithConn(func(conn *sqlite.Conn) {
+			cave.Delete(conn)
+			models.DiscardDownloadsByCaveID(conn, cave.ID)
+		})
+		return nil
+	}()
+
+	if err != nil {
+		rc.Consumer.Warnf("Could not uninstall cave (%s): %+v", item.CaveID, err)
+		b.update(rc, item, func(item *butlerd.CaveBatchItem) {
+			item.State = butlerd.CaveBatchItemStateFailed
+			item.Error = err.Error()
+			if be, ok := butlerd.AsButlerdError(err); ok {
+				item.ErrorCode = be.RpcErrorCode()
+				item.Error = be.RpcErrorMessage()
+			}
+		})
+		return
+	}
+
+	b.update(rc, item, func(item *butlerd.CaveBatchItem) {
+		item.State = butlerd.CaveBatchItemStateUninstalled
+		item.Progress = 1
+	})
+}
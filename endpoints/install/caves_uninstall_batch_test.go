@@ -0,0 +1,37 @@
+package install
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/headway/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FinishedUninstallBatchesAreForgotten(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(d time.Duration) { finishedBatchRetention = d }(finishedBatchRetention)
+	finishedBatchRetention = 50 * time.Millisecond
+
+	rc := &butlerd.RequestContext{
+		Ctx:      context.Background(),
+		Consumer: &state.Consumer{},
+	}
+
+	b := &uninstallBatch{id: "empty-batch"}
+	uninstallBatches.Lock()
+	uninstallBatches.byID[b.id] = b
+	uninstallBatches.Unlock()
+
+	assert.NoError(b.run(rc))
+	status := UninstallBatchStatus(b.id)
+	if assert.NotNil(status, "finished batches can still be looked up for a while") {
+		assert.True(status.Done)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	assert.Nil(UninstallBatchStatus(b.id), "finished batches are eventually forgotten")
+}
@@ -0,0 +1,290 @@
+package install
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"crawshaw.io/sqlite"
+	"github.com/google/uuid"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/endpoints/downloads"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/hades"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
+)
+
+// installAllBatch is a Collections.InstallAll call that's still
+// queuing games.
+type installAllBatch struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+var installAllBatches = struct {
+	sync.Mutex
+	byID map[string]*installAllBatch
+}{
+	byID: make(map[string]*installAllBatch),
+}
+
+func CollectionsInstallAll(rc *butlerd.RequestContext, params butlerd.CollectionsInstallAllParams) (*butlerd.CollectionsInstallAllResult, error) {
+	consumer := rc.Consumer
+
+	var games []*itchio.Game
+	var err error
+	rc.WithConn(func(conn *sqlite.Conn) {
+		games, err = collectionGamesToInstall(conn, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	strategy := params.UploadStrategy
+	if strategy == "" {
+		strategy = butlerd.DefaultUploadStrategyFirst
+	}
+	pick := func(uploads []*itchio.Upload) (*itchio.Upload, error) {
+		return pickUploadWithStrategy(consumer, uploads, strategy)
+	}
+
+	id := params.BatchID
+	if id == "" {
+		u, err := uuid.NewRandom()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		id = u.String()
+	}
+
+	ctx, cancel := context.WithCancel(rc.Ctx)
+	defer cancel()
+	b := &installAllBatch{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	err = startInstallAllBatch(id, b)
+	if err != nil {
+		return nil, err
+	}
+	defer finishInstallAllBatch(id, b)
+
+	rcCopy := *rc
+	batchRC := &rcCopy
+	batchRC.Ctx = ctx
+
+	consumer.Infof("Installing %d games of collection %d, as batch %s", len(games), params.CollectionID, id)
+	res := &butlerd.CollectionsInstallAllResult{
+		BatchID: id,
+		Games:   []*butlerd.CollectionInstallOutcome{},
+	}
+	for _, game := range games {
+		outcome := &butlerd.CollectionInstallOutcome{
+			Game: game,
+		}
+		res.Games = append(res.Games, outcome)
+
+		if ctx.Err() != nil {
+			outcome.Status = butlerd.CollectionInstallStatusCancelled
+			continue
+		}
+
+		err := installOneOfCollection(batchRC, params, id, game, pick, res.TotalSize, outcome)
+		if err != nil {
+			if ctx.Err() != nil {
+				outcome.Status = butlerd.CollectionInstallStatusCancelled
+				continue
+			}
+			consumer.Warnf("Could not queue install for game %d: %+v", game.ID, err)
+			outcome.Status = butlerd.CollectionInstallStatusFailed
+			outcome.Error = err.Error()
+			if be, ok := butlerd.AsButlerdError(err); ok {
+				outcome.ErrorCode = be.RpcErrorCode()
+				outcome.Error = be.RpcErrorMessage()
+			}
+			continue
+		}
+		if outcome.Status == butlerd.CollectionInstallStatusQueued {
+			res.TotalSize += outcome.EstimatedSize
+		}
+	}
+
+	consumer.Statf("Batch %s queued downloads for %d of %d games", id, countQueued(res.Games), len(res.Games))
+	return res, nil
+}
+
+// startInstallAllBatch registers b under id, so that it can be
+// cancelled, unless another batch with that ID is still queuing games.
+func startInstallAllBatch(id string, b *installAllBatch) error {
+	installAllBatches.Lock()
+	defer installAllBatches.Unlock()
+	if installAllBatches.byID[id] != nil {
+		return errors.Errorf("Batch %s is already queuing games", id)
+	}
+	installAllBatches.byID[id] = b
+	return nil
+}
+
+func finishInstallAllBatch(id string, b *installAllBatch) {
+	installAllBatches.Lock()
+	delete(installAllBatches.byID, id)
+	installAllBatches.Unlock()
+	close(b.done)
+}
+
+// collectionGamesToInstall returns the games of the collection that
+// match params' filters, as last fetched, in collection order.
+func collectionGamesToInstall(conn *sqlite.Conn, params butlerd.CollectionsInstallAllParams) ([]*itchio.Game, error) {
+	if models.InstallLocationByID(conn, params.InstallLocationID) == nil {
+		return nil, errors.Errorf("Install location (%s) not found", params.InstallLocationID)
+	}
+	if models.FetchTargetForCollectionGames(params.CollectionID).MustGetInfo(conn) == nil {
+		return nil, errors.Errorf("Collection %d wasn't fetched yet", params.CollectionID)
+	}
+
+	var cond builder.Cond = builder.Eq{"collection_id": params.CollectionID}
+	if params.Filters.Classification != "" {
+		cond = builder.And(cond, builder.Eq{"games.classification": params.Filters.Classification})
+	}
+	if params.Filters.Search != "" {
+		cond = builder.And(cond, builder.Like{"games.title", params.Filters.Search})
+	}
+	search := hades.Search{}.
+		InnerJoin("games", "games.id = collection_games.game_id").
+		OrderBy("position DESC")
+
+	var items []*itchio.CollectionGame
+	models.MustSelect(conn, &items, cond, search)
+	models.MustPreload(conn, items, hades.Assoc("Game"))
+
+	var games []*itchio.Game
+	for _, item := range items {
+		games = append(games, item.Game)
+	}
+	return games, nil
+}
+
+// installOneOfCollection queues a download for game, unless it should be
+// skipped, and fills outcome. Errors are for games that failed.
+func installOneOfCollection(rc *butlerd.RequestContext, params butlerd.CollectionsInstallAllParams, batchID string, game *itchio.Game, pick uploadPicker, totalSize int64, outcome *butlerd.CollectionInstallOutcome) error {
+	consumer := rc.Consumer
+
+	skip := func(reason butlerd.CollectionInstallSkipReason) error {
+		consumer.Infof("Skipping %s: %s", operate.GameToString(game), reason)
+		outcome.Status = butlerd.CollectionInstallStatusSkipped
+		outcome.SkipReason = reason
+		return nil
+	}
+
+	queueParams := butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: params.InstallLocationID,
+	}
+
+	var installed bool
+	var downloading int64
+	rc.WithConn(func(conn *sqlite.Conn) {
+		installed = len(models.CavesByGameID(conn, game.ID)) > 0
+		downloading = models.MustCount(conn, &models.Download{}, builder.And(
+			builder.Eq{"game_id": game.ID},
+			builder.IsNull{"finished_at"},
+			builder.Not{builder.Expr("discarded")},
+		))
+	})
+	if installed {
+		return skip(butlerd.CollectionInstallSkipReasonInstalled)
+	}
+	if downloading > 0 {
+		return skip(butlerd.CollectionInstallSkipReasonDownloading)
+	}
+
+	overBudget := false
+	queued, err := installQueueThen(rc, queueParams, pick, func(queued *butlerd.InstallQueueResult) error {
+		outcome.EstimatedSize = operate.EstimateDownloadSize(queued.Upload, queued.Build)
+		if params.MaxTotalSize > 0 && totalSize+outcome.EstimatedSize > params.MaxTotalSize {
+			wipeUnqueued(rc, queued)
+			overBudget = true
+			return nil
+		}
+
+		_, err := downloads.DownloadsQueue(rc, butlerd.DownloadsQueueParams{
+			Item:    queued,
+			BatchID: batchID,
+		})
+		if err != nil {
+			wipeUnqueued(rc, queued)
+			return errors.WithStack(err)
+		}
+		return nil
+	})
+	if err != nil {
+		var ncue *operate.NoCompatibleUploadsError
+		if errors.As(err, &ncue) {
+			return skip(butlerd.CollectionInstallSkipReasonIncompatible)
+		}
+		if errors.Is(err, butlerd.CodeOperationAborted) {
+			return skip(butlerd.CollectionInstallSkipReasonNotPicked)
+		}
+		return err
+	}
+	if queued.AlreadyQueued {
+		return skip(butlerd.CollectionInstallSkipReasonDownloading)
+	}
+	if overBudget {
+		return skip(butlerd.CollectionInstallSkipReasonOverBudget)
+	}
+
+	outcome.Upload = queued.Upload
+	outcome.Status = butlerd.CollectionInstallStatusQueued
+	outcome.DownloadID = queued.ID
+	return nil
+}
+
+// wipeUnqueued removes the staging folder of an install that was
+// queued, but whose download wasn't.
+func wipeUnqueued(rc *butlerd.RequestContext, queued *butlerd.InstallQueueResult) {
+	err := os.RemoveAll(queued.StagingFolder)
+	if err != nil {
+		rc.Consumer.Warnf("Could not remove staging folder (%s): %+v", queued.StagingFolder, err)
+	}
+}
+
+func countQueued(outcomes []*butlerd.CollectionInstallOutcome) int {
+	count := 0
+	for _, outcome := range outcomes {
+		if outcome.Status == butlerd.CollectionInstallStatusQueued {
+			count++
+		}
+	}
+	return count
+}
+
+func CollectionsInstallAllCancel(rc *butlerd.RequestContext, params butlerd.CollectionsInstallAllCancelParams) (*butlerd.CollectionsInstallAllCancelResult, error) {
+	consumer := rc.Consumer
+	res := &butlerd.CollectionsInstallAllCancelResult{}
+
+	installAllBatches.Lock()
+	b := installAllBatches.byID[params.BatchID]
+	installAllBatches.Unlock()
+
+	if b != nil {
+		consumer.Infof("Cancelling batch %s", params.BatchID)
+		b.cancel()
+		res.DidCancel = true
+		// the game being queued may still make it, wait for
+		// it so that its download gets discarded too
+		select {
+		case <-b.done:
+		case <-rc.Ctx.Done():
+			return nil, errors.WithStack(butlerd.CodeOperationCancelled)
+		}
+	}
+
+	if params.DiscardPending {
+		res.Discarded = downloads.DiscardBatch(rc, params.BatchID)
+	}
+	return res, nil
+}
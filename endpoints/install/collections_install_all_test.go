@@ -0,0 +1,85 @@
+package install
+
+import (
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database"
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CollectionGamesToInstall(t *testing.T) {
+	assert := assert.New(t)
+
+	conn, err := sqlite.OpenConn("file:collection_games_to_install_test?mode=memory", 0)
+	assert.NoError(err)
+	defer conn.Close()
+	assert.NoError(database.Prepare(&state.Consumer{}, conn, true))
+
+	save := func(record interface{}) {
+		assert.NoError(models.Save(conn, record))
+	}
+	save(&models.InstallLocation{ID: "jam", Path: "/jam"})
+	save(&itchio.Collection{ID: 50, Title: "Jam entries"})
+	entries := []*itchio.Game{
+		{ID: 1, Title: "Space Cadet", Classification: itchio.GameClassificationGame},
+		{ID: 2, Title: "Space Soundtrack", Classification: itchio.GameClassificationSoundtrack},
+		{ID: 3, Title: "Dungeon Cadet", Classification: itchio.GameClassificationGame},
+	}
+	for i, game := range entries {
+		save(game)
+		save(&itchio.CollectionGame{CollectionID: 50, GameID: game.ID, Position: int64(i)})
+	}
+
+	params := butlerd.CollectionsInstallAllParams{
+		CollectionID:      50,
+		InstallLocationID: "jam",
+	}
+	gameIDs := func(params butlerd.CollectionsInstallAllParams) []int64 {
+		games, err := collectionGamesToInstall(conn, params)
+		assert.NoError(err)
+		var ids []int64
+		for _, game := range games {
+			ids = append(ids, game.ID)
+		}
+		return ids
+	}
+
+	_, err = collectionGamesToInstall(conn, params)
+	assert.Error(err, "collection wasn't fetched yet")
+
+	models.FetchTargetForCollectionGames(50).MustMarkFresh(conn)
+	assert.EqualValues([]int64{3, 2, 1}, gameIDs(params), "same order as Fetch.Collection.Games")
+
+	params.Filters.Classification = itchio.GameClassificationGame
+	assert.EqualValues([]int64{3, 1}, gameIDs(params))
+
+	params.Filters.Search = "space"
+	assert.EqualValues([]int64{1}, gameIDs(params))
+
+	params.InstallLocationID = "nowhere"
+	_, err = collectionGamesToInstall(conn, params)
+	assert.Error(err, "install location must exist")
+}
+
+func Test_InstallAllBatchIDs(t *testing.T) {
+	assert := assert.New(t)
+
+	b := &installAllBatch{cancel: func() {}, done: make(chan struct{})}
+	assert.NoError(startInstallAllBatch("client-picked", b))
+	assert.Error(startInstallAllBatch("client-picked", &installAllBatch{}), "one batch per ID at a time")
+
+	finishInstallAllBatch("client-picked", b)
+	select {
+	case <-b.done:
+	default:
+		assert.Fail("finished batches are done")
+	}
+	other := &installAllBatch{cancel: func() {}, done: make(chan struct{})}
+	assert.NoError(startInstallAllBatch("client-picked", other), "IDs can be reused once the batch is done")
+	finishInstallAllBatch("client-picked", other)
+}
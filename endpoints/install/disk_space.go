@@ -0,0 +1,192 @@
+package install
+
+import (
+	"sort"
+
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/endpoints/system"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/headway/united"
+	"github.com/pkg/errors"
+)
+
+// swapped out in tests
+var statFS = system.StatFS
+
+// checkDiskSpace returns a *operate.NotEnoughSpaceError if the install
+// location at path doesn't have room for upload, as far as we can tell
+// before downloading anything.
+func checkDiskSpace(consumer *state.Consumer, path string, upload *itchio.Upload, build *itchio.Build) error {
+	if build == nil {
+		build = upload.Build
+	}
+	required := operate.EstimateRequiredSpace(upload, build)
+	if required == 0 {
+		consumer.Infof("Size of upload unknown, skipping disk space check")
+		return nil
+	}
+
+	stats, err := statFS(path)
+	if err != nil {
+		// not being able to tell shouldn't prevent installing
+		consumer.Warnf("Could not check free space of (%s): %+v", path, err)
+		return nil
+	}
+
+	if stats.FreeSize < required {
+		return errors.WithStack(&operate.NotEnoughSpaceError{
+			Path:      path,
+			Required:  required,
+			Available: stats.FreeSize,
+		})
+	}
+	consumer.Infof("Install needs about %s, %s available", united.FormatBytes(required), united.FormatBytes(stats.FreeSize))
+	return nil
+}
+
+// checkStagingSpace returns a *operate.NotEnoughSpaceError if the staging
+// path of an install location, when it has its own, doesn't have room
+// for what's downloaded before installing it.
+func checkStagingSpace(consumer *state.Consumer, path string, upload *itchio.Upload, build *itchio.Build) error {
+	if build == nil {
+		build = upload.Build
+	}
+	required := operate.EstimateDownloadSize(upload, build)
+	if required == 0 {
+		return nil
+	}
+
+	stats, err := statFS(path)
+	if err != nil {
+		consumer.Warnf("Could not check free space of staging path (%s): %+v", path, err)
+		return nil
+	}
+
+	if stats.FreeSize < required {
+		return errors.WithStack(&operate.NotEnoughSpaceError{
+			Path:      path,
+			Required:  required,
+			Available: stats.FreeSize,
+		})
+	}
+	consumer.Infof("Download needs about %s, %s available in staging path", united.FormatBytes(required), united.FormatBytes(stats.FreeSize))
+	return nil
+}
+
+// checkPreparedSpace returns a *operate.NotEnoughSpaceError if path
+// doesn't have the free space InstallPrepare found the install needs,
+// by looking inside the upload. That's more accurate than the guesses
+// checkDiskSpace makes, but needs the upload to be probed first.
+//
+// For installs split across two locations, overflowPath is checked for
+// the share of the install that goes there, and path for the rest.
+func checkPreparedSpace(consumer *state.Consumer, path string, overflowPath string, dui *operate.DiskUsageInfo) error {
+	if dui == nil || dui.Accuracy == operate.AccuracyNone {
+		return nil
+	}
+
+	required := dui.NeededFreeSpace
+	if overflowPath != "" && dui.OverflowNeededFreeSpace > 0 {
+		err := checkFreeSpace(consumer, overflowPath, dui.OverflowNeededFreeSpace)
+		if err != nil {
+			return err
+		}
+		required -= dui.OverflowNeededFreeSpace
+	}
+	return checkFreeSpace(consumer, path, required)
+}
+
+func checkFreeSpace(consumer *state.Consumer, path string, required int64) error {
+	if required <= 0 {
+		return nil
+	}
+
+	stats, err := statFS(path)
+	if err != nil {
+		// network mounts don't always report free space
+		consumer.Warnf("Could not check free space of (%s), skipping preflight check: %+v", path, err)
+		return nil
+	}
+	if stats.TotalSize == 0 {
+		consumer.Warnf("(%s) reports no size at all, skipping preflight check", path)
+		return nil
+	}
+
+	if stats.FreeSize < required {
+		return errors.WithStack(&operate.NotEnoughSpaceError{
+			Path:      path,
+			Required:  required,
+			Available: stats.FreeSize,
+		})
+	}
+	consumer.Infof("Preflight: (%s) needs %s, %s available", path, united.FormatBytes(required), united.FormatBytes(stats.FreeSize))
+	return nil
+}
+
+// checkLocationSpace checks that il has room for upload in its install
+// path and, when it has its own and something gets downloaded there,
+// in its staging path.
+func checkLocationSpace(consumer *state.Consumer, il *models.InstallLocation, upload *itchio.Upload, build *itchio.Build, downloads bool) error {
+	err := checkDiskSpace(consumer, il.Path, upload, build)
+	if err != nil {
+		return err
+	}
+	if il.StagingPath != "" && downloads {
+		return checkStagingSpace(consumer, il.StagingPath, upload, build)
+	}
+	return nil
+}
+
+// findFallbackLocation returns the first of candidates, most free space
+// first, that has room for upload, since preferred didn't, as described
+// by preferredErr. Candidates whose free space can't be measured are
+// skipped. If none fits, it returns a *operate.NotEnoughSpaceError listing
+// how short each install location is.
+func findFallbackLocation(consumer *state.Consumer, preferred *models.InstallLocation, preferredErr *operate.NotEnoughSpaceError, candidates []*models.InstallLocation, upload *itchio.Upload, build *itchio.Build, downloads bool) (*models.InstallLocation, error) {
+	free := make(map[string]int64)
+	var measured []*models.InstallLocation
+	for _, il := range candidates {
+		stats, err := statFS(il.Path)
+		if err != nil {
+			consumer.Warnf("Could not check free space of (%s), not falling back to it: %+v", il.Path, err)
+			continue
+		}
+		free[il.ID] = stats.FreeSize
+		measured = append(measured, il)
+	}
+	sort.SliceStable(measured, func(i, j int) bool {
+		return free[measured[i].ID] > free[measured[j].ID]
+	})
+
+	locations := []*operate.LocationSpace{
+		{
+			InstallLocationID: preferred.ID,
+			Path:              preferredErr.Path,
+			Required:          preferredErr.Required,
+			Available:         preferredErr.Available,
+		},
+	}
+	for _, il := range measured {
+		err := checkLocationSpace(consumer, il, upload, build, downloads)
+		if err == nil {
+			return il, nil
+		}
+		nes, ok := errors.Cause(err).(*operate.NotEnoughSpaceError)
+		if !ok {
+			return nil, err
+		}
+		consumer.Infof("Install location %s is %s short", il.ID, united.FormatBytes(nes.Required-nes.Available))
+		locations = append(locations, &operate.LocationSpace{
+			InstallLocationID: il.ID,
+			Path:              nes.Path,
+			Required:          nes.Required,
+			Available:         nes.Available,
+		})
+	}
+
+	res := *preferredErr
+	res.Locations = locations
+	return nil, errors.WithStack(&res)
+}
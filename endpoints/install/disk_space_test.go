@@ -0,0 +1,246 @@
+package install
+
+import (
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CheckDiskSpace(t *testing.T) {
+	assert := assert.New(t)
+	consumer := &state.Consumer{}
+
+	defer func(f func(string) (*butlerd.SystemStatFSResult, error)) { statFS = f }(statFS)
+	var free int64
+	var statted []string
+	statFS = func(path string) (*butlerd.SystemStatFSResult, error) {
+		statted = append(statted, path)
+		return &butlerd.SystemStatFSResult{FreeSize: free}, nil
+	}
+
+	upload := &itchio.Upload{Filename: "game.zip", Size: 100}
+
+	free = 500
+	assert.NoError(checkDiskSpace(consumer, "/games", upload, nil))
+	assert.EqualValues([]string{"/games"}, statted)
+
+	free = 150
+	err := checkDiskSpace(consumer, "/games", upload, nil)
+	if assert.Error(err, "archives need twice their size") {
+		be, ok := butlerd.AsButlerdError(err)
+		assert.True(ok)
+		assert.EqualValues(butlerd.CodeNotEnoughSpace, be.RpcErrorCode())
+		assert.EqualValues(map[string]interface{}{
+			"required":  int64(200),
+			"available": int64(150),
+		}, be.RpcErrorData())
+
+		nes, ok := errors.Cause(err).(*operate.NotEnoughSpaceError)
+		assert.True(ok)
+		assert.EqualValues("/games", nes.Path)
+	}
+
+	assert.NoError(checkDiskSpace(consumer, "/games", &itchio.Upload{Filename: "game.exe", Size: 100}, nil), "single files need their own size")
+
+	free = 0
+	statted = nil
+	assert.NoError(checkDiskSpace(consumer, "/games", &itchio.Upload{Filename: "game.zip"}, nil), "unknown sizes aren't checked")
+	assert.Empty(statted)
+
+	statFS = func(path string) (*butlerd.SystemStatFSResult, error) {
+		return nil, errors.New("no such volume")
+	}
+	assert.NoError(checkDiskSpace(consumer, "/games", upload, nil), "stat failures don't prevent installing")
+}
+
+func Test_CheckStagingSpace(t *testing.T) {
+	assert := assert.New(t)
+	consumer := &state.Consumer{}
+
+	defer func(f func(string) (*butlerd.SystemStatFSResult, error)) { statFS = f }(statFS)
+	var free int64
+	statFS = func(path string) (*butlerd.SystemStatFSResult, error) {
+		return &butlerd.SystemStatFSResult{FreeSize: free}, nil
+	}
+
+	upload := &itchio.Upload{Filename: "game.zip", Size: 100}
+
+	free = 150
+	assert.NoError(checkStagingSpace(consumer, "/staging", upload, nil), "archives are only downloaded there")
+
+	free = 50
+	err := checkStagingSpace(consumer, "/staging", upload, nil)
+	if assert.Error(err) {
+		nes, ok := errors.Cause(err).(*operate.NotEnoughSpaceError)
+		if assert.True(ok) {
+			assert.EqualValues("/staging", nes.Path)
+			assert.EqualValues(100, nes.Required)
+			assert.EqualValues(50, nes.Available)
+		}
+	}
+
+	build := &itchio.Build{Files: []*itchio.BuildFile{
+		{Type: itchio.BuildFileTypeArchive, Size: 40},
+	}}
+	assert.NoError(checkStagingSpace(consumer, "/staging", upload, build), "builds download their archive")
+}
+
+func Test_CheckPreparedSpace(t *testing.T) {
+	assert := assert.New(t)
+	consumer := &state.Consumer{}
+
+	defer func(f func(string) (*butlerd.SystemStatFSResult, error)) { statFS = f }(statFS)
+	var free, total int64
+	var statted []string
+	statFS = func(path string) (*butlerd.SystemStatFSResult, error) {
+		statted = append(statted, path)
+		return &butlerd.SystemStatFSResult{FreeSize: free, TotalSize: total}, nil
+	}
+
+	dui := &operate.DiskUsageInfo{
+		NeededFreeSpace: 300,
+		FinalDiskUsage:  300,
+		Accuracy:        operate.AccuracyComputed,
+	}
+
+	free, total = 500, 1000
+	assert.NoError(checkPreparedSpace(consumer, "/games", "", dui))
+	assert.EqualValues([]string{"/games"}, statted)
+
+	free = 200
+	err := checkPreparedSpace(consumer, "/games", "", dui)
+	if assert.Error(err) {
+		be, ok := butlerd.AsButlerdError(err)
+		assert.True(ok)
+		assert.EqualValues(butlerd.CodeNotEnoughSpace, be.RpcErrorCode())
+		assert.EqualValues(map[string]interface{}{
+			"required":  int64(300),
+			"available": int64(200),
+		}, be.RpcErrorData())
+	}
+
+	statted = nil
+	assert.NoError(checkPreparedSpace(consumer, "/games", "", nil))
+	assert.NoError(checkPreparedSpace(consumer, "/games", "", &operate.DiskUsageInfo{Accuracy: operate.AccuracyNone}))
+	assert.Empty(statted, "nothing to check against")
+
+	free, total = 0, 0
+	assert.NoError(checkPreparedSpace(consumer, "/mnt/share", "", dui), "some network mounts report zero sizes")
+
+	statFS = func(path string) (*butlerd.SystemStatFSResult, error) {
+		return nil, errors.New("operation not supported")
+	}
+	assert.NoError(checkPreparedSpace(consumer, "/mnt/share", "", dui), "stat failures don't prevent installing")
+}
+
+func Test_CheckPreparedSpaceSplit(t *testing.T) {
+	assert := assert.New(t)
+	consumer := &state.Consumer{}
+
+	defer func(f func(string) (*butlerd.SystemStatFSResult, error)) { statFS = f }(statFS)
+	free := map[string]int64{"/ssd": 100, "/hdd": 1000}
+	statFS = func(path string) (*butlerd.SystemStatFSResult, error) {
+		return &butlerd.SystemStatFSResult{FreeSize: free[path], TotalSize: 2000}, nil
+	}
+
+	dui := &operate.DiskUsageInfo{
+		NeededFreeSpace:         600,
+		OverflowNeededFreeSpace: 550,
+		FinalDiskUsage:          600,
+		Accuracy:                operate.AccuracyComputed,
+	}
+	assert.NoError(checkPreparedSpace(consumer, "/ssd", "/hdd", dui), "each location only needs room for its share")
+	assert.Error(checkPreparedSpace(consumer, "/ssd", "", dui), "without an overflow location, it all goes to one")
+
+	free["/hdd"] = 500
+	err := checkPreparedSpace(consumer, "/ssd", "/hdd", dui)
+	if assert.Error(err) {
+		nes, ok := errors.Cause(err).(*operate.NotEnoughSpaceError)
+		if assert.True(ok) {
+			assert.EqualValues("/hdd", nes.Path)
+			assert.EqualValues(550, nes.Required)
+		}
+	}
+
+	free["/hdd"] = 1000
+	dui.OverflowNeededFreeSpace = 400
+	err = checkPreparedSpace(consumer, "/ssd", "/hdd", dui)
+	if assert.Error(err) {
+		nes, ok := errors.Cause(err).(*operate.NotEnoughSpaceError)
+		if assert.True(ok) {
+			assert.EqualValues("/ssd", nes.Path)
+			assert.EqualValues(200, nes.Required)
+		}
+	}
+}
+
+func Test_FindFallbackLocation(t *testing.T) {
+	assert := assert.New(t)
+	consumer := &state.Consumer{}
+
+	defer func(f func(string) (*butlerd.SystemStatFSResult, error)) { statFS = f }(statFS)
+	free := map[string]int64{
+		"/ssd":      50,
+		"/hdd":      300,
+		"/usb":      250,
+		"/usb/tmp":  10,
+		"/external": 1000,
+	}
+	statFS = func(path string) (*butlerd.SystemStatFSResult, error) {
+		if path == "/external" {
+			return nil, errors.New("unplugged")
+		}
+		return &butlerd.SystemStatFSResult{FreeSize: free[path]}, nil
+	}
+
+	upload := &itchio.Upload{Filename: "game.zip", Size: 100}
+	ssd := &models.InstallLocation{ID: "ssd", Path: "/ssd"}
+	hdd := &models.InstallLocation{ID: "hdd", Path: "/hdd"}
+	usb := &models.InstallLocation{ID: "usb", Path: "/usb", StagingPath: "/usb/tmp"}
+	external := &models.InstallLocation{ID: "external", Path: "/external"}
+	candidates := []*models.InstallLocation{usb, external, hdd}
+
+	preferredErr := func() *operate.NotEnoughSpaceError {
+		err := checkLocationSpace(consumer, ssd, upload, nil, true)
+		nes, ok := errors.Cause(err).(*operate.NotEnoughSpaceError)
+		assert.True(ok)
+		return nes
+	}
+
+	il, err := findFallbackLocation(consumer, ssd, preferredErr(), candidates, upload, nil, true)
+	if assert.NoError(err) {
+		assert.EqualValues("hdd", il.ID, "most free space first")
+	}
+
+	free["/hdd"] = 150
+	il, err = findFallbackLocation(consumer, ssd, preferredErr(), candidates, upload, nil, false)
+	if assert.NoError(err) {
+		assert.EqualValues("usb", il.ID, "staging path isn't checked when nothing's downloaded")
+	}
+
+	_, err = findFallbackLocation(consumer, ssd, preferredErr(), candidates, upload, nil, true)
+	if assert.Error(err, "unmeasurable locations aren't fallen back to") {
+		be, ok := butlerd.AsButlerdError(err)
+		assert.True(ok)
+		assert.EqualValues(butlerd.CodeNotEnoughSpace, be.RpcErrorCode())
+
+		nes, ok := errors.Cause(err).(*operate.NotEnoughSpaceError)
+		if assert.True(ok) && assert.Len(nes.Locations, 3) {
+			assert.EqualValues("/ssd", nes.Path)
+			assert.EqualValues("ssd", nes.Locations[0].InstallLocationID)
+			assert.EqualValues(150, nes.Locations[0].Deficit())
+			assert.EqualValues("usb", nes.Locations[1].InstallLocationID)
+			assert.EqualValues("/usb/tmp", nes.Locations[1].Path)
+			assert.EqualValues(90, nes.Locations[1].Deficit())
+			assert.EqualValues("hdd", nes.Locations[2].InstallLocationID)
+			assert.EqualValues(50, nes.Locations[2].Deficit())
+		}
+		assert.Len(be.RpcErrorData()["locations"], 3)
+	}
+}
@@ -0,0 +1,131 @@
+package install
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/pkg/errors"
+)
+
+// What can go between braces in an install location's folder name
+// template, see butlerd.InstallLocationsAddParams.
+var folderNameVariables = map[string]func(v *folderNameValues) string{
+	"game_id": func(v *folderNameValues) string {
+		return fmt.Sprintf("%d", v.game.ID)
+	},
+	"slug": func(v *folderNameValues) string {
+		return makeInstallFolderNameFromSlug(v.game, v.consumer)
+	},
+	"title": func(v *folderNameValues) string {
+		return v.game.Title
+	},
+	"creator": func(v *folderNameValues) string {
+		if v.game.User == nil {
+			return ""
+		}
+		return v.game.User.Username
+	},
+	"channel": func(v *folderNameValues) string {
+		return v.upload.ChannelName
+	},
+	"build_id": func(v *folderNameValues) string {
+		if v.build == nil {
+			return ""
+		}
+		return fmt.Sprintf("%d", v.build.ID)
+	},
+	"upload_id": func(v *folderNameValues) string {
+		return fmt.Sprintf("%d", v.upload.ID)
+	},
+}
+
+type folderNameValues struct {
+	game     *itchio.Game
+	upload   *itchio.Upload
+	build    *itchio.Build
+	consumer *state.Consumer
+}
+
+var folderNameVariableRe = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// validateFolderNameTemplate returns an error if template uses unknown
+// variables, has stray braces, has characters that aren't allowed
+// in folder names outside of its variables, or has subfolders that
+// would step outside of the install location.
+func validateFolderNameTemplate(template string) error {
+	for _, m := range folderNameVariableRe.FindAllStringSubmatch(template, -1) {
+		if _, ok := folderNameVariables[m[1]]; !ok {
+			return errors.Errorf("folder name template (%s): unknown variable {%s}", template, m[1])
+		}
+	}
+
+	literal := folderNameVariableRe.ReplaceAllString(template, "")
+	if strings.ContainsAny(literal, "{}") {
+		return errors.Errorf("folder name template (%s): unbalanced braces", template)
+	}
+	if strings.IndexFunc(strings.ReplaceAll(literal, "/", ""), isForbiddenFolderNameRune) >= 0 {
+		return errors.Errorf("folder name template (%s): has characters not allowed in folder names", template)
+	}
+
+	if template == "" {
+		return nil
+	}
+	for _, component := range strings.Split(template, "/") {
+		switch component {
+		case "":
+			return errors.Errorf("folder name template (%s): has empty or absolute subfolders", template)
+		case ".", "..":
+			return errors.Errorf("folder name template (%s): subfolders can't be (%s)", template, component)
+		}
+	}
+	return nil
+}
+
+// renderFolderNameTemplate returns the install folder name template
+// gives for an upload (and build, for wharf-enabled uploads) of game.
+// Uploads only belong to one game, and builds to one upload, so names
+// are unique if they include the upload ID or the build ID. When they
+// wouldn't, the upload ID is appended. Each subfolder is sanitized,
+// like slug-based names, and left out if nothing is left of it. Names
+// always use `/` between subfolders, whatever the platform.
+func renderFolderNameTemplate(template string, game *itchio.Game, upload *itchio.Upload, build *itchio.Build, consumer *state.Consumer) string {
+	v := &folderNameValues{
+		game:     game,
+		upload:   upload,
+		build:    build,
+		consumer: consumer,
+	}
+
+	unique := false
+	var components []string
+	for _, component := range strings.Split(template, "/") {
+		component = folderNameVariableRe.ReplaceAllStringFunc(component, func(m string) string {
+			variable := m[1 : len(m)-1]
+			value := folderNameVariables[variable](v)
+			if value != "" && (variable == "upload_id" || variable == "build_id") {
+				unique = true
+			}
+			return value
+		})
+		components = append(components, component)
+	}
+	if !unique {
+		last := len(components) - 1
+		components[last] = fmt.Sprintf("%s-%d", components[last], upload.ID)
+	}
+
+	var sanitized []string
+	for _, component := range components {
+		component = sanitizeFolderName(component)
+		if component != "" {
+			sanitized = append(sanitized, component)
+		}
+	}
+	if len(sanitized) == 0 {
+		return makeInstallFolderNameFromID(game, consumer)
+	}
+	return strings.Join(sanitized, "/")
+}
@@ -0,0 +1,59 @@
+package install
+
+import (
+	"testing"
+
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ValidateFolderNameTemplate(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NoError(validateFolderNameTemplate(""))
+	assert.NoError(validateFolderNameTemplate("{game_id}-{channel}-{build_id}"))
+	assert.NoError(validateFolderNameTemplate("review {slug} ({upload_id})"))
+	assert.NoError(validateFolderNameTemplate("{creator}/{slug}"))
+	assert.NoError(validateFolderNameTemplate("builds/{title} [{game_id}]/{build_id}"))
+
+	assert.Error(validateFolderNameTemplate("{game_id}-{version}"), "unknown variable")
+	assert.Error(validateFolderNameTemplate("{game_id"), "unbalanced braces")
+	assert.Error(validateFolderNameTemplate("{{game_id}}"), "unbalanced braces")
+	assert.Error(validateFolderNameTemplate("../{build_id}"), "no escaping the install location")
+	assert.Error(validateFolderNameTemplate("builds/./{build_id}"))
+	assert.Error(validateFolderNameTemplate("/{build_id}"), "no absolute paths")
+	assert.Error(validateFolderNameTemplate("{creator}//{slug}"))
+	assert.Error(validateFolderNameTemplate("{creator}/"))
+	assert.Error(validateFolderNameTemplate(`{creator}\{slug}`))
+	assert.Error(validateFolderNameTemplate("{game_id}:{build_id}"))
+}
+
+func Test_RenderFolderNameTemplate(t *testing.T) {
+	assert := assert.New(t)
+
+	consumer := &state.Consumer{}
+	game := &itchio.Game{
+		ID:    42,
+		URL:   "https://example.itch.io/overland",
+		Title: "Overland: Director's Cut",
+		User:  &itchio.User{Username: "finji"},
+	}
+	upload := &itchio.Upload{ID: 7, ChannelName: "linux-stable"}
+	build := &itchio.Build{ID: 1234}
+
+	assert.EqualValues("42-linux-stable-1234", renderFolderNameTemplate("{game_id}-{channel}-{build_id}", game, upload, build, consumer))
+	assert.EqualValues("overland-7", renderFolderNameTemplate("{slug}-{upload_id}", game, upload, build, consumer))
+	assert.EqualValues("overland-7", renderFolderNameTemplate("{slug}", game, upload, build, consumer), "upload ID is appended when needed")
+	assert.EqualValues("42--7", renderFolderNameTemplate("{game_id}-{build_id}", game, upload, nil, consumer), "missing build IDs don't count")
+
+	weird := &itchio.Upload{ID: 8, ChannelName: "mac/beta?"}
+	assert.EqualValues("mac-beta--8", renderFolderNameTemplate("{channel}-{upload_id}", game, weird, nil, consumer))
+
+	assert.EqualValues("finji/overland-7", renderFolderNameTemplate("{creator}/{slug}", game, upload, build, consumer))
+	assert.EqualValues("finji/Overland- Director's Cut [42]/1234", renderFolderNameTemplate("{creator}/{title} [{game_id}]/{build_id}", game, upload, build, consumer))
+	assert.EqualValues("mac-beta-/overland-8", renderFolderNameTemplate("{channel}/{slug}", game, weird, nil, consumer), "values can't make subfolders")
+
+	anonymous := &itchio.Game{ID: 43, URL: "https://example.itch.io/orphan"}
+	assert.EqualValues("orphan-7", renderFolderNameTemplate("{creator}/{slug}", anonymous, upload, build, consumer), "empty subfolders are left out")
+}
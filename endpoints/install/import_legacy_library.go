@@ -0,0 +1,504 @@
+package install
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/dchest/safefile"
+	"github.com/google/uuid"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/manager"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/hades"
+	"github.com/itchio/hush/bfs"
+	"github.com/itchio/ox"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
+)
+
+// importStateName is where the progress of an import is kept, inside
+// the root path, so that an interrupted import can pick up where it
+// left off.
+const importStateName = ".butler-import.json"
+
+type importState struct {
+	Items map[string]*importStateItem `json:"items"`
+}
+
+type importStateItem struct {
+	Status        butlerd.LegacyLibraryImportStatus `json:"status"`
+	Message       string                            `json:"message,omitempty"`
+	CaveID        string                            `json:"caveId,omitempty"`
+	InstallFolder string                            `json:"installFolder,omitempty"`
+
+	// set while a folder is being moved, until its cave is saved
+	Pending bool `json:"pending,omitempty"`
+}
+
+// libraryFolder is what the receipt of a folder tells us about it
+type libraryFolder struct {
+	caveID   string
+	gameID   int64
+	uploadID int64
+	buildID  int64
+	files    []string
+
+	installedAt *time.Time
+}
+
+type libraryImporter struct {
+	rc     *butlerd.RequestContext
+	params butlerd.SystemImportLegacyLibraryParams
+	il     *models.InstallLocation
+
+	statePath string
+	state     *importState
+
+	// IDs of the caves we know of, by install folder
+	caveFolders map[string]string
+}
+
+func SystemImportLegacyLibrary(rc *butlerd.RequestContext, params butlerd.SystemImportLegacyLibraryParams) (*butlerd.SystemImportLegacyLibraryResult, error) {
+	consumer := rc.Consumer
+
+	if !filepath.IsAbs(params.RootPath) {
+		return nil, errors.Errorf("root path must be absolute: (%s)", params.RootPath)
+	}
+
+	li := &libraryImporter{
+		rc:        rc,
+		params:    params,
+		statePath: filepath.Join(params.RootPath, importStateName),
+	}
+
+	if params.Mode == butlerd.LegacyLibraryImportModeMove {
+		if params.InstallLocationID == "" {
+			return nil, errors.New("installLocationId must be set when moving folders")
+		}
+		rc.WithConn(func(conn *sqlite.Conn) {
+			li.il = models.InstallLocationByID(conn, params.InstallLocationID)
+		})
+		if li.il == nil {
+			return nil, errors.Errorf("install location not found (%s)", params.InstallLocationID)
+		}
+	}
+
+	err := li.loadState()
+	if err != nil {
+		return nil, err
+	}
+
+	rc.WithConn(li.indexCaves)
+
+	names, err := li.listFolders()
+	if err != nil {
+		return nil, err
+	}
+	consumer.Opf("Importing %d folders from (%s)", len(names), params.RootPath)
+
+	res := &butlerd.SystemImportLegacyLibraryResult{
+		Items: []*butlerd.LegacyLibraryImportItem{},
+	}
+
+	rc.StartProgress()
+	for i, name := range names {
+		consumer.Progress(float64(i) / float64(len(names)))
+
+		item := li.importFolder(name)
+		if item.Status == butlerd.LegacyLibraryImportStatusFailed {
+			consumer.Warnf("Could not import (%s): %s", name, item.Message)
+		} else {
+			consumer.Infof("(%s): %s", name, item.Status)
+		}
+		res.Items = append(res.Items, item)
+	}
+	rc.EndProgress()
+
+	return res, nil
+}
+
+// listFolders returns the names of the folders to import,
+// relative to the root path.
+func (li *libraryImporter) listFolders() ([]string, error) {
+	root := li.params.RootPath
+
+	// legacy install locations keep games in an "apps" folder
+	if stats, err := os.Stat(filepath.Join(root, "apps")); err == nil && stats.IsDir() {
+		if _, err := os.Stat(filepath.Join(root, "apps", ".itch")); os.IsNotExist(err) {
+			root = filepath.Join(root, "apps")
+		}
+	}
+
+	entries, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() || models.IsReservedFolderName(entry.Name()) {
+			continue
+		}
+		rel, err := filepath.Rel(li.params.RootPath, filepath.Join(root, entry.Name()))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		names = append(names, rel)
+	}
+
+	// folders moved away by a previous import are still reported,
+	// and interrupted moves still need finishing.
+	for name := range li.state.Items {
+		if !containsString(names, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (li *libraryImporter) importFolder(name string) *butlerd.LegacyLibraryImportItem {
+	folder := filepath.Join(li.params.RootPath, name)
+
+	si := li.state.Items[name]
+	if si != nil && si.Pending && li.params.Mode == butlerd.LegacyLibraryImportModeMove {
+		if _, err := os.Stat(folder); os.IsNotExist(err) {
+			// the move went through, but the cave wasn't saved
+			folder = si.InstallFolder
+		}
+	} else if si != nil && !si.Pending {
+		// dealt with by a previous import
+		return si.toItem(filepath.Join(li.params.RootPath, name))
+	}
+
+	si, err := li.importFolderAt(name, folder, si)
+	if err != nil {
+		si = &importStateItem{
+			Status:  butlerd.LegacyLibraryImportStatusFailed,
+			Message: err.Error(),
+		}
+	} else {
+		li.state.Items[name] = si
+		err = li.saveState()
+		if err != nil {
+			li.rc.Consumer.Warnf("Could not save import state: %+v", err)
+		}
+	}
+	return si.toItem(filepath.Join(li.params.RootPath, name))
+}
+
+func (li *libraryImporter) importFolderAt(name string, folder string, pending *importStateItem) (*importStateItem, error) {
+	rc := li.rc
+	consumer := rc.Consumer
+
+	lf, err := readLibraryFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+	if lf == nil {
+		return unrecognized("no receipt found"), nil
+	}
+
+	var idTaken bool
+	if lf.caveID != "" {
+		rc.WithConn(func(conn *sqlite.Conn) {
+			idTaken = models.CaveByID(conn, lf.caveID) != nil
+		})
+	}
+	if existingID, ok := li.caveFolders[filepath.Clean(folder)]; ok {
+		return &importStateItem{
+			Status:        butlerd.LegacyLibraryImportStatusImported,
+			Message:       "already imported",
+			CaveID:        existingID,
+			InstallFolder: folder,
+		}, nil
+	}
+	if idTaken {
+		// imported before from somewhere else: it's a copy
+		lf.caveID = ""
+	}
+
+	var access *operate.GameAccess
+	rc.WithConn(func(conn *sqlite.Conn) {
+		access = operate.AccessForGameID(conn, lf.gameID)
+	})
+	client := rc.Client(access.APIKey)
+
+	gameRes, err := client.GetGame(rc.Ctx, itchio.GetGameParams{
+		GameID:      lf.gameID,
+		Credentials: access.Credentials,
+	})
+	if err != nil {
+		if apiErr, ok := itchio.AsAPIError(err); ok && apiErr.StatusCode == 404 {
+			return unrecognized("game not found on itch.io"), nil
+		}
+		return nil, errors.WithStack(err)
+	}
+	game := gameRes.Game
+
+	uploadsRes, err := client.ListGameUploads(rc.Ctx, itchio.ListGameUploadsParams{
+		GameID:      game.ID,
+		Credentials: access.Credentials,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var upload *itchio.Upload
+	for _, u := range uploadsRes.Uploads {
+		if u.ID == lf.uploadID {
+			upload = u
+			break
+		}
+	}
+	if upload == nil {
+		return unrecognized("upload not found on itch.io"), nil
+	}
+
+	var needsVerification []string
+	var build *itchio.Build
+	if lf.buildID != 0 {
+		buildRes, err := client.GetBuild(rc.Ctx, itchio.GetBuildParams{
+			BuildID:     lf.buildID,
+			Credentials: access.Credentials,
+		})
+		if err != nil {
+			consumer.Warnf("Could not find build %d: %v", lf.buildID, err)
+			needsVerification = append(needsVerification, "build not found on itch.io")
+		} else {
+			build = buildRes.Build
+		}
+	}
+	if len(lf.files) == 0 {
+		needsVerification = append(needsVerification, "list of installed files unknown")
+	}
+
+	cave := &models.Cave{
+		ID:          lf.caveID,
+		Game:        game,
+		Upload:      upload,
+		Build:       build,
+		InstalledAt: lf.installedAt,
+	}
+	if pending != nil {
+		cave.ID = pending.CaveID
+	}
+	if cave.ID == "" {
+		id, err := uuid.NewRandom()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		cave.ID = id.String()
+	}
+
+	installFolder := folder
+	switch li.params.Mode {
+	case butlerd.LegacyLibraryImportModeMove:
+		cave.InstallLocationID = li.il.ID
+		if pending != nil && folder == pending.InstallFolder {
+			cave.InstallFolderName = filepath.Base(folder)
+		} else {
+			cave.InstallFolderName = filepath.Base(name)
+			rc.WithConn(func(conn *sqlite.Conn) {
+				err = ensureUniqueFolderName(conn, cave, 0)
+			})
+			if err != nil {
+				return nil, err
+			}
+			installFolder = li.il.GetInstallFolder(cave.InstallFolderName)
+
+			li.state.Items[name] = &importStateItem{
+				Pending:       true,
+				CaveID:        cave.ID,
+				InstallFolder: installFolder,
+			}
+			err = li.saveState()
+			if err != nil {
+				return nil, err
+			}
+
+			consumer.Infof("Moving (%s) to (%s)", folder, installFolder)
+			err = os.Rename(folder, installFolder)
+			if err != nil {
+				delete(li.state.Items, name)
+				return nil, errors.Wrap(err, "moving folder (is it on another partition?)")
+			}
+		}
+	case butlerd.LegacyLibraryImportModeInPlace:
+		cave.CustomInstallFolder = folder
+	}
+
+	verdict, err := manager.Configure(consumer, installFolder, ox.CurrentRuntime())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	cave.SetVerdict(verdict)
+	cave.InstalledSize = verdict.TotalSize
+
+	receipt := &bfs.Receipt{
+		Game:   game,
+		Upload: upload,
+		Build:  build,
+		Files:  lf.files,
+	}
+	err = receipt.WriteReceipt(installFolder)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	rc.WithConn(func(conn *sqlite.Conn) {
+		err = models.HadesContext().Save(conn, cave,
+			hades.Assoc("Game"),
+			hades.Assoc("Upload"),
+			hades.Assoc("Build"),
+		)
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	li.caveFolders[filepath.Clean(installFolder)] = cave.ID
+
+	si := &importStateItem{
+		Status:        butlerd.LegacyLibraryImportStatusImported,
+		CaveID:        cave.ID,
+		InstallFolder: installFolder,
+	}
+	if len(needsVerification) > 0 {
+		si.Status = butlerd.LegacyLibraryImportStatusNeedsVerification
+		si.Message = needsVerification[0]
+	}
+	return si, nil
+}
+
+// readLibraryFolder reads whatever receipt folder has, returns
+// nil if it hasn't any.
+func readLibraryFolder(folder string) (*libraryFolder, error) {
+	receipt, err := bfs.ReadReceipt(folder)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if receipt != nil && receipt.Game != nil && receipt.Upload != nil {
+		lf := &libraryFolder{
+			gameID:   receipt.Game.ID,
+			uploadID: receipt.Upload.ID,
+			files:    receipt.Files,
+		}
+		if receipt.Build != nil {
+			lf.buildID = receipt.Build.ID
+		}
+		if stats, err := os.Stat(bfs.ReceiptPath(folder)); err == nil {
+			installedAt := stats.ModTime().UTC()
+			lf.installedAt = &installedAt
+		}
+		return lf, nil
+	}
+
+	legacyReceiptPath := filepath.Join(folder, ".itch", "receipt.json")
+	legacyReceiptBytes, err := ioutil.ReadFile(legacyReceiptPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	lr := &legacyReceipt{}
+	err = json.Unmarshal(legacyReceiptBytes, lr)
+	if err != nil || lr.Cave == nil || lr.Cave.GameID == 0 || lr.Cave.UploadID == 0 {
+		return nil, nil
+	}
+
+	lf := &libraryFolder{
+		caveID:   lr.Cave.ID,
+		gameID:   lr.Cave.GameID,
+		uploadID: lr.Cave.UploadID,
+		buildID:  lr.Cave.BuildID,
+		files:    lr.Files,
+	}
+	switch installedAt := lr.Cave.InstalledAt.(type) {
+	case float64:
+		lf.installedAt = fromJSTimestamp(installedAt)
+	case string:
+		lf.installedAt = fromJSDate(installedAt)
+	}
+	return lf, nil
+}
+
+// indexCaves looks up the install folder of every cave once, so
+// folders that were already imported can be told apart quickly.
+func (li *libraryImporter) indexCaves(conn *sqlite.Conn) {
+	var caves []*models.Cave
+	models.MustSelect(conn, &caves, builder.NewCond(), hades.Search{})
+	models.MustPreload(conn, caves, hades.Assoc("InstallLocation"))
+
+	li.caveFolders = make(map[string]string)
+	for _, cave := range caves {
+		li.caveFolders[filepath.Clean(cave.GetInstallFolder(conn))] = cave.ID
+	}
+}
+
+func unrecognized(message string) *importStateItem {
+	return &importStateItem{
+		Status:  butlerd.LegacyLibraryImportStatusUnrecognized,
+		Message: message,
+	}
+}
+
+func (si *importStateItem) toItem(folder string) *butlerd.LegacyLibraryImportItem {
+	return &butlerd.LegacyLibraryImportItem{
+		Folder:        folder,
+		Status:        si.Status,
+		Message:       si.Message,
+		CaveID:        si.CaveID,
+		InstallFolder: si.InstallFolder,
+	}
+}
+
+func (li *libraryImporter) loadState() error {
+	li.state = &importState{}
+	stateBytes, err := ioutil.ReadFile(li.statePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return errors.WithStack(err)
+		}
+	} else {
+		err = json.Unmarshal(stateBytes, li.state)
+		if err != nil {
+			li.rc.Consumer.Warnf("Ignoring corrupted import state: %s", err.Error())
+			li.state = &importState{}
+		}
+	}
+	if li.state.Items == nil {
+		li.state.Items = make(map[string]*importStateItem)
+	}
+	return nil
+}
+
+func (li *libraryImporter) saveState() error {
+	f, err := safefile.Create(li.statePath, 0o644)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+
+	err = json.NewEncoder(f).Encode(li.state)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return f.Commit()
+}
+
+func containsString(list []string, s string) bool {
+	for _, candidate := range list {
+		if candidate == s {
+			return true
+		}
+	}
+	return false
+}
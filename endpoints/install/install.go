@@ -7,8 +7,12 @@ import (
 
 func Register(router *butlerd.Router) {
 	messages.GameFindUploads.Register(router, GameFindUploads)
+	messages.InstallExplainUploadChoice.Register(router, InstallExplainUploadChoice)
 	messages.InstallPlan.Register(router, InstallPlan)
 	messages.InstallQueue.Register(router, InstallQueue)
+	messages.InstallQueueMany.Register(router, InstallQueueMany)
+	messages.CollectionsInstallAll.Register(router, CollectionsInstallAll)
+	messages.CollectionsInstallAllCancel.Register(router, CollectionsInstallAllCancel)
 	messages.InstallPerform.Register(router, InstallPerform)
 	messages.InstallCancel.Register(router, InstallCancel)
 	messages.UninstallPerform.Register(router, UninstallPerform)
@@ -16,9 +20,32 @@ func Register(router *butlerd.Router) {
 	messages.InstallLocationsGetByID.Register(router, InstallLocationsGetByID)
 	messages.InstallLocationsList.Register(router, InstallLocationsList)
 	messages.InstallLocationsAdd.Register(router, InstallLocationsAdd)
+	messages.InstallLocationsUpdate.Register(router, InstallLocationsUpdate)
 	messages.InstallLocationsRemove.Register(router, InstallLocationsRemove)
 	messages.InstallLocationsScan.Register(router, InstallLocationsScan)
+	messages.SystemImportLegacyLibrary.Register(router, SystemImportLegacyLibrary)
 	messages.InstallCreateShortcut.Register(router, InstallCreateShortcut)
 
 	messages.CavesSetPinned.Register(router, CavesSetPinned)
+	messages.CavesSetAutoUpdatePolicy.Register(router, CavesSetAutoUpdatePolicy)
+	messages.CavesGetLaunchTargets.Register(router, CavesGetLaunchTargets)
+	messages.CavesSetLaunchTarget.Register(router, CavesSetLaunchTarget)
+	messages.CavesByProfile.Register(router, CavesByProfile)
+	messages.CavesGetDLCs.Register(router, CavesGetDLCs)
+	messages.CavesFilter.Register(router, CavesFilter)
+	messages.CavesFuzzySearch.Register(router, CavesFuzzySearch)
+	messages.CavesListFiles.Register(router, CavesListFiles)
+	messages.CavesReadFile.Register(router, CavesReadFile)
+	messages.CavesOpenInstallFolder.Register(router, CavesOpenInstallFolder)
+	messages.CavesDetectGhosts.Register(router, CavesDetectGhosts)
+	messages.CavesFindMoved.Register(router, CavesFindMoved)
+	messages.CavesRelink.Register(router, CavesRelink)
+	messages.CavesListEvents.Register(router, CavesListEvents)
+	messages.CavesRebuildReceipt.Register(router, CavesRebuildReceipt)
+	messages.CavesRebuildReceipts.Register(router, CavesRebuildReceipts)
+	messages.CavesApplyNamingTemplate.Register(router, CavesApplyNamingTemplate)
+	messages.CavesListBuildHistory.Register(router, CavesListBuildHistory)
+	messages.CavesDowngrade.Register(router, CavesDowngrade)
+	messages.CaveUninstallBatch.Register(router, CaveUninstallBatch)
+	messages.CaveVerify.Register(router, CaveVerify)
 }
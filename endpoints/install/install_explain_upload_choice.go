@@ -0,0 +1,35 @@
+package install
+
+import (
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/pkg/errors"
+)
+
+func InstallExplainUploadChoice(rc *butlerd.RequestContext, params butlerd.InstallExplainUploadChoiceParams) (*butlerd.InstallExplainUploadChoiceResult, error) {
+	consumer := rc.Consumer
+	consumer.Infof("Explaining upload choice for game %s", operate.GameToString(params.Game))
+
+	explainRes, err := operate.ExplainFilteredUploads(rc, params.Game)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	res := &butlerd.InstallExplainUploadChoiceResult{}
+	for _, e := range explainRes.Explanations {
+		res.Candidates = append(res.Candidates, &butlerd.UploadCandidate{
+			Upload:       e.Upload,
+			Compatible:   e.ExcludedBy == "",
+			ExcludedBy:   butlerd.UploadExclusion(e.ExcludedBy),
+			Score:        e.Score,
+			ScoreReasons: e.ScoreReasons,
+		})
+	}
+
+	uploads := explainRes.Narrowed.Uploads
+	if len(uploads) > 0 {
+		res.AutoSelected = uploads[0]
+	}
+	res.NeedsPick = len(uploads) > 1
+	return res, nil
+}
@@ -31,6 +31,14 @@ func InstallPlan(rc *butlerd.RequestContext, params butlerd.InstallPlanParams) (
 	conn := rc.GetConn()
 	defer rc.PutConn(conn)
 
+	var installLocation *models.InstallLocation
+	if params.InstallLocationID != "" {
+		installLocation = models.InstallLocationByID(conn, params.InstallLocationID)
+		if installLocation == nil {
+			return nil, errors.Errorf("Install location not found (%s)", params.InstallLocationID)
+		}
+	}
+
 	game := fetch.LazyFetchGame(rc, params.GameID)
 	consumer.Opf("Planning install for %s", operate.GameToString(game))
 
@@ -75,9 +83,20 @@ func InstallPlan(rc *butlerd.RequestContext, params butlerd.InstallPlanParams) (
 		return res, nil
 	}
 
-	info := &butlerd.InstallPlanInfo{}
+	info := &butlerd.InstallPlanInfo{
+		FreeSpace: -1,
+	}
 	res.Info = info
 
+	if installLocation != nil {
+		stats, err := statFS(installLocation.Path)
+		if err != nil {
+			consumer.Warnf("Could not check free space of (%s): %+v", installLocation.Path, err)
+		} else {
+			info.FreeSpace = stats.FreeSize
+		}
+	}
+
 	setResError := func(err error) {
 		consumer.Errorf("Planning failed: %+v", err)
 		info.Error = fmt.Sprintf("%+v", err)
@@ -122,7 +141,18 @@ func InstallPlan(rc *butlerd.RequestContext, params butlerd.InstallPlanParams) (
 		upload.Build = buildRes.Build
 	}
 	info.Build = upload.Build
-	operate.LogUpload(consumer, upload, upload.Build)
+	if params.PreferredBuildID != 0 {
+		build, err := fetchPreferredBuild(rc, client, access, upload, params.PreferredBuildID)
+		if err != nil {
+			setResError(err)
+			return res, nil
+		}
+		info.Build = build
+	}
+	operate.LogUpload(consumer, upload, info.Build)
+
+	info.DownloadSize = operate.EstimateDownloadSize(upload, info.Build)
+	info.EstimatedSize = operate.EstimateRequiredSpace(upload, info.Build)
 
 	if upload.Storage == itchio.UploadStorageExternal && operate.IsBadExternalHost(upload.Host) {
 		setResError(errors.WithStack(butlerd.CodeUnsupportedHost))
@@ -163,7 +193,7 @@ func InstallPlan(rc *butlerd.RequestContext, params butlerd.InstallPlanParams) (
 	receiptIn := (*bfs.Receipt)(nil)
 	installFolder := ""
 
-	dui, err := operate.AssessDiskUsage(file, receiptIn, installFolder, installerInfo)
+	dui, err := operate.AssessDiskUsage(file, receiptIn, installFolder, installerInfo, false)
 	if err != nil {
 		setResError(errors.WithStack(err))
 		return res, nil
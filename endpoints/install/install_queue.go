@@ -1,11 +1,17 @@
 package install
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"crawshaw.io/sqlite"
 	petname "github.com/dustinkirkland/golang-petname"
@@ -16,12 +22,150 @@ import (
 	"github.com/itchio/butler/database/models"
 	"github.com/itchio/butler/endpoints/downloads"
 	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/hades"
 	"github.com/itchio/headway/state"
+	"github.com/itchio/headway/united"
 	"github.com/pkg/errors"
+	"golang.org/x/text/unicode/norm"
 	"xorm.io/builder"
 )
 
 func InstallQueue(rc *butlerd.RequestContext, queueParams butlerd.InstallQueueParams) (*butlerd.InstallQueueResult, error) {
+	return installQueue(rc, queueParams, askPickUpload(rc, queueParams))
+}
+
+// uploadPicker settles on one of several compatible uploads
+type uploadPicker func(uploads []*itchio.Upload) (*itchio.Upload, error)
+
+// askPickUpload lets the user pick, with @@PickUploadParams. If the client
+// can't answer, or doesn't within the timeout, the default strategy is used.
+func askPickUpload(rc *butlerd.RequestContext, queueParams butlerd.InstallQueueParams) uploadPicker {
+	return func(uploads []*itchio.Upload) (*itchio.Upload, error) {
+		consumer := rc.Consumer
+		strategy := queueParams.DefaultUploadStrategy
+
+		ctx := rc.Ctx
+		if queueParams.PickUploadTimeoutSeconds > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(queueParams.PickUploadTimeoutSeconds)*time.Second)
+			// stops waiting on the client once we're done, one way or another
+			defer cancel()
+		}
+
+		var r butlerd.PickUploadResult
+		err := rc.CallContext(ctx, messages.PickUpload.Method(), butlerd.PickUploadParams{
+			Uploads: uploads,
+		}, &r)
+		if errors.Is(err, context.DeadlineExceeded) {
+			consumer.Warnf("Client didn't pick an upload within %d seconds", queueParams.PickUploadTimeoutSeconds)
+			if strategy == "" {
+				strategy = butlerd.DefaultUploadStrategyAbort
+			}
+			return pickUploadWithStrategy(consumer, uploads, strategy)
+		}
+
+		if err != nil {
+			if strategy != "" {
+				consumer.Warnf("Client couldn't pick an upload: %s", err.Error())
+				return pickUploadWithStrategy(consumer, uploads, strategy)
+			}
+			return nil, errors.WithStack(err)
+		}
+
+		if r.Index < 0 {
+			return nil, errors.WithStack(butlerd.CodeOperationAborted)
+		}
+
+		upload := uploads[r.Index]
+		consumer.Infof("Client picked upload:")
+		operate.LogUpload(consumer, upload, upload.Build)
+		return upload, nil
+	}
+}
+
+// pickUploadWithStrategy settles on an upload without asking anyone,
+// and logs which one, so automated installs can be audited.
+func pickUploadWithStrategy(consumer *state.Consumer, uploads []*itchio.Upload, strategy butlerd.DefaultUploadStrategy) (*itchio.Upload, error) {
+	var upload *itchio.Upload
+	switch strategy {
+	case butlerd.DefaultUploadStrategyFirst:
+		upload = uploads[0]
+	case butlerd.DefaultUploadStrategyPreferWharf:
+		for _, u := range uploads {
+			if u.Build != nil {
+				upload = u
+				break
+			}
+		}
+		if upload == nil {
+			consumer.Infof("No upload has builds, picking the first one")
+			upload = uploads[0]
+		}
+	case butlerd.DefaultUploadStrategyLargest:
+		upload = uploads[0]
+		for _, u := range uploads[1:] {
+			if u.Size > upload.Size {
+				upload = u
+			}
+		}
+	case butlerd.DefaultUploadStrategySmallest:
+		upload = uploads[0]
+		for _, u := range uploads[1:] {
+			if u.Size < upload.Size {
+				upload = u
+			}
+		}
+	case butlerd.DefaultUploadStrategyFail:
+		consumer.Errorf("Not picking any of %d uploads, as per strategy (%s). They were:", len(uploads), strategy)
+		for _, u := range uploads {
+			operate.LogUpload(consumer, u, u.Build)
+		}
+		return nil, errors.WithStack(&operate.AmbiguousUploadError{
+			Uploads: uploads,
+		})
+	default:
+		consumer.Infof("Not picking any of %d uploads, as per strategy (%s)", len(uploads), butlerd.DefaultUploadStrategyAbort)
+		return nil, errors.WithStack(butlerd.CodeOperationAborted)
+	}
+
+	consumer.Infof("Picked upload as per strategy (%s):", strategy)
+	operate.LogUpload(consumer, upload, upload.Build)
+	return upload, nil
+}
+
+func installQueue(rc *butlerd.RequestContext, queueParams butlerd.InstallQueueParams, pick uploadPicker) (*butlerd.InstallQueueResult, error) {
+	// without queueDownload, the lock is gone by the time the client
+	// queues the download. Calling again finds the same staging folder
+	// (see findResumableStaging) and Downloads.Queue ignores downloads
+	// that are already queued, so both clients end up with the same one.
+	return installQueueThen(rc, queueParams, pick, func(res *butlerd.InstallQueueResult) error {
+		if !queueParams.QueueDownload {
+			return nil
+		}
+		_, err := downloads.DownloadsQueue(rc, butlerd.DownloadsQueueParams{
+			Item: res,
+		})
+		return errors.WithStack(err)
+	})
+}
+
+// installQueueThen is installQueue, except that what's done with the
+// result is up to queue. If there's a download in progress for the same
+// cave or game, see lockAlreadyQueued, it's returned as already queued
+// instead. queue is called while holding that lock, so that other calls
+// find what it queued, but none of the round-trips to the client or the
+// server are: they can take forever.
+func installQueueThen(rc *butlerd.RequestContext, queueParams butlerd.InstallQueueParams, pick uploadPicker, queue func(res *butlerd.InstallQueueResult) error) (*butlerd.InstallQueueResult, error) {
+	// queueParams gets filled in as we go
+	checkParams := queueParams
+	k := acquireQueueKey(checkParams)
+	defer releaseQueueKey(k)
+	unlock, queued := lockAlreadyQueued(rc, k, checkParams)
+	unlock()
+	if queued != nil {
+		return queued, nil
+	}
+
 	var stagingFolder string
 	conn := rc.GetConn()
 	defer rc.PutConn(conn)
@@ -34,7 +178,79 @@ func InstallQueue(rc *butlerd.RequestContext, queueParams butlerd.InstallQueuePa
 		reason = butlerd.DownloadReasonInstall
 	}
 
+	if queueParams.LocalArchivePath != "" {
+		if queueParams.Upload != nil || queueParams.Build != nil || queueParams.PreferredBuildID != 0 {
+			return nil, errors.New("With localArchivePath, upload, build and preferredBuildId cannot be specified")
+		}
+		archivePath, err := filepath.Abs(queueParams.LocalArchivePath)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		queueParams.LocalArchivePath = archivePath
+
+		queueParams.Upload, err = localArchiveUpload(archivePath, nextLocalUploadID(conn))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if queueParams.DryRun && queueParams.QueueDownload {
+		return nil, errors.New("With dryRun, queueDownload cannot be specified")
+	}
+
+	if queueParams.ChannelRequired && queueParams.ChannelName == "" {
+		return nil, errors.New("With channelRequired, channelName must be specified")
+	}
+
+	if queueParams.ResumeStagingFolder != "" && (queueParams.NoCave || queueParams.DryRun) {
+		return nil, errors.New("With noCave or dryRun, resumeStagingFolder cannot be specified")
+	}
+
+	var folderName string
+	if queueParams.InstallFolderName != "" {
+		if queueParams.NoCave || queueParams.CaveID != "" {
+			return nil, errors.New("installFolderName can only be specified for new caves")
+		}
+		folderName = sanitizeFolderName(queueParams.InstallFolderName)
+		if folderName == "" {
+			return nil, errors.Errorf("Install folder name (%s) has nothing usable as a folder name", queueParams.InstallFolderName)
+		}
+	}
+
+	if queueParams.OverflowLocationID != "" {
+		if queueParams.NoCave {
+			return nil, errors.New("With noCave, overflowLocationId cannot be specified")
+		}
+		if models.InstallLocationByID(conn, queueParams.OverflowLocationID) == nil {
+			return nil, errors.Errorf("Overflow location not found (%s)", queueParams.OverflowLocationID)
+		}
+	}
+
+	var parentCave *models.Cave
+	if queueParams.ParentCaveID != "" {
+		if queueParams.NoCave || queueParams.CaveID != "" {
+			return nil, errors.New("parentCaveId can only be specified for new caves")
+		}
+		parentCave = operate.ValidateCave(rc, queueParams.ParentCaveID)
+		if queueParams.InstallLocationID == "" {
+			queueParams.InstallLocationID = parentCave.InstallLocationID
+		}
+	}
+
+	if queueParams.UseParentFolder {
+		if parentCave == nil {
+			return nil, errors.New("With useParentFolder, parentCaveId must be specified")
+		}
+		if queueParams.InstallLocationID != parentCave.InstallLocationID {
+			return nil, errors.New("With useParentFolder, installLocationId must be the parent cave's")
+		}
+		if queueParams.OverflowLocationID != "" {
+			return nil, errors.New("With useParentFolder, overflowLocationId cannot be specified")
+		}
+	}
+
 	var id string
+	var resumed *operate.InstallParams
 	if queueParams.NoCave {
 		if queueParams.StagingFolder == "" {
 			return nil, errors.New("With noCave, installFolder must be specified")
@@ -52,20 +268,64 @@ func InstallQueue(rc *butlerd.RequestContext, queueParams butlerd.InstallQueuePa
 			}
 		} else {
 			cave = operate.ValidateCave(rc, queueParams.CaveID)
+			installLocation = cave.GetInstallLocation(conn)
+		}
+
+		if queueParams.ResumeStagingFolder != "" {
+			var err error
+			id, resumed, err = resumeStaging(rc.Consumer, installLocation, queueParams.ResumeStagingFolder)
+			if err != nil {
+				return nil, err
+			}
+			if resumed != nil {
+				// an update may have been underway, the cave's
+				// upload and build are not the ones to check
+				err = checkResumedParams(&queueParams, resumed)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if cave != nil {
 			if queueParams.Game == nil {
 				queueParams.Game = cave.Game
 			}
 			if queueParams.Upload == nil {
 				queueParams.Upload = cave.Upload
 			}
-			if queueParams.Build == nil {
+			if queueParams.Build == nil && queueParams.LocalArchivePath == "" {
 				queueParams.Build = cave.Build
 			}
+		}
 
-			installLocation = cave.GetInstallLocation(conn)
+		var gameID, uploadID int64
+		if queueParams.Game != nil {
+			gameID = queueParams.Game.ID
+		}
+		if queueParams.Upload != nil {
+			uploadID = queueParams.Upload.ID
+		}
+
+		if id == "" && !queueParams.DryRun {
+			// queued before, but butler went away before it was downloaded?
+			id, resumed = findResumableStaging(conn, rc.Consumer, installLocation, queueParams.CaveID, gameID, uploadID)
+			if resumed != nil {
+				err := checkResumedParams(&queueParams, resumed)
+				if err != nil {
+					return nil, err
+				}
+			}
 		}
 
-		id = generateDownloadID(installLocation.Path)
+		if id == "" {
+			// staging roots may be shared by several locations
+			var err error
+			id, err = generateDownloadID(installLocation.GetStagingRoot(), gameID, uploadID)
+			if err != nil {
+				return nil, err
+			}
+		}
 		stagingFolder = installLocation.GetStagingFolder(id)
 	}
 
@@ -76,10 +336,15 @@ func InstallQueue(rc *butlerd.RequestContext, queueParams butlerd.InstallQueuePa
 	success := false
 	defer func() {
 		oc.Release()
-		if !success {
+		// resumed staging folders are worth keeping, even if
+		// queuing failed this time around
+		if !success && resumed == nil {
 			oc.Retire()
 		}
 	}()
+	if resumed != nil {
+		oc.Consumer().Infof("Resuming install %s in (%s)", id, stagingFolder)
+	}
 
 	consumer := oc.Consumer()
 	meta := operate.NewMetaSubcontext()
@@ -87,18 +352,55 @@ func InstallQueue(rc *butlerd.RequestContext, queueParams butlerd.InstallQueuePa
 
 	params.StagingFolder = stagingFolder
 	params.Reason = reason
-	params.IgnoreInstallers = queueParams.IgnoreInstallers
+	if cave != nil {
+		// sticks for reinstalls and updates, unless set again
+		params.IgnoreInstallers = cave.IgnoreInstallers
+	}
+	if queueParams.IgnoreInstallers != nil {
+		params.IgnoreInstallers = *queueParams.IgnoreInstallers
+	}
+	params.SkipPrereqs = queueParams.SkipPrereqs
+	if queueParams.PlayEarly {
+		if cave != nil {
+			consumer.Infof("Cave (%s) exists, ignoring playEarly", cave.ID)
+		} else {
+			params.PlayEarly = true
+		}
+	}
+	if queueParams.LocalArchivePath != "" {
+		params.LocalArchivePath = queueParams.LocalArchivePath
+		// there's nothing to update it from
+		params.Pinned = true
+	}
 
+	if queueParams.LocalArchivePath != "" {
+		if queueParams.Game == nil {
+			queueParams.Game = localArchiveGame(queueParams.LocalArchivePath, nextLocalGameID(conn))
+		}
+	}
 	if queueParams.Game == nil {
 		return nil, errors.New("Missing game in install")
 	}
 
 	params.Game = queueParams.Game
-	params.Access = operate.AccessForGameID(conn, params.Game.ID)
+	if params.LocalArchivePath != "" {
+		// no credentials needed, and there might not be any profile
+		params.Access = &operate.GameAccess{}
+	} else {
+		accessReq := operate.AccessRequest{
+			GameID:    params.Game.ID,
+			ProfileID: queueParams.ProfileID,
+		}
+		if cave != nil {
+			accessReq.CaveProfileID = cave.SourceProfileID
+		}
+		params.Access = operate.ResolveAccess(conn, accessReq)
+	}
 
 	client := rc.Client(params.Access.APIKey)
 
 	consumer.Infof("Queuing install for %s", operate.GameToString(params.Game))
+	operate.LogAccess(consumer, params.Access)
 
 	freshCave := false
 	if queueParams.NoCave {
@@ -116,32 +418,80 @@ func InstallQueue(rc *butlerd.RequestContext, queueParams butlerd.InstallQueuePa
 				ID:                uuid.New().String(),
 				InstallLocationID: queueParams.InstallLocationID,
 			}
+			if parentCave != nil {
+				cave.DLCParentCaveID = parentCave.ID
+			}
+			if resumed != nil {
+				resumeFreshCave(conn, consumer, cave, resumed, folderName)
+			}
 			consumer.Infof("Generated fresh cave %s", cave.ID)
 		} else {
 			consumer.Infof("Re-using cave %s", cave.ID)
 		}
 		params.CaveID = cave.ID
+		params.InstallLocationID = cave.InstallLocationID
+		params.ParentCaveID = cave.DLCParentCaveID
 
-		if cave.InstallFolderName == "" {
-			cave.InstallFolderName = makeInstallFolderName(params.Game, consumer)
-			ensureUniqueFolderName(conn, cave)
+		params.OverflowLocationID = queueParams.OverflowLocationID
+		if params.OverflowLocationID == "" {
+			params.OverflowLocationID = cave.OverflowLocationID
+		} else if params.OverflowLocationID == params.InstallLocationID {
+			return nil, errors.New("overflowLocationId must be another install location than the cave's")
 		}
-
-		params.InstallFolder = cave.GetInstallFolder(conn)
-		params.InstallLocationID = cave.InstallLocationID
-		params.InstallFolderName = cave.InstallFolderName
 	}
 
 	params.Upload = queueParams.Upload
 	params.Build = queueParams.Build
 
+	var lastPhase butlerd.InstallQueuePhase
+	notifyPhase := func(phase butlerd.InstallQueuePhase) {
+		if queueParams.DryRun || phase == lastPhase {
+			// dry run results don't have an ID to match
+			return
+		}
+		lastPhase = phase
+		_ = messages.InstallQueueProgress.Notify(rc, butlerd.InstallQueueProgressNotification{
+			ID:     id,
+			GameID: params.Game.ID,
+			CaveID: params.CaveID,
+			Phase:  phase,
+		})
+	}
+
+	if params.Upload == nil && operate.Simulation != nil {
+		return nil, errors.New("Transfers are simulated, an upload must be specified")
+	}
+
 	if params.Upload == nil {
+		// pre-ordered games can't be installed until they're out
+		notifyPhase(butlerd.InstallQueuePhaseRefreshingGame)
+		releaseGame := params.Game
+		gameRes, err := operate.RetryGetGame(rc.Ctx, consumer, client, itchio.GetGameParams{
+			GameID:      params.Game.ID,
+			Credentials: params.Access.Credentials,
+		})
+		if err != nil {
+			consumer.Warnf("Could not refresh game info: %v", err)
+		} else {
+			releaseGame = gameRes.Game
+		}
+		err = checkReleased(rc, queueParams, params.Access, releaseGame, nil)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
 		consumer.Infof("No upload specified, looking for compatible ones...")
+		notifyPhase(butlerd.InstallQueuePhaseResolvingUpload)
 		uploadsFilterResult, err := operate.GetFilteredUploads(rc, params.Game)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
 
+		err = checkReleased(rc, queueParams, params.Access, releaseGame, uploadsFilterResult.InitialUploads)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
 		if len(uploadsFilterResult.Uploads) == 0 {
 			consumer.Errorf("Didn't find a compatible upload.")
 			consumer.Errorf("The initial %d uploads were:", len(uploadsFilterResult.InitialUploads))
@@ -149,24 +499,41 @@ func InstallQueue(rc *butlerd.RequestContext, queueParams butlerd.InstallQueuePa
 				operate.LogUpload(consumer, upload, upload.Build)
 			}
 
-			return nil, errors.WithStack(butlerd.CodeNoCompatibleUploads)
+			return nil, errors.WithStack(&operate.NoCompatibleUploadsError{
+				Rejections: uploadsFilterResult.Rejections,
+			})
 		}
 
-		if len(uploadsFilterResult.Uploads) == 1 {
-			params.Upload = uploadsFilterResult.Uploads[0]
-		} else {
-			r, err := messages.PickUpload.Call(rc, butlerd.PickUploadParams{
-				Uploads: uploadsFilterResult.Uploads,
-			})
-			if err != nil {
-				return nil, errors.WithStack(err)
+		uploads := uploadsFilterResult.Uploads
+		if queueParams.ChannelName != "" {
+			inChannel := filterUploadsByChannel(uploads, queueParams.ChannelName)
+			if len(inChannel) > 0 {
+				consumer.Infof("%d of %d compatible uploads are in channel (%s)", len(inChannel), len(uploads), queueParams.ChannelName)
+				uploads = inChannel
+			} else if queueParams.ChannelRequired {
+				consumer.Errorf("None of the %d compatible uploads are in channel (%s)", len(uploads), queueParams.ChannelName)
+				rejections := uploadsFilterResult.Rejections
+				for _, upload := range uploads {
+					rejections = append(rejections, &butlerd.UploadRejection{
+						Upload: upload,
+						Reason: butlerd.UploadExclusionChannel,
+					})
+				}
+				return nil, errors.WithStack(&operate.NoCompatibleUploadsError{
+					Rejections: rejections,
+				})
+			} else {
+				consumer.Warnf("None of the %d compatible uploads are in channel (%s), considering them all", len(uploads), queueParams.ChannelName)
 			}
+		}
 
-			if r.Index < 0 {
-				return nil, errors.WithStack(butlerd.CodeOperationAborted)
+		if len(uploads) == 1 {
+			params.Upload = uploads[0]
+		} else {
+			params.Upload, err = pick(uploads)
+			if err != nil {
+				return nil, err
 			}
-
-			params.Upload = uploadsFilterResult.Uploads[r.Index]
 		}
 
 		if params.Upload.Build != nil {
@@ -177,6 +544,23 @@ func InstallQueue(rc *butlerd.RequestContext, queueParams butlerd.InstallQueuePa
 	} else {
 		consumer.Infof("Upload specified:")
 		operate.LogUpload(consumer, params.Upload, params.Build)
+
+		if params.Upload.Preorder {
+			err := checkReleased(rc, queueParams, params.Access, params.Game, []*itchio.Upload{params.Upload})
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+	}
+
+	if cave != nil && reason == butlerd.DownloadReasonUpdate {
+		// see update.ConfirmUploadSuccessor
+		replaced := cave.ReplacedUploadID != 0 && cave.ReplacedUploadID == cave.UploadID
+		switching := params.Upload.ID != cave.UploadID
+		if replaced && switching && params.Upload.ID != cave.SuccessorUploadID {
+			consumer.Errorf("Upload %d was replaced, but switching to upload %d wasn't confirmed", cave.UploadID, params.Upload.ID)
+			return nil, errors.WithStack(butlerd.CodeUploadSuccessorNotConfirmed)
+		}
 	}
 
 	if freshCave {
@@ -191,9 +575,20 @@ func InstallQueue(rc *butlerd.RequestContext, queueParams butlerd.InstallQueuePa
 	}
 
 	// params.Upload can't be nil by now
-	if params.Build == nil {
+	if queueParams.PreferredBuildID != 0 {
+		build, err := fetchPreferredBuild(rc, client, params.Access, params.Upload, queueParams.PreferredBuildID)
+		if err != nil {
+			return nil, err
+		}
+		consumer.Infof("Installing preferred build %d, cave will be pinned", build.ID)
+		params.Build = build
+		params.Pinned = true
+	}
+
+	if params.Build == nil && operate.Simulation == nil && params.LocalArchivePath == "" {
 		// We were passed an upload but not a build:
 		// Let's refresh upload info so we can settle on a build we want to install (if any)
+		notifyPhase(butlerd.InstallQueuePhaseResolvingUpload)
 
 		listUploadsRes, err := client.ListGameUploads(rc.Ctx, itchio.ListGameUploadsParams{
 			GameID:      params.Game.ID,
@@ -233,6 +628,85 @@ func InstallQueue(rc *butlerd.RequestContext, queueParams butlerd.InstallQueuePa
 		}
 	}
 
+	if cave != nil {
+		// templates may need the upload and build
+		var err error
+		if freshCave && queueParams.UseParentFolder {
+			err = nameDLCFolder(conn, consumer, cave, parentCave, params, folderName, int(queueParams.UniqueFolderMaxTries))
+		} else {
+			err = nameInstallFolder(conn, consumer, cave, params, folderName, int(queueParams.UniqueFolderMaxTries))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// updates and reinstalls reuse the space of the existing
+	// install, so only fresh installs are checked.
+	if freshCave {
+		if queueParams.IgnoreDiskSpace {
+			consumer.Infof("Not checking free disk space, as requested")
+		} else {
+			downloads := params.LocalArchivePath == ""
+			var err error
+			if params.OverflowLocationID != "" {
+				// there's no telling which files go where yet
+				consumer.Infof("Install is split across two locations, checking free space once the upload is probed")
+				if downloads && installLocation.StagingPath != "" {
+					err = checkStagingSpace(consumer, installLocation.StagingPath, params.Upload, params.Build)
+				}
+			} else {
+				err = checkLocationSpace(consumer, installLocation, params.Upload, params.Build, downloads)
+			}
+			if err != nil {
+				nes, ok := errors.Cause(err).(*operate.NotEnoughSpaceError)
+				if !ok || !queueParams.AllowFallbackLocation || params.UseParentFolder {
+					return nil, err
+				}
+				if resumed != nil {
+					// moving it elsewhere would throw away what was
+					// already downloaded, see the deferred cleanup
+					consumer.Warnf("Not enough space in install location %s, not looking for another one: the install is resumed", installLocation.ID)
+					return nil, err
+				}
+
+				consumer.Infof("Not enough space in install location %s, looking for another one", installLocation.ID)
+				var candidates []*models.InstallLocation
+				models.MustSelect(conn, &candidates, builder.NotIn("id", installLocation.ID, params.OverflowLocationID), hades.Search{})
+				fallback, err := findFallbackLocation(consumer, installLocation, nes, candidates, params.Upload, params.Build, downloads)
+				if err != nil {
+					return nil, err
+				}
+				consumer.Infof("Installing to install location %s (%s) instead", fallback.ID, fallback.Path)
+
+				installLocation = fallback
+				cave.InstallLocationID = fallback.ID
+				cave.InstallLocation = fallback
+				// it may have another folder name template
+				cave.InstallFolderName = ""
+				err = nameInstallFolder(conn, consumer, cave, params, folderName, int(queueParams.UniqueFolderMaxTries))
+				if err != nil {
+					return nil, err
+				}
+
+				// the staging folder goes along with it
+				id, err = generateDownloadID(installLocation.GetStagingRoot(), params.Game.ID, params.Upload.ID)
+				if err != nil {
+					return nil, err
+				}
+				stagingFolder = installLocation.GetStagingFolder(id)
+				fallbackOC, err := operate.LoadContext(rc.Ctx, rc, stagingFolder)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				oc.Retire()
+				oc = fallbackOC
+				consumer = oc.Consumer()
+				params.StagingFolder = stagingFolder
+			}
+		}
+	}
+
 	oc.Save(meta)
 
 	istate := &operate.InstallSubcontextState{}
@@ -241,21 +715,52 @@ func InstallQueue(rc *butlerd.RequestContext, queueParams butlerd.InstallQueuePa
 	}
 	oc.Load(isub)
 
-	if queueParams.FastQueue {
+	var diskUsage *operate.DiskUsageInfo
+	var strategy butlerd.InstallStrategy
+	if queueParams.FastQueue || operate.Simulation != nil {
+		// simulated transfers have nothing to probe
 		params.FastQueue = true
 	} else {
+		notifyPhase(butlerd.InstallQueuePhasePreparing)
+		oc.SetInstallID(id)
 		err = operate.InstallPrepare(oc, meta, isub, false /* disallow downloads */, func(res *operate.InstallPrepareResult) error {
-			// do muffin
+			diskUsage = res.DiskUsage
+			switch res.Strategy {
+			case operate.InstallPerformStrategyUpgrade:
+				strategy = butlerd.InstallStrategyPatch
+			case operate.InstallPerformStrategyHeal:
+				strategy = butlerd.InstallStrategyHeal
+			default:
+				strategy = butlerd.InstallStrategyArchive
+			}
 			return nil
 		})
 		if err != nil {
-			oc.Retire()
+			// retired by the deferred cleanup, unless it was resumed
 			return nil, errors.WithStack(err)
 		}
 
+		if queueParams.IgnoreDiskSpace {
+			consumer.Infof("Not running free disk space preflight, as requested")
+		} else {
+			spacePath := installLocation.Path
+			if cave != nil && cave.CustomInstallFolder != "" {
+				// might be on another disk entirely
+				spacePath = cave.CustomInstallFolder
+			}
+			var overflowPath string
+			if params.OverflowLocationID != "" {
+				if il := models.InstallLocationByID(conn, params.OverflowLocationID); il != nil {
+					overflowPath = il.Path
+				}
+			}
+			err = checkPreparedSpace(consumer, spacePath, overflowPath, diskUsage)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	success = true
 	res := &butlerd.InstallQueueResult{
 		ID:                id,
 		CaveID:            params.CaveID,
@@ -266,28 +771,229 @@ func InstallQueue(rc *butlerd.RequestContext, queueParams butlerd.InstallQueuePa
 		StagingFolder:     params.StagingFolder,
 		Reason:            params.Reason,
 		InstallLocationID: params.InstallLocationID,
+		InstallFolderName: params.InstallFolderName,
+		Access:            params.Access.Explanation,
+		Strategy:          strategy,
 	}
+	estimateQueuedSizes(res, params, isub.Data, diskUsage)
 
-	if queueParams.QueueDownload {
-		_, err := downloads.DownloadsQueue(rc, butlerd.DownloadsQueueParams{
-			Item: res,
+	if queueParams.DryRun {
+		// the staging folder gets wiped on the way out
+		res.ID = ""
+		res.StagingFolder = ""
+		if freshCave {
+			res.CaveID = ""
+		}
+		consumer.Infof("Dry run: would install to (%s), using about %s", res.InstallFolder, united.FormatBytes(res.EstimatedInstallSize))
+		return res, nil
+	}
+	if params.LocalArchivePath != "" {
+		// only kept once there's something to install
+		models.MustSave(conn, params.Game)
+		models.MustSave(conn, params.Upload)
+	}
+
+	// other calls may have queued it while this one was
+	// waiting on the client or the server
+	unlock, queued = lockAlreadyQueued(rc, k, checkParams)
+	defer unlock()
+	if queued != nil {
+		// theirs may be the very staging folder this one resumed
+		success = queued.StagingFolder == stagingFolder
+		return queued, nil
+	}
+	if k != nil && !queueParams.NoCave {
+		if resumed == nil && k.readied != "" && k.readied != stagingFolder {
+			// or readied it in another staging folder, for
+			// clients that queue the download themselves
+			other := resumableStaging(conn, installLocation, k.readied, checkParams.CaveID, params.Game.ID, params.Upload.ID)
+			if other != nil {
+				consumer.Infof("Install was readied in (%s) meanwhile, using that", k.readied)
+				// ours gets retired by the deferred cleanup
+				adoptStaging(res, other)
+				err = queue(res)
+				if err != nil {
+					return nil, err
+				}
+				return res, nil
+			}
+		}
+		k.readied = stagingFolder
+	}
+	success = true
+
+	err = queue(res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// estimateQueuedSizes fills in how much res will download and take up
+// once installed, from what InstallPrepare found out if it ran, from
+// what itch.io says about the upload and build otherwise. Nothing is
+// known about external uploads until they're downloaded.
+func estimateQueuedSizes(res *butlerd.InstallQueueResult, params *operate.InstallParams, istate *operate.InstallSubcontextState, diskUsage *operate.DiskUsageInfo) {
+	if params.Upload.Storage == itchio.UploadStorageExternal {
+		return
+	}
+
+	switch {
+	case params.LocalArchivePath != "":
+		// nothing to download
+	case res.Strategy == butlerd.InstallStrategyPatch && istate.UpgradePath != nil:
+		res.EstimatedDownloadSize = operate.EstimatePatchesSize(istate.UpgradePath, istate.UpgradePathIndex)
+	case res.Strategy == butlerd.InstallStrategyHeal:
+		// only what's missing or changed, which isn't known yet
+	default:
+		res.EstimatedDownloadSize = operate.EstimateDownloadSize(params.Upload, params.Build)
+	}
+
+	if diskUsage != nil && diskUsage.Accuracy != operate.AccuracyNone {
+		res.EstimatedInstallSize = diskUsage.FinalDiskUsage
+	} else {
+		res.EstimatedInstallSize = operate.EstimateRequiredSpace(params.Upload, params.Build)
+	}
+}
+
+// filterUploadsByChannel returns the uploads in the wharf channel
+// named channelName, keeping their order.
+func filterUploadsByChannel(uploads []*itchio.Upload, channelName string) []*itchio.Upload {
+	var res []*itchio.Upload
+	for _, u := range uploads {
+		if u.ChannelName == channelName {
+			res = append(res, u)
+		}
+	}
+	return res
+}
+
+// nameInstallFolder gives cave an install folder name, if it doesn't
+// have one yet: folderName if the caller picked one, from its install
+// location's folder name template if it has one, from the game's slug
+// otherwise. Then it points params at the cave's install folder.
+func nameInstallFolder(conn *sqlite.Conn, consumer *state.Consumer, cave *models.Cave, params *operate.InstallParams, folderName string, maxTries int) error {
+	if cave.InstallFolderName == "" {
+		il := cave.GetInstallLocation(conn)
+		switch {
+		case folderName != "":
+			cave.InstallFolderName = folderName
+		case params.LocalArchivePath != "":
+			cave.InstallFolderName = localArchiveFolderName(params.LocalArchivePath)
+		case il.FolderNameTemplate != "":
+			cave.InstallFolderName = renderFolderNameTemplate(il.FolderNameTemplate, params.Game, params.Upload, params.Build, consumer)
+			consumer.Infof("Install folder name (%s) made from template (%s)", cave.InstallFolderName, il.FolderNameTemplate)
+		default:
+			cave.InstallFolderName = makeInstallFolderName(params.Game, consumer)
+		}
+		err := ensureUniqueFolderName(conn, cave, maxTries)
+		if err != nil {
+			return err
+		}
+	}
+
+	params.InstallFolder = cave.GetInstallFolder(conn)
+	params.InstallLocationID = cave.InstallLocationID
+	params.InstallFolderName = cave.InstallFolderName
+	return nil
+}
+
+// nameDLCFolder is nameInstallFolder for a fresh cave that goes inside
+// the install folder of parentCave: its folder is named after the upload,
+// since it's often of the same game as the parent.
+func nameDLCFolder(conn *sqlite.Conn, consumer *state.Consumer, cave *models.Cave, parentCave *models.Cave, params *operate.InstallParams, folderName string, maxTries int) error {
+	if cave.CustomInstallFolder == "" {
+		name := folderName
+		if name == "" && params.Upload != nil {
+			name = sanitizeFolderName(params.Upload.DisplayName)
+			if name == "" {
+				name = sanitizeFolderName(localArchiveFolderName(params.Upload.Filename))
+			}
+		}
+		if name == "" {
+			name = makeInstallFolderName(params.Game, consumer)
+		}
+
+		parentFolder := parentCave.GetInstallFolder(conn)
+		name, err := uniqueFolderName(name, maxTries, func(name string) bool {
+			_, err := os.Stat(filepath.Join(parentFolder, name))
+			return err == nil
 		})
 		if err != nil {
-			return nil, errors.WithStack(err)
+			return errors.Wrapf(err, "in (%s)", parentFolder)
 		}
+		cave.InstallFolderName = name
+		cave.CustomInstallFolder = filepath.Join(parentFolder, name)
+		consumer.Infof("Installing DLC inside of parent cave (%s), in (%s)", parentCave.ID, cave.CustomInstallFolder)
 	}
 
-	return res, nil
+	params.InstallFolder = cave.CustomInstallFolder
+	params.InstallLocationID = cave.InstallLocationID
+	params.InstallFolderName = cave.InstallFolderName
+	params.UseParentFolder = true
+	return nil
 }
 
 func makeInstallFolderName(game *itchio.Game, consumer *state.Consumer) string {
-	name := makeInstallFolderNameFromSlug(game, consumer)
+	name := sanitizeFolderName(makeInstallFolderNameFromSlug(game, consumer))
 	if name == "" {
 		name = makeInstallFolderNameFromID(game, consumer)
 	}
 	return name
 }
 
+// Well under the 255 bytes NTFS and ext4 allow per path component,
+// so there's room left for uniqueFolderName's suffixes.
+const maxFolderNameBytes = 200
+
+// sanitizeFolderName makes name usable as a folder name on all the
+// platforms we run on: characters Windows doesn't allow (path separators
+// included) are replaced with dashes, it's normalized to NFC, names
+// Windows reserves for devices get an underscore, trailing dots and
+// spaces (which Windows drops) are trimmed, and it's truncated to
+// maxFolderNameBytes without splitting UTF-8 sequences.
+// It returns an empty string if nothing usable is left.
+//
+// Windows' rules are followed whatever the OS butler runs on: install
+// locations on removable or dual-boot drives may be read by Windows
+// later, and the same game should get the same folder name anywhere.
+func sanitizeFolderName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if isForbiddenFolderNameRune(r) {
+			return '-'
+		}
+		return r
+	}, name)
+
+	// macOS hands out decomposed names, most everything else composed
+	// ones: the same name typed anywhere should give the same folder.
+	name = norm.NFC.String(name)
+
+	name = strings.TrimRight(strings.TrimSpace(name), ". ")
+	name = reservedFolderNameRe.ReplaceAllString(name, "${1}_${2}")
+
+	if len(name) > maxFolderNameBytes {
+		cut := maxFolderNameBytes
+		for cut > 0 && !utf8.RuneStart(name[cut]) {
+			cut--
+		}
+		name = name[:cut]
+	}
+
+	return strings.TrimRight(strings.TrimSpace(name), ". ")
+}
+
+// Windows won't create folders named after devices, even
+// with an extension, like `con` or `LPT1.txt`. That includes
+// the superscript digits of code page 1252.
+var reservedFolderNameRe = regexp.MustCompile(`(?i)^(CON|PRN|AUX|NUL|COM[0-9¹²³]|LPT[0-9¹²³])( *(?:\..*)?)$`)
+
+// isForbiddenFolderNameRune returns true for characters that
+// can't be part of a folder name on Windows.
+func isForbiddenFolderNameRune(r rune) bool {
+	return r < 0x20 || r == 0x7f || r == utf8.RuneError || strings.ContainsRune(`<>:"/\|?*`, r)
+}
+
 var slugRe = regexp.MustCompile(`^\/([^\/]+)`)
 
 func makeInstallFolderNameFromSlug(game *itchio.Game, consumer *state.Consumer) string {
@@ -313,39 +1019,150 @@ func makeInstallFolderNameFromID(game *itchio.Game, consumer *state.Consumer) st
 	return fmt.Sprintf("game-%d", game.ID)
 }
 
-func ensureUniqueFolderName(conn *sqlite.Conn, cave *models.Cave) {
+const (
 	// Once we reach "Overland 200", it's time to stop
-	const uniqueMaxTries = 200
-	base := cave.InstallFolderName
-	suffix := 2
+	defaultUniqueFolderMaxTries = 200
+	// After "Overland 10", we go for "Overland (brave-otter)" instead
+	uniqueFolderSequentialTries = 10
+)
 
-	for i := 0; i < uniqueMaxTries; i++ {
-		folder := cave.GetInstallFolder(conn)
-		_, err := os.Stat(folder)
-		alreadyExists := (err == nil)
+// ensureUniqueFolderName changes the install folder name of cave
+// until no such folder exists, and it doesn't contain the install
+// folder of another cave, trying at most maxTries names (or
+// defaultUniqueFolderMaxTries, if zero). Names inside the install
+// folder of another cave are rejected, since no suffix fixes them.
+func ensureUniqueFolderName(conn *sqlite.Conn, cave *models.Cave, maxTries int) error {
+	il := cave.GetInstallLocation(conn)
+	others := caveFolderNames(conn, il, cave.ID)
+	if parent := parentFolderName(others, cave.InstallFolderName); parent != "" {
+		return errors.Errorf("install folder name (%s) is inside the install folder of another cave (%s)", cave.InstallFolderName, parent)
+	}
+	name, err := uniqueFolderName(cave.InstallFolderName, maxTries, func(name string) bool {
+		if models.IsReservedFolderName(name) {
+			return true
+		}
+		if containsFolderName(others, name) {
+			return true
+		}
+		_, err := os.Stat(il.GetInstallFolder(name))
+		return err == nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "in (%s)", il.Path)
+	}
+	cave.InstallFolderName = name
+	return nil
+}
+
+// caveFolderNames returns the install folder names of the caves in il,
+// other than exceptCaveID. Caves with a custom install folder don't
+// count, even if it happens to be in il.
+func caveFolderNames(conn *sqlite.Conn, il *models.InstallLocation, exceptCaveID string) []string {
+	var caves []*models.Cave
+	models.MustSelect(conn, &caves, builder.And(
+		builder.Eq{"install_location_id": il.ID},
+		builder.Eq{"custom_install_folder": ""},
+		builder.Neq{"id": exceptCaveID},
+	), hades.Search{})
+
+	var names []string
+	for _, cave := range caves {
+		if cave.InstallFolderName != "" {
+			names = append(names, cave.InstallFolderName)
+		}
+	}
+	return names
+}
 
-		if !alreadyExists {
+// Folder name templates may have subfolders, so install folder names
+// must be checked against each other, not just for equality.
+
+// parentFolderName returns the one of names that name is a folder
+// inside of, if any.
+func parentFolderName(names []string, name string) string {
+	for _, other := range names {
+		if strings.HasPrefix(name, other+"/") {
+			return other
+		}
+	}
+	return ""
+}
+
+// containsFolderName returns true if name is one of names, or
+// a folder that contains one of them.
+func containsFolderName(names []string, name string) bool {
+	for _, other := range names {
+		if name == other || strings.HasPrefix(other, name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueFolderName(base string, maxTries int, taken func(name string) bool) (string, error) {
+	if maxTries <= 0 {
+		maxTries = defaultUniqueFolderMaxTries
+	}
+
+	name := base
+	for i := 0; i < maxTries; i++ {
+		if !taken(name) {
 			// coolio
-			return
+			return name, nil
 		}
 
-		// uh oh, it exists
-		cave.InstallFolderName = fmt.Sprintf("%s %d", base, suffix)
-		suffix++
+		// uh oh, it exists. scanning sequentially gets slow with
+		// hundreds of copies, so go for random names after a while.
+		if i+2 <= uniqueFolderSequentialTries {
+			name = fmt.Sprintf("%s %d", base, i+2)
+		} else {
+			name = fmt.Sprintf("%s (%s)", base, petname.Generate(2, "-"))
+		}
 	}
 
-	cave.InstallFolderName = base
-	err := errors.Errorf("Could not ensure unique install folder starting with (%s)", cave.GetInstallFolder(conn))
-	panic(err)
+	return "", errors.Wrapf(butlerd.CodeInstallFolderExhausted, "tried %d install folder names starting with (%s)", maxTries, base)
 }
 
-func generateDownloadID(basePath string) string {
+// swapped out in tests
+var (
+	statDownloadFolder = os.Stat
+	newDownloadUUID    = uuid.NewRandom
+)
+
+// stableDownloadID is the download ID an install of gameID/uploadID
+// is first tried with in basePath, so queuing it again finds the
+// same staging folder, see findResumableStaging
+func stableDownloadID(basePath string, gameID int64, uploadID int64) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d|%d|%s", gameID, uploadID, basePath)))
+	return hex.EncodeToString(sum[:])[:8]
+}
+
+// generateDownloadID returns the ID of a staging folder that doesn't
+// exist yet in basePath: stableDownloadID if it's free, a petname
+// otherwise. gameID or uploadID are 0 if unknown, then only petnames
+// are tried.
+func generateDownloadID(basePath string, gameID int64, uploadID int64) (string, error) {
+	if gameID != 0 && uploadID != 0 {
+		id := stableDownloadID(basePath, gameID, uploadID)
+		_, err := statDownloadFolder(filepath.Join(basePath, id))
+		if err != nil && os.IsNotExist(err) {
+			return id, nil
+		}
+	}
+
 	for tries := 100; tries > 0; tries-- {
 		id := petname.Generate(3, "-")
-		_, err := os.Stat(filepath.Join(basePath, id))
+		_, err := statDownloadFolder(filepath.Join(basePath, id))
 		if err != nil && os.IsNotExist(err) {
-			return id
+			return id, nil
 		}
 	}
-	return uuid.New().String()
+
+	// all the petnames we tried are taken, go for something
+	// with a lot more entropy.
+	u, err := newDownloadUUID()
+	if err != nil {
+		return "", errors.Wrapf(err, "generating download ID in (%s), all petnames taken", basePath)
+	}
+	return u.String(), nil
 }
@@ -0,0 +1,112 @@
+package install
+
+import (
+	"fmt"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/pkg/errors"
+)
+
+// needsPickError is returned by deferPick, so the item can be
+// queued again once an upload was picked.
+type needsPickError struct {
+	uploads []*itchio.Upload
+}
+
+func (e *needsPickError) Error() string {
+	return fmt.Sprintf("%d compatible uploads, one needs to be picked", len(e.uploads))
+}
+
+func deferPick(uploads []*itchio.Upload) (*itchio.Upload, error) {
+	return nil, &needsPickError{uploads: uploads}
+}
+
+func InstallQueueMany(rc *butlerd.RequestContext, params butlerd.InstallQueueManyParams) (*butlerd.InstallQueueManyResult, error) {
+	consumer := rc.Consumer
+	consumer.Infof("Queuing installs for %d games", len(params.Items))
+
+	res := &butlerd.InstallQueueManyResult{}
+	var picks []*butlerd.PickUploadsItem
+	var pickItems []int
+
+	queue := func(i int, upload *itchio.Upload) {
+		item := params.Items[i]
+		itemRes := res.Items[i]
+
+		queued, err := installQueue(rc, butlerd.InstallQueueParams{
+			Game:              item.Game,
+			Upload:            upload,
+			Build:             item.Build,
+			InstallLocationID: item.InstallLocationID,
+			QueueDownload:     params.QueueDownload,
+		}, deferPick)
+		if err != nil {
+			var npe *needsPickError
+			if errors.As(err, &npe) {
+				itemRes.Status = butlerd.InstallQueueManyStatusNeedsAttention
+				itemRes.Uploads = npe.uploads
+				picks = append(picks, &butlerd.PickUploadsItem{
+					Game:    item.Game,
+					Uploads: npe.uploads,
+				})
+				pickItems = append(pickItems, i)
+				return
+			}
+
+			consumer.Warnf("Could not queue install for game %d: %+v", item.Game.ID, err)
+			itemRes.Status = butlerd.InstallQueueManyStatusFailed
+			itemRes.Error = err.Error()
+			if be, ok := butlerd.AsButlerdError(err); ok {
+				itemRes.ErrorCode = be.RpcErrorCode()
+				itemRes.Error = be.RpcErrorMessage()
+			}
+			return
+		}
+
+		itemRes.Status = butlerd.InstallQueueManyStatusQueued
+		itemRes.Queued = queued
+	}
+
+	for i, item := range params.Items {
+		res.Items = append(res.Items, &butlerd.InstallQueueManyItemResult{
+			Game: item.Game,
+		})
+		queue(i, item.Upload)
+	}
+
+	if len(picks) == 0 || !params.PickUploads {
+		return res, nil
+	}
+
+	consumer.Infof("Asking to pick uploads for %d games", len(picks))
+	r, err := messages.PickUploads.Call(rc, butlerd.PickUploadsParams{
+		Items: picks,
+	})
+	if err != nil {
+		consumer.Warnf("Could not pick uploads, leaving them for later: %s", err.Error())
+		return res, nil
+	}
+
+	for j, i := range pickItems {
+		itemRes := res.Items[i]
+		index := int64(-1)
+		if j < len(r.Indices) {
+			index = r.Indices[j]
+		}
+		if index < 0 || index >= int64(len(itemRes.Uploads)) {
+			itemRes.Status = butlerd.InstallQueueManyStatusFailed
+			itemRes.Uploads = nil
+			itemRes.Error = butlerd.CodeOperationAborted.RpcErrorMessage()
+			itemRes.ErrorCode = butlerd.CodeOperationAborted.RpcErrorCode()
+			continue
+		}
+
+		upload := itemRes.Uploads[index]
+		itemRes.Uploads = nil
+		queue(i, upload)
+	}
+
+	return res, nil
+}
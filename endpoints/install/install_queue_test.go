@@ -0,0 +1,339 @@
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"crawshaw.io/sqlite"
+	"github.com/google/uuid"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database"
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// takenFolder is what stat returns for any folder that exists
+type takenFolder struct{}
+
+func (takenFolder) Name() string       { return "taken" }
+func (takenFolder) Size() int64        { return 0 }
+func (takenFolder) Mode() os.FileMode  { return os.ModeDir | 0o755 }
+func (takenFolder) ModTime() time.Time { return time.Time{} }
+func (takenFolder) IsDir() bool        { return true }
+func (takenFolder) Sys() interface{}   { return nil }
+
+func Test_GenerateDownloadID(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(stat func(string) (os.FileInfo, error), newUUID func() (uuid.UUID, error)) {
+		statDownloadFolder = stat
+		newDownloadUUID = newUUID
+	}(statDownloadFolder, newDownloadUUID)
+
+	taken := map[string]bool{}
+	stats := 0
+	statDownloadFolder = func(name string) (os.FileInfo, error) {
+		stats++
+		if taken[name] {
+			return takenFolder{}, nil
+		}
+		return nil, os.ErrNotExist
+	}
+
+	id, err := generateDownloadID("/downloads", 12, 34)
+	assert.NoError(err)
+	assert.Len(id, 8)
+	otherID, err := generateDownloadID("/downloads", 12, 34)
+	assert.NoError(err)
+	assert.EqualValues(id, otherID, "the same install gets the same ID")
+
+	for _, other := range []struct {
+		basePath string
+		gameID   int64
+		uploadID int64
+	}{
+		{"/downloads", 12, 35},
+		{"/downloads", 13, 34},
+		{"/elsewhere/downloads", 12, 34},
+	} {
+		otherID, err = generateDownloadID(other.basePath, other.gameID, other.uploadID)
+		assert.NoError(err)
+		assert.NotEqual(id, otherID, "%v gets another ID", other)
+	}
+
+	// say another game's install hashed to the same ID
+	taken[filepath.Join("/downloads", id)] = true
+	otherID, err = generateDownloadID("/downloads", 12, 34)
+	assert.NoError(err)
+	assert.NotEqual(id, otherID)
+	assert.Contains(otherID, "-", "falls back to petnames")
+
+	otherID, err = generateDownloadID("/downloads", 0, 0)
+	assert.NoError(err)
+	assert.Contains(otherID, "-", "uses petnames if the game isn't known")
+
+	otherID, err = generateDownloadID("/downloads", 1, 0)
+	assert.NoError(err)
+	assert.Contains(otherID, "-", "uses petnames if the upload isn't picked yet")
+
+	stats = 0
+	statDownloadFolder = func(name string) (os.FileInfo, error) {
+		stats++
+		return takenFolder{}, nil
+	}
+
+	id, err = generateDownloadID("/downloads", 12, 34)
+	assert.NoError(err)
+	_, err = uuid.Parse(id)
+	assert.NoError(err, "falls back to UUIDs once all petnames are taken")
+	assert.EqualValues(101, stats)
+
+	newDownloadUUID = func() (uuid.UUID, error) {
+		return uuid.Nil, errors.New("entropy pool exhausted")
+	}
+	_, err = generateDownloadID("/downloads", 12, 34)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "entropy pool exhausted")
+		assert.Contains(err.Error(), "all petnames taken")
+	}
+}
+
+func Test_UniqueFolderName(t *testing.T) {
+	assert := assert.New(t)
+
+	// a location already full of "game", "game 2", ... "game 500"
+	taken := map[string]bool{"game": true}
+	for i := 2; i <= 500; i++ {
+		taken[fmt.Sprintf("game %d", i)] = true
+	}
+	tries := 0
+	isTaken := func(name string) bool {
+		tries++
+		return taken[name]
+	}
+
+	name, err := uniqueFolderName("other", 0, isTaken)
+	assert.NoError(err)
+	assert.EqualValues("other", name)
+
+	name, err = uniqueFolderName("game", 0, isTaken)
+	assert.NoError(err)
+	assert.False(taken[name])
+	assert.True(strings.HasPrefix(name, "game ("), "switches to petnames instead of scanning, got (%s)", name)
+
+	tries = 0
+	_, err = uniqueFolderName("game", 5, func(name string) bool {
+		tries++
+		return true
+	})
+	assert.EqualValues(5, tries)
+	if assert.Error(err) {
+		be, ok := butlerd.AsButlerdError(err)
+		if assert.True(ok) {
+			assert.EqualValues(butlerd.CodeInstallFolderExhausted, be.RpcErrorCode())
+		}
+	}
+
+	tries = 0
+	_, err = uniqueFolderName("game", 0, func(name string) bool {
+		tries++
+		return true
+	})
+	assert.Error(err)
+	assert.EqualValues(defaultUniqueFolderMaxTries, tries)
+}
+
+func Test_SanitizeFolderName(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.EqualValues("overland", sanitizeFolderName("overland"))
+	assert.EqualValues("", sanitizeFolderName(""))
+	assert.EqualValues("ゆめにっき", sanitizeFolderName("ゆめにっき"), "non-ASCII characters are fine")
+	assert.EqualValues("what-now- -yes-no-", sanitizeFolderName(`what:now? "yes|no"`))
+	assert.EqualValues("a-b-c-d", sanitizeFolderName("a<b>c\\d"))
+	assert.EqualValues("tab-here", sanitizeFolderName("tab\there"))
+	assert.EqualValues("the end", sanitizeFolderName("the end."), "Windows drops trailing dots")
+	assert.EqualValues("the end", sanitizeFolderName("the end . . "))
+	assert.EqualValues("", sanitizeFolderName("..."))
+
+	long := strings.Repeat("a", 300)
+	assert.EqualValues(strings.Repeat("a", maxFolderNameBytes), sanitizeFolderName(long))
+
+	// 3 bytes per character, 200 isn't a multiple of that
+	longJapanese := strings.Repeat("ゲーム", 50)
+	name := sanitizeFolderName(longJapanese)
+	assert.True(len(name) <= maxFolderNameBytes)
+	assert.True(utf8.ValidString(name), "doesn't split characters")
+	assert.EqualValues(strings.Repeat("ゲーム", 22), name)
+
+	game := &itchio.Game{ID: 42, URL: "https://example.itch.io/..."}
+	assert.EqualValues("game-42", makeInstallFolderName(game, &state.Consumer{}), "falls back to the game ID")
+}
+
+func Test_SanitizeFolderNameOverrides(t *testing.T) {
+	assert := assert.New(t)
+
+	// no escaping the install location
+	assert.EqualValues("..-escape", sanitizeFolderName("../escape"))
+	assert.EqualValues("..-..-escape", sanitizeFolderName(`..\..\escape`))
+	assert.EqualValues("-etc-passwd", sanitizeFolderName("/etc/passwd"))
+	assert.EqualValues("", sanitizeFolderName(".."))
+	assert.EqualValues("", sanitizeFolderName(" . "))
+
+	// device names, whatever the case or extension
+	assert.EqualValues("CON_", sanitizeFolderName("CON"))
+	assert.EqualValues("con_", sanitizeFolderName("con."))
+	assert.EqualValues("Lpt1_.txt", sanitizeFolderName("Lpt1.txt"))
+	assert.EqualValues("nul_ .tar.gz", sanitizeFolderName("nul .tar.gz"))
+	assert.EqualValues("COM10", sanitizeFolderName("COM10"))
+	assert.EqualValues("CONSOLE", sanitizeFolderName("CONSOLE"))
+	assert.EqualValues("my con", sanitizeFolderName("my con"))
+
+	// "é" as one code point, and as "e" followed by a combining accent
+	assert.EqualValues("caf\u00e9", sanitizeFolderName("caf\u00e9"))
+	assert.EqualValues("caf\u00e9", sanitizeFolderName("cafe\u0301"))
+
+	// Windows' MAX_PATH is 260 characters, whole path included
+	long := strings.Repeat("Overland ", 40)
+	name := sanitizeFolderName(long)
+	assert.True(len(name) <= maxFolderNameBytes)
+	assert.False(strings.HasSuffix(name, " "))
+	decomposed := strings.Repeat("e\u0301", 150)
+	name = sanitizeFolderName(decomposed)
+	assert.EqualValues(strings.Repeat("\u00e9", maxFolderNameBytes/2), name, "normalized before being truncated")
+}
+
+func Test_MakeInstallFolderName(t *testing.T) {
+	consumer := &state.Consumer{}
+
+	cases := []struct {
+		url      string
+		expected string
+	}{
+		{"https://example.itch.io/overland", "overland"},
+		{"https://example.itch.io/con", "con_"},
+		{"https://example.itch.io/AUX.exe", "AUX_.exe"},
+		{"https://example.itch.io/PRN.", "PRN_"},
+		{"https://example.itch.io/com%C2%B9", "com¹_"},
+		{"https://example.itch.io/nul%20.%20.", "nul_"},
+		{"https://example.itch.io/console", "console"},
+		{"https://example.itch.io/time:keeper", "time-keeper"},
+		{"https://example.itch.io/what%3Fnow", "what-now"},
+		{"https://example.itch.io/%E3%82%86%E3%82%81%E3%81%AB%E3%81%A3%E3%81%8D", "ゆめにっき"},
+		{"https://example.itch.io/cafe%CC%81", "caf\u00e9"},
+		{"https://example.itch.io/trailing.%20", "trailing"},
+		{"https://example.itch.io/%3F%3F%3F.", "---"},
+		{"https://example.itch.io/.%20.", "game-42"},
+	}
+	for _, c := range cases {
+		t.Run(c.url, func(t *testing.T) {
+			game := &itchio.Game{ID: 42, URL: c.url}
+			assert.EqualValues(t, c.expected, makeInstallFolderName(game, consumer))
+		})
+	}
+}
+
+func Test_PickUploadWithStrategy(t *testing.T) {
+	assert := assert.New(t)
+
+	consumer := &state.Consumer{}
+	plain := &itchio.Upload{ID: 1}
+	otherPlain := &itchio.Upload{ID: 2}
+	wharf := &itchio.Upload{ID: 3, Build: &itchio.Build{ID: 30}}
+
+	u, err := pickUploadWithStrategy(consumer, []*itchio.Upload{plain, wharf}, butlerd.DefaultUploadStrategyFirst)
+	assert.NoError(err)
+	assert.EqualValues(plain, u)
+
+	u, err = pickUploadWithStrategy(consumer, []*itchio.Upload{plain, wharf}, butlerd.DefaultUploadStrategyPreferWharf)
+	assert.NoError(err)
+	assert.EqualValues(wharf, u)
+
+	u, err = pickUploadWithStrategy(consumer, []*itchio.Upload{plain, otherPlain}, butlerd.DefaultUploadStrategyPreferWharf)
+	assert.NoError(err)
+	assert.EqualValues(plain, u, "falls back to the first upload")
+
+	_, err = pickUploadWithStrategy(consumer, []*itchio.Upload{plain, wharf}, butlerd.DefaultUploadStrategyAbort)
+	if assert.Error(err) {
+		assert.True(errors.Is(err, butlerd.CodeOperationAborted))
+	}
+}
+
+func Test_FilterUploadsByChannel(t *testing.T) {
+	assert := assert.New(t)
+
+	stable := &itchio.Upload{ID: 1, ChannelName: "linux-stable"}
+	beta := &itchio.Upload{ID: 2, ChannelName: "linux-beta"}
+	plain := &itchio.Upload{ID: 3}
+	otherStable := &itchio.Upload{ID: 4, ChannelName: "linux-stable"}
+	uploads := []*itchio.Upload{stable, beta, plain, otherStable}
+
+	assert.EqualValues([]*itchio.Upload{stable, otherStable}, filterUploadsByChannel(uploads, "linux-stable"))
+	assert.EqualValues([]*itchio.Upload{beta}, filterUploadsByChannel(uploads, "linux-beta"))
+	assert.Empty(filterUploadsByChannel(uploads, "Linux-Beta"), "channel names must match exactly")
+	assert.Empty(filterUploadsByChannel(uploads, "windows"))
+}
+
+func Test_AlreadyQueuedKey(t *testing.T) {
+	assert := assert.New(t)
+
+	game := &itchio.Game{ID: 12}
+	upload := &itchio.Upload{ID: 34}
+	build := &itchio.Build{ID: 56}
+
+	assert.EqualValues("cave:abc", alreadyQueuedKey(butlerd.InstallQueueParams{CaveID: "abc", Game: game}))
+	assert.EqualValues("game:12", alreadyQueuedKey(butlerd.InstallQueueParams{Game: game, Upload: upload, Build: build}), "calls with and without an upload wait for each other")
+	assert.EqualValues("game:12", alreadyQueuedKey(butlerd.InstallQueueParams{Game: game}))
+
+	assert.Empty(alreadyQueuedKey(butlerd.InstallQueueParams{}), "nothing to compare")
+	assert.Empty(alreadyQueuedKey(butlerd.InstallQueueParams{Game: game, Force: true}))
+	assert.Empty(alreadyQueuedKey(butlerd.InstallQueueParams{Game: game, DryRun: true}))
+	assert.Empty(alreadyQueuedKey(butlerd.InstallQueueParams{LocalArchivePath: "game.zip"}))
+}
+
+func Test_EnsureUniqueFolderNameNested(t *testing.T) {
+	assert := assert.New(t)
+
+	conn, err := sqlite.OpenConn("file:ensure_unique_folder_name_test?mode=memory", 0)
+	assert.NoError(err)
+	defer conn.Close()
+	assert.NoError(database.Prepare(&state.Consumer{}, conn, true))
+
+	dir, err := ioutil.TempDir("", "unique-folder-name")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	save := func(record interface{}) {
+		assert.NoError(models.Save(conn, record))
+	}
+	save(&models.InstallLocation{ID: "ci", Path: dir})
+	// not on disk yet, its install is still queued
+	save(&models.Cave{ID: "existing", InstallLocationID: "ci", InstallFolderName: "42/nightly"})
+	save(&models.Cave{ID: "custom", InstallLocationID: "ci", InstallFolderName: "elsewhere", CustomInstallFolder: "/elsewhere"})
+
+	ensure := func(name string) string {
+		t.Helper()
+		cave := &models.Cave{ID: "new", InstallLocationID: "ci", InstallFolderName: name}
+		assert.NoError(ensureUniqueFolderName(conn, cave, 0))
+		return cave.InstallFolderName
+	}
+	assert.EqualValues("42/beta", ensure("42/beta"), "siblings are fine")
+	assert.EqualValues("42/nightly 2", ensure("42/nightly"))
+	cave := &models.Cave{ID: "new", InstallLocationID: "ci", InstallFolderName: "42/nightly/extra"}
+	assert.Error(ensureUniqueFolderName(conn, cave, 0), "no installing inside another cave")
+	assert.EqualValues("42 2", ensure("42"), "no installing around another cave")
+	assert.EqualValues("elsewhere", ensure("elsewhere"), "custom install folders don't count")
+
+	existing := &models.Cave{ID: "existing", InstallLocationID: "ci", InstallFolderName: "42/nightly"}
+	assert.NoError(ensureUniqueFolderName(conn, existing, 0))
+	assert.EqualValues("42/nightly", existing.InstallFolderName, "a cave doesn't overlap itself")
+}
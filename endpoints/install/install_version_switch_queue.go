@@ -27,7 +27,7 @@ func InstallVersionSwitchQueue(rc *butlerd.RequestContext, params butlerd.Instal
 
 	var access *operate.GameAccess
 	rc.WithConn(func(conn *sqlite.Conn) {
-		access = operate.AccessForGameID(conn, cave.Game.ID)
+		access = operate.AccessForCave(conn, cave)
 	})
 	client := rc.Client(access.APIKey)
 
@@ -0,0 +1,105 @@
+package install
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/hades"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
+)
+
+// Games and uploads made up for local archives get negative IDs,
+// so they never collide with the ones from itch.io, see operate.IsLocalGame
+
+var lowestIDFirst = hades.Search{}.OrderBy("id ASC").Limit(1)
+
+// The rows are only saved once an install is queued, so IDs handed out
+// to installs still being queued are remembered here.
+var localIDs = struct {
+	sync.Mutex
+	lastGameID   int64
+	lastUploadID int64
+}{}
+
+func nextLocalGameID(conn *sqlite.Conn) int64 {
+	var games []*itchio.Game
+	models.MustSelect(conn, &games, builder.Lt{"id": 0}, lowestIDFirst)
+	id := int64(-1)
+	if len(games) > 0 {
+		id = games[0].ID - 1
+	}
+
+	localIDs.Lock()
+	defer localIDs.Unlock()
+	if localIDs.lastGameID <= id {
+		id = localIDs.lastGameID - 1
+	}
+	localIDs.lastGameID = id
+	return id
+}
+
+func nextLocalUploadID(conn *sqlite.Conn) int64 {
+	var uploads []*itchio.Upload
+	models.MustSelect(conn, &uploads, builder.Lt{"id": 0}, lowestIDFirst)
+	id := int64(-1)
+	if len(uploads) > 0 {
+		id = uploads[0].ID - 1
+	}
+
+	localIDs.Lock()
+	defer localIDs.Unlock()
+	if localIDs.lastUploadID <= id {
+		id = localIDs.lastUploadID - 1
+	}
+	localIDs.lastUploadID = id
+	return id
+}
+
+// localArchiveUpload describes the archive at archivePath as an upload
+func localArchiveUpload(archivePath string, id int64) (*itchio.Upload, error) {
+	stats, err := os.Stat(archivePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening local archive")
+	}
+	if !stats.Mode().IsRegular() {
+		return nil, errors.Errorf("local archive (%s) is not a regular file", archivePath)
+	}
+	// better find out now than once the download is queued
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening local archive")
+	}
+	f.Close()
+
+	filename := filepath.Base(archivePath)
+	return &itchio.Upload{
+		ID:          id,
+		Filename:    filename,
+		DisplayName: filename,
+		Size:        stats.Size(),
+		Storage:     operate.UploadStorageLocal,
+		Type:        itchio.UploadTypeDefault,
+	}, nil
+}
+
+// localArchiveGame makes up a game named after the archive at archivePath
+func localArchiveGame(archivePath string, id int64) *itchio.Game {
+	return &itchio.Game{
+		ID:             id,
+		Title:          localArchiveFolderName(archivePath),
+		Type:           itchio.GameTypeDefault,
+		Classification: itchio.GameClassificationGame,
+	}
+}
+
+// localArchiveFolderName is the archive's file name, without its
+// archive extension(s)
+func localArchiveFolderName(archivePath string) string {
+	return operate.TrimArchiveExtensions(filepath.Base(archivePath))
+}
@@ -1,20 +1,27 @@
 package install
 
 import (
-	"fmt"
-	"io/ioutil"
-	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"xorm.io/builder"
-	"github.com/google/uuid"
 	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/cmd/wipe"
 	"github.com/itchio/butler/database/models"
 	"github.com/itchio/butler/endpoints/fetch"
+	"github.com/itchio/butler/endpoints/system"
 	"github.com/itchio/hades"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/headway/united"
 	"github.com/pkg/errors"
 )
 
+// minStagingFreeSpace is how much room a staging path needs to
+// have left for us to agree to use it.
+const minStagingFreeSpace = 1024 * 1024 * 1024
+
 func InstallLocationsGetByID(rc *butlerd.RequestContext, params butlerd.InstallLocationsGetByIDParams) (*butlerd.InstallLocationsGetByIDResult, error) {
 	if params.ID == "" {
 		return nil, errors.Errorf("id must be set")
@@ -40,6 +47,9 @@ func InstallLocationsList(rc *butlerd.RequestContext, params butlerd.InstallLoca
 
 	var locations []*models.InstallLocation
 	models.MustSelect(conn, &locations, builder.NewCond(), hades.Search{})
+	sort.SliceStable(locations, func(i, j int) bool {
+		return locations[i].SSD && !locations[j].SSD
+	})
 
 	var flocs []*butlerd.InstallLocationSummary
 	for _, il := range locations {
@@ -57,47 +67,57 @@ func InstallLocationsAdd(rc *butlerd.RequestContext, params butlerd.InstallLocat
 	defer rc.PutConn(conn)
 	consumer := rc.Consumer
 
-	hadID := false
-	if params.ID == "" {
-		hadID = true
-		params.ID = uuid.New().String()
-	}
 	if params.Path == "" {
 		return nil, errors.New("path must be set")
 	}
 
-	if hadID {
+	if params.ID != "" {
 		existing := models.InstallLocationByID(conn, params.ID)
 		if existing != nil {
 			if existing.Path == params.Path {
 				consumer.Statf("(%s) exists, and has same path (%s), doing nothing", params.ID, params.Path)
-				res := &butlerd.InstallLocationsAddResult{}
+				res := &butlerd.InstallLocationsAddResult{
+					InstallLocation: fetch.FormatInstallLocation(conn, rc.Consumer, existing),
+				}
 				return res, nil
 			}
 			return nil, errors.Errorf("(%s) exists but has path (%s) - we were passed (%s)", params.ID, existing.Path, params.Path)
 		}
 	}
 
-	stats, err := os.Stat(params.Path)
+	il, err := models.NewInstallLocationFromPath(params.Path)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, errors.WithMessage(err, "not adding as an install location")
+	}
+	if params.ID != "" {
+		il.ID = params.ID
+	}
+
+	var locations []*models.InstallLocation
+	models.MustSelect(conn, &locations, builder.NewCond(), hades.Search{})
+	for _, other := range locations {
+		if overlaps(il.Path, other.Path) {
+			return nil, errors.Errorf("(%s) overlaps with install location (%s) at (%s), not adding as an install location", il.Path, other.ID, other.Path)
+		}
 	}
-	if !stats.IsDir() {
-		return nil, errors.Errorf("(%s) is not a directory", params.Path)
+
+	if params.StagingPath != "" {
+		err := checkStagingPath(consumer, params.StagingPath)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// try writing a file
-	testFileName := fmt.Sprintf(".butler-test-file-%d", os.Getpid())
-	testFilePath := filepath.Join(params.Path, testFileName)
-	defer os.Remove(testFilePath)
-	err = ioutil.WriteFile(testFilePath, []byte{}, os.FileMode(0o644))
+	err = validateFolderNameTemplate(params.FolderNameTemplate)
 	if err != nil {
-		return nil, errors.Errorf("Can't write to (%s) not adding as an install location: %s", params.Path, err.Error())
+		return nil, err
 	}
 
-	il := &models.InstallLocation{
-		ID:   params.ID,
-		Path: params.Path,
+	il.AccessMode = string(params.AccessMode)
+	il.StagingPath = params.StagingPath
+	il.FolderNameTemplate = params.FolderNameTemplate
+	if il.FreeBytes >= 0 {
+		consumer.Statf("Adding install location (%s) at (%s), %s free", il.Label, il.Path, united.FormatBytes(il.FreeBytes))
 	}
 	models.MustSave(conn, il)
 
@@ -107,6 +127,59 @@ func InstallLocationsAdd(rc *butlerd.RequestContext, params butlerd.InstallLocat
 	return res, nil
 }
 
+func InstallLocationsUpdate(rc *butlerd.RequestContext, params butlerd.InstallLocationsUpdateParams) (*butlerd.InstallLocationsUpdateResult, error) {
+	conn := rc.GetConn()
+	defer rc.PutConn(conn)
+	consumer := rc.Consumer
+
+	il := models.InstallLocationByID(conn, params.ID)
+	if il == nil {
+		return nil, errors.Errorf("install location (%s) not found", params.ID)
+	}
+
+	if params.StagingPath != "" && params.StagingPath != il.StagingPath {
+		err := checkStagingPath(consumer, params.StagingPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := validateFolderNameTemplate(params.FolderNameTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	il.AccessMode = string(params.AccessMode)
+	il.StagingPath = params.StagingPath
+	// only applies to new installs, see Caves.ApplyNamingTemplate
+	il.FolderNameTemplate = params.FolderNameTemplate
+	// disks may have been swapped since the location was added
+	il.SSD = detectSSD(consumer, il.Path)
+	models.MustUpdate(conn, &models.InstallLocation{},
+		hades.Where(builder.Eq{"id": il.ID}),
+		builder.Eq{
+			"access_mode":          il.AccessMode,
+			"staging_path":         il.StagingPath,
+			"ssd":                  il.SSD,
+			"folder_name_template": il.FolderNameTemplate,
+		},
+	)
+
+	// existing caves get the new permissions right away, install
+	// operations will keep enforcing them from now on.
+	for _, cave := range il.GetCaves(conn) {
+		err := operate.ApplyAccessMode(consumer, il.GetInstallFolder(cave.InstallFolderName), params.AccessMode)
+		if err != nil {
+			consumer.Warnf("Could not apply access mode to cave (%s): %+v", cave.ID, err)
+		}
+	}
+
+	res := &butlerd.InstallLocationsUpdateResult{
+		InstallLocation: fetch.FormatInstallLocation(conn, rc.Consumer, il),
+	}
+	return res, nil
+}
+
 func InstallLocationsRemove(rc *butlerd.RequestContext, params butlerd.InstallLocationsRemoveParams) (*butlerd.InstallLocationsRemoveResult, error) {
 	conn := rc.GetConn()
 	defer rc.PutConn(conn)
@@ -131,6 +204,26 @@ func InstallLocationsRemove(rc *butlerd.RequestContext, params butlerd.InstallLo
 	caveCount := models.MustCount(conn, &models.Cave{}, builder.Eq{"install_location_id": il.ID})
 	consumer.Statf("Found %d caves in install location", caveCount)
 
+	var fallback *models.InstallLocation
+	if caveCount > 0 {
+		if !params.Force {
+			consumer.Errorf("Refusing to remove install location, %d caves are still installed in it", caveCount)
+			return nil, errors.WithStack(butlerd.CodeInstallLocationNotEmpty)
+		}
+		if params.FallbackID == "" || params.FallbackID == il.ID {
+			return nil, errors.Errorf("With force, fallbackId must be set to another install location")
+		}
+		fallback = models.InstallLocationByID(conn, params.FallbackID)
+		if fallback == nil {
+			return nil, errors.Errorf("Fallback install location not found (%s)", params.FallbackID)
+		}
+	}
+
+	overflowCount := models.MustCount(conn, &models.Cave{}, builder.Eq{"overflow_location_id": il.ID})
+	if overflowCount > 0 {
+		return nil, errors.Errorf("Refusing to remove install location, %d caves in other locations overflow to it", overflowCount)
+	}
+
 	downloadsCount := models.MustCount(conn, &models.Download{}, builder.And(
 		builder.IsNull{"finished_at"},
 		builder.Eq{"install_location_id": params.ID},
@@ -142,9 +235,91 @@ func InstallLocationsRemove(rc *butlerd.RequestContext, params butlerd.InstallLo
 		consumer.Statf("No downloads in progress")
 	}
 
+	// staging folders inside the location go away with it, but
+	// those kept elsewhere would be left behind for good.
+	var downloads []*models.Download
+	models.MustSelect(conn, &downloads, builder.Eq{"install_location_id": il.ID}, hades.Search{})
+	for _, download := range downloads {
+		if download.StagingFolder == "" || isInside(download.StagingFolder, il.Path) {
+			continue
+		}
+		consumer.Infof("Wiping staging folder (%s)", download.StagingFolder)
+		err := wipe.Do(consumer, download.StagingFolder)
+		if err != nil {
+			consumer.Warnf("Could not wipe staging folder: %+v", err)
+		}
+	}
+
+	if fallback != nil {
+		// the caves' files stay where they are, so they keep working
+		// even though they're no longer in the location's folder.
+		for _, cave := range il.GetCaves(conn) {
+			installFolder := cave.GetInstallFolder(conn)
+			consumer.Infof("Moving cave (%s) over to install location (%s), keeping (%s)", cave.ID, fallback.ID, installFolder)
+			models.MustUpdate(conn, &models.Cave{},
+				hades.Where(builder.Eq{"id": cave.ID}),
+				builder.Eq{
+					"install_location_id":   fallback.ID,
+					"custom_install_folder": installFolder,
+				},
+			)
+		}
+	}
+
 	models.MustDelete(conn, &models.Download{}, builder.Eq{"install_location_id": il.ID})
-	models.MustDelete(conn, &models.Cave{}, builder.Eq{"install_location_id": il.ID})
 	models.MustDelete(conn, &models.InstallLocation{}, builder.Eq{"id": il.ID})
 	res := &butlerd.InstallLocationsRemoveResult{}
 	return res, nil
 }
+
+func checkStagingPath(consumer *state.Consumer, path string) error {
+	if !filepath.IsAbs(path) {
+		return errors.Errorf("staging path (%s) must be absolute", path)
+	}
+
+	err := models.CheckWritableFolder(path)
+	if err != nil {
+		return errors.WithMessage(err, "not using as a staging path")
+	}
+
+	stats, err := system.StatFS(path)
+	if err != nil {
+		return errors.WithMessage(err, "while checking free space of staging path")
+	}
+	if stats.FreeSize < minStagingFreeSpace {
+		return errors.Errorf("Only %s free in (%s), need at least %s for a staging path",
+			united.FormatBytes(stats.FreeSize), path, united.FormatBytes(minStagingFreeSpace))
+	}
+	consumer.Statf("Staging path (%s) has %s free", path, united.FormatBytes(stats.FreeSize))
+	return nil
+}
+
+func detectSSD(consumer *state.Consumer, path string) bool {
+	ssd, err := system.IsSSD(path)
+	if err != nil {
+		consumer.Infof("Could not tell whether (%s) is on an SSD, assuming it isn't: %v", path, err)
+		return false
+	}
+	return ssd
+}
+
+// overlaps returns true if a and b are the same folder, or
+// if either is inside of the other.
+func overlaps(a string, b string) bool {
+	if absA, err := filepath.Abs(a); err == nil {
+		a = absA
+	}
+	if absB, err := filepath.Abs(b); err == nil {
+		b = absB
+	}
+	return isInside(a, b) || isInside(b, a)
+}
+
+// isInside returns true if path is folder or one of its descendants
+func isInside(path string, folder string) bool {
+	rel, err := filepath.Rel(folder, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
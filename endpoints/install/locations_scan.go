@@ -270,7 +270,7 @@ func (sc *scanContext) DoInstallLocation(il *models.InstallLocation) error {
 		InstallFolderName := entry.Name()
 		InstallFolder := filepath.Join(il.Path, InstallFolderName)
 
-		if InstallFolderName == "downloads" {
+		if models.IsReservedFolderName(InstallFolderName) {
 			// definitely not a cave folder, skip
 			return nil
 		}
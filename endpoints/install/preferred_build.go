@@ -0,0 +1,40 @@
+package install
+
+import (
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/pkg/errors"
+)
+
+// fetchPreferredBuild returns the build with the given ID, with its list
+// of files, as long as it's one of upload's builds.
+func fetchPreferredBuild(rc *butlerd.RequestContext, client *itchio.Client, access *operate.GameAccess, upload *itchio.Upload, buildID int64) (*itchio.Build, error) {
+	buildsRes, err := client.ListUploadBuilds(rc.Ctx, itchio.ListUploadBuildsParams{
+		UploadID:    upload.ID,
+		Credentials: access.Credentials,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	found := false
+	for _, b := range buildsRes.Builds {
+		if b.ID == buildID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, errors.Wrapf(butlerd.CodeBuildNotFound, "upload %d has no build %d", upload.ID, buildID)
+	}
+
+	buildRes, err := client.GetBuild(rc.Ctx, itchio.GetBuildParams{
+		BuildID:     buildID,
+		Credentials: access.Credentials,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return buildRes.Build, nil
+}
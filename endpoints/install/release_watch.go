@@ -0,0 +1,138 @@
+package install
+
+import (
+	"sync"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/jsonrpc2"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/mansion/ratelimit"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/pkg/errors"
+)
+
+const (
+	// how often we check for release when we don't know the date
+	releaseCheckInterval = 1 * time.Hour
+	// how soon after the release date we check
+	releaseCheckGrace = 1 * time.Minute
+	// never check more often than this, in case clocks disagree
+	minReleaseCheckDelay = 1 * time.Minute
+)
+
+type releaseWatchKey struct {
+	conn   jsonrpc2.Conn
+	gameID int64
+}
+
+// games we're already watching, per connection
+var releaseWatches = struct {
+	sync.Mutex
+	watching map[releaseWatchKey]bool
+}{
+	watching: make(map[releaseWatchKey]bool),
+}
+
+// nextReleaseCheck returns how long to wait before checking again whether
+// a game is out. If we know when it comes out, that's right after then
+// (but no later than the usual interval), otherwise it's the usual interval.
+func nextReleaseCheck(releaseDate *time.Time, now time.Time) time.Duration {
+	delay := releaseCheckInterval
+	if releaseDate != nil {
+		untilRelease := releaseDate.Sub(now) + releaseCheckGrace
+		if untilRelease < delay {
+			delay = untilRelease
+		}
+	}
+	if delay < minReleaseCheckDelay {
+		delay = minReleaseCheckDelay
+	}
+	return delay
+}
+
+// watchRelease checks in the background whether the game from nyr is out
+// yet, and sends GameReleased over notifyRC's connection once it is.
+func watchRelease(notifyRC *butlerd.RequestContext, access *operate.GameAccess, nyr *operate.GameNotYetReleasedError) {
+	key := releaseWatchKey{
+		conn:   notifyRC.Conn,
+		gameID: nyr.Game.ID,
+	}
+
+	releaseWatches.Lock()
+	defer releaseWatches.Unlock()
+	if releaseWatches.watching[key] {
+		notifyRC.Consumer.Infof("Already watching for the release of %s", operate.GameToString(nyr.Game))
+		return
+	}
+	releaseWatches.watching[key] = true
+
+	notifyRC.Consumer.Infof("Will notify when %s comes out", operate.GameToString(nyr.Game))
+	notifyRC.QueueBackgroundTask(butlerd.BackgroundTask{
+		Desc: "watch release of " + operate.GameToString(nyr.Game),
+		Do: func(rc *butlerd.RequestContext) error {
+			defer func() {
+				releaseWatches.Lock()
+				delete(releaseWatches.watching, key)
+				releaseWatches.Unlock()
+			}()
+
+			rc.Conn = notifyRC.Conn
+			rc.Ctx = ratelimit.WithClass(rc.Ctx, ratelimit.ClassBackground)
+			return waitForRelease(rc, access, nyr)
+		},
+	})
+}
+
+func waitForRelease(rc *butlerd.RequestContext, access *operate.GameAccess, nyr *operate.GameNotYetReleasedError) error {
+	consumer := rc.Consumer
+	game := nyr.Game
+	releaseDate := nyr.ReleaseDate
+
+	for {
+		delay := nextReleaseCheck(releaseDate, time.Now())
+		select {
+		case <-rc.Ctx.Done():
+			return nil
+		case <-time.After(delay):
+			// check below
+		}
+
+		freshGame, err := operate.CheckGameReleased(rc, access, game)
+		if err != nil {
+			if nyr, ok := errors.Cause(err).(*operate.GameNotYetReleasedError); ok {
+				game, releaseDate = nyr.Game, nyr.ReleaseDate
+				continue
+			}
+			// network trouble, etc. - try again later
+			consumer.Warnf("Could not check whether %s is out: %v", operate.GameToString(game), err)
+			continue
+		}
+
+		consumer.Statf("%s is out!", operate.GameToString(freshGame))
+		return messages.GameReleased.Notify(rc, butlerd.GameReleasedNotification{
+			Game: freshGame,
+		})
+	}
+}
+
+// checkReleased returns a *operate.GameNotYetReleasedError if game isn't out
+// yet (see operate.ReleaseState), and starts watching for its release if
+// the client asked for it.
+func checkReleased(rc *butlerd.RequestContext, queueParams butlerd.InstallQueueParams, access *operate.GameAccess, game *itchio.Game, uploads []*itchio.Upload) error {
+	released, releaseDate := operate.ReleaseState(game, uploads, time.Now())
+	if released {
+		return nil
+	}
+
+	nyr := &operate.GameNotYetReleasedError{
+		Game:        game,
+		ReleaseDate: releaseDate,
+	}
+	rc.Consumer.Warnf("%s", nyr.Error())
+	if queueParams.NotifyOnRelease {
+		watchRelease(rc, access, nyr)
+	}
+	return nyr
+}
@@ -0,0 +1,160 @@
+package install
+
+import (
+	"path/filepath"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/headway/state"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
+)
+
+// resumeStaging looks into stagingFolder, where the client wants to
+// queue an install again. It returns the ID of the install that was
+// queued there and its params, or an empty ID and nil params if there's
+// nothing left to resume. stagingFolder must belong to installLocation.
+func resumeStaging(consumer *state.Consumer, installLocation *models.InstallLocation, stagingFolder string) (string, *operate.InstallParams, error) {
+	stagingFolder = filepath.Clean(stagingFolder)
+	id := filepath.Base(stagingFolder)
+	if installLocation.GetStagingFolder(id) != stagingFolder {
+		return "", nil, errors.Wrapf(butlerd.CodeStagingFolderMismatch, "(%s) isn't a staging folder of install location (%s)", stagingFolder, installLocation.ID)
+	}
+
+	resumed, err := operate.ReadMeta(stagingFolder)
+	if err != nil {
+		consumer.Warnf("Can't resume install in (%s), starting over: %v", stagingFolder, err)
+		return "", nil, nil
+	}
+	return id, resumed, nil
+}
+
+// findResumableStaging looks for an install of the same game and upload
+// (and cave, or lack thereof) that was queued in installLocation before, but never
+// made it to the download queue, because butler went away for example.
+// It returns its ID and params, or an empty ID and nil params if there's
+// none. If the upload isn't known yet, it's left to upload selection,
+// which may well pick another one this time.
+func findResumableStaging(conn *sqlite.Conn, consumer *state.Consumer, installLocation *models.InstallLocation, caveID string, gameID int64, uploadID int64) (string, *operate.InstallParams) {
+	if gameID == 0 || uploadID == 0 {
+		return "", nil
+	}
+
+	id := stableDownloadID(installLocation.GetStagingRoot(), gameID, uploadID)
+	resumed := resumableStaging(conn, installLocation, installLocation.GetStagingFolder(id), caveID, gameID, uploadID)
+	if resumed == nil {
+		return "", nil
+	}
+
+	consumer.Infof("Found install queued before in (%s), resuming it", resumed.StagingFolder)
+	return id, resumed
+}
+
+// resumableStaging returns the params of the install queued in
+// stagingFolder, if it's one findResumableStaging would resume,
+// nil otherwise.
+func resumableStaging(conn *sqlite.Conn, installLocation *models.InstallLocation, stagingFolder string, caveID string, gameID int64, uploadID int64) *operate.InstallParams {
+	if _, err := statDownloadFolder(stagingFolder); err != nil {
+		return nil
+	}
+
+	resumed, err := operate.ReadMeta(stagingFolder)
+	if err != nil {
+		// someone else's, or too broken to resume
+		return nil
+	}
+	switch {
+	case resumed.Game == nil || resumed.Game.ID != gameID:
+		return nil
+	case resumed.Upload == nil || resumed.Upload.ID != uploadID:
+		return nil
+	case resumed.InstallLocationID != installLocation.ID:
+		// staging roots may be shared by several locations
+		return nil
+	case resumed.CaveID != caveID && (caveID != "" || models.CaveByID(conn, resumed.CaveID) != nil):
+		// a reinstall or update of another cave, fresh installs
+		// only get to resume other fresh installs
+		return nil
+	}
+
+	queued := models.MustCount(conn, &models.Download{}, builder.And(
+		builder.Eq{"staging_folder": stagingFolder},
+		builder.IsNull{"finished_at"},
+		builder.Not{builder.Expr("discarded")},
+	))
+	if queued > 0 {
+		// it's being taken care of, see Downloads.Drive
+		return nil
+	}
+	return resumed
+}
+
+// checkResumedParams makes sure queueParams are for the same install
+// as resumed, and fills in the game, upload and build from it if
+// they're unspecified.
+func checkResumedParams(queueParams *butlerd.InstallQueueParams, resumed *operate.InstallParams) error {
+	if queueParams.CaveID != "" && queueParams.CaveID != resumed.CaveID {
+		return errors.Wrapf(butlerd.CodeStagingFolderMismatch, "staging folder is for cave (%s), not (%s)", resumed.CaveID, queueParams.CaveID)
+	}
+
+	if queueParams.Game == nil {
+		queueParams.Game = resumed.Game
+	} else if queueParams.Game.ID != resumed.Game.ID {
+		return errors.Wrapf(butlerd.CodeStagingFolderMismatch, "staging folder is for game %d, not %d", resumed.Game.ID, queueParams.Game.ID)
+	}
+
+	if queueParams.Upload == nil {
+		queueParams.Upload = resumed.Upload
+	} else if queueParams.Upload.ID != resumed.Upload.ID {
+		return errors.Wrapf(butlerd.CodeStagingFolderMismatch, "staging folder is for upload %d, not %d", resumed.Upload.ID, queueParams.Upload.ID)
+	}
+
+	// another build would make the downloaded bytes useless
+	if queueParams.Build == nil && queueParams.PreferredBuildID == 0 {
+		queueParams.Build = resumed.Build
+	}
+	return nil
+}
+
+// adoptStaging points res at the install queued in another staging
+// folder, found by resumableStaging, instead of its own. What res says
+// about the upload and its size still holds: it's for the same one.
+func adoptStaging(res *butlerd.InstallQueueResult, other *operate.InstallParams) {
+	res.ID = filepath.Base(other.StagingFolder)
+	res.CaveID = other.CaveID
+	res.Build = other.Build
+	res.StagingFolder = other.StagingFolder
+	res.InstallFolder = other.InstallFolder
+	res.InstallFolderName = other.InstallFolderName
+	res.InstallLocationID = other.InstallLocationID
+}
+
+// resumeFreshCave gives cave the ID and install folder name of the
+// cave that was going to be installed in the resumed staging folder,
+// so that whatever was already extracted is used as well. It leaves
+// cave alone if they're taken by now.
+func resumeFreshCave(conn *sqlite.Conn, consumer *state.Consumer, cave *models.Cave, resumed *operate.InstallParams, folderName string) {
+	if resumed.CaveID == "" || resumed.InstallLocationID != cave.InstallLocationID {
+		return
+	}
+	if models.CaveByID(conn, resumed.CaveID) != nil {
+		consumer.Warnf("Cave (%s) exists by now, not resuming it", resumed.CaveID)
+		return
+	}
+	cave.ID = resumed.CaveID
+
+	if folderName != "" || resumed.InstallFolderName == "" {
+		return
+	}
+	taken := models.MustCount(conn, &models.Cave{}, builder.Eq{
+		"install_location_id": cave.InstallLocationID,
+		"install_folder_name": resumed.InstallFolderName,
+	})
+	if taken > 0 {
+		consumer.Warnf("Install folder (%s) belongs to another cave by now, not resuming it", resumed.InstallFolderName)
+		return
+	}
+	cave.InstallFolderName = resumed.InstallFolderName
+}
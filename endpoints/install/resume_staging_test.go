@@ -0,0 +1,67 @@
+package install
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database"
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FindResumableStaging(t *testing.T) {
+	assert := assert.New(t)
+
+	conn, err := sqlite.OpenConn("file:find_resumable_staging_test?mode=memory", 0)
+	assert.NoError(err)
+	defer conn.Close()
+	assert.NoError(database.Prepare(&state.Consumer{}, conn, true))
+
+	dir, err := ioutil.TempDir("", "resumable-staging")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	il := &models.InstallLocation{ID: "ci", Path: dir}
+	assert.NoError(models.Save(conn, il))
+	assert.NoError(models.Save(conn, &models.Cave{ID: "installed", GameID: 1, InstallLocationID: "ci"}))
+
+	const gameID, uploadID = 1, 2
+	stage := func(caveID string) {
+		t.Helper()
+		stagingFolder := il.GetStagingFolder(stableDownloadID(il.GetStagingRoot(), gameID, uploadID))
+		assert.NoError(os.MkdirAll(stagingFolder, 0o755))
+		contents, err := json.Marshal(map[string]interface{}{
+			"meta": &operate.InstallParams{
+				CaveID:            caveID,
+				InstallLocationID: "ci",
+				Game:              &itchio.Game{ID: gameID},
+				Upload:            &itchio.Upload{ID: uploadID},
+			},
+		})
+		assert.NoError(err)
+		assert.NoError(ioutil.WriteFile(filepath.Join(stagingFolder, "operate-context.json"), contents, 0o644))
+	}
+	find := func(caveID string) string {
+		_, resumed := findResumableStaging(conn, &state.Consumer{}, il, caveID, gameID, uploadID)
+		if resumed == nil {
+			return "(none)"
+		}
+		return resumed.CaveID
+	}
+
+	stage("installed")
+	assert.EqualValues("installed", find("installed"), "the cave's own update")
+	assert.EqualValues("(none)", find(""), "fresh installs leave other caves' updates alone")
+	assert.EqualValues("(none)", find("other"))
+
+	stage("never-installed")
+	assert.EqualValues("never-installed", find(""), "fresh installs resume other fresh installs")
+	assert.EqualValues("(none)", find("installed"))
+}
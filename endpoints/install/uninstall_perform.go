@@ -7,11 +7,9 @@ import (
 )
 
 func UninstallPerform(rc *butlerd.RequestContext, params butlerd.UninstallPerformParams) (*butlerd.UninstallPerformResult, error) {
-	err := operate.UninstallPerform(rc.Ctx, rc, params)
+	res, err := operate.UninstallPerform(rc.Ctx, rc, params)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-
-	res := &butlerd.UninstallPerformResult{}
 	return res, nil
 }
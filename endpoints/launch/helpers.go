@@ -19,14 +19,14 @@ import (
 	"github.com/pkg/errors"
 )
 
-func getUploadAndBuild(rc *butlerd.RequestContext, info withInstallFolderInfo) (upload *itchio.Upload, build *itchio.Build, err error) {
+func getUploadAndBuild(rc *butlerd.RequestContext, info withInstallFolderInfo, refresh bool) (upload *itchio.Upload, build *itchio.Build, err error) {
 	consumer := rc.Consumer
 
 	upload = info.cave.Upload
 	build = info.cave.Build
 
 	// attempt to refresh upload
-	{
+	if refresh {
 		client := rc.Client(info.access.APIKey)
 		uploadRes, err := client.GetUpload(rc.Ctx, itchio.GetUploadParams{
 			Credentials: info.access.Credentials,
@@ -80,6 +80,12 @@ func getUploadAndBuild(rc *butlerd.RequestContext, info withInstallFolderInfo) (
 type getTargetsParams struct {
 	info  withInstallFolderInfo
 	hosts []manager.Host
+
+	// if true, don't refresh the upload from the API, use what we have
+	offline bool
+
+	// if set, the upload was already looked up by getUploadAndBuild
+	upload *itchio.Upload
 }
 
 type getTargetsResult struct {
@@ -100,12 +106,15 @@ func getTargets(rc *butlerd.RequestContext, params getTargetsParams) (*getTarget
 
 	installFolder := info.installFolder
 
-	upload, _, err := getUploadAndBuild(rc, info)
-	if err != nil {
-		return nil, errors.WithStack(err)
+	upload := params.upload
+	if upload == nil {
+		upload, _, err = getUploadAndBuild(rc, info, !params.offline)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
 	}
 
-	appManifest, err := manifest.Read(installFolder)
+	appManifest, err := operate.ReadAppManifest(consumer, installFolder)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
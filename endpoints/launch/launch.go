@@ -10,10 +10,12 @@ import (
 
 	"github.com/pkg/errors"
 
+	"crawshaw.io/sqlite"
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/butlerd/horror"
 	"github.com/itchio/butler/butlerd/messages"
 	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
 	"github.com/itchio/hush/manifest"
 
 	"github.com/itchio/httpkit/neterr"
@@ -26,6 +28,8 @@ var ErrCandidateDisappeared = goerrors.New("candidate disappeared from disk!")
 
 func Register(router *butlerd.Router) {
 	messages.Launch.Register(router, Launch)
+	messages.LaunchPrecomputePlans.Register(router, PrecomputePlans)
+	messages.InstallPrereqs.Register(router, InstallPrereqs)
 }
 
 func Launch(rc *butlerd.RequestContext, params butlerd.LaunchParams) (*butlerd.LaunchResult, error) {
@@ -33,9 +37,10 @@ func Launch(rc *butlerd.RequestContext, params butlerd.LaunchParams) (*butlerd.L
 	var res *butlerd.LaunchResult
 
 	err := withInstallFolderLock(withInstallFolderLockParams{
-		rc:     rc,
-		caveID: params.CaveID,
-		reason: "Launch",
+		rc:        rc,
+		caveID:    params.CaveID,
+		reason:    "Launch",
+		playEarly: true,
 	}, func(info withInstallFolderInfo) error {
 		cave := info.cave
 		installFolder := info.installFolder
@@ -47,6 +52,11 @@ func Launch(rc *butlerd.RequestContext, params butlerd.LaunchParams) (*butlerd.L
 		consumer.Infof("→ Launching %s", operate.GameToString(game))
 		consumer.Infof("   (%s) is our install folder", installFolder)
 
+		if cave.PlayableEarly {
+			endPlayEarly := operate.BeginPlayEarlySession(consumer, cave.ID)
+			defer endPlayEarly()
+		}
+
 		err := ensureLicenseAcceptance(rc, installFolder)
 		if err != nil {
 			return errors.WithStack(err)
@@ -57,50 +67,25 @@ func Launch(rc *butlerd.RequestContext, params butlerd.LaunchParams) (*butlerd.L
 			return err
 		}
 
-		targetRes, err := getTargets(rc, getTargetsParams{
+		targetRes, err := resolveTargets(rc, getTargetsParams{
 			info:  info,
 			hosts: hosts,
 		})
 		if err != nil {
 			return err
 		}
-		targets := targetRes.targets
-
-		var target *butlerd.LaunchTarget
-		if len(targets) == 0 {
-			return errors.WithStack(butlerd.CodeNoLaunchCandidates)
-		} else if len(targets) == 1 {
-			consumer.Infof("Single target, picking it:")
-			target = targets[0]
-			consumer.Logf("%s", target.Strategy.String())
-		} else {
-			consumer.Infof("Found (%d) targets, asking client to pick via PickManifestAction", len(targets))
-			var actions []*manifest.Action
-			for _, t := range targets {
-				actions = append(actions, t.Action)
-			}
-
-			r, err := messages.PickManifestAction.Call(rc, butlerd.PickManifestActionParams{
-				Actions: actions,
-			})
-			if err != nil {
-				consumer.Warnf("PickManifestAction call failed")
-				return errors.WithStack(err)
-			}
 
-			if r.Index < 0 {
-				consumer.Warnf("PickManifestAction call aborted (Index < 0)")
-				return errors.WithStack(butlerd.CodeOperationAborted)
-			}
-
-			target = targets[r.Index]
-			consumer.Infof("Target picked:")
-			consumer.Logf("%s", target.Strategy.String())
+		target, err := pickTarget(rc, targetRes.targets, cave.PreferredLaunchTarget)
+		if err != nil {
+			return err
 		}
 
 		consumer.Infof("→ Using strategy (%s)", target.Strategy.Strategy)
 		consumer.Infof("  target (%s)", target.Strategy.FullTargetPath)
 		consumer.Infof("  host (%s)", target.Host)
+		if cave.VirtualMachineRequired != "" {
+			consumer.Infof("  needs (%s)", cave.VirtualMachineRequired)
+		}
 
 		launcher := launchers[target.Strategy.Strategy]
 		if launcher == nil {
@@ -120,6 +105,11 @@ func Launch(rc *butlerd.RequestContext, params butlerd.LaunchParams) (*butlerd.L
 			return errors.WithMessage(err, "While requesting API key")
 		}
 
+		skipPrereqs := cave.PrereqsSkipped && !params.ForcePrereqs
+		if skipPrereqs {
+			consumer.Infof("Installed with skipPrereqs, not installing prereqs")
+		}
+
 		sandbox := params.Sandbox
 		if target.Action.Sandbox {
 			consumer.Infof("Enabling sandbox because of manifest opt-in")
@@ -139,13 +129,18 @@ func Launch(rc *butlerd.RequestContext, params butlerd.LaunchParams) (*butlerd.L
 			defer close(sessionWatcherDone)
 			defer horror.RecoverAndLog(consumer)
 
+			if operate.IsLocalGame(cave.GameID) {
+				consumer.Debugf("Installed from a local archive, not recording a session")
+				return
+			}
+
 			lastRunAt := time.Now().UTC()
 			sessionStartedAt := time.Now().UTC()
 			var secondsRun int64 = 0
 
 			conn := rc.GetConn()
 			defer rc.PutConn(conn)
-			access := operate.AccessForGameID(conn, cave.GameID)
+			access := operate.AccessForCave(conn, cave)
 			client := rc.Client(access.APIKey)
 
 			var session *itchio.UserGameSession
@@ -170,7 +165,7 @@ func Launch(rc *butlerd.RequestContext, params butlerd.LaunchParams) (*butlerd.L
 				session = res.UserGameSession
 
 				cave.UpdateInteractions(res.Summary)
-				rc.WithConn(cave.Save)
+				saveInteractions(rc, cave)
 
 				return
 			}
@@ -193,7 +188,7 @@ func Launch(rc *butlerd.RequestContext, params butlerd.LaunchParams) (*butlerd.L
 				session = res.UserGameSession
 
 				cave.UpdateInteractions(res.Summary)
-				rc.WithConn(cave.Save)
+				saveInteractions(rc, cave)
 
 				return
 			}
@@ -259,10 +254,15 @@ func Launch(rc *butlerd.RequestContext, params butlerd.LaunchParams) (*butlerd.L
 
 			PrereqsDir:    params.PrereqsDir,
 			ForcePrereqs:  params.ForcePrereqs,
+			SkipPrereqs:   skipPrereqs,
 			Access:        access,
 			InstallFolder: installFolder,
 			Host:          target.Host,
 
+			PrereqsInstalled: func() {
+				markPrereqsInstalled(rc, cave)
+			},
+
 			SessionStarted: func() {
 				startSessionOnce.Do(func() {
 					close(sessionStartedChan)
@@ -295,6 +295,78 @@ func Launch(rc *butlerd.RequestContext, params butlerd.LaunchParams) (*butlerd.L
 	return res, nil
 }
 
+// saveInteractions saves what UpdateInteractions changed on cave, and
+// only that: caves that are playable early get committed by their install
+// while their game runs.
+func saveInteractions(rc *butlerd.RequestContext, cave *models.Cave) {
+	rc.WithConn(func(conn *sqlite.Conn) {
+		fresh := models.CaveByID(conn, cave.ID)
+		if fresh == nil {
+			return
+		}
+		fresh.SecondsRun = cave.SecondsRun
+		fresh.LastTouchedAt = cave.LastTouchedAt
+		fresh.Save(conn)
+	})
+}
+
+// pickTarget settles on one of targets: the only one, the preferred
+// one, or the one the client picks via PickManifestAction.
+func pickTarget(rc *butlerd.RequestContext, targets []*butlerd.LaunchTarget, preferredName string) (*butlerd.LaunchTarget, error) {
+	consumer := rc.Consumer
+
+	var target *butlerd.LaunchTarget
+	if len(targets) == 0 {
+		return nil, errors.WithStack(butlerd.CodeNoLaunchCandidates)
+	} else if len(targets) == 1 {
+		consumer.Infof("Single target, picking it:")
+		target = targets[0]
+		consumer.Logf("%s", target.Strategy.String())
+	} else if preferred := findPreferredTarget(targets, preferredName); preferred != nil {
+		consumer.Infof("Found (%d) targets, picking preferred one (%s):", len(targets), preferredName)
+		target = preferred
+		consumer.Logf("%s", target.Strategy.String())
+	} else {
+		consumer.Infof("Found (%d) targets, asking client to pick via PickManifestAction", len(targets))
+		var actions []*manifest.Action
+		for _, t := range targets {
+			actions = append(actions, t.Action)
+		}
+
+		r, err := messages.PickManifestAction.Call(rc, butlerd.PickManifestActionParams{
+			Actions: actions,
+		})
+		if err != nil {
+			consumer.Warnf("PickManifestAction call failed")
+			return nil, errors.WithStack(err)
+		}
+
+		if r.Index < 0 {
+			consumer.Warnf("PickManifestAction call aborted (Index < 0)")
+			return nil, errors.WithStack(butlerd.CodeOperationAborted)
+		}
+
+		target = targets[r.Index]
+		consumer.Infof("Target picked:")
+		consumer.Logf("%s", target.Strategy.String())
+	}
+	return target, nil
+}
+
+// findPreferredTarget returns the target whose manifest action is
+// named name, if there's one, see butlerd.CavesSetLaunchTargetParams.
+func findPreferredTarget(targets []*butlerd.LaunchTarget, name string) *butlerd.LaunchTarget {
+	if name == "" {
+		return nil
+	}
+	for _, t := range targets {
+		if t.Action != nil && t.Action.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
 func requestAPIKeyIfNecessary(rc *butlerd.RequestContext, manifestAction *manifest.Action, game *itchio.Game, access *operate.GameAccess, env map[string]string) error {
 	consumer := rc.Consumer
 
@@ -37,6 +37,7 @@ func Register() {
 type Launcher struct{}
 
 var _ launch.Launcher = (*Launcher)(nil)
+var _ launch.PrereqsInstaller = (*Launcher)(nil)
 
 func (l *Launcher) Do(params launch.LauncherParams) error {
 	consumer := params.RequestContext.Consumer
@@ -69,7 +70,15 @@ func (l *Launcher) Do(params launch.LauncherParams) error {
 		consumer.Warnf("Could not determine PE info: %s", err.Error())
 	}
 
-	err = handlePrereqs(params)
+	if params.SkipPrereqs {
+		consumer.Infof("Skipping prereqs, they can be installed with Install.Prereqs")
+		err = nil
+	} else {
+		err = handlePrereqs(params)
+		if err == nil && params.PrereqsInstalled != nil {
+			params.PrereqsInstalled()
+		}
+	}
 	if err != nil {
 		if be, ok := butlerd.AsButlerdError(err); ok {
 			switch butlerd.Code(be.RpcErrorCode()) {
@@ -353,6 +362,27 @@ func (l *Launcher) AttachParams(params launch.LauncherParams) runner.AttachParam
 	}
 }
 
+func (l *Launcher) InstallPrereqs(params launch.LauncherParams) error {
+	consumer := params.RequestContext.Consumer
+
+	_, err := os.Stat(params.FullTargetPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = configureTargetIfNeeded(params)
+	if err != nil {
+		consumer.Warnf("Could not configure launch target: %s", err.Error())
+	}
+
+	err = fillPeInfoIfNeeded(params)
+	if err != nil {
+		consumer.Warnf("Could not determine PE info: %s", err.Error())
+	}
+
+	return handlePrereqs(params)
+}
+
 func configureTargetIfNeeded(params launch.LauncherParams) error {
 	if params.Candidate != nil {
 		// already configured
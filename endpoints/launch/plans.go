@@ -0,0 +1,273 @@
+package launch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/horror"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/endpoints/fetch/cavefilter"
+	"github.com/itchio/butler/manager"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/hades"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/hush/bfs"
+	"github.com/itchio/hush/manifest"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
+)
+
+// bump when the way targets are resolved changes, so that
+// plans stored by older versions get ignored
+const launchPlanVersion = 1
+
+// launchPlan is what gets stored on a cave, see Launch.PrecomputePlans
+type launchPlan struct {
+	AppManifest *manifest.Manifest      `json:"appManifest"`
+	Targets     []*butlerd.LaunchTarget `json:"targets"`
+}
+
+// launchPlanKey changes whenever something that influences target
+// resolution does: a new build got installed (receipt), the app
+// manifest was edited, the upload type changed (soundtracks are
+// browsed, not run), or the available hosts changed.
+func launchPlanKey(installFolder string, upload *itchio.Upload, hosts []manager.Host) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "v%d\n", launchPlanVersion)
+	if upload != nil {
+		fmt.Fprintf(h, "upload %d %s\n", upload.ID, upload.Type)
+	}
+
+	for _, path := range []string{bfs.ReceiptPath(installFolder), manifest.Path(installFolder)} {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return "", errors.WithStack(err)
+			}
+			contents = []byte("<missing>")
+		}
+		contentsHash := sha256.Sum256(contents)
+		fmt.Fprintf(h, "%s\n", hex.EncodeToString(contentsHash[:]))
+	}
+
+	hostsJSON, err := json.Marshal(hosts)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	h.Write(hostsJSON)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// loadLaunchPlan returns the plan stored on a cave, or nil if there
+// is none, or if it's not valid anymore.
+func loadLaunchPlan(consumer *state.Consumer, cave *models.Cave, key string) *getTargetsResult {
+	if cave.LaunchPlan == "" || cave.LaunchPlanKey != key {
+		return nil
+	}
+
+	var plan launchPlan
+	err := models.UnmarshalJSON(cave.LaunchPlan, &plan)
+	if err != nil {
+		consumer.Warnf("Could not read stored launch plan: %v", err)
+		return nil
+	}
+	if len(plan.Targets) == 0 {
+		return nil
+	}
+
+	for _, target := range plan.Targets {
+		if target.Strategy == nil {
+			return nil
+		}
+		if target.Strategy.Strategy == butlerd.LaunchStrategyURL {
+			continue
+		}
+		_, err := os.Stat(target.Strategy.FullTargetPath)
+		if err != nil {
+			consumer.Infof("Stored launch plan refers to missing target (%s), ignoring it", target.Strategy.FullTargetPath)
+			return nil
+		}
+	}
+
+	return &getTargetsResult{
+		appManifest: plan.AppManifest,
+		targets:     plan.Targets,
+	}
+}
+
+func storeLaunchPlan(rc *butlerd.RequestContext, cave *models.Cave, key string, res *getTargetsResult) error {
+	plan := &launchPlan{
+		AppManifest: res.appManifest,
+		Targets:     res.targets,
+	}
+	err := models.MarshalJSON(plan, &cave.LaunchPlan)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	cave.LaunchPlanKey = key
+
+	// only touch the plan columns, the cave might be saved
+	// concurrently for other reasons (playtime, etc.)
+	rc.WithConn(func(conn *sqlite.Conn) {
+		models.MustUpdate(conn, &models.Cave{},
+			hades.Where(builder.Eq{"id": cave.ID}),
+			builder.Eq{
+				"launch_plan":     cave.LaunchPlan,
+				"launch_plan_key": cave.LaunchPlanKey,
+			},
+		)
+	})
+	return nil
+}
+
+// resolveTargets uses the cave's stored launch plan if it's still valid,
+// and resolves targets (then stores them) otherwise.
+func resolveTargets(rc *butlerd.RequestContext, params getTargetsParams) (*getTargetsResult, error) {
+	consumer := rc.Consumer
+	cave := params.info.cave
+
+	upload, _, err := getUploadAndBuild(rc, params.info, !params.offline)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	params.upload = upload
+
+	key, err := launchPlanKey(params.info.installFolder, upload, params.hosts)
+	if err != nil {
+		consumer.Warnf("Could not compute launch plan key: %v", err)
+		return getTargets(rc, params)
+	}
+
+	if res := loadLaunchPlan(consumer, cave, key); res != nil {
+		consumer.Infof("Using stored launch plan (%d targets)", len(res.targets))
+		return res, nil
+	}
+
+	res, err := getTargets(rc, params)
+	if err != nil {
+		return nil, err
+	}
+
+	err = storeLaunchPlan(rc, cave, key, res)
+	if err != nil {
+		consumer.Warnf("Could not store launch plan: %v", err)
+	}
+	return res, nil
+}
+
+func PrecomputePlans(rc *butlerd.RequestContext, params butlerd.LaunchPrecomputePlansParams) (*butlerd.LaunchPrecomputePlansResult, error) {
+	consumer := rc.Consumer
+
+	cond := builder.NewCond()
+	if params.Filter != "" {
+		var err error
+		cond, err = cavefilter.Parse(params.Filter)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	var caves []*models.Cave
+	rc.WithConn(func(conn *sqlite.Conn) {
+		search := hades.Search{}.InnerJoin("games", "games.id = caves.game_id")
+		models.MustSelect(conn, &caves, cond, search)
+	})
+
+	hosts, err := rc.HostEnumerator().Enumerate(consumer)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &butlerd.LaunchPrecomputePlansResult{
+		Plans: []*butlerd.LaunchPlanStatus{},
+	}
+	for _, cave := range caves {
+		status := precomputePlan(rc, cave.ID, hosts, params.Force)
+		res.Plans = append(res.Plans, status)
+	}
+
+	var numOK, numReused int
+	for _, status := range res.Plans {
+		if status.OK {
+			numOK++
+		}
+		if status.Reused {
+			numReused++
+		}
+	}
+	consumer.Statf("Launch plans ready for %d/%d caves (%d reused)", numOK, len(res.Plans), numReused)
+	return res, nil
+}
+
+func precomputePlan(rc *butlerd.RequestContext, caveID string, hosts []manager.Host, force bool) *butlerd.LaunchPlanStatus {
+	consumer := rc.Consumer
+	status := &butlerd.LaunchPlanStatus{
+		CaveID: caveID,
+	}
+
+	err := func() (retErr error) {
+		defer horror.RecoverInto(&retErr)
+
+		info, err := getInstallFolderInfo(rc, caveID)
+		if err != nil {
+			return err
+		}
+
+		upload, _, err := getUploadAndBuild(rc, *info, false)
+		if err != nil {
+			return err
+		}
+
+		key, err := launchPlanKey(info.installFolder, upload, hosts)
+		if err != nil {
+			return err
+		}
+
+		var targetRes *getTargetsResult
+		if !force {
+			targetRes = loadLaunchPlan(consumer, info.cave, key)
+			status.Reused = targetRes != nil
+		}
+
+		if targetRes == nil {
+			targetRes, err = getTargets(rc, getTargetsParams{
+				info:   *info,
+				hosts:  hosts,
+				upload: upload,
+			})
+			if err != nil {
+				return err
+			}
+
+			err = storeLaunchPlan(rc, info.cave, key, targetRes)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, target := range targetRes.targets {
+			status.Strategies = append(status.Strategies, target.Strategy.Strategy)
+		}
+		return nil
+	}()
+
+	if err != nil {
+		consumer.Warnf("Could not compute launch plan for cave (%s): %v", caveID, err)
+		status.Error = err.Error()
+		if be, ok := butlerd.AsButlerdError(err); ok {
+			status.ErrorCode = be.RpcErrorCode()
+			status.Error = be.RpcErrorMessage()
+		}
+		return status
+	}
+
+	status.OK = true
+	return status
+}
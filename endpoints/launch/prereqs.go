@@ -0,0 +1,94 @@
+package launch
+
+import (
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/hades"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
+)
+
+func InstallPrereqs(rc *butlerd.RequestContext, params butlerd.InstallPrereqsParams) (*butlerd.InstallPrereqsResult, error) {
+	consumer := rc.Consumer
+
+	err := withInstallFolderLock(withInstallFolderLockParams{
+		rc:     rc,
+		caveID: params.CaveID,
+		reason: "InstallPrereqs",
+	}, func(info withInstallFolderInfo) error {
+		cave := info.cave
+
+		consumer.Infof("→ Installing prereqs for %s", operate.GameToString(cave.Game))
+
+		hosts, err := rc.HostEnumerator().Enumerate(rc.Consumer)
+		if err != nil {
+			return err
+		}
+
+		targetRes, err := resolveTargets(rc, getTargetsParams{
+			info:  info,
+			hosts: hosts,
+		})
+		if err != nil {
+			return err
+		}
+
+		target, err := pickTarget(rc, targetRes.targets, cave.PreferredLaunchTarget)
+		if err != nil {
+			return err
+		}
+
+		installer, ok := launchers[target.Strategy.Strategy].(PrereqsInstaller)
+		if !ok {
+			consumer.Infof("Strategy (%s) has no prereqs", target.Strategy.Strategy)
+			markPrereqsInstalled(rc, cave)
+			return nil
+		}
+
+		err = installer.InstallPrereqs(LauncherParams{
+			RequestContext: rc,
+			Ctx:            rc.Ctx,
+
+			FullTargetPath: target.Strategy.FullTargetPath,
+			Candidate:      target.Strategy.Candidate,
+			AppManifest:    targetRes.appManifest,
+			Action:         target.Action,
+			Sandbox:        target.Action.Sandbox,
+
+			PrereqsDir:    params.PrereqsDir,
+			ForcePrereqs:  params.Force,
+			Access:        info.access,
+			InstallFolder: info.installFolder,
+			Host:          target.Host,
+		})
+		if err != nil {
+			return errors.WithMessage(err, "While installing prereqs")
+		}
+
+		markPrereqsInstalled(rc, cave)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &butlerd.InstallPrereqsResult{}, nil
+}
+
+// markPrereqsInstalled clears the cave's PrereqsSkipped flag, so
+// the next launches handle prereqs as usual.
+func markPrereqsInstalled(rc *butlerd.RequestContext, cave *models.Cave) {
+	if !cave.PrereqsSkipped {
+		return
+	}
+
+	cave.PrereqsSkipped = false
+	rc.WithConn(func(conn *sqlite.Conn) {
+		models.MustUpdate(conn, &models.Cave{},
+			hades.Where(builder.Eq{"id": cave.ID}),
+			builder.Eq{"prereqs_skipped": false},
+		)
+	})
+	rc.Consumer.Infof("Prereqs installed, cave (%s) will handle them on launch again", cave.ID)
+}
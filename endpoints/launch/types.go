@@ -52,11 +52,15 @@ type LauncherParams struct {
 
 	PrereqsDir    string
 	ForcePrereqs  bool
+	SkipPrereqs   bool
 	Access        *operate.GameAccess
 	InstallFolder string
 	Host          manager.Host
 
 	SessionStarted func()
+
+	// Called once prereqs were handled successfully. May be nil
+	PrereqsInstalled func()
 }
 
 // cf. https://github.com/itchio/itch/issues/1751
@@ -114,6 +118,12 @@ type Launcher interface {
 	Do(params LauncherParams) error
 }
 
+// PrereqsInstaller is implemented by launchers whose targets
+// may need prereqs, see butlerd.InstallPrereqsParams
+type PrereqsInstaller interface {
+	InstallPrereqs(params LauncherParams) error
+}
+
 var launchers = make(map[butlerd.LaunchStrategy]Launcher)
 
 func RegisterLauncher(strategy butlerd.LaunchStrategy, launcher Launcher) {
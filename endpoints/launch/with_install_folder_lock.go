@@ -9,6 +9,8 @@ import (
 	"github.com/itchio/butler/cmd/operate"
 	"github.com/itchio/butler/database/models"
 	"github.com/itchio/butler/manager/runlock"
+	"github.com/itchio/butler/mansion/settings"
+	"github.com/itchio/headway/united"
 	"github.com/pkg/errors"
 
 	"github.com/itchio/ox"
@@ -20,6 +22,9 @@ type withInstallFolderLockParams struct {
 	rc     *butlerd.RequestContext
 	caveID string
 	reason string
+	// if set, caves that are playable early are used without locking
+	// them, since their install holds the lock until it's done
+	playEarly bool
 }
 
 type withInstallFolderInfo struct {
@@ -42,40 +47,118 @@ func withInstallFolderLock(params withInstallFolderLockParams, f func(info withI
 	rc := params.rc
 	consumer := rc.Consumer
 
-	cave := operate.ValidateCave(rc, params.caveID)
-	var installFolder string
-	rc.WithConn(func(conn *sqlite.Conn) {
-		installFolder = cave.GetInstallFolder(conn)
-	})
+	info, err := getInstallFolderInfo(rc, params.caveID)
+	if err != nil {
+		return err
+	}
 
-	_, err = os.Stat(installFolder)
-	if err != nil && os.IsNotExist(err) {
-		return &butlerd.RpcError{
-			Code:    int64(butlerd.CodeInstallFolderDisappeared),
-			Message: fmt.Sprintf("Could not find install folder (%s)", installFolder),
-		}
+	if params.playEarly && info.cave.PlayableEarly {
+		consumer.Warnf("Cave is still being installed (%s left), going ahead anyway",
+			united.FormatBytes(operate.PlayEarlyRemainingBytes(info.cave)))
+		return f(*info)
 	}
 
-	rlock := runlock.New(consumer, installFolder)
+	rlock := runlock.New(consumer, info.installFolder)
 	err = rlock.Lock(rc.Ctx, params.reason)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 	defer rlock.Unlock()
 
+	return f(*info)
+}
+
+// getInstallFolderInfo looks up a cave and makes sure its install folder
+// still exists, without locking it.
+func getInstallFolderInfo(rc *butlerd.RequestContext, caveID string) (*withInstallFolderInfo, error) {
+	cave := operate.ValidateCave(rc, caveID)
+	var installFolder string
+	rc.WithConn(func(conn *sqlite.Conn) {
+		installFolder = cave.GetInstallFolder(conn)
+	})
+
+	_, err := os.Stat(installFolder)
+	if err != nil && os.IsNotExist(err) {
+		installFolder, err = relinkMovedCave(rc, cave, installFolder)
+		if err != nil {
+			return nil, err
+		}
+		cave = operate.ValidateCave(rc, caveID)
+	}
+
 	var access *operate.GameAccess
 	rc.WithConn(func(conn *sqlite.Conn) {
-		access = operate.AccessForGameID(conn, cave.Game.ID).OnlyAPIKey()
+		access = operate.AccessForCave(conn, cave).OnlyAPIKey()
 	})
 
 	runtime := ox.CurrentRuntime()
 
-	info := withInstallFolderInfo{
+	return &withInstallFolderInfo{
 		installFolder,
 		cave,
 		access,
 		runtime,
+	}, nil
+}
+
+var autoRelink = settings.Register(settings.Setting{
+	Key:         "caves.autoRelink",
+	Description: "Relink caves whose install folder was moved without asking, when exactly one folder matches exactly.",
+	Default:     false,
+})
+
+// relinkMovedCave looks for the folder cave was moved to, now that
+// installFolder is gone. It relinks the cave if caves.autoRelink allows
+// it, and returns the new install folder. Otherwise, it fails with
+// what was found, for the user to pick from.
+func relinkMovedCave(rc *butlerd.RequestContext, cave *models.Cave, installFolder string) (string, error) {
+	consumer := rc.Consumer
+	consumer.Warnf("Install folder (%s) is missing, looking for where it was moved...", installFolder)
+
+	var candidates []*butlerd.MovedCaveCandidate
+	rc.WithConn(func(conn *sqlite.Conn) {
+		candidates = operate.FindMovedCave(conn, consumer, cave, nil)
+	})
+
+	// several candidates are never guessed between, even if one is exact
+	if autoRelink.Bool() && len(candidates) == 1 && candidates[0].Exact {
+		var err error
+		rc.WithConn(func(conn *sqlite.Conn) {
+			err = operate.RelinkCave(conn, consumer, cave, candidates[0].InstallFolder)
+		})
+		if err == nil {
+			return candidates[0].InstallFolder, nil
+		}
+		consumer.Warnf("Could not relink automatically: %s", err.Error())
 	}
 
-	return f(info)
+	return "", &installFolderDisappearedError{
+		InstallFolder: installFolder,
+		Candidates:    candidates,
+	}
+}
+
+type installFolderDisappearedError struct {
+	InstallFolder string
+	Candidates    []*butlerd.MovedCaveCandidate
+}
+
+var _ butlerd.Error = (*installFolderDisappearedError)(nil)
+
+func (e *installFolderDisappearedError) RpcErrorCode() int64 {
+	return int64(butlerd.CodeInstallFolderDisappeared)
+}
+
+func (e *installFolderDisappearedError) RpcErrorMessage() string {
+	return fmt.Sprintf("Could not find install folder (%s)", e.InstallFolder)
+}
+
+func (e *installFolderDisappearedError) RpcErrorData() map[string]interface{} {
+	return map[string]interface{}{
+		"candidates": e.Candidates,
+	}
+}
+
+func (e *installFolderDisappearedError) Error() string {
+	return e.RpcErrorMessage()
 }
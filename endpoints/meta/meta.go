@@ -8,12 +8,16 @@ import (
 
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/endpoints/tasks"
 	"github.com/pkg/errors"
 )
 
 var establishedAt *time.Time
 var establishedLock sync.Mutex
 
+// ghost caves are looked for in the background once per daemon
+var ghostCaveDetectOnce sync.Once
+
 func Register(router *butlerd.Router) {
 	messages.MetaAuthenticate.Register(router, func(rc *butlerd.RequestContext, params butlerd.MetaAuthenticateParams) (*butlerd.MetaAuthenticateResult, error) {
 		return nil, errors.Errorf("Meta.Authenticate not needed (and not valid) for your current transport")
@@ -33,6 +37,9 @@ func Register(router *butlerd.Router) {
 		messages.MetaFlowEstablished.Notify(rc, butlerd.MetaFlowEstablishedNotification{
 			PID: int64(os.Getpid()),
 		})
+		ghostCaveDetectOnce.Do(func() {
+			rc.QueueBackgroundTask(tasks.GhostCaveDetect(rc))
+		})
 
 		var never chan struct{}
 		select {
@@ -0,0 +1,211 @@
+package profile
+
+import (
+	"strconv"
+
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/horror"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/endpoints/tasks"
+	"github.com/itchio/go-itchio"
+	"github.com/itchio/hades"
+	"github.com/itchio/headway/state"
+	"xorm.io/builder"
+)
+
+func DataErase(rc *butlerd.RequestContext, params butlerd.ProfileDataEraseParams) (*butlerd.ProfileDataEraseResult, error) {
+	consumer := rc.Consumer
+
+	res := &butlerd.ProfileDataEraseResult{
+		Removed: &butlerd.ProfileDataEraseCounts{},
+	}
+
+	var caves []*models.Cave
+	var err error
+	rc.WithConn(func(conn *sqlite.Conn) {
+		caves, err = eraseProfileData(conn, consumer, params.ProfileID, res)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cave := range caves {
+		if params.AlsoUninstall {
+			consumer.Infof("Queuing uninstall for cave (%s)", cave.ID)
+			rc.QueueBackgroundTask(tasks.UninstallCave(rc, cave.ID))
+			res.UninstallsQueued++
+		} else {
+			consumer.Warnf("Cave (%s) was installed by profile %d, it no longer belongs to any profile", cave.ID, params.ProfileID)
+			res.CavesReassigned++
+		}
+	}
+
+	r := res.Removed
+	consumer.Statf("Erased profile %d: %d games, %d keys, %d collections, %d downloads, %d profile data entries",
+		params.ProfileID, r.Games, r.DownloadKeys, r.Collections, r.Downloads, r.ProfileData)
+	return res, nil
+}
+
+// eraseProfileData removes every row that belongs to a profile, within a
+// single savepoint, and returns the caves that were installed with it (they
+// are kept, but reassigned to no profile).
+func eraseProfileData(conn *sqlite.Conn, consumer *state.Consumer, profileID int64, res *butlerd.ProfileDataEraseResult) (caves []*models.Cave, retErr error) {
+	defer horror.RecoverInto(&retErr)
+	defer sqlitex.Save(conn)(&retErr)
+
+	removed := res.Removed
+	erase := func(model interface{}, cond builder.Cond) int64 {
+		count := models.MustCount(conn, model, cond)
+		if count > 0 {
+			models.MustDelete(conn, model, cond)
+		}
+		return count
+	}
+
+	res.Success = models.ProfileByID(conn, profileID) != nil
+	if !res.Success {
+		consumer.Warnf("No profile %d, erasing leftovers only", profileID)
+	}
+
+	// games we may have cached on behalf of this profile
+	candidateGameIDs := make(map[int64]bool)
+
+	var profileGames []*models.ProfileGame
+	models.MustSelect(conn, &profileGames, builder.Eq{"profile_id": profileID}, hades.Search{})
+	for _, pg := range profileGames {
+		candidateGameIDs[pg.GameID] = true
+	}
+	removed.ProfileGames = erase(&models.ProfileGame{}, builder.Eq{"profile_id": profileID})
+
+	var keys []*itchio.DownloadKey
+	models.MustSelect(conn, &keys, builder.Eq{"owner_id": profileID}, hades.Search{})
+	for _, key := range keys {
+		candidateGameIDs[key.GameID] = true
+	}
+	removed.DownloadKeys = erase(&itchio.DownloadKey{}, builder.Eq{"owner_id": profileID})
+
+	// collections, unless another profile also has them
+	collectionIDs := make(map[int64]bool)
+	var profileCollections []*models.ProfileCollection
+	models.MustSelect(conn, &profileCollections, builder.Eq{"profile_id": profileID}, hades.Search{})
+	for _, pc := range profileCollections {
+		collectionIDs[pc.CollectionID] = true
+	}
+	var ownCollections []*itchio.Collection
+	models.MustSelect(conn, &ownCollections, builder.Eq{"user_id": profileID}, hades.Search{})
+	for _, c := range ownCollections {
+		collectionIDs[c.ID] = true
+	}
+	erase(&models.ProfileCollection{}, builder.Eq{"profile_id": profileID})
+
+	var fetchInfoTargets []models.FetchTarget
+	for collectionID := range collectionIDs {
+		if models.MustCount(conn, &models.ProfileCollection{}, builder.Eq{"collection_id": collectionID}) > 0 {
+			continue
+		}
+
+		var collectionGames []*itchio.CollectionGame
+		models.MustSelect(conn, &collectionGames, builder.Eq{"collection_id": collectionID}, hades.Search{})
+		for _, cg := range collectionGames {
+			candidateGameIDs[cg.GameID] = true
+		}
+		removed.CollectionGames += erase(&itchio.CollectionGame{}, builder.Eq{"collection_id": collectionID})
+		removed.Collections += erase(&itchio.Collection{}, builder.Eq{"id": collectionID})
+		fetchInfoTargets = append(fetchInfoTargets,
+			models.FetchTargetForCollection(collectionID),
+			models.FetchTargetForCollectionGames(collectionID),
+		)
+	}
+
+	// caves are kept, downloads are history
+	models.MustSelect(conn, &caves, builder.Eq{"source_profile_id": profileID}, hades.Search{})
+	caveIDs := make(map[string]bool)
+	for _, cave := range caves {
+		caveIDs[cave.ID] = true
+	}
+	if len(caves) > 0 {
+		models.MustUpdate(conn, &models.Cave{},
+			hades.Where(builder.Eq{"source_profile_id": profileID}),
+			builder.Eq{"source_profile_id": 0},
+		)
+	}
+
+	var downloads []*models.Download
+	models.MustSelect(conn, &downloads, builder.NewCond(), hades.Search{})
+	for _, dl := range downloads {
+		if !caveIDs[dl.CaveID] && downloadProfileID(dl) != profileID {
+			continue
+		}
+		// pending downloads go too: they couldn't proceed
+		// without the profile's credentials anyway
+		removed.Downloads += erase(&models.Download{}, builder.Eq{"id": dl.ID})
+	}
+
+	for gameID := range candidateGameIDs {
+		if gameStillReferenced(conn, gameID) {
+			continue
+		}
+		erase(&models.GameUpload{}, builder.Eq{"game_id": gameID})
+		erase(&itchio.GameEmbedData{}, builder.Eq{"game_id": gameID})
+		erase(&itchio.Sale{}, builder.Eq{"game_id": gameID})
+		removed.Games += erase(&itchio.Game{}, builder.Eq{"id": gameID})
+		fetchInfoTargets = append(fetchInfoTargets,
+			models.FetchTargetForGame(gameID),
+			models.FetchTargetForGameUploads(gameID),
+		)
+	}
+
+	removed.ProfileData = erase(&models.ProfileData{}, builder.Eq{"profile_id": profileID})
+
+	fetchInfoTargets = append(fetchInfoTargets,
+		models.FetchTargetForProfileCollections(profileID),
+		models.FetchTargetForProfileGames(profileID),
+		models.FetchTargetForProfileOwnedKeys(profileID),
+	)
+	for _, ft := range fetchInfoTargets {
+		removed.FetchInfo += erase(&models.FetchInfo{}, builder.Eq{
+			"object_type": ft.Type,
+			"object_id":   strconv.FormatInt(ft.ID, 10),
+		})
+	}
+
+	erase(&models.Profile{}, builder.Eq{"id": profileID})
+
+	// the profile's user record, unless it's also a game or collection author
+	userReferenced := models.MustCount(conn, &itchio.Game{}, builder.Eq{"user_id": profileID}) > 0 ||
+		models.MustCount(conn, &itchio.Collection{}, builder.Eq{"user_id": profileID}) > 0
+	if !userReferenced {
+		erase(&itchio.User{}, builder.Eq{"id": profileID})
+		removed.FetchInfo += erase(&models.FetchInfo{}, builder.Eq{
+			"object_type": models.FetchTargetForUser(profileID).Type,
+			"object_id":   strconv.FormatInt(profileID, 10),
+		})
+	}
+
+	return caves, nil
+}
+
+// downloadProfileID returns the ID of the profile whose credentials
+// were picked for a download, or 0 if unknown
+func downloadProfileID(dl *models.Download) int64 {
+	if dl.AccessExplanation == "" {
+		return 0
+	}
+	var access butlerd.AccessExplanation
+	err := models.UnmarshalJSON(dl.AccessExplanation, &access)
+	if err != nil {
+		return 0
+	}
+	return access.ProfileID
+}
+
+func gameStillReferenced(conn *sqlite.Conn, gameID int64) bool {
+	cond := builder.Eq{"game_id": gameID}
+	return models.MustCount(conn, &models.Cave{}, cond) > 0 ||
+		models.MustCount(conn, &models.Download{}, cond) > 0 ||
+		models.MustCount(conn, &models.ProfileGame{}, cond) > 0 ||
+		models.MustCount(conn, &itchio.DownloadKey{}, cond) > 0 ||
+		models.MustCount(conn, &itchio.CollectionGame{}, cond) > 0
+}
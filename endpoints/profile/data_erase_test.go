@@ -0,0 +1,206 @@
+package profile
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/stretchr/testify/assert"
+	"xorm.io/builder"
+)
+
+const (
+	erasedProfileID = 1
+	otherProfileID  = 2
+	developerID     = 100
+)
+
+func Test_DataErase(t *testing.T) {
+	assert := assert.New(t)
+
+	conn, err := sqlite.OpenConn("file:data_erase_test?mode=memory", 0)
+	must(t, err)
+	defer conn.Close()
+
+	consumer := &state.Consumer{}
+	must(t, database.Prepare(consumer, conn, true))
+
+	seedProfiles(t, conn)
+
+	res := &butlerd.ProfileDataEraseResult{
+		Removed: &butlerd.ProfileDataEraseCounts{},
+	}
+	caves, err := eraseProfileData(conn, consumer, erasedProfileID, res)
+	must(t, err)
+
+	assert.True(res.Success)
+	assert.EqualValues(&butlerd.ProfileDataEraseCounts{
+		ProfileGames:    2,
+		Games:           2,
+		DownloadKeys:    1,
+		Collections:     1,
+		CollectionGames: 1,
+		Downloads:       2,
+		ProfileData:     1,
+		FetchInfo:       3,
+	}, res.Removed)
+
+	assert.Len(caves, 1)
+	assert.EqualValues("cave-1", caves[0].ID)
+
+	assert.Empty(rowsReferencing(t, conn, erasedProfileID))
+
+	// the cave is still here, it just doesn't belong to anyone
+	cave := models.CaveByID(conn, "cave-1")
+	if assert.NotNil(cave) {
+		assert.EqualValues(0, cave.SourceProfileID)
+	}
+
+	// the other profile's data is untouched
+	assert.NotNil(models.ProfileByID(conn, otherProfileID))
+	assert.EqualValues(1, models.MustCount(conn, &itchio.DownloadKey{}, builder.NewCond()))
+	assert.EqualValues(1, models.MustCount(conn, &itchio.Collection{}, builder.NewCond()))
+	assert.EqualValues(1, models.MustCount(conn, &models.Download{}, builder.NewCond()))
+	assert.EqualValues(1, models.MustCount(conn, &models.ProfileData{}, builder.NewCond()))
+	// games still referenced by caves, other keys, shared collections or
+	// their developer's profile are kept
+	assert.EqualValues(4, models.MustCount(conn, &itchio.Game{}, builder.NewCond()))
+
+	// erasing again finds nothing
+	res = &butlerd.ProfileDataEraseResult{
+		Removed: &butlerd.ProfileDataEraseCounts{},
+	}
+	caves, err = eraseProfileData(conn, consumer, erasedProfileID, res)
+	must(t, err)
+	assert.False(res.Success)
+	assert.Empty(caves)
+	assert.EqualValues(&butlerd.ProfileDataEraseCounts{}, res.Removed)
+}
+
+func seedProfiles(t *testing.T, conn *sqlite.Conn) {
+	now := time.Now().UTC()
+	save := func(record interface{}) {
+		must(t, models.Save(conn, record))
+	}
+
+	for _, id := range []int64{erasedProfileID, otherProfileID} {
+		save(&models.Profile{
+			ID:     id,
+			APIKey: fmt.Sprintf("key-%d", id),
+			UserID: id,
+			User: &itchio.User{
+				ID:       id,
+				Username: fmt.Sprintf("user%d", id),
+			},
+		})
+	}
+
+	game := func(id int64) {
+		save(&itchio.Game{ID: id, Title: fmt.Sprintf("Game %d", id), UserID: developerID})
+	}
+	// only in the erased profile's library
+	game(10)
+	save(&models.ProfileGame{ProfileID: erasedProfileID, GameID: 10})
+	save(&models.GameUpload{GameID: 10, UploadID: 1000})
+
+	// owned by the erased profile, and installed with it
+	game(11)
+	save(&itchio.DownloadKey{ID: 500, GameID: 11, OwnerID: erasedProfileID})
+	save(&models.Cave{ID: "cave-1", GameID: 11, SourceProfileID: erasedProfileID})
+	save(&models.Download{ID: "dl-1", CaveID: "cave-1", GameID: 11, FinishedAt: &now})
+
+	// in a collection only the erased profile has
+	game(12)
+	save(&itchio.Collection{ID: 50, Title: "Mine", UserID: erasedProfileID})
+	save(&models.ProfileCollection{ProfileID: erasedProfileID, CollectionID: 50})
+	save(&itchio.CollectionGame{CollectionID: 50, GameID: 12})
+
+	// in a collection both profiles have
+	game(13)
+	save(&itchio.Collection{ID: 51, Title: "Shared", UserID: otherProfileID})
+	save(&models.ProfileCollection{ProfileID: erasedProfileID, CollectionID: 51})
+	save(&models.ProfileCollection{ProfileID: otherProfileID, CollectionID: 51})
+	save(&itchio.CollectionGame{CollectionID: 51, GameID: 13})
+
+	// owned by the other profile, once downloaded with the erased one's
+	// credentials, once with its own
+	game(14)
+	save(&itchio.DownloadKey{ID: 501, GameID: 14, OwnerID: otherProfileID})
+	save(&models.Cave{ID: "cave-2", GameID: 14, SourceProfileID: otherProfileID})
+	dl2 := &models.Download{ID: "dl-2", CaveID: "cave-2", GameID: 14, FinishedAt: &now}
+	must(t, models.MarshalJSON(&butlerd.AccessExplanation{ProfileID: erasedProfileID}, &dl2.AccessExplanation))
+	save(dl2)
+	dl3 := &models.Download{ID: "dl-3", CaveID: "cave-2", GameID: 14, FinishedAt: &now}
+	must(t, models.MarshalJSON(&butlerd.AccessExplanation{ProfileID: otherProfileID}, &dl3.AccessExplanation))
+	save(dl3)
+
+	// in the erased profile's library, but also in the developer's
+	game(15)
+	save(&models.ProfileGame{ProfileID: erasedProfileID, GameID: 15})
+	save(&models.ProfileGame{ProfileID: developerID, GameID: 15})
+
+	for _, id := range []int64{erasedProfileID, otherProfileID} {
+		save(&models.ProfileData{ProfileID: id, Key: "@itch/library-layout", Value: "grid"})
+		models.FetchTargetForProfileGames(id).MustMarkFresh(conn)
+	}
+	models.FetchTargetForCollection(50).MustMarkFresh(conn)
+	models.FetchTargetForGame(10).MustMarkFresh(conn)
+}
+
+// rowsReferencing lists every row, in any table, that still points to a profile
+func rowsReferencing(t *testing.T, conn *sqlite.Conn, profileID int64) []string {
+	profileColumns := map[string]bool{
+		"profile_id":        true,
+		"owner_id":          true,
+		"user_id":           true,
+		"source_profile_id": true,
+	}
+
+	var tables []string
+	must(t, sqlitex.Exec(conn, "SELECT name FROM sqlite_master WHERE type = 'table'", func(stmt *sqlite.Stmt) error {
+		tables = append(tables, stmt.ColumnText(0))
+		return nil
+	}))
+
+	var res []string
+	check := func(table string, cond string, args ...interface{}) {
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %q WHERE %s", table, cond)
+		must(t, sqlitex.Exec(conn, query, func(stmt *sqlite.Stmt) error {
+			if n := stmt.ColumnInt64(0); n > 0 {
+				res = append(res, fmt.Sprintf("%d rows in %s where %s", n, table, cond))
+			}
+			return nil
+		}, args...))
+	}
+
+	for _, table := range tables {
+		var columns []string
+		must(t, sqlitex.Exec(conn, fmt.Sprintf("PRAGMA table_info(%q)", table), func(stmt *sqlite.Stmt) error {
+			columns = append(columns, stmt.ColumnText(1))
+			return nil
+		}))
+		for _, column := range columns {
+			if profileColumns[column] {
+				check(table, fmt.Sprintf("%q = ?", column), profileID)
+			}
+		}
+	}
+	check("profiles", "id = ?", profileID)
+	check("users", "id = ?", profileID)
+	check("fetch_infos", "object_type LIKE 'profile%' AND object_id = ?", fmt.Sprint(profileID))
+	check("downloads", "access_explanation LIKE ?", fmt.Sprintf(`%%"profileId":%d%%`, profileID))
+	return res
+}
+
+func must(t *testing.T, err error) {
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
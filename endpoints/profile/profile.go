@@ -18,6 +18,7 @@ func Register(router *butlerd.Router) {
 	messages.ProfileLoginWithAPIKey.Register(router, LoginWithAPIKey)
 	messages.ProfileUseSavedLogin.Register(router, UseSavedLogin)
 	messages.ProfileForget.Register(router, Forget)
+	messages.ProfileDataErase.Register(router, DataErase)
 	messages.ProfileDataPut.Register(router, DataPut)
 	messages.ProfileDataGet.Register(router, DataGet)
 }
@@ -216,12 +217,15 @@ func Forget(rc *butlerd.RequestContext, params butlerd.ProfileForgetParams) (*bu
 		return nil, errors.New("profileId must be set")
 	}
 
+	var existed bool
 	rc.WithConn(func(conn *sqlite.Conn) {
-		models.MustDelete(conn, &models.Profile{}, builder.Eq{"id": params.ProfileID})
+		cond := builder.Eq{"id": params.ProfileID}
+		existed = models.MustCount(conn, &models.Profile{}, cond) > 0
+		models.MustDelete(conn, &models.Profile{}, cond)
 	})
 
 	res := &butlerd.ProfileForgetResult{
-		Success: true,
+		Success: existed,
 	}
 	return res, nil
 }
@@ -0,0 +1,41 @@
+package system
+
+import (
+	"bytes"
+	"os/exec"
+	"regexp"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+var solidStateRegexp = regexp.MustCompile(`<key>SolidState</key>\s*<(true|false)/>`)
+
+// IsSSD returns true if path lives on a solid-state drive, as
+// reported by diskutil for the device it's mounted from.
+func IsSSD(path string) (bool, error) {
+	var stats syscall.Statfs_t
+	err := syscall.Statfs(path, &stats)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	var device []byte
+	for _, c := range stats.Mntfromname {
+		if c == 0 {
+			break
+		}
+		device = append(device, byte(c))
+	}
+
+	out, err := exec.Command("diskutil", "info", "-plist", string(device)).Output()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	matches := solidStateRegexp.FindSubmatch(out)
+	if matches == nil {
+		return false, errors.Errorf("diskutil didn't say whether (%s) is solid state", device)
+	}
+	return bytes.Equal(matches[1], []byte("true")), nil
+}
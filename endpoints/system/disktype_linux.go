@@ -0,0 +1,42 @@
+package system
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// IsSSD returns true if path lives on a solid-state drive,
+// according to the kernel's "rotational" flag for its block device.
+func IsSSD(path string) (bool, error) {
+	var stats syscall.Stat_t
+	err := syscall.Stat(path, &stats)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	dev := uint64(stats.Dev)
+	major := (dev >> 8) & 0xfff
+	minor := (dev & 0xff) | ((dev >> 12) & 0xfff00)
+	devPath, err := filepath.EvalSymlinks(fmt.Sprintf("/sys/dev/block/%d:%d", major, minor))
+	if err != nil {
+		// not backed by a block device (tmpfs, network filesystems...)
+		return false, errors.WithStack(err)
+	}
+
+	// partitions don't have a queue, their parent device does
+	if _, err := os.Stat(filepath.Join(devPath, "partition")); err == nil {
+		devPath = filepath.Dir(devPath)
+	}
+
+	payload, err := ioutil.ReadFile(filepath.Join(devPath, "queue", "rotational"))
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return strings.TrimSpace(string(payload)) == "0", nil
+}
@@ -0,0 +1,9 @@
+// +build !linux,!darwin,!windows
+
+package system
+
+import "github.com/pkg/errors"
+
+func IsSSD(path string) (bool, error) {
+	return false, errors.New("telling SSDs apart isn't supported on this platform")
+}
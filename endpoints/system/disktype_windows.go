@@ -0,0 +1,71 @@
+package system
+
+import (
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows"
+)
+
+const (
+	ioctlStorageQueryProperty        = 0x2d1400
+	storageDeviceSeekPenaltyProperty = 7
+	propertyStandardQuery            = 0
+)
+
+type storagePropertyQuery struct {
+	PropertyID           uint32
+	QueryType            uint32
+	AdditionalParameters [1]byte
+}
+
+type deviceSeekPenaltyDescriptor struct {
+	Version           uint32
+	Size              uint32
+	IncursSeekPenalty byte
+}
+
+// IsSSD returns true if path lives on a drive that doesn't incur
+// a seek penalty, which is how Windows tells SSDs apart.
+func IsSSD(path string) (bool, error) {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	volumeBuf := make([]uint16, syscall.MAX_PATH+1)
+	err = windows.GetVolumePathName(pathPtr, &volumeBuf[0], uint32(len(volumeBuf)))
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	// `C:\` -> `\\.\C:`
+	volume := strings.TrimSuffix(syscall.UTF16ToString(volumeBuf), `\`)
+	devicePtr, err := syscall.UTF16PtrFromString(`\\.\` + volume)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	handle, err := windows.CreateFile(devicePtr, 0,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE, nil,
+		windows.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	defer windows.CloseHandle(handle)
+
+	query := storagePropertyQuery{
+		PropertyID: storageDeviceSeekPenaltyProperty,
+		QueryType:  propertyStandardQuery,
+	}
+	var desc deviceSeekPenaltyDescriptor
+	var returned uint32
+	err = windows.DeviceIoControl(handle, ioctlStorageQueryProperty,
+		(*byte)(unsafe.Pointer(&query)), uint32(unsafe.Sizeof(query)),
+		(*byte)(unsafe.Pointer(&desc)), uint32(unsafe.Sizeof(desc)),
+		&returned, nil)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return desc.IncursSeekPenalty == 0, nil
+}
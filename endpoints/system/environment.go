@@ -0,0 +1,100 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/itchio/butler/butlerd"
+)
+
+// environment variables worth attaching to bug reports. Anything
+// else is left out: there's no telling what ends up in there.
+var reportedVariables = map[string]bool{
+	"PATH": true, "PATHEXT": true, "SHELL": true, "TERM": true,
+	"LANG": true, "LANGUAGE": true, "LC_ALL": true, "LC_CTYPE": true, "TZ": true,
+	"HOME": true, "USERPROFILE": true, "APPDATA": true, "LOCALAPPDATA": true,
+	"TMPDIR": true, "TEMP": true, "TMP": true,
+	"XDG_CACHE_HOME": true, "XDG_DATA_HOME": true, "XDG_CONFIG_HOME": true,
+	"XDG_RUNTIME_DIR": true, "XDG_SESSION_TYPE": true, "XDG_CURRENT_DESKTOP": true,
+	"DISPLAY": true, "WAYLAND_DISPLAY": true,
+	"HTTP_PROXY": true, "HTTPS_PROXY": true, "NO_PROXY": true,
+	"http_proxy": true, "https_proxy": true, "no_proxy": true,
+}
+
+func EnvironmentInfoHandler(rc *butlerd.RequestContext, params butlerd.SystemEnvironmentInfoParams) (*butlerd.SystemEnvironmentInfoResult, error) {
+	res := &butlerd.SystemEnvironmentInfoResult{
+		Environment: gatherEnvironment(&redactor{}),
+	}
+	return res, nil
+}
+
+// gatherEnvironment describes the environment the daemon runs in,
+// for bug reports. Anything that fails to be looked up is left empty.
+func gatherEnvironment(r *redactor) *butlerd.ButlerEnvironmentInfo {
+	info := &butlerd.ButlerEnvironmentInfo{
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		GoVersion:     runtime.Version(),
+		TempDir:       os.TempDir(),
+		NumCPU:        int64(runtime.NumCPU()),
+		TotalRAMBytes: -1,
+		ProcessID:     int64(os.Getpid()),
+		Variables:     reportVariables(r, os.Environ()),
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		info.HomeDir = home
+	}
+	info.XDGCacheHome = xdgDir("XDG_CACHE_HOME", info.HomeDir, ".cache")
+	info.XDGDataHome = xdgDir("XDG_DATA_HOME", info.HomeDir, ".local/share")
+	info.XDGConfigHome = xdgDir("XDG_CONFIG_HOME", info.HomeDir, ".config")
+
+	if totalRAM, err := TotalRAM(); err == nil {
+		info.TotalRAMBytes = totalRAM
+	}
+	if exe, err := os.Executable(); err == nil {
+		info.ExecutablePath = exe
+	}
+	if wd, err := os.Getwd(); err == nil {
+		info.WorkingDir = wd
+	}
+	return info
+}
+
+// xdgDir returns the value of an XDG base directory variable, falling
+// back to the spec's default on platforms it applies to.
+func xdgDir(variable string, home string, defaultPath string) string {
+	if dir := os.Getenv(variable); dir != "" {
+		return dir
+	}
+	if home == "" || runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		return ""
+	}
+	return filepath.Join(home, filepath.FromSlash(defaultPath))
+}
+
+// reportVariables returns sorted `NAME=value` pairs for the variables
+// of environ that are in reportedVariables, with their values scrubbed
+// like any other text. Proxy URLs with credentials in them are
+// redacted entirely.
+func reportVariables(r *redactor, environ []string) []string {
+	res := []string{}
+	for _, kv := range environ {
+		tokens := strings.SplitN(kv, "=", 2)
+		if len(tokens) != 2 || !reportedVariables[tokens[0]] {
+			continue
+		}
+		name, value := tokens[0], tokens[1]
+		if strings.Contains(value, "@") {
+			value = redacted
+		} else {
+			value = r.String(value)
+		}
+		res = append(res, name+"="+value)
+	}
+	sort.Strings(res)
+	return res
+}
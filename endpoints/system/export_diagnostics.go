@@ -0,0 +1,328 @@
+package system
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/buildinfo"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/hades"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
+)
+
+type diagnosticsVersion struct {
+	Version       string    `json:"version"`
+	VersionString string    `json:"versionString"`
+	Commit        string    `json:"commit"`
+	SchemaVersion int64     `json:"schemaVersion"`
+	OS            string    `json:"os"`
+	Arch          string    `json:"arch"`
+	GoVersion     string    `json:"goVersion"`
+	GeneratedAt   time.Time `json:"generatedAt"`
+}
+
+type diagnosticsProfile struct {
+	ID            int64     `json:"id"`
+	UserID        int64     `json:"userId"`
+	Username      string    `json:"username"`
+	LastConnected time.Time `json:"lastConnected"`
+	Developer     bool      `json:"developer"`
+	PressUser     bool      `json:"pressUser"`
+	APIKey        string    `json:"apiKey"`
+}
+
+type diagnosticsProfileData struct {
+	ProfileID int64  `json:"profileId"`
+	Key       string `json:"dataKey"`
+	Value     string `json:"value"`
+}
+
+type diagnosticsSettings struct {
+	Profiles    []*diagnosticsProfile     `json:"profiles"`
+	ProfileData []*diagnosticsProfileData `json:"profileData"`
+}
+
+type diagnosticsEvent struct {
+	Time       time.Time `json:"time"`
+	Kind       string    `json:"kind"`
+	CaveID     string    `json:"caveId,omitempty"`
+	DownloadID string    `json:"downloadId,omitempty"`
+	GameID     int64     `json:"gameId,omitempty"`
+	GameTitle  string    `json:"gameTitle,omitempty"`
+	Details    string    `json:"details,omitempty"`
+}
+
+type diagnosticsInstallLocation struct {
+	ID        string `json:"id"`
+	Path      string `json:"path"`
+	Exists    bool   `json:"exists"`
+	Writable  bool   `json:"writable"`
+	FreeSize  int64  `json:"freeSize"`
+	TotalSize int64  `json:"totalSize"`
+	NumCaves  int64  `json:"numCaves"`
+	// caves whose install folder is missing
+	MissingFolders []string `json:"missingFolders"`
+	Errors         []string `json:"errors"`
+}
+
+func ExportDiagnosticsHandler(rc *butlerd.RequestContext, params butlerd.SystemExportDiagnosticsParams) (*butlerd.SystemExportDiagnosticsResult, error) {
+	consumer := rc.Consumer
+	r := &redactor{}
+
+	var version *diagnosticsVersion
+	var settings *diagnosticsSettings
+	var timeline []*diagnosticsEvent
+	var locations []*diagnosticsInstallLocation
+	rc.WithConn(func(conn *sqlite.Conn) {
+		version = gatherVersion(conn)
+		settings = gatherSettings(conn, r)
+		timeline = gatherTimeline(conn)
+		locations = gatherInstallLocations(conn)
+	})
+	// after settings, so profiles' API keys are known secrets
+	environment := gatherEnvironment(r)
+
+	var logs strings.Builder
+	for _, entry := range butlerd.RecentLogs.Entries() {
+		fmt.Fprintf(&logs, "%s [%s] %s\n", entry.Time.Format(time.RFC3339), entry.Level, entry.Message)
+	}
+
+	f, err := os.Create(params.Path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	res := &butlerd.SystemExportDiagnosticsResult{
+		Path: params.Path,
+	}
+
+	writeEntry := func(name string, contents string) error {
+		w, err := zw.Create(name)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		_, err = w.Write([]byte(r.String(contents)))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		res.Files = append(res.Files, name)
+		return nil
+	}
+	writeJSON := func(name string, v interface{}) error {
+		payload, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return writeEntry(name, string(payload))
+	}
+
+	err = writeJSON("version.json", version)
+	if err != nil {
+		return nil, err
+	}
+	err = writeJSON("settings.json", settings)
+	if err != nil {
+		return nil, err
+	}
+	err = writeJSON("timeline.json", timeline)
+	if err != nil {
+		return nil, err
+	}
+	err = writeJSON("install-locations.json", locations)
+	if err != nil {
+		return nil, err
+	}
+	err = writeJSON("environment.json", environment)
+	if err != nil {
+		return nil, err
+	}
+	err = writeEntry("logs.txt", logs.String())
+	if err != nil {
+		return nil, err
+	}
+
+	err = zw.Close()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	consumer.Statf("Wrote diagnostics to (%s): %s", params.Path, strings.Join(res.Files, ", "))
+	return res, nil
+}
+
+func gatherVersion(conn *sqlite.Conn) *diagnosticsVersion {
+	return &diagnosticsVersion{
+		Version:       buildinfo.Version,
+		VersionString: buildinfo.VersionString,
+		Commit:        buildinfo.Commit,
+		SchemaVersion: models.GetSchemaVersion(conn),
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		GoVersion:     runtime.Version(),
+		GeneratedAt:   time.Now().UTC(),
+	}
+}
+
+func gatherSettings(conn *sqlite.Conn, r *redactor) *diagnosticsSettings {
+	settings := &diagnosticsSettings{
+		Profiles:    []*diagnosticsProfile{},
+		ProfileData: []*diagnosticsProfileData{},
+	}
+
+	var profiles []*models.Profile
+	models.MustSelect(conn, &profiles, builder.NewCond(), hades.Search{}.OrderBy("last_connected DESC"))
+	models.MustPreload(conn, profiles, hades.Assoc("User"))
+	for _, p := range profiles {
+		r.addSecret(p.APIKey)
+
+		dp := &diagnosticsProfile{
+			ID:            p.ID,
+			UserID:        p.UserID,
+			LastConnected: p.LastConnected,
+			Developer:     p.Developer,
+			PressUser:     p.PressUser,
+			APIKey:        redacted,
+		}
+		if p.User != nil {
+			dp.Username = p.User.Username
+		}
+		settings.Profiles = append(settings.Profiles, dp)
+	}
+
+	var data []*models.ProfileData
+	models.MustSelect(conn, &data, builder.NewCond(), hades.Search{})
+	for _, d := range data {
+		settings.ProfileData = append(settings.ProfileData, &diagnosticsProfileData{
+			ProfileID: d.ProfileID,
+			Key:       d.Key,
+			Value:     d.Value,
+		})
+	}
+
+	return settings
+}
+
+func gatherTimeline(conn *sqlite.Conn) []*diagnosticsEvent {
+	timeline := []*diagnosticsEvent{}
+
+	var caves []*models.Cave
+	models.MustSelect(conn, &caves, builder.NewCond(), hades.Search{})
+	models.PreloadCaves(conn, caves)
+	for _, cave := range caves {
+		event := func(t *time.Time, kind string, details string) {
+			if t == nil {
+				return
+			}
+			e := &diagnosticsEvent{
+				Time:    *t,
+				Kind:    kind,
+				CaveID:  cave.ID,
+				GameID:  cave.GameID,
+				Details: details,
+			}
+			if cave.Game != nil {
+				e.GameTitle = cave.Game.Title
+			}
+			timeline = append(timeline, e)
+		}
+		event(cave.InstalledAt, "cave-installed", fmt.Sprintf("upload %d, build %d", cave.UploadID, cave.BuildID))
+		event(cave.LastTouchedAt, "cave-last-touched", fmt.Sprintf("%d seconds run", cave.SecondsRun))
+	}
+
+	var downloads []*models.Download
+	models.MustSelect(conn, &downloads, builder.NewCond(), hades.Search{})
+	models.PreloadDownloads(conn, downloads)
+	for _, dl := range downloads {
+		event := func(t *time.Time, kind string, details string) {
+			if t == nil {
+				return
+			}
+			e := &diagnosticsEvent{
+				Time:       *t,
+				Kind:       kind,
+				CaveID:     dl.CaveID,
+				DownloadID: dl.ID,
+				GameID:     dl.GameID,
+				Details:    details,
+			}
+			if dl.Game != nil {
+				e.GameTitle = dl.Game.Title
+			}
+			timeline = append(timeline, e)
+		}
+		event(dl.StartedAt, "download-started", fmt.Sprintf("reason %s", dl.Reason))
+		if dl.Error != nil {
+			event(dl.FinishedAt, "download-errored", *dl.Error)
+		} else {
+			event(dl.FinishedAt, "download-finished", "")
+		}
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Time.Before(timeline[j].Time)
+	})
+	return timeline
+}
+
+func gatherInstallLocations(conn *sqlite.Conn) []*diagnosticsInstallLocation {
+	locations := []*diagnosticsInstallLocation{}
+
+	var ils []*models.InstallLocation
+	models.MustSelect(conn, &ils, builder.NewCond(), hades.Search{})
+	for _, il := range ils {
+		dil := &diagnosticsInstallLocation{
+			ID:             il.ID,
+			Path:           il.Path,
+			MissingFolders: []string{},
+			Errors:         []string{},
+		}
+		locations = append(locations, dil)
+
+		caves := il.GetCaves(conn)
+		dil.NumCaves = int64(len(caves))
+
+		if _, err := os.Stat(il.Path); err != nil {
+			dil.Errors = append(dil.Errors, err.Error())
+			continue
+		}
+		dil.Exists = true
+
+		if stats, err := StatFS(il.Path); err != nil {
+			dil.Errors = append(dil.Errors, err.Error())
+		} else {
+			dil.FreeSize = stats.FreeSize
+			dil.TotalSize = stats.TotalSize
+		}
+
+		if tf, err := ioutil.TempFile(il.Path, ".butler-diagnostics-"); err != nil {
+			dil.Errors = append(dil.Errors, err.Error())
+		} else {
+			dil.Writable = true
+			tf.Close()
+			os.Remove(tf.Name())
+		}
+
+		for _, cave := range caves {
+			if cave.CustomInstallFolder != "" {
+				continue
+			}
+			folder := il.GetInstallFolder(cave.InstallFolderName)
+			if _, err := os.Stat(folder); err != nil && os.IsNotExist(err) {
+				dil.MissingFolders = append(dil.MissingFolders, folder)
+			}
+		}
+	}
+	return locations
+}
@@ -0,0 +1,21 @@
+package system
+
+import (
+	"github.com/itchio/butler/butlerd"
+)
+
+func LogDumpHandler(rc *butlerd.RequestContext, params butlerd.SystemLogDumpParams) (*butlerd.SystemLogDumpResult, error) {
+	res := &butlerd.SystemLogDumpResult{
+		Entries: []*butlerd.LogEntry{},
+	}
+	for _, entry := range butlerd.RecentLogs.Last(int(params.Lines)) {
+		res.Entries = append(res.Entries, &butlerd.LogEntry{
+			Timestamp: entry.Time,
+			Level:     entry.Level,
+			Module:    entry.Module,
+			Message:   entry.Message,
+			Fields:    entry.Fields,
+		})
+	}
+	return res, nil
+}
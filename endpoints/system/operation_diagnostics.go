@@ -0,0 +1,30 @@
+package system
+
+import (
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+	"github.com/pkg/errors"
+)
+
+func GetOperationDiagnosticsHandler(rc *butlerd.RequestContext, params butlerd.SystemGetOperationDiagnosticsParams) (*butlerd.SystemGetOperationDiagnosticsResult, error) {
+	conn := rc.GetConn()
+	download := models.DownloadByID(conn, params.DownloadID)
+	rc.PutConn(conn)
+	if download == nil {
+		return nil, errors.Errorf("No such download (%s)", params.DownloadID)
+	}
+
+	res := &butlerd.SystemGetOperationDiagnosticsResult{}
+	if download.Error == nil || download.StagingFolder == "" {
+		// never failed, or failed before it was staged
+		return res, nil
+	}
+
+	diag, err := operate.ReadDiagnostics(download.StagingFolder)
+	if err != nil {
+		return nil, err
+	}
+	res.Diagnostics = diag
+	return res, nil
+}
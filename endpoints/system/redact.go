@@ -0,0 +1,71 @@
+package system
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[redacted]"
+
+var (
+	urlRegexp = regexp.MustCompile(`https?://[^\s"'<>\\]+`)
+
+	// things like `api_key=abc`, `"token": "abc"` or `Authorization: abc`
+	secretParamRegexp = regexp.MustCompile(`(?i)\b(api_?key|key|token|password|secret|jwt|authorization|signature)(["']?\s*[:=]\s*["']?)([^\s"'&,)]+)`)
+
+	downloadHostSuffixes = []string{"itch.zone", "itch.ovh", "amazonaws.com", "googleapis.com"}
+)
+
+// A redactor removes API keys and credentials from text
+type redactor struct {
+	secrets []string
+}
+
+// addSecret makes sure s never appears in redacted text
+func (r *redactor) addSecret(s string) {
+	// don't redact every single "a" if we somehow get a short secret
+	if len(s) < 8 {
+		return
+	}
+	r.secrets = append(r.secrets, s)
+}
+
+func (r *redactor) String(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.Replace(s, secret, redacted, -1)
+	}
+	s = urlRegexp.ReplaceAllStringFunc(s, redactURL)
+	s = secretParamRegexp.ReplaceAllString(s, "${1}${2}"+redacted)
+	return s
+}
+
+// redactURL drops the query string of any URL. For download URLs,
+// where credentials are often part of the path, only the host is kept.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return redacted
+	}
+
+	isDownload := strings.Contains(u.Path, "/download")
+	host := u.Hostname()
+	for _, suffix := range downloadHostSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			isDownload = true
+		}
+	}
+
+	res := u.Scheme + "://" + u.Host
+	if isDownload {
+		if u.Path != "" && u.Path != "/" {
+			res += "/" + redacted
+		}
+	} else {
+		res += u.EscapedPath()
+		if u.RawQuery != "" {
+			res += "?" + redacted
+		}
+	}
+	return res
+}
@@ -0,0 +1,30 @@
+package system
+
+import (
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/mansion/ratelimit"
+)
+
+func StatsHandler(rc *butlerd.RequestContext, params butlerd.SystemStatsParams) (*butlerd.SystemStatsResult, error) {
+	rl := ratelimit.Default().Stats()
+
+	res := &butlerd.SystemStatsResult{
+		RateLimiter: &butlerd.RateLimiterStats{
+			Requests:            rl.Requests,
+			RateLimited:         rl.RateLimited,
+			Retries:             rl.Retries,
+			Failures:            rl.Failures,
+			WaitingInteractive:  rl.WaitingInteractive,
+			WaitingBackground:   rl.WaitingBackground,
+			Limit:               rl.Limit,
+			Remaining:           rl.Remaining,
+			ResetAt:             rl.ResetAt,
+			PausedUntil:         rl.PausedUntil,
+			BackgroundSpacingMs: int64(rl.BackgroundSpacing / time.Millisecond),
+		},
+		Connections: butlerd.Connections.Stats(),
+	}
+	return res, nil
+}
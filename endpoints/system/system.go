@@ -3,12 +3,29 @@ package system
 import (
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/database/models"
 	"github.com/itchio/headway/united"
 	"github.com/pkg/errors"
 )
 
+func init() {
+	models.DetectSSD = IsSSD
+	models.MeasureFreeBytes = func(path string) (int64, error) {
+		stats, err := StatFS(path)
+		if err != nil {
+			return 0, err
+		}
+		return stats.FreeSize, nil
+	}
+}
+
 func Register(router *butlerd.Router) {
 	messages.SystemStatFS.Register(router, StatFSHandler)
+	messages.SystemStats.Register(router, StatsHandler)
+	messages.SystemExportDiagnostics.Register(router, ExportDiagnosticsHandler)
+	messages.SystemGetOperationDiagnostics.Register(router, GetOperationDiagnosticsHandler)
+	messages.SystemLogDump.Register(router, LogDumpHandler)
+	messages.SystemEnvironmentInfo.Register(router, EnvironmentInfoHandler)
 }
 
 func StatFSHandler(rc *butlerd.RequestContext, params butlerd.SystemStatFSParams) (*butlerd.SystemStatFSResult, error) {
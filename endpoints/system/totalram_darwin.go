@@ -0,0 +1,15 @@
+package system
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// TotalRAM returns how much physical memory is installed, in bytes
+func TotalRAM() (int64, error) {
+	memsize, err := unix.SysctlUint64("hw.memsize")
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return int64(memsize), nil
+}
@@ -0,0 +1,16 @@
+package system
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// TotalRAM returns how much physical memory is installed, in bytes
+func TotalRAM() (int64, error) {
+	var info unix.Sysinfo_t
+	err := unix.Sysinfo(&info)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return int64(info.Totalram) * int64(info.Unit), nil
+}
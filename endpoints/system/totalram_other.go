@@ -0,0 +1,9 @@
+// +build !linux,!darwin,!windows
+
+package system
+
+import "github.com/pkg/errors"
+
+func TotalRAM() (int64, error) {
+	return 0, errors.New("measuring installed memory isn't supported on this platform")
+}
@@ -0,0 +1,102 @@
+package tasks
+
+import (
+	"os"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/hades"
+	"github.com/itchio/headway/state"
+	"xorm.io/builder"
+)
+
+// Checking install folders on every startup would be a lot
+// of disk activity for large libraries, so only do it once a day.
+const ghostCaveCheckTTL = 24 * time.Hour
+
+func fetchTargetForGhostCaveCheck(caveID string) models.FetchTarget {
+	return models.FetchTarget{
+		StringID: caveID,
+		Type:     "ghost_cave_check",
+		TTL:      ghostCaveCheckTTL,
+	}
+}
+
+// DetectGhostCaves returns all caves whose install folder doesn't
+// exist on disk. If throttle is true, caves that were checked less
+// than 24 hours ago are skipped, and the others are marked as checked.
+// Unthrottled checks leave those marks alone.
+func DetectGhostCaves(conn *sqlite.Conn, consumer *state.Consumer, throttle bool) []*butlerd.GhostCave {
+	var caves []*models.Cave
+	models.MustSelect(conn, &caves, builder.NewCond(), hades.Search{})
+	models.PreloadCaves(conn, caves)
+
+	var ghosts []*butlerd.GhostCave
+	var checked []models.FetchTarget
+	for _, cave := range caves {
+		ft := fetchTargetForGhostCaveCheck(cave.ID)
+		if throttle && !ft.MustIsStale(conn) {
+			continue
+		}
+		checked = append(checked, ft)
+
+		if cave.CustomInstallFolder == "" && cave.InstallLocation == nil {
+			consumer.Warnf("Cave (%s) has no install location, skipping", cave.ID)
+			continue
+		}
+
+		installFolder := cave.GetInstallFolder(conn)
+		_, err := os.Stat(installFolder)
+		if err == nil || !os.IsNotExist(err) {
+			continue
+		}
+
+		ghosts = append(ghosts, &butlerd.GhostCave{
+			CaveID:        cave.ID,
+			Game:          cave.Game,
+			InstallFolder: installFolder,
+			SuggestedActions: []butlerd.GhostCaveAction{
+				butlerd.GhostCaveActionReinstall,
+				butlerd.GhostCaveActionDeleteCave,
+				butlerd.GhostCaveActionUpdatePath,
+			},
+		})
+	}
+	if throttle && len(checked) > 0 {
+		models.MustMarkAllFresh(conn, checked)
+	}
+
+	consumer.Infof("Checked %d caves, found %d ghosts", len(checked), len(ghosts))
+	for _, ghost := range ghosts {
+		consumer.Warnf("Ghost cave (%s): install folder (%s) is missing", ghost.CaveID, ghost.InstallFolder)
+	}
+	return ghosts
+}
+
+// GhostCaveDetect checks caves for missing install folders, at most once
+// a day per cave, and notifies about ghosts over notifyRC (typically the
+// Meta.Flow conversation).
+func GhostCaveDetect(notifyRC *butlerd.RequestContext) butlerd.BackgroundTask {
+	return butlerd.BackgroundTask{
+		Desc: "detect ghost caves",
+		Do: func(rc *butlerd.RequestContext) error {
+			var ghosts []*butlerd.GhostCave
+			rc.WithConn(func(conn *sqlite.Conn) {
+				ghosts = DetectGhostCaves(conn, rc.Consumer, true)
+			})
+
+			for _, ghost := range ghosts {
+				err := messages.GhostCaveDetected.Notify(notifyRC, butlerd.GhostCaveDetectedNotification{
+					Ghost: ghost,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
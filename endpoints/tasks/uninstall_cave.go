@@ -0,0 +1,22 @@
+package tasks
+
+import (
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+)
+
+// UninstallCave performs a graceful uninstall of a cave in the background.
+// Uninstall progress is reported over notifyRC's connection, which should
+// outlive the task (typically the Meta.Flow conversation).
+func UninstallCave(notifyRC *butlerd.RequestContext, caveID string) butlerd.BackgroundTask {
+	return butlerd.BackgroundTask{
+		Desc: "uninstall cave " + caveID,
+		Do: func(rc *butlerd.RequestContext) error {
+			rc.Conn = notifyRC.Conn
+			_, err := operate.UninstallPerform(rc.Ctx, rc, butlerd.UninstallPerformParams{
+				CaveID: caveID,
+			})
+			return err
+		},
+	}
+}
@@ -0,0 +1,293 @@
+package update
+
+import (
+	"sync"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/google/uuid"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/horror"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/endpoints/fetch"
+	"github.com/itchio/butler/endpoints/install"
+	"github.com/itchio/butler/mansion/ratelimit"
+	"github.com/pkg/errors"
+)
+
+// MaxConcurrentInstalls is the most caves CaveUpdateBatch
+// will update at the same time, whatever clients ask for.
+const MaxConcurrentInstalls = 4
+
+type updateBatch struct {
+	id string
+
+	// protects items
+	lock  sync.Mutex
+	items []*butlerd.CaveBatchItem
+	done  bool
+}
+
+var batches = struct {
+	sync.Mutex
+	byID map[string]*updateBatch
+}{
+	byID: make(map[string]*updateBatch),
+}
+
+// finishedBatchRetention is how long a finished batch can still be
+// looked up with CaveBatchStatus, before it's forgotten.
+var finishedBatchRetention = 10 * time.Minute
+
+// update calls f with the batch locked, f may modify item
+func (b *updateBatch) update(item *butlerd.CaveBatchItem, f func(item *butlerd.CaveBatchItem)) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	f(item)
+}
+
+// status returns a copy of the batch's state, safe to send over the wire
+func (b *updateBatch) status() *butlerd.CaveBatchStatusResult {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	res := &butlerd.CaveBatchStatusResult{
+		Done: b.done,
+	}
+	var total float64
+	for _, item := range b.items {
+		itemCopy := *item
+		res.Caves = append(res.Caves, &itemCopy)
+
+		switch item.State {
+		case butlerd.CaveBatchItemStateQueued:
+			// counts for nothing
+		case butlerd.CaveBatchItemStateChecking, butlerd.CaveBatchItemStateInstalling:
+			total += item.Progress
+		default:
+			total += 1
+		}
+	}
+	if len(b.items) > 0 {
+		res.Progress = total / float64(len(b.items))
+	}
+	return res
+}
+
+func CaveUpdateBatch(rc *butlerd.RequestContext, params butlerd.CaveUpdateBatchParams) (*butlerd.CaveUpdateBatchResult, error) {
+	maxConcurrent := int(params.MaxConcurrent)
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+	if maxConcurrent > MaxConcurrentInstalls {
+		maxConcurrent = MaxConcurrentInstalls
+	}
+
+	batchID, err := uuid.NewRandom()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	b := &updateBatch{
+		id: batchID.String(),
+	}
+	conn := rc.GetConn()
+	for _, caveID := range params.CaveIDs {
+		if models.CaveByID(conn, caveID) == nil {
+			rc.PutConn(conn)
+			return nil, errors.Errorf("No such cave (%s)", caveID)
+		}
+		b.items = append(b.items, &butlerd.CaveBatchItem{
+			CaveID: caveID,
+			State:  butlerd.CaveBatchItemStateQueued,
+		})
+	}
+	rc.PutConn(conn)
+
+	batches.Lock()
+	batches.byID[b.id] = b
+	batches.Unlock()
+
+	notifyRC := rc
+	rc.QueueBackgroundTask(butlerd.BackgroundTask{
+		Desc: "update batch " + b.id,
+		Do: func(rc *butlerd.RequestContext) error {
+			rc.Conn = notifyRC.Conn
+			rc.Ctx = ratelimit.WithClass(rc.Ctx, ratelimit.ClassBackground)
+			return b.run(rc, maxConcurrent)
+		},
+	})
+
+	rc.Consumer.Infof("Queued update batch %s (%d caves, %d at a time)", b.id, len(b.items), maxConcurrent)
+	return &butlerd.CaveUpdateBatchResult{
+		BatchID: b.id,
+	}, nil
+}
+
+func CaveBatchStatus(rc *butlerd.RequestContext, params butlerd.CaveBatchStatusParams) (*butlerd.CaveBatchStatusResult, error) {
+	batches.Lock()
+	b := batches.byID[params.BatchID]
+	batches.Unlock()
+
+	if b == nil {
+		if status := install.UninstallBatchStatus(params.BatchID); status != nil {
+			return status, nil
+		}
+		return nil, errors.Errorf("No such batch (%s)", params.BatchID)
+	}
+	return b.status(), nil
+}
+
+func (b *updateBatch) run(rc *butlerd.RequestContext, maxConcurrent int) error {
+	items := make(chan *butlerd.CaveBatchItem)
+	var wg sync.WaitGroup
+	for i := 0; i < maxConcurrent; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range items {
+				b.updateOne(rc, item)
+			}
+		}()
+	}
+
+	for _, item := range b.items {
+		items <- item
+	}
+	close(items)
+	wg.Wait()
+
+	b.lock.Lock()
+	b.done = true
+	b.lock.Unlock()
+	time.AfterFunc(finishedBatchRetention, func() {
+		batches.Lock()
+		delete(batches.byID, b.id)
+		batches.Unlock()
+	})
+
+	status := b.status()
+	rc.Consumer.Statf("Update batch %s done", b.id)
+	return messages.BatchUpdateComplete.Notify(rc, butlerd.BatchUpdateCompleteNotification{
+		BatchID: b.id,
+		Caves:   status.Caves,
+	})
+}
+
+func (b *updateBatch) updateOne(parentRC *butlerd.RequestContext, item *butlerd.CaveBatchItem) {
+	// each cave gets its own consumer, so progress can be told apart
+	consumer := *parentRC.Consumer
+	consumer.OnProgress = func(alpha float64) {
+		b.update(item, func(item *butlerd.CaveBatchItem) {
+			item.Progress = alpha
+		})
+	}
+	rcCopy := *parentRC
+	rc := &rcCopy
+	rc.Consumer = &consumer
+
+	setState := func(state butlerd.CaveBatchItemState) {
+		b.update(item, func(item *butlerd.CaveBatchItem) {
+			item.State = state
+			item.Progress = 0
+		})
+	}
+
+	err := func() (retErr error) {
+		defer horror.RecoverInto(&retErr)
+
+		setState(butlerd.CaveBatchItemStateChecking)
+		select {
+		case <-rc.Ctx.Done():
+			return errors.WithStack(butlerd.CodeOperationCancelled)
+		default:
+			// keep going!
+		}
+
+		var cave *models.Cave
+		rc.WithConn(func(conn *sqlite.Conn) {
+			cave = models.CaveByID(conn, item.CaveID)
+			if cave != nil {
+				models.PreloadCaves(conn, cave)
+			}
+		})
+		if cave == nil {
+			return errors.Errorf("No such cave (%s)", item.CaveID)
+		}
+
+		update, err := checkUpdateCave(checkUpdateCaveParams{
+			rc:           rc,
+			ignoreSnooze: true,
+		}, rc.Consumer, cave)
+		if err != nil {
+			return err
+		}
+
+		if update == nil || len(update.Choices) == 0 {
+			setState(butlerd.CaveBatchItemStateUpToDate)
+			return nil
+		}
+
+		choice := update.Choices[0]
+		if update.UploadReplaced && !choice.Confirmed {
+			b.update(item, func(item *butlerd.CaveBatchItem) {
+				item.State = butlerd.CaveBatchItemStateSkipped
+				item.Error = "upload was replaced, successor must be confirmed first"
+			})
+			return nil
+		}
+
+		policy := fetch.CaveAutoUpdatePolicy(cave)
+		allow, reason, err := allowAutoUpdate(rc.Consumer, policy, askUser(rc, cave, choice))
+		if err != nil {
+			return err
+		}
+		if !allow {
+			rc.Consumer.Infof("Not updating cave (%s): %s", cave.ID, reason)
+			b.update(item, func(item *butlerd.CaveBatchItem) {
+				item.State = butlerd.CaveBatchItemStateSkipped
+				item.Error = reason
+			})
+			return nil
+		}
+
+		setState(butlerd.CaveBatchItemStateInstalling)
+		queueRes, err := install.InstallQueue(rc, butlerd.InstallQueueParams{
+			CaveID: cave.ID,
+			Upload: choice.Upload,
+			Build:  choice.Build,
+			Reason: butlerd.DownloadReasonUpdate,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = install.InstallPerform(rc, butlerd.InstallPerformParams{
+			ID:            queueRes.ID,
+			StagingFolder: queueRes.StagingFolder,
+		})
+		if err != nil {
+			return err
+		}
+
+		b.update(item, func(item *butlerd.CaveBatchItem) {
+			item.State = butlerd.CaveBatchItemStateUpdated
+			item.Progress = 1
+			item.Upload = choice.Upload
+			item.Build = choice.Build
+		})
+		return nil
+	}()
+
+	if err != nil {
+		rc.Consumer.Warnf("Could not update cave (%s): %+v", item.CaveID, err)
+		b.update(item, func(item *butlerd.CaveBatchItem) {
+			item.State = butlerd.CaveBatchItemStateFailed
+			item.Error = err.Error()
+			if be, ok := butlerd.AsButlerdError(err); ok {
+				item.ErrorCode = be.RpcErrorCode()
+				item.Error = be.RpcErrorMessage()
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package update
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/headway/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FinishedBatchesAreForgotten(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(d time.Duration) { finishedBatchRetention = d }(finishedBatchRetention)
+	finishedBatchRetention = 50 * time.Millisecond
+
+	rc := &butlerd.RequestContext{
+		Ctx:      context.Background(),
+		Consumer: &state.Consumer{},
+	}
+	completed := make(chan string, 1)
+	rc.InterceptNotification(messages.BatchUpdateComplete.Method(), func(method string, params interface{}) error {
+		completed <- params.(butlerd.BatchUpdateCompleteNotification).BatchID
+		return nil
+	})
+
+	b := &updateBatch{id: "empty-batch"}
+	batches.Lock()
+	batches.byID[b.id] = b
+	batches.Unlock()
+
+	assert.NoError(b.run(rc, 1))
+	assert.EqualValues(b.id, <-completed)
+
+	status, err := CaveBatchStatus(rc, butlerd.CaveBatchStatusParams{BatchID: b.id})
+	assert.NoError(err)
+	assert.True(status.Done, "finished batches can still be looked up for a while")
+
+	time.Sleep(200 * time.Millisecond)
+	_, err = CaveBatchStatus(rc, butlerd.CaveBatchStatusParams{BatchID: b.id})
+	assert.Error(err, "finished batches are eventually forgotten")
+}
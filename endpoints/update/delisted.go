@@ -0,0 +1,98 @@
+package update
+
+import (
+	"net/http"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/endpoints/fetch"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/hades"
+	"github.com/itchio/headway/state"
+	"xorm.io/builder"
+)
+
+// Games get taken down temporarily (DMCA claims, reviews, developers
+// fixing a page...), so a single "not found" doesn't mean much. A cave
+// is only flagged delisted once its game has been gone for at least
+// delistedMinChecks update checks in a row, spanning delistedMinAge.
+const (
+	delistedMinChecks = 3
+	delistedMinAge    = 7 * 24 * time.Hour
+)
+
+// isGameGone returns true if err is the API telling us
+// the game doesn't exist (anymore).
+func isGameGone(err error) bool {
+	if apiErr, ok := itchio.AsAPIError(err); ok {
+		return apiErr.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// recordGameGone is called when an update check finds the cave's
+// game gone. It returns true if the cave just became delisted.
+func recordGameGone(cave *models.Cave, now time.Time) bool {
+	if cave.GameGoneSince == nil {
+		cave.GameGoneSince = &now
+	}
+	cave.GameGoneChecks++
+
+	if cave.GameDelisted {
+		return false
+	}
+	if cave.GameGoneChecks >= delistedMinChecks && now.Sub(*cave.GameGoneSince) >= delistedMinAge {
+		cave.GameDelisted = true
+		return true
+	}
+	return false
+}
+
+// recordGameFound is called when an update check finds the cave's
+// game alive. It returns true if the cave was delisted until now.
+func recordGameFound(cave *models.Cave) bool {
+	wasDelisted := cave.GameDelisted
+	cave.GameGoneChecks = 0
+	cave.GameGoneSince = nil
+	cave.GameDelisted = false
+	return wasDelisted
+}
+
+// onGameGone records that an update check found the cave's game gone,
+// and lets the client know if that makes the cave delisted.
+func onGameGone(rc *butlerd.RequestContext, consumer *state.Consumer, cave *models.Cave) {
+	delisted := recordGameGone(cave, time.Now().UTC())
+	rc.WithConn(func(conn *sqlite.Conn) {
+		saveGameGoneState(conn, cave)
+	})
+	if !delisted {
+		consumer.Infof("Game (%d) not found, %d checks in a row since %s", cave.GameID, cave.GameGoneChecks, cave.GameGoneSince)
+		return
+	}
+
+	consumer.Statf("Game (%d) has been gone since %s, flagging cave as delisted", cave.GameID, cave.GameGoneSince)
+	var formatted *butlerd.Cave
+	rc.WithConn(func(conn *sqlite.Conn) {
+		formatted = fetch.FormatCave(conn, cave)
+	})
+	err := messages.GameDelisted.Notify(rc, butlerd.GameDelistedNotification{
+		Cave: formatted,
+	})
+	if err != nil {
+		consumer.Warnf("Could not send GameDelisted notification: %s", err.Error())
+	}
+}
+
+func saveGameGoneState(conn *sqlite.Conn, cave *models.Cave) {
+	models.MustUpdate(conn, &models.Cave{},
+		hades.Where(builder.Eq{"id": cave.ID}),
+		builder.Eq{
+			"game_gone_checks": cave.GameGoneChecks,
+			"game_gone_since":  hades.DBValue(cave.GameGoneSince),
+			"game_delisted":    cave.GameDelisted,
+		},
+	)
+}
@@ -0,0 +1,106 @@
+package update
+
+import (
+	"testing"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/database"
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var day = 24 * time.Hour
+
+func Test_IsGameGone(t *testing.T) {
+	assert := assert.New(t)
+
+	notFound := &itchio.APIError{StatusCode: 404, Messages: []string{"invalid game"}}
+	assert.True(isGameGone(notFound))
+	assert.True(isGameGone(errors.WithStack(notFound)), "errors may be wrapped")
+	assert.False(isGameGone(&itchio.APIError{StatusCode: 500}))
+	assert.False(isGameGone(errors.New("connection reset")))
+}
+
+func Test_TemporaryTakedown(t *testing.T) {
+	assert := assert.New(t)
+
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	cave := &models.Cave{}
+
+	// checked a few times over a couple days
+	for i := 0; i < 5; i++ {
+		assert.False(recordGameGone(cave, start.Add(time.Duration(i)*12*time.Hour)))
+	}
+	assert.EqualValues(5, cave.GameGoneChecks)
+	assert.False(cave.GameDelisted, "not gone for long enough")
+
+	assert.False(recordGameFound(cave))
+	assert.EqualValues(0, cave.GameGoneChecks)
+	assert.Nil(cave.GameGoneSince)
+
+	// gone again much later: starts over
+	assert.False(recordGameGone(cave, start.Add(10*day)))
+	assert.False(recordGameGone(cave, start.Add(18*day)))
+	assert.False(cave.GameDelisted, "not enough checks in a row")
+	assert.EqualValues(start.Add(10*day), *cave.GameGoneSince)
+}
+
+func Test_PermanentDeletion(t *testing.T) {
+	assert := assert.New(t)
+
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	cave := &models.Cave{}
+
+	assert.False(recordGameGone(cave, start))
+	assert.False(recordGameGone(cave, start.Add(1*day)))
+	assert.False(recordGameGone(cave, start.Add(2*day)), "enough checks, but not gone for long enough")
+	assert.True(recordGameGone(cave, start.Add(7*day)), "delisted after a week")
+	assert.True(cave.GameDelisted)
+	assert.False(recordGameGone(cave, start.Add(8*day)), "only reported once")
+	assert.True(cave.GameDelisted)
+
+	assert.True(recordGameFound(cave), "game came back")
+	assert.False(cave.GameDelisted)
+	assert.EqualValues(0, cave.GameGoneChecks)
+}
+
+func Test_SaveGameGoneState(t *testing.T) {
+	assert := assert.New(t)
+
+	conn, err := sqlite.OpenConn("file:delisted_test?mode=memory", 0)
+	must(t, err)
+	defer conn.Close()
+	must(t, database.Prepare(&state.Consumer{}, conn, true))
+
+	cave := &models.Cave{ID: "zombie", GameID: 1}
+	must(t, models.Save(conn, cave))
+
+	since := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	cave.GameGoneChecks = 3
+	cave.GameGoneSince = &since
+	cave.GameDelisted = true
+	saveGameGoneState(conn, cave)
+
+	saved := models.CaveByID(conn, "zombie")
+	assert.EqualValues(3, saved.GameGoneChecks)
+	if assert.NotNil(saved.GameGoneSince) {
+		assert.True(since.Equal(*saved.GameGoneSince))
+	}
+	assert.True(saved.GameDelisted)
+
+	recordGameFound(cave)
+	saveGameGoneState(conn, cave)
+	saved = models.CaveByID(conn, "zombie")
+	assert.Nil(saved.GameGoneSince)
+	assert.False(saved.GameDelisted)
+}
+
+func must(t *testing.T, err error) {
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
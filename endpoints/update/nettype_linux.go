@@ -0,0 +1,82 @@
+package update
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+// getNetworkType looks up the interface of the default IPv4 route
+// over netlink, then asks sysfs what kind of device it is.
+func getNetworkType() (networkType, error) {
+	iface, err := defaultRouteInterface()
+	if err != nil {
+		return networkTypeUnknown, err
+	}
+
+	devPath := filepath.Join("/sys/class/net", iface)
+	if exists(filepath.Join(devPath, "wireless")) || exists(filepath.Join(devPath, "phy80211")) {
+		return networkTypeWifi, nil
+	}
+
+	uevent, err := ioutil.ReadFile(filepath.Join(devPath, "uevent"))
+	if err == nil && strings.Contains(string(uevent), "DEVTYPE=wwan") {
+		return networkTypeCellular, nil
+	}
+
+	// virtual interfaces (VPNs, bridges...) don't tell us
+	// anything about the connection underneath
+	if !exists(filepath.Join(devPath, "device")) {
+		return networkTypeUnknown, nil
+	}
+	return networkTypeWired, nil
+}
+
+func defaultRouteInterface() (string, error) {
+	tab, err := syscall.NetlinkRIB(syscall.RTM_GETROUTE, syscall.AF_INET)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	msgs, err := syscall.ParseNetlinkMessage(tab)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	for _, m := range msgs {
+		if m.Header.Type != syscall.RTM_NEWROUTE {
+			continue
+		}
+		rtm := (*syscall.RtMsg)(unsafe.Pointer(&m.Data[0]))
+		if rtm.Dst_len != 0 || rtm.Table != syscall.RT_TABLE_MAIN {
+			continue
+		}
+
+		attrs, err := syscall.ParseNetlinkRouteAttr(&m)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		for _, attr := range attrs {
+			if attr.Attr.Type != syscall.RTA_OIF || len(attr.Value) < 4 {
+				continue
+			}
+			index := *(*uint32)(unsafe.Pointer(&attr.Value[0]))
+			iface, err := net.InterfaceByIndex(int(index))
+			if err != nil {
+				return "", errors.WithStack(err)
+			}
+			return iface.Name, nil
+		}
+	}
+	return "", errors.New("no default route")
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,9 @@
+// +build !linux
+
+package update
+
+import "github.com/pkg/errors"
+
+func getNetworkType() (networkType, error) {
+	return networkTypeUnknown, errors.New("telling network types apart isn't supported on this platform")
+}
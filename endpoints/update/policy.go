@@ -0,0 +1,72 @@
+package update
+
+import (
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/headway/state"
+)
+
+type networkType string
+
+const (
+	networkTypeUnknown  networkType = "unknown"
+	networkTypeWifi     networkType = "wifi"
+	networkTypeWired    networkType = "wired"
+	networkTypeCellular networkType = "cellular"
+)
+
+// detectNetworkType is swapped out by tests
+var detectNetworkType = getNetworkType
+
+// askFunc asks the user whether an update should be installed
+type askFunc func() (bool, error)
+
+// allowAutoUpdate decides whether an update batch may install an update
+// for a cave with the given policy. If not, it returns why.
+func allowAutoUpdate(consumer *state.Consumer, policy butlerd.CaveAutoUpdatePolicy, ask askFunc) (bool, string, error) {
+	switch policy {
+	case butlerd.CaveAutoUpdatePolicyNever:
+		return false, "auto-updates are disabled for this cave", nil
+	case butlerd.CaveAutoUpdatePolicyWifiOnly:
+		nt, err := detectNetworkType()
+		if err != nil {
+			consumer.Warnf("Could not determine network type: %s", err.Error())
+		}
+		consumer.Infof("Network type: %s", nt)
+		switch nt {
+		case networkTypeWifi, networkTypeWired:
+			return true, "", nil
+		case networkTypeCellular:
+			return false, "cave only auto-updates over Wi-Fi, and we're on a cellular connection", nil
+		default:
+			return false, "cave only auto-updates over Wi-Fi, and we couldn't tell the network type", nil
+		}
+	case butlerd.CaveAutoUpdatePolicyAsk:
+		accept, err := ask()
+		if err != nil {
+			return false, "", err
+		}
+		if !accept {
+			return false, "update declined by the user", nil
+		}
+		return true, "", nil
+	default:
+		return true, "", nil
+	}
+}
+
+// askUser sends @@UpdateAvailableAskUserParams over rc's connection
+func askUser(rc *butlerd.RequestContext, cave *models.Cave, choice *butlerd.GameUpdateChoice) askFunc {
+	return func() (bool, error) {
+		res, err := messages.UpdateAvailableAskUser.Call(rc, butlerd.UpdateAvailableAskUserParams{
+			CaveID: cave.ID,
+			Game:   cave.Game,
+			Choice: choice,
+		})
+		if err != nil {
+			return false, err
+		}
+		return res.Accept, nil
+	}
+}
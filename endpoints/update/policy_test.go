@@ -0,0 +1,71 @@
+package update
+
+import (
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/headway/state"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AllowAutoUpdate(t *testing.T) {
+	assert := assert.New(t)
+	consumer := &state.Consumer{}
+
+	defer func(f func() (networkType, error)) { detectNetworkType = f }(detectNetworkType)
+	var nt networkType
+	detectNetworkType = func() (networkType, error) {
+		if nt == networkTypeUnknown {
+			return nt, errors.New("no idea")
+		}
+		return nt, nil
+	}
+
+	var asked int
+	answer := func(accept bool) askFunc {
+		return func() (bool, error) {
+			asked++
+			return accept, nil
+		}
+	}
+
+	allow, _, err := allowAutoUpdate(consumer, butlerd.CaveAutoUpdatePolicyAlways, answer(false))
+	must(t, err)
+	assert.True(allow)
+
+	allow, reason, err := allowAutoUpdate(consumer, butlerd.CaveAutoUpdatePolicyNever, answer(true))
+	must(t, err)
+	assert.False(allow)
+	assert.NotEmpty(reason)
+	assert.EqualValues(0, asked, "only asks with the ask policy")
+
+	for _, tc := range []struct {
+		nt    networkType
+		allow bool
+	}{
+		{networkTypeWifi, true},
+		{networkTypeWired, true},
+		{networkTypeCellular, false},
+		{networkTypeUnknown, false},
+	} {
+		nt = tc.nt
+		allow, _, err = allowAutoUpdate(consumer, butlerd.CaveAutoUpdatePolicyWifiOnly, answer(true))
+		must(t, err)
+		assert.EqualValues(tc.allow, allow, "on %s", tc.nt)
+	}
+
+	allow, _, err = allowAutoUpdate(consumer, butlerd.CaveAutoUpdatePolicyAsk, answer(true))
+	must(t, err)
+	assert.True(allow)
+	allow, reason, err = allowAutoUpdate(consumer, butlerd.CaveAutoUpdatePolicyAsk, answer(false))
+	must(t, err)
+	assert.False(allow)
+	assert.NotEmpty(reason)
+	assert.EqualValues(2, asked)
+
+	_, _, err = allowAutoUpdate(consumer, butlerd.CaveAutoUpdatePolicyAsk, func() (bool, error) {
+		return false, errors.New("client went away")
+	})
+	assert.Error(err)
+}
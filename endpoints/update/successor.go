@@ -0,0 +1,242 @@
+package update
+
+import (
+	"sort"
+	"strings"
+
+	"crawshaw.io/sqlite"
+	"github.com/arbovm/levenshtein"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/manager"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/hades"
+	"github.com/itchio/headway/state"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
+)
+
+// uploads scoring lower than this aren't suggested as a successor
+const minSuccessorScore = 0.5
+
+type checkReplacedUploadParams struct {
+	cave   *models.Cave
+	client *itchio.Client
+	access *operate.GameAccess
+	// uploads currently listed for the cave's game
+	listed []*itchio.Upload
+}
+
+// checkReplacedUpload is called when the cave's upload isn't listed
+// anymore. If the developer deleted it and added another one for "the
+// same thing" (engine port, renamed channel...), it returns an update
+// flagged UploadReplaced. handled is true if the caller shouldn't look
+// for other updates (the user declined replacements for this cave).
+func checkReplacedUpload(rc *butlerd.RequestContext, consumer *state.Consumer, params checkReplacedUploadParams) (res *butlerd.GameUpdate, handled bool, err error) {
+	cave := params.cave
+	old := cave.Upload
+
+	if old.Filename == "" && old.DisplayName == "" {
+		consumer.Infof("Not enough information on our upload to look for a successor")
+		return nil, false, nil
+	}
+
+	// the listing might just be incomplete (lacking credentials, etc.)
+	_, err = params.client.GetUpload(rc.Ctx, itchio.GetUploadParams{
+		UploadID:    old.ID,
+		Credentials: params.access.Credentials,
+	})
+	if err == nil {
+		consumer.Infof("Our upload isn't listed, but still exists - not looking for a successor")
+		return nil, false, nil
+	}
+
+	if cave.ReplacedUploadID == old.ID {
+		if cave.SuccessorDeclined {
+			consumer.Statf("Our upload was replaced, but switching to a successor was declined.")
+			return nil, true, nil
+		}
+
+		if cave.SuccessorUploadID != 0 {
+			for _, u := range params.listed {
+				if u.ID == cave.SuccessorUploadID {
+					consumer.Statf("↑ Our upload was replaced, offering confirmed successor:")
+					operate.LogUpload(consumer, u, u.Build)
+					res := &butlerd.GameUpdate{
+						CaveID:         cave.ID,
+						Game:           cave.Game,
+						UploadReplaced: true,
+					}
+					res.Choices = append(res.Choices, &butlerd.GameUpdateChoice{
+						Upload:         u,
+						Build:          u.Build,
+						Confidence:     1,
+						UploadReplaced: true,
+						Confirmed:      true,
+					})
+					return res, true, nil
+				}
+			}
+			consumer.Infof("Confirmed successor (%d) isn't listed anymore either, looking again", cave.SuccessorUploadID)
+		}
+	}
+
+	var candidates []*itchio.Upload
+	for _, u := range params.listed {
+		if u.ID == old.ID {
+			continue
+		}
+		if successorScore(old, u) > 0 {
+			candidates = append(candidates, u)
+		}
+	}
+	if len(candidates) == 0 {
+		consumer.Infof("Our upload is gone, and no upload looks like its successor")
+		return nil, false, nil
+	}
+
+	narrowDownResult, err := manager.NarrowDownUploads(consumer, cave.Game, candidates, rc.HostEnumerator())
+	if err != nil {
+		return nil, false, err
+	}
+
+	res = &butlerd.GameUpdate{
+		CaveID:         cave.ID,
+		Game:           cave.Game,
+		UploadReplaced: true,
+	}
+	for _, u := range narrowDownResult.Uploads {
+		score := successorScore(old, u)
+		consumer.Infof("(successor score %.2f for upload %d)", score, u.ID)
+		if score < minSuccessorScore {
+			continue
+		}
+		res.Choices = append(res.Choices, &butlerd.GameUpdateChoice{
+			Upload:         u,
+			Build:          u.Build,
+			Confidence:     score,
+			UploadReplaced: true,
+		})
+	}
+	if len(res.Choices) == 0 {
+		consumer.Infof("Our upload is gone, and no upload looks enough like its successor")
+		return nil, false, nil
+	}
+
+	sort.SliceStable(res.Choices, func(i, j int) bool {
+		return res.Choices[i].Confidence > res.Choices[j].Confidence
+	})
+
+	consumer.Statf("↑ Our upload was replaced, %d possible successors need confirmation:", len(res.Choices))
+	for _, c := range res.Choices {
+		operate.LogUpload(consumer, c.Upload, c.Build)
+	}
+
+	// remember we asked, so install operations can check the
+	// answer (see ConfirmUploadSuccessor)
+	if cave.ReplacedUploadID != old.ID || cave.SuccessorUploadID != 0 {
+		cave.ReplacedUploadID = old.ID
+		cave.SuccessorUploadID = 0
+		cave.SuccessorDeclined = false
+		rc.WithConn(func(conn *sqlite.Conn) {
+			saveSuccessorDecision(conn, cave)
+		})
+	}
+	return res, true, nil
+}
+
+// successorScore is between 0 and 1: how likely it is that u replaces
+// old, an upload that was deleted. The upload type has to match, the
+// rest is a weighted mix of same channel, same platforms and similar name.
+func successorScore(old *itchio.Upload, u *itchio.Upload) float64 {
+	if old.Type != "" && u.Type != old.Type {
+		return 0
+	}
+	if old.Demo != u.Demo || u.Preorder {
+		return 0
+	}
+
+	var score float64
+	if old.ChannelName != "" && u.ChannelName == old.ChannelName {
+		score += 0.4
+	}
+	score += 0.3 * platformsSimilarity(old.Platforms, u.Platforms)
+	score += 0.3 * nameSimilarity(old, u)
+	return score
+}
+
+// platformsSimilarity is the fraction of platforms both have, among
+// the platforms either has.
+func platformsSimilarity(a itchio.Platforms, b itchio.Platforms) float64 {
+	pairs := [][2]itchio.Architectures{
+		{a.Windows, b.Windows},
+		{a.Linux, b.Linux},
+		{a.OSX, b.OSX},
+	}
+
+	var both, either int
+	for _, p := range pairs {
+		if p[0] != "" || p[1] != "" {
+			either++
+		}
+		if p[0] != "" && p[1] != "" {
+			both++
+		}
+	}
+	if either == 0 {
+		// neither has platforms (html games, etc.)
+		return 1
+	}
+	return float64(both) / float64(either)
+}
+
+// nameSimilarity compares display names if both uploads have one,
+// and file names otherwise.
+func nameSimilarity(old *itchio.Upload, u *itchio.Upload) float64 {
+	lhs, rhs := old.DisplayName, u.DisplayName
+	if lhs == "" || rhs == "" {
+		lhs, rhs = old.Filename, u.Filename
+	}
+	lhs, rhs = strings.ToLower(lhs), strings.ToLower(rhs)
+
+	longest := len(lhs)
+	if len(rhs) > longest {
+		longest = len(rhs)
+	}
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein.Distance(lhs, rhs))/float64(longest)
+}
+
+func saveSuccessorDecision(conn *sqlite.Conn, cave *models.Cave) {
+	models.MustUpdate(conn, &models.Cave{},
+		hades.Where(builder.Eq{"id": cave.ID}),
+		builder.Eq{
+			"replaced_upload_id":  cave.ReplacedUploadID,
+			"successor_upload_id": cave.SuccessorUploadID,
+			"successor_declined":  cave.SuccessorDeclined,
+		},
+	)
+}
+
+func ConfirmUploadSuccessor(rc *butlerd.RequestContext, params butlerd.ConfirmUploadSuccessorParams) (*butlerd.ConfirmUploadSuccessorResult, error) {
+	conn := rc.GetConn()
+	defer rc.PutConn(conn)
+
+	cave := models.CaveByID(conn, params.CaveID)
+	if cave == nil {
+		return nil, errors.Errorf("No such cave (%s)", params.CaveID)
+	}
+
+	if cave.ReplacedUploadID == 0 || cave.ReplacedUploadID != cave.UploadID {
+		return nil, errors.Errorf("No replacement was suggested for the upload of cave (%s), check for updates first", params.CaveID)
+	}
+
+	cave.SuccessorUploadID = params.UploadID
+	cave.SuccessorDeclined = params.UploadID == 0
+	saveSuccessorDecision(conn, cave)
+
+	return &butlerd.ConfirmUploadSuccessorResult{}, nil
+}
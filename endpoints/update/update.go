@@ -18,6 +18,8 @@ import (
 	"github.com/itchio/butler/cmd/operate"
 	"github.com/itchio/butler/cmd/operate/memorylogger"
 	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/endpoints/fetch"
+	"github.com/itchio/butler/mansion/ratelimit"
 	itchio "github.com/itchio/go-itchio"
 	"github.com/itchio/hades"
 	"github.com/itchio/headway/state"
@@ -28,11 +30,17 @@ import (
 func Register(router *butlerd.Router) {
 	messages.CheckUpdate.Register(router, CheckUpdate)
 	messages.SnoozeCave.Register(router, SnoozeCave)
+	messages.ConfirmUploadSuccessor.Register(router, ConfirmUploadSuccessor)
+	messages.CaveUpdateBatch.Register(router, CaveUpdateBatch)
+	messages.CaveBatchStatus.Register(router, CaveBatchStatus)
 }
 
 func CheckUpdate(rc *butlerd.RequestContext, params butlerd.CheckUpdateParams) (*butlerd.CheckUpdateResult, error) {
 	startTime := time.Now()
 
+	// update checks aren't urgent, let interactive API calls go first
+	rc.Ctx = ratelimit.WithClass(rc.Ctx, ratelimit.ClassBackground)
+
 	consumer := rc.Consumer
 	res := &butlerd.CheckUpdateResult{}
 
@@ -107,6 +115,7 @@ func CheckUpdate(rc *butlerd.RequestContext, params butlerd.CheckUpdateParams) (
 				ml.Copy(consumer)
 			}
 			if update != nil {
+				update.AutoUpdatePolicy = fetch.CaveAutoUpdatePolicy(spec.cave)
 				res.Updates = append(res.Updates, update)
 				err := messages.GameUpdateAvailable.Notify(rc, butlerd.GameUpdateAvailableNotification{
 					Update: update,
@@ -174,12 +183,37 @@ func checkUpdateCave(params checkUpdateCaveParams, consumer *state.Consumer, cav
 		return nil, nil
 	}
 
+	if cave.PlayableEarly {
+		// it's being installed, an update would step on that
+		consumer.Statf("Cave is still being installed, skipping")
+		return nil, nil
+	}
+
 	var access *operate.GameAccess
 	rc.WithConn(func(conn *sqlite.Conn) {
-		access = operate.AccessForGameID(conn, cave.GameID)
+		access = operate.AccessForCave(conn, cave)
 	})
 	client := rc.Client(access.APIKey)
 
+	if cave.GameDelisted {
+		consumer.Statf("Game (%d) was delisted, only checking whether it came back", cave.GameID)
+		_, err := client.GetGame(rc.Ctx, itchio.GetGameParams{
+			GameID:      cave.GameID,
+			Credentials: access.Credentials,
+		})
+		if err != nil {
+			if isGameGone(err) {
+				recordGameGone(cave, time.Now().UTC())
+				rc.WithConn(func(conn *sqlite.Conn) {
+					saveGameGoneState(conn, cave)
+				})
+				consumer.Infof("Still gone, not looking for updates.")
+				return nil, nil
+			}
+			return nil, errors.WithStack(err)
+		}
+	}
+
 	if cave.Game == nil {
 		consumer.Opf("Cave game is missing, trying to fetch it...")
 
@@ -188,6 +222,9 @@ func checkUpdateCave(params checkUpdateCaveParams, consumer *state.Consumer, cav
 			Credentials: access.Credentials,
 		})
 		if err != nil {
+			if isGameGone(err) {
+				onGameGone(rc, consumer, cave)
+			}
 			return nil, errors.Wrap(err, "Cave missing game, and error'd when fetching it")
 		}
 
@@ -288,9 +325,21 @@ func checkUpdateCave(params checkUpdateCaveParams, consumer *state.Consumer, cav
 		Credentials: access.Credentials,
 	})
 	if err != nil {
+		if isGameGone(err) {
+			onGameGone(rc, consumer, cave)
+		}
 		return nil, errors.WithStack(err)
 	}
 
+	if cave.GameGoneChecks > 0 || cave.GameDelisted {
+		if recordGameFound(cave) {
+			consumer.Statf("Game (%d) is back on itch.io, it's not delisted anymore", cave.GameID)
+		}
+		rc.WithConn(func(conn *sqlite.Conn) {
+			saveGameGoneState(conn, cave)
+		})
+	}
+
 	var currentUpload = cave.Upload
 	var freshUpload *itchio.Upload
 	var newerUploads []*itchio.Upload
@@ -339,6 +388,21 @@ func checkUpdateCave(params checkUpdateCaveParams, consumer *state.Consumer, cav
 		newerUploads = append(newerUploads, u)
 	}
 
+	if freshUpload == nil {
+		res, handled, err := checkReplacedUpload(rc, consumer, checkReplacedUploadParams{
+			cave:   cave,
+			client: client,
+			access: access,
+			listed: listUploadsRes.Uploads,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if handled {
+			return res, nil
+		}
+	}
+
 	// wharf updates
 	if currentUpload.ChannelName != "" {
 		consumer.Infof("We're currently on a wharf channel (%s)", currentUpload.ChannelName)
@@ -0,0 +1,159 @@
+package utilities
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/headway/united"
+	"github.com/pkg/errors"
+)
+
+type networkTargets struct {
+	// host:port pairs we resolve and connect to
+	APIAddress string
+	CDNAddress string
+
+	WhoamiURL string
+	// any file larger than speedTestSize will do, we
+	// stop reading after that.
+	SpeedTestURL string
+}
+
+var defaultNetworkTargets = networkTargets{
+	APIAddress:   "api.itch.io:443",
+	CDNAddress:   "dl.itch.ovh:443",
+	WhoamiURL:    "https://api.itch.io/v2/whoami",
+	SpeedTestURL: "https://broth.itch.ovh/butler/linux-amd64/LATEST/archive/default",
+}
+
+const (
+	diagnosticsDialTimeout = 5 * time.Second
+	diagnosticsHTTPTimeout = 10 * time.Second
+	speedTestTimeout       = 30 * time.Second
+	speedTestSize          = 1024 * 1024
+)
+
+func NetworkDiagnostics(rc *butlerd.RequestContext, params butlerd.NetworkDiagnosticsParams) (*butlerd.NetworkDiagnosticsResult, error) {
+	res := diagnoseNetwork(rc.Ctx, rc.Consumer, rc.HTTPClient, defaultNetworkTargets)
+	return res, nil
+}
+
+func diagnoseNetwork(ctx context.Context, consumer *state.Consumer, client *http.Client, targets networkTargets) *butlerd.NetworkDiagnosticsResult {
+	res := &butlerd.NetworkDiagnosticsResult{
+		DNSResolved:  true,
+		TCPConnected: true,
+		Errors:       []string{},
+	}
+	fail := func(step string, err error) {
+		consumer.Warnf("Network diagnostics: %s: %v", step, err)
+		res.Errors = append(res.Errors, fmt.Sprintf("%s: %v", step, err))
+	}
+
+	for _, address := range []string{targets.APIAddress, targets.CDNAddress} {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			fail("parsing "+address, err)
+			res.DNSResolved = false
+			res.TCPConnected = false
+			continue
+		}
+
+		addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+		if err != nil {
+			fail("resolving "+host, err)
+			res.DNSResolved = false
+			res.TCPConnected = false
+			continue
+		}
+		consumer.Infof("Network diagnostics: (%s) resolved to %v", host, addrs)
+
+		dialer := &net.Dialer{Timeout: diagnosticsDialTimeout}
+		before := time.Now()
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			fail("connecting to "+address, err)
+			res.TCPConnected = false
+			continue
+		}
+		conn.Close()
+		consumer.Infof("Network diagnostics: connected to (%s) in %s", address, time.Since(before))
+	}
+
+	err := func() error {
+		ctx, cancel := context.WithTimeout(ctx, diagnosticsHTTPTimeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, "GET", targets.WhoamiURL, nil)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		httpRes, err := client.Do(req)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer httpRes.Body.Close()
+
+		// we're not authenticated, so any answer that isn't
+		// a server error means the API is up.
+		if httpRes.StatusCode >= 500 {
+			return errors.Errorf("HTTP %s", httpRes.Status)
+		}
+		consumer.Infof("Network diagnostics: API replied with HTTP %s", httpRes.Status)
+		return nil
+	}()
+	if err != nil {
+		fail("requesting "+targets.WhoamiURL, err)
+	} else {
+		res.APIReachable = true
+	}
+
+	err = func() error {
+		ctx, cancel := context.WithTimeout(ctx, speedTestTimeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, "GET", targets.SpeedTestURL, nil)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		before := time.Now()
+		httpRes, err := client.Do(req)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer httpRes.Body.Close()
+
+		if httpRes.StatusCode/100 != 2 {
+			return errors.Errorf("HTTP %s", httpRes.Status)
+		}
+
+		n, err := io.Copy(ioutil.Discard, io.LimitReader(httpRes.Body, speedTestSize))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		elapsed := time.Since(before)
+		if elapsed <= 0 {
+			elapsed = time.Millisecond
+		}
+
+		bps := float64(n) / elapsed.Seconds()
+		res.MeasuredSpeedKBps = int64(bps / 1024)
+		consumer.Infof("Network diagnostics: downloaded %s in %s (%s/s)",
+			united.FormatBytes(n), elapsed, united.FormatBytes(int64(bps)))
+		return nil
+	}()
+	if err != nil {
+		fail("speed test", err)
+	} else {
+		res.CDNReachable = true
+	}
+
+	return res
+}
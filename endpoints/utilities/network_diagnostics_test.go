@@ -0,0 +1,60 @@
+package utilities
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/itchio/headway/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DiagnoseNetwork(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/whoami", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	mux.HandleFunc("/speedtest", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bytes.Repeat([]byte{0x42}, 2*speedTestSize))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	assert.NoError(t, err)
+	consumer := &state.Consumer{
+		OnMessage: func(lvl string, msg string) {
+			t.Logf("[%s] %s", lvl, msg)
+		},
+	}
+
+	res := diagnoseNetwork(context.Background(), consumer, srv.Client(), networkTargets{
+		APIAddress:   u.Host,
+		CDNAddress:   u.Host,
+		WhoamiURL:    srv.URL + "/v2/whoami",
+		SpeedTestURL: srv.URL + "/speedtest",
+	})
+	assert.Empty(t, res.Errors)
+	assert.True(t, res.DNSResolved)
+	assert.True(t, res.TCPConnected)
+	assert.True(t, res.APIReachable)
+	assert.True(t, res.CDNReachable)
+	assert.True(t, res.MeasuredSpeedKBps > 0)
+
+	srv.Close()
+	res = diagnoseNetwork(context.Background(), consumer, srv.Client(), networkTargets{
+		APIAddress:   u.Host,
+		CDNAddress:   "doesnotexist.invalid:443",
+		WhoamiURL:    srv.URL + "/v2/whoami",
+		SpeedTestURL: srv.URL + "/speedtest",
+	})
+	assert.False(t, res.DNSResolved)
+	assert.False(t, res.TCPConnected)
+	assert.False(t, res.APIReachable)
+	assert.False(t, res.CDNReachable)
+	assert.EqualValues(t, 0, res.MeasuredSpeedKBps)
+	assert.Len(t, res.Errors, 4)
+}
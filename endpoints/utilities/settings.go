@@ -0,0 +1,133 @@
+package utilities
+
+import (
+	"encoding/json"
+
+	"crawshaw.io/sqlite"
+	"github.com/efarrer/iothrottler"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/mansion/settings"
+	"github.com/itchio/httpkit/timeout"
+	"github.com/pkg/errors"
+)
+
+var bandwidthLimit = settings.Register(settings.Setting{
+	Key:         "network.bandwidthLimit",
+	Description: "Maximum download bandwidth, in kbps. 0 means unlimited.",
+	Default:     int64(0),
+	Validate: func(value interface{}) error {
+		if value.(int64) < 0 {
+			return errors.New("must be positive")
+		}
+		return nil
+	},
+})
+
+var simulateOffline = settings.Register(settings.Setting{
+	Key:         "network.simulateOffline",
+	Description: "Behave as if there were no network connections. Meant for testing.",
+	Default:     false,
+	Transient:   true,
+})
+
+func registerSettings(router *butlerd.Router) {
+	settings.Subscribe(bandwidthLimit.Key, func(value interface{}) {
+		rate := value.(int64)
+		if rate > 0 {
+			timeout.ThrottlerPool.SetBandwidth(iothrottler.Bandwidth(rate) * iothrottler.Kbps)
+		} else {
+			timeout.ThrottlerPool.SetBandwidth(iothrottler.Unlimited)
+		}
+	})
+
+	settings.Subscribe(simulateOffline.Key, func(value interface{}) {
+		enabled := value.(bool)
+		timeout.SetSimulateOffline(enabled)
+		if enabled {
+			// with http/2, we need to do this, otherwise it'll re-use existing connections
+			if transport := router.HTTPTransport(); transport != nil {
+				transport.CloseIdleConnections()
+			}
+		}
+	})
+
+	messages.SettingsGet.Register(router, func(rc *butlerd.RequestContext, params butlerd.SettingsGetParams) (*butlerd.SettingsGetResult, error) {
+		entry, err := settings.Get(params.Key)
+		if err != nil {
+			return nil, err
+		}
+		return &butlerd.SettingsGetResult{
+			Setting: formatSetting(entry),
+		}, nil
+	})
+
+	messages.SettingsList.Register(router, func(rc *butlerd.RequestContext, params butlerd.SettingsListParams) (*butlerd.SettingsListResult, error) {
+		res := &butlerd.SettingsListResult{
+			Settings: []*butlerd.Setting{},
+		}
+		for _, entry := range settings.List() {
+			res.Settings = append(res.Settings, formatSetting(entry))
+		}
+		return res, nil
+	})
+
+	messages.SettingsSet.Register(router, func(rc *butlerd.RequestContext, params butlerd.SettingsSetParams) (*butlerd.SettingsSetResult, error) {
+		raw, err := json.Marshal(params.Value)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		var entry settings.Entry
+		var changed bool
+		rc.WithConn(func(conn *sqlite.Conn) {
+			entry, changed, err = settings.SetJSON(conn, params.Key, raw)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			notifySettingChanged(rc, entry)
+		}
+
+		return &butlerd.SettingsSetResult{
+			Setting: formatSetting(entry),
+		}, nil
+	})
+}
+
+// ChangeSetting sets the value of s, for requests that predate Settings.Set
+// or are more specific than it, and lets clients know if it changed.
+func ChangeSetting(rc *butlerd.RequestContext, s *settings.Setting, value interface{}) error {
+	var entry settings.Entry
+	var changed bool
+	var err error
+	rc.WithConn(func(conn *sqlite.Conn) {
+		entry, changed, err = s.Set(conn, value)
+	})
+	if err != nil {
+		return err
+	}
+	if changed {
+		notifySettingChanged(rc, entry)
+	}
+	return nil
+}
+
+func notifySettingChanged(rc *butlerd.RequestContext, entry settings.Entry) {
+	rc.Consumer.Infof("Setting %s is now %v", entry.Setting.Key, entry.Value)
+	rc.Broadcast(messages.SettingChanged.Method(), butlerd.SettingChangedNotification{
+		Setting: formatSetting(entry),
+	})
+}
+
+func formatSetting(entry settings.Entry) *butlerd.Setting {
+	return &butlerd.Setting{
+		Key:         entry.Setting.Key,
+		Description: entry.Setting.Description,
+		Value:       entry.Value,
+		Default:     entry.Setting.Default,
+		IsDefault:   entry.IsDefault(),
+		Transient:   entry.Setting.Transient,
+	}
+}
@@ -1,11 +1,9 @@
 package utilities
 
 import (
-	"github.com/efarrer/iothrottler"
 	"github.com/itchio/butler/buildinfo"
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/butlerd/messages"
-	"github.com/itchio/httpkit/timeout"
 )
 
 func Register(router *butlerd.Router) {
@@ -16,14 +14,12 @@ func Register(router *butlerd.Router) {
 		}, nil
 	})
 
-	messages.NetworkSetSimulateOffline.Register(router, func(rc *butlerd.RequestContext, params butlerd.NetworkSetSimulateOfflineParams) (*butlerd.NetworkSetSimulateOfflineResult, error) {
-		rc.Consumer.Infof("Setting offline mode to: %v", params.Enabled)
-		timeout.SetSimulateOffline(params.Enabled)
+	registerSettings(router)
 
-		if params.Enabled {
-			// with http/2, we need to do this, otherwise it'll re-use existing connections
-			rc.Consumer.Infof("Closing idle connections")
-			rc.HTTPTransport.CloseIdleConnections()
+	messages.NetworkSetSimulateOffline.Register(router, func(rc *butlerd.RequestContext, params butlerd.NetworkSetSimulateOfflineParams) (*butlerd.NetworkSetSimulateOfflineResult, error) {
+		err := ChangeSetting(rc, simulateOffline, params.Enabled)
+		if err != nil {
+			return nil, err
 		}
 
 		res := &butlerd.NetworkSetSimulateOfflineResult{}
@@ -31,12 +27,18 @@ func Register(router *butlerd.Router) {
 	})
 
 	messages.NetworkSetBandwidthThrottle.Register(router, func(rc *butlerd.RequestContext, params butlerd.NetworkSetBandwidthThrottleParams) (*butlerd.NetworkSetBandwidthThrottleResult, error) {
+		var rate int64
 		if params.Enabled {
-			timeout.ThrottlerPool.SetBandwidth(iothrottler.Bandwidth(params.Rate) * iothrottler.Kbps)
-		} else {
-			timeout.ThrottlerPool.SetBandwidth(iothrottler.Unlimited)
+			rate = params.Rate
 		}
+		err := ChangeSetting(rc, bandwidthLimit, rate)
+		if err != nil {
+			return nil, err
+		}
+
 		res := &butlerd.NetworkSetBandwidthThrottleResult{}
 		return res, nil
 	})
+
+	messages.NetworkDiagnostics.Register(router, NetworkDiagnostics)
 }
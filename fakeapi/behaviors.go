@@ -0,0 +1,95 @@
+package fakeapi
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Behaviors makes the fake API misbehave like the real one sometimes
+// does, so clients can be tested against it. The zero value behaves.
+type Behaviors struct {
+	// Added to every response, downloads included
+	Latency time.Duration
+	// If positive, every Nth API call is answered with a 429,
+	// which asks to retry after RetryAfter
+	RateLimitEvery int64
+	// Sent along with 429 responses, rounded down to seconds
+	RetryAfter time.Duration
+	// If positive, every Nth API call has its response body cut in half
+	TruncateEvery int64
+}
+
+// cdnPrefix is where the fake API serves files from. Downloads are only
+// ever slowed down, other behaviors only apply to API calls.
+const cdnPrefix = "/@cdn"
+
+// Proxy returns a handler that forwards requests to upstream,
+// misbehaving along the way according to b.
+func Proxy(upstream *url.URL, b Behaviors) http.Handler {
+	rp := httputil.NewSingleHostReverseProxy(upstream)
+
+	var calls int64
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if b.Latency > 0 {
+			time.Sleep(b.Latency)
+		}
+
+		if strings.HasPrefix(req.URL.Path, cdnPrefix) {
+			rp.ServeHTTP(w, req)
+			return
+		}
+
+		n := atomic.AddInt64(&calls, 1)
+		if b.RateLimitEvery > 0 && n%b.RateLimitEvery == 0 {
+			w.Header().Set("Retry-After", formatSeconds(b.RetryAfter))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"errors":["rate limited"]}`))
+			return
+		}
+
+		if b.TruncateEvery > 0 && n%b.TruncateEvery == 0 {
+			tw := &truncatingWriter{ResponseWriter: w}
+			rp.ServeHTTP(tw, req)
+			tw.flush()
+			return
+		}
+
+		rp.ServeHTTP(w, req)
+	})
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(d/time.Second), 10)
+}
+
+// truncatingWriter holds on to a response, so that only
+// the first half of its body is sent when flushed
+type truncatingWriter struct {
+	http.ResponseWriter
+	status int
+	body   []byte
+}
+
+func (tw *truncatingWriter) WriteHeader(status int) {
+	tw.status = status
+}
+
+func (tw *truncatingWriter) Write(p []byte) (int, error) {
+	tw.body = append(tw.body, p...)
+	return len(p), nil
+}
+
+func (tw *truncatingWriter) flush() {
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	tw.ResponseWriter.Header().Del("Content-Length")
+	tw.ResponseWriter.WriteHeader(tw.status)
+	tw.ResponseWriter.Write(tw.body[:len(tw.body)/2])
+}
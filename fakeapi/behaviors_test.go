@@ -0,0 +1,62 @@
+package fakeapi
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Proxy(t *testing.T) {
+	assert := assert.New(t)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"game":{"id":1}}`))
+	}))
+	defer upstream.Close()
+	upstreamURL, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+
+	proxy := httptest.NewServer(Proxy(upstreamURL, Behaviors{
+		Latency:        20 * time.Millisecond,
+		RateLimitEvery: 2,
+		RetryAfter:     3 * time.Second,
+		TruncateEvery:  3,
+	}))
+	defer proxy.Close()
+
+	get := func(path string) (*http.Response, string) {
+		t.Helper()
+		res, err := http.Get(proxy.URL + path)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, string(body)
+	}
+
+	startTime := time.Now()
+	res, body := get("/games/1")
+	assert.True(time.Since(startTime) >= 20*time.Millisecond, "slowed down")
+	assert.EqualValues(http.StatusOK, res.StatusCode)
+	assert.EqualValues(`{"game":{"id":1}}`, body)
+
+	res, _ = get("/games/1")
+	assert.EqualValues(http.StatusTooManyRequests, res.StatusCode)
+	assert.EqualValues("3", res.Header.Get("Retry-After"))
+
+	res, body = get("/games/1")
+	assert.EqualValues(http.StatusOK, res.StatusCode)
+	assert.EqualValues(`{"game":`, body, "truncated")
+
+	for i := 0; i < 6; i++ {
+		res, body = get("/@cdn/games/1/default.zip")
+		assert.EqualValues(http.StatusOK, res.StatusCode, "downloads are left alone")
+		assert.EqualValues(`{"game":{"id":1}}`, body)
+	}
+}
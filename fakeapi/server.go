@@ -0,0 +1,134 @@
+// Package fakeapi runs a fake itch.io API, with a few games
+// to install, for tests and local client development.
+//
+// It's built on mitch, which implements the part of the API that
+// butler uses to install games (games, uploads, builds, upgrade paths
+// and download URLs), and serves files and wharf builds from memory.
+package fakeapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/itchio/headway/state"
+	"github.com/itchio/mitch"
+	"github.com/pkg/errors"
+)
+
+// APIKey is the key of the user the fixtures belong to
+const APIKey = "fake-api-key"
+
+type Server struct {
+	// Where the API is, to pass to `butler --address`
+	Address string
+	// Same as the APIKey constant
+	APIKey string
+	// Games that can be installed, see Fixtures
+	Fixtures *Fixtures
+
+	upstream mitch.Server
+}
+
+type ServerParams struct {
+	// Port to listen on, or 0 for any free one
+	Port int
+	// How the API misbehaves
+	Behaviors Behaviors
+	// Where mitch logs to, optional
+	Consumer *state.Consumer
+}
+
+// Fixtures are the games a fresh fake API has
+type Fixtures struct {
+	// Has a single zip upload
+	ZipGameID int64
+	// Has a wharf build, and a second one to update to
+	WharfGameID int64
+}
+
+// Start runs a fake API until ctx is done. Calls to Store on what it
+// returns, while it's being used, are how tests add more games.
+func Start(ctx context.Context, params ServerParams) (*Server, error) {
+	var opts []mitch.ServerOpt
+	if params.Consumer != nil {
+		opts = append(opts, mitch.WithConsumer(params.Consumer))
+	}
+	upstream, err := mitch.NewServer(ctx, opts...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	upstreamURL, err := url.Parse(fmt.Sprintf("http://%s", upstream.Address()))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", params.Port))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	srv := &http.Server{
+		Handler: Proxy(upstreamURL, params.Behaviors),
+	}
+	go srv.Serve(listener)
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+
+	s := &Server{
+		Address:  fmt.Sprintf("http://%s", listener.Addr()),
+		upstream: upstream,
+	}
+	s.APIKey, s.Fixtures = seed(upstream.Store())
+	return s, nil
+}
+
+// Store holds everything the fake API knows about
+func (s *Server) Store() *mitch.Store {
+	return s.upstream.Store()
+}
+
+func seed(store *mitch.Store) (string, *Fixtures) {
+	user := store.MakeUser("Fake Player")
+	apiKey := user.MakeAPIKey()
+	apiKey.Key = APIKey
+
+	developer := store.MakeUser("Fake Developer")
+	fixtures := &Fixtures{}
+
+	{
+		game := developer.MakeGame("Zipped Up")
+		game.Publish()
+		upload := game.MakeUpload("All platforms")
+		upload.SetAllPlatforms()
+		upload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+			ac.SetName("zipped-up.zip")
+			ac.Entry("readme.txt").String("Thanks for playing")
+			ac.Entry("data/level1.json").String(`{"name":"one"}`)
+		})
+		fixtures.ZipGameID = game.ID
+	}
+
+	{
+		game := developer.MakeGame("Pushed Often")
+		game.Publish()
+		upload := game.MakeUpload("All platforms")
+		upload.SetAllPlatforms()
+		upload.ChannelName = "default"
+		for _, version := range []string{"1.0", "1.1"} {
+			version := version
+			upload.PushBuild(func(ac *mitch.ArchiveContext) {
+				ac.SetName("default.zip")
+				ac.Entry("version.txt").String(version)
+				ac.Entry("data/assets.bin").Random(4, 256*1024)
+			})
+		}
+		fixtures.WharfGameID = game.ID
+	}
+
+	return apiKey.Key, fixtures
+}
@@ -5,6 +5,7 @@ go 1.14
 require (
 	crawshaw.io/sqlite v0.3.2
 	github.com/BurntSushi/toml v0.3.1
+	github.com/alecthomas/units v0.0.0-20190924025748-f65c72e2690d
 	github.com/arbovm/levenshtein v0.0.0-20160628152529-48b4e1c0c4d0
 	github.com/dchest/safefile v0.0.0-20151022103144-855e8d98f185
 	github.com/dustinkirkland/golang-petname v0.0.0-20191129215211-8e5a1ed0cff0
@@ -40,7 +41,7 @@ require (
 	github.com/itchio/wharf v0.0.0-20200618110241-8896e2c6e09b
 	github.com/itchio/wizardry v0.0.0-20200301161332-e8c8c4a5a488
 	github.com/kballard/go-shellquote v0.0.0-20180428030007-95032a82bc51
-	github.com/klauspost/compress v1.10.9 // indirect
+	github.com/klauspost/compress v1.10.9
 	github.com/mattn/go-colorable v0.1.6 // indirect
 	github.com/mattn/go-runewidth v0.0.9 // indirect
 	github.com/mitchellh/mapstructure v1.3.2
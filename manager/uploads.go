@@ -1,6 +1,7 @@
 package manager
 
 import (
+	"fmt"
 	"regexp"
 	"sort"
 	"strings"
@@ -115,8 +116,9 @@ func (uf *uploadFilter) excludeWrongFormat(uploads []*itchio.Upload) []*itchio.U
 }
 
 type scoredUpload struct {
-	score  int64
-	upload *itchio.Upload
+	score   int64
+	upload  *itchio.Upload
+	reasons []string
 }
 
 var (
@@ -127,30 +129,40 @@ var (
 func (uf *uploadFilter) scoreUpload(upload *itchio.Upload, index int) *scoredUpload {
 	filename := strings.ToLower(upload.Filename)
 	var score int64 = 500 - int64(index)
+	reasons := []string{fmt.Sprintf("%d base score (listed at position %d)", score, index)}
 
 	if preferredFormatRegexp.MatchString(filename) {
 		// Preferred formats
 		score += 100
+		reasons = append(reasons, "+100 preferred format (.zip)")
 	} else if usuallySourceFormatRegexp.MatchString(filename) {
 		// Usually not what you want (usually set of sources on Linux)
 		score -= 100
+		reasons = append(reasons, "-100 usually a source archive (.tar.*)")
 	}
 
 	// We prefer things we can launch
 	if upload.Type == "default" {
 		score += 400
+		reasons = append(reasons, "+400 executable")
 	}
 
 	// Demos are penalized (if we have access to non-demo files)
 	if upload.Demo {
 		score -= 500
+		reasons = append(reasons, "-500 demo")
 	}
 
-	score += ExclusivityScore(upload.Platforms)
+	exclusivity := ExclusivityScore(upload.Platforms)
+	score += exclusivity
+	if exclusivity != 0 {
+		reasons = append(reasons, fmt.Sprintf("%+d platform exclusivity", exclusivity))
+	}
 
 	return &scoredUpload{
-		score:  score,
-		upload: upload,
+		score:   score,
+		upload:  upload,
+		reasons: reasons,
 	}
 }
 
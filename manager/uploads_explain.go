@@ -0,0 +1,99 @@
+package manager
+
+import (
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+)
+
+// UploadExclusion is the filter that removed an upload
+// from the list of compatible uploads.
+type UploadExclusion string
+
+const (
+	UploadExclusionPlatform UploadExclusion = "platform"
+	UploadExclusionFormat   UploadExclusion = "format"
+	UploadExclusionArch     UploadExclusion = "arch"
+)
+
+// UploadExplanation describes what NarrowDownUploads made of a single upload
+type UploadExplanation struct {
+	Upload *itchio.Upload
+
+	// Empty if the upload made it through all filters
+	ExcludedBy UploadExclusion
+
+	// Only set for uploads that weren't excluded. Higher is better.
+	Score        int64
+	ScoreReasons []string
+}
+
+type ExplainUploadsResult struct {
+	// Same as NarrowDownUploads would return
+	Narrowed *NarrowDownUploadsResult
+
+	// One entry per initial upload: compatible uploads first,
+	// highest score first, then excluded uploads.
+	Explanations []*UploadExplanation
+}
+
+// ExplainUploads runs the same filters and sorting as NarrowDownUploads,
+// but also reports why each upload was excluded, or how it was scored.
+func ExplainUploads(consumer *state.Consumer, game *itchio.Game, uploads []*itchio.Upload, runtimeEnum HostEnumerator) (*ExplainUploadsResult, error) {
+	runtimes, err := runtimeEnum.Enumerate(consumer)
+	if err != nil {
+		return nil, err
+	}
+
+	uf := &uploadFilter{
+		consumer: consumer,
+		runtimes: runtimes,
+		game:     game,
+	}
+
+	excludedBy := make(map[*itchio.Upload]UploadExclusion)
+	markExcluded := func(before []*itchio.Upload, after []*itchio.Upload, exclusion UploadExclusion) {
+		kept := make(map[*itchio.Upload]bool)
+		for _, u := range after {
+			kept[u] = true
+		}
+		for _, u := range before {
+			if !kept[u] {
+				excludedBy[u] = exclusion
+			}
+		}
+	}
+
+	platformUploads := uf.excludeWrongPlatform(uploads)
+	markExcluded(uploads, platformUploads, UploadExclusionPlatform)
+	formatUploads := uf.excludeWrongFormat(platformUploads)
+	markExcluded(platformUploads, formatUploads, UploadExclusionFormat)
+	archUploads := uf.excludeWrongArch(formatUploads)
+	markExcluded(formatUploads, archUploads, UploadExclusionArch)
+
+	res := &ExplainUploadsResult{
+		Narrowed: uf.narrowDownUploads(uploads),
+	}
+
+	scores := make(map[*itchio.Upload]*scoredUpload)
+	for index, u := range archUploads {
+		scores[u] = uf.scoreUpload(u, index)
+	}
+	for _, u := range res.Narrowed.Uploads {
+		su := scores[u]
+		res.Explanations = append(res.Explanations, &UploadExplanation{
+			Upload:       u,
+			Score:        su.score,
+			ScoreReasons: su.reasons,
+		})
+	}
+	for _, u := range uploads {
+		if exclusion, ok := excludedBy[u]; ok {
+			res.Explanations = append(res.Explanations, &UploadExplanation{
+				Upload:     u,
+				ExcludedBy: exclusion,
+			})
+		}
+	}
+
+	return res, nil
+}
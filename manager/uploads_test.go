@@ -353,3 +353,58 @@ func Test_NarrowDownUploads(t *testing.T) {
 		}, ndu(bothWindowsUploads, windows32), "do exclude 64-bit on 32-bit windows, if we have both")
 	}
 }
+
+func Test_ExplainUploads(t *testing.T) {
+	consumer := makeTestConsumer(t)
+
+	game := &itchio.Game{
+		Classification: itchio.GameClassificationGame,
+	}
+
+	linux64 := ox.Runtime{
+		Platform: ox.PlatformLinux,
+		Is64:     true,
+	}
+
+	windows := &itchio.Upload{
+		Platforms: itchio.Platforms{Windows: "all"},
+		Filename:  "windows.zip",
+		Type:      "default",
+	}
+	deb := &itchio.Upload{
+		Platforms: itchio.Platforms{Linux: "all"},
+		Filename:  "linux.deb",
+		Type:      "default",
+	}
+	sources := &itchio.Upload{
+		Platforms: itchio.Platforms{Linux: "all"},
+		Filename:  "sources.tar.gz",
+		Type:      "default",
+	}
+	linux := &itchio.Upload{
+		Platforms: itchio.Platforms{Linux: "all"},
+		Filename:  "linux.zip",
+		Type:      "default",
+	}
+	uploads := []*itchio.Upload{windows, deb, sources, linux}
+
+	res, err := manager.ExplainUploads(consumer, game, uploads, manager.SingleHostEnumerator(linux64))
+	wtest.Must(t, err)
+
+	narrowed, err := manager.NarrowDownUploads(consumer, game, uploads, manager.SingleHostEnumerator(linux64))
+	wtest.Must(t, err)
+	assert.EqualValues(t, narrowed, res.Narrowed)
+
+	assert.Len(t, res.Explanations, 4)
+
+	assert.EqualValues(t, linux, res.Explanations[0].Upload)
+	assert.EqualValues(t, manager.UploadExclusion(""), res.Explanations[0].ExcludedBy)
+	assert.EqualValues(t, sources, res.Explanations[1].Upload)
+	assert.True(t, res.Explanations[0].Score > res.Explanations[1].Score)
+	assert.NotEmpty(t, res.Explanations[1].ScoreReasons)
+
+	assert.EqualValues(t, windows, res.Explanations[2].Upload)
+	assert.EqualValues(t, manager.UploadExclusionPlatform, res.Explanations[2].ExcludedBy)
+	assert.EqualValues(t, deb, res.Explanations[3].Upload)
+	assert.EqualValues(t, manager.UploadExclusionFormat, res.Explanations[3].ExcludedBy)
+}
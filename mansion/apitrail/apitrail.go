@@ -0,0 +1,177 @@
+// Package apitrail records the itch.io API calls made on behalf of an
+// operation (an install, for example), so that when it fails, support
+// can see what was asked and what the server answered.
+//
+// A Trail is attached to a context with WithTrail. The transport returned
+// by Wrap sits in the HTTP transport chain, and records calls whose request
+// context has a trail. Calls without a trail are not looked at.
+//
+// Everything is scrubbed before it is recorded (see Scrubber),
+// and only the last few calls are kept. Trails only live in memory: it's
+// up to the operation to persist it if it fails, and to drop it otherwise.
+package apitrail
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultCapacity is how many calls a trail keeps, unless specified otherwise
+const DefaultCapacity = 50
+
+// Call is a scrubbed record of a single API call
+type Call struct {
+	StartedAt time.Time
+	Method    string
+	// Scrubbed URL, see Scrubber.URL
+	URL string
+	// HTTP status, 0 if no response was received
+	Status int
+	// Time until the whole response was received
+	Latency time.Duration
+	// Set if no response was received
+	Error string
+	// Scrubbed and truncated response body, see Scrubber.Body
+	Body string
+	// True if Body was cut short
+	BodyTruncated bool
+}
+
+// Trail is a ring buffer of calls. It is safe for concurrent use.
+type Trail struct {
+	mu       sync.Mutex
+	calls    []Call
+	next     int
+	full     bool
+	recorded int64
+}
+
+// New returns an empty trail keeping the last capacity calls
+func New(capacity int) *Trail {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Trail{
+		calls: make([]Call, capacity),
+	}
+}
+
+func (t *Trail) record(c Call) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.calls[t.next] = c
+	t.next = (t.next + 1) % len(t.calls)
+	if t.next == 0 {
+		t.full = true
+	}
+	t.recorded++
+}
+
+// Calls returns the calls still in the trail, oldest first
+func (t *Trail) Calls() []Call {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.full {
+		return append([]Call(nil), t.calls[:t.next]...)
+	}
+	res := append([]Call(nil), t.calls[t.next:]...)
+	return append(res, t.calls[:t.next]...)
+}
+
+// Dropped returns how many calls were pushed out of the trail by newer ones
+func (t *Trail) Dropped() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.full {
+		return 0
+	}
+	return t.recorded - int64(len(t.calls))
+}
+
+type trailKey struct{}
+
+// WithTrail returns a context whose API calls will be recorded in t
+func WithTrail(ctx context.Context, t *Trail) context.Context {
+	return context.WithValue(ctx, trailKey{}, t)
+}
+
+// FromContext returns the trail set by WithTrail, or nil
+func FromContext(ctx context.Context) *Trail {
+	if t, ok := ctx.Value(trailKey{}).(*Trail); ok {
+		return t
+	}
+	return nil
+}
+
+// Wrap returns a transport that records calls made through next
+// in the trail of their request's context, if any.
+func Wrap(next http.RoundTripper) http.RoundTripper {
+	return &transport{next: next}
+}
+
+type transport struct {
+	next http.RoundTripper
+}
+
+var _ http.RoundTripper = (*transport)(nil)
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	trail := FromContext(req.Context())
+	if trail == nil {
+		return t.next.RoundTrip(req)
+	}
+
+	s := scrubberFor(req)
+	call := Call{
+		StartedAt: time.Now(),
+		Method:    req.Method,
+		URL:       s.URL(req.URL),
+	}
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		call.Latency = time.Since(call.StartedAt)
+		call.Error = s.Text(err.Error())
+		trail.record(call)
+		return nil, err
+	}
+	call.Status = res.StatusCode
+
+	// API responses are small, so we can afford to read them
+	// whole and hand the caller a copy.
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	call.Latency = time.Since(call.StartedAt)
+	if err != nil {
+		call.Error = s.Text(err.Error())
+		trail.record(call)
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	call.Body, call.BodyTruncated = Truncate(s.Body(body), MaxBodyLength)
+
+	trail.record(call)
+	return res, nil
+}
+
+// scrubberFor returns a scrubber that knows about the
+// credentials sent along with req.
+func scrubberFor(req *http.Request) *Scrubber {
+	s := &Scrubber{}
+	s.AddSecret(req.Header.Get("Authorization"))
+	for name, values := range req.URL.Query() {
+		if isSensitiveName(name) {
+			for _, v := range values {
+				s.AddSecret(v)
+			}
+		}
+	}
+	return s
+}
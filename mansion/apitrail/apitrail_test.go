@@ -0,0 +1,93 @@
+package apitrail_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/itchio/butler/mansion/apitrail"
+	"github.com/stretchr/testify/assert"
+)
+
+func get(t *testing.T, client *http.Client, ctx context.Context, url string) string {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", apiKey)
+
+	res, err := client.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	assert.NoError(t, err)
+	return string(body)
+}
+
+func Test_Trail(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"errors":["invalid key %s"]}`, r.Header.Get("Authorization"))
+			return
+		}
+		fmt.Fprintf(w, `{"game":{"id":123,"title":"Hello"}}`)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{
+		Transport: apitrail.Wrap(http.DefaultTransport),
+	}
+
+	// nothing is recorded without a trail
+	trail := apitrail.New(3)
+	get(t, client, context.Background(), srv.URL+"/games/123")
+	assert.Len(t, trail.Calls(), 0)
+
+	ctx := apitrail.WithTrail(context.Background(), trail)
+	body := get(t, client, ctx, srv.URL+"/missing?api_key="+apiKey)
+	assert.Contains(t, body, apiKey, "callers get the untouched body")
+
+	calls := trail.Calls()
+	assert.Len(t, calls, 1)
+	call := calls[0]
+	assert.EqualValues(t, "GET", call.Method)
+	assert.EqualValues(t, srv.URL+"/missing?api_key=[scrubbed]", call.URL)
+	assert.EqualValues(t, http.StatusBadRequest, call.Status)
+	assert.EqualValues(t, `{"errors":["invalid key [scrubbed]"]}`, call.Body)
+	assert.False(t, call.BodyTruncated)
+
+	for i := 0; i < 4; i++ {
+		get(t, client, ctx, fmt.Sprintf("%s/games/%d", srv.URL, i))
+	}
+	calls = trail.Calls()
+	assert.Len(t, calls, 3)
+	assert.EqualValues(t, 2, trail.Dropped())
+	for i, call := range calls {
+		assert.EqualValues(t, fmt.Sprintf("%s/games/%d", srv.URL, i+1), call.URL, "oldest first")
+		assert.EqualValues(t, http.StatusOK, call.Status)
+	}
+}
+
+func Test_TrailTransportError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Close()
+
+	client := &http.Client{
+		Transport: apitrail.Wrap(http.DefaultTransport),
+	}
+	trail := apitrail.New(0)
+	ctx := apitrail.WithTrail(context.Background(), trail)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", srv.URL+"/profile", nil)
+	assert.NoError(t, err)
+	_, err = client.Do(req)
+	assert.Error(t, err)
+
+	calls := trail.Calls()
+	assert.Len(t, calls, 1)
+	assert.EqualValues(t, 0, calls[0].Status)
+	assert.NotEmpty(t, calls[0].Error)
+}
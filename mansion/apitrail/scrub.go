@@ -0,0 +1,183 @@
+package apitrail
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxBodyLength is how much of a (scrubbed) response body is kept, in bytes
+const MaxBodyLength = 4096
+
+const scrubbed = "[scrubbed]"
+
+var (
+	urlRegexp   = regexp.MustCompile(`https?://[^\s"'<>\\]+`)
+	emailRegexp = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+	// things like `api_key=abc` or `token: abc`, for bodies that aren't JSON
+	secretParamRegexp = regexp.MustCompile(`(?i)\b(api_?key|key|token|password|secret|jwt|authorization|signature|cookie)(["']?\s*[:=]\s*["']?)([^\s"'&,)]+)`)
+
+	// JSON fields and query parameters whose values are always scrubbed,
+	// matched against lowercase names with '_' and '-' removed
+	sensitiveNameParts = []string{"key", "token", "password", "secret", "jwt", "auth", "signature", "cookie", "email", "totp", "session", "csrf", "captcha"}
+	sensitiveNames     = []string{"sig", "code"}
+)
+
+func isSensitiveName(name string) bool {
+	name = strings.ToLower(name)
+	name = strings.NewReplacer("_", "", "-", "").Replace(name)
+	for _, s := range sensitiveNames {
+		if name == s {
+			return true
+		}
+	}
+	for _, part := range sensitiveNameParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// A Scrubber removes credentials and email addresses from what
+// we record of API calls.
+type Scrubber struct {
+	secrets []string
+}
+
+// AddSecret makes sure s never appears in scrubbed output
+func (s *Scrubber) AddSecret(secret string) {
+	// don't scrub every "a" if we somehow get a short secret
+	if len(secret) < 8 {
+		return
+	}
+	s.secrets = append(s.secrets, secret)
+}
+
+func (s *Scrubber) removeSecrets(text string) string {
+	for _, secret := range s.secrets {
+		text = strings.Replace(text, secret, scrubbed, -1)
+	}
+	return text
+}
+
+// URL returns u without user info, and with the values of
+// sensitive query parameters (api_key, download_key_id...) scrubbed.
+// The other parameters are kept, in order.
+func (s *Scrubber) URL(u *url.URL) string {
+	res := u.Scheme + "://" + u.Host + u.EscapedPath()
+	if u.RawQuery != "" {
+		var params []string
+		for _, param := range strings.Split(u.RawQuery, "&") {
+			name := param
+			if i := strings.Index(param, "="); i >= 0 {
+				name = param[:i]
+			}
+			if unescaped, err := url.QueryUnescape(name); err == nil {
+				name = unescaped
+			}
+			if isSensitiveName(name) {
+				param = url.QueryEscape(name) + "=" + scrubbed
+			}
+			params = append(params, param)
+		}
+		res += "?" + strings.Join(params, "&")
+	}
+	return s.removeSecrets(res)
+}
+
+// Text scrubs free-form text: known secrets, email addresses, credentials
+// that look like `key=value`, and URLs. URLs found in text are usually
+// download links, which may be signed: for hosts other than itch.io,
+// their path is scrubbed as well.
+func (s *Scrubber) Text(text string) string {
+	text = s.removeSecrets(text)
+	text = urlRegexp.ReplaceAllStringFunc(text, s.textURL)
+	text = emailRegexp.ReplaceAllString(text, scrubbed)
+	text = secretParamRegexp.ReplaceAllString(text, "${1}${2}"+scrubbed)
+	return text
+}
+
+func (s *Scrubber) textURL(text string) string {
+	u, err := url.Parse(text)
+	if err != nil || u.Host == "" {
+		return scrubbed
+	}
+
+	host := u.Hostname()
+	if host != "itch.io" && !strings.HasSuffix(host, ".itch.io") {
+		if u.Path != "" && u.Path != "/" {
+			u.Path = "/" + scrubbed
+			u.RawPath = u.Path
+		}
+	}
+	return s.URL(u)
+}
+
+// Body scrubs a response body. If it's JSON, the values of sensitive
+// fields (keys, tokens, cookies, emails...) are replaced wholesale, and
+// all other strings are scrubbed like Text. Otherwise, the whole body
+// is scrubbed like Text.
+func (s *Scrubber) Body(body []byte) string {
+	var doc interface{}
+	err := json.Unmarshal(body, &doc)
+	if err != nil {
+		return s.Text(string(body))
+	}
+
+	doc = s.jsonValue(doc)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	err = enc.Encode(doc)
+	if err != nil {
+		return s.Text(string(body))
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func (s *Scrubber) jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for name, value := range v {
+			if isSensitiveName(name) {
+				switch value.(type) {
+				case nil, bool:
+					// can't leak anything
+				default:
+					value = scrubbed
+				}
+				v[name] = value
+				continue
+			}
+			v[name] = s.jsonValue(value)
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = s.jsonValue(value)
+		}
+		return v
+	case string:
+		return s.Text(v)
+	}
+	return v
+}
+
+// Truncate cuts s to at most max bytes, without splitting characters.
+// truncated is true if anything was cut.
+func Truncate(s string, max int) (res string, truncated bool) {
+	if len(s) <= max {
+		return s, false
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut], true
+}
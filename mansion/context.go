@@ -8,6 +8,8 @@ import (
 
 	"github.com/itchio/butler/buildinfo"
 	"github.com/itchio/butler/comm"
+	"github.com/itchio/butler/mansion/apitrail"
+	"github.com/itchio/butler/mansion/ratelimit"
 	itchio "github.com/itchio/go-itchio"
 	"github.com/itchio/httpkit/timeout"
 	"github.com/itchio/wharf/pwr"
@@ -46,6 +48,11 @@ type Context struct {
 	HTTPClient    *http.Client
 	HTTPTransport *http.Transport
 
+	// apiHTTPClient is HTTPClient, but with API calls going through
+	// the process-wide rate limiter, and recorded in the operation's
+	// trail if any (see apitrail)
+	apiHTTPClient *http.Client
+
 	// url of the itch.io API server we're talking to
 	apiAddress string
 	// url of the itch.io web instance we're talking to
@@ -68,6 +75,10 @@ func NewContext(app *kingpin.Application) *Context {
 		Context:           ctx,
 	}
 
+	apiHTTPClient := *client
+	apiHTTPClient.Transport = ratelimit.Default().Wrap(apitrail.Wrap(client.Transport))
+	ctx.apiHTTPClient = &apiHTTPClient
+
 	return ctx
 }
 
@@ -125,7 +136,7 @@ func (ctx *Context) DefaultCtx() context.Context {
 
 func (ctx *Context) NewClient(key string) *itchio.Client {
 	client := itchio.ClientWithKey(key)
-	client.HTTPClient = ctx.HTTPClient
+	client.HTTPClient = ctx.apiHTTPClient
 	client.SetServer(ctx.APIAddress())
 	client.UserAgent = ctx.UserAgent()
 	return client
@@ -0,0 +1,430 @@
+// Package ratelimit implements a process-wide, server-driven rate limiter
+// for itch.io API calls. It sits in the HTTP transport chain, so every
+// itchio.Client created by mansion shares it.
+//
+// The limiter learns from the server: it honors Retry-After on 429
+// responses and tracks the X-RateLimit-* headers, delaying outgoing calls
+// when we get close to the limit. Calls are classified as interactive or
+// background (see WithClass): background calls yield to interactive ones,
+// and get spaced out further every time the server pushes back.
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Class indicates how urgent an API call is
+type Class int
+
+const (
+	// ClassInteractive is for calls someone is actively waiting on.
+	// It is the default.
+	ClassInteractive Class = iota
+	// ClassBackground is for calls that can be delayed, like update
+	// checks and collection syncs.
+	ClassBackground
+)
+
+func (c Class) String() string {
+	switch c {
+	case ClassInteractive:
+		return "interactive"
+	case ClassBackground:
+		return "background"
+	}
+	return "unknown"
+}
+
+type classKey struct{}
+
+// WithClass returns a context whose API calls will be scheduled
+// with the given class.
+func WithClass(ctx context.Context, class Class) context.Context {
+	return context.WithValue(ctx, classKey{}, class)
+}
+
+// ClassFromContext returns the class set by WithClass, or ClassInteractive
+func ClassFromContext(ctx context.Context) Class {
+	if class, ok := ctx.Value(classKey{}).(Class); ok {
+		return class
+	}
+	return ClassInteractive
+}
+
+const (
+	// how many retries after a 429, per class
+	maxInteractiveRetries = 6
+	maxBackgroundRetries  = 12
+
+	// used when a 429 doesn't come with a (valid) Retry-After
+	defaultRetryAfter = 1 * time.Second
+	// we never trust a Retry-After longer than that
+	maxRetryAfter = 2 * time.Minute
+
+	// when the server tells us we have that many calls left (or fewer)
+	// in the current window, background calls wait for the next window,
+	// so interactive calls can still go through.
+	backgroundReserve = 3
+
+	// every 429 doubles the spacing between background calls, up to
+	// maxBackgroundSpacing. every successful call halves it.
+	minBackgroundSpacing = 250 * time.Millisecond
+	maxBackgroundSpacing = 30 * time.Second
+
+	// how often background calls check whether interactive ones are done
+	yieldInterval = 50 * time.Millisecond
+)
+
+// Stats is a snapshot of the limiter's state
+type Stats struct {
+	// Total number of requests sent
+	Requests int64
+	// Number of 429 responses received
+	RateLimited int64
+	// Number of requests that were retried after a 429
+	Retries int64
+	// Number of 429 responses that were passed on to the caller,
+	// because they could not be retried
+	Failures int64
+
+	// Calls currently waiting for their turn, per class
+	WaitingInteractive int64
+	WaitingBackground  int64
+
+	// Last limit & remaining count reported by the server, -1 if unknown
+	Limit     int64
+	Remaining int64
+	// When the server's rate limit window resets, if known
+	ResetAt *time.Time
+
+	// If set, no calls are sent until then (from Retry-After)
+	PausedUntil *time.Time
+	// Current minimum delay between two background calls
+	BackgroundSpacing time.Duration
+}
+
+// Limiter schedules outgoing API calls. It is safe for concurrent use.
+type Limiter struct {
+	now func() time.Time
+
+	mu sync.Mutex
+
+	requests    int64
+	rateLimited int64
+	retries     int64
+	failures    int64
+
+	waitingInteractive int64
+	waitingBackground  int64
+
+	limit     int64
+	remaining int64
+	resetAt   time.Time
+
+	pausedUntil       time.Time
+	backgroundSpacing time.Duration
+	lastBackground    time.Time
+	lastThrottled     time.Time
+}
+
+// New returns a fresh limiter. Most callers want Default instead.
+func New() *Limiter {
+	return &Limiter{
+		now:       time.Now,
+		limit:     -1,
+		remaining: -1,
+	}
+}
+
+var defaultLimiter = New()
+
+// Default returns the limiter shared by the whole process
+func Default() *Limiter {
+	return defaultLimiter
+}
+
+// Stats returns a snapshot of the current state of the limiter
+func (l *Limiter) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	s := Stats{
+		Requests:           l.requests,
+		RateLimited:        l.rateLimited,
+		Retries:            l.retries,
+		Failures:           l.failures,
+		WaitingInteractive: l.waitingInteractive,
+		WaitingBackground:  l.waitingBackground,
+		Limit:              l.limit,
+		Remaining:          l.remaining,
+		BackgroundSpacing:  l.backgroundSpacing,
+	}
+	if l.resetAt.After(now) {
+		resetAt := l.resetAt
+		s.ResetAt = &resetAt
+	}
+	if l.pausedUntil.After(now) {
+		pausedUntil := l.pausedUntil
+		s.PausedUntil = &pausedUntil
+	}
+	return s
+}
+
+// Wrap returns a transport that schedules requests through the limiter,
+// and transparently retries them when the server responds with 429.
+func (l *Limiter) Wrap(next http.RoundTripper) http.RoundTripper {
+	return &transport{limiter: l, next: next}
+}
+
+type transport struct {
+	limiter *Limiter
+	next    http.RoundTripper
+}
+
+var _ http.RoundTripper = (*transport)(nil)
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	l := t.limiter
+	ctx := req.Context()
+	class := ClassFromContext(ctx)
+
+	maxRetries := maxInteractiveRetries
+	if class == ClassBackground {
+		maxRetries = maxBackgroundRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := l.wait(ctx, class)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := t.next.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		throttled := l.observe(res)
+		if !throttled {
+			return res, nil
+		}
+
+		if attempt >= maxRetries || !canRewind(req) {
+			l.mu.Lock()
+			l.failures++
+			l.mu.Unlock()
+			return res, nil
+		}
+
+		// we're retrying, so nobody is going to read that body
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+
+		req, err = rewind(req)
+		if err != nil {
+			return nil, err
+		}
+
+		l.mu.Lock()
+		l.retries++
+		l.mu.Unlock()
+	}
+}
+
+// wait blocks until a call of the given class is allowed to go through,
+// or ctx is done.
+func (l *Limiter) wait(ctx context.Context, class Class) error {
+	l.mu.Lock()
+	waiting := &l.waitingInteractive
+	if class == ClassBackground {
+		waiting = &l.waitingBackground
+	}
+	*waiting++
+	defer func() {
+		*waiting--
+		l.mu.Unlock()
+	}()
+
+	for {
+		delay := l.delayLocked(class, l.now())
+		if delay <= 0 {
+			break
+		}
+
+		l.mu.Unlock()
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			l.mu.Lock()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		l.mu.Lock()
+	}
+
+	now := l.now()
+	l.requests++
+	if l.remaining > 0 {
+		// the server will tell us the real number in its response,
+		// but other calls may go out before that.
+		l.remaining--
+	}
+	if class == ClassBackground {
+		l.lastBackground = now
+	}
+	return nil
+}
+
+// delayLocked returns how long a call of the given class should
+// wait before going out. It must be called with l.mu held.
+func (l *Limiter) delayLocked(class Class, now time.Time) time.Duration {
+	if l.pausedUntil.After(now) {
+		return l.pausedUntil.Sub(now)
+	}
+
+	if class != ClassBackground {
+		return 0
+	}
+
+	if l.waitingInteractive > 0 {
+		return yieldInterval
+	}
+
+	if l.remaining >= 0 && l.remaining <= backgroundReserve && l.resetAt.After(now) {
+		return l.resetAt.Sub(now)
+	}
+
+	if l.backgroundSpacing > 0 {
+		next := l.lastBackground.Add(l.backgroundSpacing)
+		if next.After(now) {
+			return next.Sub(now)
+		}
+	}
+
+	return 0
+}
+
+// observe updates the limiter's state from a response, and returns
+// true if the server asked us to back off.
+func (l *Limiter) observe(res *http.Response) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	if limit, ok := parseHeaderInt(res.Header, "X-RateLimit-Limit"); ok {
+		l.limit = limit
+	}
+	if remaining, ok := parseHeaderInt(res.Header, "X-RateLimit-Remaining"); ok {
+		l.remaining = remaining
+	}
+	if reset, ok := parseHeaderInt(res.Header, "X-RateLimit-Reset"); ok {
+		l.resetAt = parseReset(reset, now)
+	}
+
+	if res.StatusCode != http.StatusTooManyRequests {
+		l.backgroundSpacing /= 2
+		if l.backgroundSpacing < minBackgroundSpacing {
+			l.backgroundSpacing = 0
+		}
+		return false
+	}
+
+	l.rateLimited++
+
+	retryAfter, ok := parseRetryAfter(res.Header.Get("Retry-After"), now)
+	if !ok {
+		retryAfter = defaultRetryAfter
+	}
+	if retryAfter > maxRetryAfter {
+		retryAfter = maxRetryAfter
+	}
+	pausedUntil := now.Add(retryAfter)
+	if pausedUntil.After(l.pausedUntil) {
+		l.pausedUntil = pausedUntil
+	}
+
+	// concurrent calls tend to get throttled together, only count
+	// that as one push back from the server.
+	if now.Sub(l.lastThrottled) >= l.backgroundSpacing {
+		if l.backgroundSpacing == 0 {
+			l.backgroundSpacing = minBackgroundSpacing
+		} else {
+			l.backgroundSpacing *= 2
+		}
+		if l.backgroundSpacing > maxBackgroundSpacing {
+			l.backgroundSpacing = maxBackgroundSpacing
+		}
+	}
+	l.lastThrottled = now
+	return true
+}
+
+func parseHeaderInt(header http.Header, name string) (int64, bool) {
+	value := header.Get(name)
+	if value == "" {
+		return 0, false
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || i < 0 {
+		return 0, false
+	}
+	return i, true
+}
+
+// X-RateLimit-Reset is either a number of seconds, or
+// a unix timestamp, depending on who you ask.
+func parseReset(reset int64, now time.Time) time.Time {
+	const oneYear = 365 * 24 * 60 * 60
+	if reset > oneYear {
+		return time.Unix(reset, 0)
+	}
+	return now.Add(time.Duration(reset) * time.Second)
+}
+
+// Retry-After is either a number of seconds, or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		d := date.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func canRewind(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	newReq := req.Clone(req.Context())
+	newReq.Body = body
+	return newReq, nil
+}
@@ -0,0 +1,184 @@
+package ratelimit_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/mansion/ratelimit"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/stretchr/testify/assert"
+)
+
+// every third request gets a 429
+func flakyServer(t *testing.T, hits *int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(hits, 1)
+		if n%3 == 0 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprintf(w, `{"errors":["too many requests"]}`)
+			return
+		}
+
+		if r.Method == "POST" {
+			assert.NoError(t, r.ParseForm())
+			assert.EqualValues(t, "bar", r.Form.Get("foo"))
+		}
+		fmt.Fprintf(w, `{"game":{"id":123,"title":"Hello"}}`)
+	}))
+}
+
+func Test_RetriesTooManyRequests(t *testing.T) {
+	var hits int64
+	srv := flakyServer(t, &hits)
+	defer srv.Close()
+
+	l := ratelimit.New()
+	client := itchio.ClientWithKey("key")
+	client.HTTPClient = &http.Client{Transport: l.Wrap(http.DefaultTransport)}
+	client.SetServer(srv.URL)
+
+	const numCalls = 30
+	classes := []ratelimit.Class{ratelimit.ClassInteractive, ratelimit.ClassBackground}
+
+	var wg sync.WaitGroup
+	var failures int64
+	for i := 0; i < numCalls; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx := ratelimit.WithClass(context.Background(), classes[i%2])
+			res, err := client.GetGame(ctx, itchio.GetGameParams{GameID: 123})
+			if err != nil || res.Game.ID != 123 {
+				t.Logf("call %d failed: %+v", i, err)
+				atomic.AddInt64(&failures, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 0, failures)
+
+	stats := l.Stats()
+	assert.True(t, stats.RateLimited > 0)
+	assert.EqualValues(t, stats.RateLimited, stats.Retries)
+	assert.EqualValues(t, 0, stats.Failures)
+	assert.EqualValues(t, numCalls+stats.Retries, stats.Requests)
+	assert.EqualValues(t, hits, stats.Requests)
+}
+
+func Test_RetriesPostBody(t *testing.T) {
+	var hits int64 = 2
+	srv := flakyServer(t, &hits)
+	defer srv.Close()
+
+	l := ratelimit.New()
+	httpClient := &http.Client{Transport: l.Wrap(http.DefaultTransport)}
+
+	// the first request gets a 429
+	req, err := http.NewRequest("POST", srv.URL, strings.NewReader("foo=bar"))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := httpClient.Do(req)
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.EqualValues(t, http.StatusOK, res.StatusCode)
+
+	stats := l.Stats()
+	assert.EqualValues(t, 1, stats.Retries)
+	assert.EqualValues(t, 2, stats.Requests)
+}
+
+func Test_HonorsRateLimitHeaders(t *testing.T) {
+	var hits int64
+	var order []ratelimit.Class
+	var orderLock sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&hits, 1)
+		orderLock.Lock()
+		if r.URL.Query().Get("class") == "background" {
+			order = append(order, ratelimit.ClassBackground)
+		} else {
+			order = append(order, ratelimit.ClassInteractive)
+		}
+		orderLock.Unlock()
+
+		w.Header().Set("X-RateLimit-Limit", "10")
+		if n == 1 {
+			// almost out of calls for the next second
+			w.Header().Set("X-RateLimit-Remaining", "1")
+			w.Header().Set("X-RateLimit-Reset", "1")
+		} else {
+			w.Header().Set("X-RateLimit-Remaining", "9")
+		}
+		fmt.Fprintf(w, `{}`)
+	}))
+	defer srv.Close()
+
+	l := ratelimit.New()
+	httpClient := &http.Client{Transport: l.Wrap(http.DefaultTransport)}
+
+	do := func(class ratelimit.Class) {
+		ctx := ratelimit.WithClass(context.Background(), class)
+		req, err := http.NewRequest("GET", srv.URL+"?class="+class.String(), nil)
+		assert.NoError(t, err)
+		res, err := httpClient.Do(req.WithContext(ctx))
+		assert.NoError(t, err)
+		res.Body.Close()
+	}
+
+	do(ratelimit.ClassInteractive)
+	stats := l.Stats()
+	assert.EqualValues(t, 10, stats.Limit)
+	assert.EqualValues(t, 1, stats.Remaining)
+	assert.NotNil(t, stats.ResetAt)
+
+	// background calls wait for the window to reset,
+	// interactive ones go through right away
+	var wg sync.WaitGroup
+	wg.Add(1)
+	startTime := time.Now()
+	go func() {
+		defer wg.Done()
+		do(ratelimit.ClassBackground)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	do(ratelimit.ClassInteractive)
+	wg.Wait()
+
+	assert.True(t, time.Since(startTime) > 500*time.Millisecond)
+	assert.EqualValues(t, []ratelimit.Class{
+		ratelimit.ClassInteractive,
+		ratelimit.ClassInteractive,
+		ratelimit.ClassBackground,
+	}, order)
+}
+
+func Test_GivesUpWhenContextIsDone(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	l := ratelimit.New()
+	httpClient := &http.Client{Transport: l.Wrap(http.DefaultTransport)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	assert.NoError(t, err)
+	_, err = httpClient.Do(req.WithContext(ctx))
+	assert.Error(t, err)
+
+	stats := l.Stats()
+	assert.EqualValues(t, 1, stats.RateLimited)
+	assert.NotNil(t, stats.PausedUntil)
+}
@@ -0,0 +1,343 @@
+// Package settings keeps track of daemon-wide settings, like the
+// bandwidth limit.
+//
+// Features register their settings (with a default value, which also
+// determines the type of the setting, and an optional validation func)
+// when their package is initialized. Values are persisted in the
+// `settings` table, and loaded once the database is ready, see Load.
+//
+// Components that need to react to changes subscribe to them, see
+// Subscribe, instead of polling.
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/hades"
+	"github.com/itchio/headway/state"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
+)
+
+// Setting describes a setting, see Register
+type Setting struct {
+	// Key of the setting, like `network.bandwidthLimit`
+	Key string
+
+	// Human-readable description, shown to clients
+	Description string
+
+	// Value used when the setting was never set. Its type
+	// is the type of all values of the setting.
+	Default interface{}
+
+	// Validate is called before a new value is accepted, if set
+	Validate func(value interface{}) error
+
+	// Transient settings are not persisted, and start over
+	// from their default value every time the daemon starts
+	Transient bool
+
+	typ reflect.Type
+}
+
+// Entry is the current state of a setting
+type Entry struct {
+	Setting *Setting
+	Value   interface{}
+}
+
+// IsDefault returns true if the setting has its default value
+func (e Entry) IsDefault() bool {
+	return reflect.DeepEqual(e.Value, e.Setting.Default)
+}
+
+// Listener is called with the new value of a setting
+type Listener func(value interface{})
+
+type subscription struct {
+	key string
+	f   Listener
+}
+
+var (
+	lock          sync.Mutex
+	registry      = make(map[string]*Setting)
+	values        = make(map[string]interface{})
+	subscriptions = make(map[int64]subscription)
+	subSeed       int64
+
+	// serializes changes, so that subscribers see them in the
+	// order they were persisted
+	setLock sync.Mutex
+)
+
+// ErrUnknownSetting is returned when a key was never registered
+var ErrUnknownSetting = errors.New("unknown setting")
+
+// Register adds a setting to the registry, and returns it.
+// It panics if the key is taken or if the default value is invalid,
+// since that's a programming error.
+func Register(s Setting) *Setting {
+	if s.Key == "" {
+		panic("settings: empty key")
+	}
+	if s.Default == nil {
+		panic(fmt.Sprintf("settings: no default value for %s", s.Key))
+	}
+	if s.Validate != nil {
+		if err := s.Validate(s.Default); err != nil {
+			panic(fmt.Sprintf("settings: invalid default value for %s: %v", s.Key, err))
+		}
+	}
+	s.typ = reflect.TypeOf(s.Default)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, ok := registry[s.Key]; ok {
+		panic(fmt.Sprintf("settings: %s registered twice", s.Key))
+	}
+	registry[s.Key] = &s
+	return &s
+}
+
+// Value returns the current value of s
+func (s *Setting) Value() interface{} {
+	lock.Lock()
+	defer lock.Unlock()
+
+	return valueLocked(s)
+}
+
+func valueLocked(s *Setting) interface{} {
+	if v, ok := values[s.Key]; ok {
+		return v
+	}
+	return s.Default
+}
+
+// Bool returns the current value of s, which must be a bool setting
+func (s *Setting) Bool() bool {
+	return s.Value().(bool)
+}
+
+// Int64 returns the current value of s, which must be an int64 setting
+func (s *Setting) Int64() int64 {
+	return s.Value().(int64)
+}
+
+// decode turns a JSON value into a value of the setting's type,
+// and validates it.
+func (s *Setting) decode(raw json.RawMessage) (interface{}, error) {
+	ptr := reflect.New(s.typ)
+	err := json.Unmarshal(raw, ptr.Interface())
+	if err != nil {
+		return nil, errors.Errorf("invalid value for %s: expected %s", s.Key, s.typ)
+	}
+	value := ptr.Elem().Interface()
+
+	if s.Validate != nil {
+		if err := s.Validate(value); err != nil {
+			return nil, errors.Wrapf(err, "invalid value for %s", s.Key)
+		}
+	}
+	return value, nil
+}
+
+// Get returns the current state of the setting named key
+func Get(key string) (Entry, error) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	s, ok := registry[key]
+	if !ok {
+		return Entry{}, errors.Wrap(ErrUnknownSetting, key)
+	}
+	return Entry{Setting: s, Value: valueLocked(s)}, nil
+}
+
+// List returns the current state of all settings, sorted by key
+func List() []Entry {
+	lock.Lock()
+	defer lock.Unlock()
+
+	var entries []Entry
+	for _, s := range registry {
+		entries = append(entries, Entry{Setting: s, Value: valueLocked(s)})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Setting.Key < entries[j].Setting.Key
+	})
+	return entries
+}
+
+// SetJSON decodes and validates a JSON value for the setting named key,
+// then sets it, see Set.
+func SetJSON(conn *sqlite.Conn, key string, raw json.RawMessage) (Entry, bool, error) {
+	lock.Lock()
+	s, ok := registry[key]
+	lock.Unlock()
+	if !ok {
+		return Entry{}, false, errors.Wrap(ErrUnknownSetting, key)
+	}
+
+	value, err := s.decode(raw)
+	if err != nil {
+		return Entry{}, false, err
+	}
+	return set(conn, s, value)
+}
+
+// Set changes the value of s, persisting it (unless s is transient)
+// and letting subscribers know. It returns the new state of the setting,
+// and whether its value actually changed. Setting a value equal to the
+// default removes the persisted value.
+func (s *Setting) Set(conn *sqlite.Conn, value interface{}) (Entry, bool, error) {
+	if reflect.TypeOf(value) != s.typ {
+		return Entry{}, false, errors.Errorf("invalid value for %s: expected %s, got %T", s.Key, s.typ, value)
+	}
+	if s.Validate != nil {
+		if err := s.Validate(value); err != nil {
+			return Entry{}, false, errors.Wrapf(err, "invalid value for %s", s.Key)
+		}
+	}
+	return set(conn, s, value)
+}
+
+func set(conn *sqlite.Conn, s *Setting, value interface{}) (Entry, bool, error) {
+	setLock.Lock()
+	defer setLock.Unlock()
+
+	lock.Lock()
+	changed := !reflect.DeepEqual(valueLocked(s), value)
+	lock.Unlock()
+
+	if !s.Transient {
+		err := persist(conn, s, value)
+		if err != nil {
+			return Entry{}, false, err
+		}
+	}
+
+	lock.Lock()
+	if reflect.DeepEqual(value, s.Default) {
+		delete(values, s.Key)
+	} else {
+		values[s.Key] = value
+	}
+	listeners := listenersLocked(s.Key)
+	lock.Unlock()
+
+	if changed {
+		for _, l := range listeners {
+			l(value)
+		}
+	}
+	return Entry{Setting: s, Value: value}, changed, nil
+}
+
+func persist(conn *sqlite.Conn, s *Setting, value interface{}) error {
+	if reflect.DeepEqual(value, s.Default) {
+		return models.Delete(conn, &models.Setting{}, builder.Eq{"id": s.Key})
+	}
+
+	var encoded models.JSON
+	err := models.MarshalJSON(value, &encoded)
+	if err != nil {
+		return err
+	}
+	return models.Save(conn, &models.Setting{ID: s.Key, Value: encoded})
+}
+
+// Load reads persisted values from the database, and lets subscribers
+// of settings that aren't at their default value know. Values that are
+// unknown or no longer valid are ignored.
+func Load(consumer *state.Consumer, conn *sqlite.Conn) error {
+	setLock.Lock()
+	defer setLock.Unlock()
+
+	var rows []*models.Setting
+	err := models.Select(conn, &rows, builder.NewCond(), hades.Search{})
+	if err != nil {
+		return err
+	}
+
+	type change struct {
+		value     interface{}
+		listeners []Listener
+	}
+	var changes []change
+
+	for _, row := range rows {
+		lock.Lock()
+		s, ok := registry[row.ID]
+		lock.Unlock()
+		if !ok || s.Transient {
+			consumer.Debugf("Ignoring stored value for unknown setting %s", row.ID)
+			continue
+		}
+
+		value, err := s.decode(json.RawMessage(row.Value))
+		if err != nil {
+			consumer.Warnf("Ignoring stored value of setting: %s", err.Error())
+			continue
+		}
+
+		lock.Lock()
+		if !reflect.DeepEqual(valueLocked(s), value) {
+			values[s.Key] = value
+			changes = append(changes, change{value, listenersLocked(s.Key)})
+		}
+		lock.Unlock()
+	}
+
+	for _, c := range changes {
+		for _, l := range c.listeners {
+			l(c.value)
+		}
+	}
+	return nil
+}
+
+// Subscribe calls f, from the goroutine that changed it, every time
+// the value of the setting named key changes. It doesn't call f with
+// the current value, and f must not change settings itself.
+// The returned func unsubscribes.
+func Subscribe(key string, f Listener) (unsubscribe func()) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	subSeed++
+	id := subSeed
+	subscriptions[id] = subscription{key: key, f: f}
+
+	return func() {
+		lock.Lock()
+		defer lock.Unlock()
+		delete(subscriptions, id)
+	}
+}
+
+func listenersLocked(key string) []Listener {
+	var ids []int64
+	for id, sub := range subscriptions {
+		if sub.key == key {
+			ids = append(ids, id)
+		}
+	}
+	// in subscription order
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var listeners []Listener
+	for _, id := range ids {
+		listeners = append(listeners, subscriptions[id].f)
+	}
+	return listeners
+}
@@ -0,0 +1,140 @@
+package settings
+
+import (
+	"encoding/json"
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/database"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/headway/state"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"xorm.io/builder"
+)
+
+var testLimit = Register(Setting{
+	Key:         "test.limit",
+	Description: "A limit",
+	Default:     int64(10),
+	Validate: func(value interface{}) error {
+		if value.(int64) > 100 {
+			return errors.New("too large")
+		}
+		return nil
+	},
+})
+
+var testToggle = Register(Setting{
+	Key:       "test.toggle",
+	Default:   false,
+	Transient: true,
+})
+
+func openTestDB(t *testing.T, name string) *sqlite.Conn {
+	conn, err := sqlite.OpenConn("file:"+name+"?mode=memory", 0)
+	must(t, err)
+	must(t, database.Prepare(&state.Consumer{}, conn, true))
+	return conn
+}
+
+func Test_SetAndSubscribe(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := openTestDB(t, "settings_set_test")
+	defer conn.Close()
+
+	var seen []interface{}
+	unsubscribe := Subscribe(testLimit.Key, func(value interface{}) {
+		seen = append(seen, value)
+	})
+	defer unsubscribe()
+
+	assert.EqualValues(10, testLimit.Int64())
+
+	entry, changed, err := testLimit.Set(conn, int64(42))
+	must(t, err)
+	assert.True(changed)
+	assert.False(entry.IsDefault())
+	assert.EqualValues(42, testLimit.Int64())
+
+	_, changed, err = SetJSON(conn, testLimit.Key, json.RawMessage(`42`))
+	must(t, err)
+	assert.False(changed, "same value")
+
+	_, _, err = SetJSON(conn, testLimit.Key, json.RawMessage(`"lots"`))
+	assert.Error(err, "wrong type")
+	_, _, err = testLimit.Set(conn, int64(500))
+	assert.Error(err, "fails validation")
+	_, _, err = SetJSON(conn, "test.nope", json.RawMessage(`1`))
+	assert.True(errors.Is(err, ErrUnknownSetting))
+	assert.EqualValues(42, testLimit.Int64())
+
+	assert.EqualValues([]interface{}{int64(42)}, seen)
+
+	var row models.Setting
+	ok, err := models.SelectOne(conn, &row, builder.Eq{"id": testLimit.Key})
+	must(t, err)
+	assert.True(ok)
+	assert.EqualValues(`42`, row.Value)
+
+	// back to default: nothing to persist
+	_, _, err = testLimit.Set(conn, int64(10))
+	must(t, err)
+	ok, err = models.SelectOne(conn, &row, builder.Eq{"id": testLimit.Key})
+	must(t, err)
+	assert.False(ok)
+	assert.EqualValues([]interface{}{int64(42), int64(10)}, seen)
+
+	unsubscribe()
+	_, _, err = testLimit.Set(conn, int64(20))
+	must(t, err)
+	assert.Len(seen, 2, "unsubscribed")
+	_, _, err = testLimit.Set(conn, int64(10))
+	must(t, err)
+}
+
+func Test_Load(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := openTestDB(t, "settings_load_test")
+	defer conn.Close()
+
+	_, _, err := testLimit.Set(conn, int64(64))
+	must(t, err)
+	_, _, err = testToggle.Set(conn, true)
+	must(t, err)
+	must(t, models.Save(conn, &models.Setting{ID: "test.gone", Value: `1`}))
+
+	// as if the daemon just started
+	lock.Lock()
+	values = make(map[string]interface{})
+	lock.Unlock()
+
+	var loaded interface{}
+	unsubscribe := Subscribe(testLimit.Key, func(value interface{}) {
+		loaded = value
+	})
+	defer unsubscribe()
+
+	must(t, Load(&state.Consumer{}, conn))
+	assert.EqualValues(int64(64), loaded)
+	assert.EqualValues(64, testLimit.Int64())
+	assert.False(testToggle.Bool(), "transient settings aren't persisted")
+
+	entries := List()
+	var keys []string
+	for _, e := range entries {
+		keys = append(keys, e.Setting.Key)
+	}
+	assert.EqualValues([]string{"test.limit", "test.toggle"}, keys)
+
+	_, _, err = testLimit.Set(conn, int64(10))
+	must(t, err)
+}
+
+func must(t *testing.T, err error) {
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
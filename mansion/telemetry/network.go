@@ -0,0 +1,112 @@
+package telemetry
+
+import (
+	"net"
+	"time"
+)
+
+// NetworkSampleInterval is how often the connection of an active
+// download is looked at, see StartSampling.
+const NetworkSampleInterval = 2 * time.Second
+
+// NetworkStats is what we know of the connection a download
+// is currently going through.
+type NetworkStats struct {
+	SampledAt  time.Time
+	LocalAddr  string
+	RemoteAddr string
+
+	// The fields below are only set if TCPInfo is true,
+	// which requires OS support (Linux only for now).
+	TCPInfo bool
+	// Receive window, in bytes
+	TCPWindowSize int64
+	// Bytes sent but not acknowledged yet
+	BytesInFlight int64
+	// Segments retransmitted over the lifetime of the connection
+	RetransmitCount int64
+	// Smoothed round-trip time
+	RTT time.Duration
+}
+
+func (t *DownloadTelemetry) setConn(conn net.Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.conn = conn
+}
+
+// StartSampling samples the network stats of the download's current
+// connection every NetworkSampleInterval, and its transfer speed every
+// SpeedSampleInterval, until stop is called. Stopping forgets the
+// network stats, but not the speed samples.
+func (t *DownloadTelemetry) StartSampling() (stop func()) {
+	t.resetSpeedBaseline()
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(NetworkSampleInterval)
+		defer ticker.Stop()
+		speedTicker := time.NewTicker(SpeedSampleInterval)
+		defer speedTicker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.SampleNetwork()
+			case <-speedTicker.C:
+				t.SampleSpeed()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.conn = nil
+		t.networkStats = nil
+	}
+}
+
+// SampleNetwork records the network stats of the download's
+// current connection, if it has one.
+func (t *DownloadTelemetry) SampleNetwork() {
+	t.mu.Lock()
+	conn := t.conn
+	t.mu.Unlock()
+	if conn == nil {
+		return
+	}
+
+	stats := &NetworkStats{
+		SampledAt:  time.Now(),
+		LocalAddr:  conn.LocalAddr().String(),
+		RemoteAddr: conn.RemoteAddr().String(),
+	}
+	// TLS connections wrap the TCP one
+	if nc, ok := conn.(interface{ NetConn() net.Conn }); ok {
+		conn = nc.NetConn()
+	}
+	stats.TCPInfo = readTCPInfo(conn, stats)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.networkStats = stats
+}
+
+// NetworkStats returns the last network stats sampled, or
+// nil if there's none.
+func (t *DownloadTelemetry) NetworkStats() *NetworkStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.networkStats == nil {
+		return nil
+	}
+	stats := *t.networkStats
+	return &stats
+}
@@ -0,0 +1,96 @@
+package telemetry
+
+import (
+	"time"
+)
+
+const (
+	// SpeedSampleInterval is how often the transfer speed of an
+	// active download is sampled, see StartSampling.
+	SpeedSampleInterval = time.Second
+
+	// MaxSpeedSamples is how many speed samples are kept per download
+	MaxSpeedSamples = 300
+)
+
+// SpeedSample is how fast a download went since the previous sample
+type SpeedSample struct {
+	Timestamp      time.Time
+	BytesPerSecond int64
+}
+
+// speedHistory is a ring buffer of the last MaxSpeedSamples samples.
+// It's a fixed-size array so sampling never allocates.
+type speedHistory struct {
+	samples [MaxSpeedSamples]SpeedSample
+	next    int
+	full    bool
+
+	// what the counter was at the previous sample
+	lastBytes int64
+	lastTime  time.Time
+}
+
+// AddBytes counts n more bytes transferred. Response bodies read
+// through Client are counted already.
+func (t *DownloadTelemetry) AddBytes(n int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.bytesTransferred += n
+}
+
+// SampleSpeed records how fast the download went since the
+// previous sample.
+func (t *DownloadTelemetry) SampleSpeed() {
+	t.sampleSpeedAt(time.Now())
+}
+
+func (t *DownloadTelemetry) sampleSpeedAt(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sh := &t.speed
+	if !sh.lastTime.IsZero() {
+		elapsed := now.Sub(sh.lastTime).Seconds()
+		if elapsed <= 0 {
+			return
+		}
+		sh.samples[sh.next] = SpeedSample{
+			Timestamp:      now,
+			BytesPerSecond: int64(float64(t.bytesTransferred-sh.lastBytes) / elapsed),
+		}
+		sh.next = (sh.next + 1) % len(sh.samples)
+		if sh.next == 0 {
+			sh.full = true
+		}
+	}
+	sh.lastBytes = t.bytesTransferred
+	sh.lastTime = now
+}
+
+// resetSpeedBaseline makes the next sample only count bytes
+// transferred from now on, so time spent not driving the download
+// doesn't show up as a slow sample.
+func (t *DownloadTelemetry) resetSpeedBaseline() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.speed.lastBytes = t.bytesTransferred
+	t.speed.lastTime = time.Now()
+}
+
+// SpeedSamples returns the last n speed samples (or fewer), oldest first
+func (t *DownloadTelemetry) SpeedSamples(n int) []SpeedSample {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sh := &t.speed
+	var res []SpeedSample
+	if sh.full {
+		res = append(res, sh.samples[sh.next:]...)
+	}
+	res = append(res, sh.samples[:sh.next]...)
+	if n < len(res) {
+		res = res[len(res)-n:]
+	}
+	return res
+}
@@ -0,0 +1,35 @@
+package telemetry
+
+import (
+	"net"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func readTCPInfo(conn net.Conn, stats *NetworkStats) bool {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return false
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return false
+	}
+
+	var info *unix.TCPInfo
+	var infoErr error
+	err = raw.Control(func(fd uintptr) {
+		info, infoErr = unix.GetsockoptTCPInfo(int(fd), unix.IPPROTO_TCP, unix.TCP_INFO)
+	})
+	if err != nil || infoErr != nil {
+		return false
+	}
+
+	stats.TCPWindowSize = int64(info.Rcv_space)
+	stats.BytesInFlight = int64(info.Unacked) * int64(info.Snd_mss)
+	stats.RetransmitCount = int64(info.Total_retrans)
+	stats.RTT = time.Duration(info.Rtt) * time.Microsecond
+	return true
+}
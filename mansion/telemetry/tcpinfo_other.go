@@ -0,0 +1,9 @@
+// +build !linux
+
+package telemetry
+
+import "net"
+
+func readTCPInfo(conn net.Conn, stats *NetworkStats) bool {
+	return false
+}
@@ -0,0 +1,345 @@
+// Package telemetry measures how downloads perform, one chunk at a time,
+// so we can tell a slow server from a flaky connection.
+//
+// A DownloadTelemetry wraps the HTTP client used to fetch a download's
+// install source (see Client). Every response body read through it is
+// cut into ChunkSize chunks, and each chunk is recorded along with what
+// we know of the request that carried it: which server answered, how
+// long the TLS handshake took, and how long until the first byte.
+package telemetry
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+const (
+	// ChunkSize is how many bytes each chunk record covers
+	ChunkSize = 1024 * 1024
+
+	// MaxChunks is how many chunk records are kept per download
+	MaxChunks = 1000
+
+	// MinJitterChunks is how many full chunks we need before jitter means anything
+	MinJitterChunks = 10
+
+	// HighJitter is the jitter above which we warn about connection quality
+	HighJitter = 0.5
+)
+
+// Chunk is what we know of the transfer of (at most) ChunkSize bytes
+type Chunk struct {
+	StartTime        time.Time
+	EndTime          time.Time
+	BytesTransferred int64
+
+	// Of the request that carried this chunk
+	ServerIP     string
+	TLSHandshake time.Duration
+	TTFB         time.Duration
+}
+
+// rate returns the transfer rate of the chunk in bytes per second
+func (c Chunk) rate() float64 {
+	d := c.EndTime.Sub(c.StartTime).Seconds()
+	if d <= 0 {
+		return 0
+	}
+	return float64(c.BytesTransferred) / d
+}
+
+// DownloadTelemetry keeps the last MaxChunks chunk records
+// of a download. It is safe for concurrent use.
+type DownloadTelemetry struct {
+	mu           sync.Mutex
+	chunks       []Chunk
+	next         int
+	full         bool
+	jitterHigh   bool
+	onHighJitter func(jitter float64)
+
+	// see StartSampling
+	conn         net.Conn
+	networkStats *NetworkStats
+
+	// see AddBytes and SampleSpeed
+	bytesTransferred int64
+	speed            speedHistory
+}
+
+// New returns a DownloadTelemetry with no chunks recorded
+func New() *DownloadTelemetry {
+	return &DownloadTelemetry{
+		chunks: make([]Chunk, MaxChunks),
+	}
+}
+
+// SetOnHighJitter sets a function to call when jitter goes above
+// HighJitter. It isn't called again until jitter has gone back down.
+func (t *DownloadTelemetry) SetOnHighJitter(f func(jitter float64)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onHighJitter = f
+}
+
+func (t *DownloadTelemetry) record(c Chunk) {
+	t.mu.Lock()
+	t.chunks[t.next] = c
+	t.next = (t.next + 1) % len(t.chunks)
+	if t.next == 0 {
+		t.full = true
+	}
+
+	jitter, ok := t.jitterLocked()
+	notify := false
+	if ok && jitter > HighJitter {
+		notify = !t.jitterHigh
+		t.jitterHigh = true
+	} else {
+		t.jitterHigh = false
+	}
+	onHighJitter := t.onHighJitter
+	t.mu.Unlock()
+
+	if notify && onHighJitter != nil {
+		onHighJitter(jitter)
+	}
+}
+
+// Chunks returns the chunk records, oldest first
+func (t *DownloadTelemetry) Chunks() []Chunk {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.chunksLocked()
+}
+
+func (t *DownloadTelemetry) chunksLocked() []Chunk {
+	if !t.full {
+		return append([]Chunk(nil), t.chunks[:t.next]...)
+	}
+	res := append([]Chunk(nil), t.chunks[t.next:]...)
+	return append(res, t.chunks[:t.next]...)
+}
+
+// Jitter returns the standard deviation of the transfer rates of
+// recorded chunks, relative to their mean: 0.5 means rates typically
+// stray 50% from the average. Only full chunks are taken into account,
+// and ok is false until there's at least MinJitterChunks of them.
+func (t *DownloadTelemetry) Jitter() (jitter float64, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.jitterLocked()
+}
+
+func (t *DownloadTelemetry) jitterLocked() (float64, bool) {
+	var rates []float64
+	for _, c := range t.chunksLocked() {
+		if c.BytesTransferred == ChunkSize {
+			rates = append(rates, c.rate())
+		}
+	}
+	return relativeStdDev(rates)
+}
+
+func relativeStdDev(values []float64) (float64, bool) {
+	if len(values) < MinJitterChunks {
+		return 0, false
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	if mean == 0 {
+		return 0, false
+	}
+
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float64(len(values))
+	return math.Sqrt(variance) / mean, true
+}
+
+// Client returns a copy of base whose responses are measured.
+func (t *DownloadTelemetry) Client(base *http.Client) *http.Client {
+	client := *base
+	next := base.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	client.Transport = &transport{telemetry: t, next: next}
+	return &client
+}
+
+type telemetryKey struct{}
+
+// WithTelemetry returns a context for an operation whose
+// downloads should be recorded in t.
+func WithTelemetry(ctx context.Context, t *DownloadTelemetry) context.Context {
+	return context.WithValue(ctx, telemetryKey{}, t)
+}
+
+// FromContext returns the telemetry set by WithTelemetry, or nil
+func FromContext(ctx context.Context) *DownloadTelemetry {
+	if t, ok := ctx.Value(telemetryKey{}).(*DownloadTelemetry); ok {
+		return t
+	}
+	return nil
+}
+
+type transport struct {
+	telemetry *DownloadTelemetry
+	next      http.RoundTripper
+}
+
+var _ http.RoundTripper = (*transport)(nil)
+
+// what we learn about a request while it's in flight
+type requestInfo struct {
+	mu           sync.Mutex
+	start        time.Time
+	serverIP     string
+	tlsStart     time.Time
+	tlsHandshake time.Duration
+	ttfb         time.Duration
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	info := &requestInfo{start: time.Now()}
+	trace := &httptrace.ClientTrace{
+		GotConn: func(gci httptrace.GotConnInfo) {
+			t.telemetry.setConn(gci.Conn)
+			if addr, ok := gci.Conn.RemoteAddr().(*net.TCPAddr); ok {
+				info.mu.Lock()
+				info.serverIP = addr.IP.String()
+				info.mu.Unlock()
+			}
+		},
+		TLSHandshakeStart: func() {
+			info.mu.Lock()
+			info.tlsStart = time.Now()
+			info.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			info.mu.Lock()
+			info.tlsHandshake = time.Since(info.tlsStart)
+			info.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			info.mu.Lock()
+			info.ttfb = time.Since(info.start)
+			info.mu.Unlock()
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		// redirects and errors aren't part of the download
+		return res, nil
+	}
+
+	info.mu.Lock()
+	template := Chunk{
+		ServerIP:     info.serverIP,
+		TLSHandshake: info.tlsHandshake,
+		TTFB:         info.ttfb,
+	}
+	info.mu.Unlock()
+
+	res.Body = &chunkingBody{
+		body:      res.Body,
+		telemetry: t.telemetry,
+		template:  template,
+		start:     time.Now(),
+	}
+	return res, nil
+}
+
+// chunkingBody records a chunk every ChunkSize bytes read,
+// and one for the remainder when the body is done.
+type chunkingBody struct {
+	body      io.ReadCloser
+	telemetry *DownloadTelemetry
+	template  Chunk
+
+	mu    sync.Mutex
+	start time.Time
+	read  int64
+	done  bool
+}
+
+func (cb *chunkingBody) Read(p []byte) (int, error) {
+	cb.mu.Lock()
+	done := cb.done
+	// never read past the end of the current chunk, so
+	// chunks are exactly ChunkSize
+	if remaining := ChunkSize - cb.read; !done && int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	cb.mu.Unlock()
+
+	// not holding the lock, so Close can interrupt us
+	n, err := cb.body.Read(p)
+	if done {
+		return n, err
+	}
+
+	cb.telemetry.AddBytes(int64(n))
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.done {
+		return n, err
+	}
+	cb.read += int64(n)
+	if cb.read == ChunkSize {
+		cb.flushLocked()
+	}
+	if err != nil {
+		cb.finishLocked()
+	}
+	return n, err
+}
+
+func (cb *chunkingBody) Close() error {
+	cb.mu.Lock()
+	cb.finishLocked()
+	cb.mu.Unlock()
+	return cb.body.Close()
+}
+
+func (cb *chunkingBody) flushLocked() {
+	if cb.read == 0 {
+		return
+	}
+	c := cb.template
+	c.StartTime = cb.start
+	c.EndTime = time.Now()
+	c.BytesTransferred = cb.read
+	cb.telemetry.record(c)
+
+	cb.start = c.EndTime
+	cb.read = 0
+}
+
+func (cb *chunkingBody) finishLocked() {
+	if cb.done {
+		return
+	}
+	cb.flushLocked()
+	cb.done = true
+}
@@ -0,0 +1,185 @@
+package telemetry
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Chunking(t *testing.T) {
+	payload := bytes.Repeat([]byte{0x42}, 2*ChunkSize+ChunkSize/2)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/file", http.StatusFound)
+	})
+	mux.HandleFunc("/file", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(payload)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	dt := New()
+	client := dt.Client(http.DefaultClient)
+
+	res, err := client.Get(srv.URL + "/download")
+	assert.NoError(t, err)
+	body, err := ioutil.ReadAll(res.Body)
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.EqualValues(t, payload, body, "callers get the whole body")
+
+	chunks := dt.Chunks()
+	if assert.Len(t, chunks, 3, "redirect isn't recorded") {
+		assert.EqualValues(t, ChunkSize, chunks[0].BytesTransferred)
+		assert.EqualValues(t, ChunkSize, chunks[1].BytesTransferred)
+		assert.EqualValues(t, ChunkSize/2, chunks[2].BytesTransferred)
+		for _, c := range chunks {
+			assert.EqualValues(t, "127.0.0.1", c.ServerIP)
+			assert.True(t, c.TTFB > 0)
+			assert.False(t, c.EndTime.Before(c.StartTime))
+		}
+		assert.EqualValues(t, chunks[0].EndTime, chunks[1].StartTime, "chunks are back to back")
+	}
+
+	_, ok := dt.Jitter()
+	assert.False(t, ok, "not enough chunks for jitter")
+}
+
+// makeChunk returns a full chunk transferred at rate MiB/s
+func makeChunk(rate float64) Chunk {
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	return Chunk{
+		StartTime:        start,
+		EndTime:          start.Add(time.Duration(float64(time.Second) / rate)),
+		BytesTransferred: ChunkSize,
+	}
+}
+
+func Test_RingBuffer(t *testing.T) {
+	dt := New()
+	for i := 0; i < MaxChunks+5; i++ {
+		c := makeChunk(1)
+		c.ServerIP = string(rune('a' + i%26))
+		dt.record(c)
+	}
+
+	chunks := dt.Chunks()
+	assert.Len(t, chunks, MaxChunks)
+	assert.EqualValues(t, string(rune('a'+5)), chunks[0].ServerIP, "oldest first")
+}
+
+func Test_Jitter(t *testing.T) {
+	dt := New()
+	var calls []float64
+	dt.SetOnHighJitter(func(jitter float64) {
+		calls = append(calls, jitter)
+	})
+
+	for i := 0; i < MinJitterChunks; i++ {
+		dt.record(makeChunk(2))
+	}
+	jitter, ok := dt.Jitter()
+	assert.True(t, ok)
+	assert.InDelta(t, 0, jitter, 0.001, "steady connection")
+	assert.Len(t, calls, 0)
+
+	// partial chunks don't count
+	dt.record(Chunk{
+		StartTime:        time.Now(),
+		EndTime:          time.Now().Add(time.Hour),
+		BytesTransferred: 12,
+	})
+	jitter, _ = dt.Jitter()
+	assert.InDelta(t, 0, jitter, 0.001)
+
+	dt = New()
+	calls = nil
+	dt.SetOnHighJitter(func(jitter float64) {
+		calls = append(calls, jitter)
+	})
+	// rates of 1 and 4 MiB/s: mean 2.5, standard deviation 1.5
+	for i := 0; i < 2*MinJitterChunks; i++ {
+		if i%2 == 0 {
+			dt.record(makeChunk(1))
+		} else {
+			dt.record(makeChunk(4))
+		}
+	}
+	jitter, ok = dt.Jitter()
+	assert.True(t, ok)
+	assert.InDelta(t, 0.6, jitter, 0.001)
+	assert.Len(t, calls, 1, "only called when going above the threshold")
+}
+
+func Test_NetworkStats(t *testing.T) {
+	payload := bytes.Repeat([]byte{0x42}, 4*ChunkSize)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(payload)
+	}))
+	defer srv.Close()
+
+	dt := New()
+	stop := dt.StartSampling()
+	assert.Nil(t, dt.NetworkStats(), "nothing sampled before connecting")
+
+	client := dt.Client(http.DefaultClient)
+	res, err := client.Get(srv.URL)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+
+	buf := make([]byte, 1024)
+	_, err = res.Body.Read(buf)
+	assert.NoError(t, err)
+
+	dt.SampleNetwork()
+	stats := dt.NetworkStats()
+	if assert.NotNil(t, stats) {
+		assert.EqualValues(t, srv.Listener.Addr().String(), stats.RemoteAddr)
+		assert.NotEmpty(t, stats.LocalAddr)
+		if runtime.GOOS == "linux" {
+			assert.True(t, stats.TCPInfo)
+			assert.True(t, stats.TCPWindowSize > 0)
+		}
+	}
+
+	stop()
+	assert.Nil(t, dt.NetworkStats(), "forgotten once the download is done")
+}
+
+func Test_SpeedHistory(t *testing.T) {
+	dt := New()
+	assert.Empty(t, dt.SpeedSamples(MaxSpeedSamples))
+
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	dt.sampleSpeedAt(start)
+	assert.Empty(t, dt.SpeedSamples(MaxSpeedSamples), "first sample is just the baseline")
+
+	dt.AddBytes(3000)
+	dt.sampleSpeedAt(start.Add(2 * time.Second))
+	samples := dt.SpeedSamples(MaxSpeedSamples)
+	if assert.Len(t, samples, 1) {
+		assert.EqualValues(t, 1500, samples[0].BytesPerSecond)
+		assert.EqualValues(t, start.Add(2*time.Second), samples[0].Timestamp)
+	}
+
+	for i := 0; i < MaxSpeedSamples+5; i++ {
+		dt.AddBytes(int64(i))
+		dt.sampleSpeedAt(start.Add(time.Duration(3+i) * time.Second))
+	}
+	samples = dt.SpeedSamples(MaxSpeedSamples)
+	assert.Len(t, samples, MaxSpeedSamples)
+	assert.EqualValues(t, 5, samples[0].BytesPerSecond, "oldest first")
+	assert.EqualValues(t, MaxSpeedSamples+4, samples[len(samples)-1].BytesPerSecond)
+
+	samples = dt.SpeedSamples(10)
+	assert.Len(t, samples, 10)
+	assert.EqualValues(t, MaxSpeedSamples+4, samples[9].BytesPerSecond, "last ones")
+}
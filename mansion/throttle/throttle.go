@@ -0,0 +1,150 @@
+// Package throttle caps how fast downloads go, on top of the global
+// bandwidth limit of the HTTP transport.
+//
+// A Limiter wraps the HTTP client used to fetch a download's install
+// source (see Client). Every response body read through it waits for
+// enough tokens first. Its rate can be changed while bodies are read.
+// A Limiter may be shared by several downloads, to cap their total.
+package throttle
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Limiter caps the throughput of the responses of clients it wraps.
+// It is safe for concurrent use.
+type Limiter struct {
+	limiter *rate.Limiter
+}
+
+// New returns a Limiter that lets through bytesPerSecond,
+// or everything if bytesPerSecond is zero or less. Transferring
+// N bytes through it takes N / bytesPerSecond seconds.
+func New(bytesPerSecond int64) *Limiter {
+	l := &Limiter{
+		limiter: rate.NewLimiter(rate.Inf, 0),
+	}
+	l.SetRate(bytesPerSecond)
+	if bytesPerSecond > 0 {
+		// start with an empty bucket, so transfers don't begin with a burst
+		l.limiter.AllowN(time.Now(), int(bytesPerSecond))
+	}
+	return l
+}
+
+// SetRate changes how many bytes per second l lets through, zero
+// or less meaning unlimited. Reads in progress pick it up right away.
+func (l *Limiter) SetRate(bytesPerSecond int64) {
+	if bytesPerSecond <= 0 {
+		l.limiter.SetLimit(rate.Inf)
+		return
+	}
+	// bursts of up to a second's worth
+	l.limiter.SetBurst(int(bytesPerSecond))
+	l.limiter.SetLimit(rate.Limit(bytesPerSecond))
+}
+
+// Rate returns how many bytes per second l lets through,
+// or 0 if it's unlimited.
+func (l *Limiter) Rate() int64 {
+	limit := l.limiter.Limit()
+	if limit == rate.Inf {
+		return 0
+	}
+	return int64(limit)
+}
+
+// burst returns how many bytes can go through at once,
+// or 0 if l is unlimited.
+func (l *Limiter) burst() int {
+	if l.limiter.Limit() == rate.Inf {
+		return 0
+	}
+	return l.limiter.Burst()
+}
+
+// Client returns a copy of base whose responses are throttled.
+func (l *Limiter) Client(base *http.Client) *http.Client {
+	client := *base
+	next := base.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	client.Transport = &transport{limiter: l, next: next}
+	return &client
+}
+
+type limiterKey struct{}
+
+// WithLimiter returns a context for an operation whose
+// downloads should be throttled by l, on top of any limiter
+// ctx already has.
+func WithLimiter(ctx context.Context, l *Limiter) context.Context {
+	parent := FromContext(ctx)
+	limiters := make([]*Limiter, 0, len(parent)+1)
+	limiters = append(limiters, parent...)
+	limiters = append(limiters, l)
+	return context.WithValue(ctx, limiterKey{}, limiters)
+}
+
+// FromContext returns the limiters set by WithLimiter, outermost first
+func FromContext(ctx context.Context) []*Limiter {
+	if limiters, ok := ctx.Value(limiterKey{}).([]*Limiter); ok {
+		return limiters
+	}
+	return nil
+}
+
+type transport struct {
+	limiter *Limiter
+	next    http.RoundTripper
+}
+
+var _ http.RoundTripper = (*transport)(nil)
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Body = &throttledBody{
+		body:    res.Body,
+		limiter: t.limiter,
+		ctx:     req.Context(),
+	}
+	return res, nil
+}
+
+type throttledBody struct {
+	body    io.ReadCloser
+	limiter *Limiter
+	ctx     context.Context
+}
+
+func (tb *throttledBody) Read(p []byte) (int, error) {
+	burst := tb.limiter.burst()
+	if burst == 0 {
+		return tb.body.Read(p)
+	}
+
+	if len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := tb.body.Read(p)
+	if n > 0 {
+		if waitErr := tb.limiter.limiter.WaitN(tb.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+func (tb *throttledBody) Close() error {
+	return tb.body.Close()
+}
@@ -0,0 +1,96 @@
+package throttle
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Throttle(t *testing.T) {
+	assert := assert.New(t)
+
+	payload := []byte("ab")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(payload)
+	}))
+	defer srv.Close()
+
+	get := func(client *http.Client) time.Duration {
+		start := time.Now()
+		res, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.True(bytes.Equal(payload, body))
+		return time.Since(start)
+	}
+
+	l := New(1)
+	assert.EqualValues(1, l.Rate())
+	elapsed := get(l.Client(http.DefaultClient))
+	assert.True(elapsed >= 1900*time.Millisecond, "one second per byte, took %s", elapsed)
+	assert.True(elapsed < 3*time.Second, "one second per byte, took %s", elapsed)
+
+	l.SetRate(0)
+	assert.EqualValues(0, l.Rate())
+	elapsed = get(l.Client(http.DefaultClient))
+	assert.True(elapsed < 500*time.Millisecond, "unlimited, took %s", elapsed)
+}
+
+func Test_SetRateMidTransfer(t *testing.T) {
+	assert := assert.New(t)
+
+	payload := bytes.Repeat([]byte{0x42}, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(payload)
+	}))
+	defer srv.Close()
+
+	l := New(1)
+	res, err := l.Client(http.DefaultClient).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	buf := make([]byte, 1)
+	_, err = res.Body.Read(buf)
+	assert.NoError(err)
+
+	// 9 bytes left, which would take 9 seconds at the original rate
+	start := time.Now()
+	l.SetRate(1000)
+	rest, err := ioutil.ReadAll(res.Body)
+	assert.NoError(err)
+	assert.Len(rest, 9)
+	elapsed := time.Since(start)
+	assert.True(elapsed < 2*time.Second, "took %s", elapsed)
+}
+
+func Test_WithLimiterStacks(t *testing.T) {
+	assert := assert.New(t)
+
+	shared := New(0)
+	own := New(0)
+
+	ctx := context.Background()
+	assert.Empty(FromContext(ctx))
+
+	ctx = WithLimiter(ctx, shared)
+	other := WithLimiter(ctx, New(0))
+	ctx = WithLimiter(ctx, own)
+	assert.EqualValues([]*Limiter{shared, own}, FromContext(ctx))
+	assert.Len(FromContext(other), 2, "siblings don't see each other's limiters")
+	assert.True(FromContext(other)[1] != own)
+}
@@ -0,0 +1,172 @@
+// Package pathsafety checks whether the entries of a build can be
+// created on a given platform, before we actually try to.
+//
+// Some builds contain file names that are fine on Linux but illegal on
+// Windows (colons, question marks, trailing dots, etc.). Extracting those
+// fails halfway through with a cryptic OS error, so it's better to find
+// out earlier and report every offending entry at once.
+package pathsafety
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/itchio/ox"
+)
+
+// Problem is something that makes a path unsafe on a platform
+type Problem string
+
+const (
+	// The path contains a character that isn't allowed in file names,
+	// like `:`, `?` or `*` on Windows
+	ProblemReservedCharacter Problem = "reserved-character"
+	// A component of the path ends with a dot or a space, which
+	// Windows silently strips
+	ProblemTrailingDotOrSpace Problem = "trailing-dot-or-space"
+	// A component of the path is a reserved device name on Windows,
+	// like `CON`, `NUL` or `COM1` (with or without an extension)
+	ProblemReservedName Problem = "reserved-name"
+	// A component of the path is longer than the platform allows
+	ProblemComponentTooLong Problem = "component-too-long"
+	// The full path (install folder included) is longer than MAX_PATH.
+	// butler can deal with those, but many games and tools can't, so it
+	// is only a warning.
+	ProblemPathTooLong Problem = "path-too-long"
+)
+
+// Fatal returns true if extracting a file with that problem will fail
+func (p Problem) Fatal() bool {
+	return p != ProblemPathTooLong
+}
+
+const (
+	windowsMaxPath       = 260
+	maxComponentLength   = 255
+	windowsReservedChars = `<>:"|?*`
+)
+
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// Offender is an entry that has at least one problem
+type Offender struct {
+	// Slash-separated path of the entry, relative to the install folder
+	Path     string    `json:"path"`
+	Problems []Problem `json:"problems"`
+}
+
+func (o Offender) String() string {
+	var problems []string
+	for _, p := range o.Problems {
+		problems = append(problems, string(p))
+	}
+	return fmt.Sprintf("%s (%s)", o.Path, strings.Join(problems, ", "))
+}
+
+// Fatal returns true if any of the offender's problems is fatal
+func (o Offender) Fatal() bool {
+	for _, p := range o.Problems {
+		if p.Fatal() {
+			return true
+		}
+	}
+	return false
+}
+
+// Report is the result of a scan
+type Report struct {
+	Platform  ox.Platform
+	Offenders []Offender
+}
+
+// Fatal returns the offenders that would make extraction fail
+func (r *Report) Fatal() []Offender {
+	var res []Offender
+	for _, o := range r.Offenders {
+		if o.Fatal() {
+			res = append(res, o)
+		}
+	}
+	return res
+}
+
+// Check returns all the problems entryPath would have on platform, once
+// extracted in installFolder. entryPath is slash-separated, like in
+// zip files and wharf containers. installFolder may be empty, in which
+// case the full path length isn't checked.
+func Check(platform ox.Platform, installFolder string, entryPath string) []Problem {
+	var problems []Problem
+	seen := make(map[Problem]bool)
+	add := func(p Problem) {
+		if !seen[p] {
+			seen[p] = true
+			problems = append(problems, p)
+		}
+	}
+
+	for _, component := range strings.Split(path.Clean(entryPath), "/") {
+		if component == "" || component == "." || component == ".." {
+			continue
+		}
+
+		if len(component) > maxComponentLength {
+			add(ProblemComponentTooLong)
+		}
+
+		if platform != ox.PlatformWindows {
+			continue
+		}
+
+		for _, r := range component {
+			if r < 0x20 || strings.ContainsRune(windowsReservedChars, r) {
+				add(ProblemReservedCharacter)
+			}
+		}
+
+		if strings.HasSuffix(component, ".") || strings.HasSuffix(component, " ") {
+			add(ProblemTrailingDotOrSpace)
+		}
+
+		base := component
+		if i := strings.IndexRune(base, '.'); i >= 0 {
+			base = base[:i]
+		}
+		if windowsReservedNames[strings.ToUpper(strings.TrimRight(base, " "))] {
+			add(ProblemReservedName)
+		}
+	}
+
+	if platform == ox.PlatformWindows && installFolder != "" {
+		fullPath := strings.TrimRight(installFolder, `\/`) + `\` + entryPath
+		if utf8.RuneCountInString(fullPath) >= windowsMaxPath {
+			add(ProblemPathTooLong)
+		}
+	}
+
+	return problems
+}
+
+// Scan checks all paths, see Check
+func Scan(platform ox.Platform, installFolder string, paths []string) *Report {
+	report := &Report{
+		Platform: platform,
+	}
+	for _, p := range paths {
+		problems := Check(platform, installFolder, p)
+		if len(problems) > 0 {
+			report.Offenders = append(report.Offenders, Offender{
+				Path:     p,
+				Problems: problems,
+			})
+		}
+	}
+	return report
+}
@@ -0,0 +1,169 @@
+package pathsafety_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/itchio/butler/pathsafety"
+	"github.com/itchio/lake/tlc"
+	"github.com/itchio/ox"
+	"github.com/stretchr/testify/assert"
+)
+
+var longName = strings.Repeat("a", 300)
+
+// one fixture per class of bad name, plus some good ones
+var fixtureEntries = map[string][]pathsafety.Problem{
+	"data/level1.dat":          nil,
+	"data/":                    nil,
+	"sounds/boom.wav":          nil,
+	"notes/12:30.txt":          {pathsafety.ProblemReservedCharacter},
+	"what?.txt":                {pathsafety.ProblemReservedCharacter},
+	"quote\".txt":              {pathsafety.ProblemReservedCharacter},
+	"pipe|star*.txt":           {pathsafety.ProblemReservedCharacter},
+	"bell\a.txt":               {pathsafety.ProblemReservedCharacter},
+	"trailing-dot./file.txt":   {pathsafety.ProblemTrailingDotOrSpace},
+	"trailing-space /file.txt": {pathsafety.ProblemTrailingDotOrSpace},
+	"devices/CON":              {pathsafety.ProblemReservedName},
+	"devices/nul.txt":          {pathsafety.ProblemReservedName},
+	"devices/com1.tar.gz":      {pathsafety.ProblemReservedName},
+	"devices/console.txt":      nil,
+	"deep/" + longName:         {pathsafety.ProblemComponentTooLong},
+}
+
+func fixtureZip(t *testing.T) *bytes.Reader {
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for name := range fixtureEntries {
+		w, err := zw.Create(name)
+		assert.NoError(t, err)
+		if !strings.HasSuffix(name, "/") {
+			_, err = w.Write([]byte("fixture"))
+			assert.NoError(t, err)
+		}
+	}
+	assert.NoError(t, zw.Close())
+	return bytes.NewReader(buf.Bytes())
+}
+
+func fixtureContainer() *tlc.Container {
+	c := &tlc.Container{}
+	for name := range fixtureEntries {
+		if strings.HasSuffix(name, "/") {
+			c.Dirs = append(c.Dirs, &tlc.Dir{Path: strings.TrimSuffix(name, "/")})
+		} else {
+			c.Files = append(c.Files, &tlc.File{Path: name})
+		}
+	}
+	return c
+}
+
+func assertWindowsReport(t *testing.T, report *pathsafety.Report) {
+	offenders := make(map[string][]pathsafety.Problem)
+	for _, o := range report.Offenders {
+		offenders[o.Path] = o.Problems
+	}
+
+	for name, expected := range fixtureEntries {
+		name = strings.TrimSuffix(name, "/")
+		if expected == nil {
+			assert.NotContains(t, offenders, name)
+		} else {
+			assert.EqualValues(t, expected, offenders[name], "for %q", name)
+		}
+	}
+	assert.Len(t, report.Fatal(), len(report.Offenders))
+}
+
+func Test_ScanZip(t *testing.T) {
+	r := fixtureZip(t)
+	paths, err := pathsafety.ZipPaths(r, r.Size())
+	assert.NoError(t, err)
+	assert.Len(t, paths, len(fixtureEntries))
+
+	for i := range paths {
+		paths[i] = strings.TrimSuffix(paths[i], "/")
+	}
+	assertWindowsReport(t, pathsafety.Scan(ox.PlatformWindows, "", paths))
+}
+
+func Test_ScanContainer(t *testing.T) {
+	paths := pathsafety.ContainerPaths(fixtureContainer())
+	assert.Len(t, paths, len(fixtureEntries))
+	assertWindowsReport(t, pathsafety.Scan(ox.PlatformWindows, "", paths))
+}
+
+func Test_ScanOtherPlatforms(t *testing.T) {
+	paths := pathsafety.ContainerPaths(fixtureContainer())
+
+	for _, platform := range []ox.Platform{ox.PlatformLinux, ox.PlatformOSX} {
+		report := pathsafety.Scan(platform, "/home/user/games", paths)
+		assert.Len(t, report.Offenders, 1, "on %s", platform)
+		assert.EqualValues(t, []pathsafety.Problem{pathsafety.ProblemComponentTooLong}, report.Offenders[0].Problems)
+	}
+}
+
+func Test_PathTooLong(t *testing.T) {
+	installFolder := `C:\Users\Someone\AppData\Roaming\itch\apps\some-game`
+	entry := strings.Repeat("folder/", 30) + "file.txt"
+
+	problems := pathsafety.Check(ox.PlatformWindows, installFolder, entry)
+	assert.EqualValues(t, []pathsafety.Problem{pathsafety.ProblemPathTooLong}, problems)
+	assert.False(t, problems[0].Fatal())
+
+	report := pathsafety.Scan(ox.PlatformWindows, installFolder, []string{entry, "ok.txt"})
+	assert.Len(t, report.Offenders, 1)
+	assert.Empty(t, report.Fatal())
+
+	assert.Empty(t, pathsafety.Check(ox.PlatformWindows, "", entry))
+	assert.Empty(t, pathsafety.Check(ox.PlatformLinux, "/home/someone/games", entry))
+}
+
+func TestSanitize(t *testing.T) {
+	assert := assert.New(t)
+
+	for name, problems := range fixtureEntries {
+		sanitized := pathsafety.Sanitize(ox.PlatformWindows, name)
+		if len(problems) == 0 {
+			assert.EqualValues(name, sanitized, "%s should be left alone", name)
+		} else {
+			assert.NotEqual(name, sanitized, "%s should be sanitized", name)
+		}
+		assert.Empty(pathsafety.Check(ox.PlatformWindows, "", sanitized), "%s sanitized to %s", name, sanitized)
+		assert.EqualValues(sanitized, pathsafety.Sanitize(ox.PlatformWindows, sanitized), "sanitizing twice changes nothing")
+	}
+
+	assert.EqualValues("notes/12_30.txt", pathsafety.Sanitize(ox.PlatformWindows, "notes/12:30.txt"))
+	assert.EqualValues("trailing-dot_/file.txt", pathsafety.Sanitize(ox.PlatformWindows, "trailing-dot./file.txt"))
+	assert.EqualValues("devices/CON_", pathsafety.Sanitize(ox.PlatformWindows, "devices/CON"))
+	assert.EqualValues("devices/com1_.tar.gz", pathsafety.Sanitize(ox.PlatformWindows, "devices/com1.tar.gz"))
+	assert.EqualValues("notes/12:30.txt", pathsafety.Sanitize(ox.PlatformLinux, "notes/12:30.txt"), "colons are fine on Linux")
+
+	long := pathsafety.Sanitize(ox.PlatformLinux, "deep/"+longName+".txt")
+	assert.True(strings.HasPrefix(long, "deep/aaa"))
+	assert.True(strings.HasSuffix(long, ".txt"))
+	assert.Empty(pathsafety.Check(ox.PlatformLinux, "", long))
+}
+
+func TestRenames(t *testing.T) {
+	assert := assert.New(t)
+
+	renames := pathsafety.Renames(ox.PlatformWindows, []string{
+		"readme.txt",
+		"what?.txt",
+		"what_.txt",
+		"what*.txt",
+		"trailing-dot./",
+		"trailing-dot./file.txt",
+	})
+	assert.EqualValues(map[string]string{
+		"what*.txt":              "what_~2.txt",
+		"what?.txt":              "what_~3.txt",
+		"trailing-dot.":          "trailing-dot_",
+		"trailing-dot./file.txt": "trailing-dot_/file.txt",
+	}, renames)
+
+	assert.Empty(pathsafety.Renames(ox.PlatformLinux, []string{"what?.txt"}))
+}
@@ -0,0 +1,117 @@
+package pathsafety
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/itchio/ox"
+)
+
+// room left in truncated components for a collision suffix, see Renames
+const collisionRoom = 8
+
+// Sanitize returns entryPath with every component that has a fatal
+// problem on platform (see Check) changed so it doesn't anymore:
+//
+//   - reserved characters become `_`
+//   - trailing dots and spaces become `_`
+//   - reserved device names get a `_` appended (`CON.txt` becomes `CON_.txt`)
+//   - over-long components are cut short, keeping their extension
+//
+// The same entryPath always gives the same result. Paths without
+// fatal problems are returned unchanged.
+func Sanitize(platform ox.Platform, entryPath string) string {
+	dir := strings.HasSuffix(entryPath, "/")
+	components := strings.Split(strings.TrimSuffix(entryPath, "/"), "/")
+	for i, c := range components {
+		components[i] = sanitizeComponent(platform, c)
+	}
+	res := strings.Join(components, "/")
+	if dir {
+		res += "/"
+	}
+	return res
+}
+
+func sanitizeComponent(platform ox.Platform, c string) string {
+	if c == "" || c == "." || c == ".." {
+		return c
+	}
+
+	if platform == ox.PlatformWindows {
+		c = strings.Map(func(r rune) rune {
+			if r < 0x20 || strings.ContainsRune(windowsReservedChars, r) {
+				return '_'
+			}
+			return r
+		}, c)
+
+		trimmed := strings.TrimRight(c, ". ")
+		if len(trimmed) < len(c) {
+			c = trimmed + strings.Repeat("_", len(c)-len(trimmed))
+		}
+
+		base := c
+		if i := strings.IndexRune(base, '.'); i >= 0 {
+			base = base[:i]
+		}
+		if windowsReservedNames[strings.ToUpper(strings.TrimRight(base, " "))] {
+			c = base + "_" + c[len(base):]
+		}
+	}
+
+	if len(c) > maxComponentLength {
+		ext := path.Ext(c)
+		if len(ext) > collisionRoom {
+			ext = ""
+		}
+		c = truncate(c[:len(c)-len(ext)], maxComponentLength-collisionRoom-len(ext)) + ext
+	}
+	return c
+}
+
+// truncate cuts s to at most n bytes, without splitting a character
+func truncate(s string, n int) string {
+	for len(s) > n {
+		_, size := utf8.DecodeLastRuneInString(s)
+		s = s[:len(s)-size]
+	}
+	return s
+}
+
+// Renames returns the paths that must be sanitized to be created on
+// platform, mapped to what they should be created as instead (both
+// without trailing slashes). Sanitized files that would end up with
+// the same name as another entry get a `~2`, `~3`, etc. suffix. The
+// same list of paths always gives the same renames.
+func Renames(platform ox.Platform, paths []string) map[string]string {
+	sorted := make([]string, 0, len(paths))
+	taken := make(map[string]bool)
+	for _, p := range paths {
+		p = strings.TrimSuffix(p, "/")
+		sorted = append(sorted, p)
+		taken[p] = true
+	}
+	sort.Strings(sorted)
+
+	renames := make(map[string]string)
+	for _, p := range sorted {
+		np := Sanitize(platform, p)
+		if np == p {
+			continue
+		}
+		if taken[np] {
+			ext := path.Ext(np)
+			stem := strings.TrimSuffix(np, ext)
+			for i := 2; taken[np]; i++ {
+				np = fmt.Sprintf("%s~%d%s", stem, i, ext)
+			}
+		}
+		taken[np] = true
+		renames[p] = np
+	}
+	return renames
+}
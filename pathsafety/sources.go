@@ -0,0 +1,40 @@
+package pathsafety
+
+import (
+	"archive/zip"
+	"io"
+
+	"github.com/itchio/lake/tlc"
+	"github.com/pkg/errors"
+)
+
+// ZipPaths lists the entries of a zip file from its central
+// directory, without extracting anything.
+func ZipPaths(r io.ReaderAt, size int64) ([]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var paths []string
+	for _, f := range zr.File {
+		paths = append(paths, f.Name)
+	}
+	return paths, nil
+}
+
+// ContainerPaths lists all the directories, files and symlinks
+// of a wharf container.
+func ContainerPaths(c *tlc.Container) []string {
+	var paths []string
+	for _, d := range c.Dirs {
+		paths = append(paths, d.Path)
+	}
+	for _, f := range c.Files {
+		paths = append(paths, f.Path)
+	}
+	for _, s := range c.Symlinks {
+		paths = append(paths, s.Path)
+	}
+	return paths
+}